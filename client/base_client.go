@@ -40,6 +40,8 @@ type baseClient struct {
 	leader atomic.Value // Store as string
 	// PD follower URLs
 	followers atomic.Value // Store as []string
+	// PD follower URL which is most likely to be the next leader
+	nextLeader atomic.Value // Store as string
 	// dc-location -> TSO allocator leader gRPC connection
 	clientConns sync.Map // Store as map[string]*grpc.ClientConn
 	// dc-location -> TSO allocator leader URL
@@ -58,6 +60,26 @@ type baseClient struct {
 	timeout          time.Duration
 	maxRetryTimes    int
 	enableForwarding bool
+	enableWarmUp     bool
+	routingDomain    string
+	callerComponent  string
+	adminToken       string
+	// compressor is the gRPC compressor of the region-heavy requests, empty
+	// means no compression.
+	compressor string
+	// enableStoreFollowerRead allows the followers to serve the store
+	// metadata reads.
+	enableStoreFollowerRead bool
+	// offlineCachePath is the file to save the metadata for the offline
+	// mode, empty means the offline mode is disabled.
+	offlineCachePath string
+	// membersUnreachable is 1 if the client failed to reach every PD member
+	// the last time it updated the members.
+	membersUnreachable int32
+
+	// The bound of the pending TSO requests of each dc-location, zero means
+	// no bound other than the capacity of the request channel.
+	maxPendingTSORequests int64
 }
 
 // SecurityOption records options about tls
@@ -91,6 +113,84 @@ func WithForwardingOption(enableForwarding bool) ClientOption {
 	}
 }
 
+// WithWarmUpOption configures the client to keep the connections to all the
+// PD members and to create the TSO stream to the most likely next leader in
+// advance, which shortens the unavailability after the leader changes.
+func WithWarmUpOption(enableWarmUp bool) ClientOption {
+	return func(c *baseClient) {
+		c.enableWarmUp = enableWarmUp
+	}
+}
+
+// WithRoutingDomain configures the client to use the PD urls advertised for
+// the given routing domain.
+func WithRoutingDomain(domain string) ClientOption {
+	return func(c *baseClient) {
+		c.routingDomain = domain
+	}
+}
+
+// WithCallerComponent configures the client to tag its requests with the
+// component of the caller, such as "br" or "cdc", so that PD can apply the
+// request quota of the component to them.
+func WithCallerComponent(component string) ClientOption {
+	return func(c *baseClient) {
+		c.callerComponent = component
+	}
+}
+
+// WithAdminToken configures the client to attach the token to its requests,
+// which authorizes them to the admin methods if PD enables the admin
+// authorization.
+func WithAdminToken(token string) ClientOption {
+	return func(c *baseClient) {
+		c.adminToken = token
+	}
+}
+
+// WithMaxPendingTSORequests configures the client to reject the TSO requests
+// once the given number of requests of a dc-location are pending, instead of
+// blocking the callers once the request channel is full. Zero means no bound,
+// and the bound should not exceed the capacity of the channel, which is 10000.
+func WithMaxPendingTSORequests(count int64) ClientOption {
+	return func(c *baseClient) {
+		c.maxPendingTSORequests = count
+	}
+}
+
+// WithGRPCCompression configures the client to compress the region-heavy
+// requests with the gRPC compressor, such as "gzip", and PD compresses their
+// responses with it too, which saves the bandwidth at the cost of the CPU.
+func WithGRPCCompression(compressor string) ClientOption {
+	return func(c *baseClient) {
+		c.compressor = compressor
+	}
+}
+
+// WithStoreFollowerRead configures the client to read the store metadata from
+// the followers, which spreads the reads across the PD members. The client
+// never reads the metadata older than it has read, and it falls back to the
+// leader if the follower is not synced. The followers return the stores as
+// they are persisted, so the store stats are only returned by the leader, and
+// the last heartbeat time returned by the followers may be stale.
+func WithStoreFollowerRead(enable bool) ClientOption {
+	return func(c *baseClient) {
+		c.enableStoreFollowerRead = enable
+	}
+}
+
+// WithOfflineCache configures the client to save the last known metadata of
+// the regions and stores to the file, and serve the region and store lookups
+// from it once every PD member is unreachable. The regions served from it are
+// flagged as Stale, and so are the stores with WithStaleFlag. It only serves
+// the lookups, the client still needs PD to be created. At most 100000 regions
+// are kept, and the least recently updated ones are evicted beyond it.
+func WithOfflineCache(path string) ClientOption {
+	return func(c *baseClient) {
+		c.offlineCachePath = path
+	}
+}
+
 // WithMaxErrorRetry configures the client max retry times when connect meets error.
 func WithMaxErrorRetry(count int) ClientOption {
 	return func(c *baseClient) {
@@ -114,6 +214,22 @@ func newBaseClient(ctx context.Context, urls []string, security SecurityOption,
 	for _, opt := range opts {
 		opt(c)
 	}
+	if c.maxPendingTSORequests < 0 || c.maxPendingTSORequests > maxMergeTSORequests {
+		c.cancel()
+		return nil, errors.WithStack(errs.ErrClientMaxPendingTSORequests.FastGenByArgs(c.maxPendingTSORequests, maxMergeTSORequests))
+	}
+	if !grpcutil.IsCompressorSupported(c.compressor) {
+		c.cancel()
+		return nil, errors.WithStack(errs.ErrClientGRPCCompressor.FastGenByArgs(c.compressor))
+	}
+	if c.callerComponent != "" {
+		c.gRPCDialOptions = append(c.gRPCDialOptions,
+			grpc.WithChainUnaryInterceptor(c.callerComponentUnaryInterceptor),
+			grpc.WithChainStreamInterceptor(c.callerComponentStreamInterceptor))
+	}
+	if c.adminToken != "" {
+		c.gRPCDialOptions = append(c.gRPCDialOptions, grpc.WithChainUnaryInterceptor(c.adminTokenUnaryInterceptor))
+	}
 
 	if err := c.initRetry(c.initClusterID); err != nil {
 		c.cancel()
@@ -131,6 +247,14 @@ func newBaseClient(ctx context.Context, urls []string, security SecurityOption,
 	return c, nil
 }
 
+// regionCallOptions returns the options of the region-heavy requests.
+func (c *baseClient) regionCallOptions() []grpc.CallOption {
+	if c.compressor == "" {
+		return nil
+	}
+	return []grpc.CallOption{grpc.UseCompressor(c.compressor)}
+}
+
 func (c *baseClient) initRetry(f func() error) error {
 	var err error
 	for i := 0; i < c.maxRetryTimes; i++ {
@@ -183,6 +307,12 @@ func (c *baseClient) scheduleCheckTSODispatcher() {
 	}
 }
 
+// isMembersUnreachable returns true if every PD member was unreachable the
+// last time the client updated the members.
+func (c *baseClient) isMembersUnreachable() bool {
+	return atomic.LoadInt32(&c.membersUnreachable) == 1
+}
+
 // GetClusterID returns the ClusterID.
 func (c *baseClient) GetClusterID(context.Context) uint64 {
 	return c.clusterID
@@ -277,11 +407,21 @@ func (c *baseClient) initClusterID() error {
 }
 
 func (c *baseClient) updateMember() error {
+	unreachable := true
+	defer func() {
+		if unreachable {
+			atomic.StoreInt32(&c.membersUnreachable, 1)
+		} else {
+			atomic.StoreInt32(&c.membersUnreachable, 0)
+		}
+	}()
 	for _, u := range c.urls {
 		ctx, cancel := context.WithTimeout(c.ctx, updateMemberTimeout)
 		members, err := c.getMembers(ctx, u)
 		if err != nil {
 			log.Warn("[pd] cannot update member", zap.String("address", u), errs.ZapError(err))
+		} else {
+			unreachable = false
 		}
 		cancel()
 		if err := c.switchTSOAllocatorLeader(members.GetTsoAllocatorLeaders()); err != nil {
@@ -297,6 +437,9 @@ func (c *baseClient) updateMember() error {
 		}
 		c.updateURLs(members.GetMembers())
 		c.updateFollowers(members.GetMembers(), members.GetLeader())
+		if c.enableWarmUp {
+			c.warmUp(members.GetMembers(), members.GetLeader())
+		}
 		if err := c.switchLeader(members.GetLeader().GetClientUrls()); err != nil {
 			return err
 		}
@@ -306,11 +449,26 @@ func (c *baseClient) updateMember() error {
 	return errs.ErrClientGetLeader.FastGenByArgs(c.urls)
 }
 
+func (c *baseClient) callerComponentUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(grpcutil.BuildCallerComponentContext(ctx, c.callerComponent), method, req, reply, cc, opts...)
+}
+
+func (c *baseClient) callerComponentStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(grpcutil.BuildCallerComponentContext(ctx, c.callerComponent), desc, cc, method, opts...)
+}
+
+func (c *baseClient) adminTokenUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(grpcutil.BuildAdminTokenContext(ctx, c.adminToken), method, req, reply, cc, opts...)
+}
+
 func (c *baseClient) getMembers(ctx context.Context, url string) (*pdpb.GetMembersResponse, error) {
 	cc, err := c.getOrCreateGRPCConn(url)
 	if err != nil {
 		return nil, err
 	}
+	if c.routingDomain != "" {
+		ctx = grpcutil.BuildRoutingDomainContext(ctx, c.routingDomain)
+	}
 	members, err := pdpb.NewPDClient(cc).GetMembers(ctx, &pdpb.GetMembersRequest{})
 	if err != nil {
 		attachErr := errors.Errorf("error:%s target:%s status:%s", err, cc.Target(), cc.GetState().String())
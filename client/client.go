@@ -16,6 +16,7 @@ package pd
 import (
 	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"strings"
 	"sync"
@@ -28,12 +29,15 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/grpcutil"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -43,6 +47,9 @@ type Region struct {
 	Leader       *metapb.Peer
 	DownPeers    []*metapb.Peer
 	PendingPeers []*metapb.Peer
+	// Stale is true if the region is served from the offline cache because
+	// every PD member is unreachable. See WithOfflineCache.
+	Stale bool
 }
 
 // Client is a PD (Placement Driver) client.
@@ -80,10 +87,33 @@ type Client interface {
 	// If a region has no leader, corresponding leader will be placed by a peer
 	// with empty value (PeerID is 0).
 	ScanRegions(ctx context.Context, key, endKey []byte, limit int) ([]*Region, error)
+	// ScanRegionsStream scans the regions like ScanRegions, but receives them
+	// from a stream in chunks of chunkSize regions, so that a huge key range can
+	// be scanned with a single request. The handler is called for each chunk,
+	// and the scan stops once the handler returns an error. A zero limit means
+	// no limit, and a zero chunkSize means the default chunk size of the server.
+	ScanRegionsStream(ctx context.Context, key, endKey []byte, limit, chunkSize int, handler func([]*Region) error) error
+	// GetRegionsByStore gets a page of the regions which have peers on the
+	// store, in key order, starting from the region that contains key. The
+	// next page starts from the end key of the last region, and the listing is
+	// done when fewer than limit regions are returned. A zero limit means the
+	// default page size of the server.
+	GetRegionsByStore(ctx context.Context, storeID uint64, key []byte, limit int) ([]*Region, error)
+	// WatchRegions watches the changes of the regions in the key range,
+	// including creations, splits, merges and leader changes. The handler is
+	// called with the changed regions and the index to resume watching from,
+	// and the watch stops once the handler returns an error. Without
+	// WithResumeIndex, the watch starts from now, and the handler is called
+	// with no region and the current index first. It is usually used with
+	// ScanRegions to keep a region cache warm without polling.
+	WatchRegions(ctx context.Context, key, endKey []byte, handler func(regions []*Region, nextIndex uint64) error, opts ...WatchRegionsOption) error
 	// GetStore gets a store from PD by store id.
 	// The store may expire later. Caller is responsible for caching and taking care
 	// of store change.
 	GetStore(ctx context.Context, storeID uint64) (*metapb.Store, error)
+	// GetStoreWithOptions is like GetStore, and only WithStaleFlag applies to
+	// it.
+	GetStoreWithOptions(ctx context.Context, storeID uint64, opts ...GetStoreOption) (*metapb.Store, error)
 	// GetAllStores gets all stores from pd.
 	// The store may expire later. Caller is responsible for caching and taking care
 	// of store change.
@@ -109,13 +139,34 @@ type Client interface {
 	SplitRegions(ctx context.Context, splitKeys [][]byte, opts ...RegionsOption) (*pdpb.SplitRegionsResponse, error)
 	// GetOperator gets the status of operator of the specified region.
 	GetOperator(ctx context.Context, regionID uint64) (*pdpb.GetOperatorResponse, error)
+	// WaitForConfigRevision blocks until the PD leader has observed the given
+	// etcd revision, which is usually the revision of a config change. It can
+	// be used to sequence the operations depending on the config change.
+	WaitForConfigRevision(ctx context.Context, revision int64, opts ...ConfigRevisionOption) error
+	// AllocIDRange leases count contiguous unique ids from PD for ttl, so that
+	// the caller can hand them out by itself instead of calling PD for each
+	// one. The ids are never allocated again, even if they are not used up.
+	AllocIDRange(ctx context.Context, count uint64, ttl time.Duration) (*IDRange, error)
 	// Close closes the client.
 	Close()
 }
 
+// IDRange is a range of unique ids leased from PD.
+type IDRange struct {
+	// Start is the first id of the range, and End is the one after the last.
+	Start uint64
+	End   uint64
+	// Deadline is when the lease expires, after which the ids should not be
+	// handed out any more.
+	Deadline time.Time
+}
+
 // GetStoreOp represents available options when getting stores.
 type GetStoreOp struct {
 	excludeTombstone bool
+	keyspaceID       *uint32
+	labels           []*metapb.StoreLabel
+	stale            *bool
 }
 
 // GetStoreOption configures GetStoreOp.
@@ -126,10 +177,30 @@ func WithExcludeTombstone() GetStoreOption {
 	return func(op *GetStoreOp) { op.excludeTombstone = true }
 }
 
+// WithKeyspaceID only returns the stores which can hold the peers of the
+// keyspace under the placement rules.
+func WithKeyspaceID(keyspaceID uint32) GetStoreOption {
+	return func(op *GetStoreOp) { op.keyspaceID = &keyspaceID }
+}
+
+// WithStoreLabel only returns the stores with the label. It can be used
+// multiple times, and the stores must have all the labels.
+func WithStoreLabel(key, value string) GetStoreOption {
+	return func(op *GetStoreOp) { op.labels = append(op.labels, &metapb.StoreLabel{Key: key, Value: value}) }
+}
+
+// WithStaleFlag sets stale to true if the stores are served from the offline
+// cache because every PD member is unreachable. See WithOfflineCache.
+func WithStaleFlag(stale *bool) GetStoreOption {
+	return func(op *GetStoreOp) { op.stale = stale }
+}
+
 // RegionsOp represents available options when operate regions
 type RegionsOp struct {
-	group      string
-	retryLimit uint64
+	group           string
+	retryLimit      uint64
+	idempotencyKey  string
+	scatterFailures *[]*ScatterFailure
 }
 
 // RegionsOption configures RegionsOp
@@ -145,6 +216,53 @@ func WithRetry(retry uint64) RegionsOption {
 	return func(op *RegionsOp) { op.retryLimit = retry }
 }
 
+// WithIdempotencyKey specify the idempotency key during Scatter/Split Regions,
+// so that PD returns the result of the first request to its retries with the
+// same key instead of executing them again.
+func WithIdempotencyKey(key string) RegionsOption {
+	return func(op *RegionsOp) { op.idempotencyKey = key }
+}
+
+// ScatterFailure describes a region failed to be scattered, with the reason
+// and whether the failure is transient.
+type ScatterFailure = grpcutil.ScatterFailure
+
+// WithScatterFailures collects the regions failed to be scattered by
+// ScatterRegions into failures, so that the caller can retry the transient
+// failures and handle the structural ones differently.
+func WithScatterFailures(failures *[]*ScatterFailure) RegionsOption {
+	return func(op *RegionsOp) { op.scatterFailures = failures }
+}
+
+// WatchRegionsOp represents available options when watching regions.
+type WatchRegionsOp struct {
+	resume      bool
+	resumeIndex uint64
+}
+
+// WatchRegionsOption configures WatchRegionsOp.
+type WatchRegionsOption func(op *WatchRegionsOp)
+
+// WithResumeIndex resumes watching from the index passed to the handler of a
+// previous watch, so that no change is missed in between.
+func WithResumeIndex(index uint64) WatchRegionsOption {
+	return func(op *WatchRegionsOp) { op.resume, op.resumeIndex = true, index }
+}
+
+// ConfigRevisionOp represents available options when waiting for a config
+// revision.
+type ConfigRevisionOp struct {
+	allMembers bool
+}
+
+// ConfigRevisionOption configures ConfigRevisionOp.
+type ConfigRevisionOption func(op *ConfigRevisionOp)
+
+// WithAllMembers waits until all PD members have observed the revision.
+func WithAllMembers() ConfigRevisionOption {
+	return func(op *ConfigRevisionOp) { op.allMembers = true }
+}
+
 type tsoRequest struct {
 	start      time.Time
 	clientCtx  context.Context
@@ -156,9 +274,14 @@ type tsoRequest struct {
 }
 
 type tsoDispatcher struct {
+	// pending is the number of the requests which are dispatched but not
+	// finished yet, it must be accessed atomically.
+	pending          int64
 	dispatcherCtx    context.Context
 	dispatcherCancel context.CancelFunc
 	tsoRequestCh     chan *tsoRequest
+
+	pendingRequests prometheus.Gauge
 }
 
 type lastTSO struct {
@@ -200,8 +323,14 @@ type client struct {
 	lastTSMap sync.Map // Same as map[string]*lastTSO
 
 	checkTSDeadlineCh chan struct{}
+	standbyTSOStreams standbyTSOStreams
 
 	leaderNetworkFailure int32
+	// storeRevision is the latest revision of the store metadata the client
+	// has read, the followers older than it do not serve the client.
+	storeRevision int64
+	// offlineCache is nil if the offline mode is disabled.
+	offlineCache *offlineCache
 }
 
 // NewClient creates a PD client.
@@ -226,6 +355,11 @@ func NewClientWithContext(ctx context.Context, pdAddrs []string, security Securi
 	go c.tsLoop()
 	go c.tsCancelLoop()
 	go c.leaderCheckLoop()
+	if base.offlineCachePath != "" {
+		c.offlineCache = newOfflineCache(base.offlineCachePath, base.clusterID)
+		c.wg.Add(1)
+		go c.offlineCacheFlushLoop()
+	}
 
 	return c, nil
 }
@@ -239,12 +373,11 @@ func (c *client) updateTSODispatcher() {
 			c.createTSODispatcher(dcLocation)
 			dispatcher, _ := c.tsoDispatcher.Load(dcLocation)
 			dispatcherCtx := dispatcher.(*tsoDispatcher).dispatcherCtx
-			tsoRequestCh := dispatcher.(*tsoDispatcher).tsoRequestCh
 			// Each goroutine is responsible for handling the tso stream request for its dc-location.
 			// The only case that will make the dispatcher goroutine exit
 			// is that the loopCtx is done, otherwise there is no circumstance
 			// this goroutine should exit.
-			go c.handleDispatcher(dispatcherCtx, dcLocation, tsoRequestCh)
+			go c.handleDispatcher(dispatcherCtx, dcLocation, dispatcher.(*tsoDispatcher))
 		}
 		return true
 	})
@@ -402,6 +535,9 @@ func (c *client) tsLoop() {
 	defer ticker.Stop()
 	for {
 		c.updateTSODispatcher()
+		if c.enableWarmUp {
+			c.prepareStandbyTSOStream(loopCtx)
+		}
 		select {
 		case <-ticker.C:
 		case <-c.checkTSODispatcherCh:
@@ -485,6 +621,7 @@ func (c *client) createTSODispatcher(dcLocation string) {
 		dispatcherCtx:    dispatcherCtx,
 		dispatcherCancel: dispatcherCancel,
 		tsoRequestCh:     make(chan *tsoRequest, maxMergeTSORequests),
+		pendingRequests:  tsoPendingRequests.WithLabelValues(dcLocation),
 	}
 	c.tsoDispatcher.Store(dcLocation, dispatcher)
 }
@@ -494,8 +631,9 @@ type streamCh chan struct {
 	stream pdpb.PD_TsoClient
 }
 
-func (c *client) handleDispatcher(dispatcherCtx context.Context, dc string, tsoDispatcher chan *tsoRequest) {
+func (c *client) handleDispatcher(dispatcherCtx context.Context, dc string, dispatcher *tsoDispatcher) {
 	var (
+		tsoDispatcher = dispatcher.tsoRequestCh
 		err           error
 		cancel        context.CancelFunc
 		stream        pdpb.PD_TsoClient
@@ -540,7 +678,7 @@ func (c *client) handleDispatcher(dispatcherCtx context.Context, dc string, tsoD
 				}
 				log.Error("[pd] create tso stream error", zap.String("dc-location", dc), errs.ZapError(errs.ErrClientCreateTSOStream, err))
 				c.ScheduleCheckLeader()
-				c.revokeTSORequest(errors.WithStack(err), tsoDispatcher)
+				c.revokeTSORequest(errors.WithStack(err), dispatcher)
 				select {
 				case <-time.After(time.Second):
 				case <-dispatcherCtx.Done():
@@ -585,6 +723,7 @@ func (c *client) handleDispatcher(dispatcherCtx context.Context, dc string, tsoD
 			default:
 			}
 			err = c.processTSORequests(stream, dc, requests[:pendingPlus1], opts)
+			dispatcher.finish(int64(pendingPlus1))
 			close(done)
 		case <-dispatcherCtx.Done():
 			return
@@ -603,6 +742,15 @@ func (c *client) handleDispatcher(dispatcherCtx context.Context, dc string, tsoD
 			if IsLeaderChange(err) {
 				needUpdate = true
 			}
+			// Waits as the server recommends, such as when the caller
+			// component is rate limited.
+			if backoff := grpcutil.GetRetryBackoff(err, 0); backoff > 0 {
+				select {
+				case <-dispatcherCtx.Done():
+					return
+				case <-time.After(backoff):
+				}
+			}
 		}
 	}
 }
@@ -625,6 +773,14 @@ func (c *client) tryConnect(dispatcherCtx context.Context, dc string) (connectio
 	// retry several times before falling back to the follower when the network problem happens
 	for i := 0; i < maxRetryTimes; i++ {
 		cc, url = c.getAllocatorClientConnByDCLocation(dc)
+		if dc == globalDCLocation && c.enableWarmUp {
+			if stream, cancel := c.takeStandbyTSOStream(url); stream != nil {
+				log.Info("[pd] take over the standby tso stream", zap.String("url", url))
+				// Prepare the stream to the next leader.
+				c.scheduleCheckTSODispatcher()
+				return connectionContext{stream, cancel, nil, nil}, nil
+			}
+		}
 		cctx, cancel := context.WithCancel(dispatcherCtx)
 		stream, err = c.createTsoStream(cctx, cancel, pdpb.NewPDClient(cc))
 		failpoint.Inject("unreachableNetwork", func() {
@@ -650,7 +806,7 @@ func (c *client) tryConnect(dispatcherCtx context.Context, dc string) (connectio
 		select {
 		case <-dispatcherCtx.Done():
 			return connectionContext{}, err
-		case <-time.After(retryInterval):
+		case <-time.After(grpcutil.GetRetryBackoff(err, retryInterval)):
 		}
 	}
 
@@ -780,11 +936,13 @@ func (c *client) finishTSORequest(requests []*tsoRequest, physical, firstLogical
 	}
 }
 
-func (c *client) revokeTSORequest(err error, tsoDispatcher chan *tsoRequest) {
-	for i := 0; i < len(tsoDispatcher); i++ {
-		req := <-tsoDispatcher
+func (c *client) revokeTSORequest(err error, dispatcher *tsoDispatcher) {
+	count := len(dispatcher.tsoRequestCh)
+	for i := 0; i < count; i++ {
+		req := <-dispatcher.tsoRequestCh
 		req.done <- err
 	}
+	dispatcher.finish(int64(count))
 }
 
 func (c *client) Close() {
@@ -793,7 +951,7 @@ func (c *client) Close() {
 
 	c.tsoDispatcher.Range(func(_, dispatcher interface{}) bool {
 		if dispatcher != nil {
-			c.revokeTSORequest(errors.WithStack(errClosing), dispatcher.(*tsoDispatcher).tsoRequestCh)
+			c.revokeTSORequest(errors.WithStack(errClosing), dispatcher.(*tsoDispatcher))
 			dispatcher.(*tsoDispatcher).dispatcherCancel()
 		}
 		return true
@@ -876,6 +1034,12 @@ func (c *client) GetLocalTSAsync(ctx context.Context, dcLocation string) TSFutur
 	req.start = time.Now()
 	req.dcLocation = dcLocation
 	if err := c.dispatchRequest(dcLocation, req); err != nil {
+		// Reject the request at once if there are too many pending requests,
+		// waiting here only makes the burst worse.
+		if errs.ErrClientTSOQueueFull.Equal(err) {
+			req.done <- err
+			return req
+		}
 		// Wait for a while and try again
 		time.Sleep(50 * time.Millisecond)
 		if err = c.dispatchRequest(dcLocation, req); err != nil {
@@ -893,10 +1057,36 @@ func (c *client) dispatchRequest(dcLocation string, request *tsoRequest) error {
 		c.ScheduleCheckLeader()
 		return err
 	}
+	if err := c.checkPendingTSORequests(dcLocation, dispatcher.(*tsoDispatcher)); err != nil {
+		return err
+	}
 	dispatcher.(*tsoDispatcher).tsoRequestCh <- request
 	return nil
 }
 
+// checkPendingTSORequests counts a new pending request of the dispatcher, or
+// rejects it if the bounds of the pending requests are exceeded.
+func (c *client) checkPendingTSORequests(dcLocation string, dispatcher *tsoDispatcher) error {
+	pending := atomic.AddInt64(&dispatcher.pending, 1)
+	var reason string
+	if c.maxPendingTSORequests > 0 && pending > c.maxPendingTSORequests {
+		reason = fmt.Sprintf("%d requests of dc-location %s are pending", pending-1, dcLocation)
+		tsoRejectedByQueueLength.Inc()
+	}
+	if len(reason) > 0 {
+		dispatcher.finish(1)
+		return errs.ErrClientTSOQueueFull.FastGenByArgs(reason)
+	}
+	dispatcher.pendingRequests.Set(float64(pending))
+	return nil
+}
+
+// finish uncounts the finished pending requests.
+func (d *tsoDispatcher) finish(count int64) {
+	pending := atomic.AddInt64(&d.pending, -count)
+	d.pendingRequests.Set(float64(pending))
+}
+
 // TSFuture is a future which promises to return a TSO.
 type TSFuture interface {
 	// Wait gets the physical and logical time, it would block caller if data is not available yet.
@@ -974,9 +1164,17 @@ func (c *client) GetRegion(ctx context.Context, key []byte) (*Region, error) {
 	if err != nil {
 		cmdFailDurationGetRegion.Observe(time.Since(start).Seconds())
 		c.ScheduleCheckLeader()
+		if c.serveOffline(err) {
+			if region := c.offlineCache.getRegion(key); region != nil {
+				offlineServed.WithLabelValues("get_region").Inc()
+				return region, nil
+			}
+		}
 		return nil, errors.WithStack(err)
 	}
-	return handleRegionResponse(resp), nil
+	region := handleRegionResponse(resp)
+	c.cacheRegions(region)
+	return region, nil
 }
 
 func isNetworkError(code codes.Code) bool {
@@ -1041,9 +1239,17 @@ func (c *client) GetPrevRegion(ctx context.Context, key []byte) (*Region, error)
 	if err != nil {
 		cmdFailDurationGetPrevRegion.Observe(time.Since(start).Seconds())
 		c.ScheduleCheckLeader()
+		if c.serveOffline(err) {
+			if region := c.offlineCache.getPrevRegion(key); region != nil {
+				offlineServed.WithLabelValues("get_prev_region").Inc()
+				return region, nil
+			}
+		}
 		return nil, errors.WithStack(err)
 	}
-	return handleRegionResponse(resp), nil
+	region := handleRegionResponse(resp)
+	c.cacheRegions(region)
+	return region, nil
 }
 
 func (c *client) GetRegionByID(ctx context.Context, regionID uint64) (*Region, error) {
@@ -1066,9 +1272,17 @@ func (c *client) GetRegionByID(ctx context.Context, regionID uint64) (*Region, e
 	if err != nil {
 		cmdFailedDurationGetRegionByID.Observe(time.Since(start).Seconds())
 		c.ScheduleCheckLeader()
+		if c.serveOffline(err) {
+			if region := c.offlineCache.getRegionByID(regionID); region != nil {
+				offlineServed.WithLabelValues("get_region_byid").Inc()
+				return region, nil
+			}
+		}
 		return nil, errors.WithStack(err)
 	}
-	return handleRegionResponse(resp), nil
+	region := handleRegionResponse(resp)
+	c.cacheRegions(region)
+	return region, nil
 }
 
 func (c *client) ScanRegions(ctx context.Context, key, endKey []byte, limit int) ([]*Region, error) {
@@ -1092,17 +1306,166 @@ func (c *client) ScanRegions(ctx context.Context, key, endKey []byte, limit int)
 		Limit:    int32(limit),
 	}
 	scanCtx = grpcutil.BuildForwardContext(scanCtx, c.GetLeaderAddr())
-	resp, err := c.getClient().ScanRegions(scanCtx, req)
+	resp, err := c.getClient().ScanRegions(scanCtx, req, c.regionCallOptions()...)
 
 	if err != nil {
 		cmdFailedDurationScanRegions.Observe(time.Since(start).Seconds())
 		c.ScheduleCheckLeader()
+		if c.serveOffline(err) {
+			if regions := c.offlineCache.scanRegions(key, endKey, limit); len(regions) > 0 {
+				offlineServed.WithLabelValues("scan_regions").Inc()
+				return regions, nil
+			}
+		}
 		return nil, errors.WithStack(err)
 	}
 
+	regions := handleRegionsResponse(resp)
+	c.cacheRegions(regions...)
+	return regions, nil
+}
+
+func (c *client) ScanRegionsStream(ctx context.Context, key, endKey []byte, limit, chunkSize int, handler func([]*Region) error) error {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span = opentracing.StartSpan("pdclient.ScanRegionsStream", opentracing.ChildOf(span.Context()))
+		defer span.Finish()
+	}
+	start := time.Now()
+	defer func() { cmdDurationScanRegionsStream.Observe(time.Since(start).Seconds()) }()
+
+	cc, ok := c.clientConns.Load(c.GetLeaderAddr())
+	if !ok {
+		return errors.WithStack(errs.ErrClientGetLeader.FastGenByArgs(c.GetLeaderAddr()))
+	}
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if chunkSize > 0 {
+		streamCtx = grpcutil.BuildScanChunkSizeContext(streamCtx, chunkSize)
+	}
+	stream, err := cc.(*grpc.ClientConn).NewStream(streamCtx, grpcutil.ScanRegionsStreamDesc, grpcutil.ScanRegionsStreamMethod, c.regionCallOptions()...)
+	if err == nil {
+		err = stream.SendMsg(&pdpb.ScanRegionsRequest{
+			Header:   c.requestHeader(),
+			StartKey: key,
+			EndKey:   endKey,
+			Limit:    int32(limit),
+		})
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	for err == nil {
+		resp := &pdpb.ScanRegionsResponse{}
+		if err = stream.RecvMsg(resp); err != nil {
+			break
+		}
+		if pdErr := resp.GetHeader().GetError(); pdErr != nil {
+			err = errors.Errorf("[pd] scan regions stream failed: %s", pdErr.GetMessage())
+			break
+		}
+		if err = handler(handleRegionsResponse(resp)); err != nil {
+			return err
+		}
+	}
+	if err == io.EOF {
+		return nil
+	}
+	cmdFailedDurationScanRegionsStream.Observe(time.Since(start).Seconds())
+	c.ScheduleCheckLeader()
+	return errors.WithStack(err)
+}
+
+func (c *client) GetRegionsByStore(ctx context.Context, storeID uint64, key []byte, limit int) ([]*Region, error) {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span = opentracing.StartSpan("pdclient.GetRegionsByStore", opentracing.ChildOf(span.Context()))
+		defer span.Finish()
+	}
+	start := time.Now()
+	defer func() { cmdDurationGetRegionsByStore.Observe(time.Since(start).Seconds()) }()
+
+	cc, ok := c.clientConns.Load(c.GetLeaderAddr())
+	if !ok {
+		return nil, errors.WithStack(errs.ErrClientGetLeader.FastGenByArgs(c.GetLeaderAddr()))
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	ctx = grpcutil.BuildStoreIDContext(ctx, storeID)
+	req := &pdpb.ScanRegionsRequest{
+		Header:   c.requestHeader(),
+		StartKey: key,
+		Limit:    int32(limit),
+	}
+	resp := &pdpb.ScanRegionsResponse{}
+	var trailer metadata.MD
+	if err := cc.(*grpc.ClientConn).Invoke(ctx, grpcutil.GetRegionsByStoreMethod, req, resp, append(c.regionCallOptions(), grpc.Trailer(&trailer))...); err != nil {
+		cmdFailedDurationGetRegionsByStore.Observe(time.Since(start).Seconds())
+		c.ScheduleCheckLeader()
+		return nil, errors.WithStack(err)
+	}
+	if pdErr := resp.GetHeader().GetError(); pdErr != nil {
+		return nil, newHeaderError("[pd] get regions by store failed: "+pdErr.GetMessage(), trailer)
+	}
 	return handleRegionsResponse(resp), nil
 }
 
+func (c *client) WatchRegions(ctx context.Context, key, endKey []byte, handler func(regions []*Region, nextIndex uint64) error, opts ...WatchRegionsOption) error {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span = opentracing.StartSpan("pdclient.WatchRegions", opentracing.ChildOf(span.Context()))
+		defer span.Finish()
+	}
+
+	cc, ok := c.clientConns.Load(c.GetLeaderAddr())
+	if !ok {
+		return errors.WithStack(errs.ErrClientGetLeader.FastGenByArgs(c.GetLeaderAddr()))
+	}
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	options := &WatchRegionsOp{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.resume {
+		streamCtx = grpcutil.BuildWatchStartIndexContext(streamCtx, options.resumeIndex)
+	}
+	stream, err := cc.(*grpc.ClientConn).NewStream(streamCtx, grpcutil.WatchRegionsStreamDesc, grpcutil.WatchRegionsStreamMethod, c.regionCallOptions()...)
+	if err == nil {
+		err = stream.SendMsg(&pdpb.ScanRegionsRequest{
+			Header:   c.requestHeader(),
+			StartKey: key,
+			EndKey:   endKey,
+		})
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	for err == nil {
+		resp := &pdpb.SyncRegionResponse{}
+		if err = stream.RecvMsg(resp); err != nil {
+			break
+		}
+		if pdErr := resp.GetHeader().GetError(); pdErr != nil {
+			err = errors.Errorf("[pd] watch regions failed: %s", pdErr.GetMessage())
+			break
+		}
+		regions := make([]*Region, 0, len(resp.GetRegions()))
+		leaders := resp.GetRegionLeaders()
+		for i, meta := range resp.GetRegions() {
+			region := &Region{Meta: meta}
+			if i < len(leaders) && leaders[i].GetId() != 0 {
+				region.Leader = leaders[i]
+			}
+			regions = append(regions, region)
+		}
+		if err = handler(regions, resp.GetStartIndex()); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() == nil {
+		c.ScheduleCheckLeader()
+	}
+	return errors.WithStack(err)
+}
+
 func handleRegionsResponse(resp *pdpb.ScanRegionsResponse) []*Region {
 	var regions []*Region
 	if len(resp.GetRegions()) == 0 {
@@ -1132,6 +1495,15 @@ func handleRegionsResponse(resp *pdpb.ScanRegionsResponse) []*Region {
 }
 
 func (c *client) GetStore(ctx context.Context, storeID uint64) (*metapb.Store, error) {
+	return c.GetStoreWithOptions(ctx, storeID)
+}
+
+func (c *client) GetStoreWithOptions(ctx context.Context, storeID uint64, opts ...GetStoreOption) (*metapb.Store, error) {
+	options := &GetStoreOp{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	if span := opentracing.SpanFromContext(ctx); span != nil {
 		span = opentracing.StartSpan("pdclient.GetStore", opentracing.ChildOf(span.Context()))
 		defer span.Finish()
@@ -1139,20 +1511,39 @@ func (c *client) GetStore(ctx context.Context, storeID uint64) (*metapb.Store, e
 	start := time.Now()
 	defer func() { cmdDurationGetStore.Observe(time.Since(start).Seconds()) }()
 
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	req := &pdpb.GetStoreRequest{
 		Header:  c.requestHeader(),
 		StoreId: storeID,
 	}
-	ctx = grpcutil.BuildForwardContext(ctx, c.GetLeaderAddr())
-	resp, err := c.getClient().GetStore(ctx, req)
+	var resp *pdpb.GetStoreResponse
+	if c.readStoresFromFollower(ctx, func(ctx context.Context, cli pdpb.PDClient, opts ...grpc.CallOption) (err error) {
+		resp, err = cli.GetStore(ctx, req, opts...)
+		return err
+	}) {
+		c.cacheStores([]*metapb.Store{resp.GetStore()}, false)
+		return handleStoreResponse(resp)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx = c.buildStoreReadContext(grpcutil.BuildForwardContext(ctx, c.GetLeaderAddr()))
+	var header metadata.MD
+	resp, err := c.getClient().GetStore(ctx, req, grpc.Header(&header))
 	cancel()
+	c.updateStoreRevision(header)
 
 	if err != nil {
 		cmdFailedDurationGetStore.Observe(time.Since(start).Seconds())
 		c.ScheduleCheckLeader()
+		if c.serveOffline(err) {
+			if store, ok := c.offlineCache.getStore(storeID); ok {
+				offlineServed.WithLabelValues("get_store").Inc()
+				setStale(options.stale)
+				return handleStoreResponse(&pdpb.GetStoreResponse{Store: store})
+			}
+		}
 		return nil, errors.WithStack(err)
 	}
+	c.cacheStores([]*metapb.Store{resp.GetStore()}, false)
 	return handleStoreResponse(resp)
 }
 
@@ -1181,23 +1572,104 @@ func (c *client) GetAllStores(ctx context.Context, opts ...GetStoreOption) ([]*m
 	start := time.Now()
 	defer func() { cmdDurationGetAllStores.Observe(time.Since(start).Seconds()) }()
 
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	req := &pdpb.GetAllStoresRequest{
 		Header:                 c.requestHeader(),
 		ExcludeTombstoneStores: options.excludeTombstone,
 	}
-	ctx = grpcutil.BuildForwardContext(ctx, c.GetLeaderAddr())
-	resp, err := c.getClient().GetAllStores(ctx, req)
+	withLabels := func(ctx context.Context) context.Context {
+		for _, label := range options.labels {
+			ctx = grpcutil.BuildStoreLabelContext(ctx, label.GetKey(), label.GetValue())
+		}
+		return ctx
+	}
+	var resp *pdpb.GetAllStoresResponse
+	// Only the leader can filter the stores by the keyspace.
+	if options.keyspaceID == nil && c.readStoresFromFollower(ctx, func(ctx context.Context, cli pdpb.PDClient, opts ...grpc.CallOption) (err error) {
+		resp, err = cli.GetAllStores(withLabels(ctx), req, opts...)
+		return err
+	}) {
+		c.cacheStores(resp.GetStores(), isAllStores(options))
+		return resp.GetStores(), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx = c.buildStoreReadContext(grpcutil.BuildForwardContext(ctx, c.GetLeaderAddr()))
+	if options.keyspaceID != nil {
+		ctx = grpcutil.BuildStoreKeyspaceContext(ctx, *options.keyspaceID)
+	}
+	ctx = withLabels(ctx)
+	var header metadata.MD
+	resp, err := c.getClient().GetAllStores(ctx, req, grpc.Header(&header))
 	cancel()
+	c.updateStoreRevision(header)
 
 	if err != nil {
 		cmdFailedDurationGetAllStores.Observe(time.Since(start).Seconds())
 		c.ScheduleCheckLeader()
+		// The offline cache cannot filter the stores by the keyspace.
+		if options.keyspaceID == nil && c.serveOffline(err) {
+			if stores := c.offlineCache.getAllStores(options); len(stores) > 0 {
+				offlineServed.WithLabelValues("get_all_stores").Inc()
+				setStale(options.stale)
+				return stores, nil
+			}
+		}
 		return nil, errors.WithStack(err)
 	}
+	if options.keyspaceID == nil {
+		c.cacheStores(resp.GetStores(), isAllStores(options))
+	}
 	return resp.GetStores(), nil
 }
 
+// readStoresFromFollower calls the store metadata method on a follower if the
+// follower read is enabled. It returns false if the follower cannot serve it,
+// and the caller falls back to the leader then.
+func (c *client) readStoresFromFollower(ctx context.Context, call func(context.Context, pdpb.PDClient, ...grpc.CallOption) error) bool {
+	if !c.enableStoreFollowerRead {
+		return false
+	}
+	addrs := c.GetFollowerAddr()
+	if len(addrs) == 0 {
+		return false
+	}
+	addr := addrs[rand.Intn(len(addrs))]
+	cc, err := c.getOrCreateGRPCConn(addr)
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	ctx = c.buildStoreReadContext(ctx)
+	var header metadata.MD
+	if err := call(ctx, pdpb.NewPDClient(cc), grpc.Header(&header)); err != nil {
+		log.Debug("[pd] failed to read the stores from the follower, fall back to the leader",
+			zap.String("addr", addr), errs.ZapError(err))
+		return false
+	}
+	c.updateStoreRevision(header)
+	return true
+}
+
+// buildStoreReadContext makes the PD members return the revision of the store
+// metadata if the follower read is enabled.
+func (c *client) buildStoreReadContext(ctx context.Context) context.Context {
+	if !c.enableStoreFollowerRead {
+		return ctx
+	}
+	return grpcutil.BuildStoreFollowerReadContext(ctx, atomic.LoadInt64(&c.storeRevision))
+}
+
+func (c *client) updateStoreRevision(header metadata.MD) {
+	revision := grpcutil.GetStoreRevision(header)
+	for {
+		old := atomic.LoadInt64(&c.storeRevision)
+		if revision <= old || atomic.CompareAndSwapInt64(&c.storeRevision, old, revision) {
+			return
+		}
+	}
+}
+
 func (c *client) UpdateGCSafePoint(ctx context.Context, safePoint uint64) (uint64, error) {
 	if span := opentracing.SpanFromContext(ctx); span != nil {
 		span = opentracing.StartSpan("pdclient.UpdateGCSafePoint", opentracing.ChildOf(span.Context()))
@@ -1311,6 +1783,69 @@ func (c *client) GetOperator(ctx context.Context, regionID uint64) (*pdpb.GetOpe
 	return c.getClient().GetOperator(ctx, req)
 }
 
+// configRevisionCheckInterval is the interval to check whether the members
+// have observed the revision.
+const configRevisionCheckInterval = 100 * time.Millisecond
+
+func (c *client) WaitForConfigRevision(ctx context.Context, revision int64, opts ...ConfigRevisionOption) error {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span = opentracing.StartSpan("pdclient.WaitForConfigRevision", opentracing.ChildOf(span.Context()))
+		defer span.Finish()
+	}
+	options := &ConfigRevisionOp{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	ticker := time.NewTicker(configRevisionCheckInterval)
+	defer ticker.Stop()
+	for {
+		observed, err := c.checkConfigRevision(ctx, revision, options.allMembers)
+		if err != nil {
+			log.Debug("[pd] failed to check config revision", zap.Int64("revision", revision), errs.ZapError(err))
+		}
+		if observed {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkConfigRevision checks whether the members have observed the revision.
+// It reads the revision from the etcd server embedded in each member with a
+// serializable read, which is served by the member locally, so the revision
+// in the response header is the one the member itself has applied.
+func (c *client) checkConfigRevision(ctx context.Context, revision int64, allMembers bool) (bool, error) {
+	addrs := []string{c.GetLeaderAddr()}
+	if allMembers {
+		addrs = c.GetURLs()
+	}
+	for _, addr := range addrs {
+		cc, err := c.getOrCreateGRPCConn(addr)
+		if err != nil {
+			return false, err
+		}
+		rangeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		resp, err := etcdserverpb.NewKVClient(cc).Range(rangeCtx, &etcdserverpb.RangeRequest{
+			Key:          []byte("/pd"),
+			Serializable: true,
+			CountOnly:    true,
+		})
+		cancel()
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		if resp.GetHeader().GetRevision() < revision {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 // SplitRegions split regions by given split keys
 func (c *client) SplitRegions(ctx context.Context, splitKeys [][]byte, opts ...RegionsOption) (*pdpb.SplitRegionsResponse, error) {
 	if span := opentracing.SpanFromContext(ctx); span != nil {
@@ -1331,9 +1866,47 @@ func (c *client) SplitRegions(ctx context.Context, splitKeys [][]byte, opts ...R
 		RetryLimit: options.retryLimit,
 	}
 	ctx = grpcutil.BuildForwardContext(ctx, c.GetLeaderAddr())
+	if options.idempotencyKey != "" {
+		ctx = grpcutil.BuildIdempotencyKeyContext(ctx, options.idempotencyKey)
+	}
 	return c.getClient().SplitRegions(ctx, req)
 }
 
+func (c *client) AllocIDRange(ctx context.Context, count uint64, ttl time.Duration) (*IDRange, error) {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span = opentracing.StartSpan("pdclient.AllocIDRange", opentracing.ChildOf(span.Context()))
+		defer span.Finish()
+	}
+	start := time.Now()
+	defer func() { cmdDurationAllocIDRange.Observe(time.Since(start).Seconds()) }()
+
+	cc, ok := c.clientConns.Load(c.GetLeaderAddr())
+	if !ok {
+		return nil, errors.WithStack(errs.ErrClientGetLeader.FastGenByArgs(c.GetLeaderAddr()))
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	ctx = grpcutil.BuildIDRangeContext(ctx, count, ttl)
+	resp := &pdpb.AllocIDResponse{}
+	var trailer metadata.MD
+	err := cc.(*grpc.ClientConn).Invoke(ctx, grpcutil.AllocIDRangeMethod, &pdpb.AllocIDRequest{Header: c.requestHeader()}, resp, grpc.Trailer(&trailer))
+	if err != nil {
+		cmdFailedDurationAllocIDRange.Observe(time.Since(start).Seconds())
+		c.ScheduleCheckLeader()
+		return nil, errors.WithStack(err)
+	}
+	if pdErr := resp.GetHeader().GetError(); pdErr != nil {
+		return nil, newHeaderError("[pd] alloc id range failed: "+pdErr.GetMessage(), trailer)
+	}
+	// The lease is counted from the time the request is sent, which is no
+	// later than the server counts it.
+	return &IDRange{
+		Start:    resp.GetId(),
+		End:      resp.GetId() + count,
+		Deadline: start.Add(ttl),
+	}, nil
+}
+
 func (c *client) requestHeader() *pdpb.RequestHeader {
 	return &pdpb.RequestHeader{
 		ClusterId: c.clusterID,
@@ -1356,14 +1929,25 @@ func (c *client) scatterRegionsWithOptions(ctx context.Context, regionsID []uint
 	}
 
 	ctx = grpcutil.BuildForwardContext(ctx, c.GetLeaderAddr())
-	resp, err := c.getClient().ScatterRegion(ctx, req)
+	if options.idempotencyKey != "" {
+		ctx = grpcutil.BuildIdempotencyKeyContext(ctx, options.idempotencyKey)
+	}
+	var trailer metadata.MD
+	resp, err := c.getClient().ScatterRegion(ctx, req, grpc.Trailer(&trailer))
 	cancel()
 
 	if err != nil {
 		return nil, err
 	}
 	if resp.Header.GetError() != nil {
-		return nil, errors.Errorf("scatter regions %v failed: %s", regionsID, resp.Header.GetError().String())
+		return nil, newHeaderError(fmt.Sprintf("scatter regions %v failed: %s", regionsID, resp.Header.GetError().String()), trailer)
+	}
+	if options.scatterFailures != nil {
+		failures, err := grpcutil.GetScatterFailures(trailer)
+		if err != nil {
+			return nil, err
+		}
+		*options.scatterFailures = failures
 	}
 	return resp, nil
 }
@@ -1381,8 +1965,21 @@ func addrsToUrls(addrs []string) []string {
 	return urls
 }
 
+// newHeaderError returns the error reported in the response header. It carries
+// the retry hint in the trailer if the error is retryable, so that
+// grpcutil.GetRetryHint works for it like for the gRPC errors.
+func newHeaderError(msg string, trailer metadata.MD) error {
+	if hint, ok := grpcutil.GetRetryHintFromTrailer(trailer); ok {
+		return errors.WithStack(grpcutil.NewRetryableError(codes.Unavailable, msg, hint))
+	}
+	return errors.New(msg)
+}
+
 // IsLeaderChange will determine whether there is a leader change.
 func IsLeaderChange(err error) bool {
+	if hint, ok := grpcutil.GetRetryHint(err); ok && hint.Leader != "" {
+		return true
+	}
 	errMsg := err.Error()
 	return strings.Contains(errMsg, errs.NotLeaderErr) || strings.Contains(errMsg, errs.MismatchLeaderErr)
 }
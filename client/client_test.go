@@ -15,12 +15,16 @@ package pd
 
 import (
 	"context"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/testutil"
 	"go.uber.org/goleak"
 	"google.golang.org/grpc"
@@ -68,6 +72,41 @@ func (s *testClientSuite) TestUpdateURLs(c *C) {
 	c.Assert(cli.urls, DeepEquals, getURLs([]*pdpb.Member{members[1], members[3], members[2], members[0]}))
 }
 
+func (s *testClientSuite) TestWarmUp(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	members := []*pdpb.Member{
+		{Name: "pd1", MemberId: 1, ClientUrls: []string{"tmp://pd1"}},
+		{Name: "pd2", MemberId: 2, ClientUrls: []string{"tmp://pd2"}},
+		{Name: "pd3", MemberId: 3, ClientUrls: []string{"tmp://pd3"}},
+		{Name: "pd4", MemberId: 4},
+	}
+	cli := &baseClient{ctx: ctx}
+	defer cli.clientConns.Range(func(_, cc interface{}) bool {
+		cc.(*grpc.ClientConn).Close()
+		return true
+	})
+
+	// The name breaks the tie of the leader priorities.
+	cli.warmUp(members, members[0])
+	c.Assert(cli.GetNextLeaderAddr(), Equals, "tmp://pd2")
+	for _, url := range []string{"tmp://pd2", "tmp://pd3"} {
+		_, ok := cli.clientConns.Load(url)
+		c.Assert(ok, IsTrue)
+	}
+	_, ok := cli.clientConns.Load("tmp://pd1")
+	c.Assert(ok, IsFalse)
+
+	members[2].LeaderPriority = 1
+	cli.warmUp(members, members[0])
+	c.Assert(cli.GetNextLeaderAddr(), Equals, "tmp://pd3")
+	cli.warmUp(members, members[2])
+	c.Assert(cli.GetNextLeaderAddr(), Equals, "tmp://pd1")
+	// No member can be the next leader.
+	cli.warmUp(members[1:2], members[1])
+	c.Assert(cli.GetNextLeaderAddr(), Equals, "")
+}
+
 const testClientURL = "tmp://test.url:5255"
 
 var _ = Suite(&testClientCtxSuite{})
@@ -90,6 +129,13 @@ func (s *testClientCtxSuite) TestClientWithRetry(c *C) {
 	c.Assert(time.Since(start), Less, time.Second*10)
 }
 
+func (s *testClientCtxSuite) TestClientWithCompression(c *C) {
+	start := time.Now()
+	_, err := NewClientWithContext(context.TODO(), []string{testClientURL}, SecurityOption{}, WithGRPCCompression("zstd"))
+	c.Assert(errs.ErrClientGRPCCompressor.Equal(errors.Cause(err)), IsTrue)
+	c.Assert(time.Since(start), Less, time.Second)
+}
+
 var _ = Suite(&testClientDialOptionSuite{})
 
 type testClientDialOptionSuite struct{}
@@ -143,3 +189,87 @@ func (s *testTsoRequestSuite) TestTsoRequestWait(c *C) {
 	_, _, err = req.Wait()
 	c.Assert(errors.Cause(err), Equals, context.Canceled)
 }
+
+func (s *testClientSuite) TestPendingTSORequestsBound(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cli := &client{baseClient: &baseClient{ctx: ctx, maxPendingTSORequests: 2}}
+	cli.createTSODispatcher(globalDCLocation)
+	dispatcher, _ := cli.tsoDispatcher.Load(globalDCLocation)
+
+	// Nobody handles the requests, so they keep pending.
+	c.Assert(cli.GetTSAsync(ctx).(*tsoRequest).done, HasLen, 0)
+	c.Assert(cli.GetTSAsync(ctx).(*tsoRequest).done, HasLen, 0)
+	_, _, err := cli.GetTSAsync(ctx).Wait()
+	c.Assert(errs.ErrClientTSOQueueFull.Equal(errors.Cause(err)), IsTrue)
+	c.Assert(atomic.LoadInt64(&dispatcher.(*tsoDispatcher).pending), Equals, int64(2))
+
+	cli.revokeTSORequest(errClosing, dispatcher.(*tsoDispatcher))
+	c.Assert(atomic.LoadInt64(&dispatcher.(*tsoDispatcher).pending), Equals, int64(0))
+
+	// The bound cannot exceed the capacity of the request channel.
+	_, err = NewClientWithContext(ctx, []string{"127.0.0.1:0"}, SecurityOption{}, WithMaxPendingTSORequests(maxMergeTSORequests+1))
+	c.Assert(errs.ErrClientMaxPendingTSORequests.Equal(errors.Cause(err)), IsTrue)
+}
+
+func (s *testClientSuite) TestOfflineCache(c *C) {
+	path := filepath.Join(c.MkDir(), "offline-cache")
+	newRegion := func(id uint64, start, end string) *Region {
+		return &Region{
+			Meta:   &metapb.Region{Id: id, StartKey: []byte(start), EndKey: []byte(end)},
+			Leader: &metapb.Peer{Id: id*10 + 1, StoreId: 1},
+		}
+	}
+	cache := newOfflineCache(path, 1)
+	cache.putRegions(newRegion(1, "", "b"), newRegion(2, "b", "d"), newRegion(3, "d", ""))
+	// The split regions replace the overlapping one.
+	cache.putRegions(newRegion(4, "b", "c"), newRegion(5, "c", "d"))
+	cache.putStores([]*metapb.Store{
+		{Id: 1, Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z1"}}},
+		{Id: 2, State: metapb.StoreState_Tombstone},
+	}, true)
+
+	check := func(cache *offlineCache) {
+		c.Assert(cache.getRegionByID(2), IsNil)
+		region := cache.getRegion([]byte("c"))
+		c.Assert(region.Meta.GetId(), Equals, uint64(5))
+		c.Assert(region.Stale, IsTrue)
+		c.Assert(cache.getPrevRegion([]byte("c")).Meta.GetId(), Equals, uint64(4))
+		c.Assert(cache.getPrevRegion([]byte("a")), IsNil)
+		c.Assert(cache.getRegionByID(3).Meta.GetStartKey(), DeepEquals, []byte("d"))
+
+		var ids []uint64
+		for _, r := range cache.scanRegions([]byte("a"), []byte("d"), 0) {
+			ids = append(ids, r.Meta.GetId())
+		}
+		c.Assert(ids, DeepEquals, []uint64{1, 4, 5})
+		c.Assert(cache.scanRegions([]byte("bb"), nil, 2), HasLen, 2)
+
+		_, ok := cache.getStore(2)
+		c.Assert(ok, IsTrue)
+		c.Assert(cache.getAllStores(&GetStoreOp{}), HasLen, 2)
+		c.Assert(cache.getAllStores(&GetStoreOp{excludeTombstone: true}), HasLen, 1)
+		c.Assert(cache.getAllStores(&GetStoreOp{labels: []*metapb.StoreLabel{{Key: "Zone", Value: "z1"}}}), HasLen, 1)
+		c.Assert(cache.getAllStores(&GetStoreOp{labels: []*metapb.StoreLabel{{Key: "zone", Value: "z2"}}}), HasLen, 0)
+	}
+	check(cache)
+
+	// The cache is loaded from the disk, unless it is of another cluster.
+	c.Assert(cache.flush(), IsNil)
+	check(newOfflineCache(path, 1))
+	cache = newOfflineCache(path, 2)
+	c.Assert(cache.getRegionByID(1), IsNil)
+	_, ok := cache.getStore(1)
+	c.Assert(ok, IsFalse)
+
+	// The least recently updated regions are evicted beyond the limit.
+	cache.maxRegions = 2
+	cache.putRegions(newRegion(1, "", "b"), newRegion(2, "b", "d"))
+	cache.putRegions(newRegion(1, "", "b"))
+	cache.putRegions(newRegion(3, "d", ""))
+	c.Assert(cache.getRegionByID(2), IsNil)
+	c.Assert(cache.getRegionByID(1), NotNil)
+	c.Assert(cache.getRegionByID(3), NotNil)
+	c.Assert(cache.tree.Len(), Equals, 2)
+	c.Assert(cache.lru.Len(), Equals, 2)
+}
@@ -51,6 +51,20 @@ var (
 			Help:      "Bucketed histogram of the batch size of handled requests.",
 			Buckets:   prometheus.ExponentialBuckets(1, 2, 13),
 		})
+	tsoPendingRequests = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd_client",
+			Subsystem: "request",
+			Name:      "tso_pending_requests",
+			Help:      "The number of the pending TSO requests.",
+		}, []string{"dc"})
+	tsoRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd_client",
+			Subsystem: "request",
+			Name:      "tso_rejected_requests_total",
+			Help:      "Counter of the TSO requests rejected because of too many pending requests.",
+		}, []string{"reason"})
 	requestForwarded = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd_client",
@@ -58,6 +72,13 @@ var (
 			Name:      "forwarded_status",
 			Help:      "The status to indicate if the request is forwarded",
 		}, []string{"host", "delegate"})
+	offlineServed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd_client",
+			Subsystem: "request",
+			Name:      "offline_served_total",
+			Help:      "Counter of the requests served from the offline cache.",
+		}, []string{"type"})
 )
 
 var (
@@ -70,6 +91,8 @@ var (
 	cmdDurationGetPrevRegion            = cmdDuration.WithLabelValues("get_prev_region")
 	cmdDurationGetRegionByID            = cmdDuration.WithLabelValues("get_region_byid")
 	cmdDurationScanRegions              = cmdDuration.WithLabelValues("scan_regions")
+	cmdDurationScanRegionsStream        = cmdDuration.WithLabelValues("scan_regions_stream")
+	cmdDurationGetRegionsByStore        = cmdDuration.WithLabelValues("get_regions_by_store")
 	cmdDurationGetStore                 = cmdDuration.WithLabelValues("get_store")
 	cmdDurationGetAllStores             = cmdDuration.WithLabelValues("get_all_stores")
 	cmdDurationUpdateGCSafePoint        = cmdDuration.WithLabelValues("update_gc_safe_point")
@@ -78,6 +101,7 @@ var (
 	cmdDurationScatterRegions           = cmdDuration.WithLabelValues("scatter_regions")
 	cmdDurationGetOperator              = cmdDuration.WithLabelValues("get_operator")
 	cmdDurationSplitRegions             = cmdDuration.WithLabelValues("split_regions")
+	cmdDurationAllocIDRange             = cmdDuration.WithLabelValues("alloc_id_range")
 
 	cmdFailDurationGetRegion                  = cmdFailedDuration.WithLabelValues("get_region")
 	cmdFailDurationTSO                        = cmdFailedDuration.WithLabelValues("tso")
@@ -85,11 +109,16 @@ var (
 	cmdFailDurationGetPrevRegion              = cmdFailedDuration.WithLabelValues("get_prev_region")
 	cmdFailedDurationGetRegionByID            = cmdFailedDuration.WithLabelValues("get_region_byid")
 	cmdFailedDurationScanRegions              = cmdFailedDuration.WithLabelValues("scan_regions")
+	cmdFailedDurationScanRegionsStream        = cmdFailedDuration.WithLabelValues("scan_regions_stream")
+	cmdFailedDurationGetRegionsByStore        = cmdFailedDuration.WithLabelValues("get_regions_by_store")
 	cmdFailedDurationGetStore                 = cmdFailedDuration.WithLabelValues("get_store")
 	cmdFailedDurationGetAllStores             = cmdFailedDuration.WithLabelValues("get_all_stores")
 	cmdFailedDurationUpdateGCSafePoint        = cmdFailedDuration.WithLabelValues("update_gc_safe_point")
 	cmdFailedDurationUpdateServiceGCSafePoint = cmdFailedDuration.WithLabelValues("update_service_gc_safe_point")
+	cmdFailedDurationAllocIDRange             = cmdFailedDuration.WithLabelValues("alloc_id_range")
 	requestDurationTSO                        = requestDuration.WithLabelValues("tso")
+
+	tsoRejectedByQueueLength = tsoRejected.WithLabelValues("queue-length")
 )
 
 func init() {
@@ -97,5 +126,8 @@ func init() {
 	prometheus.MustRegister(cmdFailedDuration)
 	prometheus.MustRegister(requestDuration)
 	prometheus.MustRegister(tsoBatchSize)
+	prometheus.MustRegister(tsoPendingRequests)
+	prometheus.MustRegister(tsoRejected)
 	prometheus.MustRegister(requestForwarded)
+	prometheus.MustRegister(offlineServed)
 }
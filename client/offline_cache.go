@@ -0,0 +1,410 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pd
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/btree"
+	"github.com/tikv/pd/pkg/errs"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	offlineCacheFlushInterval = 10 * time.Second
+	offlineCacheBTreeDegree   = 64
+	// offlineCacheMaxRegions is the max number of the regions in the offline
+	// cache. The least recently updated regions are evicted beyond it.
+	offlineCacheMaxRegions = 100000
+)
+
+// offlineCacheFile is the content of the offline cache on the disk.
+type offlineCacheFile struct {
+	ClusterID uint64           `json:"cluster_id"`
+	Regions   []*offlineRegion `json:"regions"`
+	Stores    []*metapb.Store  `json:"stores"`
+}
+
+type offlineRegion struct {
+	Meta   *metapb.Region `json:"meta"`
+	Leader *metapb.Peer   `json:"leader,omitempty"`
+	// elem is the element of the region in the eviction list.
+	elem *list.Element
+}
+
+// Less returns true if the start key of the region is less than the other's.
+func (r *offlineRegion) Less(other btree.Item) bool {
+	return bytes.Compare(r.Meta.GetStartKey(), other.(*offlineRegion).Meta.GetStartKey()) < 0
+}
+
+func (r *offlineRegion) contains(key []byte) bool {
+	end := r.Meta.GetEndKey()
+	return bytes.Compare(key, r.Meta.GetStartKey()) >= 0 && (len(end) == 0 || bytes.Compare(key, end) < 0)
+}
+
+func (r *offlineRegion) toRegion() *Region {
+	return &Region{Meta: r.Meta, Leader: r.Leader, Stale: true}
+}
+
+func keyItem(key []byte) *offlineRegion {
+	return &offlineRegion{Meta: &metapb.Region{StartKey: key}}
+}
+
+// offlineCache keeps the last known metadata of the regions and stores, and
+// saves it to the local disk periodically, so that the client can serve the
+// routing lookups from it when every PD member is unreachable. It keeps at
+// most maxRegions regions.
+type offlineCache struct {
+	path       string
+	clusterID  uint64
+	maxRegions int
+
+	mu      sync.RWMutex
+	tree    *btree.BTree
+	regions map[uint64]*offlineRegion
+	// lru orders the regions from the most recently updated one.
+	lru    *list.List
+	stores map[uint64]*metapb.Store
+	dirty  bool
+}
+
+// newOfflineCache creates the cache and loads the metadata saved in the path.
+// The saved metadata of another cluster is ignored.
+func newOfflineCache(path string, clusterID uint64) *offlineCache {
+	c := &offlineCache{
+		path:       path,
+		clusterID:  clusterID,
+		maxRegions: offlineCacheMaxRegions,
+		tree:       btree.New(offlineCacheBTreeDegree),
+		regions:    make(map[uint64]*offlineRegion),
+		lru:        list.New(),
+		stores:     make(map[uint64]*metapb.Store),
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("[pd] failed to load the offline cache", zap.String("path", path), errs.ZapError(err))
+		}
+		return c
+	}
+	var file offlineCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Warn("[pd] failed to parse the offline cache", zap.String("path", path), errs.ZapError(err))
+		return c
+	}
+	if file.ClusterID != clusterID {
+		log.Warn("[pd] ignore the offline cache of another cluster", zap.String("path", path), zap.Uint64("cluster-id", file.ClusterID))
+		return c
+	}
+	for _, r := range file.Regions {
+		if r.Meta != nil {
+			c.putRegionLocked(r)
+		}
+	}
+	for _, s := range file.Stores {
+		c.stores[s.GetId()] = s
+	}
+	log.Info("[pd] load the offline cache", zap.String("path", path), zap.Int("regions", len(c.regions)), zap.Int("stores", len(c.stores)))
+	return c
+}
+
+func (c *offlineCache) putRegions(regions ...*Region) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range regions {
+		if r == nil || r.Meta == nil || r.Stale {
+			continue
+		}
+		c.putRegionLocked(&offlineRegion{Meta: r.Meta, Leader: r.Leader})
+	}
+	c.dirty = true
+}
+
+// putRegionLocked replaces the regions overlapping with the new one, and
+// evicts the least recently updated regions beyond the limit.
+func (c *offlineCache) putRegionLocked(r *offlineRegion) {
+	if old, ok := c.regions[r.Meta.GetId()]; ok {
+		c.removeRegionLocked(old)
+	}
+	var overlaps []*offlineRegion
+	c.tree.DescendLessOrEqual(r, func(i btree.Item) bool {
+		if prev := i.(*offlineRegion); prev.contains(r.Meta.GetStartKey()) {
+			overlaps = append(overlaps, prev)
+		}
+		return false
+	})
+	end := r.Meta.GetEndKey()
+	c.tree.AscendGreaterOrEqual(r, func(i btree.Item) bool {
+		next := i.(*offlineRegion)
+		if len(end) > 0 && bytes.Compare(next.Meta.GetStartKey(), end) >= 0 {
+			return false
+		}
+		overlaps = append(overlaps, next)
+		return true
+	})
+	for _, o := range overlaps {
+		c.removeRegionLocked(o)
+	}
+	c.tree.ReplaceOrInsert(r)
+	c.regions[r.Meta.GetId()] = r
+	r.elem = c.lru.PushFront(r)
+	for len(c.regions) > c.maxRegions {
+		c.removeRegionLocked(c.lru.Back().Value.(*offlineRegion))
+	}
+}
+
+func (c *offlineCache) removeRegionLocked(r *offlineRegion) {
+	c.tree.Delete(r)
+	delete(c.regions, r.Meta.GetId())
+	c.lru.Remove(r.elem)
+}
+
+// putStores saves the stores. If all is true, the stores are all the stores
+// of the cluster, and the others are removed.
+func (c *offlineCache) putStores(stores []*metapb.Store, all bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if all {
+		c.stores = make(map[uint64]*metapb.Store, len(stores))
+	}
+	for _, s := range stores {
+		if s != nil {
+			c.stores[s.GetId()] = s
+		}
+	}
+	c.dirty = true
+}
+
+func (c *offlineCache) getRegion(key []byte) *Region {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if r := c.searchLocked(key); r != nil {
+		return r.toRegion()
+	}
+	return nil
+}
+
+func (c *offlineCache) getPrevRegion(key []byte) *Region {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	r := c.searchLocked(key)
+	if r == nil {
+		return nil
+	}
+	var prev *Region
+	c.tree.DescendLessOrEqual(r, func(i btree.Item) bool {
+		if item := i.(*offlineRegion); item != r {
+			if bytes.Equal(item.Meta.GetEndKey(), r.Meta.GetStartKey()) {
+				prev = item.toRegion()
+			}
+			return false
+		}
+		return true
+	})
+	return prev
+}
+
+func (c *offlineCache) getRegionByID(id uint64) *Region {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if r, ok := c.regions[id]; ok {
+		return r.toRegion()
+	}
+	return nil
+}
+
+// scanRegions returns the cached regions in the range, starting from the one
+// containing the key. The scan stops at the first gap of the cache, so that
+// the result never misses a region in the middle.
+func (c *offlineCache) scanRegions(key, endKey []byte, limit int) []*Region {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	start := c.searchLocked(key)
+	if start == nil {
+		return nil
+	}
+	var regions []*Region
+	next := start.Meta.GetStartKey()
+	c.tree.AscendGreaterOrEqual(start, func(i btree.Item) bool {
+		r := i.(*offlineRegion)
+		if !bytes.Equal(r.Meta.GetStartKey(), next) || (len(endKey) > 0 && bytes.Compare(next, endKey) >= 0) {
+			return false
+		}
+		regions = append(regions, r.toRegion())
+		next = r.Meta.GetEndKey()
+		return len(next) > 0 && (limit <= 0 || len(regions) < limit)
+	})
+	return regions
+}
+
+func (c *offlineCache) searchLocked(key []byte) *offlineRegion {
+	var found *offlineRegion
+	c.tree.DescendLessOrEqual(keyItem(key), func(i btree.Item) bool {
+		if r := i.(*offlineRegion); r.contains(key) {
+			found = r
+		}
+		return false
+	})
+	return found
+}
+
+func (c *offlineCache) getStore(id uint64) (*metapb.Store, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.stores[id]
+	return s, ok
+}
+
+func (c *offlineCache) getAllStores(options *GetStoreOp) []*metapb.Store {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stores := make([]*metapb.Store, 0, len(c.stores))
+	for _, s := range c.stores {
+		if options.excludeTombstone && s.GetState() == metapb.StoreState_Tombstone {
+			continue
+		}
+		if !storeHasLabels(s, options.labels) {
+			continue
+		}
+		stores = append(stores, s)
+	}
+	return stores
+}
+
+// storeHasLabels matches the labels in the same way as PD.
+func storeHasLabels(store *metapb.Store, labels []*metapb.StoreLabel) bool {
+	for _, label := range labels {
+		var value string
+		for _, l := range store.GetLabels() {
+			if strings.EqualFold(l.GetKey(), label.GetKey()) {
+				value = l.GetValue()
+				break
+			}
+		}
+		if value != label.GetValue() {
+			return false
+		}
+	}
+	return true
+}
+
+// flush saves the cache to the disk if it is changed. It only clones the tree
+// under the lock, which is copy-on-write, and builds the file outside.
+func (c *offlineCache) flush() error {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	tree := c.tree.Clone()
+	file := offlineCacheFile{
+		ClusterID: c.clusterID,
+		Stores:    make([]*metapb.Store, 0, len(c.stores)),
+	}
+	for _, s := range c.stores {
+		file.Stores = append(file.Stores, s)
+	}
+	c.dirty = false
+	c.mu.Unlock()
+
+	file.Regions = make([]*offlineRegion, 0, tree.Len())
+	tree.Ascend(func(i btree.Item) bool {
+		file.Regions = append(file.Regions, i.(*offlineRegion))
+		return true
+	})
+
+	if err := c.save(&file); err != nil {
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (c *offlineCache) save(file *offlineCacheFile) error {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Write to a temporary file first, so that a crash never leaves a broken
+	// cache behind.
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp, c.path))
+}
+
+// offlineCacheFlushLoop saves the offline cache periodically until the client
+// is closed, and saves it for the last time then.
+func (c *client) offlineCacheFlushLoop() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(offlineCacheFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			if err := c.offlineCache.flush(); err != nil {
+				log.Warn("[pd] failed to save the offline cache", zap.String("path", c.offlineCache.path), errs.ZapError(err))
+			}
+			return
+		}
+		if err := c.offlineCache.flush(); err != nil {
+			log.Warn("[pd] failed to save the offline cache", zap.String("path", c.offlineCache.path), errs.ZapError(err))
+		}
+	}
+}
+
+// serveOffline returns true if the request failed with err can be served from
+// the offline cache, which is the case once every PD member is unreachable.
+func (c *client) serveOffline(err error) bool {
+	return c.offlineCache != nil && isNetworkError(status.Code(err)) && c.isMembersUnreachable()
+}
+
+func (c *client) cacheRegions(regions ...*Region) {
+	if c.offlineCache != nil {
+		c.offlineCache.putRegions(regions...)
+	}
+}
+
+func (c *client) cacheStores(stores []*metapb.Store, all bool) {
+	if c.offlineCache != nil {
+		c.offlineCache.putStores(stores, all)
+	}
+}
+
+// isAllStores returns true if the stores got with the options are all the
+// stores of the cluster.
+func isAllStores(options *GetStoreOp) bool {
+	return !options.excludeTombstone && options.keyspaceID == nil && len(options.labels) == 0
+}
+
+func setStale(stale *bool) {
+	if stale != nil {
+		*stale = true
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// GetNextLeaderAddr returns the address of the follower which is most likely
+// to be the next leader. It is empty if the warm-up is disabled.
+func (c *baseClient) GetNextLeaderAddr() string {
+	addr := c.nextLeader.Load()
+	if addr == nil {
+		return ""
+	}
+	return addr.(string)
+}
+
+// warmUp connects to all the followers in advance and picks the most likely
+// next leader, so that the client does not need to dial after the leader
+// changes.
+func (c *baseClient) warmUp(members []*pdpb.Member, leader *pdpb.Member) {
+	var next *pdpb.Member
+	for _, member := range members {
+		if member.GetMemberId() == leader.GetMemberId() || len(member.GetClientUrls()) == 0 {
+			continue
+		}
+		addr := member.GetClientUrls()[0]
+		if _, err := c.getOrCreateGRPCConn(addr); err != nil {
+			log.Warn("[pd] failed to warm up the connection", zap.String("address", addr), errs.ZapError(err))
+			continue
+		}
+		if next == nil || isMoreLikelyLeader(member, next) {
+			next = member
+		}
+	}
+	var addr string
+	if next != nil {
+		addr = next.GetClientUrls()[0]
+	}
+	if old := c.GetNextLeaderAddr(); old != addr {
+		log.Info("[pd] switch next leader", zap.String("new-next-leader", addr), zap.String("old-next-leader", old))
+	}
+	c.nextLeader.Store(addr)
+}
+
+// isMoreLikelyLeader returns true if a is more likely to be elected than b.
+// The member with a higher leader priority tends to be elected, and the name
+// breaks the tie to keep the choice stable.
+func isMoreLikelyLeader(a, b *pdpb.Member) bool {
+	if a.GetLeaderPriority() != b.GetLeaderPriority() {
+		return a.GetLeaderPriority() > b.GetLeaderPriority()
+	}
+	return a.GetName() < b.GetName()
+}
+
+type standbyTSOStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	stream pdpb.PD_TsoClient
+}
+
+// standbyTSOStreams are the global TSO streams created in advance if the
+// warm-up is enabled. The stream to a follower waits there, and the dispatcher
+// takes it over once the follower becomes the leader.
+type standbyTSOStreams struct {
+	sync.Mutex
+	streams map[string]*standbyTSOStream // url -> stream
+	// connectedURL is the url of the stream used by the dispatcher.
+	connectedURL string
+}
+
+// prepareStandbyTSOStream creates the TSO stream to the most likely next
+// leader. The stream to the current leader is kept until the dispatcher takes
+// it over or connects to the leader by itself, and the others are closed.
+func (c *client) prepareStandbyTSOStream(ctx context.Context) {
+	next, leader := c.GetNextLeaderAddr(), c.GetLeaderAddr()
+	c.standbyTSOStreams.Lock()
+	for url, s := range c.standbyTSOStreams.streams {
+		if (url == next || url == leader) && url != c.standbyTSOStreams.connectedURL && s.ctx.Err() == nil && c.isConnReady(url) {
+			continue
+		}
+		s.cancel()
+		delete(c.standbyTSOStreams.streams, url)
+	}
+	_, exist := c.standbyTSOStreams.streams[next]
+	c.standbyTSOStreams.Unlock()
+	if next == "" || exist {
+		return
+	}
+
+	cc, ok := c.clientConns.Load(next)
+	if !ok {
+		return
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	stream, err := c.createTsoStream(cctx, cancel, pdpb.NewPDClient(cc.(*grpc.ClientConn)))
+	if err != nil {
+		cancel()
+		log.Warn("[pd] failed to create the standby tso stream", zap.String("url", next), errs.ZapError(errs.ErrClientCreateTSOStream, err))
+		return
+	}
+	c.standbyTSOStreams.Lock()
+	defer c.standbyTSOStreams.Unlock()
+	if c.standbyTSOStreams.streams == nil {
+		c.standbyTSOStreams.streams = make(map[string]*standbyTSOStream)
+	}
+	c.standbyTSOStreams.streams[next] = &standbyTSOStream{ctx: cctx, cancel: cancel, stream: stream}
+}
+
+// takeStandbyTSOStream returns the standby TSO stream to the url and records
+// the url as connected. It returns nil if there is no such stream.
+func (c *client) takeStandbyTSOStream(url string) (pdpb.PD_TsoClient, context.CancelFunc) {
+	c.standbyTSOStreams.Lock()
+	defer c.standbyTSOStreams.Unlock()
+	c.standbyTSOStreams.connectedURL = url
+	s, ok := c.standbyTSOStreams.streams[url]
+	if !ok {
+		return nil, nil
+	}
+	delete(c.standbyTSOStreams.streams, url)
+	if s.ctx.Err() != nil {
+		return nil, nil
+	}
+	return s.stream, s.cancel
+}
+
+func (c *baseClient) isConnReady(url string) bool {
+	cc, ok := c.clientConns.Load(url)
+	return ok && cc.(*grpc.ClientConn).GetState() == connectivity.Ready
+}
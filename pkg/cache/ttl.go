@@ -257,6 +257,11 @@ func (c *TTLString) Get(id string) (interface{}, bool) {
 	return c.ttlCache.get(id)
 }
 
+// Remove removes the key
+func (c *TTLString) Remove(key string) {
+	c.ttlCache.remove(key)
+}
+
 // GetAllID returns all key ids
 func (c *TTLString) GetAllID() []string {
 	keys := c.ttlCache.getKeys()
@@ -29,6 +29,8 @@ type Manager struct {
 	storage *core.Storage
 	// component -> addresses
 	Addresses map[string][]string `json:"address"`
+	// address -> version reported by the component
+	Versions map[string]string `json:"versions,omitempty"`
 }
 
 // NewManager creates a new component manager.
@@ -36,6 +38,7 @@ func NewManager(storage *core.Storage) *Manager {
 	return &Manager{
 		storage:   storage,
 		Addresses: make(map[string][]string),
+		Versions:  make(map[string]string),
 	}
 }
 
@@ -80,6 +83,41 @@ func (c *Manager) GetComponent(addr string) string {
 	return ""
 }
 
+// GetComponentVersion returns the version reported by the component with the
+// given address. It returns an empty string if the version is unknown.
+func (c *Manager) GetComponentVersion(addr string) string {
+	c.RLock()
+	defer c.RUnlock()
+
+	addr, err := validateAddr(addr)
+	if err != nil {
+		return ""
+	}
+	return c.Versions[addr]
+}
+
+// SetComponentVersion records the version of a registered component address.
+func (c *Manager) SetComponentVersion(component, addr, version string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	addr, err := validateAddr(addr)
+	if err != nil {
+		return err
+	}
+	if exist, _ := contains(c.Addresses[component], addr); !exist {
+		return fmt.Errorf("component %s address %s not found", component, addr)
+	}
+	if c.Versions == nil {
+		c.Versions = make(map[string]string)
+	}
+	c.Versions[addr] = version
+	if err := c.storage.SaveComponent(c); err != nil {
+		return fmt.Errorf("failed to save component when setting component %s address %s version", component, addr)
+	}
+	return nil
+}
+
 // Register is used for registering a component with an address to PD.
 func (c *Manager) Register(component, addr string) error {
 	c.Lock()
@@ -119,6 +157,7 @@ func (c *Manager) UnRegister(component, addr string) error {
 
 	if exist, idx := contains(ca, addr); exist {
 		ca = append(ca[:idx], ca[idx+1:]...)
+		delete(c.Versions, addr)
 		if len(ca) == 0 {
 			delete(c.Addresses, component)
 			if err := c.storage.SaveComponent(c); err != nil {
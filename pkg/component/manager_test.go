@@ -58,8 +58,16 @@ func (s *testManagerSuite) TestManager(c *C) {
 	c.Assert(m.GetComponent("127.0.0.1:2"), Equals, "c1")
 	c.Assert(m.GetComponent("127.0.0.1:3"), Equals, "c2")
 
+	// set the component version
+	c.Assert(m.GetComponentVersion("127.0.0.1:1"), Equals, "")
+	c.Assert(m.SetComponentVersion("c1", "127.0.0.1:1", "5.0.0"), IsNil)
+	c.Assert(m.GetComponentVersion("127.0.0.1:1"), Equals, "5.0.0")
+	c.Assert(m.SetComponentVersion("c2", "127.0.0.1:1", "5.0.0"), NotNil)
+	c.Assert(m.SetComponentVersion("c1", "127.0.0.1:4", "5.0.0"), NotNil)
+
 	// unregister address
 	c.Assert(m.UnRegister("c1", "127.0.0.1:1"), IsNil)
+	c.Assert(m.GetComponentVersion("127.0.0.1:1"), Equals, "")
 	c.Assert(m.GetComponentAddrs("c1"), DeepEquals, []string{"127.0.0.1:2"})
 	c.Assert(m.UnRegister("c1", "127.0.0.1:2"), IsNil)
 	c.Assert(m.GetComponentAddrs("c1"), DeepEquals, []string{})
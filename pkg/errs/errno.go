@@ -42,6 +42,7 @@ var (
 	ErrGenerateTimestamp  = errors.Normalize("generate timestamp failed, %s", errors.RFCCodeText("PD:tso:ErrGenerateTimestamp"))
 	ErrInvalidTimestamp   = errors.Normalize("invalid timestamp", errors.RFCCodeText("PD:tso:ErrInvalidTimestamp"))
 	ErrLogicOverflow      = errors.Normalize("logic part overflow", errors.RFCCodeText("PD:tso:ErrLogicOverflow"))
+	ErrInvalidTSOLease    = errors.Normalize("invalid tso lease, %s", errors.RFCCodeText("PD:tso:ErrInvalidTSOLease"))
 )
 
 // member errors
@@ -52,20 +53,24 @@ var (
 
 // core errors
 var (
-	ErrWrongRangeKeys      = errors.Normalize("wrong range keys", errors.RFCCodeText("PD:core:ErrWrongRangeKeys"))
-	ErrStoreNotFound       = errors.Normalize("store %v not found", errors.RFCCodeText("PD:core:ErrStoreNotFound"))
-	ErrPauseLeaderTransfer = errors.Normalize("store %v is paused for leader transfer", errors.RFCCodeText("PD:core:ErrPauseLeaderTransfer"))
-	ErrStoreTombstone      = errors.Normalize("store %v has been removed", errors.RFCCodeText("PD:core:ErrStoreTombstone"))
-	ErrStoreDestroyed      = errors.Normalize("store %v has been physically destroyed", errors.RFCCodeText("PD:core:ErrStoreDestroyed"))
+	ErrWrongRangeKeys        = errors.Normalize("wrong range keys", errors.RFCCodeText("PD:core:ErrWrongRangeKeys"))
+	ErrStoreNotFound         = errors.Normalize("store %v not found", errors.RFCCodeText("PD:core:ErrStoreNotFound"))
+	ErrPauseLeaderTransfer   = errors.Normalize("store %v is paused for leader transfer", errors.RFCCodeText("PD:core:ErrPauseLeaderTransfer"))
+	ErrStoreTombstone        = errors.Normalize("store %v has been removed", errors.RFCCodeText("PD:core:ErrStoreTombstone"))
+	ErrStoreDestroyed        = errors.Normalize("store %v has been physically destroyed", errors.RFCCodeText("PD:core:ErrStoreDestroyed"))
+	ErrInvalidRegionSnapshot = errors.Normalize("invalid region snapshot, %s", errors.RFCCodeText("PD:core:ErrInvalidRegionSnapshot"))
 )
 
 // client errors
 var (
-	ErrClientCreateTSOStream = errors.Normalize("create TSO stream failed", errors.RFCCodeText("PD:client:ErrClientCreateTSOStream"))
-	ErrClientGetTSOTimeout   = errors.Normalize("get TSO timeout", errors.RFCCodeText("PD:client:ErrClientGetTSOTimeout"))
-	ErrClientGetTSO          = errors.Normalize("get TSO failed, %v", errors.RFCCodeText("PD:client:ErrClientGetTSO"))
-	ErrClientGetLeader       = errors.Normalize("get leader from %v error", errors.RFCCodeText("PD:client:ErrClientGetLeader"))
-	ErrClientGetMember       = errors.Normalize("get member failed", errors.RFCCodeText("PD:client:ErrClientGetMember"))
+	ErrClientCreateTSOStream       = errors.Normalize("create TSO stream failed", errors.RFCCodeText("PD:client:ErrClientCreateTSOStream"))
+	ErrClientGetTSOTimeout         = errors.Normalize("get TSO timeout", errors.RFCCodeText("PD:client:ErrClientGetTSOTimeout"))
+	ErrClientGetTSO                = errors.Normalize("get TSO failed, %v", errors.RFCCodeText("PD:client:ErrClientGetTSO"))
+	ErrClientGetLeader             = errors.Normalize("get leader from %v error", errors.RFCCodeText("PD:client:ErrClientGetLeader"))
+	ErrClientGetMember             = errors.Normalize("get member failed", errors.RFCCodeText("PD:client:ErrClientGetMember"))
+	ErrClientTSOQueueFull          = errors.Normalize("TSO request is rejected, %v", errors.RFCCodeText("PD:client:ErrClientTSOQueueFull"))
+	ErrClientGRPCCompressor        = errors.Normalize("gRPC compressor %s is not supported", errors.RFCCodeText("PD:client:ErrClientGRPCCompressor"))
+	ErrClientMaxPendingTSORequests = errors.Normalize("max pending TSO requests %d should be between 0 and %d", errors.RFCCodeText("PD:client:ErrClientMaxPendingTSORequests"))
 )
 
 // schedule errors
@@ -74,6 +79,19 @@ var (
 	ErrUnknownOperatorStep      = errors.Normalize("unknown operator step found", errors.RFCCodeText("PD:schedule:ErrUnknownOperatorStep"))
 	ErrMergeOperator            = errors.Normalize("merge operator error, %s", errors.RFCCodeText("PD:schedule:ErrMergeOperator"))
 	ErrCreateOperator           = errors.Normalize("unable to create operator, %s", errors.RFCCodeText("PD:schedule:ErrCreateOperator"))
+	ErrOperatorWatcherTooSlow   = errors.Normalize("the operator watcher falls behind", errors.RFCCodeText("PD:schedule:ErrOperatorWatcherTooSlow"))
+)
+
+// scatter errors
+var (
+	ErrScatterRegionNotFound      = errors.Normalize("region %v not found", errors.RFCCodeText("PD:scatter:ErrScatterRegionNotFound"))
+	ErrScatterRegionNotReplicated = errors.Normalize("region %v is not fully replicated", errors.RFCCodeText("PD:scatter:ErrScatterRegionNotReplicated"))
+	ErrScatterRegionNoLeader      = errors.Normalize("region %v has no leader", errors.RFCCodeText("PD:scatter:ErrScatterRegionNoLeader"))
+	ErrScatterRegionHot           = errors.Normalize("region %v is hot", errors.RFCCodeText("PD:scatter:ErrScatterRegionHot"))
+	ErrScatterNoCandidateStore    = errors.Normalize("no candidate store for region %v after filtering", errors.RFCCodeText("PD:scatter:ErrScatterNoCandidateStore"))
+	ErrScatterStoreLimit          = errors.Normalize("store limit exhausted when adding operator of region %v", errors.RFCCodeText("PD:scatter:ErrScatterStoreLimit"))
+	ErrScatterOperatorConflict    = errors.Normalize("region %v has a conflicting pending operator", errors.RFCCodeText("PD:scatter:ErrScatterOperatorConflict"))
+	ErrScatterAddOperator         = errors.Normalize("region %v failed to add operator", errors.RFCCodeText("PD:scatter:ErrScatterAddOperator"))
 )
 
 // scheduler errors
@@ -98,8 +116,15 @@ var (
 
 // cluster errors
 var (
-	ErrNotBootstrapped = errors.Normalize("TiKV cluster not bootstrapped, please start TiKV first", errors.RFCCodeText("PD:cluster:ErrNotBootstrapped"))
-	ErrStoreIsUp       = errors.Normalize("store is still up, please remove store gracefully", errors.RFCCodeText("PD:cluster:ErrStoreIsUp"))
+	ErrNotBootstrapped           = errors.Normalize("TiKV cluster not bootstrapped, please start TiKV first", errors.RFCCodeText("PD:cluster:ErrNotBootstrapped"))
+	ErrStoreIsUp                 = errors.Normalize("store is still up, please remove store gracefully", errors.RFCCodeText("PD:cluster:ErrStoreIsUp"))
+	ErrStoreNotUp                = errors.Normalize("store %v is not up", errors.RFCCodeText("PD:cluster:ErrStoreNotUp"))
+	ErrStoreMaintenance          = errors.Normalize("invalid maintenance window of store %v, %v", errors.RFCCodeText("PD:cluster:ErrStoreMaintenance"))
+	ErrRecoverUnverified         = errors.Normalize("the cluster is recovered but not verified, please run pd-recover verify", errors.RFCCodeText("PD:cluster:ErrRecoverUnverified"))
+	ErrMaintenanceBudgetExceeded = errors.Normalize("maintenance would take %v failure domains at the same time, exceeding the budget of %v", errors.RFCCodeText("PD:cluster:ErrMaintenanceBudgetExceeded"))
+	ErrStoreOfflineNoSpace       = errors.Normalize("store %v cannot be offline, %v regions of %v MB cannot be placed on the other stores", errors.RFCCodeText("PD:cluster:ErrStoreOfflineNoSpace"))
+	ErrArchivedStoreNotFound     = errors.Normalize("archived store %v not found", errors.RFCCodeText("PD:cluster:ErrArchivedStoreNotFound"))
+	ErrArchivedStoreExists       = errors.Normalize("store %v is already in the cluster", errors.RFCCodeText("PD:cluster:ErrArchivedStoreExists"))
 )
 
 // versioninfo errors
@@ -127,13 +152,55 @@ var (
 	ErrSecurityConfig = errors.Normalize("security config error: %s", errors.RFCCodeText("PD:grpcutil:ErrSecurityConfig"))
 )
 
+// idempotency errors
+var (
+	ErrIdempotencyKeyReused = errors.Normalize("idempotency key %s is reused by a different request", errors.RFCCodeText("PD:idempotency:ErrKeyReused"))
+)
+
+// region syncer errors
+var (
+	ErrRegionWatcherTooSlow    = errors.Normalize("the region watcher falls behind", errors.RFCCodeText("PD:syncer:ErrRegionWatcherTooSlow"))
+	ErrRegionWatcherLeaderLost = errors.Normalize("the leadership is lost while watching regions", errors.RFCCodeText("PD:syncer:ErrRegionWatcherLeaderLost"))
+	ErrRegionHistoryCompacted  = errors.Normalize("the region history from index %d is not available", errors.RFCCodeText("PD:syncer:ErrRegionHistoryCompacted"))
+)
+
+// job errors
+var (
+	ErrJobNotFound   = errors.Normalize("job %d not found", errors.RFCCodeText("PD:job:ErrJobNotFound"))
+	ErrJobNotRunning = errors.Normalize("job %d is not running, state: %s", errors.RFCCodeText("PD:job:ErrJobNotRunning"))
+)
+
+// ttl config errors
+var (
+	ErrTTLConfigNotFound = errors.Normalize("ttl config %s not found", errors.RFCCodeText("PD:config:ErrTTLConfigNotFound"))
+)
+
+// config history errors
+var (
+	ErrConfigVersionNotFound = errors.Normalize("config version %d not found", errors.RFCCodeText("PD:confighistory:ErrConfigVersionNotFound"))
+)
+
+// store tier errors
+var (
+	ErrStoreTierLabelNotSet  = errors.Normalize("store tier label is not set", errors.RFCCodeText("PD:tier:ErrStoreTierLabelNotSet"))
+	ErrStoreTierNotFound     = errors.Normalize("store tier %s not found", errors.RFCCodeText("PD:tier:ErrStoreTierNotFound"))
+	ErrStoreTierRangeOverlap = errors.Normalize("key range overlaps the range [%s, %s) moved to tier %s", errors.RFCCodeText("PD:tier:ErrStoreTierRangeOverlap"))
+)
+
 // server errors
 var (
-	ErrServiceRegistered     = errors.Normalize("service with path [%s] already registered", errors.RFCCodeText("PD:server:ErrServiceRegistered"))
-	ErrAPIInformationInvalid = errors.Normalize("invalid api information, group %s version %s", errors.RFCCodeText("PD:server:ErrAPIInformationInvalid"))
-	ErrClientURLEmpty        = errors.Normalize("client url empty", errors.RFCCodeText("PD:server:ErrClientEmpty"))
-	ErrLeaderNil             = errors.Normalize("leader is nil", errors.RFCCodeText("PD:server:ErrLeaderNil"))
-	ErrCancelStartEtcd       = errors.Normalize("etcd start canceled", errors.RFCCodeText("PD:server:ErrCancelStartEtcd"))
+	ErrServiceRegistered         = errors.Normalize("service with path [%s] already registered", errors.RFCCodeText("PD:server:ErrServiceRegistered"))
+	ErrAPIInformationInvalid     = errors.Normalize("invalid api information, group %s version %s", errors.RFCCodeText("PD:server:ErrAPIInformationInvalid"))
+	ErrClientURLEmpty            = errors.Normalize("client url empty", errors.RFCCodeText("PD:server:ErrClientEmpty"))
+	ErrLeaderNil                 = errors.Normalize("leader is nil", errors.RFCCodeText("PD:server:ErrLeaderNil"))
+	ErrCancelStartEtcd           = errors.Normalize("etcd start canceled", errors.RFCCodeText("PD:server:ErrCancelStartEtcd"))
+	ErrRollingRestartRunning     = errors.Normalize("rolling restart is already running", errors.RFCCodeText("PD:server:ErrRollingRestartRunning"))
+	ErrRollingRestartNotRunning  = errors.Normalize("rolling restart is not running", errors.RFCCodeText("PD:server:ErrRollingRestartNotRunning"))
+	ErrRollingRestartMemberState = errors.Normalize("member %s is not waiting for restart, state: %s", errors.RFCCodeText("PD:server:ErrRollingRestartMemberState"))
+	ErrRollingRestartStep        = errors.Normalize("rolling restart failed to %s of member %s, %v", errors.RFCCodeText("PD:server:ErrRollingRestartStep"))
+	ErrSlowStorageRead           = errors.Normalize("storage read took %v, which exceeds %v", errors.RFCCodeText("PD:server:ErrSlowStorageRead"))
+	ErrInvalidGCPause            = errors.Normalize("invalid GC pause, %s", errors.RFCCodeText("PD:server:ErrInvalidGCPause"))
+	ErrHotStatsUnavailable       = errors.Normalize("the hot-region statistics replicated from the leader are unavailable or stale", errors.RFCCodeText("PD:server:ErrHotStatsUnavailable"))
 )
 
 // logutil errors
@@ -207,6 +274,7 @@ var (
 	ErrPrometheusPushMetrics  = errors.Normalize("push metrics to gateway failed", errors.RFCCodeText("PD:prometheus:ErrPrometheusPushMetrics"))
 	ErrPrometheusCreateClient = errors.Normalize("create client error", errors.RFCCodeText("PD:prometheus:ErrPrometheusCreateClient"))
 	ErrPrometheusQuery        = errors.Normalize("query error", errors.RFCCodeText("PD:prometheus:ErrPrometheusQuery"))
+	ErrPrometheusRemoteWrite  = errors.Normalize("remote write failed, status code %d", errors.RFCCodeText("PD:prometheus:ErrPrometheusRemoteWrite"))
 )
 
 // http errors
@@ -0,0 +1,178 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"sync"
+
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// Topic is the kind of the events.
+type Topic string
+
+// The topics published inside PD.
+const (
+	// TopicStore carries a *StoreEvent when a store is put or deleted. The
+	// store heartbeats which only update the statistics are not published.
+	TopicStore Topic = "store"
+	// TopicRegion carries the *pdpb.SyncRegionResponse of each batch of the
+	// changed regions sent by the region syncer.
+	TopicRegion Topic = "region"
+	// TopicConfig carries a *ConfigEvent when the persisted config is changed.
+	TopicConfig Topic = "config"
+	// TopicLeader carries a *LeaderEvent when the server becomes or is no
+	// longer the PD leader.
+	TopicLeader Topic = "leader"
+	// TopicOperator carries an *ophistory.Record when an operator is created
+	// or ended.
+	TopicOperator Topic = "operator"
+)
+
+// StoreEvent is published when a store is put or deleted.
+type StoreEvent struct {
+	StoreID uint64
+}
+
+// ConfigEvent is published when a section of the persisted config, such as
+// "schedule" or "replication", is changed.
+type ConfigEvent struct {
+	Section string
+}
+
+// LeaderEvent is published when the leadership of the server is changed.
+type LeaderEvent struct {
+	IsLeader bool
+}
+
+// Event is an event published to the bus.
+type Event struct {
+	Topic   Topic
+	Payload interface{}
+}
+
+// Bus passes the events inside PD to the subscribers. Publishing never
+// blocks. A subscriber whose queue is full is stopped instead, and it should
+// resubscribe and resync by itself, so that a slow consumer neither blocks
+// the publisher nor silently misses events.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[Topic]map[*Subscription]struct{}
+}
+
+// NewBus creates a Bus.
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[Topic]map[*Subscription]struct{})}
+}
+
+// Subscribe subscribes the topics with a queue of size events. The name is
+// used in the metrics and logs.
+func (b *Bus) Subscribe(name string, size int, topics ...Topic) *Subscription {
+	sub := &Subscription{
+		bus:     b,
+		name:    name,
+		topics:  topics,
+		ch:      make(chan Event, size),
+		stopped: make(chan struct{}),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range topics {
+		if b.subscribers[topic] == nil {
+			b.subscribers[topic] = make(map[*Subscription]struct{})
+		}
+		b.subscribers[topic][sub] = struct{}{}
+	}
+	subscriberGauge.WithLabelValues(name).Inc()
+	return sub
+}
+
+// HasSubscribers returns whether the topic has any subscriber, so that the
+// publisher can skip building the event.
+func (b *Bus) HasSubscribers(topic Topic) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers[topic]) > 0
+}
+
+// Publish passes the event to the subscribers of the topic. The payload is
+// shared by the subscribers, and should not be modified after it is
+// published. It is a no-op on a nil Bus, which is used by the tests without
+// a server.
+func (b *Bus) Publish(topic Topic, payload interface{}) {
+	if b == nil {
+		return
+	}
+	publishedCounter.WithLabelValues(string(topic)).Inc()
+	event := Event{Topic: topic, Payload: payload}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers[topic] {
+		select {
+		case sub.ch <- event:
+		default:
+			log.Warn("event subscriber falls behind and is stopped",
+				zap.String("subscriber", sub.name), zap.String("topic", string(topic)))
+			stoppedCounter.WithLabelValues(sub.name).Inc()
+			b.removeLocked(sub)
+			close(sub.stopped)
+		}
+	}
+}
+
+func (b *Bus) removeLocked(sub *Subscription) {
+	if sub.removed {
+		return
+	}
+	sub.removed = true
+	for _, topic := range sub.topics {
+		delete(b.subscribers[topic], sub)
+	}
+	subscriberGauge.WithLabelValues(sub.name).Dec()
+}
+
+// Subscription is the events of some topics received from the bus.
+type Subscription struct {
+	bus    *Bus
+	name   string
+	topics []Topic
+	ch     chan Event
+	// stopped is closed when the subscriber falls behind.
+	stopped chan struct{}
+	// removed is protected by the mutex of the bus.
+	removed bool
+}
+
+// Events returns the channel of the events.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Stopped returns a channel which is closed when the subscriber falls behind
+// and no longer receives events. The events already queued can still be
+// received.
+func (s *Subscription) Stopped() <-chan struct{} {
+	return s.stopped
+}
+
+// Close unsubscribes the topics.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.removeLocked(s)
+}
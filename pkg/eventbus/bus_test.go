@@ -0,0 +1,73 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testBusSuite{})
+
+type testBusSuite struct{}
+
+func (s *testBusSuite) TestPublish(c *C) {
+	b := NewBus()
+	stores := b.Subscribe("stores", 4, TopicStore)
+	all := b.Subscribe("all", 4, TopicStore, TopicConfig)
+	c.Assert(b.HasSubscribers(TopicStore), IsTrue)
+	c.Assert(b.HasSubscribers(TopicLeader), IsFalse)
+
+	b.Publish(TopicStore, &StoreEvent{StoreID: 1})
+	b.Publish(TopicConfig, &ConfigEvent{Section: "schedule"})
+	b.Publish(TopicLeader, &LeaderEvent{IsLeader: true})
+	c.Assert(<-stores.Events(), DeepEquals, Event{Topic: TopicStore, Payload: &StoreEvent{StoreID: 1}})
+	c.Assert(stores.Events(), HasLen, 0)
+	c.Assert(<-all.Events(), DeepEquals, Event{Topic: TopicStore, Payload: &StoreEvent{StoreID: 1}})
+	c.Assert(<-all.Events(), DeepEquals, Event{Topic: TopicConfig, Payload: &ConfigEvent{Section: "schedule"}})
+
+	stores.Close()
+	// Closing twice is fine.
+	stores.Close()
+	b.Publish(TopicStore, &StoreEvent{StoreID: 2})
+	c.Assert(stores.Events(), HasLen, 0)
+	c.Assert(all.Events(), HasLen, 1)
+
+	// Publishing to a nil bus is a no-op.
+	var nilBus *Bus
+	nilBus.Publish(TopicStore, &StoreEvent{StoreID: 3})
+	c.Assert(nilBus.HasSubscribers(TopicStore), IsFalse)
+}
+
+func (s *testBusSuite) TestSlowSubscriber(c *C) {
+	b := NewBus()
+	slow := b.Subscribe("slow", 1, TopicStore, TopicConfig)
+	fast := b.Subscribe("fast", 8, TopicStore)
+	for id := uint64(1); id <= 3; id++ {
+		b.Publish(TopicStore, &StoreEvent{StoreID: id})
+	}
+	// The slow subscriber is stopped without blocking the others, and keeps
+	// the queued events.
+	<-slow.Stopped()
+	c.Assert(slow.Events(), HasLen, 1)
+	c.Assert(fast.Events(), HasLen, 3)
+	c.Assert(b.HasSubscribers(TopicConfig), IsFalse)
+	slow.Close()
+	c.Assert(b.HasSubscribers(TopicStore), IsTrue)
+}
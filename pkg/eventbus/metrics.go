@@ -0,0 +1,48 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	publishedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "eventbus",
+			Name:      "published_events_total",
+			Help:      "Counter of the events published to the event bus.",
+		}, []string{"topic"})
+
+	stoppedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "eventbus",
+			Name:      "stopped_subscribers_total",
+			Help:      "Counter of the subscribers stopped for falling behind.",
+		}, []string{"subscriber"})
+
+	subscriberGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "eventbus",
+			Name:      "subscribers",
+			Help:      "The number of the subscribers of the event bus.",
+		}, []string{"subscriber"})
+)
+
+func init() {
+	prometheus.MustRegister(publishedCounter)
+	prometheus.MustRegister(stoppedCounter)
+	prometheus.MustRegister(subscriberGauge)
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fairqueue
+
+import (
+	"context"
+	"sync"
+)
+
+// Queue limits the number of concurrent tasks and serves the waiting tasks of
+// different keys in a round-robin way, so a key with a burst of tasks does not
+// starve the others. The tasks of the same key are served in order.
+//
+// The fairness is only in the number of the tasks started: a key whose tasks
+// run longer still takes a larger share of the time, and the tasks which start
+// while nothing waits are not counted in the turns.
+type Queue struct {
+	mu       sync.Mutex
+	capacity int
+	running  int
+	waiters  map[string][]chan struct{}
+	// keys are the keys with waiting tasks in the order to be served.
+	keys []string
+}
+
+// NewQueue creates a Queue which runs at most capacity tasks at once.
+func NewQueue(capacity int) *Queue {
+	return &Queue{
+		capacity: capacity,
+		waiters:  make(map[string][]chan struct{}),
+	}
+}
+
+// SetCapacity changes the max number of concurrent tasks. The waiting tasks
+// are started at once if the capacity is increased.
+func (q *Queue) SetCapacity(capacity int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.capacity = capacity
+	for q.running < q.capacity && q.wakeLocked() {
+		q.running++
+	}
+}
+
+// Acquire waits until the task of the key can run, and returns the function to
+// call when the task is done.
+func (q *Queue) Acquire(ctx context.Context, key string) (release func(), err error) {
+	q.mu.Lock()
+	if q.running < q.capacity && len(q.keys) == 0 {
+		q.running++
+		q.mu.Unlock()
+		return q.release, nil
+	}
+	ch := make(chan struct{})
+	if len(q.waiters[key]) == 0 {
+		q.keys = append(q.keys, key)
+	}
+	q.waiters[key] = append(q.waiters[key], ch)
+	q.mu.Unlock()
+
+	select {
+	case <-ch:
+		return q.release, nil
+	case <-ctx.Done():
+	}
+	q.mu.Lock()
+	removed := q.removeLocked(key, ch)
+	q.mu.Unlock()
+	if !removed {
+		// The task has been woken up at the same time, so give the slot back.
+		q.release()
+	}
+	return nil, ctx.Err()
+}
+
+// Len returns the number of the waiting tasks.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var n int
+	for _, ws := range q.waiters {
+		n += len(ws)
+	}
+	return n
+}
+
+// release gives the slot back, and wakes the waiters while there are free
+// slots.
+func (q *Queue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running--
+	for q.running < q.capacity && q.wakeLocked() {
+		q.running++
+	}
+}
+
+// wakeLocked hands a slot to the first waiter of the next key, and returns
+// false if there is no waiter.
+func (q *Queue) wakeLocked() bool {
+	if len(q.keys) == 0 {
+		return false
+	}
+	key := q.keys[0]
+	q.keys = q.keys[1:]
+	ws := q.waiters[key]
+	close(ws[0])
+	if len(ws) == 1 {
+		delete(q.waiters, key)
+	} else {
+		q.waiters[key] = ws[1:]
+		q.keys = append(q.keys, key)
+	}
+	return true
+}
+
+func (q *Queue) removeLocked(key string, ch chan struct{}) bool {
+	ws := q.waiters[key]
+	for i, w := range ws {
+		if w != ch {
+			continue
+		}
+		if len(ws) > 1 {
+			q.waiters[key] = append(ws[:i:i], ws[i+1:]...)
+			return true
+		}
+		delete(q.waiters, key)
+		for j, k := range q.keys {
+			if k == key {
+				q.keys = append(q.keys[:j:j], q.keys[j+1:]...)
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
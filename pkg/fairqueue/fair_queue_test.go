@@ -0,0 +1,120 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fairqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testFairQueueSuite{})
+
+type testFairQueueSuite struct{}
+
+func (s *testFairQueueSuite) TestRoundRobin(c *C) {
+	q := NewQueue(1)
+	ctx := context.Background()
+	release, err := q.Acquire(ctx, "a")
+	c.Assert(err, IsNil)
+
+	order := make(chan string, 4)
+	enqueue := func(key string) {
+		n := q.Len()
+		go func() {
+			r, err := q.Acquire(ctx, key)
+			c.Assert(err, IsNil)
+			order <- key
+			r()
+		}()
+		// Wait for the task to be queued so the order is deterministic.
+		for q.Len() == n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// The key "a" has a burst of tasks, but "b" does not wait for all of them.
+	enqueue("a")
+	enqueue("a")
+	enqueue("a")
+	enqueue("b")
+	release()
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, <-order)
+	}
+	c.Assert(got, DeepEquals, []string{"a", "b", "a", "a"})
+	c.Assert(q.Len(), Equals, 0)
+}
+
+func (s *testFairQueueSuite) TestCancel(c *C) {
+	q := NewQueue(1)
+	release, err := q.Acquire(context.Background(), "a")
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = q.Acquire(ctx, "b")
+	c.Assert(err, Equals, context.DeadlineExceeded)
+	c.Assert(q.Len(), Equals, 0)
+
+	release()
+	release, err = q.Acquire(context.Background(), "b")
+	c.Assert(err, IsNil)
+	release()
+}
+
+func (s *testFairQueueSuite) TestSetCapacity(c *C) {
+	q := NewQueue(1)
+	ctx := context.Background()
+	release1, err := q.Acquire(ctx, "a")
+	c.Assert(err, IsNil)
+
+	done := make(chan func())
+	go func() {
+		r, err := q.Acquire(ctx, "b")
+		c.Assert(err, IsNil)
+		done <- r
+	}()
+	for q.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	q.SetCapacity(2)
+	release2 := <-done
+
+	// Shrink the capacity, so the slot released is not handed to the waiter.
+	q.SetCapacity(1)
+	go func() {
+		r, err := q.Acquire(ctx, "c")
+		c.Assert(err, IsNil)
+		done <- r
+	}()
+	for q.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	release1()
+	select {
+	case <-done:
+		c.Fatal("the task should wait for the capacity")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release2()
+	(<-done)()
+}
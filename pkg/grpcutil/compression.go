@@ -0,0 +1,46 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutil
+
+import (
+	"google.golang.org/grpc/encoding"
+	// Register the gzip compressor, so that PD and its clients can compress
+	// the messages with it.
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// GzipCompressor is the name of the gzip compressor.
+const GzipCompressor = gzip.Name
+
+// regionHeavyMethods are the methods of PD whose messages may carry lots of
+// regions, which are worth compressing.
+var regionHeavyMethods = map[string]struct{}{
+	"ScanRegions":       {},
+	"ScanRegionsStream": {},
+	"WatchRegions":      {},
+	"RegionHeartbeat":   {},
+}
+
+// IsRegionHeavyMethod returns whether the messages of the method of PD may
+// carry lots of regions.
+func IsRegionHeavyMethod(method string) bool {
+	_, ok := regionHeavyMethods[method]
+	return ok
+}
+
+// IsCompressorSupported returns whether the gRPC compressor is registered.
+// The empty name means no compression, which is always supported.
+func IsCompressorSupported(name string) bool {
+	return name == "" || encoding.GetCompressor(name) != nil
+}
@@ -24,10 +24,25 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
-// ForwardMetadataKey is used to record the forwarded host of PD.
-const ForwardMetadataKey = "pd-forwarded-host"
+const (
+	// ForwardMetadataKey is used to record the forwarded host of PD.
+	ForwardMetadataKey = "pd-forwarded-host"
+	// RoutingDomainMetadataKey is used to record the routing domain requested
+	// by the client.
+	RoutingDomainMetadataKey = "pd-routing-domain"
+	// CallerComponentMetadataKey is used to record the component of the
+	// caller, such as "br" or "cdc", which the request quota is applied to.
+	CallerComponentMetadataKey = "pd-caller-component"
+	// IdempotencyKeyMetadataKey is used to record the idempotency key of a
+	// mutating request, so that its retries are not executed again.
+	IdempotencyKeyMetadataKey = "pd-idempotency-key"
+	// AdminTokenMetadataKey is used to record the token which authorizes the
+	// request to the admin methods.
+	AdminTokenMetadataKey = "pd-admin-token"
+)
 
 // TLSConfig is the configuration for supporting tls.
 type TLSConfig struct {
@@ -123,3 +138,104 @@ func ResetForwardContext(ctx context.Context) context.Context {
 	md.Set(ForwardMetadataKey, "")
 	return metadata.NewOutgoingContext(ctx, md)
 }
+
+// BuildRoutingDomainContext creates a context with the routing domain in
+// metadata. It is used in client side.
+func BuildRoutingDomainContext(ctx context.Context, domain string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, RoutingDomainMetadataKey, domain)
+}
+
+// GetRoutingDomain returns the routing domain requested by the client, or
+// an empty string if there is none.
+func GetRoutingDomain(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if t := md.Get(RoutingDomainMetadataKey); len(t) > 0 {
+		return t[0]
+	}
+	return ""
+}
+
+// BuildCallerComponentContext creates a context with the caller component in
+// metadata. It is used in client side.
+func BuildCallerComponentContext(ctx context.Context, component string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, CallerComponentMetadataKey, component)
+}
+
+// GetCallerComponent returns the caller component of the request, or an
+// empty string if there is none.
+func GetCallerComponent(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if t := md.Get(CallerComponentMetadataKey); len(t) > 0 {
+		return t[0]
+	}
+	return ""
+}
+
+// BuildIdempotencyKeyContext creates a context with the idempotency key in
+// metadata. It is used in client side.
+func BuildIdempotencyKeyContext(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, IdempotencyKeyMetadataKey, key)
+}
+
+// GetIdempotencyKey returns the idempotency key of the request, or an empty
+// string if there is none.
+func GetIdempotencyKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if t := md.Get(IdempotencyKeyMetadataKey); len(t) > 0 {
+		return t[0]
+	}
+	return ""
+}
+
+// BuildAdminTokenContext creates a context with the admin token in metadata.
+// It is used in client side.
+func BuildAdminTokenContext(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, AdminTokenMetadataKey, token)
+}
+
+// GetAdminToken returns the admin token of the request, or an empty string if
+// there is none.
+func GetAdminToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if t := md.Get(AdminTokenMetadataKey); len(t) > 0 {
+		return t[0]
+	}
+	return ""
+}
+
+// GetPeerAddress returns the address of the client of the request, or an
+// empty string if it is unknown.
+func GetPeerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// GetPeerCommonName returns the CN of the verified client certificate of the
+// request. The second return value is false if the client does not provide a
+// verified certificate.
+func GetPeerCommonName(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName, true
+}
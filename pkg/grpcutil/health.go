@@ -0,0 +1,48 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutil
+
+const (
+	// The services which can be checked by the health service. The empty
+	// service is SERVING as long as the member is running.
+
+	// HealthServiceLeader is SERVING if the member is the PD leader.
+	HealthServiceLeader = "leader"
+	// HealthServiceBootstrapped is SERVING if the member is running a
+	// bootstrapped raft cluster, which only happens on the leader.
+	HealthServiceBootstrapped = "bootstrapped"
+	// HealthServiceTSO is SERVING if the global TSO allocator of the member is
+	// initialized.
+	HealthServiceTSO = "tso"
+	// HealthServiceReady is SERVING if all of the above are SERVING, that is,
+	// the member is ready to serve all requests.
+	HealthServiceReady = "ready"
+
+	// The services below report the health of the dependencies of the member,
+	// which are not counted in HealthServiceReady. They are SERVICE_UNKNOWN
+	// if the dependency is not checked, such as the allocators on followers.
+
+	// HealthServiceEtcd is SERVING if a quorum read of etcd succeeds.
+	HealthServiceEtcd = "etcd"
+	// HealthServiceStorage is SERVING if a read of the cluster meta from the
+	// storage succeeds in time. It is a read, so that the probes of all the
+	// members do not add writes to the storage.
+	HealthServiceStorage = "storage"
+	// HealthServiceIDAllocator is SERVING if the ID allocator of the leader
+	// succeeded in its last allocation.
+	HealthServiceIDAllocator = "id-allocator"
+	// HealthServiceTSOPersistence is SERVING if the global TSO allocator of the
+	// leader succeeded in persisting its last time window.
+	HealthServiceTSOPersistence = "tso-persistence"
+)
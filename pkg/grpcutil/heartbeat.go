@@ -0,0 +1,81 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutil
+
+import (
+	"fmt"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/protoext"
+)
+
+// regionHeartbeatBatchRequestsField is the field number of the requests in
+// RegionHeartbeatBatch.
+const regionHeartbeatBatchRequestsField = 1
+
+// RegionHeartbeatBatch is a batch of region heartbeats, which is not a part
+// of kvproto yet. It is encoded as the message:
+//
+//	message RegionHeartbeatBatch {
+//	    repeated pdpb.RegionHeartbeatRequest requests = 1;
+//	}
+type RegionHeartbeatBatch struct {
+	Requests []*pdpb.RegionHeartbeatRequest
+}
+
+// Reset implements proto.Message.
+func (m *RegionHeartbeatBatch) Reset() { *m = RegionHeartbeatBatch{} }
+
+// String implements proto.Message.
+func (m *RegionHeartbeatBatch) String() string {
+	return fmt.Sprintf("RegionHeartbeatBatch{requests:%d}", len(m.Requests))
+}
+
+// ProtoMessage implements proto.Message.
+func (*RegionHeartbeatBatch) ProtoMessage() {}
+
+// Marshal implements proto.Marshaler.
+func (m *RegionHeartbeatBatch) Marshal() ([]byte, error) {
+	var data []byte
+	for _, request := range m.Requests {
+		b, err := request.Marshal()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		data = protoext.AppendBytesField(data, regionHeartbeatBatchRequestsField, b)
+	}
+	return data, nil
+}
+
+// Unmarshal implements proto.Unmarshaler. The unknown fields are ignored.
+func (m *RegionHeartbeatBatch) Unmarshal(data []byte) error {
+	m.Reset()
+	for len(data) > 0 {
+		fieldNum, wireType, value, n, err := protoext.DecodeField(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+		if fieldNum != regionHeartbeatBatchRequestsField || wireType != protoext.WireBytes {
+			continue
+		}
+		request := &pdpb.RegionHeartbeatRequest{}
+		if err := request.Unmarshal(value); err != nil {
+			return errors.WithStack(err)
+		}
+		m.Requests = append(m.Requests, request)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// LeaderResourceType is the resource type of the leader hint carried by
+	// a gRPC error.
+	LeaderResourceType = "pd-leader"
+	// RetryBackoffMetadataKey is the key of the trailer which carries the
+	// recommended backoff of a retryable error reported in the response
+	// header, as the header has no field for it.
+	RetryBackoffMetadataKey = "pd-retry-backoff"
+)
+
+// headerErrorBackoffs are the recommended backoffs of the retryable errors
+// reported in the response header. The cluster may be bootstrapped, and the
+// region may be reported by the heartbeats soon. The other errors do not go
+// away by retrying.
+var headerErrorBackoffs = map[pdpb.ErrorType]time.Duration{
+	pdpb.ErrorType_NOT_BOOTSTRAPPED: time.Second,
+	pdpb.ErrorType_REGION_NOT_FOUND: 100 * time.Millisecond,
+}
+
+// RetryHint describes whether and how a failed request can be retried. It is
+// carried by the details of gRPC errors, so that clients can decide the retry
+// behavior without parsing the error message.
+type RetryHint struct {
+	// Retryable indicates the request may succeed if it is retried.
+	Retryable bool
+	// Backoff is the recommended duration to wait before retrying.
+	Backoff time.Duration
+	// Leader is the client URL of the current leader if it is known.
+	Leader string
+}
+
+// NewRetryableError creates a gRPC error with the retry hint attached.
+func NewRetryableError(code codes.Code, msg string, hint RetryHint) error {
+	st := status.New(code, msg)
+	var details []proto.Message
+	if hint.Retryable {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(hint.Backoff)})
+	}
+	if hint.Leader != "" {
+		details = append(details, &errdetails.ResourceInfo{ResourceType: LeaderResourceType, ResourceName: hint.Leader})
+	}
+	if len(details) == 0 {
+		return st.Err()
+	}
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// GetRetryHint extracts the retry hint from a gRPC error. The second return
+// value is false if the error does not carry any hint.
+func GetRetryHint(err error) (RetryHint, bool) {
+	st, ok := status.FromError(errors.Cause(err))
+	if !ok {
+		return RetryHint{}, false
+	}
+	var (
+		hint  RetryHint
+		found bool
+	)
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.RetryInfo:
+			hint.Retryable, found = true, true
+			if backoff, err := ptypes.Duration(d.GetRetryDelay()); err == nil {
+				hint.Backoff = backoff
+			}
+		case *errdetails.ResourceInfo:
+			if d.GetResourceType() == LeaderResourceType {
+				hint.Leader, found = d.GetResourceName(), true
+			}
+		}
+	}
+	return hint, found
+}
+
+// GetHeaderErrorRetryHint returns the retry hint of an error reported in the
+// response header. The second return value is false if it is not retryable.
+func GetHeaderErrorRetryHint(err *pdpb.Error) (RetryHint, bool) {
+	backoff, ok := headerErrorBackoffs[err.GetType()]
+	if !ok {
+		return RetryHint{}, false
+	}
+	return RetryHint{Retryable: true, Backoff: backoff}, true
+}
+
+// SetRetryHintTrailer sets the retry hint of the error reported in the
+// response header to the trailer. It is used in server side.
+func SetRetryHintTrailer(ctx context.Context, hint RetryHint) error {
+	return grpc.SetTrailer(ctx, metadata.Pairs(RetryBackoffMetadataKey, hint.Backoff.String()))
+}
+
+// GetRetryHintFromTrailer returns the retry hint of the error reported in the
+// response header from the trailer. The second return value is false if the
+// trailer does not carry any hint. It is used in client side.
+func GetRetryHintFromTrailer(trailer metadata.MD) (RetryHint, bool) {
+	values := trailer.Get(RetryBackoffMetadataKey)
+	if len(values) == 0 {
+		return RetryHint{}, false
+	}
+	backoff, err := time.ParseDuration(values[0])
+	if err != nil {
+		return RetryHint{}, false
+	}
+	return RetryHint{Retryable: true, Backoff: backoff}, true
+}
+
+// GetRetryBackoff returns the backoff recommended by the retry hint of the
+// error, or def if the error does not recommend any.
+func GetRetryBackoff(err error, def time.Duration) time.Duration {
+	if hint, ok := GetRetryHint(err); ok && hint.Retryable && hint.Backoff > 0 {
+		return hint.Backoff
+	}
+	return def
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutil
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/tikv/pd/pkg/errs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ScatterFailuresMetadataKey is the key of the trailer metadata carrying the
+// regions failed to be scattered. The binary suffix lets the value contain
+// any byte of the error messages.
+const ScatterFailuresMetadataKey = "pd-scatter-failures-bin"
+
+// maxScatterFailures limits the size of the trailer.
+const maxScatterFailures = 1024
+
+// ScatterReason is the reason why a region fails to be scattered.
+type ScatterReason string
+
+// The reasons why a region fails to be scattered.
+const (
+	ScatterReasonRegionNotFound   ScatterReason = "region-not-found"
+	ScatterReasonNotReplicated    ScatterReason = "not-replicated"
+	ScatterReasonNoLeader         ScatterReason = "no-leader"
+	ScatterReasonHot              ScatterReason = "hot"
+	ScatterReasonNoCandidateStore ScatterReason = "no-candidate-store"
+	ScatterReasonStoreLimit       ScatterReason = "store-limit"
+	ScatterReasonOperatorConflict ScatterReason = "operator-conflict"
+	ScatterReasonUnknown          ScatterReason = "unknown"
+)
+
+// ScatterFailure describes a region failed to be scattered.
+type ScatterFailure struct {
+	RegionID uint64        `json:"region_id"`
+	Reason   ScatterReason `json:"reason"`
+	// Transient indicates the failure is likely to go away, so scattering the
+	// region again later may succeed. Otherwise, the cluster or the region
+	// needs to be changed first.
+	Transient bool   `json:"transient"`
+	Message   string `json:"message"`
+	// Rejections is the stores rejected as the target of the region peer
+	// which has no candidate store.
+	Rejections []*ScatterRejection `json:"rejections,omitempty"`
+}
+
+// ScatterRejection describes a store rejected by a filter when scattering a
+// region.
+type ScatterRejection struct {
+	StoreID uint64 `json:"store_id"`
+	Filter  string `json:"filter"`
+}
+
+// rejectedStoresGetter is implemented by the errors which know the stores
+// rejected by the filters.
+type rejectedStoresGetter interface {
+	GetRejectedStores() map[uint64]string
+}
+
+// NewScatterFailure creates a ScatterFailure from the error of scattering the
+// region.
+func NewScatterFailure(regionID uint64, err error) *ScatterFailure {
+	failure := &ScatterFailure{RegionID: regionID, Message: err.Error()}
+	switch {
+	case errs.ErrScatterRegionNotFound.Equal(err):
+		failure.Reason = ScatterReasonRegionNotFound
+	case errs.ErrScatterRegionNotReplicated.Equal(err):
+		failure.Reason, failure.Transient = ScatterReasonNotReplicated, true
+	case errs.ErrScatterRegionNoLeader.Equal(err):
+		failure.Reason, failure.Transient = ScatterReasonNoLeader, true
+	case errs.ErrScatterRegionHot.Equal(err):
+		failure.Reason, failure.Transient = ScatterReasonHot, true
+	case errs.ErrScatterNoCandidateStore.Equal(err):
+		failure.Reason = ScatterReasonNoCandidateStore
+	case errs.ErrScatterStoreLimit.Equal(err):
+		failure.Reason, failure.Transient = ScatterReasonStoreLimit, true
+	case errs.ErrScatterOperatorConflict.Equal(err):
+		failure.Reason, failure.Transient = ScatterReasonOperatorConflict, true
+	default:
+		failure.Reason = ScatterReasonUnknown
+	}
+	if getter, ok := err.(rejectedStoresGetter); ok {
+		for storeID, filter := range getter.GetRejectedStores() {
+			failure.Rejections = append(failure.Rejections, &ScatterRejection{StoreID: storeID, Filter: filter})
+		}
+		sort.Slice(failure.Rejections, func(i, j int) bool {
+			return failure.Rejections[i].StoreID < failure.Rejections[j].StoreID
+		})
+	}
+	return failure
+}
+
+// SetScatterFailures attaches the failures sorted by the region ID to the
+// trailer of the response. At most 1024 failures are attached.
+func SetScatterFailures(ctx context.Context, failures []*ScatterFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].RegionID < failures[j].RegionID })
+	if len(failures) > maxScatterFailures {
+		failures = failures[:maxScatterFailures]
+	}
+	value, err := json.Marshal(failures)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	return grpc.SetTrailer(ctx, metadata.Pairs(ScatterFailuresMetadataKey, string(value)))
+}
+
+// GetScatterFailures returns the failures in the trailer of the response. It
+// is used in client side.
+func GetScatterFailures(trailer metadata.MD) ([]*ScatterFailure, error) {
+	values := trailer.Get(ScatterFailuresMetadataKey)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var failures []*ScatterFailure
+	if err := json.Unmarshal([]byte(values[0]), &failures); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return failures, nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutil
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// The gRPC services owned by PD, which are not a part of kvproto, live in the
+// namespace "pd" instead of "pdpb", so that they never clash with the services
+// added to kvproto. They reuse the messages of pdpb where they fit, and carry
+// the extra parameters in metadata.
+const servicePrefix = "pd."
+
+const (
+	// RegionScanServiceName is the name of the gRPC service which streams the
+	// regions in a key range. It reuses the messages of ScanRegions.
+	RegionScanServiceName = servicePrefix + "RegionScan"
+	// ScanRegionsStreamName is the name of the server streaming method.
+	ScanRegionsStreamName = "ScanRegions"
+	// ScanRegionsStreamMethod is the full method name of ScanRegions.
+	ScanRegionsStreamMethod = "/" + RegionScanServiceName + "/" + ScanRegionsStreamName
+	// ScanChunkSizeMetadataKey is used to record the number of regions in each
+	// response of the stream.
+	ScanChunkSizeMetadataKey = "pd-scan-chunk-size"
+)
+
+const (
+	// RegionWatchServiceName is the name of the gRPC service which pushes the
+	// changes of the regions in a key range. The request reuses the message
+	// of ScanRegions, and the responses reuse the message of SyncRegions.
+	RegionWatchServiceName = servicePrefix + "RegionWatch"
+	// WatchRegionsStreamName is the name of the server streaming method.
+	WatchRegionsStreamName = "WatchRegions"
+	// WatchRegionsStreamMethod is the full method name of WatchRegions.
+	WatchRegionsStreamMethod = "/" + RegionWatchServiceName + "/" + WatchRegionsStreamName
+	// WatchStartIndexMetadataKey is used to record the region history index
+	// to start watching from.
+	WatchStartIndexMetadataKey = "pd-watch-start-index"
+)
+
+const (
+	// RegionSnapshotServiceName is the name of the gRPC service which sends a
+	// follower the regions which differ from the regions it has, so that a
+	// follower with the regions in its region storage does not load all the
+	// regions again. The requests are syncer.SnapshotRequest, and the
+	// responses reuse the message of SyncRegions.
+	RegionSnapshotServiceName = servicePrefix + "RegionSnapshot"
+	// SyncRegionSnapshotStreamName is the name of the server streaming method.
+	SyncRegionSnapshotStreamName = "SyncRegionSnapshot"
+	// SyncRegionSnapshotStreamMethod is the full method name of
+	// SyncRegionSnapshot.
+	SyncRegionSnapshotStreamMethod = "/" + RegionSnapshotServiceName + "/" + SyncRegionSnapshotStreamName
+)
+
+const (
+	// IDReservationServiceName is the name of the gRPC service which leases
+	// ranges of ids to clients. It reuses the messages of AllocID, and the
+	// response carries the first id of the range.
+	IDReservationServiceName = servicePrefix + "IDReservation"
+	// AllocIDRangeMethodName is the name of the unary method.
+	AllocIDRangeMethodName = "AllocIDRange"
+	// AllocIDRangeMethod is the full method name of AllocIDRange.
+	AllocIDRangeMethod = "/" + IDReservationServiceName + "/" + AllocIDRangeMethodName
+	// IDRangeCountMetadataKey is used to record the number of ids to allocate.
+	IDRangeCountMetadataKey = "pd-id-range-count"
+	// IDRangeTTLMetadataKey is used to record the ttl of the lease in
+	// milliseconds.
+	IDRangeTTLMetadataKey = "pd-id-range-ttl"
+)
+
+const (
+	// StoreRegionServiceName is the name of the gRPC service which lists the
+	// regions of a store page by page. It reuses the messages of ScanRegions:
+	// the start key of the request is the cursor of the page, and the limit
+	// is the size of the page.
+	StoreRegionServiceName = servicePrefix + "StoreRegion"
+	// GetRegionsByStoreMethodName is the name of the unary method.
+	GetRegionsByStoreMethodName = "GetRegionsByStore"
+	// GetRegionsByStoreMethod is the full method name of GetRegionsByStore.
+	GetRegionsByStoreMethod = "/" + StoreRegionServiceName + "/" + GetRegionsByStoreMethodName
+	// StoreIDMetadataKey is used to record the store whose regions are
+	// requested.
+	StoreIDMetadataKey = "pd-store-id"
+)
+
+const (
+	// BulkRegionHeartbeatServiceName is the name of the gRPC service which
+	// accepts the region heartbeats in batches. The requests are
+	// RegionHeartbeatBatch, and the responses reuse the message of
+	// RegionHeartbeat.
+	BulkRegionHeartbeatServiceName = servicePrefix + "BulkRegionHeartbeat"
+	// RegionHeartbeatsStreamName is the name of the bidirectional streaming
+	// method.
+	RegionHeartbeatsStreamName = "RegionHeartbeats"
+	// RegionHeartbeatsStreamMethod is the full method name of
+	// RegionHeartbeats.
+	RegionHeartbeatsStreamMethod = "/" + BulkRegionHeartbeatServiceName + "/" + RegionHeartbeatsStreamName
+)
+
+const (
+	// ReplicationStatusServiceName is the name of the gRPC service which
+	// pushes the replication status of the cluster, e.g. the state of DR
+	// auto-sync. The request reuses the message of GetClusterConfig, and the
+	// responses reuse the message of StoreHeartbeat, which carries the
+	// replication status to the stores.
+	ReplicationStatusServiceName = servicePrefix + "ReplicationStatus"
+	// WatchReplicationStatusStreamName is the name of the server streaming
+	// method.
+	WatchReplicationStatusStreamName = "WatchReplicationStatus"
+	// WatchReplicationStatusStreamMethod is the full method name of
+	// WatchReplicationStatus.
+	WatchReplicationStatusStreamMethod = "/" + ReplicationStatusServiceName + "/" + WatchReplicationStatusStreamName
+)
+
+const (
+	// HealthServiceName is the name of the gRPC service which reports the
+	// health and readiness of a PD member. It is served by the health server
+	// of google.golang.org/grpc/health, but uses its own name because the
+	// embedded etcd registers grpc.health.v1.Health on the same gRPC server
+	// before PD does, and a duplicate registration is fatal. That one always
+	// reports SERVING for the empty service, so the probes which need the
+	// statuses of health.go have to query this name.
+	HealthServiceName = servicePrefix + "Health"
+	// HealthCheckMethod is the full method name of the Check method.
+	HealthCheckMethod = "/" + HealthServiceName + "/Check"
+	// HealthWatchMethod is the full method name of the Watch method.
+	HealthWatchMethod = "/" + HealthServiceName + "/Watch"
+)
+
+// The descriptions of the streaming methods, which are used to create the
+// streams in client side.
+var (
+	ScanRegionsStreamDesc            = newStreamDesc(ScanRegionsStreamName, false)
+	WatchRegionsStreamDesc           = newStreamDesc(WatchRegionsStreamName, false)
+	SyncRegionSnapshotStreamDesc     = newStreamDesc(SyncRegionSnapshotStreamName, false)
+	RegionHeartbeatsStreamDesc       = newStreamDesc(RegionHeartbeatsStreamName, true)
+	WatchReplicationStatusStreamDesc = newStreamDesc(WatchReplicationStatusStreamName, false)
+)
+
+func newStreamDesc(name string, clientStreams bool) *grpc.StreamDesc {
+	return &grpc.StreamDesc{
+		StreamName:    name,
+		ServerStreams: true,
+		ClientStreams: clientStreams,
+	}
+}
+
+// getUintMetadata returns the unsigned integer of the key in the metadata of
+// the request. The second return value is false if it is absent or invalid.
+func getUintMetadata(ctx context.Context, key string) (uint64, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	t := md.Get(key)
+	if len(t) == 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(t[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// BuildScanChunkSizeContext creates a context with the chunk size of the
+// region stream in metadata. It is used in client side.
+func BuildScanChunkSizeContext(ctx context.Context, chunkSize int) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, ScanChunkSizeMetadataKey, strconv.Itoa(chunkSize))
+}
+
+// GetScanChunkSize returns the chunk size requested by the client. The second
+// return value is false if the client does not specify a valid one.
+func GetScanChunkSize(ctx context.Context) (int, bool) {
+	chunkSize, ok := getUintMetadata(ctx, ScanChunkSizeMetadataKey)
+	if !ok || chunkSize == 0 || chunkSize > math.MaxInt32 {
+		return 0, false
+	}
+	return int(chunkSize), true
+}
+
+// BuildWatchStartIndexContext creates a context with the start index of the
+// region watch in metadata. It is used in client side.
+func BuildWatchStartIndexContext(ctx context.Context, startIndex uint64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, WatchStartIndexMetadataKey, strconv.FormatUint(startIndex, 10))
+}
+
+// GetWatchStartIndex returns the start index requested by the client. The
+// second return value is false if the client does not specify a valid one.
+func GetWatchStartIndex(ctx context.Context) (uint64, bool) {
+	return getUintMetadata(ctx, WatchStartIndexMetadataKey)
+}
+
+// BuildIDRangeContext creates a context with the count and ttl of the id range
+// in metadata. It is used in client side.
+func BuildIDRangeContext(ctx context.Context, count uint64, ttl time.Duration) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		IDRangeCountMetadataKey, strconv.FormatUint(count, 10),
+		IDRangeTTLMetadataKey, strconv.FormatInt(ttl.Milliseconds(), 10))
+}
+
+// GetIDRange returns the count and ttl of the id range requested by the
+// client. The third return value is false if the client does not specify
+// valid ones.
+func GetIDRange(ctx context.Context) (uint64, time.Duration, bool) {
+	count, ok := getUintMetadata(ctx, IDRangeCountMetadataKey)
+	if !ok || count == 0 {
+		return 0, 0, false
+	}
+	ttl, ok := getUintMetadata(ctx, IDRangeTTLMetadataKey)
+	if !ok || ttl == 0 || ttl > math.MaxInt64/uint64(time.Millisecond) {
+		return 0, 0, false
+	}
+	return count, time.Duration(ttl) * time.Millisecond, true
+}
+
+// BuildStoreIDContext creates a context with the store id in metadata. It is
+// used in client side.
+func BuildStoreIDContext(ctx context.Context, storeID uint64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, StoreIDMetadataKey, strconv.FormatUint(storeID, 10))
+}
+
+// GetStoreID returns the store id requested by the client. The second return
+// value is false if the client does not specify a valid one.
+func GetStoreID(ctx context.Context) (uint64, bool) {
+	storeID, ok := getUintMetadata(ctx, StoreIDMetadataKey)
+	if !ok || storeID == 0 {
+		return 0, false
+	}
+	return storeID, true
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutil
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/pkg/keyspace"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// StoreKeyspaceMetadataKey is used to record the keyspace whose eligible
+	// stores are requested by GetAllStores.
+	StoreKeyspaceMetadataKey = "pd-store-keyspace"
+	// StoreLabelMetadataKey is used to record a label the stores returned by
+	// GetAllStores must have, in the form of key=value.
+	StoreLabelMetadataKey = "pd-store-label"
+	// StoreMinRevisionMetadataKey is used to record the minimum revision of
+	// the store metadata the client accepts. It allows the followers synced to
+	// the revision to serve GetStore and GetAllStores.
+	StoreMinRevisionMetadataKey = "pd-store-min-revision"
+	// StoreRevisionHeaderKey is used to record the revision of the store
+	// metadata in the response header of GetStore and GetAllStores.
+	StoreRevisionHeaderKey = "pd-store-revision"
+)
+
+// BuildStoreKeyspaceContext creates a context with the keyspace to filter the
+// stores in metadata. It is used in client side.
+func BuildStoreKeyspaceContext(ctx context.Context, keyspaceID uint32) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, StoreKeyspaceMetadataKey, strconv.FormatUint(uint64(keyspaceID), 10))
+}
+
+// BuildStoreLabelContext creates a context with a label to filter the stores
+// in metadata. It is used in client side.
+func BuildStoreLabelContext(ctx context.Context, key, value string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, StoreLabelMetadataKey, key+"="+value)
+}
+
+// GetStoreKeyspace returns the keyspace to filter the stores requested by the
+// client. The second return value is false if the client does not specify a
+// valid one.
+func GetStoreKeyspace(ctx context.Context) (uint32, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	t := md.Get(StoreKeyspaceMetadataKey)
+	if len(t) == 0 {
+		return 0, false
+	}
+	id, err := keyspace.ParseKeyspaceID(t[0])
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// GetStoreLabels returns the labels to filter the stores requested by the
+// client. The malformed ones are ignored.
+func GetStoreLabels(ctx context.Context) []*metapb.StoreLabel {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	var labels []*metapb.StoreLabel
+	for _, t := range md.Get(StoreLabelMetadataKey) {
+		kv := strings.SplitN(t, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		labels = append(labels, &metapb.StoreLabel{Key: kv[0], Value: kv[1]})
+	}
+	return labels
+}
+
+// BuildStoreFollowerReadContext creates a context with the minimum revision of
+// the store metadata in metadata, which allows the followers to serve the
+// request. It is used in client side.
+func BuildStoreFollowerReadContext(ctx context.Context, minRevision int64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, StoreMinRevisionMetadataKey, strconv.FormatInt(minRevision, 10))
+}
+
+// GetStoreMinRevision returns the minimum revision of the store metadata the
+// client accepts. The second return value is false if the client does not
+// allow the followers to serve the request.
+func GetStoreMinRevision(ctx context.Context) (int64, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	t := md.Get(StoreMinRevisionMetadataKey)
+	if len(t) == 0 {
+		return 0, false
+	}
+	revision, err := strconv.ParseInt(t[0], 10, 64)
+	if err != nil || revision < 0 {
+		return 0, false
+	}
+	return revision, true
+}
+
+// GetStoreRevision returns the revision of the store metadata in the response
+// header, or 0 if there is none. It is used in client side.
+func GetStoreRevision(header metadata.MD) int64 {
+	t := header.Get(StoreRevisionHeaderKey)
+	if len(t) == 0 {
+		return 0
+	}
+	revision, err := strconv.ParseInt(t[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
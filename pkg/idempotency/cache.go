@@ -0,0 +1,156 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idempotency
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+const (
+	// DefaultTTL is how long the result of a request is kept by default.
+	DefaultTTL = 10 * time.Minute
+	// DefaultCapacity is the number of results kept at most by default.
+	DefaultCapacity = 100000
+)
+
+type entry struct {
+	key         string
+	fingerprint []byte
+	// done is closed when the first request with the key finishes.
+	done     chan struct{}
+	result   interface{}
+	recorded bool
+	expire   time.Time
+	// elem is the element of the entry in the recorded list.
+	elem *list.Element
+}
+
+// Cache keeps the results of the requests with idempotency keys for a while,
+// so that a retried request gets the result of the original one instead of
+// being executed again. A key can only be used by the requests with the same
+// fingerprint, which is usually derived from the method and body of the
+// request. At most capacity results are kept, and the oldest ones are dropped
+// first, even if they have not expired.
+type Cache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	capacity int
+	entries  map[string]*entry
+	// recorded lists the recorded entries in the order of recording, which is
+	// also the order of expiration as they share the ttl.
+	recorded *list.List
+}
+
+// NewCache creates a Cache which keeps at most capacity results for ttl.
+func NewCache(ttl time.Duration, capacity int) *Cache {
+	return &Cache{
+		ttl:      ttl,
+		capacity: capacity,
+		entries:  make(map[string]*entry),
+		recorded: list.New(),
+	}
+}
+
+// Fingerprint returns the fingerprint of the given parts of a request.
+func Fingerprint(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, part := range parts {
+		// Separate the parts by their lengths to avoid ambiguity.
+		h.Write([]byte{byte(len(part) >> 24), byte(len(part) >> 16), byte(len(part) >> 8), byte(len(part))})
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+// Do executes fn once for the key and returns its result, along with whether
+// the result is replayed from an earlier request. If a request with the same
+// key is running, Do waits for it. fn decides whether its result is recorded,
+// and a request whose result is not recorded can be retried with the same key.
+// ErrIdempotencyKeyReused is returned if the key has been used by a request
+// with a different fingerprint.
+func (c *Cache) Do(key string, fingerprint []byte, fn func() (result interface{}, record bool)) (interface{}, bool, error) {
+	for {
+		c.mu.Lock()
+		c.gcLocked(time.Now())
+		e, ok := c.entries[key]
+		if ok && !bytes.Equal(e.fingerprint, fingerprint) {
+			c.mu.Unlock()
+			return nil, false, errs.ErrIdempotencyKeyReused.FastGenByArgs(key)
+		}
+		if ok {
+			c.mu.Unlock()
+			<-e.done
+			if e.recorded {
+				return e.result, true, nil
+			}
+			// The earlier request is not recorded, so try to execute again.
+			continue
+		}
+		e = &entry{key: key, fingerprint: fingerprint, done: make(chan struct{})}
+		c.entries[key] = e
+		c.mu.Unlock()
+		return c.execute(key, e, fn), false, nil
+	}
+}
+
+func (c *Cache) execute(key string, e *entry, fn func() (interface{}, bool)) (result interface{}) {
+	record := false
+	// The waiters are released even if fn panics.
+	defer func() {
+		c.mu.Lock()
+		if record {
+			e.result, e.recorded, e.expire = result, true, time.Now().Add(c.ttl)
+			e.elem = c.recorded.PushBack(e)
+			for c.recorded.Len() > c.capacity {
+				c.removeLocked(c.recorded.Front().Value.(*entry))
+			}
+		} else {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+		close(e.done)
+	}()
+	result, record = fn()
+	return result
+}
+
+// Len returns the number of the keys in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// gcLocked drops the expired results, which are at the front of the recorded
+// list.
+func (c *Cache) gcLocked(now time.Time) {
+	for front := c.recorded.Front(); front != nil; front = c.recorded.Front() {
+		e := front.Value.(*entry)
+		if !now.After(e.expire) {
+			return
+		}
+		c.removeLocked(e)
+	}
+}
+
+func (c *Cache) removeLocked(e *entry) {
+	c.recorded.Remove(e.elem)
+	delete(c.entries, e.key)
+}
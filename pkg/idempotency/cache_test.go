@@ -0,0 +1,121 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idempotency
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/tikv/pd/pkg/errs"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testCacheSuite{})
+
+type testCacheSuite struct{}
+
+func (s *testCacheSuite) TestDo(c *C) {
+	cache := NewCache(time.Minute, DefaultCapacity)
+	var executed int32
+	fn := func(result string, record bool) func() (interface{}, bool) {
+		return func() (interface{}, bool) {
+			atomic.AddInt32(&executed, 1)
+			return result, record
+		}
+	}
+	fp1, fp2 := Fingerprint([]byte("POST"), []byte("a")), Fingerprint([]byte("POSTa"))
+	c.Assert(fp1, Not(DeepEquals), fp2)
+
+	result, replayed, err := cache.Do("k1", fp1, fn("r1", true))
+	c.Assert(err, IsNil)
+	c.Assert(replayed, IsFalse)
+	c.Assert(result, Equals, "r1")
+	result, replayed, err = cache.Do("k1", fp1, fn("r2", true))
+	c.Assert(err, IsNil)
+	c.Assert(replayed, IsTrue)
+	c.Assert(result, Equals, "r1")
+	c.Assert(atomic.LoadInt32(&executed), Equals, int32(1))
+
+	_, _, err = cache.Do("k1", fp2, fn("r3", true))
+	c.Assert(errs.ErrIdempotencyKeyReused.Equal(err), IsTrue)
+
+	// The result which is not recorded is executed again.
+	result, _, err = cache.Do("k2", fp1, fn("r4", false))
+	c.Assert(err, IsNil)
+	c.Assert(result, Equals, "r4")
+	result, replayed, err = cache.Do("k2", fp1, fn("r5", true))
+	c.Assert(err, IsNil)
+	c.Assert(replayed, IsFalse)
+	c.Assert(result, Equals, "r5")
+	c.Assert(cache.Len(), Equals, 2)
+}
+
+func (s *testCacheSuite) TestConcurrentDo(c *C) {
+	cache := NewCache(time.Minute, DefaultCapacity)
+	fp := Fingerprint([]byte("req"))
+	var executed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _, err := cache.Do("k", fp, func() (interface{}, bool) {
+				atomic.AddInt32(&executed, 1)
+				time.Sleep(10 * time.Millisecond)
+				return "r", true
+			})
+			c.Assert(err, IsNil)
+			c.Assert(result, Equals, "r")
+		}()
+	}
+	wg.Wait()
+	c.Assert(atomic.LoadInt32(&executed), Equals, int32(1))
+}
+
+func (s *testCacheSuite) TestExpire(c *C) {
+	cache := NewCache(10*time.Millisecond, DefaultCapacity)
+	fp := Fingerprint([]byte("req"))
+	cache.Do("k", fp, func() (interface{}, bool) { return "r1", true })
+	time.Sleep(20 * time.Millisecond)
+	result, replayed, err := cache.Do("k", fp, func() (interface{}, bool) { return "r2", true })
+	c.Assert(err, IsNil)
+	c.Assert(replayed, IsFalse)
+	c.Assert(result, Equals, "r2")
+	c.Assert(cache.Len(), Equals, 1)
+}
+
+func (s *testCacheSuite) TestCapacity(c *C) {
+	cache := NewCache(time.Minute, 2)
+	fp := Fingerprint([]byte("req"))
+	for _, key := range []string{"k1", "k2", "k3"} {
+		cache.Do(key, fp, func() (interface{}, bool) { return key, true })
+	}
+	c.Assert(cache.Len(), Equals, 2)
+	// The oldest result is dropped.
+	result, replayed, err := cache.Do("k1", fp, func() (interface{}, bool) { return "r", true })
+	c.Assert(err, IsNil)
+	c.Assert(replayed, IsFalse)
+	c.Assert(result, Equals, "r")
+	result, replayed, err = cache.Do("k3", fp, func() (interface{}, bool) { return "r", true })
+	c.Assert(err, IsNil)
+	c.Assert(replayed, IsTrue)
+	c.Assert(result, Equals, "k3")
+	c.Assert(cache.Len(), Equals, 2)
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyspace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+
+	"github.com/pingcap/errors"
+	"github.com/tikv/pd/pkg/codec"
+)
+
+const (
+	// MaxKeyspaceID is the maximum keyspace ID, which is encoded in 3 bytes.
+	MaxKeyspaceID = 1<<24 - 1
+	// RawModePrefix is the key prefix of raw keys in API V2.
+	RawModePrefix byte = 'r'
+	// TxnModePrefix is the key prefix of transactional keys in API V2.
+	TxnModePrefix byte = 'x'
+)
+
+// KeyRange is a range of region keys which belongs to a keyspace. The keys are
+// encoded in the same way as the region boundaries.
+type KeyRange struct {
+	StartKey []byte
+	EndKey   []byte
+}
+
+// Contains checks if the key is in the range.
+func (r KeyRange) Contains(key []byte) bool {
+	return bytes.Compare(key, r.StartKey) >= 0 && bytes.Compare(key, r.EndKey) < 0
+}
+
+// Overlaps checks if the range overlaps with [startKey, endKey). An empty end
+// key means the end of the key space.
+func (r KeyRange) Overlaps(startKey, endKey []byte) bool {
+	return bytes.Compare(startKey, r.EndKey) < 0 && (len(endKey) == 0 || bytes.Compare(r.StartKey, endKey) < 0)
+}
+
+// ParseKeyspaceID parses the keyspace ID from a string.
+func ParseKeyspaceID(s string) (uint32, error) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid keyspace id %q", s)
+	}
+	if id > MaxKeyspaceID {
+		return 0, errors.Errorf("keyspace id %d exceeds the maximum %d", id, MaxKeyspaceID)
+	}
+	return uint32(id), nil
+}
+
+// MakeKeyRanges returns the region key ranges of the keyspace, which are the
+// raw mode range followed by the transactional mode range.
+func MakeKeyRanges(id uint32) []KeyRange {
+	return []KeyRange{
+		makeKeyRange(RawModePrefix, id),
+		makeKeyRange(TxnModePrefix, id),
+	}
+}
+
+func makeKeyRange(mode byte, id uint32) KeyRange {
+	start := binary.BigEndian.Uint32([]byte{mode, 0, 0, 0}) | id
+	return KeyRange{
+		StartKey: encodePrefix(start),
+		EndKey:   encodePrefix(start + 1),
+	}
+}
+
+func encodePrefix(prefix uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], prefix)
+	return codec.EncodeBytes(b[:])
+}
+
+// RegionInKeyspace checks if the region with the given boundaries overlaps
+// with any of the ranges.
+func RegionInKeyspace(ranges []KeyRange, startKey, endKey []byte) bool {
+	for _, r := range ranges {
+		if r.Overlaps(startKey, endKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyspaceOfKey returns the keyspace ID of an encoded region key, or false if
+// the key does not belong to any keyspace.
+func KeyspaceOfKey(key []byte) (uint32, bool) {
+	// The first group of the encoded key must hold the mode prefix and the
+	// keyspace ID, so it has at most 4 bytes of padding.
+	const groupSize = 8
+	if len(key) <= groupSize || 0xFF-key[groupSize] > groupSize-4 {
+		return 0, false
+	}
+	if key[0] != RawModePrefix && key[0] != TxnModePrefix {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(key[:4]) & MaxKeyspaceID, true
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyspace
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/tikv/pd/pkg/codec"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testKeyspaceSuite{})
+
+type testKeyspaceSuite struct{}
+
+func (s *testKeyspaceSuite) TestParseKeyspaceID(c *C) {
+	id, err := ParseKeyspaceID("100")
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, uint32(100))
+	_, err = ParseKeyspaceID("abc")
+	c.Assert(err, NotNil)
+	_, err = ParseKeyspaceID("16777216")
+	c.Assert(err, NotNil)
+}
+
+func (s *testKeyspaceSuite) TestMakeKeyRanges(c *C) {
+	ranges := MakeKeyRanges(1)
+	c.Assert(ranges, HasLen, 2)
+	c.Assert(ranges[0].StartKey, DeepEquals, []byte(codec.EncodeBytes([]byte{'r', 0, 0, 1})))
+	c.Assert(ranges[0].EndKey, DeepEquals, []byte(codec.EncodeBytes([]byte{'r', 0, 0, 2})))
+	c.Assert(ranges[1].StartKey, DeepEquals, []byte(codec.EncodeBytes([]byte{'x', 0, 0, 1})))
+	c.Assert(ranges[1].EndKey, DeepEquals, []byte(codec.EncodeBytes([]byte{'x', 0, 0, 2})))
+
+	key := codec.EncodeBytes([]byte{'x', 0, 0, 1, 'a'})
+	c.Assert(ranges[1].Contains(key), IsTrue)
+	c.Assert(ranges[0].Contains(key), IsFalse)
+	c.Assert(RegionInKeyspace(ranges, key, nil), IsTrue)
+	c.Assert(RegionInKeyspace(ranges, []byte(""), ranges[0].StartKey), IsFalse)
+	c.Assert(RegionInKeyspace(ranges, ranges[1].EndKey, nil), IsFalse)
+
+	// The last keyspace ends at the next mode.
+	ranges = MakeKeyRanges(MaxKeyspaceID)
+	c.Assert(ranges[0].EndKey, DeepEquals, []byte(codec.EncodeBytes([]byte{'s', 0, 0, 0})))
+}
+
+func (s *testKeyspaceSuite) TestKeyspaceOfKey(c *C) {
+	id, ok := KeyspaceOfKey(codec.EncodeBytes([]byte{'x', 0, 1, 2, 'a'}))
+	c.Assert(ok, IsTrue)
+	c.Assert(id, Equals, uint32(258))
+	id, ok = KeyspaceOfKey(MakeKeyRanges(3)[0].StartKey)
+	c.Assert(ok, IsTrue)
+	c.Assert(id, Equals, uint32(3))
+
+	for _, key := range [][]byte{
+		nil,
+		codec.EncodeBytes([]byte{'x', 0, 1}),
+		codec.EncodeBytes([]byte("t\x80\x00\x00\x00")),
+		[]byte{'x', 0, 0, 1},
+	} {
+		_, ok = KeyspaceOfKey(key)
+		c.Assert(ok, IsFalse)
+	}
+}
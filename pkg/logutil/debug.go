@@ -0,0 +1,105 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	zaplog "github.com/pingcap/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The modules which support targeted debug logging.
+const (
+	RegionHeartbeatModule = "region-heartbeat"
+	MergeCheckerModule    = "merge-checker"
+)
+
+var debugModules = struct {
+	sync.RWMutex
+	// expires maps the module to the time its debug logging is turned off.
+	expires map[string]time.Time
+}{expires: make(map[string]time.Time)}
+
+// IsDebugModule returns whether the module supports targeted debug logging.
+func IsDebugModule(module string) bool {
+	switch module {
+	case RegionHeartbeatModule, MergeCheckerModule:
+		return true
+	}
+	return false
+}
+
+// EnableModuleDebug turns on the debug logging of the module for the ttl,
+// regardless of the global log level.
+func EnableModuleDebug(module string, ttl time.Duration) {
+	debugModules.Lock()
+	defer debugModules.Unlock()
+	debugModules.expires[module] = time.Now().Add(ttl)
+}
+
+// DisableModuleDebug turns off the debug logging of the module.
+func DisableModuleDebug(module string) {
+	debugModules.Lock()
+	defer debugModules.Unlock()
+	delete(debugModules.expires, module)
+}
+
+// IsModuleDebugEnabled returns whether the debug logging of the module is on.
+func IsModuleDebugEnabled(module string) bool {
+	debugModules.RLock()
+	defer debugModules.RUnlock()
+	expire, ok := debugModules.expires[module]
+	return ok && time.Now().Before(expire)
+}
+
+// DebugModuleStatus is the status of the debug logging of a module.
+type DebugModuleStatus struct {
+	Module     string    `json:"module"`
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+// GetDebugModules returns the modules whose debug logging is on, sorted by
+// the module name. The expired modules are removed.
+func GetDebugModules() []DebugModuleStatus {
+	debugModules.Lock()
+	defer debugModules.Unlock()
+	now := time.Now()
+	modules := make([]DebugModuleStatus, 0, len(debugModules.expires))
+	for module, expire := range debugModules.expires {
+		if !now.Before(expire) {
+			delete(debugModules.expires, module)
+			continue
+		}
+		modules = append(modules, DebugModuleStatus{Module: module, ExpireTime: expire})
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Module < modules[j].Module })
+	return modules
+}
+
+// ModuleDebug logs the message at the debug level. If the global log level is
+// higher but the debug logging of the module is on, the message is logged at
+// the info level with the module attached instead.
+func ModuleDebug(module string, msg string, fields ...zap.Field) {
+	if zaplog.GetLevel() <= zapcore.DebugLevel {
+		zaplog.L().WithOptions(zap.AddCallerSkip(1)).Debug(msg, fields...)
+		return
+	}
+	if IsModuleDebugEnabled(module) {
+		zaplog.L().WithOptions(zap.AddCallerSkip(1)).Info(msg, append(fields, zap.String("debug-module", module))...)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	. "github.com/pingcap/check"
@@ -162,3 +163,20 @@ func (s *testLogSuite) TestRedactLog(c *C) {
 		}
 	}
 }
+
+func (s *testLogSuite) TestModuleDebug(c *C) {
+	c.Assert(IsDebugModule(RegionHeartbeatModule), IsTrue)
+	c.Assert(IsDebugModule("unknown"), IsFalse)
+
+	EnableModuleDebug(RegionHeartbeatModule, time.Minute)
+	EnableModuleDebug(MergeCheckerModule, -time.Minute)
+	c.Assert(IsModuleDebugEnabled(RegionHeartbeatModule), IsTrue)
+	c.Assert(IsModuleDebugEnabled(MergeCheckerModule), IsFalse)
+	modules := GetDebugModules()
+	c.Assert(modules, HasLen, 1)
+	c.Assert(modules[0].Module, Equals, RegionHeartbeatModule)
+
+	DisableModuleDebug(RegionHeartbeatModule)
+	c.Assert(IsModuleDebugEnabled(RegionHeartbeatModule), IsFalse)
+	c.Assert(GetDebugModules(), HasLen, 0)
+}
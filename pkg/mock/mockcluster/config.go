@@ -128,6 +128,16 @@ func (mc *Cluster) SetLocationLabels(v []string) {
 	mc.updateReplicationConfig(func(r *config.ReplicationConfig) { r.LocationLabels = v })
 }
 
+// SetSoftAntiAffinityLabels updates the SoftAntiAffinityLabels configuration.
+func (mc *Cluster) SetSoftAntiAffinityLabels(v []string) {
+	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.SoftAntiAffinityLabels = v })
+}
+
+// SetStoreTierLabel updates the StoreTierLabel configuration.
+func (mc *Cluster) SetStoreTierLabel(v string) {
+	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.StoreTierLabel = v })
+}
+
 func (mc *Cluster) updateScheduleConfig(f func(*config.ScheduleConfig)) {
 	s := mc.GetScheduleConfig().Clone()
 	f(s)
@@ -30,6 +30,11 @@ func (alloc *IDAllocator) Alloc() (uint64, error) {
 	return atomic.AddUint64(&alloc.base, 1), nil
 }
 
+// AllocRange returns the first id of count new ids.
+func (alloc *IDAllocator) AllocRange(count uint64) (uint64, error) {
+	return atomic.AddUint64(&alloc.base, count) - count + 1, nil
+}
+
 // Rebase implements the IDAllocator interface.
 func (alloc *IDAllocator) Rebase() error {
 	return nil
@@ -0,0 +1,107 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protoext reads and writes the protobuf fields by hand, which the
+// messages owned by PD use to carry the fields not generated for them.
+package protoext
+
+import (
+	"encoding/binary"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pingcap/errors"
+)
+
+// The wire types of protobuf.
+const (
+	WireVarint  = 0
+	WireFixed64 = 1
+	WireBytes   = 2
+	WireFixed32 = 5
+)
+
+// DecodeField decodes the first field of the data, which is usually the
+// unknown fields of a message. It returns the field number, the wire type,
+// the value without the length prefix and the length of the whole field.
+func DecodeField(data []byte) (fieldNum, wireType uint64, value []byte, n int, err error) {
+	key, n := proto.DecodeVarint(data)
+	if n == 0 {
+		return 0, 0, nil, 0, errors.New("invalid field key")
+	}
+	fieldNum, wireType = key>>3, key&7
+	switch wireType {
+	case WireVarint:
+		_, m := proto.DecodeVarint(data[n:])
+		if m == 0 {
+			return 0, 0, nil, 0, errors.New("invalid varint")
+		}
+		return fieldNum, wireType, data[n : n+m], n + m, nil
+	case WireFixed64, WireFixed32:
+		size := 8
+		if wireType == WireFixed32 {
+			size = 4
+		}
+		if len(data) < n+size {
+			return 0, 0, nil, 0, errors.New("unexpected end of data")
+		}
+		return fieldNum, wireType, data[n : n+size], n + size, nil
+	case WireBytes:
+		size, m := proto.DecodeVarint(data[n:])
+		if m == 0 || uint64(len(data)-n-m) < size {
+			return 0, 0, nil, 0, errors.New("unexpected end of data")
+		}
+		start := n + m
+		return fieldNum, wireType, data[start : start+int(size)], start + int(size), nil
+	default:
+		return 0, 0, nil, 0, errors.Errorf("unsupported wire type %d", wireType)
+	}
+}
+
+// AppendVarintField appends a varint field to the data.
+func AppendVarintField(data []byte, fieldNum, v uint64) []byte {
+	data = append(data, proto.EncodeVarint(fieldNum<<3|WireVarint)...)
+	return append(data, proto.EncodeVarint(v)...)
+}
+
+// AppendBytesField appends a length-delimited field to the data.
+func AppendBytesField(data []byte, fieldNum uint64, v []byte) []byte {
+	data = append(data, proto.EncodeVarint(fieldNum<<3|WireBytes)...)
+	data = append(data, proto.EncodeVarint(uint64(len(v)))...)
+	return append(data, v...)
+}
+
+// AppendFixed64Field appends a fixed64 field to the data, which is also used
+// for the double fields with math.Float64bits.
+func AppendFixed64Field(data []byte, fieldNum, v uint64) []byte {
+	data = append(data, proto.EncodeVarint(fieldNum<<3|WireFixed64)...)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(data, buf[:]...)
+}
+
+// RemoveField returns a copy of the data without the fields of the given
+// field number. The data is returned as is if it cannot be decoded.
+func RemoveField(data []byte, fieldNum uint64) []byte {
+	var res []byte
+	for rest := data; len(rest) > 0; {
+		num, _, _, n, err := DecodeField(rest)
+		if err != nil {
+			return data
+		}
+		if num != fieldNum {
+			res = append(res, rest[:n]...)
+		}
+		rest = rest[n:]
+	}
+	return res
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoext
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testUnknownFieldSuite{})
+
+type testUnknownFieldSuite struct{}
+
+func (s *testUnknownFieldSuite) TestField(c *C) {
+	var data []byte
+	data = AppendVarintField(data, 1001, 300)
+	data = AppendBytesField(data, 1002, []byte("abc"))
+	data = AppendVarintField(data, 1001, 1)
+
+	fieldNum, wireType, value, n, err := DecodeField(data)
+	c.Assert(err, IsNil)
+	c.Assert(fieldNum, Equals, uint64(1001))
+	c.Assert(wireType, Equals, uint64(WireVarint))
+	c.Assert(value, DeepEquals, []byte{0xac, 0x02})
+	fieldNum, wireType, value, _, err = DecodeField(data[n:])
+	c.Assert(err, IsNil)
+	c.Assert(fieldNum, Equals, uint64(1002))
+	c.Assert(wireType, Equals, uint64(WireBytes))
+	c.Assert(value, DeepEquals, []byte("abc"))
+
+	c.Assert(RemoveField(data, 1001), DeepEquals, AppendBytesField(nil, 1002, []byte("abc")))
+	c.Assert(RemoveField(data, 1003), DeepEquals, data)
+	c.Assert(RemoveField(AppendVarintField(nil, 1001, 1), 1001), HasLen, 0)
+
+	fieldNum, wireType, value, n, err = DecodeField(AppendFixed64Field(nil, 2, 1))
+	c.Assert(err, IsNil)
+	c.Assert(fieldNum, Equals, uint64(2))
+	c.Assert(wireType, Equals, uint64(WireFixed64))
+	c.Assert(value, DeepEquals, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	c.Assert(n, Equals, 9)
+
+	truncated := AppendBytesField(nil, 1002, []byte("abc"))
+	_, _, _, _, err = DecodeField(truncated[:len(truncated)-1])
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/juju/ratelimit"
+)
+
+// Quota is the request quota of a caller.
+type Quota struct {
+	// QPS is the number of requests allowed per second. Zero means no limit.
+	QPS float64 `toml:"qps" json:"qps"`
+	// Burst is the number of requests allowed at once. If it is zero, the
+	// burst is the QPS rounded up.
+	Burst int64 `toml:"burst" json:"burst"`
+}
+
+// IsUnlimited returns true if the quota does not limit any request.
+func (q Quota) IsUnlimited() bool {
+	return q.QPS <= 0
+}
+
+func (q Quota) burst() int64 {
+	if q.Burst > 0 {
+		return q.Burst
+	}
+	return int64(math.Max(1, math.Ceil(q.QPS)))
+}
+
+type limit struct {
+	quota  Quota
+	bucket *ratelimit.Bucket
+}
+
+// Limiter limits the requests of callers by their names, each of which has
+// its own token bucket. The callers without a quota are not limited.
+type Limiter struct {
+	mu     sync.RWMutex
+	limits map[string]*limit
+}
+
+// NewLimiter creates a Limiter without any quota.
+func NewLimiter() *Limiter {
+	return &Limiter{limits: make(map[string]*limit)}
+}
+
+// Allow takes a token of the caller without blocking, and returns false if
+// the caller is out of quota.
+func (l *Limiter) Allow(caller string) bool {
+	l.mu.RLock()
+	lim, ok := l.limits[caller]
+	l.mu.RUnlock()
+	if !ok {
+		return true
+	}
+	return lim.bucket.TakeAvailable(1) == 1
+}
+
+// RetryAfter returns how long the caller should wait before its next request
+// is allowed. It is zero if the caller is not out of quota.
+func (l *Limiter) RetryAfter(caller string) time.Duration {
+	l.mu.RLock()
+	lim, ok := l.limits[caller]
+	l.mu.RUnlock()
+	if !ok || lim.bucket.Available() > 0 {
+		return 0
+	}
+	// A token is added every 1/QPS seconds.
+	return time.Duration(float64(time.Second) / lim.bucket.Rate())
+}
+
+// SetQuotas replaces the quotas of all callers. The token bucket of a caller
+// is kept if its quota is not changed, so updating the quota of one caller
+// does not refill the others.
+func (l *Limiter) SetQuotas(quotas map[string]Quota) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limits := make(map[string]*limit, len(quotas))
+	for caller, quota := range quotas {
+		if quota.IsUnlimited() {
+			continue
+		}
+		if old, ok := l.limits[caller]; ok && old.quota == quota {
+			limits[caller] = old
+			continue
+		}
+		limits[caller] = &limit{
+			quota:  quota,
+			bucket: ratelimit.NewBucketWithRate(quota.QPS, quota.burst()),
+		}
+	}
+	l.limits = limits
+}
+
+// GetQuotas returns the quotas of the limited callers.
+func (l *Limiter) GetQuotas() map[string]Quota {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	quotas := make(map[string]Quota, len(l.limits))
+	for caller, lim := range l.limits {
+		quotas[caller] = lim.quota
+	}
+	return quotas
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testLimiterSuite{})
+
+type testLimiterSuite struct{}
+
+func (s *testLimiterSuite) TestLimiter(c *C) {
+	l := NewLimiter()
+	c.Assert(l.Allow("br"), IsTrue)
+
+	l.SetQuotas(map[string]Quota{
+		"br":   {QPS: 0.001, Burst: 2},
+		"cdc":  {QPS: 0.001},
+		"tidb": {},
+	})
+	c.Assert(l.GetQuotas(), HasLen, 2)
+	c.Assert(l.Allow("br"), IsTrue)
+	c.Assert(l.Allow("br"), IsTrue)
+	c.Assert(l.Allow("br"), IsFalse)
+	c.Assert(l.RetryAfter("br"), Equals, 1000*time.Second)
+	c.Assert(l.Allow("cdc"), IsTrue)
+	c.Assert(l.Allow("cdc"), IsFalse)
+	for i := 0; i < 10; i++ {
+		c.Assert(l.Allow("tidb"), IsTrue)
+		c.Assert(l.Allow(""), IsTrue)
+		c.Assert(l.RetryAfter("tidb"), Equals, time.Duration(0))
+	}
+
+	// The bucket of br is kept since its quota is not changed.
+	l.SetQuotas(map[string]Quota{
+		"br":  {QPS: 0.001, Burst: 2},
+		"cdc": {QPS: 0.001, Burst: 3},
+	})
+	c.Assert(l.Allow("br"), IsFalse)
+	c.Assert(l.Allow("cdc"), IsTrue)
+	c.Assert(l.GetQuotas()["cdc"], Equals, Quota{QPS: 0.001, Burst: 3})
+
+	l.SetQuotas(nil)
+	c.Assert(l.Allow("br"), IsTrue)
+	c.Assert(l.GetQuotas(), HasLen, 0)
+}
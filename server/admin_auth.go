@@ -0,0 +1,80 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/server/config"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// adminAuthorizer authorizes the requests to the admin gRPC methods by the CN
+// of the client certificate or the token in metadata.
+type adminAuthorizer struct {
+	methods   map[string]struct{}
+	allowedCN map[string]struct{}
+	tokens    [][]byte
+}
+
+// newAdminAuthorizer returns nil if the authorization is disabled.
+func newAdminAuthorizer(cfg config.AdminAuthConfig) *adminAuthorizer {
+	if !cfg.Enable {
+		return nil
+	}
+	a := &adminAuthorizer{
+		methods:   make(map[string]struct{}, len(cfg.Methods)),
+		allowedCN: make(map[string]struct{}, len(cfg.AllowedCN)),
+	}
+	for _, method := range cfg.Methods {
+		a.methods[method] = struct{}{}
+	}
+	for _, cn := range cfg.AllowedCN {
+		a.allowedCN[cn] = struct{}{}
+	}
+	for _, token := range cfg.Tokens {
+		a.tokens = append(a.tokens, []byte(token))
+	}
+	return a
+}
+
+func (a *adminAuthorizer) authorize(ctx context.Context, method string) error {
+	if a == nil {
+		return nil
+	}
+	if _, ok := a.methods[method]; !ok {
+		return nil
+	}
+	if token := grpcutil.GetAdminToken(ctx); token != "" {
+		for _, t := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), t) == 1 {
+				return nil
+			}
+		}
+	}
+	cn, ok := grpcutil.GetPeerCommonName(ctx)
+	if ok {
+		if _, allowed := a.allowedCN[cn]; allowed {
+			return nil
+		}
+	}
+	adminAuthDeniedCounter.WithLabelValues(method).Inc()
+	log.Warn("admin request is not authorized", zap.String("method", method), zap.String("cn", cn))
+	return status.Errorf(codes.PermissionDenied, "method %s is not authorized", method)
+}
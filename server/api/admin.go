@@ -19,8 +19,13 @@ import (
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/pingcap/errcode"
+	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/core"
 	"github.com/unrolled/render"
 )
 
@@ -56,6 +61,71 @@ func (h *adminHandler) HandleDropCacheRegion(w http.ResponseWriter, r *http.Requ
 	h.rd.JSON(w, http.StatusOK, "The region is removed from server cache.")
 }
 
+// @Tags admin
+// @Summary Export the snapshot of all regions with their statistics.
+// @Produce octet-stream
+// @Success 200 {string} string "The region snapshot."
+// @Router /admin/regions/snapshot [get]
+func (h *adminHandler) ExportRegionSnapshot(w http.ResponseWriter, r *http.Request) {
+	rc := h.svr.GetRaftCluster()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", "attachment; filename=region-snapshot")
+	w.WriteHeader(http.StatusOK)
+	if err := core.WriteRegionSnapshot(w, rc.GetRegions()); err != nil {
+		log.Warn("failed to export the region snapshot", errs.ZapError(err))
+	}
+}
+
+// regionSnapshotImportBatchSize is the number of regions handled at a time
+// when importing a region snapshot.
+const regionSnapshotImportBatchSize = 1024
+
+// RegionSnapshotImportResult is the result of importing a region snapshot.
+type RegionSnapshotImportResult struct {
+	Imported int `json:"imported"`
+	// Skipped is the number of regions which are rejected by the cluster,
+	// such as the stale ones.
+	Skipped int `json:"skipped"`
+}
+
+// @Tags admin
+// @Summary Import a region snapshot as the region heartbeats.
+// @Accept octet-stream
+// @Param body body string true "The region snapshot"
+// @Produce json
+// @Success 200 {object} RegionSnapshotImportResult
+// @Failure 400 {string} string "The input is invalid."
+// @Router /admin/regions/snapshot [post]
+func (h *adminHandler) ImportRegionSnapshot(w http.ResponseWriter, r *http.Request) {
+	rc := h.svr.GetRaftCluster()
+	defer r.Body.Close()
+	var result RegionSnapshotImportResult
+	batch := make([]*core.RegionInfo, 0, regionSnapshotImportBatchSize)
+	flush := func() {
+		for _, err := range rc.HandleRegionHeartbeats(batch) {
+			if err != nil {
+				result.Skipped++
+			} else {
+				result.Imported++
+			}
+		}
+		batch = batch[:0]
+	}
+	err := core.ReadRegionSnapshot(r.Body, func(region *core.RegionInfo) error {
+		batch = append(batch, region)
+		if len(batch) >= regionSnapshotImportBatchSize {
+			flush()
+		}
+		return nil
+	})
+	flush()
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
 // FIXME: details of input json body params
 // @Tags admin
 // @Summary Reset the ts.
@@ -141,3 +211,108 @@ func (h *adminHandler) UpdateWaitAsyncTime(w http.ResponseWriter, r *http.Reques
 	cluster.GetReplicationMode().UpdateMemberWaitAsyncTime(memberID)
 	h.rd.JSON(w, http.StatusOK, nil)
 }
+
+type rollingRestartInput struct {
+	// StepTimeout is the max duration of each step, default is 10m.
+	StepTimeout typeutil.Duration `json:"step-timeout"`
+}
+
+// @Tags admin
+// @Summary Start a rolling restart of all PD members.
+// @Accept json
+// @Param body body rollingRestartInput true "json params"
+// @Produce json
+// @Success 200 {string} string "The rolling restart is started."
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 409 {string} string "A rolling restart is already running."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /admin/rolling-restart [post]
+func (h *adminHandler) StartRollingRestart(w http.ResponseWriter, r *http.Request) {
+	var input rollingRestartInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if err := h.svr.StartRollingRestart(input.StepTimeout.Duration); err != nil {
+		if errs.ErrRollingRestartRunning.Equal(err) {
+			h.rd.JSON(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The rolling restart is started.")
+}
+
+// @Tags admin
+// @Summary Get the progress of the last rolling restart.
+// @Produce json
+// @Success 200 {object} server.RollingRestartStatus
+// @Failure 404 {string} string "No rolling restart has been started."
+// @Router /admin/rolling-restart [get]
+func (h *adminHandler) GetRollingRestartStatus(w http.ResponseWriter, r *http.Request) {
+	status := h.svr.GetRollingRestartStatus()
+	if status == nil {
+		h.rd.JSON(w, http.StatusNotFound, "no rolling restart has been started")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, status)
+}
+
+// @Tags admin
+// @Summary Abort the running rolling restart.
+// @Produce json
+// @Success 200 {string} string "The rolling restart is aborted."
+// @Failure 400 {string} string "The rolling restart is not running."
+// @Router /admin/rolling-restart [delete]
+func (h *adminHandler) AbortRollingRestart(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.AbortRollingRestart(); err != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The rolling restart is aborted.")
+}
+
+// @Tags admin
+// @Summary Notify the rolling restart that a member has been restarted.
+// @Param name path string true "PD server name"
+// @Produce json
+// @Success 200 {string} string "The member is marked as restarted."
+// @Failure 400 {string} string "The member is not waiting for restart."
+// @Router /admin/rolling-restart/members/{name}/restarted [post]
+func (h *adminHandler) MarkRollingRestartMemberRestarted(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.MarkRollingRestartMemberRestarted(mux.Vars(r)["name"]); err != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The member is marked as restarted.")
+}
+
+// @Tags admin
+// @Summary Check the keys of the cluster in etcd for the unknown and orphan keys.
+// @Produce json
+// @Success 200 {object} server.KeyLayoutReport
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /admin/key-layout [get]
+func (h *adminHandler) CheckKeyLayout(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svr.CheckKeyLayout(false)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, report)
+}
+
+// @Tags admin
+// @Summary Remove the orphan keys of the cluster in etcd. The unknown keys are kept.
+// @Produce json
+// @Success 200 {object} server.KeyLayoutReport
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /admin/key-layout/orphans [delete]
+func (h *adminHandler) CleanupOrphanKeys(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svr.CheckKeyLayout(true)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, report)
+}
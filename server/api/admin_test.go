@@ -14,16 +14,21 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/job"
 )
 
 var _ = Suite(&testAdminSuite{})
@@ -158,3 +163,133 @@ func (s *testTSOSuite) TestResetTS(c *C) {
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Equals, "\"invalid tso value\"\n")
 }
+
+func (s *testAdminSuite) TestRollingRestart(c *C) {
+	url := s.urlPrefix + "/admin/rolling-restart"
+	var status server.RollingRestartStatus
+	c.Assert(readJSON(testDialClient, url, &status), NotNil)
+	// abort without a running job
+	res, err := doDelete(testDialClient, url)
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusBadRequest)
+
+	restarted := make(chan string, 1)
+	s.svr.SetRollingRestartHook(func(_ context.Context, m *pdpb.Member) error {
+		restarted <- m.GetName()
+		return nil
+	})
+	defer s.svr.SetRollingRestartHook(nil)
+	c.Assert(postJSON(testDialClient, url, []byte(`{"step-timeout":"10s"}`)), IsNil)
+	c.Assert(<-restarted, Equals, s.svr.Name())
+	// The member is not done until it rejoins after the restart.
+	c.Assert(readJSON(testDialClient, url, &status), IsNil)
+	c.Assert(status.State, Equals, server.RollingRestartRunning)
+	c.Assert(status.Members, HasLen, 1)
+	c.Assert(status.Members[0].Name, Equals, s.svr.Name())
+	c.Assert(status.Members[0].State, Equals, server.RollingRestartMemberRestartIssued)
+	c.Assert(status.Members[0].StartTimestamp, Equals, s.svr.StartTimestamp())
+	j := &job.Job{}
+	c.Assert(readJSON(testDialClient, fmt.Sprintf("%s/jobs/%d", s.urlPrefix, status.JobID), j), IsNil)
+	c.Assert(j.Type, Equals, server.RollingRestartJobType)
+
+	// The plan is persisted for the next leader.
+	value, err := s.svr.GetStorage().Load("rolling_restart")
+	c.Assert(err, IsNil)
+	persisted := &server.RollingRestartStatus{}
+	c.Assert(json.Unmarshal([]byte(value), persisted), IsNil)
+	c.Assert(persisted.JobID, Equals, status.JobID)
+	c.Assert(persisted.Members[0].State, Equals, server.RollingRestartMemberRestartIssued)
+
+	// the member is not waiting for restart
+	c.Assert(postJSON(testDialClient, url+"/members/"+s.svr.Name()+"/restarted", nil), NotNil)
+
+	res, err = doDelete(testDialClient, url)
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusOK)
+	c.Assert(readJSON(testDialClient, url, &status), IsNil)
+	c.Assert(status.State, Equals, server.RollingRestartAborted)
+}
+
+func (s *testAdminSuite) TestRegionSnapshot(c *C) {
+	url := fmt.Sprintf("%s/admin/regions/snapshot", s.urlPrefix)
+	regions := []*core.RegionInfo{
+		newTestRegionInfo(100, 1, nil, []byte("m"), core.SetRegionVersion(1000)),
+		newTestRegionInfo(101, 1, []byte("m"), nil, core.SetRegionVersion(1000)),
+		// The stale region is skipped.
+		newTestRegionInfo(102, 1, []byte("a"), []byte("b"), core.SetRegionVersion(1)),
+	}
+	var buf bytes.Buffer
+	c.Assert(core.WriteRegionSnapshot(&buf, regions), IsNil)
+	res, err := testDialClient.Post(url, "application/octet-stream", &buf)
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusOK)
+	var result RegionSnapshotImportResult
+	c.Assert(json.NewDecoder(res.Body).Decode(&result), IsNil)
+	res.Body.Close()
+	c.Assert(result, Equals, RegionSnapshotImportResult{Imported: 2, Skipped: 1})
+
+	res, err = testDialClient.Get(url)
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusOK)
+	var exported []*core.RegionInfo
+	err = core.ReadRegionSnapshot(res.Body, func(region *core.RegionInfo) error {
+		exported = append(exported, region)
+		return nil
+	})
+	res.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(exported, HasLen, 2)
+	sort.Slice(exported, func(i, j int) bool { return exported[i].GetID() < exported[j].GetID() })
+	for i, region := range exported {
+		c.Assert(region.GetMeta(), DeepEquals, regions[i].GetMeta())
+		c.Assert(region.GetApproximateSize(), Equals, regions[i].GetApproximateSize())
+		c.Assert(region.GetBytesWritten(), Equals, regions[i].GetBytesWritten())
+	}
+
+	// The invalid snapshot is rejected.
+	res, err = testDialClient.Post(url, "application/octet-stream", strings.NewReader("foo"))
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusBadRequest)
+	res.Body.Close()
+}
+
+func (s *testAdminSuite) TestKeyLayout(c *C) {
+	url := s.urlPrefix + "/admin/key-layout"
+	storage := s.svr.GetStorage()
+	orphanKey := fmt.Sprintf("schedule/store_drain/%020d", 100)
+	c.Assert(storage.Save(orphanKey, "{}"), IsNil)
+	c.Assert(storage.Save("foo/bar", "baz"), IsNil)
+	defer storage.Remove("foo/bar")
+	archivedKey := fmt.Sprintf("raft/archived_stores/%020d", 100)
+	c.Assert(storage.Save(archivedKey, "{}"), IsNil)
+	defer storage.Remove(archivedKey)
+
+	var report server.KeyLayoutReport
+	c.Assert(readJSON(testDialClient, url, &report), IsNil)
+	c.Assert(report.Valid, IsFalse)
+	c.Assert(report.LayoutVersion, Equals, server.KeyLayoutVersion)
+	c.Assert(report.ExpectedLayoutVersion, Equals, server.KeyLayoutVersion)
+	c.Assert(report.KeyCount > 0, IsTrue)
+	c.Assert(report.UnknownKeys, DeepEquals, []string{"foo/bar"})
+	c.Assert(report.OrphanKeys, DeepEquals, []*server.OrphanKey{{Key: orphanKey, Owner: "store 100"}})
+	c.Assert(report.RemovedKeys, HasLen, 0)
+
+	req, err := http.NewRequest(http.MethodDelete, url+"/orphans", nil)
+	c.Assert(err, IsNil)
+	res, err := testDialClient.Do(req)
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusOK)
+	report = server.KeyLayoutReport{}
+	c.Assert(json.NewDecoder(res.Body).Decode(&report), IsNil)
+	res.Body.Close()
+	c.Assert(report.RemovedKeys, DeepEquals, []string{orphanKey})
+	value, err := storage.Load(orphanKey)
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "")
+
+	// The unknown keys are kept.
+	report = server.KeyLayoutReport{}
+	c.Assert(readJSON(testDialClient, url, &report), IsNil)
+	c.Assert(report.UnknownKeys, DeepEquals, []string{"foo/bar"})
+	c.Assert(report.OrphanKeys, HasLen, 0)
+}
@@ -55,3 +55,14 @@ func (h *clusterHandler) GetClusterStatus(w http.ResponseWriter, r *http.Request
 	}
 	h.rd.JSON(w, http.StatusOK, status)
 }
+
+// @Tags cluster
+// @Summary Check the store labels against the location labels and the placement rules.
+// @Produce json
+// @Success 200 {object} cluster.TopologyReport
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /cluster/topology [get]
+func (h *clusterHandler) CheckTopology(w http.ResponseWriter, r *http.Request) {
+	rc := h.svr.GetRaftCluster()
+	h.rd.JSON(w, http.StatusOK, rc.CheckTopology())
+}
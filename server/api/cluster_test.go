@@ -64,6 +64,14 @@ func (s *testClusterSuite) TestCluster(c *C) {
 
 	c1.MaxPeerCount = 6
 	c.Assert(c1, DeepEquals, c2)
+
+	// The only store cannot hold all the replicas.
+	report := &cluster.TopologyReport{}
+	c.Assert(readJSON(testDialClient, url+"/topology", report), IsNil)
+	c.Assert(report.Valid, IsFalse)
+	c.Assert(report.Findings, HasLen, 1)
+	c.Assert(report.Findings[0].Kind, Equals, cluster.TopologyInsufficientStores)
+	c.Assert(report.Findings[0].Actual, Equals, 1)
 }
 
 func (s *testClusterSuite) testGetClusterStatus(c *C) {
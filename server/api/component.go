@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/errcode"
 	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/versioninfo"
 	"github.com/unrolled/render"
 )
 
@@ -40,7 +41,7 @@ func newComponentHandler(svr *server.Server, rd *render.Render) *componentHandle
 }
 
 // @Tags component
-// @Summary Register component address.
+// @Summary Register component address, and optionally the component version.
 // @Produce json
 // @Success 200 {string} string "The component address is registered successfully."
 // @Failure 400 {string} string "The input is invalid."
@@ -62,10 +63,23 @@ func (h *componentHandler) Register(w http.ResponseWriter, r *http.Request) {
 		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errors.New("not set addr")))
 		return
 	}
+	version, hasVersion := input["version"]
+	if hasVersion {
+		if _, err := versioninfo.ParseVersion(version); err != nil {
+			apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+			return
+		}
+	}
 	if err := rc.GetComponentManager().Register(component, addr); err != nil {
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if hasVersion {
+		if err := rc.GetComponentManager().SetComponentVersion(component, addr, version); err != nil {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
 	h.rd.JSON(w, http.StatusOK, "The component address is registered successfully.")
 }
 
@@ -24,10 +24,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/pingcap/errcode"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/logutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
@@ -71,6 +73,138 @@ func (h *confHandler) GetDefault(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, config)
 }
 
+// ConfigRevision is the etcd revision of the persisted config.
+type ConfigRevision struct {
+	Revision int64 `json:"revision"`
+}
+
+// @Tags config
+// @Summary Get the etcd revision at which the config was persisted last time.
+// @Produce json
+// @Success 200 {object} ConfigRevision
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /config/revision [get]
+func (h *confHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	revision, err := h.svr.GetConfigRevision()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &ConfigRevision{Revision: revision})
+}
+
+// @Tags config
+// @Summary List the latest versions of the config with who changes what, newest first.
+// @Param limit query integer false "The maximum number of the versions."
+// @Produce json
+// @Success 200 {array} confighistory.Entry
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /config/history [get]
+func (h *confHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	entries, err := h.svr.GetConfigHistory(limit)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, entries)
+}
+
+// @Tags config
+// @Summary Get a version of the config with its content.
+// @Param version path integer true "The version of the config."
+// @Produce json
+// @Success 200 {object} confighistory.Entry
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The version is not found."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /config/history/{version} [get]
+func (h *confHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.ParseUint(mux.Vars(r)["version"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	entry, err := h.svr.GetConfigVersion(version)
+	if err != nil {
+		h.rd.JSON(w, configVersionErrorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, entry)
+}
+
+// @Tags config
+// @Summary Roll the config back to a version. The schedulers and the cluster version are not rolled back.
+// @Param version path integer true "The version of the config."
+// @Produce json
+// @Success 200 {string} string "The config is rolled back."
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The version is not found."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /config/rollback/{version} [post]
+func (h *confHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.ParseUint(mux.Vars(r)["version"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.svr.RollbackConfig(version, configOperator(r)); err != nil {
+		h.rd.JSON(w, configVersionErrorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The config is rolled back.")
+}
+
+// @Tags config
+// @Summary List the active ttl configs with their remaining ttl and origin.
+// @Produce json
+// @Success 200 {array} config.TTLConfig
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /config/ttl [get]
+func (h *confHandler) GetTTL(w http.ResponseWriter, r *http.Request) {
+	configs, err := h.svr.GetTTLConfigs()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, configs)
+}
+
+// @Tags config
+// @Summary Cancel an active ttl config before it expires.
+// @Param key path string true "The key of the ttl config."
+// @Produce json
+// @Success 200 {string} string "The ttl config is canceled."
+// @Failure 404 {string} string "The ttl config is not found."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /config/ttl/{key} [delete]
+func (h *confHandler) DeleteTTL(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.DeleteTTLConfig(mux.Vars(r)["key"]); err != nil {
+		status := http.StatusInternalServerError
+		if errs.ErrTTLConfigNotFound.Equal(errors.Cause(err)) {
+			status = http.StatusNotFound
+		}
+		h.rd.JSON(w, status, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The ttl config is canceled.")
+}
+
+func configVersionErrorStatus(err error) int {
+	if errs.ErrConfigVersionNotFound.Equal(errors.Cause(err)) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
 // FIXME: details of input json body params
 // @Tags config
 // @Summary Update a config item.
@@ -109,7 +243,7 @@ func (h *confHandler) Post(w http.ResponseWriter, r *http.Request) {
 
 	// if ttlSecond defined, we will apply if to temp configuration.
 	if ttls > 0 {
-		err := h.svr.SaveTTLConfig(conf, time.Duration(ttls)*time.Second)
+		err := h.svr.SaveTTLConfig(conf, time.Duration(ttls)*time.Second, ttlConfigOrigin(r))
 		if err != nil {
 			h.rd.JSON(w, http.StatusBadRequest, err.Error())
 			return
@@ -16,13 +16,16 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/tikv/pd/pkg/ratelimit"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/confighistory"
 	"github.com/tikv/pd/server/versioninfo"
 )
 
@@ -163,6 +166,98 @@ func (s *testConfigSuite) TestConfigSchedule(c *C) {
 	c.Assert(*sc, DeepEquals, *sc1)
 }
 
+func (s *testConfigSuite) TestConfigRevision(c *C) {
+	revisionAddr := fmt.Sprintf("%s/config/revision", s.urlPrefix)
+	rev := &ConfigRevision{}
+	c.Assert(readJSON(testDialClient, revisionAddr, rev), IsNil)
+
+	addr := fmt.Sprintf("%s/config/schedule", s.urlPrefix)
+	sc := &config.ScheduleConfig{}
+	c.Assert(readJSON(testDialClient, addr, sc), IsNil)
+	sc.LeaderScheduleLimit++
+	postData, err := json.Marshal(sc)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, addr, postData), IsNil)
+
+	rev1 := &ConfigRevision{}
+	c.Assert(readJSON(testDialClient, revisionAddr, rev1), IsNil)
+	c.Assert(rev1.Revision > rev.Revision, IsTrue)
+}
+
+func (s *testConfigSuite) TestConfigHistory(c *C) {
+	addr := fmt.Sprintf("%s/config/schedule", s.urlPrefix)
+	sc := &config.ScheduleConfig{}
+	c.Assert(readJSON(testDialClient, addr, sc), IsNil)
+	oldLimit := sc.LeaderScheduleLimit
+	sc.LeaderScheduleLimit = oldLimit + 10
+	postData, err := json.Marshal(sc)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, addr, postData), IsNil)
+
+	historyAddr := fmt.Sprintf("%s/config/history", s.urlPrefix)
+	var entries []*confighistory.Entry
+	c.Assert(readJSON(testDialClient, historyAddr+"?limit=2", &entries), IsNil)
+	c.Assert(entries, HasLen, 2)
+	latest := entries[0]
+	c.Assert(latest.Version, Equals, entries[1].Version+1)
+	c.Assert(latest.Operator, Not(Equals), "")
+	c.Assert(latest.Changes, HasLen, 1)
+	c.Assert(latest.Changes[0].Item, Equals, "schedule.leader-schedule-limit")
+	c.Assert(latest.Changes[0].New, Equals, float64(oldLimit+10))
+
+	// Roll back to the version before the change.
+	rollbackAddr := fmt.Sprintf("%s/config/rollback/%d", s.urlPrefix, entries[1].Version)
+	c.Assert(postJSON(testDialClient, rollbackAddr, nil), IsNil)
+	c.Assert(readJSON(testDialClient, addr, sc), IsNil)
+	c.Assert(sc.LeaderScheduleLimit, Equals, oldLimit)
+	var rollbackEntries []*confighistory.Entry
+	c.Assert(readJSON(testDialClient, historyAddr+"?limit=1", &rollbackEntries), IsNil)
+	c.Assert(rollbackEntries[0].Version, Equals, latest.Version+1)
+	c.Assert(rollbackEntries[0].RollbackTo, Equals, latest.Version-1)
+	c.Assert(rollbackEntries[0].Operator, Not(Equals), "")
+	c.Assert(rollbackEntries[0].Changes, HasLen, 1)
+	entry := &confighistory.Entry{}
+	c.Assert(readJSON(testDialClient, fmt.Sprintf("%s/%d", historyAddr, latest.Version), entry), IsNil)
+	c.Assert(entry.Config, NotNil)
+
+	err = postJSON(testDialClient, fmt.Sprintf("%s/config/rollback/%d", s.urlPrefix, latest.Version+100), nil)
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(err.Error(), "not found"), IsTrue)
+}
+
+func (s *testConfigSuite) TestConfigCallerRateLimits(c *C) {
+	addr := fmt.Sprintf("%s/config", s.urlPrefix)
+	postData, err := json.Marshal(map[string]interface{}{
+		"pd-server.caller-rate-limits": map[string]ratelimit.Quota{
+			"br":  {QPS: 10, Burst: 20},
+			"cdc": {QPS: 100},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, addr, postData), IsNil)
+	cfg := &config.Config{}
+	c.Assert(readJSON(testDialClient, addr, cfg), IsNil)
+	c.Assert(cfg.PDServerCfg.CallerRateLimits, DeepEquals, map[string]ratelimit.Quota{
+		"br":  {QPS: 10, Burst: 20},
+		"cdc": {QPS: 100},
+	})
+
+	// An invalid quota is rejected.
+	postData, err = json.Marshal(map[string]interface{}{
+		"pd-server.caller-rate-limits": map[string]ratelimit.Quota{"br": {QPS: -1}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, addr, postData), NotNil)
+
+	// Reset the quotas so that the other tests are not affected.
+	postData, err = json.Marshal(map[string]interface{}{"pd-server.caller-rate-limits": nil})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, addr, postData), IsNil)
+	cfg = &config.Config{}
+	c.Assert(readJSON(testDialClient, addr, cfg), IsNil)
+	c.Assert(cfg.PDServerCfg.CallerRateLimits, IsNil)
+}
+
 func (s *testConfigSuite) TestConfigReplication(c *C) {
 	addr := fmt.Sprintf("%s/config/replicate", s.urlPrefix)
 	rc := &config.ReplicationConfig{}
@@ -324,3 +419,37 @@ func (s *testConfigSuite) TestConfigTTL(c *C) {
 	c.Assert(err, Not(IsNil))
 	c.Assert(err.Error(), Equals, "\"unsupported ttl config schedule.invalid-ttl-config\"\n")
 }
+
+func (s *testConfigSuite) TestConfigTTLIntrospection(c *C) {
+	addr := fmt.Sprintf("%s/config?ttlSecond=60", s.urlPrefix)
+	postData, err := json.Marshal(map[string]interface{}{"schedule.max-snapshot-count": 999})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, addr, postData), IsNil)
+	c.Assert(s.svr.GetPersistOptions().GetMaxSnapshotCount(), Equals, uint64(999))
+
+	// The ttl configs set by the other tests may not expire yet.
+	findTTLConfig := func(key string) *config.TTLConfig {
+		var configs []*config.TTLConfig
+		c.Assert(readJSON(testDialClient, s.urlPrefix+"/config/ttl", &configs), IsNil)
+		for _, cfg := range configs {
+			if cfg.Key == key {
+				return cfg
+			}
+		}
+		return nil
+	}
+	cfg := findTTLConfig("schedule.max-snapshot-count")
+	c.Assert(cfg, NotNil)
+	c.Assert(cfg.Value, Equals, "999")
+	c.Assert(cfg.TTL > 0 && cfg.TTL <= 60, IsTrue)
+	c.Assert(strings.HasPrefix(cfg.Origin, "POST /pd/api/v1/config by "), IsTrue)
+
+	// Cancel the ttl config before it expires.
+	url := s.urlPrefix + "/config/ttl/schedule.max-snapshot-count"
+	code, _ := requestStatusBody(c, testDialClient, http.MethodDelete, url)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(s.svr.GetPersistOptions().GetMaxSnapshotCount(), Not(Equals), uint64(999))
+	c.Assert(findTTLConfig("schedule.max-snapshot-count"), IsNil)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodDelete, url)
+	c.Assert(code, Equals, http.StatusNotFound)
+}
@@ -0,0 +1,42 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+type deprecatedRPCHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newDeprecatedRPCHandler(svr *server.Server, rd *render.Render) *deprecatedRPCHandler {
+	return &deprecatedRPCHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags deprecated-rpc
+// @Summary List the callers of the deprecated RPCs handled by this member since it is started. The callers are kept in memory per member, up to 1024 callers.
+// @Produce json
+// @Success 200 {array} server.DeprecatedRPCCaller
+// @Router /deprecated-rpc/callers [get]
+func (h *deprecatedRPCHandler) GetCallers(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetDeprecatedRPCCallers())
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/server"
+)
+
+var _ = Suite(&testDeprecatedRPCSuite{})
+
+type testDeprecatedRPCSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testDeprecatedRPCSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testDeprecatedRPCSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testDeprecatedRPCSuite) TestGetCallers(c *C) {
+	grpcPDClient := testutil.MustNewGrpcClient(c, s.svr.GetAddr())
+	header := testutil.NewRequestHeader(s.svr.ClusterID())
+	ctx := grpcutil.BuildCallerComponentContext(context.Background(), "br")
+	for i := 0; i < 2; i++ {
+		_, err := grpcPDClient.ScanRegions(ctx, &pdpb.ScanRegionsRequest{Header: header})
+		c.Assert(err, IsNil)
+	}
+	// Only the single-id form of ScatterRegion is deprecated. The calls are
+	// counted even if the region cannot be scattered.
+	grpcPDClient.ScatterRegion(context.Background(), &pdpb.ScatterRegionRequest{Header: header, RegionsId: []uint64{region.GetId()}})
+	grpcPDClient.ScatterRegion(context.Background(), &pdpb.ScatterRegionRequest{Header: header, RegionId: region.GetId()})
+
+	var callers []*server.DeprecatedRPCCaller
+	err := readJSON(testDialClient, s.urlPrefix+"/deprecated-rpc/callers", &callers)
+	c.Assert(err, IsNil)
+	c.Assert(callers, HasLen, 2)
+	c.Assert(callers[0].Method, Equals, "ScanRegions")
+	c.Assert(callers[0].Component, Equals, "br")
+	c.Assert(callers[0].Address, Equals, "127.0.0.1")
+	c.Assert(callers[0].Count, Equals, uint64(2))
+	c.Assert(callers[1].Method, Equals, "ScatterRegion")
+	c.Assert(callers[1].Component, Equals, "unknown")
+	c.Assert(callers[1].Count, Equals, uint64(1))
+}
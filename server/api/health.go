@@ -70,3 +70,11 @@ func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	h.rd.JSON(w, http.StatusOK, healths)
 }
+
+// @Summary Health of the dependencies of the PD server, such as etcd and the allocators.
+// @Produce json
+// @Success 200 {array} server.DependencyStatus
+// @Router /health/dependencies [get]
+func (h *healthHandler) GetDependencies(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetDependencyHealth())
+}
@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	. "github.com/pingcap/check"
+	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
 )
@@ -69,3 +70,23 @@ func (s *testHealthAPISuite) TestHealthSlice(c *C) {
 	c.Assert(err, IsNil)
 	checkSliceResponse(c, buf, cfgs, follow.GetConfig().Name)
 }
+
+func (s *testHealthAPISuite) TestDependencyHealth(c *C) {
+	svr, clean := mustNewServer(c)
+	defer clean()
+	mustWaitLeader(c, []*server.Server{svr})
+
+	addr := svr.GetAddr() + apiPrefix + "/api/v1/health/dependencies"
+	testutil.WaitUntil(c, func(c *C) bool {
+		var statuses []server.DependencyStatus
+		c.Assert(readJSON(testDialClient, addr, &statuses), IsNil)
+		c.Assert(statuses, HasLen, 4)
+		for _, status := range statuses {
+			if status.Status != server.DependencyHealthy {
+				return false
+			}
+			c.Assert(status.LastError, Equals, "")
+		}
+		return true
+	})
+}
@@ -15,8 +15,11 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/hothistory"
 	"github.com/tikv/pd/server/statistics"
 	"github.com/unrolled/render"
 )
@@ -34,6 +37,10 @@ type HotStoreStats struct {
 	KeysReadStats   map[uint64]float64 `json:"keys-read-rate,omitempty"`
 }
 
+// hotStatsUpdateTimeHeader is set in the responses of a follower to tell the
+// update time of the hot-region statistics replicated from the leader.
+const hotStatsUpdateTimeHeader = "PD-Hot-Stats-Update-Time"
+
 func newHotStatusHandler(handler *server.Handler, rd *render.Render) *hotStatusHandler {
 	return &hotStatusHandler{
 		Handler: handler,
@@ -45,17 +52,123 @@ func newHotStatusHandler(handler *server.Handler, rd *render.Render) *hotStatusH
 // @Summary List the hot write regions.
 // @Produce json
 // @Success 200 {object} statistics.StoreHotPeersInfos
+// @Header 200 {string} PD-Hot-Stats-Update-Time "The update time of the statistics replicated from the leader if a follower handles the request."
+// @Failure 503 {string} string "The follower has no statistics younger than hot-stats-max-staleness."
 // @Router /hotspot/regions/write [get]
 func (h *hotStatusHandler) GetHotWriteRegions(w http.ResponseWriter, r *http.Request) {
+	snapshot, ok := h.getFollowerHotStats(w)
+	if !ok {
+		return
+	}
+	if snapshot != nil {
+		h.rd.JSON(w, http.StatusOK, snapshot.WriteRegions)
+		return
+	}
 	h.rd.JSON(w, http.StatusOK, h.Handler.GetHotWriteRegions())
 }
 
+// getFollowerHotStats returns the statistics replicated from the leader if a
+// follower handles the request, and sets their update time in the header. It
+// responds with 503 and returns false if the follower has no fresh statistics
+// to serve.
+func (h *hotStatusHandler) getFollowerHotStats(w http.ResponseWriter) (*server.HotStatsSnapshot, bool) {
+	snapshot, err := h.GetFollowerHotStats()
+	if err != nil {
+		h.rd.JSON(w, http.StatusServiceUnavailable, err.Error())
+		return nil, false
+	}
+	if snapshot != nil {
+		w.Header().Set(hotStatsUpdateTimeHeader, snapshot.UpdateTime.Format(time.RFC3339))
+	}
+	return snapshot, true
+}
+
+// @Tags hotspot
+// @Summary Get the hot-region statistics of the cluster, which are replicated to the followers.
+// @Produce json
+// @Success 200 {object} server.HotStatsSnapshot
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /hotspot/snapshot [get]
+func (h *hotStatusHandler) GetHotStatsSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := h.Handler.GetHotStatsSnapshot()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, snapshot)
+}
+
+const (
+	defaultHotRegionHistoryLimit = 1000
+	maxHotRegionHistoryLimit     = 10000
+)
+
+// @Tags hotspot
+// @Summary List the hot peers persisted in the past, whose regions overlap the key range.
+// @Param start query integer false "The start of the time range in unix seconds."
+// @Param end query integer false "The end of the time range in unix seconds, exclusive."
+// @Param start_key query string false "The start of the key range."
+// @Param end_key query string false "The end of the key range, exclusive."
+// @Param type query string false "The type of the hot regions." Enums(read, write)
+// @Param limit query integer false "The maximum number of the records." default(1000)
+// @Produce json
+// @Success 200 {array} hothistory.Record
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /hotspot/regions/history [get]
+func (h *hotStatusHandler) GetHotRegionHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var start, end time.Time
+	limit := defaultHotRegionHistoryLimit
+	var err error
+	for name, t := range map[string]*time.Time{"start": &start, "end": &end} {
+		if v := query.Get(name); v != "" {
+			sec, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				h.rd.JSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			*t = time.Unix(sec, 0)
+		}
+	}
+	hotType := query.Get("type")
+	if hotType != "" && hotType != hothistory.ReadType && hotType != hothistory.WriteType {
+		h.rd.JSON(w, http.StatusBadRequest, "type should be read or write")
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if limit <= 0 || limit > maxHotRegionHistoryLimit {
+		limit = maxHotRegionHistoryLimit
+	}
+	records, err := h.Handler.GetHotRegionHistory(start, end, []byte(query.Get("start_key")), []byte(query.Get("end_key")), hotType, limit)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, records)
+}
+
 // @Tags hotspot
 // @Summary List the hot read regions.
 // @Produce json
 // @Success 200 {object} statistics.StoreHotPeersInfos
+// @Header 200 {string} PD-Hot-Stats-Update-Time "The update time of the statistics replicated from the leader if a follower handles the request."
+// @Failure 503 {string} string "The follower has no statistics younger than hot-stats-max-staleness."
 // @Router /hotspot/regions/read [get]
 func (h *hotStatusHandler) GetHotReadRegions(w http.ResponseWriter, r *http.Request) {
+	snapshot, ok := h.getFollowerHotStats(w)
+	if !ok {
+		return
+	}
+	if snapshot != nil {
+		h.rd.JSON(w, http.StatusOK, snapshot.ReadRegions)
+		return
+	}
 	h.rd.JSON(w, http.StatusOK, h.Handler.GetHotReadRegions())
 }
 
@@ -63,6 +176,8 @@ func (h *hotStatusHandler) GetHotReadRegions(w http.ResponseWriter, r *http.Requ
 // @Summary List the hot stores.
 // @Produce json
 // @Success 200 {object} HotStoreStats
+// @Header 200 {string} PD-Hot-Stats-Update-Time "The update time of the statistics replicated from the leader if a follower handles the request."
+// @Failure 503 {string} string "The follower has no statistics younger than hot-stats-max-staleness."
 // @Router /hotspot/stores [get]
 func (h *hotStatusHandler) GetHotStores(w http.ResponseWriter, r *http.Request) {
 	stats := HotStoreStats{
@@ -71,7 +186,15 @@ func (h *hotStatusHandler) GetHotStores(w http.ResponseWriter, r *http.Request)
 		KeysWriteStats:  make(map[uint64]float64),
 		KeysReadStats:   make(map[uint64]float64),
 	}
-	for id, loads := range h.GetStoresLoads() {
+	snapshot, ok := h.getFollowerHotStats(w)
+	if !ok {
+		return
+	}
+	storesLoads := h.GetStoresLoads()
+	if snapshot != nil {
+		storesLoads = snapshot.StoreLoads
+	}
+	for id, loads := range storesLoads {
 		stats.BytesWriteStats[id] = loads[statistics.StoreWriteBytes]
 		stats.BytesReadStats[id] = loads[statistics.StoreReadBytes]
 		stats.KeysWriteStats[id] = loads[statistics.StoreWriteKeys]
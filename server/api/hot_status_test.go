@@ -18,6 +18,7 @@ import (
 
 	. "github.com/pingcap/check"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/hothistory"
 	_ "github.com/tikv/pd/server/schedulers"
 )
 
@@ -48,3 +49,14 @@ func (s testHotStatusSuite) TestGetHotStore(c *C) {
 	err := readJSON(testDialClient, s.urlPrefix+"/stores", &stat)
 	c.Assert(err, IsNil)
 }
+
+func (s testHotStatusSuite) TestGetHotRegionHistory(c *C) {
+	var records []*hothistory.Record
+	err := readJSON(testDialClient, s.urlPrefix+"/regions/history?type=write&start_key=a&end_key=b", &records)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 0)
+	err = readJSON(testDialClient, s.urlPrefix+"/regions/history?type=unknown", &records)
+	c.Assert(err, NotNil)
+	err = readJSON(testDialClient, s.urlPrefix+"/regions/history?start=yesterday", &records)
+	c.Assert(err, NotNil)
+}
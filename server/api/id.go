@@ -0,0 +1,48 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+type idHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newIDHandler(svr *server.Server, rd *render.Render) *idHandler {
+	return &idHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags id
+// @Summary List the id ranges leased to clients, whose leases have not expired.
+// @Produce json
+// @Success 200 {array} id.Reservation
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /id/reservations [get]
+func (h *idHandler) GetReservations(w http.ResponseWriter, r *http.Request) {
+	reservations, err := h.svr.GetIDReservations()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, reservations)
+}
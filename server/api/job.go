@@ -0,0 +1,94 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/errcode"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+type jobHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newJobHandler(svr *server.Server, rd *render.Render) *jobHandler {
+	return &jobHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags job
+// @Summary List the long-running jobs.
+// @Param type query string false "Only list the jobs of the type"
+// @Produce json
+// @Success 200 {array} job.Job
+// @Router /jobs [get]
+func (h *jobHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetJobManager().List(r.URL.Query().Get("type")))
+}
+
+// @Tags job
+// @Summary Get a long-running job.
+// @Param id path integer true "Job Id"
+// @Produce json
+// @Success 200 {object} job.Job
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The job does not exist."
+// @Router /jobs/{id} [get]
+func (h *jobHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	j, err := h.svr.GetJobManager().Get(id)
+	if err != nil {
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, j)
+}
+
+// @Tags job
+// @Summary Cancel a running job.
+// @Param id path integer true "Job Id"
+// @Produce json
+// @Success 200 {string} string "The job is cancelled."
+// @Failure 400 {string} string "The job is not running."
+// @Failure 404 {string} string "The job does not exist."
+// @Router /jobs/{id} [delete]
+func (h *jobHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	if err := h.svr.GetJobManager().Cancel(id); err != nil {
+		if errs.ErrJobNotFound.Equal(err) {
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The job is cancelled.")
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/job"
+)
+
+var _ = Suite(&testJobSuite{})
+
+type testJobSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testJobSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testJobSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testJobSuite) TestJobs(c *C) {
+	_, err := doDelete(testDialClient, s.urlPrefix+"/stores/remove-tombstone")
+	c.Assert(err, IsNil)
+
+	var jobs []*job.Job
+	err = readJSON(testDialClient, s.urlPrefix+"/jobs?type="+server.TombstoneCleanupJobType, &jobs)
+	c.Assert(err, IsNil)
+	c.Assert(jobs, HasLen, 1)
+	c.Assert(jobs[0].State, Equals, job.StateFinished)
+
+	j := &job.Job{}
+	url := fmt.Sprintf("%s/jobs/%d", s.urlPrefix, jobs[0].ID)
+	c.Assert(readJSON(testDialClient, url, j), IsNil)
+	c.Assert(j.Type, Equals, server.TombstoneCleanupJobType)
+
+	// The job is done.
+	res, err := doDelete(testDialClient, url)
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusBadRequest)
+	status, _ := requestStatusBody(c, testDialClient, http.MethodGet, fmt.Sprintf("%s/jobs/%d", s.urlPrefix, j.ID+1000))
+	c.Assert(status, Equals, http.StatusNotFound)
+	res, err = doDelete(testDialClient, fmt.Sprintf("%s/jobs/%d", s.urlPrefix, j.ID+1000))
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusNotFound)
+}
@@ -0,0 +1,246 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/pingcap/errcode"
+	"github.com/pingcap/errors"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/filter"
+	"github.com/unrolled/render"
+)
+
+const leaderTransferSimulationScope = "leader-transfer-simulation"
+
+// LeaderTransferSimulationInput is a proposed leader transfer, which moves all
+// leaders out of the source stores.
+type LeaderTransferSimulationInput struct {
+	SourceStoreIDs []uint64 `json:"source_store_ids"`
+	// TargetStoreIDs limits the stores to receive the leaders. All the other
+	// stores are used if it is empty.
+	TargetStoreIDs []uint64 `json:"target_store_ids,omitempty"`
+	// The thresholds of a target store after the transfer. Zero means no
+	// limit.
+	MaxLeaderCount int     `json:"max_leader_count,omitempty"`
+	MaxReadQPS     float64 `json:"max_read_qps,omitempty"`
+	MaxWriteQPS    float64 `json:"max_write_qps,omitempty"`
+}
+
+// LeaderLoad is the load of the leaders. The QPS is estimated by the keys read
+// or written per second.
+type LeaderLoad struct {
+	LeaderCount   int     `json:"leader_count"`
+	ReadQPS       float64 `json:"read_qps"`
+	WriteQPS      float64 `json:"write_qps"`
+	ReadByteRate  float64 `json:"read_byte_rate"`
+	WriteByteRate float64 `json:"write_byte_rate"`
+}
+
+func (l *LeaderLoad) add(o *LeaderLoad) {
+	l.LeaderCount += o.LeaderCount
+	l.ReadQPS += o.ReadQPS
+	l.WriteQPS += o.WriteQPS
+	l.ReadByteRate += o.ReadByteRate
+	l.WriteByteRate += o.WriteByteRate
+}
+
+// LeaderTransferImpact is the impact of the leader transfer on a target store.
+type LeaderTransferImpact struct {
+	StoreID uint64     `json:"store_id"`
+	Current LeaderLoad `json:"current"`
+	Delta   LeaderLoad `json:"delta"`
+	// Exceeded is the thresholds the store exceeds after the transfer.
+	Exceeded []string `json:"exceeded,omitempty"`
+}
+
+// LeaderTransferSimulation is the estimated result of a leader transfer.
+type LeaderTransferSimulation struct {
+	LeaderCount int `json:"leader_count"`
+	// UnmovableRegions is the regions whose leaders cannot be moved to any
+	// target store.
+	UnmovableRegions []uint64                `json:"unmovable_regions"`
+	Targets          []*LeaderTransferImpact `json:"targets"`
+	Exceeded         bool                    `json:"exceeded"`
+}
+
+type leaderTransferHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newLeaderTransferHandler(svr *server.Server, rd *render.Render) *leaderTransferHandler {
+	return &leaderTransferHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags leader_transfer
+// @Summary Estimate the impact of moving all leaders out of some stores, without scheduling anything.
+// @Accept json
+// @Param body body LeaderTransferSimulationInput true "The proposed leader transfer"
+// @Produce json
+// @Success 200 {object} LeaderTransferSimulation
+// @Failure 400 {string} string "The input is invalid."
+// @Router /leader-transfer/simulate [post]
+func (h *leaderTransferHandler) Simulate(w http.ResponseWriter, r *http.Request) {
+	var input LeaderTransferSimulationInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	rc := h.svr.GetRaftCluster()
+	if len(input.SourceStoreIDs) == 0 {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errors.New("source_store_ids is empty")))
+		return
+	}
+	for _, id := range append(input.SourceStoreIDs, input.TargetStoreIDs...) {
+		if rc.GetStore(id) == nil {
+			apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(server.ErrStoreNotFound(id)))
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, simulateLeaderTransfer(rc, &input))
+}
+
+// simulateLeaderTransfer moves the leaders of the source stores one by one to
+// the follower which has the least leaders among the available targets, and
+// sums up the load of the moved leaders by the target stores.
+func simulateLeaderTransfer(rc *cluster.RaftCluster, input *LeaderTransferSimulationInput) *LeaderTransferSimulation {
+	sources := make(map[uint64]struct{}, len(input.SourceStoreIDs))
+	for _, id := range input.SourceStoreIDs {
+		sources[id] = struct{}{}
+	}
+	var targets map[uint64]struct{}
+	if len(input.TargetStoreIDs) > 0 {
+		targets = make(map[uint64]struct{}, len(input.TargetStoreIDs))
+		for _, id := range input.TargetStoreIDs {
+			targets[id] = struct{}{}
+		}
+	}
+
+	opts := rc.GetOpts()
+	stateFilter := &filter.StoreStateFilter{ActionScope: leaderTransferSimulationScope, TransferLeader: true}
+	isTarget := func(store *core.StoreInfo) bool {
+		if _, ok := sources[store.GetID()]; ok {
+			return false
+		}
+		if targets != nil {
+			if _, ok := targets[store.GetID()]; !ok {
+				return false
+			}
+		}
+		return stateFilter.Target(opts, store)
+	}
+
+	impacts := make(map[uint64]*LeaderTransferImpact)
+	getImpact := func(store *core.StoreInfo) *LeaderTransferImpact {
+		if impact, ok := impacts[store.GetID()]; ok {
+			return impact
+		}
+		impact := &LeaderTransferImpact{
+			StoreID: store.GetID(),
+			Current: storeLeaderLoad(rc, store.GetID()),
+		}
+		impacts[store.GetID()] = impact
+		return impact
+	}
+	for id := range targets {
+		if store := rc.GetStore(id); isTarget(store) {
+			getImpact(store)
+		}
+	}
+
+	result := &LeaderTransferSimulation{UnmovableRegions: []uint64{}, Targets: []*LeaderTransferImpact{}}
+	for _, id := range input.SourceStoreIDs {
+		for _, region := range rc.GetStoreRegions(id) {
+			if region.GetLeader().GetStoreId() != id {
+				continue
+			}
+			result.LeaderCount++
+			var best *LeaderTransferImpact
+			for _, store := range rc.GetFollowerStores(region) {
+				if !isTarget(store) {
+					continue
+				}
+				impact := getImpact(store)
+				if best == nil || impact.Current.LeaderCount+impact.Delta.LeaderCount < best.Current.LeaderCount+best.Delta.LeaderCount ||
+					(impact.Current.LeaderCount+impact.Delta.LeaderCount == best.Current.LeaderCount+best.Delta.LeaderCount && impact.StoreID < best.StoreID) {
+					best = impact
+				}
+			}
+			if best == nil {
+				result.UnmovableRegions = append(result.UnmovableRegions, region.GetID())
+				continue
+			}
+			best.Delta.add(regionLeaderLoad(region))
+		}
+	}
+
+	for _, impact := range impacts {
+		after := impact.Current
+		after.add(&impact.Delta)
+		if input.MaxLeaderCount > 0 && after.LeaderCount > input.MaxLeaderCount {
+			impact.Exceeded = append(impact.Exceeded, "max_leader_count")
+		}
+		if input.MaxReadQPS > 0 && after.ReadQPS > input.MaxReadQPS {
+			impact.Exceeded = append(impact.Exceeded, "max_read_qps")
+		}
+		if input.MaxWriteQPS > 0 && after.WriteQPS > input.MaxWriteQPS {
+			impact.Exceeded = append(impact.Exceeded, "max_write_qps")
+		}
+		if len(impact.Exceeded) > 0 {
+			result.Exceeded = true
+		}
+		result.Targets = append(result.Targets, impact)
+	}
+	sort.Slice(result.Targets, func(i, j int) bool {
+		return result.Targets[i].StoreID < result.Targets[j].StoreID
+	})
+	sort.Slice(result.UnmovableRegions, func(i, j int) bool {
+		return result.UnmovableRegions[i] < result.UnmovableRegions[j]
+	})
+	return result
+}
+
+func storeLeaderLoad(rc *cluster.RaftCluster, storeID uint64) LeaderLoad {
+	var load LeaderLoad
+	for _, region := range rc.GetStoreRegions(storeID) {
+		if region.GetLeader().GetStoreId() == storeID {
+			load.add(regionLeaderLoad(region))
+		}
+	}
+	return load
+}
+
+// regionLeaderLoad returns the load of the region leader by the flow of the
+// last heartbeat.
+func regionLeaderLoad(region *core.RegionInfo) *LeaderLoad {
+	load := &LeaderLoad{LeaderCount: 1}
+	interval := region.GetInterval()
+	seconds := float64(interval.GetEndTimestamp() - interval.GetStartTimestamp())
+	if seconds <= 0 {
+		return load
+	}
+	load.ReadQPS = float64(region.GetKeysRead()) / seconds
+	load.WriteQPS = float64(region.GetKeysWritten()) / seconds
+	load.ReadByteRate = float64(region.GetBytesRead()) / seconds
+	load.WriteByteRate = float64(region.GetBytesWritten()) / seconds
+	return load
+}
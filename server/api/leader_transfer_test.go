@@ -0,0 +1,123 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/core"
+)
+
+var _ = Suite(&testLeaderTransferSuite{})
+
+type testLeaderTransferSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testLeaderTransferSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	for id := uint64(1); id <= 4; id++ {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+	}
+	// The leaders of region 2, 3, 4 are on store 1, and the leader of region
+	// 5 is on store 2.
+	for _, r := range []struct {
+		id       uint64
+		stores   []uint64
+		readKeys uint64
+	}{
+		{2, []uint64{1, 2, 3}, 100},
+		{3, []uint64{1, 2, 3}, 200},
+		{4, []uint64{1, 4}, 300},
+		{5, []uint64{2, 1, 3}, 400},
+	} {
+		meta := &metapb.Region{
+			Id:          r.id,
+			StartKey:    []byte(fmt.Sprintf("k%d", r.id)),
+			EndKey:      []byte(fmt.Sprintf("k%d", r.id+1)),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}
+		for i, storeID := range r.stores {
+			meta.Peers = append(meta.Peers, &metapb.Peer{Id: r.id*10 + uint64(i), StoreId: storeID})
+		}
+		mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(meta, meta.Peers[0],
+			core.SetReadKeys(r.readKeys), core.SetReportInterval(10)))
+	}
+}
+
+func (s *testLeaderTransferSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testLeaderTransferSuite) simulate(input *LeaderTransferSimulationInput) (*LeaderTransferSimulation, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	result := &LeaderTransferSimulation{}
+	err = postJSON(testDialClient, s.urlPrefix+"/leader-transfer/simulate", data, func(res []byte, _ int) {
+		err = json.Unmarshal(res, result)
+	})
+	return result, err
+}
+
+func (s *testLeaderTransferSuite) TestSimulate(c *C) {
+	result, err := s.simulate(&LeaderTransferSimulationInput{SourceStoreIDs: []uint64{1}, MaxLeaderCount: 1})
+	c.Assert(err, IsNil)
+	c.Assert(result.LeaderCount, Equals, 3)
+	c.Assert(result.UnmovableRegions, HasLen, 0)
+	c.Assert(result.Targets, HasLen, 3)
+	// Region 2 goes to store 3 which has no leader, and then region 3 goes to
+	// store 2 which has the smaller ID.
+	c.Assert(result.Targets[0].StoreID, Equals, uint64(2))
+	c.Assert(result.Targets[0].Current.LeaderCount, Equals, 1)
+	c.Assert(result.Targets[0].Current.ReadQPS, Equals, 40.0)
+	c.Assert(result.Targets[0].Delta.LeaderCount, Equals, 1)
+	c.Assert(result.Targets[0].Delta.ReadQPS, Equals, 20.0)
+	c.Assert(result.Targets[0].Exceeded, DeepEquals, []string{"max_leader_count"})
+	c.Assert(result.Targets[1].StoreID, Equals, uint64(3))
+	c.Assert(result.Targets[1].Delta.ReadQPS, Equals, 10.0)
+	c.Assert(result.Targets[1].Exceeded, HasLen, 0)
+	c.Assert(result.Targets[2].StoreID, Equals, uint64(4))
+	c.Assert(result.Targets[2].Delta.ReadQPS, Equals, 30.0)
+	c.Assert(result.Exceeded, IsTrue)
+
+	// Only store 2 and 3 can be the targets.
+	result, err = s.simulate(&LeaderTransferSimulationInput{
+		SourceStoreIDs: []uint64{1},
+		TargetStoreIDs: []uint64{2, 3},
+		MaxReadQPS:     100,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.UnmovableRegions, DeepEquals, []uint64{4})
+	c.Assert(result.Targets, HasLen, 2)
+	c.Assert(result.Exceeded, IsFalse)
+
+	_, err = s.simulate(&LeaderTransferSimulationInput{})
+	c.Assert(err, NotNil)
+	_, err = s.simulate(&LeaderTransferSimulationInput{SourceStoreIDs: []uint64{10086}})
+	c.Assert(err, NotNil)
+}
@@ -17,6 +17,10 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
 
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/logutil"
@@ -69,3 +73,65 @@ func (h *logHandler) Handle(w http.ResponseWriter, r *http.Request) {
 
 	h.rd.JSON(w, http.StatusOK, "The log level is updated.")
 }
+
+// defaultModuleDebugTTL is used when the ttl of the module debug logging is
+// not specified.
+const defaultModuleDebugTTL = 10 * time.Minute
+
+// LogStatus is the log level and the modules whose debug logging is on.
+type LogStatus struct {
+	Level        string                      `json:"level"`
+	DebugModules []logutil.DebugModuleStatus `json:"debug_modules"`
+}
+
+// @Tags admin
+// @Summary Get the log level and the modules whose debug logging is on.
+// @Produce json
+// @Success 200 {object} LogStatus
+// @Router /admin/log [get]
+func (h *logHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, &LogStatus{
+		Level:        log.GetLevel().String(),
+		DebugModules: logutil.GetDebugModules(),
+	})
+}
+
+// @Tags admin
+// @Summary Turn on the debug logging of a module for a while, regardless of the log level.
+// @Param module path string true "The module, one of region-heartbeat and merge-checker"
+// @Param ttlSecond query integer false "How long the debug logging lasts, 600 by default"
+// @Produce json
+// @Success 200 {string} string "The debug logging of the module is enabled."
+// @Failure 400 {string} string "The input is invalid."
+// @Router /admin/log/modules/{module} [post]
+func (h *logHandler) EnableModuleDebug(w http.ResponseWriter, r *http.Request) {
+	ttl := defaultModuleDebugTTL
+	if ttlSec := r.URL.Query().Get("ttlSecond"); ttlSec != "" {
+		sec, err := strconv.Atoi(ttlSec)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		ttl = time.Duration(sec) * time.Second
+	}
+	if err := h.svr.EnableModuleDebug(mux.Vars(r)["module"], ttl); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The debug logging of the module is enabled.")
+}
+
+// @Tags admin
+// @Summary Turn off the debug logging of a module.
+// @Param module path string true "The module"
+// @Produce json
+// @Success 200 {string} string "The debug logging of the module is disabled."
+// @Failure 400 {string} string "The input is invalid."
+// @Router /admin/log/modules/{module} [delete]
+func (h *logHandler) DisableModuleDebug(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.DisableModuleDebug(mux.Vars(r)["module"]); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The debug logging of the module is disabled.")
+}
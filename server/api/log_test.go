@@ -16,9 +16,11 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/logutil"
 	"github.com/tikv/pd/server"
 )
 
@@ -52,3 +54,27 @@ func (s *testLogSuite) TestSetLogLevel(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(log.GetLevel().String(), Equals, level)
 }
+
+func (s *testLogSuite) TestModuleDebug(c *C) {
+	url := fmt.Sprintf("%s/log/modules/%s", s.urlPrefix, logutil.MergeCheckerModule)
+	err := postJSON(testDialClient, url+"?ttlSecond=60", nil)
+	c.Assert(err, IsNil)
+	c.Assert(logutil.IsModuleDebugEnabled(logutil.MergeCheckerModule), IsTrue)
+	c.Assert(logutil.IsModuleDebugEnabled(logutil.RegionHeartbeatModule), IsFalse)
+
+	status := &LogStatus{}
+	err = readJSON(testDialClient, s.urlPrefix+"/log", status)
+	c.Assert(err, IsNil)
+	c.Assert(status.Level, Equals, log.GetLevel().String())
+	c.Assert(status.DebugModules, HasLen, 1)
+	c.Assert(status.DebugModules[0].Module, Equals, logutil.MergeCheckerModule)
+
+	code, _ := requestStatusBody(c, testDialClient, http.MethodPost, fmt.Sprintf("%s/log/modules/unknown", s.urlPrefix))
+	c.Assert(code, Equals, http.StatusBadRequest)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodPost, url+"?ttlSecond=0")
+	c.Assert(code, Equals, http.StatusBadRequest)
+
+	_, err = doDelete(testDialClient, url)
+	c.Assert(err, IsNil)
+	c.Assert(logutil.IsModuleDebugEnabled(logutil.MergeCheckerModule), IsFalse)
+}
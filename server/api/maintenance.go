@@ -0,0 +1,134 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/pingcap/errors"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+type maintenanceHandler struct {
+	*server.Handler
+	rd *render.Render
+}
+
+func newMaintenanceHandler(handler *server.Handler, rd *render.Render) *maintenanceHandler {
+	return &maintenanceHandler{
+		Handler: handler,
+		rd:      rd,
+	}
+}
+
+// @Tags maintenance
+// @Summary Get the maintenance budget, which is how many failure domains can be in maintenance at the same time, and the failure domains with maintenance windows.
+// @Produce json
+// @Success 200 {object} cluster.MaintenanceBudget
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /maintenance [get]
+func (h *maintenanceHandler) GetBudget(w http.ResponseWriter, r *http.Request) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetMaintenanceBudget())
+}
+
+// MaintenanceInput is the input to declare a maintenance window of a group of
+// stores, which are given by the IDs or the labels, e.g. {"zone": "z1"}. A
+// store is selected by the labels if it has all of them.
+type MaintenanceInput struct {
+	StoreIDs []uint64          `json:"store_ids,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	StoreMaintenanceInput
+}
+
+// @Tags maintenance
+// @Summary Declare a maintenance window of a group of stores. It is rejected if the failure domains in maintenance at the same time would exceed the budget.
+// @Accept json
+// @Param body body MaintenanceInput true "json params, e.g. {\"labels\": {\"zone\": \"z1\"}, \"ttl\": \"2h\", \"reason\": \"upgrade\"}"
+// @Produce json
+// @Success 200 {array} uint64 "The IDs of the stores in maintenance."
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "No store is selected, or the store does not exist."
+// @Failure 409 {string} string "The window exceeds the maintenance budget of failure domains."
+// @Failure 410 {string} string "The store has been removed."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /maintenance [post]
+func (h *maintenanceHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var input MaintenanceInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if (len(input.StoreIDs) == 0) == (len(input.Labels) == 0) {
+		h.rd.JSON(w, http.StatusBadRequest, "either store_ids or labels should be given")
+		return
+	}
+	maintenance, err := input.window()
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	storeIDs := input.StoreIDs
+	if len(input.Labels) > 0 {
+		for _, store := range rc.GetStores() {
+			if store.IsTombstone() {
+				continue
+			}
+			matched := true
+			for key, value := range input.Labels {
+				if store.GetLabelValue(key) != value {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				storeIDs = append(storeIDs, store.GetID())
+			}
+		}
+		if len(storeIDs) == 0 {
+			h.rd.JSON(w, http.StatusNotFound, "no store matches the labels")
+			return
+		}
+	}
+
+	if err := rc.SetStoresMaintenance(storeIDs, maintenance); err != nil {
+		h.rd.JSON(w, maintenanceErrorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, storeIDs)
+}
+
+func maintenanceErrorStatus(err error) int {
+	switch cause := errors.Cause(err); {
+	case errs.ErrStoreNotFound.Equal(cause):
+		return http.StatusNotFound
+	case errs.ErrStoreTombstone.Equal(cause):
+		return http.StatusGone
+	case errs.ErrMaintenanceBudgetExceeded.Equal(cause):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/config"
+)
+
+var _ = Suite(&testMaintenanceSuite{})
+
+type testMaintenanceSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testMaintenanceSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.Replication.LocationLabels = []string{"zone", "host"} })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	for id, zone := range map[uint64]string{1: "z1", 2: "z1", 3: "z2", 4: "z3"} {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, []*metapb.StoreLabel{{Key: "zone", Value: zone}})
+	}
+}
+
+func (s *testMaintenanceSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testMaintenanceSuite) setMaintenance(c *C, input string) int {
+	resp, err := testDialClient.Post(s.urlPrefix+"/maintenance", "application/json", bytes.NewBufferString(input))
+	c.Assert(err, IsNil)
+	c.Assert(resp.Body.Close(), IsNil)
+	return resp.StatusCode
+}
+
+func (s *testMaintenanceSuite) TestMaintenance(c *C) {
+	c.Assert(s.setMaintenance(c, `{"labels": {"zone": "z1"}, "ttl": "1h", "reason": "upgrade"}`), Equals, http.StatusOK)
+	c.Assert(s.setMaintenance(c, `{"store_ids": [3], "ttl": "1h"}`), Equals, http.StatusConflict)
+	c.Assert(s.setMaintenance(c, `{"store_ids": [3], "start_time": "2030-01-01T00:00:00Z", "ttl": "1h"}`), Equals, http.StatusOK)
+
+	budget := &cluster.MaintenanceBudget{}
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/maintenance", budget), IsNil)
+	c.Assert(budget.DomainLabel, Equals, "zone")
+	c.Assert(budget.Budget, Equals, 1)
+	c.Assert(budget.Domains, HasLen, 2)
+	c.Assert(budget.Domains[0].Name, Equals, "zone=z1")
+	c.Assert(budget.Domains[0].StoreIDs, DeepEquals, []uint64{1, 2})
+	c.Assert(budget.Domains[1].StoreIDs, DeepEquals, []uint64{3})
+
+	// The per-store API is also limited by the budget.
+	code, _ := requestStatusBody(c, testDialClient, http.MethodDelete, s.urlPrefix+"/store/3/maintenance")
+	c.Assert(code, Equals, http.StatusOK)
+	resp, err := testDialClient.Post(s.urlPrefix+"/store/4/maintenance", "application/json", bytes.NewBufferString(`{"ttl": "1h"}`))
+	c.Assert(err, IsNil)
+	c.Assert(resp.Body.Close(), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusConflict)
+
+	c.Assert(s.setMaintenance(c, `{"store_ids": [4], "labels": {"zone": "z3"}, "ttl": "1h"}`), Equals, http.StatusBadRequest)
+	c.Assert(s.setMaintenance(c, `{"store_ids": [4]}`), Equals, http.StatusBadRequest)
+	c.Assert(s.setMaintenance(c, `{"labels": {"zone": "z4"}, "ttl": "1h"}`), Equals, http.StatusNotFound)
+	c.Assert(s.setMaintenance(c, `{"store_ids": [10086], "ttl": "1h"}`), Equals, http.StatusNotFound)
+}
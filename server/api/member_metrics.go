@@ -0,0 +1,123 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tikv/pd/pkg/apiutil/serverapi"
+	"github.com/tikv/pd/server"
+)
+
+const (
+	memberMetricsURL     = "/pd/api/v1/members/metrics/self"
+	memberMetricsTimeout = 3 * time.Second
+)
+
+// MemberMetrics is the resource usage of a PD member.
+type MemberMetrics struct {
+	Name       string                `json:"name"`
+	MemberID   uint64                `json:"member_id"`
+	ClientUrls []string              `json:"client_urls"`
+	Metrics    *server.ResourceUsage `json:"metrics,omitempty"`
+	// Error is set if the metrics cannot be fetched from the member.
+	Error string `json:"error,omitempty"`
+}
+
+// @Tags member
+// @Summary Get the resource usage of all PD servers in the cluster.
+// @Produce json
+// @Success 200 {array} MemberMetrics
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /members/metrics [get]
+func (h *memberHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	members, err := getMembers(h.svr)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	metrics := make([]MemberMetrics, len(members.GetMembers()))
+	var wg sync.WaitGroup
+	for i, m := range members.GetMembers() {
+		metrics[i] = MemberMetrics{
+			Name:       m.GetName(),
+			MemberID:   m.GetMemberId(),
+			ClientUrls: m.GetClientUrls(),
+		}
+		wg.Add(1)
+		go func(mm *MemberMetrics) {
+			defer wg.Done()
+			usage, err := h.fetchMemberMetrics(r.Context(), mm.ClientUrls)
+			if err != nil {
+				mm.Error = err.Error()
+				return
+			}
+			mm.Metrics = usage
+		}(&metrics[i])
+	}
+	wg.Wait()
+	h.rd.JSON(w, http.StatusOK, metrics)
+}
+
+// fetchMemberMetrics tries the client URLs of the member in turn.
+func (h *memberHandler) fetchMemberMetrics(ctx context.Context, urls []string) (*server.ResourceUsage, error) {
+	err := fmt.Errorf("no client url")
+	for _, url := range urls {
+		var usage *server.ResourceUsage
+		if usage, err = h.fetchMemberMetricsFrom(ctx, url); err == nil {
+			return usage, nil
+		}
+	}
+	return nil, err
+}
+
+func (h *memberHandler) fetchMemberMetricsFrom(ctx context.Context, url string) (*server.ResourceUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, memberMetricsTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+memberMetricsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Let the member serve the request by itself instead of redirecting it
+	// to the leader.
+	req.Header.Set(serverapi.AllowFollowerHandle, "true")
+	resp, err := h.svr.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("[%d] %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	usage := &server.ResourceUsage{}
+	if err := json.NewDecoder(resp.Body).Decode(usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// @Tags member
+// @Summary Get the resource usage of the PD server which serves the request.
+// @Description The request is redirected to the leader unless the header PD-Allow-follower-handle is set.
+// @Produce json
+// @Success 200 {object} server.ResourceUsage
+// @Router /members/metrics/self [get]
+func (h *memberHandler) GetSelfMetrics(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetResourceUsage())
+}
@@ -26,6 +26,7 @@ import (
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/apiutil/serverapi"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
 )
@@ -107,6 +108,41 @@ func (s *testMemberAPISuite) TestMemberLeader(c *C) {
 	c.Assert(got.GetMemberId(), Equals, leader.GetMemberId())
 }
 
+func (s *testMemberAPISuite) TestMemberMetrics(c *C) {
+	addr := s.cfgs[rand.Intn(len(s.cfgs))].ClientUrls + apiPrefix + "/api/v1/members/metrics"
+	var metrics []MemberMetrics
+	c.Assert(readJSON(testDialClient, addr, &metrics), IsNil)
+	c.Assert(metrics, HasLen, len(s.cfgs))
+	for _, m := range metrics {
+		c.Assert(m.Error, Equals, "")
+		c.Assert(m.Metrics, NotNil)
+		c.Assert(m.Metrics.Name, Equals, m.Name)
+		c.Assert(m.Metrics.MemberID, Equals, m.MemberID)
+		c.Assert(m.Metrics.Goroutines > 0, IsTrue)
+		c.Assert(m.Metrics.SysMemory > 0, IsTrue)
+	}
+
+	// a follower reports its own metrics if it is allowed to handle the request.
+	var follower *server.Server
+	for _, svr := range s.servers {
+		if !svr.GetMember().IsLeader() {
+			follower = svr
+			break
+		}
+	}
+	c.Assert(follower, NotNil)
+	req, err := http.NewRequest(http.MethodGet, follower.GetAddr()+apiPrefix+"/api/v1/members/metrics/self", nil)
+	c.Assert(err, IsNil)
+	req.Header.Set(serverapi.AllowFollowerHandle, "true")
+	resp, err := testDialClient.Do(req)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	var usage server.ResourceUsage
+	c.Assert(json.NewDecoder(resp.Body).Decode(&usage), IsNil)
+	c.Assert(usage.Name, Equals, follower.Name())
+}
+
 func (s *testMemberAPISuite) TestChangeLeaderPeerUrls(c *C) {
 	leader := s.servers[0].GetLeader()
 	addr := s.cfgs[rand.Intn(len(s.cfgs))].ClientUrls + apiPrefix + "/api/v1/leader"
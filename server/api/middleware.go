@@ -14,9 +14,14 @@
 package api
 
 import (
+	"bytes"
+	"io/ioutil"
 	"net/http"
 
+	"github.com/gorilla/mux"
+	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/idempotency"
 	"github.com/tikv/pd/server"
 	"github.com/unrolled/render"
 )
@@ -43,3 +48,161 @@ func (m clusterMiddleware) Middleware(h http.Handler) http.Handler {
 		h.ServeHTTP(w, r)
 	})
 }
+
+// deprecationMiddleware marks the responses of the deprecated endpoints, so
+// that the clients can find out the successors of them.
+type deprecationMiddleware struct {
+	successors map[string]string
+}
+
+// newDeprecationMiddleware creates a deprecationMiddleware. The keys of
+// successors are the path templates of the deprecated endpoints, and the
+// values are the path templates of their successors.
+func newDeprecationMiddleware(successors map[string]string) deprecationMiddleware {
+	return deprecationMiddleware{successors: successors}
+}
+
+func (m deprecationMiddleware) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the reading endpoints are covered by the successors.
+		if route := mux.CurrentRoute(r); route != nil && r.Method == http.MethodGet {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				if successor, ok := m.successors[tpl]; ok {
+					w.Header().Set("Deprecation", "true")
+					w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+				}
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+const (
+	// idempotencyKeyHeader carries the idempotency key of a mutating request.
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader is set in the responses replayed from an
+	// earlier request with the same idempotency key.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+type recordedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// responseRecorder passes the response through and keeps a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// idempotencyMiddleware records the responses of the mutating requests with
+// the Idempotency-Key header, and replays them to the retries with the same
+// key, so that a client can safely retry a request after a timeout. The
+// responses with server errors are not recorded, since the requests are
+// expected to be retried.
+type idempotencyMiddleware struct {
+	cache *idempotency.Cache
+	rd    *render.Render
+}
+
+func newIdempotencyMiddleware(cache *idempotency.Cache) idempotencyMiddleware {
+	return idempotencyMiddleware{
+		cache: cache,
+		rd:    render.New(render.Options{IndentJSON: true}),
+	}
+}
+
+func (m idempotencyMiddleware) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+		data, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			m.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(data))
+		fingerprint := idempotency.Fingerprint([]byte(r.Method), []byte(r.URL.RequestURI()), data)
+		result, replayed, err := m.cache.Do(key, fingerprint, func() (interface{}, bool) {
+			recorder := &responseRecorder{ResponseWriter: w}
+			h.ServeHTTP(recorder, r)
+			if recorder.status == 0 {
+				recorder.status = http.StatusOK
+			}
+			return &recordedResponse{
+				status: recorder.status,
+				header: w.Header().Clone(),
+				body:   recorder.body.Bytes(),
+			}, recorder.status < http.StatusInternalServerError
+		})
+		if err != nil {
+			m.rd.JSON(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if !replayed {
+			return
+		}
+		resp := result.(*recordedResponse)
+		for k, v := range resp.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set(idempotentReplayedHeader, "true")
+		w.WriteHeader(resp.status)
+		if _, err := w.Write(resp.body); err != nil {
+			log.Error("write failed", errs.ZapError(errs.ErrWriteHTTPBody, err))
+		}
+	})
+}
+
+// configHistoryMiddleware attributes the config changes made by the requests
+// to their callers in the config history.
+type configHistoryMiddleware struct {
+	s *server.Server
+}
+
+func newConfigHistoryMiddleware(s *server.Server) configHistoryMiddleware {
+	return configHistoryMiddleware{s: s}
+}
+
+func (m configHistoryMiddleware) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.s.WithConfigOperator(configOperator(r), func() {
+			h.ServeHTTP(w, r)
+		})
+	})
+}
+
+// configOperator identifies the caller of the request by its address and user
+// agent, such as "pd-ctl@10.0.0.1:52341".
+func configOperator(r *http.Request) string {
+	if agent := r.UserAgent(); agent != "" {
+		return agent + "@" + r.RemoteAddr
+	}
+	return r.RemoteAddr
+}
+
+// ttlConfigOrigin tells which request of whom sets a ttl config, such as
+// "POST /pd/api/v1/config by pd-ctl@10.0.0.1:52341".
+func ttlConfigOrigin(r *http.Request) string {
+	return r.Method + " " + r.URL.Path + " by " + configOperator(r)
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/tikv/pd/server"
+)
+
+var _ = Suite(&testIdempotencySuite{})
+
+type testIdempotencySuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testIdempotencySuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testIdempotencySuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testIdempotencySuite) post(c *C, url, key, body string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	resp, err := testDialClient.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp
+}
+
+func (s *testIdempotencySuite) TestIdempotencyKey(c *C) {
+	url := fmt.Sprintf("%s/config/schedule", s.urlPrefix)
+	resp := s.post(c, url, "key-1", `{"leader-schedule-limit": 10}`)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.Header.Get(idempotentReplayedHeader), Equals, "")
+	c.Assert(s.svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(10))
+
+	resp = s.post(c, url, "", `{"leader-schedule-limit": 20}`)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	// The retry gets the original result without being executed again.
+	resp = s.post(c, url, "key-1", `{"leader-schedule-limit": 10}`)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.Header.Get(idempotentReplayedHeader), Equals, "true")
+	c.Assert(s.svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(20))
+
+	// The key cannot be reused by a different request.
+	resp = s.post(c, url, "key-1", `{"leader-schedule-limit": 30}`)
+	c.Assert(resp.StatusCode, Equals, http.StatusUnprocessableEntity)
+	c.Assert(s.svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(20))
+
+	resp = s.post(c, url, "key-2", `{"leader-schedule-limit": 30}`)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(s.svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(30))
+}
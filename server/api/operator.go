@@ -14,11 +14,16 @@
 package api
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/placement"
@@ -63,6 +68,128 @@ func (h *operatorHandler) Get(w http.ResponseWriter, r *http.Request) {
 	h.r.JSON(w, http.StatusOK, op)
 }
 
+const (
+	defaultOperatorHistoryLimit = 1000
+	maxOperatorHistoryLimit     = 10000
+)
+
+// @Tags operator
+// @Summary Watch the records of the operators created or ended from now on, which are sent as newline delimited JSON. The stream ends once the server is no longer the leader.
+// @Param region_id query integer false "Only watch the operators of the region."
+// @Param store_id query integer false "Only watch the operators moving the peers or the leader out of or into the store."
+// @Produce json
+// @Success 200 {object} ophistory.Record
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /operators/watch [get]
+func (h *operatorHandler) Watch(w http.ResponseWriter, r *http.Request) {
+	var filter server.OperatorWatchFilter
+	query := r.URL.Query()
+	for name, id := range map[string]*uint64{"region_id": &filter.RegionID, "store_id": &filter.StoreID} {
+		if v := query.Get(name); v != "" {
+			var err error
+			if *id, err = strconv.ParseUint(v, 10, 64); err != nil {
+				h.r.JSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.r.JSON(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	watcher, err := h.WatchOperators(filter)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer watcher.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	encoder := json.NewEncoder(w)
+	for {
+		record, err := watcher.Next(r.Context())
+		if err != nil {
+			if err != io.EOF && r.Context().Err() == nil {
+				log.Warn("operator watch is stopped", errs.ZapError(err))
+			}
+			return
+		}
+		if err := encoder.Encode(record); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// @Tags operator
+// @Summary Show the conflict graph of the waiting operators, which points them to the running operators and the stores blocking them.
+// @Produce json
+// @Success 200 {object} schedule.ConflictGraph
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /operators/conflicts [get]
+func (h *operatorHandler) Conflicts(w http.ResponseWriter, r *http.Request) {
+	g, err := h.GetOperatorConflictGraph()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, g)
+}
+
+// @Tags operator
+// @Summary List the history of the operators, including their creation and their end.
+// @Param region_id query integer false "Only list the history of the region."
+// @Param start query integer false "The start of the time range in unix seconds."
+// @Param end query integer false "The end of the time range in unix seconds, exclusive."
+// @Param limit query integer false "The maximum number of the records." default(1000)
+// @Produce json
+// @Success 200 {array} ophistory.Record
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /operators/history [get]
+func (h *operatorHandler) History(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var regionID uint64
+	var start, end time.Time
+	limit := defaultOperatorHistoryLimit
+	var err error
+	if v := query.Get("region_id"); v != "" {
+		if regionID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	for name, t := range map[string]*time.Time{"start": &start, "end": &end} {
+		if v := query.Get(name); v != "" {
+			sec, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				h.r.JSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			*t = time.Unix(sec, 0)
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if limit <= 0 || limit > maxOperatorHistoryLimit {
+		limit = maxOperatorHistoryLimit
+	}
+	records, err := h.GetOperatorHistory(regionID, start, end, limit)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, records)
+}
+
 // @Tags operator
 // @Summary List pending operators.
 // @Param kind query string false "Specify the operator kind." Enums(admin, leader, region)
@@ -338,6 +465,67 @@ func (h *operatorHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	h.r.JSON(w, http.StatusOK, "The pending operator is canceled.")
 }
 
+// @Tags operator
+// @Summary Cancel the running and the waiting operators matched by all the given filters.
+// @Param desc query string false "The description of the operators, such as balance-region."
+// @Param source query string false "Where the operators come from." Enums(scheduler, checker, api)
+// @Param kind query string false "The operator kinds, separated by commas. An operator is matched if it has any of the kinds."
+// @Param start_key query string false "The start of the key range which the regions of the operators overlap."
+// @Param end_key query string false "The end of the key range, exclusive."
+// @Param store_id query integer false "The store which the operators add a peer to or transfer the leader to."
+// @Param dry_run query boolean false "Only list the matched operators without canceling them."
+// @Produce json
+// @Success 200 {array} operator.Operator
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /operators [delete]
+func (h *operatorHandler) DeleteMatched(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := &server.OperatorFilter{
+		Desc:     query.Get("desc"),
+		Source:   query.Get("source"),
+		StartKey: []byte(query.Get("start_key")),
+		EndKey:   []byte(query.Get("end_key")),
+	}
+	switch filter.Source {
+	case "", operator.SourceScheduler, operator.SourceChecker, operator.SourceAPI:
+	default:
+		h.r.JSON(w, http.StatusBadRequest, "unknown source: "+filter.Source)
+		return
+	}
+	var err error
+	if kind := query.Get("kind"); kind != "" {
+		if filter.Kind, err = operator.ParseOperatorKind(kind); err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if storeID := query.Get("store_id"); storeID != "" {
+		if filter.TargetStoreID, err = strconv.ParseUint(storeID, 10, 64); err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if filter.IsEmpty() {
+		h.r.JSON(w, http.StatusBadRequest, "at least one filter is required")
+		return
+	}
+	dryRun := false
+	if v := query.Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	ops, err := h.RemoveOperators(filter, dryRun)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, ops)
+}
+
 func parseStoreIDsAndPeerRole(ids interface{}, roles interface{}) (map[uint64]placement.PeerRoleType, bool) {
 	items, ok := ids.([]interface{})
 	if !ok {
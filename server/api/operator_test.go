@@ -15,19 +15,25 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strings"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/tikv/pd/pkg/mock/mockhbstream"
+	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/ophistory"
+	"github.com/tikv/pd/server/schedule"
 	pdoperator "github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/versioninfo"
@@ -113,6 +119,88 @@ func (s *testOperatorSuite) TestAddRemovePeer(c *C) {
 	c.Assert(err, NotNil)
 	err = postJSON(testDialClient, fmt.Sprintf("%s/operators", s.urlPrefix), []byte(`{"name":"transfer-region", "region_id": 1, "to_store_ids": [1, 2, 3]}`))
 	c.Assert(err, NotNil)
+
+	// The history records the operators and their ends.
+	historyURL := fmt.Sprintf("%s/operators/history?region_id=1", s.urlPrefix)
+	var records []*ophistory.Record
+	testutil.WaitUntil(c, func(c *C) bool {
+		records = nil
+		c.Assert(readJSON(testDialClient, historyURL, &records), IsNil)
+		return len(records) >= 5
+	})
+	c.Assert(records[0].RegionID, Equals, uint64(1))
+	c.Assert(records[0].Event, Equals, schedule.OperatorEventCreate)
+	c.Assert(records[0].Source, Equals, pdoperator.SourceAPI)
+	c.Assert(records[1].Event, Equals, schedule.OperatorEventCancel)
+	c.Assert(readJSON(testDialClient, historyURL+"&limit=1", &records), IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(readJSON(testDialClient, historyURL+"&end=1", &records), IsNil)
+	c.Assert(records, HasLen, 0)
+	c.Assert(readJSON(testDialClient, historyURL+"&start=x", &records), NotNil)
+}
+
+func (s *testOperatorSuite) TestWatch(c *C) {
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	peer1 := &metapb.Peer{Id: 101, StoreId: 1}
+	peer2 := &metapb.Peer{Id: 102, StoreId: 2}
+	region := &metapb.Region{
+		Id:          100,
+		StartKey:    []byte("watch-a"),
+		EndKey:      []byte("watch-b"),
+		Peers:       []*metapb.Peer{peer1, peer2},
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 10, Version: 10},
+	}
+	mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(region, peer1))
+
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/operators/watch?store_id=x", nil), NotNil)
+	resp, err := testDialClient.Get(s.urlPrefix + "/operators/watch?region_id=100&store_id=2")
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("Content-Type"), Equals, "application/x-ndjson")
+	records := make(chan *ophistory.Record, 2)
+	go func() {
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			r := &ophistory.Record{}
+			if decoder.Decode(r) != nil {
+				close(records)
+				return
+			}
+			records <- r
+		}
+	}()
+
+	regionURL := fmt.Sprintf("%s/operators/%d", s.urlPrefix, region.GetId())
+	err = postJSON(testDialClient, s.urlPrefix+"/operators", []byte(`{"name":"transfer-leader", "region_id": 100, "to_store_id": 2}`))
+	c.Assert(err, IsNil)
+	_, err = doDelete(testDialClient, regionURL)
+	c.Assert(err, IsNil)
+	for _, event := range []string{schedule.OperatorEventCreate, schedule.OperatorEventCancel} {
+		select {
+		case r := <-records:
+			c.Assert(r.Event, Equals, event)
+			c.Assert(r.RegionID, Equals, uint64(100))
+			c.Assert(r.Source, Equals, pdoperator.SourceAPI)
+			c.Assert(r.SourceStores, DeepEquals, []uint64{1})
+			c.Assert(r.TargetStores, DeepEquals, []uint64{2})
+			c.Assert(r.StartKey, Equals, core.HexRegionKeyStr([]byte("watch-a")))
+			c.Assert(r.EndKey, Equals, core.HexRegionKeyStr([]byte("watch-b")))
+		case <-time.After(5 * time.Second):
+			c.Fatal("no operator record is received")
+		}
+	}
+}
+
+func (s *testOperatorSuite) TestConflicts(c *C) {
+	g := &schedule.ConflictGraph{}
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/operators/conflicts", g), IsNil)
+	c.Assert(g.Nodes, NotNil)
+	c.Assert(g.Edges, NotNil)
+	for _, node := range g.Nodes {
+		c.Assert(node.Type, Not(Equals), schedule.ConflictNodeStore)
+	}
 }
 
 func (s *testOperatorSuite) TestMergeRegionOperator(c *C) {
@@ -375,6 +463,101 @@ func (s *testTransferRegionOperatorSuite) TestTransferRegionWithPlacementRule(c
 	}
 }
 
+var _ = Suite(&testOperatorCancelSuite{})
+
+type testOperatorCancelSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testOperatorCancelSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.Replication.MaxReplicas = 1 })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testOperatorCancelSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testOperatorCancelSuite) cancel(c *C, query string) (int, []string) {
+	req, err := http.NewRequest(http.MethodDelete, s.urlPrefix+"/operators?"+query, nil)
+	c.Assert(err, IsNil)
+	resp, err := testDialClient.Do(req)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	var ops []string
+	c.Assert(json.NewDecoder(resp.Body).Decode(&ops), IsNil)
+	return resp.StatusCode, ops
+}
+
+func (s *testOperatorCancelSuite) TestCancelMatched(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+	}
+	keys := []string{"a", "b", "c", "d"}
+	for i := 0; i < 3; i++ {
+		peer := &metapb.Peer{Id: uint64(10 + i), StoreId: 1}
+		region := &metapb.Region{
+			Id:          uint64(100 + i),
+			StartKey:    []byte(keys[i]),
+			EndKey:      []byte(keys[i+1]),
+			Peers:       []*metapb.Peer{peer},
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}
+		mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(region, peer))
+	}
+	handler := s.svr.GetHandler()
+	c.Assert(handler.AddAddPeerOperator(100, 2), IsNil)
+	c.Assert(handler.AddAddLearnerOperator(101, 3), IsNil)
+	c.Assert(handler.AddAddPeerOperator(102, 2), IsNil)
+	containsRegions := func(ops []string, regionIDs ...uint64) {
+		c.Assert(ops, HasLen, len(regionIDs))
+		for i, id := range regionIDs {
+			c.Assert(strings.Contains(ops[i], fmt.Sprintf("region:%d(", id)), IsTrue)
+		}
+	}
+
+	// invalid filters
+	for _, query := range []string{"", "dry_run=true", "kind=foo", "store_id=x", "source=foo", "desc=admin-add-peer&dry_run=x"} {
+		status, _ := s.cancel(c, query)
+		c.Assert(status, Equals, http.StatusBadRequest)
+	}
+
+	// dry run
+	status, ops := s.cancel(c, "store_id=2&dry_run=true")
+	c.Assert(status, Equals, http.StatusOK)
+	containsRegions(ops, 100, 102)
+	status, ops = s.cancel(c, "source=api&kind=admin&dry_run=true")
+	c.Assert(status, Equals, http.StatusOK)
+	containsRegions(ops, 100, 101, 102)
+
+	// cancel by the target store and the key range
+	status, ops = s.cancel(c, "store_id=2&start_key=c")
+	c.Assert(status, Equals, http.StatusOK)
+	containsRegions(ops, 102)
+	_, err := handler.GetOperator(102)
+	c.Assert(err, NotNil)
+
+	// cancel by the description
+	status, ops = s.cancel(c, "desc=admin-add-learner")
+	c.Assert(status, Equals, http.StatusOK)
+	containsRegions(ops, 101)
+	status, ops = s.cancel(c, "desc=balance-region")
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(ops, HasLen, 0)
+	_, err = handler.GetOperator(100)
+	c.Assert(err, IsNil)
+}
+
 func mustPutStore(c *C, svr *server.Server, id uint64, state metapb.StoreState, labels []*metapb.StoreLabel) {
 	_, err := svr.PutStore(context.Background(), &pdpb.PutStoreRequest{
 		Header: &pdpb.RequestHeader{ClusterId: svr.ClusterID()},
@@ -21,6 +21,7 @@ import (
 	"net/url"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/failpoint"
@@ -29,6 +30,8 @@ import (
 	"github.com/pingcap/kvproto/pkg/replication_modepb"
 	log "github.com/sirupsen/logrus"
 	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule/operator"
@@ -144,6 +147,43 @@ func (h *regionHandler) GetRegionByID(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, NewRegionInfo(regionInfo))
 }
 
+// @Tags region
+// @Summary Show where the replicas and the leader of a region were at a time, from the latest snapshot of the region topology taken at or before it.
+// @Param id path integer true "Region Id"
+// @Param time query integer false "The time in unix seconds, now by default."
+// @Produce json
+// @Success 200 {object} topohistory.Record
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The region is not in any snapshot taken at or before the time."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /region/id/{id}/topology [get]
+func (h *regionHandler) GetRegionTopology(w http.ResponseWriter, r *http.Request) {
+	regionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	t := time.Now()
+	if v := r.URL.Query().Get("time"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		t = time.Unix(sec, 0)
+	}
+	record, err := h.svr.GetHandler().GetRegionTopology(regionID, t)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if record == nil {
+		h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("region %d is not in any snapshot taken at or before %s", regionID, t))
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, record)
+}
+
 // @Tags region
 // @Summary Search for a region by a key.
 // @Param key path string true "Region key"
@@ -246,6 +286,8 @@ func (h *regionsHandler) GetRegionCount(w http.ResponseWriter, r *http.Request)
 // @Tags region
 // @Summary List all regions of a specific store.
 // @Param id path integer true "Store Id"
+// @Param key query string false "Region key, to paginate the regions in key order"
+// @Param limit query integer false "Limit count"
 // @Produce json
 // @Success 200 {object} RegionsInfo
 // @Failure 400 {string} string "The input is invalid."
@@ -259,7 +301,26 @@ func (h *regionsHandler) GetStoreRegions(w http.ResponseWriter, r *http.Request)
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	regions := rc.GetStoreRegions(uint64(id))
+	query := r.URL.Query()
+	// Keep returning all regions if pagination is not requested.
+	if _, ok := query["limit"]; !ok && query.Get("key") == "" {
+		regions := rc.GetStoreRegions(uint64(id))
+		regionsInfo := convertToAPIRegions(regions)
+		h.rd.JSON(w, http.StatusOK, regionsInfo)
+		return
+	}
+	limit := defaultRegionLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if limit > maxRegionLimit {
+		limit = maxRegionLimit
+	}
+	regions := rc.ScanStoreRegions(uint64(id), []byte(query.Get("key")), limit)
 	regionsInfo := convertToAPIRegions(regions)
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
@@ -633,6 +694,41 @@ func (h *regionsHandler) AccelerateRegionsScheduleInRange(w http.ResponseWriter,
 	h.rd.Text(w, http.StatusOK, fmt.Sprintf("Accelerate regions scheduling in a given range [%s,%s)", rawStartKey, rawEndKey))
 }
 
+// @Tags region
+// @Summary List the latest region heartbeats rejected for stale epochs, the latest first.
+// @Param region_id query integer false "Only the conflicts of the region, as the reported or the current region"
+// @Param store_id query integer false "Only the conflicts reported by the store"
+// @Param limit query integer false "Limit count"
+// @Produce json
+// @Success 200 {array} cluster.EpochConflict
+// @Failure 400 {string} string "The input is invalid."
+// @Router /regions/epoch-conflicts [get]
+func (h *regionsHandler) GetEpochConflicts(w http.ResponseWriter, r *http.Request) {
+	var regionID, storeID uint64
+	var limit int
+	var err error
+	query := r.URL.Query()
+	if v := query.Get("region_id"); v != "" {
+		if regionID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if v := query.Get("store_id"); v != "" {
+		if storeID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, h.svr.GetRaftCluster().GetEpochConflicts(regionID, storeID, limit))
+}
+
 func (h *regionsHandler) GetTopNRegions(w http.ResponseWriter, r *http.Request, less func(a, b *core.RegionInfo) bool) {
 	rc := h.svr.GetRaftCluster()
 	limit := defaultRegionLimit
@@ -706,10 +802,11 @@ func (h *regionsHandler) ScatterRegions(w http.ResponseWriter, r *http.Request)
 	// If there existed any operator failed to be added into Operator Controller, add its regions into unProcessedRegions
 	for _, op := range ops {
 		if ok := rc.GetOperatorController().AddOperator(op); !ok {
-			failures[op.RegionID()] = fmt.Errorf("region %v failed to add operator", op.RegionID())
+			failures[op.RegionID()] = errs.ErrScatterAddOperator.FastGenByArgs(op.RegionID())
 		}
 	}
 	percentage := 100
+	scatterFailures := make([]*grpcutil.ScatterFailure, 0, len(failures))
 	if len(failures) > 0 {
 		percentage = 100 - 100*len(failures)/(len(ops)+len(failures))
 		log.Debug("scatter regions", zap.Errors("failures", func() []error {
@@ -719,17 +816,25 @@ func (h *regionsHandler) ScatterRegions(w http.ResponseWriter, r *http.Request)
 			}
 			return r
 		}()))
+		for regionID, err := range failures {
+			scatterFailures = append(scatterFailures, grpcutil.NewScatterFailure(regionID, err))
+		}
+		sort.Slice(scatterFailures, func(i, j int) bool {
+			return scatterFailures[i].RegionID < scatterFailures[j].RegionID
+		})
 	}
 	s := struct {
-		ProcessedPercentage int `json:"processed-percentage"`
+		ProcessedPercentage int                        `json:"processed-percentage"`
+		Failures            []*grpcutil.ScatterFailure `json:"failures"`
 	}{
 		ProcessedPercentage: percentage,
+		Failures:            scatterFailures,
 	}
 	h.rd.JSON(w, http.StatusOK, &s)
 }
 
 // @Tags region
-// @Summary Split regions with given split keys
+// @Summary Split regions with given split keys, or split the key range between start_key and end_key until the regions reach target_size in MB or target_count
 // @Accept json
 // @Param body body object true "json params"
 // @Produce json
@@ -742,6 +847,35 @@ func (h *regionsHandler) SplitRegions(w http.ResponseWriter, r *http.Request) {
 	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
 		return
 	}
+	retryLimit, ok := input["retry_limit"].(int)
+	if !ok {
+		retryLimit = 5
+	}
+	s := struct {
+		ProcessedPercentage int      `json:"processed-percentage"`
+		NewRegionsID        []uint64 `json:"regions-id"`
+	}{}
+	targetSize, hasSize := input["target_size"].(float64)
+	targetCount, hasCount := input["target_count"].(float64)
+	if hasSize || hasCount {
+		if targetSize < 0 || targetCount < 0 || (targetSize == 0 && targetCount == 0) {
+			h.rd.JSON(w, http.StatusBadRequest, "target_size or target_count should be positive.")
+			return
+		}
+		var keys [2][]byte
+		for i, name := range []string{"start_key", "end_key"} {
+			rawKey, _ := input[name].(string)
+			key, err := hex.DecodeString(rawKey)
+			if err != nil {
+				h.rd.JSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			keys[i] = key
+		}
+		s.ProcessedPercentage, s.NewRegionsID = rc.GetRegionSplitter().SplitRegionsBySize(r.Context(), keys[0], keys[1], uint64(targetSize), uint64(targetCount), retryLimit)
+		h.rd.JSON(w, http.StatusOK, &s)
+		return
+	}
 	rawSplitKeys, ok := input["split_keys"].([]interface{})
 	if !ok {
 		h.rd.JSON(w, http.StatusBadRequest, "split_keys should be provided.")
@@ -751,10 +885,6 @@ func (h *regionsHandler) SplitRegions(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusBadRequest, "empty split keys.")
 		return
 	}
-	retryLimit, ok := input["retry_limit"].(int)
-	if !ok {
-		retryLimit = 5
-	}
 	splitKeys := make([][]byte, 0, len(rawSplitKeys))
 	for _, rawKey := range rawSplitKeys {
 		key, err := hex.DecodeString(rawKey.(string))
@@ -764,10 +894,6 @@ func (h *regionsHandler) SplitRegions(w http.ResponseWriter, r *http.Request) {
 		}
 		splitKeys = append(splitKeys, key)
 	}
-	s := struct {
-		ProcessedPercentage int      `json:"processed-percentage"`
-		NewRegionsID        []uint64 `json:"regions-id"`
-	}{}
 	percentage, newRegionsID := rc.GetRegionSplitter().SplitRegions(r.Context(), splitKeys, retryLimit)
 	s.ProcessedPercentage = percentage
 	s.NewRegionsID = newRegionsID
@@ -0,0 +1,197 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tikv/pd/pkg/apiutil/serverapi"
+	"github.com/tikv/pd/server"
+	syncer "github.com/tikv/pd/server/region_syncer"
+	"github.com/unrolled/render"
+)
+
+const (
+	regionSyncerVerifyURL     = "/pd/api/v1/admin/region-syncer/verify/self"
+	regionSyncerVerifyTimeout = 30 * time.Second
+)
+
+// RegionSyncerVerifyResult is the result of verifying the regions synced by a
+// follower.
+type RegionSyncerVerifyResult struct {
+	Name   string               `json:"name"`
+	Report *syncer.VerifyReport `json:"report,omitempty"`
+	// Error is set if the follower fails to verify.
+	Error string `json:"error,omitempty"`
+}
+
+type regionSyncerHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newRegionSyncerHandler(svr *server.Server, rd *render.Render) *regionSyncerHandler {
+	return &regionSyncerHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags admin
+// @Summary Get the checksums of the regions in the chunks of the key space, which are compared by the followers.
+// @Produce json
+// @Success 200 {array} syncer.Chunk
+// @Router /admin/region-syncer/chunks [get]
+func (h *regionSyncerHandler) GetChunks(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetRegionSyncerChunks())
+}
+
+// @Tags admin
+// @Summary Get the digests of the regions starting in a key range.
+// @Param start_key query string false "The hex encoded start key of the range"
+// @Param end_key query string false "The hex encoded end key of the range"
+// @Produce json
+// @Success 200 {array} syncer.RegionDigest
+// @Failure 400 {string} string "The input is invalid."
+// @Router /admin/region-syncer/digests [get]
+func (h *regionSyncerHandler) GetDigests(w http.ResponseWriter, r *http.Request) {
+	startKey, err := hex.DecodeString(r.URL.Query().Get("start_key"))
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	endKey, err := hex.DecodeString(r.URL.Query().Get("end_key"))
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, h.svr.GetRegionSyncerDigests(startKey, endKey))
+}
+
+// @Tags admin
+// @Summary Verify the regions synced from the leader on all the followers now.
+// @Produce json
+// @Success 200 {array} RegionSyncerVerifyResult
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /admin/region-syncer/verify [post]
+func (h *regionSyncerHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	members, err := getMembers(h.svr)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	results := make([]RegionSyncerVerifyResult, 0, len(members.GetMembers()))
+	var urls [][]string
+	for _, m := range members.GetMembers() {
+		if m.GetMemberId() == members.GetLeader().GetMemberId() {
+			continue
+		}
+		results = append(results, RegionSyncerVerifyResult{Name: m.GetName()})
+		urls = append(urls, m.GetClientUrls())
+	}
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(res *RegionSyncerVerifyResult, urls []string) {
+			defer wg.Done()
+			report, err := h.verifyMember(r.Context(), urls)
+			if err != nil {
+				res.Error = err.Error()
+				return
+			}
+			res.Report = report
+		}(&results[i], urls[i])
+	}
+	wg.Wait()
+	h.rd.JSON(w, http.StatusOK, results)
+}
+
+// verifyMember tries the client URLs of the member in turn.
+func (h *regionSyncerHandler) verifyMember(ctx context.Context, urls []string) (*syncer.VerifyReport, error) {
+	err := fmt.Errorf("no client url")
+	for _, url := range urls {
+		var report *syncer.VerifyReport
+		if report, err = h.verifyMemberAt(ctx, url); err == nil {
+			return report, nil
+		}
+	}
+	return nil, err
+}
+
+func (h *regionSyncerHandler) verifyMemberAt(ctx context.Context, url string) (*syncer.VerifyReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, regionSyncerVerifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+regionSyncerVerifyURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Let the follower verify by itself instead of redirecting the request
+	// to the leader.
+	req.Header.Set(serverapi.AllowFollowerHandle, "true")
+	resp, err := h.svr.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var msg string
+		if json.NewDecoder(resp.Body).Decode(&msg) != nil {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return nil, fmt.Errorf("[%d] %s", resp.StatusCode, msg)
+	}
+	report := &syncer.VerifyReport{}
+	if err := json.NewDecoder(resp.Body).Decode(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// @Tags admin
+// @Summary Verify the regions synced from the leader on the follower which serves the request now.
+// @Description The request is redirected to the leader unless the header PD-Allow-follower-handle is set.
+// @Produce json
+// @Success 200 {object} syncer.VerifyReport
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /admin/region-syncer/verify/self [post]
+func (h *regionSyncerHandler) VerifySelf(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svr.VerifyRegionSyncer(r.Context())
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, report)
+}
+
+// @Tags admin
+// @Summary Get the report of the last verification on the follower which serves the request.
+// @Description The request is redirected to the leader unless the header PD-Allow-follower-handle is set.
+// @Produce json
+// @Success 200 {object} syncer.VerifyReport
+// @Failure 404 {string} string "There is no verification yet."
+// @Router /admin/region-syncer/verify/self [get]
+func (h *regionSyncerHandler) GetSelfReport(w http.ResponseWriter, r *http.Request) {
+	report := h.svr.GetRegionSyncerVerifyReport()
+	if report == nil {
+		h.rd.JSON(w, http.StatusNotFound, "there is no verification yet")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, report)
+}
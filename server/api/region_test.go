@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"net/url"
 	"sort"
 	"testing"
@@ -27,7 +28,9 @@ import (
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/core"
 )
 
@@ -101,6 +104,62 @@ func (s *testRegionSuite) TestRegion(c *C) {
 	c.Assert(r2, DeepEquals, NewRegionInfo(r))
 }
 
+func (s *testRegionSuite) TestRegionTopology(c *C) {
+	r := newTestRegionInfo(2, 1, []byte("a"), []byte("b"))
+	mustRegionHeartbeat(c, s.svr, r)
+	// No snapshot is taken before the interval passes.
+	url := fmt.Sprintf("%s/region/id/%d/topology", s.urlPrefix, r.GetID())
+	code, _ := requestStatusBody(c, testDialClient, http.MethodGet, url)
+	c.Assert(code, Equals, http.StatusNotFound)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodGet, url+"?time=abc")
+	c.Assert(code, Equals, http.StatusBadRequest)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodGet, fmt.Sprintf("%s/region/id/abc/topology", s.urlPrefix))
+	c.Assert(code, Equals, http.StatusBadRequest)
+}
+
+var _ = Suite(&testEpochConflictSuite{})
+
+type testEpochConflictSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testEpochConflictSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testEpochConflictSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testEpochConflictSuite) TestEpochConflicts(c *C) {
+	r := newTestRegionInfo(60, 1, []byte("x1"), []byte("x2"))
+	mustRegionHeartbeat(c, s.svr, r.Clone(core.WithIncVersion()))
+	c.Assert(s.svr.GetRaftCluster().HandleRegionHeartbeat(r), NotNil)
+
+	url := fmt.Sprintf("%s/regions/epoch-conflicts?region_id=%d", s.urlPrefix, r.GetID())
+	var conflicts []*cluster.EpochConflict
+	c.Assert(readJSON(testDialClient, url, &conflicts), IsNil)
+	c.Assert(conflicts, HasLen, 1)
+	c.Assert(conflicts[0].Reason, Equals, "stale-version")
+	c.Assert(conflicts[0].StoreID, Equals, uint64(1))
+	c.Assert(conflicts[0].Reported.Version, Equals, uint64(1))
+	c.Assert(conflicts[0].Current.Version, Equals, uint64(2))
+	c.Assert(conflicts[0].Current.StartKey, Equals, core.HexRegionKeyStr([]byte("x1")))
+
+	c.Assert(readJSON(testDialClient, url+"&store_id=2", &conflicts), IsNil)
+	c.Assert(conflicts, HasLen, 0)
+	code, _ := requestStatusBody(c, testDialClient, http.MethodGet, url+"&limit=abc")
+	c.Assert(code, Equals, http.StatusBadRequest)
+}
+
 func (s *testRegionSuite) TestRegionCheck(c *C) {
 	r := newTestRegionInfo(2, 1, []byte("a"), []byte("b"))
 	downPeer := &metapb.Peer{Id: 13, StoreId: 2}
@@ -212,6 +271,22 @@ func (s *testRegionSuite) TestStoreRegions(c *C) {
 		c.Assert(r.ID, Equals, regionIDs[i])
 	}
 
+	// paginate the regions of store 1
+	url = fmt.Sprintf("%s/regions/store/%d?limit=1", s.urlPrefix, 1)
+	r7 := &RegionsInfo{}
+	c.Assert(readJSON(testDialClient, url, r7), IsNil)
+	c.Assert(r7.Count, Equals, 1)
+	c.Assert(r7.Regions[0].ID, Equals, uint64(2))
+	url = fmt.Sprintf("%s/regions/store/%d?key=%s&limit=1", s.urlPrefix, 1, "b")
+	r8 := &RegionsInfo{}
+	c.Assert(readJSON(testDialClient, url, r8), IsNil)
+	c.Assert(r8.Count, Equals, 1)
+	c.Assert(r8.Regions[0].ID, Equals, uint64(3))
+	url = fmt.Sprintf("%s/regions/store/%d?key=%s&limit=1", s.urlPrefix, 1, "c")
+	r9 := &RegionsInfo{}
+	c.Assert(readJSON(testDialClient, url, r9), IsNil)
+	c.Assert(r9.Count, Equals, 0)
+
 	regionIDs = []uint64{4}
 	url = fmt.Sprintf("%s/regions/store/%d", s.urlPrefix, 2)
 	r5 := &RegionsInfo{}
@@ -291,8 +366,20 @@ func (s *testRegionSuite) TestScatterRegions(c *C) {
 	mustPutStore(c, s.svr, 15, metapb.StoreState_Up, []*metapb.StoreLabel{})
 	body := fmt.Sprintf(`{"start_key":"%s", "end_key": "%s"}`, hex.EncodeToString([]byte("b1")), hex.EncodeToString([]byte("b3")))
 
-	err := postJSON(testDialClient, fmt.Sprintf("%s/regions/scatter", s.urlPrefix), []byte(body))
+	result := &struct {
+		ProcessedPercentage int                        `json:"processed-percentage"`
+		Failures            []*grpcutil.ScatterFailure `json:"failures"`
+	}{}
+	err := postJSON(testDialClient, fmt.Sprintf("%s/regions/scatter", s.urlPrefix), []byte(body), func(res []byte, _ int) {
+		c.Assert(json.Unmarshal(res, result), IsNil)
+	})
 	c.Assert(err, IsNil)
+	c.Assert(result.Failures, NotNil)
+	// The failed regions are returned with the reasons.
+	c.Assert(result.ProcessedPercentage == 100, Equals, len(result.Failures) == 0)
+	for _, failure := range result.Failures {
+		c.Assert(failure.Reason, Not(Equals), grpcutil.ScatterReason(""))
+	}
 	op1 := s.svr.GetRaftCluster().GetOperatorController().GetOperator(601)
 	op2 := s.svr.GetRaftCluster().GetOperatorController().GetOperator(602)
 	op3 := s.svr.GetRaftCluster().GetOperatorController().GetOperator(603)
@@ -326,6 +413,58 @@ func (s *testRegionSuite) TestSplitRegions(c *C) {
 	c.Assert(err, IsNil)
 }
 
+var _ = Suite(&testSplitBySizeSuite{})
+
+// testSplitBySizeSuite has its own server, so that its regions are not counted
+// by the tests of testRegionSuite.
+type testSplitBySizeSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testSplitBySizeSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testSplitBySizeSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testSplitBySizeSuite) TestSplitRegionsBySize(c *C) {
+	r := newTestRegionInfo(701, 13, []byte("split-size-a"), []byte("split-size-b"))
+	mustRegionHeartbeat(c, s.svr, r)
+	url := fmt.Sprintf("%s/regions/split", s.urlPrefix)
+	body := fmt.Sprintf(`{"start_key": "%s", "end_key": "%s", "target_size": 64}`,
+		hex.EncodeToString([]byte("split-size-a")), hex.EncodeToString([]byte("split-size-b")))
+	// The region is smaller than the target size, so it is not split.
+	err := postJSON(testDialClient, url, []byte(body), func(res []byte, code int) {
+		result := &struct {
+			ProcessedPercentage int      `json:"processed-percentage"`
+			NewRegionsID        []uint64 `json:"regions-id"`
+		}{}
+		c.Assert(json.Unmarshal(res, result), IsNil)
+		c.Assert(result.ProcessedPercentage, Equals, 100)
+		c.Assert(result.NewRegionsID, HasLen, 0)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.svr.GetRaftCluster().GetOperatorController().GetOperator(701), IsNil)
+
+	for _, body := range []string{
+		`{"start_key": "zz", "target_size": 64}`,
+		`{"target_size": 0}`,
+		`{"target_count": -1}`,
+	} {
+		c.Assert(postJSON(testDialClient, url, []byte(body)), NotNil)
+	}
+}
+
 func (s *testRegionSuite) checkTopRegions(c *C, url string, regionIDs []uint64) {
 	regions := &RegionsInfo{}
 	err := readJSON(testDialClient, url, regions)
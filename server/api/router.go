@@ -21,6 +21,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/failpoint"
+	"github.com/tikv/pd/pkg/idempotency"
 	"github.com/tikv/pd/server"
 	"github.com/unrolled/render"
 )
@@ -54,22 +55,60 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) *mux.R
 	handler := svr.GetHandler()
 
 	apiPrefix := "/api/v1"
+	apiV2Prefix := "/api/v2"
 	apiRouter := rootRouter.PathPrefix(apiPrefix).Subrouter()
 
 	clusterRouter := apiRouter.NewRoute().Subrouter()
 	clusterRouter.Use(newClusterMiddleware(svr).Middleware)
+	// The config changes made by the requests of configRouter are attributed
+	// to the callers in the config history.
+	configRouter := apiRouter.NewRoute().Subrouter()
+	configRouter.Use(newConfigHistoryMiddleware(svr).Middleware)
+	// The v1 endpoints covered by the v2 API keep working, but are marked as
+	// deprecated in favor of their successors.
+	apiRouter.Use(newDeprecationMiddleware(map[string]string{
+		prefix + apiPrefix + "/regions":               prefix + apiV2Prefix + "/regions",
+		prefix + apiPrefix + "/regions/key":           prefix + apiV2Prefix + "/regions",
+		prefix + apiPrefix + "/region/id/{id}":        prefix + apiV2Prefix + "/regions/{id}",
+		prefix + apiPrefix + "/stores":                prefix + apiV2Prefix + "/stores",
+		prefix + apiPrefix + "/store/{id}":            prefix + apiV2Prefix + "/stores/{id}",
+		prefix + apiPrefix + "/operators":             prefix + apiV2Prefix + "/operators",
+		prefix + apiPrefix + "/operators/{region_id}": prefix + apiV2Prefix + "/operators/{region_id}",
+	}).Middleware)
+
+	apiRouter.Use(newIdempotencyMiddleware(idempotency.NewCache(idempotency.DefaultTTL, idempotency.DefaultCapacity)).Middleware)
+
+	apiV2Router := rootRouter.PathPrefix(apiV2Prefix).Subrouter()
+	apiV2Router.Use(newClusterMiddleware(svr).Middleware)
+	regionsV2Handler := newRegionsV2Handler(svr, rd)
+	apiV2Router.HandleFunc("/regions", regionsV2Handler.List).Methods("GET")
+	apiV2Router.HandleFunc("/regions/{id}", regionsV2Handler.Get).Methods("GET")
+	apiV2Router.HandleFunc("/regions/check/down-peer", regionsV2Handler.ListDownPeer).Methods("GET")
+	apiV2Router.HandleFunc("/regions/check/pending-peer", regionsV2Handler.ListPendingPeer).Methods("GET")
+	storesV2Handler := newStoresV2Handler(handler, rd)
+	apiV2Router.HandleFunc("/stores", storesV2Handler.List).Methods("GET")
+	apiV2Router.HandleFunc("/stores/{id}", storesV2Handler.Get).Methods("GET")
+	operatorsV2Handler := newOperatorsV2Handler(handler, rd)
+	apiV2Router.HandleFunc("/operators", operatorsV2Handler.List).Methods("GET")
+	apiV2Router.HandleFunc("/operators/{region_id}", operatorsV2Handler.Get).Methods("GET")
 
 	operatorHandler := newOperatorHandler(handler, rd)
 	apiRouter.HandleFunc("/operators", operatorHandler.List).Methods("GET")
 	apiRouter.HandleFunc("/operators", operatorHandler.Post).Methods("POST")
+	apiRouter.HandleFunc("/operators", operatorHandler.DeleteMatched).Methods("DELETE")
+	apiRouter.HandleFunc("/operators/history", operatorHandler.History).Methods("GET")
+	apiRouter.HandleFunc("/operators/conflicts", operatorHandler.Conflicts).Methods("GET")
+	apiRouter.HandleFunc("/operators/watch", operatorHandler.Watch).Methods("GET")
 	apiRouter.HandleFunc("/operators/{region_id}", operatorHandler.Get).Methods("GET")
 	apiRouter.HandleFunc("/operators/{region_id}", operatorHandler.Delete).Methods("DELETE")
 
 	schedulerHandler := newSchedulerHandler(svr, rd)
 	apiRouter.HandleFunc("/schedulers", schedulerHandler.List).Methods("GET")
-	apiRouter.HandleFunc("/schedulers", schedulerHandler.Post).Methods("POST")
-	apiRouter.HandleFunc("/schedulers/{name}", schedulerHandler.Delete).Methods("DELETE")
+	configRouter.HandleFunc("/schedulers", schedulerHandler.Post).Methods("POST")
+	apiRouter.HandleFunc("/schedulers/estimate", schedulerHandler.Estimate).Methods("GET")
+	configRouter.HandleFunc("/schedulers/{name}", schedulerHandler.Delete).Methods("DELETE")
 	apiRouter.HandleFunc("/schedulers/{name}", schedulerHandler.PauseOrResume).Methods("POST")
+	apiRouter.HandleFunc("/schedulers/{name}/dry-run", schedulerHandler.DryRun).Methods("GET")
 
 	schedulerConfigHandler := newSchedulerConfigHandler(svr, rd)
 	apiRouter.PathPrefix("/scheduler-config").Handler(schedulerConfigHandler)
@@ -77,21 +116,28 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) *mux.R
 	clusterHandler := newClusterHandler(svr, rd)
 	apiRouter.Handle("/cluster", clusterHandler).Methods("GET")
 	apiRouter.HandleFunc("/cluster/status", clusterHandler.GetClusterStatus).Methods("GET")
+	clusterRouter.HandleFunc("/cluster/topology", clusterHandler.CheckTopology).Methods("GET")
 
 	confHandler := newConfHandler(svr, rd)
 	apiRouter.HandleFunc("/config", confHandler.Get).Methods("GET")
-	apiRouter.HandleFunc("/config", confHandler.Post).Methods("POST")
+	configRouter.HandleFunc("/config", confHandler.Post).Methods("POST")
 	apiRouter.HandleFunc("/config/default", confHandler.GetDefault).Methods("GET")
+	apiRouter.HandleFunc("/config/revision", confHandler.GetRevision).Methods("GET")
+	apiRouter.HandleFunc("/config/history", confHandler.GetHistory).Methods("GET")
+	apiRouter.HandleFunc("/config/history/{version}", confHandler.GetVersion).Methods("GET")
+	apiRouter.HandleFunc("/config/rollback/{version}", confHandler.Rollback).Methods("POST")
+	apiRouter.HandleFunc("/config/ttl", confHandler.GetTTL).Methods("GET")
+	configRouter.HandleFunc("/config/ttl/{key}", confHandler.DeleteTTL).Methods("DELETE")
 	apiRouter.HandleFunc("/config/schedule", confHandler.GetSchedule).Methods("GET")
-	apiRouter.HandleFunc("/config/schedule", confHandler.SetSchedule).Methods("POST")
+	configRouter.HandleFunc("/config/schedule", confHandler.SetSchedule).Methods("POST")
 	apiRouter.HandleFunc("/config/replicate", confHandler.GetReplication).Methods("GET")
-	apiRouter.HandleFunc("/config/replicate", confHandler.SetReplication).Methods("POST")
+	configRouter.HandleFunc("/config/replicate", confHandler.SetReplication).Methods("POST")
 	apiRouter.HandleFunc("/config/label-property", confHandler.GetLabelProperty).Methods("GET")
-	apiRouter.HandleFunc("/config/label-property", confHandler.SetLabelProperty).Methods("POST")
+	configRouter.HandleFunc("/config/label-property", confHandler.SetLabelProperty).Methods("POST")
 	apiRouter.HandleFunc("/config/cluster-version", confHandler.GetClusterVersion).Methods("GET")
-	apiRouter.HandleFunc("/config/cluster-version", confHandler.SetClusterVersion).Methods("POST")
+	configRouter.HandleFunc("/config/cluster-version", confHandler.SetClusterVersion).Methods("POST")
 	apiRouter.HandleFunc("/config/replication-mode", confHandler.GetReplicationMode).Methods("GET")
-	apiRouter.HandleFunc("/config/replication-mode", confHandler.SetReplicationMode).Methods("POST")
+	configRouter.HandleFunc("/config/replication-mode", confHandler.SetReplicationMode).Methods("POST")
 
 	rulesHandler := newRulesHandler(svr, rd)
 	clusterRouter.HandleFunc("/config/rules", rulesHandler.GetAll).Methods("GET")
@@ -125,9 +171,24 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) *mux.R
 	clusterRouter.HandleFunc("/store/{id}/label", storeHandler.SetLabels).Methods("POST")
 	clusterRouter.HandleFunc("/store/{id}/weight", storeHandler.SetWeight).Methods("POST")
 	clusterRouter.HandleFunc("/store/{id}/limit", storeHandler.SetLimit).Methods("POST")
+	clusterRouter.HandleFunc("/store/{id}/drain", storeHandler.Drain).Methods("POST")
+	clusterRouter.HandleFunc("/store/{id}/drain", storeHandler.CancelDrain).Methods("DELETE")
+	clusterRouter.HandleFunc("/store/{id}/maintenance", storeHandler.SetMaintenance).Methods("POST")
+	clusterRouter.HandleFunc("/store/{id}/maintenance", storeHandler.GetMaintenance).Methods("GET")
+	clusterRouter.HandleFunc("/store/{id}/maintenance", storeHandler.CancelMaintenance).Methods("DELETE")
+	clusterRouter.HandleFunc("/store/{id}/offline-plan", storeHandler.GetOfflinePlan).Methods("GET")
 	storesHandler := newStoresHandler(handler, rd)
 	clusterRouter.Handle("/stores", storesHandler).Methods("GET")
 	clusterRouter.HandleFunc("/stores/remove-tombstone", storesHandler.RemoveTombStone).Methods("DELETE")
+	clusterRouter.HandleFunc("/stores/archived", storesHandler.GetArchived).Methods("GET")
+	clusterRouter.HandleFunc("/stores/archived/{id}/restore", storesHandler.RestoreArchived).Methods("POST")
+	clusterRouter.HandleFunc("/stores/label", storesHandler.SetLabels).Methods("POST")
+	clusterRouter.HandleFunc("/stores/progress", storesHandler.GetProgress).Methods("GET")
+
+	leaderTransferHandler := newLeaderTransferHandler(svr, rd)
+	clusterRouter.HandleFunc("/leader-transfer/simulate", leaderTransferHandler.Simulate).Methods("POST")
+	storeLossHandler := newStoreLossHandler(svr, rd)
+	clusterRouter.HandleFunc("/store-loss/simulate", storeLossHandler.Simulate).Methods("POST")
 	clusterRouter.HandleFunc("/stores/limit", storesHandler.GetAllLimit).Methods("GET")
 	clusterRouter.HandleFunc("/stores/limit", storesHandler.SetAllLimit).Methods("POST")
 	clusterRouter.HandleFunc("/stores/limit/scene", storesHandler.SetStoreLimitScene).Methods("POST")
@@ -137,13 +198,24 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) *mux.R
 	clusterRouter.HandleFunc("/labels", labelsHandler.Get).Methods("GET")
 	clusterRouter.HandleFunc("/labels/stores", labelsHandler.GetStores).Methods("GET")
 
+	storeTierHandler := newStoreTierHandler(handler, rd)
+	clusterRouter.HandleFunc("/tiers", storeTierHandler.List).Methods("GET")
+	clusterRouter.HandleFunc("/tiers/ranges", storeTierHandler.MoveRange).Methods("POST")
+
+	maintenanceHandler := newMaintenanceHandler(handler, rd)
+	clusterRouter.HandleFunc("/maintenance", maintenanceHandler.GetBudget).Methods("GET")
+	clusterRouter.HandleFunc("/maintenance", maintenanceHandler.SetMaintenance).Methods("POST")
+
 	hotStatusHandler := newHotStatusHandler(handler, rd)
 	apiRouter.HandleFunc("/hotspot/regions/write", hotStatusHandler.GetHotWriteRegions).Methods("GET")
 	apiRouter.HandleFunc("/hotspot/regions/read", hotStatusHandler.GetHotReadRegions).Methods("GET")
+	apiRouter.HandleFunc("/hotspot/regions/history", hotStatusHandler.GetHotRegionHistory).Methods("GET")
 	apiRouter.HandleFunc("/hotspot/stores", hotStatusHandler.GetHotStores).Methods("GET")
+	apiRouter.HandleFunc("/hotspot/snapshot", hotStatusHandler.GetHotStatsSnapshot).Methods("GET")
 
 	regionHandler := newRegionHandler(svr, rd)
 	clusterRouter.HandleFunc("/region/id/{id}", regionHandler.GetRegionByID).Methods("GET")
+	clusterRouter.HandleFunc("/region/id/{id}/topology", regionHandler.GetRegionTopology).Methods("GET")
 	clusterRouter.UseEncodedPath().HandleFunc("/region/key/{key}", regionHandler.GetRegionByKey).Methods("GET")
 
 	srd := createStreamingRender()
@@ -170,15 +242,20 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) *mux.R
 	clusterRouter.HandleFunc("/regions/check/hist-size", regionsHandler.GetSizeHistogram).Methods("GET")
 	clusterRouter.HandleFunc("/regions/check/hist-keys", regionsHandler.GetKeysHistogram).Methods("GET")
 	clusterRouter.HandleFunc("/regions/sibling/{id}", regionsHandler.GetRegionSiblings).Methods("GET")
+	clusterRouter.HandleFunc("/regions/epoch-conflicts", regionsHandler.GetEpochConflicts).Methods("GET")
 	clusterRouter.HandleFunc("/regions/accelerate-schedule", regionsHandler.AccelerateRegionsScheduleInRange).Methods("POST")
 	clusterRouter.HandleFunc("/regions/scatter", regionsHandler.ScatterRegions).Methods("POST")
 	clusterRouter.HandleFunc("/regions/split", regionsHandler.SplitRegions).Methods("POST")
 
 	apiRouter.Handle("/version", newVersionHandler(rd)).Methods("GET")
+	versionSkewHandler := newVersionSkewHandler(svr, rd)
+	clusterRouter.HandleFunc("/version/skew", versionSkewHandler.GetVersionSkew).Methods("GET")
 	apiRouter.Handle("/status", newStatusHandler(svr, rd)).Methods("GET")
 
 	memberHandler := newMemberHandler(svr, rd)
 	apiRouter.HandleFunc("/members", memberHandler.ListMembers).Methods("GET")
+	apiRouter.HandleFunc("/members/metrics", memberHandler.GetMetrics).Methods("GET")
+	apiRouter.HandleFunc("/members/metrics/self", memberHandler.GetSelfMetrics).Methods("GET")
 	apiRouter.HandleFunc("/members/name/{name}", memberHandler.DeleteByName).Methods("DELETE")
 	apiRouter.HandleFunc("/members/id/{id}", memberHandler.DeleteByID).Methods("DELETE")
 	apiRouter.HandleFunc("/members/name/{name}", memberHandler.SetMemberPropertyByName).Methods("POST")
@@ -197,11 +274,29 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) *mux.R
 	adminHandler := newAdminHandler(svr, rd)
 	clusterRouter.HandleFunc("/admin/cache/region/{id}", adminHandler.HandleDropCacheRegion).Methods("DELETE")
 	clusterRouter.HandleFunc("/admin/reset-ts", adminHandler.ResetTS).Methods("POST")
+	clusterRouter.HandleFunc("/admin/regions/snapshot", adminHandler.ExportRegionSnapshot).Methods("GET")
+	clusterRouter.HandleFunc("/admin/regions/snapshot", adminHandler.ImportRegionSnapshot).Methods("POST")
 	apiRouter.HandleFunc("/admin/persist-file/{file_name}", adminHandler.persistFile).Methods("POST")
 	clusterRouter.HandleFunc("/admin/replication_mode/wait-async", adminHandler.UpdateWaitAsyncTime).Methods("POST")
+	apiRouter.HandleFunc("/admin/rolling-restart", adminHandler.GetRollingRestartStatus).Methods("GET")
+	apiRouter.HandleFunc("/admin/rolling-restart", adminHandler.StartRollingRestart).Methods("POST")
+	apiRouter.HandleFunc("/admin/rolling-restart", adminHandler.AbortRollingRestart).Methods("DELETE")
+	apiRouter.HandleFunc("/admin/rolling-restart/members/{name}/restarted", adminHandler.MarkRollingRestartMemberRestarted).Methods("POST")
+	apiRouter.HandleFunc("/admin/key-layout", adminHandler.CheckKeyLayout).Methods("GET")
+	apiRouter.HandleFunc("/admin/key-layout/orphans", adminHandler.CleanupOrphanKeys).Methods("DELETE")
+
+	regionSyncerHandler := newRegionSyncerHandler(svr, rd)
+	apiRouter.HandleFunc("/admin/region-syncer/chunks", regionSyncerHandler.GetChunks).Methods("GET")
+	apiRouter.HandleFunc("/admin/region-syncer/digests", regionSyncerHandler.GetDigests).Methods("GET")
+	apiRouter.HandleFunc("/admin/region-syncer/verify", regionSyncerHandler.Verify).Methods("POST")
+	apiRouter.HandleFunc("/admin/region-syncer/verify/self", regionSyncerHandler.VerifySelf).Methods("POST")
+	apiRouter.HandleFunc("/admin/region-syncer/verify/self", regionSyncerHandler.GetSelfReport).Methods("GET")
 
 	logHandler := newLogHandler(svr, rd)
 	apiRouter.HandleFunc("/admin/log", logHandler.Handle).Methods("POST")
+	apiRouter.HandleFunc("/admin/log", logHandler.Get).Methods("GET")
+	apiRouter.HandleFunc("/admin/log/modules/{module}", logHandler.EnableModuleDebug).Methods("POST")
+	apiRouter.HandleFunc("/admin/log/modules/{module}", logHandler.DisableModuleDebug).Methods("DELETE")
 
 	replicationModeHandler := newReplicationModeHandler(svr, rd)
 	clusterRouter.HandleFunc("/replication_mode/status", replicationModeHandler.GetStatus)
@@ -216,7 +311,9 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) *mux.R
 	apiRouter.HandleFunc("/plugin", pluginHandler.LoadPlugin).Methods("POST")
 	apiRouter.HandleFunc("/plugin", pluginHandler.UnloadPlugin).Methods("DELETE")
 
-	apiRouter.Handle("/health", newHealthHandler(svr, rd)).Methods("GET")
+	healthHandler := newHealthHandler(svr, rd)
+	apiRouter.Handle("/health", healthHandler).Methods("GET")
+	apiRouter.HandleFunc("/health/dependencies", healthHandler.GetDependencies).Methods("GET")
 	apiRouter.Handle("/diagnose", newDiagnoseHandler(svr, rd)).Methods("GET")
 	apiRouter.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
 	// metric query use to query metric data, the protocol is compatible with prometheus.
@@ -226,6 +323,20 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) *mux.R
 	// tso API
 	tsoHandler := newTSOHandler(svr, rd)
 	apiRouter.HandleFunc("/tso/allocator/transfer/{name}", tsoHandler.TransferLocalTSOAllocator).Methods("POST")
+	apiRouter.HandleFunc("/tso/leases", tsoHandler.GetLeases).Methods("GET")
+	apiRouter.HandleFunc("/tso/leases", tsoHandler.GrantLease).Methods("POST")
+	apiRouter.HandleFunc("/tso/leases/{id}", tsoHandler.RevokeLease).Methods("DELETE")
+
+	idHandler := newIDHandler(svr, rd)
+	apiRouter.HandleFunc("/id/reservations", idHandler.GetReservations).Methods("GET")
+
+	deprecatedRPCHandler := newDeprecatedRPCHandler(svr, rd)
+	apiRouter.HandleFunc("/deprecated-rpc/callers", deprecatedRPCHandler.GetCallers).Methods("GET")
+
+	jobHandler := newJobHandler(svr, rd)
+	apiRouter.HandleFunc("/jobs", jobHandler.List).Methods("GET")
+	apiRouter.HandleFunc("/jobs/{id}", jobHandler.Get).Methods("GET")
+	apiRouter.HandleFunc("/jobs/{id}", jobHandler.Cancel).Methods("DELETE")
 
 	// profile API
 	apiRouter.HandleFunc("/debug/pprof/profile", pprof.Profile)
@@ -242,6 +353,9 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) *mux.R
 	serviceGCSafepointHandler := newServiceGCSafepointHandler(svr, rd)
 	apiRouter.HandleFunc("/gc/safepoint", serviceGCSafepointHandler.List).Methods("GET")
 	apiRouter.HandleFunc("/gc/safepoint/{service_id}", serviceGCSafepointHandler.Delete).Methods("DELETE")
+	apiRouter.HandleFunc("/gc/pause", serviceGCSafepointHandler.Pause).Methods("POST")
+	apiRouter.HandleFunc("/gc/pause", serviceGCSafepointHandler.GetPause).Methods("GET")
+	apiRouter.HandleFunc("/gc/pause", serviceGCSafepointHandler.Resume).Methods("DELETE")
 
 	// API to set or unset failpoints
 	failpoint.Inject("enableFailpointAPI", func() {
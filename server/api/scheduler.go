@@ -17,6 +17,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -24,6 +25,7 @@ import (
 	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedulers"
 	"github.com/unrolled/render"
 )
@@ -169,7 +171,7 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 		}
 		err := h.AddGrantLeaderScheduler(uint64(storeID))
 		if errors.ErrorEqual(err, errs.ErrSchedulerExisted.FastGenByArgs()) {
-			if err := h.redirectSchedulerUpdate(schedulers.GrantLeaderName, storeID); err != nil {
+			if err := h.redirectSchedulerUpdate(schedulers.GrantLeaderName, storeID, nil); err != nil {
 				h.r.JSON(w, http.StatusInternalServerError, err.Error())
 				return
 			}
@@ -184,9 +186,23 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusBadRequest, "missing store id")
 			return
 		}
-		err := h.AddEvictLeaderScheduler(uint64(storeID))
+		ranges, ok := input["ranges"].([]interface{})
+		if input["ranges"] != nil && (!ok || len(ranges)%2 != 0) {
+			h.r.JSON(w, http.StatusBadRequest, "invalid ranges")
+			return
+		}
+		var args []string
+		for _, key := range ranges {
+			k, ok := key.(string)
+			if !ok {
+				h.r.JSON(w, http.StatusBadRequest, "invalid ranges")
+				return
+			}
+			args = append(args, url.QueryEscape(k))
+		}
+		err := h.AddEvictLeaderScheduler(uint64(storeID), args...)
 		if errors.ErrorEqual(err, errs.ErrSchedulerExisted.FastGenByArgs()) {
-			if err := h.redirectSchedulerUpdate(schedulers.EvictLeaderName, storeID); err != nil {
+			if err := h.redirectSchedulerUpdate(schedulers.EvictLeaderName, storeID, ranges); err != nil {
 				h.r.JSON(w, http.StatusInternalServerError, err.Error())
 				return
 			}
@@ -228,10 +244,13 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 	h.r.JSON(w, http.StatusOK, "The scheduler is created.")
 }
 
-func (h *schedulerHandler) redirectSchedulerUpdate(name string, storeID float64) error {
+func (h *schedulerHandler) redirectSchedulerUpdate(name string, storeID float64, ranges []interface{}) error {
 	input := make(map[string]interface{})
 	input["name"] = name
 	input["store_id"] = storeID
+	if ranges != nil {
+		input["ranges"] = ranges
+	}
 	updateURL := fmt.Sprintf("%s/%s/%s/config", h.GetAddr(), schedulerConfigPrefix, name)
 	body, err := json.Marshal(input)
 	if err != nil {
@@ -322,6 +341,57 @@ func (h *schedulerHandler) PauseOrResume(w http.ResponseWriter, r *http.Request)
 	h.r.JSON(w, http.StatusOK, "Pause or resume the scheduler successfully.")
 }
 
+// SchedulerDryRun is the result of running a scheduler once in simulation.
+type SchedulerDryRun struct {
+	Name string `json:"name"`
+	// Allowed is false if the scheduler is not allowed to schedule now, such
+	// as when the operator limit is reached. The operators are generated
+	// anyway.
+	Allowed   bool                 `json:"allowed"`
+	Operators []*operator.Operator `json:"operators"`
+}
+
+// @Tags scheduler
+// @Summary Run a scheduler once and return the operators it would generate, without adding them.
+// @Param name path string true "The name of a running scheduler, or the type of a new scheduler."
+// @Param arg query []string false "The arguments to create the new scheduler, in order."
+// @Produce json
+// @Success 200 {object} SchedulerDryRun
+// @Failure 400 {string} string "The arguments are invalid."
+// @Failure 404 {string} string "The scheduler is not found."
+// @Router /schedulers/{name}/dry-run [get]
+func (h *schedulerHandler) DryRun(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	ops, allowed, err := h.DryRunScheduler(name, r.URL.Query()["arg"]...)
+	if err != nil {
+		if errors.ErrorEqual(err, errs.ErrSchedulerCreateFuncNotRegistered.FastGenByArgs()) {
+			h.r.JSON(w, http.StatusNotFound, err.Error())
+		} else {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	if ops == nil {
+		ops = []*operator.Operator{}
+	}
+	h.r.JSON(w, http.StatusOK, &SchedulerDryRun{Name: name, Allowed: allowed, Operators: ops})
+}
+
+// @Tags scheduler
+// @Summary Estimate the operators generated by the schedulers and the checkers in the next hour, and the data they move.
+// @Produce json
+// @Success 200 {object} cluster.OperatorRateEstimates
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /schedulers/estimate [get]
+func (h *schedulerHandler) Estimate(w http.ResponseWriter, r *http.Request) {
+	estimates, err := h.EstimateOperatorRates()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, estimates)
+}
+
 type schedulerConfigHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -16,12 +16,16 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
 	_ "github.com/tikv/pd/server/schedulers"
 )
 
@@ -88,6 +92,85 @@ func (s *testScheduleSuite) TestOriginAPI(c *C) {
 	c.Assert(r.StatusCode, Equals, 404)
 }
 
+func (s *testScheduleSuite) TestEvictLeaderRanges(c *C) {
+	input := map[string]interface{}{
+		"name":     "evict-leader-scheduler",
+		"store_id": 1,
+		"ranges":   []string{"a", "b%"},
+	}
+	body, err := json.Marshal(input)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, s.urlPrefix, body), IsNil)
+	rc := s.svr.GetRaftCluster()
+	c.Assert(rc.GetStore(1).AllowLeaderTransfer(), IsTrue)
+
+	listURL := fmt.Sprintf("%s%s%s/%s/list", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, "evict-leader-scheduler")
+	resp := make(map[string]map[uint64][]core.KeyRange)
+	c.Assert(readJSON(testDialClient, listURL, &resp), IsNil)
+	c.Assert(resp["store-id-ranges"][1], DeepEquals, []core.KeyRange{core.NewKeyRange("a", "b%")})
+
+	// Evict all leaders of the store.
+	input["ranges"] = []string{"", ""}
+	body, err = json.Marshal(input)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, s.urlPrefix, body), IsNil)
+	c.Assert(rc.GetStore(1).AllowLeaderTransfer(), IsFalse)
+
+	input["ranges"] = []string{"a"}
+	body, err = json.Marshal(input)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, s.urlPrefix, body), NotNil)
+
+	_, err = doDelete(testDialClient, fmt.Sprintf("%s/%s", s.urlPrefix, "evict-leader-scheduler-1"))
+	c.Assert(err, IsNil)
+	c.Assert(rc.GetSchedulers(), HasLen, 0)
+	c.Assert(rc.GetStore(1).AllowLeaderTransfer(), IsTrue)
+}
+
+func (s *testScheduleSuite) TestDryRun(c *C) {
+	meta := &metapb.Region{
+		Id:          100,
+		StartKey:    []byte("dry-run-a"),
+		EndKey:      []byte("dry-run-b"),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		Peers:       []*metapb.Peer{{Id: 101, StoreId: 2}, {Id: 102, StoreId: 1}},
+	}
+	mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(meta, meta.Peers[0]))
+
+	// The operators are encoded as strings.
+	result := &struct {
+		Name      string   `json:"name"`
+		Allowed   bool     `json:"allowed"`
+		Operators []string `json:"operators"`
+	}{}
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/grant-leader/dry-run?arg=1", result), IsNil)
+	c.Assert(result.Name, Equals, "grant-leader")
+	c.Assert(result.Allowed, IsTrue)
+	c.Assert(result.Operators, HasLen, 1)
+	c.Assert(strings.HasPrefix(result.Operators[0], "grant-leader"), IsTrue)
+	c.Assert(s.svr.GetRaftCluster().GetOperatorController().GetOperators(), HasLen, 0)
+
+	code, _ := requestStatusBody(c, testDialClient, http.MethodGet, s.urlPrefix+"/grant-leader/dry-run?arg=x")
+	c.Assert(code, Equals, http.StatusBadRequest)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodGet, s.urlPrefix+"/no-such-scheduler/dry-run")
+	c.Assert(code, Equals, http.StatusNotFound)
+}
+
+func (s *testScheduleSuite) TestEstimate(c *C) {
+	result := &cluster.OperatorRateEstimates{}
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/estimate", result), IsNil)
+	c.Assert(result.Estimates, Not(HasLen), 0)
+	c.Assert(result.Estimates[0].Name, Equals, "checkers")
+	names := make(map[string]bool)
+	for _, est := range result.Estimates {
+		names[est.Name] = true
+	}
+	for _, name := range s.svr.GetRaftCluster().GetSchedulers() {
+		c.Assert(names[name], IsTrue)
+	}
+	c.Assert(s.svr.GetRaftCluster().GetOperatorController().GetOperators(), HasLen, 0)
+}
+
 func (s *testScheduleSuite) TestAPI(c *C) {
 	type arg struct {
 		opt   string
@@ -17,6 +17,10 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/pingcap/errors"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/core"
 	"github.com/unrolled/render"
@@ -37,6 +41,7 @@ func newServiceGCSafepointHandler(svr *server.Server, rd *render.Render) *servic
 type listServiceGCSafepoint struct {
 	ServiceGCSafepoints []*core.ServiceSafePoint `json:"service_gc_safe_points"`
 	GCSafePoint         uint64                   `json:"gc_safe_point"`
+	GCPause             *core.GCPause            `json:"gc_pause,omitempty"`
 }
 
 // @Tags servicegcsafepoint
@@ -57,9 +62,15 @@ func (h *serviceGCSafepointHandler) List(w http.ResponseWriter, r *http.Request)
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	pause, err := h.svr.GetGCPause()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	list := listServiceGCSafepoint{
 		GCSafePoint:         gcSafepoint,
 		ServiceGCSafepoints: ssps,
+		GCPause:             pause,
 	}
 	h.rd.JSON(w, http.StatusOK, list)
 }
@@ -82,3 +93,69 @@ func (h *serviceGCSafepointHandler) Delete(w http.ResponseWriter, r *http.Reques
 	}
 	h.rd.JSON(w, http.StatusOK, "Delete service GC safepoint successfully.")
 }
+
+// GCPauseInput is the input to pause the GC, both the reason and the ttl are
+// required.
+type GCPauseInput struct {
+	Reason string            `json:"reason"`
+	TTL    typeutil.Duration `json:"ttl"`
+}
+
+// @Tags servicegcsafepoint
+// @Summary Pause the GC of the cluster. The GC safe point is kept at the current one until the GC is resumed or the ttl expires.
+// @Param body body GCPauseInput true "json params, e.g. {\"reason\": \"investigate data loss\", \"ttl\": \"24h\"}"
+// @Produce json
+// @Success 200 {object} core.GCPause
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /gc/pause [post]
+func (h *serviceGCSafepointHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	var input GCPauseInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	pause, err := h.svr.PauseGC(input.Reason, input.TTL.Duration)
+	if err != nil {
+		if errors.ErrorEqual(err, errs.ErrInvalidGCPause.FastGenByArgs()) {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, pause)
+}
+
+// @Tags servicegcsafepoint
+// @Summary Get the GC pause.
+// @Produce json
+// @Success 200 {object} core.GCPause
+// @Failure 404 {string} string "The GC is not paused."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /gc/pause [get]
+func (h *serviceGCSafepointHandler) GetPause(w http.ResponseWriter, r *http.Request) {
+	pause, err := h.svr.GetGCPause()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if pause == nil {
+		h.rd.JSON(w, http.StatusNotFound, "the gc is not paused")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, pause)
+}
+
+// @Tags servicegcsafepoint
+// @Summary Resume the paused GC.
+// @Produce json
+// @Success 200 {string} string "The GC is resumed."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /gc/pause [delete]
+func (h *serviceGCSafepointHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.ResumeGC(); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The GC is resumed.")
+}
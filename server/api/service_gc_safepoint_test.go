@@ -14,12 +14,15 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/core"
@@ -93,3 +96,49 @@ func (s *testServiceGCSafepointSuite) TestRegionStats(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(left, DeepEquals, list.ServiceGCSafepoints[1:])
 }
+
+func (s *testServiceGCSafepointSuite) TestGCPause(c *C) {
+	pauseURL := s.urlPrefix + "/gc/pause"
+	storage := s.svr.GetStorage()
+	c.Assert(storage.SaveGCSafePoint(10), IsNil)
+	res, err := testDialClient.Get(pauseURL)
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusNotFound)
+	res.Body.Close()
+
+	// Both the reason and the ttl are required.
+	c.Assert(postJSON(testDialClient, pauseURL, []byte(`{"ttl": "1h"}`)), NotNil)
+	c.Assert(postJSON(testDialClient, pauseURL, []byte(`{"reason": "investigate"}`)), NotNil)
+	c.Assert(postJSON(testDialClient, pauseURL, []byte(`{"reason": "investigate", "ttl": "1h"}`)), IsNil)
+	pause := &core.GCPause{}
+	c.Assert(readJSON(testDialClient, pauseURL, pause), IsNil)
+	c.Assert(pause.Reason, Equals, "investigate")
+	c.Assert(pause.SafePoint, Equals, uint64(10))
+	c.Assert(pause.ExpiredAt-pause.StartedAt, Equals, int64(3600))
+	list := &listServiceGCSafepoint{}
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/gc/safepoint", list), IsNil)
+	c.Assert(list.GCPause, DeepEquals, pause)
+
+	// Neither the GC safe point nor the min service safe point moves beyond the pause.
+	header := &pdpb.RequestHeader{ClusterId: s.svr.ClusterID()}
+	ssp, err := s.svr.UpdateServiceGCSafePoint(context.Background(), &pdpb.UpdateServiceGCSafePointRequest{
+		Header: header, ServiceId: []byte("gc_worker"), TTL: math.MaxInt64, SafePoint: 20,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(string(ssp.GetServiceId()), Equals, "gc_pause")
+	c.Assert(ssp.GetMinSafePoint(), Equals, uint64(10))
+	gc, err := s.svr.UpdateGCSafePoint(context.Background(), &pdpb.UpdateGCSafePointRequest{Header: header, SafePoint: 20})
+	c.Assert(err, IsNil)
+	c.Assert(gc.GetNewSafePoint(), Equals, uint64(10))
+
+	res, err = doDelete(testDialClient, pauseURL)
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusOK)
+	res.Body.Close()
+	gc, err = s.svr.UpdateGCSafePoint(context.Background(), &pdpb.UpdateGCSafePointRequest{Header: header, SafePoint: 20})
+	c.Assert(err, IsNil)
+	c.Assert(gc.GetNewSafePoint(), Equals, uint64(20))
+
+	// Clean up the service safe point of gc_worker for the other tests.
+	c.Assert(storage.Remove("gc/safe_point/service/gc_worker"), IsNil)
+}
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -27,40 +28,46 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/keyspace"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/job"
 	"github.com/unrolled/render"
 )
 
 // MetaStore contains meta information about a store.
 type MetaStore struct {
 	*metapb.Store
-	StateName string `json:"state_name"`
+	StateName    string                 `json:"state_name"`
+	Capabilities core.StoreCapabilities `json:"capabilities"`
 }
 
 // StoreStatus contains status about a store.
 type StoreStatus struct {
-	Capacity           typeutil.ByteSize  `json:"capacity"`
-	Available          typeutil.ByteSize  `json:"available"`
-	UsedSize           typeutil.ByteSize  `json:"used_size"`
-	LeaderCount        int                `json:"leader_count"`
-	LeaderWeight       float64            `json:"leader_weight"`
-	LeaderScore        float64            `json:"leader_score"`
-	LeaderSize         int64              `json:"leader_size"`
-	RegionCount        int                `json:"region_count"`
-	RegionWeight       float64            `json:"region_weight"`
-	RegionScore        float64            `json:"region_score"`
-	RegionSize         int64              `json:"region_size"`
-	SendingSnapCount   uint32             `json:"sending_snap_count,omitempty"`
-	ReceivingSnapCount uint32             `json:"receiving_snap_count,omitempty"`
-	ApplyingSnapCount  uint32             `json:"applying_snap_count,omitempty"`
-	IsBusy             bool               `json:"is_busy,omitempty"`
-	StartTS            *time.Time         `json:"start_ts,omitempty"`
-	LastHeartbeatTS    *time.Time         `json:"last_heartbeat_ts,omitempty"`
-	Uptime             *typeutil.Duration `json:"uptime,omitempty"`
+	Capacity           typeutil.ByteSize      `json:"capacity"`
+	Available          typeutil.ByteSize      `json:"available"`
+	UsedSize           typeutil.ByteSize      `json:"used_size"`
+	LeaderCount        int                    `json:"leader_count"`
+	LeaderWeight       float64                `json:"leader_weight"`
+	LeaderScore        float64                `json:"leader_score"`
+	LeaderSize         int64                  `json:"leader_size"`
+	RegionCount        int                    `json:"region_count"`
+	RegionWeight       float64                `json:"region_weight"`
+	RegionScore        float64                `json:"region_score"`
+	RegionSize         int64                  `json:"region_size"`
+	SendingSnapCount   uint32                 `json:"sending_snap_count,omitempty"`
+	ReceivingSnapCount uint32                 `json:"receiving_snap_count,omitempty"`
+	ApplyingSnapCount  uint32                 `json:"applying_snap_count,omitempty"`
+	IsBusy             bool                   `json:"is_busy,omitempty"`
+	IsDraining         bool                   `json:"is_draining,omitempty"`
+	Maintenance        *core.StoreMaintenance `json:"maintenance,omitempty"`
+	StartTS            *time.Time             `json:"start_ts,omitempty"`
+	LastHeartbeatTS    *time.Time             `json:"last_heartbeat_ts,omitempty"`
+	Uptime             *typeutil.Duration     `json:"uptime,omitempty"`
 }
 
 // StoreInfo contains information about a store.
@@ -77,8 +84,9 @@ const (
 func newStoreInfo(opt *config.ScheduleConfig, store *core.StoreInfo) *StoreInfo {
 	s := &StoreInfo{
 		Store: &MetaStore{
-			Store:     store.GetMeta(),
-			StateName: store.GetState().String(),
+			Store:        store.GetMeta(),
+			StateName:    store.GetState().String(),
+			Capabilities: store.GetCapabilities(),
 		},
 		Status: &StoreStatus{
 			Capacity:           typeutil.ByteSize(store.GetCapacity()),
@@ -96,6 +104,8 @@ func newStoreInfo(opt *config.ScheduleConfig, store *core.StoreInfo) *StoreInfo
 			ReceivingSnapCount: store.GetReceivingSnapCount(),
 			ApplyingSnapCount:  store.GetApplyingSnapCount(),
 			IsBusy:             store.IsBusy(),
+			IsDraining:         store.IsDraining(),
+			Maintenance:        store.GetMaintenance(),
 		},
 	}
 
@@ -173,7 +183,7 @@ func (h *storeHandler) Get(w http.ResponseWriter, r *http.Request) {
 // @Param force query string true "force" Enums(true, false), when force is true it means the store is physically destroyed and can never up gain
 // @Produce json
 // @Success 200 {string} string "The store is set as Offline."
-// @Failure 400 {string} string "The input is invalid."
+// @Failure 400 {string} string "The input is invalid, or the other stores have no space for the regions."
 // @Failure 404 {string} string "The store does not exist."
 // @Failure 410 {string} string "The store has already been removed."
 // @Failure 500 {string} string "PD server failed to proceed the request."
@@ -246,6 +256,11 @@ func (h *storeHandler) responseStoreErr(w http.ResponseWriter, err error, storeI
 		return
 	}
 
+	if errs.ErrMaintenanceBudgetExceeded.Equal(errors.Cause(err)) {
+		h.rd.JSON(w, http.StatusConflict, err.Error())
+		return
+	}
+
 	if err != nil {
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 	}
@@ -412,7 +427,7 @@ func (h *storeHandler) SetLimit(w http.ResponseWriter, r *http.Request) {
 			if typ == storelimit.RemovePeer {
 				key = fmt.Sprintf("remove-peer-%v", storeID)
 			}
-			h.Handler.SetStoreLimitTTL(key, ratePerMin, time.Duration(ttl)*time.Second)
+			h.Handler.SetStoreLimitTTL(key, ratePerMin, time.Duration(ttl)*time.Second, ttlConfigOrigin(r))
 			continue
 		}
 		if err := h.SetStoreLimit(storeID, ratePerMin, typ); err != nil {
@@ -423,6 +438,201 @@ func (h *storeHandler) SetLimit(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, "The store's label is updated.")
 }
 
+// @Tags store
+// @Summary Drain the store. The leaders of the store are moved away and no new leader or peer is placed on it, while the store is kept Up.
+// @Param id path integer true "Store Id"
+// @Produce json
+// @Success 200 {string} string "The store starts draining."
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The store does not exist."
+// @Failure 410 {string} string "The store has been removed."
+// @Router /store/{id}/drain [post]
+func (h *storeHandler) Drain(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	if err := rc.DrainStore(storeID); err != nil {
+		h.responseStoreErr(w, err, storeID)
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, "The store starts draining.")
+}
+
+// @Tags store
+// @Summary Get the capacity reserved on the other stores to take the store offline.
+// @Param id path integer true "Store Id"
+// @Produce json
+// @Success 200 {object} cluster.StoreOfflinePlan
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The store is not taken offline."
+// @Router /store/{id}/offline-plan [get]
+func (h *storeHandler) GetOfflinePlan(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	plan := rc.GetStoreOfflinePlan(storeID)
+	if plan == nil {
+		h.rd.JSON(w, http.StatusNotFound, "the store is not taken offline")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, plan)
+}
+
+// @Tags store
+// @Summary Cancel the drain of the store.
+// @Param id path integer true "Store Id"
+// @Produce json
+// @Success 200 {string} string "The store stops draining."
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The store does not exist."
+// @Router /store/{id}/drain [delete]
+func (h *storeHandler) CancelDrain(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	if err := rc.CancelStoreDrain(storeID); err != nil {
+		h.responseStoreErr(w, err, storeID)
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, "The store stops draining.")
+}
+
+// StoreMaintenanceInput is the input to declare a maintenance window of a
+// store. The window ends at EndTime, or lasts for TTL if EndTime is not given.
+// It starts at StartTime, or now if StartTime is not given.
+type StoreMaintenanceInput struct {
+	StartTime *time.Time         `json:"start_time,omitempty"`
+	EndTime   *time.Time         `json:"end_time,omitempty"`
+	TTL       *typeutil.Duration `json:"ttl,omitempty"`
+	Reason    string             `json:"reason,omitempty"`
+}
+
+func (input *StoreMaintenanceInput) window() (*core.StoreMaintenance, error) {
+	if (input.EndTime == nil) == (input.TTL == nil) {
+		return nil, errors.New("either end_time or ttl should be given")
+	}
+	maintenance := &core.StoreMaintenance{StartTime: time.Now(), Reason: input.Reason}
+	if input.StartTime != nil {
+		maintenance.StartTime = *input.StartTime
+	}
+	if input.EndTime != nil {
+		maintenance.EndTime = *input.EndTime
+	} else {
+		maintenance.EndTime = maintenance.StartTime.Add(input.TTL.Duration)
+	}
+	return maintenance, nil
+}
+
+// @Tags store
+// @Summary Declare a maintenance window of the store. During the window no new leader is placed on the store, its balance weights are lowered, and it is not reported as down.
+// @Param id path integer true "Store Id"
+// @Param body body StoreMaintenanceInput true "json params, e.g. {\"ttl\": \"2h\", \"reason\": \"upgrade\"}"
+// @Produce json
+// @Success 200 {object} core.StoreMaintenance
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The store does not exist."
+// @Failure 409 {string} string "The window exceeds the maintenance budget of failure domains."
+// @Failure 410 {string} string "The store has been removed."
+// @Router /store/{id}/maintenance [post]
+func (h *storeHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	var input StoreMaintenanceInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	maintenance, err := input.window()
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := rc.SetStoreMaintenance(storeID, maintenance); err != nil {
+		h.responseStoreErr(w, err, storeID)
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, maintenance)
+}
+
+// @Tags store
+// @Summary Get the maintenance window of the store.
+// @Param id path integer true "Store Id"
+// @Produce json
+// @Success 200 {object} core.StoreMaintenance
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The store does not exist or has no maintenance window."
+// @Router /store/{id}/maintenance [get]
+func (h *storeHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	store := rc.GetStore(storeID)
+	if store == nil {
+		h.rd.JSON(w, http.StatusNotFound, errs.ErrStoreNotFound.FastGenByArgs(storeID).Error())
+		return
+	}
+	maintenance := store.GetMaintenance()
+	if maintenance == nil {
+		h.rd.JSON(w, http.StatusNotFound, "the store has no maintenance window")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, maintenance)
+}
+
+// @Tags store
+// @Summary Remove the maintenance window of the store.
+// @Param id path integer true "Store Id"
+// @Produce json
+// @Success 200 {string} string "The store's maintenance window is removed."
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The store does not exist."
+// @Router /store/{id}/maintenance [delete]
+func (h *storeHandler) CancelMaintenance(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	if err := rc.CancelStoreMaintenance(storeID); err != nil {
+		h.responseStoreErr(w, err, storeID)
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, "The store's maintenance window is removed.")
+}
+
 type storesHandler struct {
 	*server.Handler
 	rd *render.Render
@@ -442,16 +652,167 @@ func newStoresHandler(handler *server.Handler, rd *render.Render) *storesHandler
 // @Failure 500 {string} string "PD server failed to proceed the request."
 // @Router /stores/remove-tombstone [delete]
 func (h *storesHandler) RemoveTombStone(w http.ResponseWriter, r *http.Request) {
-	rc, _ := h.GetRaftCluster()
-	err := rc.RemoveTombStoneRecords()
+	j, err := h.RemoveTombStoneRecords(r.Context())
 	if err != nil {
 		apiutil.ErrorResp(h.rd, w, err)
 		return
 	}
+	if j.State != job.StateFinished {
+		h.rd.JSON(w, http.StatusInternalServerError, j.Error)
+		return
+	}
 
 	h.rd.JSON(w, http.StatusOK, "Remove tombstone successfully.")
 }
 
+// @Tags store
+// @Summary List the tombstone stores archived after the retention.
+// @Produce json
+// @Success 200 {array} core.ArchivedStore
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /stores/archived [get]
+func (h *storesHandler) GetArchived(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	stores, err := rc.GetArchivedStores()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, stores)
+}
+
+// @Tags store
+// @Summary Restore an archived store to the cluster as a tombstone store.
+// @Param id path integer true "Store Id"
+// @Produce json
+// @Success 200 {string} string "The store is restored."
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The store is not archived."
+// @Failure 409 {string} string "The store is in the cluster."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /stores/archived/{id}/restore [post]
+func (h *storesHandler) RestoreArchived(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	storeID, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	err := rc.RestoreArchivedStore(storeID)
+	switch {
+	case err == nil:
+		h.rd.JSON(w, http.StatusOK, "The store is restored.")
+	case errs.ErrArchivedStoreNotFound.Equal(errors.Cause(err)):
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	case errs.ErrArchivedStoreExists.Equal(errors.Cause(err)):
+		h.rd.JSON(w, http.StatusConflict, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// StoresLabelInput is the input of updating the labels of multiple stores.
+// The stores are selected by IDs, by labels, or by both.
+type StoresLabelInput struct {
+	StoreIDs []uint64 `json:"store_ids"`
+	// Selector selects the non-tombstone stores having all of the labels.
+	Selector map[string]string `json:"selector"`
+	// Set is the labels to set. The existing labels with the same keys are
+	// overwritten.
+	Set map[string]string `json:"set"`
+	// Remove is the keys of the labels to remove.
+	Remove []string `json:"remove"`
+	// DryRun returns the updated labels without applying them.
+	DryRun bool `json:"dry_run"`
+}
+
+// StoreLabels is the labels of a store.
+type StoreLabels struct {
+	StoreID uint64               `json:"store_id"`
+	Labels  []*metapb.StoreLabel `json:"labels"`
+}
+
+// StoresLabelResult is the result of updating the labels of multiple stores.
+type StoresLabelResult struct {
+	DryRun bool           `json:"dry_run"`
+	Stores []*StoreLabels `json:"stores"`
+}
+
+// @Tags store
+// @Summary Set and remove the labels of multiple stores at once.
+// @Accept json
+// @Param body body StoresLabelInput true "The stores and the labels"
+// @Produce json
+// @Success 200 {object} StoresLabelResult
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /stores/label [post]
+func (h *storesHandler) SetLabels(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	var input StoresLabelInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if len(input.StoreIDs) == 0 && len(input.Selector) == 0 {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errors.New("no store is selected")))
+		return
+	}
+	if len(input.Set) == 0 && len(input.Remove) == 0 {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errors.New("no label is set or removed")))
+		return
+	}
+	setLabels := make([]*metapb.StoreLabel, 0, len(input.Set))
+	for k, v := range input.Set {
+		setLabels = append(setLabels, &metapb.StoreLabel{Key: k, Value: v})
+	}
+	sort.Slice(setLabels, func(i, j int) bool { return setLabels[i].Key < setLabels[j].Key })
+	if err := config.ValidateLabels(setLabels); err != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+		return
+	}
+
+	storeIDs := make(map[uint64]struct{})
+	for _, id := range input.StoreIDs {
+		if rc.GetStore(id) == nil {
+			apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(server.ErrStoreNotFound(id)))
+			return
+		}
+		storeIDs[id] = struct{}{}
+	}
+	if len(input.Selector) > 0 {
+		for _, store := range rc.GetStores() {
+			if !store.IsTombstone() && storeHasLabels(store, input.Selector) {
+				storeIDs[store.GetID()] = struct{}{}
+			}
+		}
+	}
+	ids := make([]uint64, 0, len(storeIDs))
+	for id := range storeIDs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	stores, err := rc.UpdateStoresLabels(ids, setLabels, input.Remove, input.DryRun)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := &StoresLabelResult{DryRun: input.DryRun, Stores: make([]*StoreLabels, 0, len(stores))}
+	for _, store := range stores {
+		result.Stores = append(result.Stores, &StoreLabels{StoreID: store.GetID(), Labels: store.GetLabels()})
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
+func storeHasLabels(store *core.StoreInfo, labels map[string]string) bool {
+	for k, v := range labels {
+		if store.GetLabelValue(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
 // FIXME: details of input json body params
 // @Tags store
 // @Summary Set limit of all stores in the cluster.
@@ -499,7 +860,7 @@ func (h *storesHandler) SetAllLimit(w http.ResponseWriter, r *http.Request) {
 	if _, ok := input["labels"]; !ok {
 		for _, typ := range typeValues {
 			if ttl > 0 {
-				if err := h.SetAllStoresLimitTTL(ratePerMin, typ, time.Duration(ttl)*time.Second); err != nil {
+				if err := h.SetAllStoresLimitTTL(ratePerMin, typ, time.Duration(ttl)*time.Second, ttlConfigOrigin(r)); err != nil {
 					h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 					return
 				}
@@ -610,11 +971,94 @@ func (h *storesHandler) GetStoreLimitScene(w http.ResponseWriter, r *http.Reques
 	h.rd.JSON(w, http.StatusOK, scene)
 }
 
+// StoreDrainProgress is the progress of a draining store.
+type StoreDrainProgress struct {
+	StoreID          uint64            `json:"store_id"`
+	Address          string            `json:"address"`
+	StartTime        time.Time         `json:"start_time"`
+	Elapsed          typeutil.Duration `json:"elapsed"`
+	StartLeaderCount int               `json:"start_leader_count"`
+	LeaderCount      int               `json:"leader_count"`
+	// Progress is the ratio of the leaders moved away, between 0 and 1.
+	Progress float64 `json:"progress"`
+	Finished bool    `json:"finished"`
+}
+
+// StoresDrainProgress records the progress of the draining stores.
+type StoresDrainProgress struct {
+	Count  int                   `json:"count"`
+	Stores []*StoreDrainProgress `json:"stores"`
+}
+
+func newStoreDrainProgress(store *core.StoreInfo, now time.Time) *StoreDrainProgress {
+	drain := store.GetDrain()
+	p := &StoreDrainProgress{
+		StoreID:          store.GetID(),
+		Address:          store.GetAddress(),
+		StartTime:        drain.StartTime,
+		Elapsed:          typeutil.NewDuration(now.Sub(drain.StartTime)),
+		StartLeaderCount: drain.StartLeaderCount,
+		LeaderCount:      store.GetLeaderCount(),
+		Progress:         1,
+		Finished:         store.GetLeaderCount() == 0,
+	}
+	if p.StartLeaderCount > 0 && p.LeaderCount > 0 {
+		p.Progress = 1 - float64(p.LeaderCount)/float64(p.StartLeaderCount)
+		// The store may receive leaders from the split regions.
+		if p.Progress < 0 {
+			p.Progress = 0
+		}
+	} else if p.LeaderCount > 0 {
+		p.Progress = 0
+	}
+	return p
+}
+
+// @Tags store
+// @Summary Get the progress of the draining stores.
+// @Param id query integer false "Store Id"
+// @Produce json
+// @Success 200 {object} StoresDrainProgress
+// @Failure 400 {string} string "The input is invalid."
+// @Router /stores/progress [get]
+func (h *storesHandler) GetProgress(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	var storeID uint64
+	if idStr := r.URL.Query().Get("id"); idStr != "" {
+		var err error
+		storeID, err = strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+			return
+		}
+	}
+
+	now := time.Now()
+	result := &StoresDrainProgress{Stores: []*StoreDrainProgress{}}
+	for _, store := range rc.GetStores() {
+		if !store.IsDraining() || store.IsTombstone() {
+			continue
+		}
+		if storeID != 0 && store.GetID() != storeID {
+			continue
+		}
+		result.Stores = append(result.Stores, newStoreDrainProgress(store, now))
+	}
+	sort.Slice(result.Stores, func(i, j int) bool {
+		return result.Stores[i].StoreID < result.Stores[j].StoreID
+	})
+	result.Count = len(result.Stores)
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
 // @Tags store
 // @Summary Get stores in the cluster.
 // @Param state query array true "Specify accepted store states."
+// @Param keyspace_id query integer false "Only list the stores which can hold the peers of the keyspace under the placement rules."
+// @Param label query array false "Only list the stores with all the labels, each in the form of key=value."
 // @Produce json
 // @Success 200 {object} StoresInfo
+// @Failure 400 {string} string "The input is invalid."
 // @Failure 500 {string} string "PD server failed to proceed the request."
 // @Router /stores [get]
 func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -629,8 +1073,13 @@ func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	filter, err := newStoreFilter(r.URL)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	stores = urlFilter.filter(rc.GetMetaStores())
+	stores = rc.FilterMetaStores(urlFilter.filter(rc.GetMetaStores()), filter)
 	for _, s := range stores {
 		storeID := s.GetId()
 		store := rc.GetStore(storeID)
@@ -651,6 +1100,27 @@ type storeStateFilter struct {
 	accepts []metapb.StoreState
 }
 
+// newStoreFilter parses the keyspace and labels to filter the stores.
+func newStoreFilter(u *url.URL) (*cluster.StoreFilter, error) {
+	filter := &cluster.StoreFilter{}
+	query := u.Query()
+	if v := query.Get("keyspace_id"); v != "" {
+		id, err := keyspace.ParseKeyspaceID(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.KeyspaceID = &id
+	}
+	for _, v := range query["label"] {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.Errorf("invalid label %q, which should be key=value", v)
+		}
+		filter.Labels = append(filter.Labels, &metapb.StoreLabel{Key: kv[0], Value: kv[1]})
+	}
+	return filter, nil
+}
+
 func newStoreStateFilter(u *url.URL) (*storeStateFilter, error) {
 	var acceptStates []metapb.StoreState
 	if v, ok := u.Query()["state"]; ok {
@@ -0,0 +1,192 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pingcap/errcode"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/unrolled/render"
+)
+
+// StoreLossSimulationInput selects the stores to lose. A store is lost if it
+// is in StoreIDs, or if it has all the Labels, such as a zone.
+type StoreLossSimulationInput struct {
+	StoreIDs []uint64             `json:"store_ids,omitempty"`
+	Labels   []*metapb.StoreLabel `json:"labels,omitempty"`
+}
+
+// StoreLossSimulation is the estimated impact of losing the stores at once.
+// The peers which are already down are counted as lost too.
+type StoreLossSimulation struct {
+	StoreIDs []uint64 `json:"store_ids"`
+	// LostQuorumRegions is the regions which lose the majority of the voters,
+	// and become unavailable until they are recovered.
+	LostQuorumRegions []uint64 `json:"lost_quorum_regions"`
+	// LostQuorumSize is the approximate size of the regions losing quorum in MB.
+	LostQuorumSize int64 `json:"lost_quorum_size"`
+	// ReplicateRegionCount is the number of the regions which keep the quorum
+	// but need the lost peers to be replicated again.
+	ReplicateRegionCount int `json:"replicate_region_count"`
+	ReplicatePeerCount   int `json:"replicate_peer_count"`
+	// ReplicateSize is the approximate size of the data to replicate in MB.
+	ReplicateSize int64 `json:"replicate_size"`
+	// AddPeerCapacityPerHour is the number of peers that can be added to the
+	// remaining stores in an hour under the store limits.
+	AddPeerCapacityPerHour float64 `json:"add_peer_capacity_per_hour"`
+	// EstimatedSeconds is the time to replicate the lost peers under the store
+	// limits, or -1 if no store can receive them.
+	EstimatedSeconds float64 `json:"estimated_seconds"`
+}
+
+type storeLossHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newStoreLossHandler(svr *server.Server, rd *render.Render) *storeLossHandler {
+	return &storeLossHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags store
+// @Summary Estimate the impact of losing some stores at once, without scheduling anything.
+// @Accept json
+// @Param body body StoreLossSimulationInput true "The stores to lose"
+// @Produce json
+// @Success 200 {object} StoreLossSimulation
+// @Failure 400 {string} string "The input is invalid."
+// @Router /store-loss/simulate [post]
+func (h *storeLossHandler) Simulate(w http.ResponseWriter, r *http.Request) {
+	var input StoreLossSimulationInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	rc := h.svr.GetRaftCluster()
+	for _, id := range input.StoreIDs {
+		if rc.GetStore(id) == nil {
+			apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(server.ErrStoreNotFound(id)))
+			return
+		}
+	}
+	lost := selectLostStores(rc, &input)
+	if len(lost) == 0 {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errors.New("no store is selected")))
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, simulateStoreLoss(rc, lost))
+}
+
+func selectLostStores(rc *cluster.RaftCluster, input *StoreLossSimulationInput) map[uint64]struct{} {
+	lost := make(map[uint64]struct{}, len(input.StoreIDs))
+	for _, id := range input.StoreIDs {
+		lost[id] = struct{}{}
+	}
+	if len(input.Labels) == 0 {
+		return lost
+	}
+	for _, store := range rc.GetStores() {
+		if store.IsTombstone() {
+			continue
+		}
+		matched := true
+		for _, label := range input.Labels {
+			if store.GetLabelValue(label.GetKey()) != label.GetValue() {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			lost[store.GetID()] = struct{}{}
+		}
+	}
+	return lost
+}
+
+// simulateStoreLoss counts the regions losing quorum and the peers to be
+// replicated if the stores are lost, and estimates the time to replicate the
+// peers by the add-peer limits of the remaining up stores.
+func simulateStoreLoss(rc *cluster.RaftCluster, lost map[uint64]struct{}) *StoreLossSimulation {
+	result := &StoreLossSimulation{
+		StoreIDs:          make([]uint64, 0, len(lost)),
+		LostQuorumRegions: []uint64{},
+	}
+	for id := range lost {
+		result.StoreIDs = append(result.StoreIDs, id)
+	}
+	sort.Slice(result.StoreIDs, func(i, j int) bool { return result.StoreIDs[i] < result.StoreIDs[j] })
+
+	isLost := func(region *core.RegionInfo, peer *metapb.Peer) bool {
+		if _, ok := lost[peer.GetStoreId()]; ok {
+			return true
+		}
+		return region.GetDownPeer(peer.GetId()) != nil
+	}
+	for _, region := range rc.GetRegions() {
+		var lostPeers, lostVoters int
+		for _, peer := range region.GetPeers() {
+			if isLost(region, peer) {
+				lostPeers++
+			}
+		}
+		if lostPeers == 0 {
+			continue
+		}
+		voters := region.GetVoters()
+		for _, peer := range voters {
+			if isLost(region, peer) {
+				lostVoters++
+			}
+		}
+		if (len(voters)-lostVoters)*2 <= len(voters) {
+			result.LostQuorumRegions = append(result.LostQuorumRegions, region.GetID())
+			result.LostQuorumSize += region.GetApproximateSize()
+			continue
+		}
+		result.ReplicateRegionCount++
+		result.ReplicatePeerCount += lostPeers
+		result.ReplicateSize += int64(lostPeers) * region.GetApproximateSize()
+	}
+	sort.Slice(result.LostQuorumRegions, func(i, j int) bool {
+		return result.LostQuorumRegions[i] < result.LostQuorumRegions[j]
+	})
+
+	opt := rc.GetOpts()
+	for _, store := range rc.GetStores() {
+		if _, ok := lost[store.GetID()]; ok || !store.IsUp() {
+			continue
+		}
+		result.AddPeerCapacityPerHour += opt.GetStoreLimitByType(store.GetID(), storelimit.AddPeer) / schedule.StoreBalanceBaseTime * time.Hour.Seconds()
+	}
+	switch {
+	case result.ReplicatePeerCount == 0:
+	case result.AddPeerCapacityPerHour <= 0:
+		result.EstimatedSeconds = -1
+	default:
+		result.EstimatedSeconds = float64(result.ReplicatePeerCount) / result.AddPeerCapacityPerHour * time.Hour.Seconds()
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/core"
+)
+
+var _ = Suite(&testStoreLossSuite{})
+
+type testStoreLossSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testStoreLossSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	// Store 1 and 2 are in zone z1, and store 3 and 4 are in zone z2.
+	for id := uint64(1); id <= 4; id++ {
+		zone := fmt.Sprintf("z%d", (id+1)/2)
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, []*metapb.StoreLabel{{Key: "zone", Value: zone}})
+	}
+	for _, r := range []struct {
+		id     uint64
+		stores []uint64
+		down   uint64
+	}{
+		{2, []uint64{1, 2, 3}, 0},
+		{3, []uint64{1, 3, 4}, 0},
+		{4, []uint64{2, 3, 4}, 4},
+	} {
+		meta := &metapb.Region{
+			Id:          r.id,
+			StartKey:    []byte(fmt.Sprintf("k%d", r.id)),
+			EndKey:      []byte(fmt.Sprintf("k%d", r.id+1)),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}
+		var downPeers []*pdpb.PeerStats
+		for i, storeID := range r.stores {
+			peer := &metapb.Peer{Id: r.id*10 + uint64(i), StoreId: storeID}
+			meta.Peers = append(meta.Peers, peer)
+			if storeID == r.down {
+				downPeers = append(downPeers, &pdpb.PeerStats{Peer: peer, DownSeconds: 3600})
+			}
+		}
+		mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(meta, meta.Peers[0],
+			core.SetApproximateSize(10), core.WithDownPeers(downPeers)))
+	}
+}
+
+func (s *testStoreLossSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testStoreLossSuite) simulate(input *StoreLossSimulationInput) (*StoreLossSimulation, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	result := &StoreLossSimulation{}
+	err = postJSON(testDialClient, s.urlPrefix+"/store-loss/simulate", data, func(res []byte, _ int) {
+		err = json.Unmarshal(res, result)
+	})
+	return result, err
+}
+
+func (s *testStoreLossSuite) TestSimulate(c *C) {
+	result, err := s.simulate(&StoreLossSimulationInput{StoreIDs: []uint64{1}})
+	c.Assert(err, IsNil)
+	c.Assert(result.StoreIDs, DeepEquals, []uint64{1})
+	c.Assert(result.LostQuorumRegions, HasLen, 0)
+	// The down peer of region 4 needs to be replicated too.
+	c.Assert(result.ReplicateRegionCount, Equals, 3)
+	c.Assert(result.ReplicatePeerCount, Equals, 3)
+	c.Assert(result.ReplicateSize, Equals, int64(30))
+	// Store 2, 3 and 4 can add 15 peers per minute by default.
+	c.Assert(result.AddPeerCapacityPerHour, Equals, 2700.0)
+	c.Assert(result.EstimatedSeconds, Equals, 3.0/2700*3600)
+
+	// Region 4 loses quorum since its peer on store 4 is already down.
+	result, err = s.simulate(&StoreLossSimulationInput{Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z1"}}})
+	c.Assert(err, IsNil)
+	c.Assert(result.StoreIDs, DeepEquals, []uint64{1, 2})
+	c.Assert(result.LostQuorumRegions, DeepEquals, []uint64{2, 4})
+	c.Assert(result.LostQuorumSize, Equals, int64(20))
+	c.Assert(result.ReplicateRegionCount, Equals, 1)
+	c.Assert(result.ReplicatePeerCount, Equals, 1)
+
+	result, err = s.simulate(&StoreLossSimulationInput{StoreIDs: []uint64{1, 2, 3, 4}})
+	c.Assert(err, IsNil)
+	c.Assert(result.LostQuorumRegions, DeepEquals, []uint64{2, 3, 4})
+	c.Assert(result.EstimatedSeconds, Equals, 0.0)
+
+	_, err = s.simulate(&StoreLossSimulationInput{})
+	c.Assert(err, NotNil)
+	_, err = s.simulate(&StoreLossSimulationInput{StoreIDs: []uint64{10086}})
+	c.Assert(err, NotNil)
+	_, err = s.simulate(&StoreLossSimulationInput{Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z3"}}})
+	c.Assert(err, NotNil)
+}
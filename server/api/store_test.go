@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
@@ -29,6 +30,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 )
@@ -140,6 +142,36 @@ func (s *testStoreSuite) TestStoresList(c *C) {
 
 }
 
+func (s *testStoreSuite) TestStoresFilter(c *C) {
+	url := fmt.Sprintf("%s/store/4/label", s.urlPrefix)
+	c.Assert(postJSON(testDialClient, url, []byte(`{"engine":"tiflash"}`)), IsNil)
+	defer func() {
+		c.Assert(postJSON(testDialClient, url, []byte(`{"engine":""}`)), IsNil)
+	}()
+
+	storeIDs := func(query string) []uint64 {
+		info := new(StoresInfo)
+		c.Assert(readJSON(testDialClient, fmt.Sprintf("%s/stores?%s", s.urlPrefix, query), info), IsNil)
+		ids := make([]uint64, 0, len(info.Stores))
+		for _, store := range info.Stores {
+			ids = append(ids, store.Store.GetId())
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		return ids
+	}
+	c.Assert(storeIDs("label=engine=tiflash"), DeepEquals, []uint64{4})
+	// The TiFlash store cannot hold the peers without the placement rules.
+	c.Assert(storeIDs("keyspace_id=1"), DeepEquals, []uint64{1, 6})
+	c.Assert(storeIDs("keyspace_id=1&state=0"), DeepEquals, []uint64{1})
+	c.Assert(storeIDs("keyspace_id=1&label=engine=tiflash"), DeepEquals, []uint64{})
+
+	for _, query := range []string{"keyspace_id=abc", "keyspace_id=16777216", "label=engine"} {
+		err := readJSON(testDialClient, fmt.Sprintf("%s/stores?%s", s.urlPrefix, query), new(StoresInfo))
+		c.Assert(err, NotNil)
+		c.Assert(strings.Contains(err.Error(), "400"), IsTrue)
+	}
+}
+
 func (s *testStoreSuite) TestStoreGet(c *C) {
 	url := fmt.Sprintf("%s/store/1", s.urlPrefix)
 	s.svr.StoreHeartbeat(
@@ -161,6 +193,7 @@ func (s *testStoreSuite) TestStoreGet(c *C) {
 	available, _ := units.RAMInBytes("1.555TiB")
 	c.Assert(int64(info.Status.Capacity), Equals, capacity)
 	c.Assert(int64(info.Status.Available), Equals, available)
+	c.Assert(info.Store.Capabilities, Equals, core.StoreCapabilities{Engine: core.EngineTiKV, Leader: true})
 	checkStoresInfo(c, []*StoreInfo{info}, s.stores[:1])
 }
 
@@ -265,6 +298,8 @@ func (s *testStoreSuite) TestStoreDelete(c *C) {
 	// up store success because it is offline but not physically destroyed
 	status, _ := requestStatusBody(c, testDialClient, http.MethodPost, fmt.Sprintf("%s/state?state=Up", url))
 	c.Assert(status, Equals, http.StatusOK)
+	status, _ = requestStatusBody(c, testDialClient, http.MethodGet, url+"/offline-plan")
+	c.Assert(status, Equals, http.StatusNotFound)
 
 	status, _ = requestStatusBody(c, testDialClient, http.MethodGet, url)
 	c.Assert(status, Equals, http.StatusOK)
@@ -281,6 +316,9 @@ func (s *testStoreSuite) TestStoreDelete(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(store.Store.State, Equals, metapb.StoreState_Offline)
 	c.Assert(store.Store.PhysicallyDestroyed, Equals, true)
+	plan := &cluster.StoreOfflinePlan{}
+	c.Assert(readJSON(testDialClient, url+"/offline-plan", plan), IsNil)
+	c.Assert(plan.StoreID, Equals, uint64(6))
 
 	// try to up store again failed because it is physically destroyed
 	status, _ = requestStatusBody(c, testDialClient, http.MethodPost, fmt.Sprintf("%s/state?state=Up", url))
@@ -330,6 +368,93 @@ func (s *testStoreSuite) TestStoreSetState(c *C) {
 	c.Assert(info.Store.State, Equals, metapb.StoreState_Up)
 }
 
+func (s *testStoreSuite) TestStoreDrain(c *C) {
+	url := fmt.Sprintf("%s/store/4", s.urlPrefix)
+	progress := &StoresDrainProgress{}
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/stores/progress", progress), IsNil)
+	c.Assert(progress.Count, Equals, 0)
+
+	c.Assert(postJSON(testDialClient, url+"/drain", nil), IsNil)
+	// Draining a draining store is OK.
+	c.Assert(postJSON(testDialClient, url+"/drain", nil), IsNil)
+	info := StoreInfo{}
+	c.Assert(readJSON(testDialClient, url, &info), IsNil)
+	c.Assert(info.Store.State, Equals, metapb.StoreState_Up)
+	c.Assert(info.Status.IsDraining, IsTrue)
+
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/stores/progress?id=4", progress), IsNil)
+	c.Assert(progress.Count, Equals, 1)
+	c.Assert(progress.Stores[0].StoreID, Equals, uint64(4))
+	c.Assert(progress.Stores[0].Progress, Equals, 1.0)
+	c.Assert(progress.Stores[0].Finished, IsTrue)
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/stores/progress?id=1", progress), IsNil)
+	c.Assert(progress.Count, Equals, 0)
+
+	// Only the Up stores can be drained.
+	code, _ := requestStatusBody(c, testDialClient, http.MethodPost, s.urlPrefix+"/store/6/drain")
+	c.Assert(code, Equals, http.StatusBadRequest)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodPost, s.urlPrefix+"/store/7/drain")
+	c.Assert(code, Equals, http.StatusGone)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodPost, s.urlPrefix+"/store/10086/drain")
+	c.Assert(code, Equals, http.StatusNotFound)
+
+	code, _ = requestStatusBody(c, testDialClient, http.MethodDelete, url+"/drain")
+	c.Assert(code, Equals, http.StatusOK)
+	info = StoreInfo{}
+	c.Assert(readJSON(testDialClient, url, &info), IsNil)
+	c.Assert(info.Status.IsDraining, IsFalse)
+	progress = &StoresDrainProgress{}
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/stores/progress", progress), IsNil)
+	c.Assert(progress.Count, Equals, 0)
+}
+
+func (s *testStoreSuite) TestStoreMaintenance(c *C) {
+	url := fmt.Sprintf("%s/store/4/maintenance", s.urlPrefix)
+	code, _ := requestStatusBody(c, testDialClient, http.MethodGet, url)
+	c.Assert(code, Equals, http.StatusNotFound)
+
+	c.Assert(postJSON(testDialClient, url, []byte(`{"ttl": "1h", "reason": "upgrade"}`)), IsNil)
+	maintenance := &core.StoreMaintenance{}
+	c.Assert(readJSON(testDialClient, url, maintenance), IsNil)
+	c.Assert(maintenance.Reason, Equals, "upgrade")
+	c.Assert(maintenance.EndTime.Sub(maintenance.StartTime), Equals, time.Hour)
+	info := StoreInfo{}
+	c.Assert(readJSON(testDialClient, fmt.Sprintf("%s/store/4", s.urlPrefix), &info), IsNil)
+	c.Assert(info.Status.Maintenance, NotNil)
+	c.Assert(info.Status.Maintenance.Reason, Equals, "upgrade")
+
+	// Either end_time or ttl should be given, and the window should not end in the past.
+	c.Assert(postJSON(testDialClient, url, []byte(`{"reason": "upgrade"}`)), NotNil)
+	c.Assert(postJSON(testDialClient, url, []byte(`{"ttl": "1h", "end_time": "2030-01-01T00:00:00Z"}`)), NotNil)
+	c.Assert(postJSON(testDialClient, url, []byte(`{"end_time": "2020-01-01T00:00:00Z"}`)), NotNil)
+	c.Assert(postJSON(testDialClient, s.urlPrefix+"/store/7/maintenance", []byte(`{"ttl": "1h"}`)), NotNil)
+	c.Assert(postJSON(testDialClient, s.urlPrefix+"/store/10086/maintenance", []byte(`{"ttl": "1h"}`)), NotNil)
+
+	code, _ = requestStatusBody(c, testDialClient, http.MethodDelete, url)
+	c.Assert(code, Equals, http.StatusOK)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodGet, url)
+	c.Assert(code, Equals, http.StatusNotFound)
+}
+
+func (s *testStoreSuite) TestStoreDrainProgress(c *C) {
+	now := time.Now()
+	store := core.NewStoreInfo(&metapb.Store{Id: 1}, core.SetLeaderCount(30),
+		core.SetStoreDrain(&core.StoreDrain{StartTime: now.Add(-time.Minute), StartLeaderCount: 40}))
+	progress := newStoreDrainProgress(store, now)
+	c.Assert(progress.Progress, Equals, 0.25)
+	c.Assert(progress.Finished, IsFalse)
+	c.Assert(progress.Elapsed.Duration, Equals, time.Minute)
+
+	// More leaders than the start.
+	store = store.Clone(core.SetLeaderCount(50))
+	c.Assert(newStoreDrainProgress(store, now).Progress, Equals, 0.0)
+	store = store.Clone(core.SetStoreDrain(&core.StoreDrain{StartTime: now}))
+	c.Assert(newStoreDrainProgress(store, now).Progress, Equals, 0.0)
+	store = store.Clone(core.SetLeaderCount(0))
+	c.Assert(newStoreDrainProgress(store, now).Progress, Equals, 1.0)
+	c.Assert(newStoreDrainProgress(store, now).Finished, IsTrue)
+}
+
 func (s *testStoreSuite) TestUrlStoreFilter(c *C) {
 	table := []struct {
 		u    string
@@ -491,3 +616,146 @@ func (s *testStoreSuite) TestStoreLimitTTL(c *C) {
 	c.Assert(s.svr.GetPersistOptions().GetStoreLimit(uint64(2)).AddPeer, Not(Equals), float64(997))
 	c.Assert(s.svr.GetPersistOptions().GetStoreLimit(uint64(2)).RemovePeer, Not(Equals), float64(996))
 }
+
+var _ = Suite(&testStoresLabelSuite{})
+
+type testStoresLabelSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testStoresLabelSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.Replication.EnablePlacementRules = false })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	for id, zone := range map[uint64]string{1: "z1", 2: "z1", 3: "z2"} {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, []*metapb.StoreLabel{{Key: "zone", Value: zone}})
+	}
+}
+
+func (s *testStoresLabelSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testStoresLabelSuite) postStoresLabel(input *StoresLabelInput) (*StoresLabelResult, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	result := &StoresLabelResult{}
+	err = postJSON(testDialClient, s.urlPrefix+"/stores/label", data, func(res []byte, _ int) {
+		err = json.Unmarshal(res, result)
+	})
+	return result, err
+}
+
+func (s *testStoresLabelSuite) checkLabels(c *C, storeID uint64, expect map[string]string) {
+	store := s.svr.GetRaftCluster().GetStore(storeID)
+	c.Assert(store.GetLabels(), HasLen, len(expect))
+	for k, v := range expect {
+		c.Assert(store.GetLabelValue(k), Equals, v)
+	}
+}
+
+func (s *testStoresLabelSuite) TestStoresLabel(c *C) {
+	// Dry run.
+	result, err := s.postStoresLabel(&StoresLabelInput{
+		Selector: map[string]string{"zone": "z1"},
+		Set:      map[string]string{"rack": "r1"},
+		DryRun:   true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.DryRun, IsTrue)
+	c.Assert(result.Stores, HasLen, 2)
+	c.Assert(result.Stores[0].StoreID, Equals, uint64(1))
+	c.Assert(result.Stores[1].StoreID, Equals, uint64(2))
+	c.Assert(result.Stores[0].Labels, HasLen, 2)
+	s.checkLabels(c, 1, map[string]string{"zone": "z1"})
+
+	// Select the stores by both labels and IDs.
+	result, err = s.postStoresLabel(&StoresLabelInput{
+		StoreIDs: []uint64{3},
+		Selector: map[string]string{"zone": "z1"},
+		Set:      map[string]string{"rack": "r1"},
+		Remove:   []string{"Zone"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.DryRun, IsFalse)
+	c.Assert(result.Stores, HasLen, 3)
+	for _, id := range []uint64{1, 2, 3} {
+		s.checkLabels(c, id, map[string]string{"rack": "r1"})
+	}
+
+	// Invalid input.
+	_, err = s.postStoresLabel(&StoresLabelInput{Set: map[string]string{"rack": "r2"}})
+	c.Assert(err, ErrorMatches, "(?s).*no store is selected.*")
+	_, err = s.postStoresLabel(&StoresLabelInput{StoreIDs: []uint64{1}})
+	c.Assert(err, ErrorMatches, "(?s).*no label is set or removed.*")
+	_, err = s.postStoresLabel(&StoresLabelInput{StoreIDs: []uint64{100}, Set: map[string]string{"rack": "r2"}})
+	c.Assert(err, NotNil)
+	_, err = s.postStoresLabel(&StoresLabelInput{StoreIDs: []uint64{1}, Set: map[string]string{"rack": "r@2"}})
+	c.Assert(err, NotNil)
+
+	// No store is updated if any of them fails the label check.
+	err = postJSON(testDialClient, s.urlPrefix+"/config", []byte(`{"location-labels": "rack,host"}`))
+	c.Assert(err, IsNil)
+	_, err = s.postStoresLabel(&StoresLabelInput{StoreIDs: []uint64{1}, Set: map[string]string{"host": "h1"}})
+	c.Assert(err, IsNil)
+	err = postJSON(testDialClient, s.urlPrefix+"/config", []byte(`{"strictly-match-label": "true"}`))
+	c.Assert(err, IsNil)
+	_, err = s.postStoresLabel(&StoresLabelInput{StoreIDs: []uint64{1, 2}, Set: map[string]string{"rack": "r2"}})
+	c.Assert(err, ErrorMatches, "(?s).*store 2.*")
+	s.checkLabels(c, 1, map[string]string{"rack": "r1", "host": "h1"})
+	s.checkLabels(c, 2, map[string]string{"rack": "r1"})
+}
+
+var _ = Suite(&testArchivedStoreSuite{})
+
+type testArchivedStoreSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testArchivedStoreSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testArchivedStoreSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testArchivedStoreSuite) TestArchivedStores(c *C) {
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	archived := &core.ArchivedStore{
+		Store:         &metapb.Store{Id: 3, Address: "tikv3", State: metapb.StoreState_Tombstone, Version: "2.0.0"},
+		TombstoneTime: time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+		ArchiveTime:   time.Now().UTC().Truncate(time.Second),
+	}
+	c.Assert(s.svr.GetStorage().SaveArchivedStore(archived), IsNil)
+
+	var stores []*core.ArchivedStore
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/stores/archived", &stores), IsNil)
+	c.Assert(stores, DeepEquals, []*core.ArchivedStore{archived})
+
+	code, _ := requestStatusBody(c, testDialClient, http.MethodPost, s.urlPrefix+"/stores/archived/2/restore")
+	c.Assert(code, Equals, http.StatusConflict)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodPost, s.urlPrefix+"/stores/archived/4/restore")
+	c.Assert(code, Equals, http.StatusNotFound)
+	code, _ = requestStatusBody(c, testDialClient, http.MethodPost, s.urlPrefix+"/stores/archived/3/restore")
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(s.svr.GetRaftCluster().GetStore(3).IsTombstone(), IsTrue)
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/stores/archived", &stores), IsNil)
+	c.Assert(stores, HasLen, 0)
+}
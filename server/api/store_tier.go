@@ -0,0 +1,119 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"github.com/pingcap/errors"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+type storeTierHandler struct {
+	*server.Handler
+	rd *render.Render
+}
+
+func newStoreTierHandler(handler *server.Handler, rd *render.Render) *storeTierHandler {
+	return &storeTierHandler{
+		Handler: handler,
+		rd:      rd,
+	}
+}
+
+// @Tags tier
+// @Summary List the store tiers, which are the values of the store tier label.
+// @Produce json
+// @Success 200 {array} server.StoreTier
+// @Failure 412 {string} string "The store tier label is not set."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /tiers [get]
+func (h *storeTierHandler) List(w http.ResponseWriter, r *http.Request) {
+	tiers, err := h.GetStoreTiers()
+	if err != nil {
+		h.rd.JSON(w, storeTierErrorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, tiers)
+}
+
+type moveRangeToTierInput struct {
+	// StartKey and EndKey are hex encoded, and an empty EndKey means the end
+	// of the key space.
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+	Tier     string `json:"tier"`
+}
+
+// @Tags tier
+// @Summary Move a key range to a store tier, by a placement rule in the pd-tier group which places the voters of the range in the tier.
+// @Accept json
+// @Param body body moveRangeToTierInput true "The hex encoded key range and the tier."
+// @Produce json
+// @Success 200 {object} placement.Rule
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The tier is not found."
+// @Failure 409 {string} string "The key range overlaps another moved range."
+// @Failure 412 {string} string "The placement rules feature is disabled or the store tier label is not set."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /tiers/ranges [post]
+func (h *storeTierHandler) MoveRange(w http.ResponseWriter, r *http.Request) {
+	var input moveRangeToTierInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	startKey, err := hex.DecodeString(input.StartKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "start_key should be in hex format")
+		return
+	}
+	endKey, err := hex.DecodeString(input.EndKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "end_key should be in hex format")
+		return
+	}
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !rc.GetOpts().IsPlacementRulesEnabled() {
+		h.rd.JSON(w, http.StatusPreconditionFailed, errPlacementDisabled.Error())
+		return
+	}
+	rule, err := h.MoveRangeToTier(startKey, endKey, input.Tier)
+	if err != nil {
+		h.rd.JSON(w, storeTierErrorStatus(err), err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, rule)
+}
+
+func storeTierErrorStatus(err error) int {
+	switch cause := errors.Cause(err); {
+	case errs.ErrStoreTierLabelNotSet.Equal(cause):
+		return http.StatusPreconditionFailed
+	case errs.ErrStoreTierNotFound.Equal(cause):
+		return http.StatusNotFound
+	case errs.ErrStoreTierRangeOverlap.Equal(cause):
+		return http.StatusConflict
+	case errs.ErrRuleContent.Equal(cause):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
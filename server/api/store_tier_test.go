@@ -0,0 +1,118 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
+)
+
+var _ = Suite(&testStoreTierSuite{})
+
+type testStoreTierSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testStoreTierSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	for id, tier := range map[uint64]string{1: "hot", 2: "hot", 3: "cold", 4: ""} {
+		var labels []*metapb.StoreLabel
+		if tier != "" {
+			labels = append(labels, &metapb.StoreLabel{Key: "tier", Value: tier})
+		}
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, labels)
+	}
+	c.Assert(postJSON(testDialClient, s.urlPrefix+"/config", []byte(`{"enable-placement-rules":"true"}`)), IsNil)
+}
+
+func (s *testStoreTierSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testStoreTierSuite) moveRange(c *C, startKey, endKey, tier string) (int, *placement.Rule) {
+	data, err := json.Marshal(map[string]string{"start_key": startKey, "end_key": endKey, "tier": tier})
+	c.Assert(err, IsNil)
+	resp, err := testDialClient.Post(s.urlPrefix+"/tiers/ranges", "application/json", bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	rule := &placement.Rule{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(rule), IsNil)
+	return resp.StatusCode, rule
+}
+
+func (s *testStoreTierSuite) TestStoreTiers(c *C) {
+	code, _ := requestStatusBody(c, testDialClient, http.MethodGet, s.urlPrefix+"/tiers")
+	c.Assert(code, Equals, http.StatusPreconditionFailed)
+	code, _ = s.moveRange(c, "", "", "cold")
+	c.Assert(code, Equals, http.StatusPreconditionFailed)
+
+	c.Assert(postJSON(testDialClient, s.urlPrefix+"/config", []byte(`{"store-tier-label":"tier"}`)), IsNil)
+	var tiers []*server.StoreTier
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/tiers", &tiers), IsNil)
+	c.Assert(tiers, HasLen, 3)
+	c.Assert(tiers[0].Name, Equals, "")
+	c.Assert(tiers[0].StoreIDs, DeepEquals, []uint64{4})
+	c.Assert(tiers[1].Name, Equals, "cold")
+	c.Assert(tiers[1].StoreIDs, DeepEquals, []uint64{3})
+	c.Assert(tiers[2].Name, Equals, "hot")
+	c.Assert(tiers[2].StoreIDs, DeepEquals, []uint64{1, 2})
+
+	code, rule := s.moveRange(c, "6161", "6262", "cold")
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(rule.GroupID, Equals, server.StoreTierRuleGroup)
+	c.Assert(rule.LabelConstraints, DeepEquals, []placement.LabelConstraint{placement.TierConstraint("tier", "cold")})
+	manager := s.svr.GetRaftCluster().GetRuleManager()
+	c.Assert(manager.GetRule(server.StoreTierRuleGroup, rule.ID), NotNil)
+	group := manager.GetRuleGroup(server.StoreTierRuleGroup)
+	c.Assert(group.Override, IsTrue)
+	// The rule of the moved range overrides the default rule.
+	rules := manager.GetRulesForApplyRegion(core.NewRegionInfo(&metapb.Region{StartKey: []byte("aa"), EndKey: []byte("ab")}, nil))
+	c.Assert(rules, HasLen, 1)
+	c.Assert(rules[0].ID, Equals, rule.ID)
+
+	// Moving the same range again replaces the rule.
+	code, rule = s.moveRange(c, "6161", "6262", "hot")
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(manager.GetRulesByGroup(server.StoreTierRuleGroup), HasLen, 1)
+	c.Assert(manager.GetRule(server.StoreTierRuleGroup, rule.ID).LabelConstraints[0].Values, DeepEquals, []string{"hot"})
+
+	code, _ = s.moveRange(c, "6200", "", "cold")
+	c.Assert(code, Equals, http.StatusConflict)
+	code, _ = s.moveRange(c, "6262", "", "warm")
+	c.Assert(code, Equals, http.StatusNotFound)
+	code, _ = s.moveRange(c, "zz", "", "cold")
+	c.Assert(code, Equals, http.StatusBadRequest)
+	code, _ = s.moveRange(c, "6262", "", "")
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(manager.GetRulesByGroup(server.StoreTierRuleGroup), HasLen, 2)
+}
@@ -16,9 +16,14 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pingcap/errcode"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/tso"
 	"github.com/unrolled/render"
 )
 
@@ -75,3 +80,91 @@ func (h *tsoHandler) TransferLocalTSOAllocator(w http.ResponseWriter, r *http.Re
 	}
 	h.rd.JSON(w, http.StatusOK, "The transfer command is submitted.")
 }
+
+type tsoLeaseInput struct {
+	DCLocation string `json:"dc-location"`
+	Count      uint32 `json:"count"`
+	// TTL is the lease time-to-live in seconds.
+	TTL int64 `json:"ttl"`
+}
+
+// @Tags tso
+// @Summary Grant a range of timestamps as a lease.
+// @Accept json
+// @Param body body tsoLeaseInput true "json params"
+// @Produce json
+// @Success 200 {object} tso.Lease
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /tso/leases [post]
+func (h *tsoHandler) GrantLease(w http.ResponseWriter, r *http.Request) {
+	var input tsoLeaseInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	lease, err := h.svr.GetTSOAllocatorManager().GrantTSOLease(input.DCLocation, input.Count, time.Duration(input.TTL)*time.Second)
+	if err != nil {
+		if errs.ErrInvalidTSOLease.Equal(err) {
+			apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, lease)
+}
+
+type tsoLeases struct {
+	Leases []*tso.Lease `json:"leases"`
+	// MinLeasedTS is the minimal timestamp that may be still assigned by lease
+	// holders, it is 0 if there is no outstanding lease.
+	MinLeasedTS uint64 `json:"min-leased-ts"`
+}
+
+// @Tags tso
+// @Summary List all outstanding TSO leases.
+// @Produce json
+// @Success 200 {object} tsoLeases
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /tso/leases [get]
+func (h *tsoHandler) GetLeases(w http.ResponseWriter, r *http.Request) {
+	leases, err := h.svr.GetTSOAllocatorManager().GetTSOLeases()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := &tsoLeases{Leases: leases}
+	for _, lease := range leases {
+		if resp.MinLeasedTS == 0 || lease.Start < resp.MinLeasedTS {
+			resp.MinLeasedTS = lease.Start
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, resp)
+}
+
+// @Tags tso
+// @Summary Revoke a TSO lease.
+// @Param id path integer true "Lease Id"
+// @Produce json
+// @Success 200 {string} string "The lease is revoked."
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The lease does not exist."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /tso/leases/{id} [delete]
+func (h *tsoHandler) RevokeLease(w http.ResponseWriter, r *http.Request) {
+	id, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	revoked, err := h.svr.GetTSOAllocatorManager().RevokeTSOLease(id)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !revoked {
+		h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("lease %d not found", id))
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The lease is revoked.")
+}
@@ -14,13 +14,19 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"net/http"
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/tso"
 )
 
 var _ = Suite(&testTsoSuite{})
@@ -37,6 +43,7 @@ func (s *testTsoSuite) SetUpSuite(c *C) {
 		cfg.Labels[config.ZoneLabel] = "dc-1"
 	})
 	mustWaitLeader(c, []*server.Server{s.svr})
+	mustBootstrapCluster(c, s.svr)
 
 	addr := s.svr.GetAddr()
 	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
@@ -56,3 +63,61 @@ func (s *testTsoSuite) TestTransferAllocator(c *C) {
 	err := postJSON(testDialClient, addr, nil)
 	c.Assert(err, IsNil)
 }
+
+func (s *testTsoSuite) TestLease(c *C) {
+	url := s.urlPrefix + "/tso/leases"
+	var leases tsoLeases
+	c.Assert(readJSON(testDialClient, url, &leases), IsNil)
+	c.Assert(leases.Leases, HasLen, 0)
+	c.Assert(leases.MinLeasedTS, Equals, uint64(0))
+
+	// invalid input
+	data, err := json.Marshal(&tsoLeaseInput{Count: 0, TTL: 10})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, url, data), NotNil)
+
+	var first, second tso.Lease
+	data, err = json.Marshal(&tsoLeaseInput{Count: 100, TTL: 60})
+	c.Assert(err, IsNil)
+	err = postJSON(testDialClient, url, data, func(res []byte, statusCode int) {
+		c.Assert(statusCode, Equals, http.StatusOK)
+		c.Assert(json.Unmarshal(res, &first), IsNil)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(first.End-first.Start+1, Equals, uint64(100))
+	c.Assert(first.DCLocation, Equals, tso.GlobalDCLocation)
+
+	err = postJSON(testDialClient, url, data, func(res []byte, statusCode int) {
+		c.Assert(statusCode, Equals, http.StatusOK)
+		c.Assert(json.Unmarshal(res, &second), IsNil)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(second.Start > first.End, IsTrue)
+
+	c.Assert(readJSON(testDialClient, url, &leases), IsNil)
+	c.Assert(leases.Leases, HasLen, 2)
+	c.Assert(leases.MinLeasedTS, Equals, first.Start)
+
+	// The leases hold the GC safe point.
+	header := &pdpb.RequestHeader{ClusterId: s.svr.ClusterID()}
+	ssp, err := s.svr.UpdateServiceGCSafePoint(context.Background(), &pdpb.UpdateServiceGCSafePointRequest{
+		Header: header, ServiceId: []byte("gc_worker"), TTL: math.MaxInt64, SafePoint: second.End,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(string(ssp.GetServiceId()), Equals, "tso_lease")
+	c.Assert(ssp.GetMinSafePoint(), Equals, first.Start)
+	gc, err := s.svr.UpdateGCSafePoint(context.Background(), &pdpb.UpdateGCSafePointRequest{Header: header, SafePoint: second.End})
+	c.Assert(err, IsNil)
+	c.Assert(gc.GetNewSafePoint(), Equals, first.Start)
+
+	res, err := doDelete(testDialClient, fmt.Sprintf("%s/%d", url, first.ID))
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusOK)
+	res, err = doDelete(testDialClient, fmt.Sprintf("%s/%d", url, first.ID))
+	c.Assert(err, IsNil)
+	c.Assert(res.StatusCode, Equals, http.StatusNotFound)
+
+	c.Assert(readJSON(testDialClient, url, &leases), IsNil)
+	c.Assert(leases.Leases, HasLen, 1)
+	c.Assert(leases.MinLeasedTS, Equals, second.Start)
+}
@@ -0,0 +1,614 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/errors"
+	"github.com/tikv/pd/pkg/keyspace"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/statistics"
+	"github.com/unrolled/render"
+)
+
+// The v2 API lists resources page by page. Every list accepts an optional
+// `keyspace_id` to only return the resources related to the keyspace, and a
+// `limit` to bound the page size. The response carries the cursor of the next
+// page, which is omitted once the last page is returned.
+const (
+	defaultV2PageLimit = 100
+	maxV2PageLimit     = 1000
+)
+
+// RegionsPage is a page of regions.
+type RegionsPage struct {
+	Count   int           `json:"count"`
+	Regions []*RegionInfo `json:"regions"`
+	// NextKey is the hex encoded start key of the next page.
+	NextKey string `json:"next_key,omitempty"`
+}
+
+// StoresPage is a page of stores.
+type StoresPage struct {
+	Count  int          `json:"count"`
+	Stores []*StoreInfo `json:"stores"`
+	// NextID is the store ID that the next page starts from.
+	NextID uint64 `json:"next_id,omitempty"`
+}
+
+// OperatorInfo is the typed description of an operator.
+type OperatorInfo struct {
+	RegionID   uint64    `json:"region_id"`
+	Desc       string    `json:"desc"`
+	Kind       string    `json:"kind"`
+	Status     string    `json:"status"`
+	Steps      []string  `json:"steps"`
+	CreateTime time.Time `json:"create_time"`
+	StartTime  time.Time `json:"start_time"`
+}
+
+func newOperatorInfo(op *operator.Operator) *OperatorInfo {
+	steps := make([]string, 0, op.Len())
+	for i := 0; i < op.Len(); i++ {
+		steps = append(steps, op.Step(i).String())
+	}
+	return &OperatorInfo{
+		RegionID:   op.RegionID(),
+		Desc:       op.Desc(),
+		Kind:       op.Kind().String(),
+		Status:     operator.OpStatusToString(op.Status()),
+		Steps:      steps,
+		CreateTime: op.GetCreateTime(),
+		StartTime:  op.GetStartTime(),
+	}
+}
+
+// PeerIssue describes a down or pending peer of a region.
+type PeerIssue struct {
+	PeerID  uint64 `json:"peer_id"`
+	StoreID uint64 `json:"store_id"`
+	// DurationSeconds is how long the peer has been down or pending.
+	DurationSeconds uint64 `json:"duration_seconds"`
+}
+
+// PeerIssueRegion is a region with down or pending peers.
+type PeerIssueRegion struct {
+	ID       uint64       `json:"id"`
+	StartKey string       `json:"start_key"`
+	EndKey   string       `json:"end_key"`
+	Peers    []*PeerIssue `json:"peers"`
+	// Operator is the operator running or waiting to run on the region, which
+	// is usually the one fixing the peers.
+	Operator        *OperatorInfo `json:"operator,omitempty"`
+	OperatorWaiting bool          `json:"operator_waiting,omitempty"`
+}
+
+// PeerIssueRegionsPage is a page of regions with down or pending peers.
+type PeerIssueRegionsPage struct {
+	Count   int                `json:"count"`
+	Regions []*PeerIssueRegion `json:"regions"`
+	// NextID is the region ID that the next page starts from.
+	NextID uint64 `json:"next_id,omitempty"`
+}
+
+// OperatorsPage is a page of operators.
+type OperatorsPage struct {
+	Count     int             `json:"count"`
+	Operators []*OperatorInfo `json:"operators"`
+	// NextID is the region ID that the next page starts from.
+	NextID uint64 `json:"next_id,omitempty"`
+}
+
+// parseKeyspaceRanges returns the key ranges of the requested keyspace, or nil
+// if the request is not limited to a keyspace.
+func parseKeyspaceRanges(r *http.Request) ([]keyspace.KeyRange, error) {
+	idStr := r.URL.Query().Get("keyspace_id")
+	if idStr == "" {
+		return nil, nil
+	}
+	id, err := keyspace.ParseKeyspaceID(idStr)
+	if err != nil {
+		return nil, err
+	}
+	return keyspace.MakeKeyRanges(id), nil
+}
+
+func parsePageLimit(r *http.Request) (int, error) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return defaultV2PageLimit, nil
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		return 0, errors.Errorf("invalid limit %q", limitStr)
+	}
+	if limit > maxV2PageLimit {
+		limit = maxV2PageLimit
+	}
+	return limit, nil
+}
+
+func parseStartID(r *http.Request) (uint64, error) {
+	idStr := r.URL.Query().Get("start_id")
+	if idStr == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(idStr, 10, 64)
+}
+
+func regionInRanges(ranges []keyspace.KeyRange, region *core.RegionInfo) bool {
+	return ranges == nil || keyspace.RegionInKeyspace(ranges, region.GetStartKey(), region.GetEndKey())
+}
+
+type regionsV2Handler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newRegionsV2Handler(svr *server.Server, rd *render.Render) *regionsV2Handler {
+	return &regionsV2Handler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags region
+// @Summary List regions page by page in key order.
+// @Param keyspace_id query integer false "Only list the regions of the keyspace"
+// @Param start_key query string false "Hex encoded key to start from"
+// @Param limit query integer false "Page size"
+// @Produce json
+// @Success 200 {object} RegionsPage
+// @Failure 400 {string} string "The input is invalid."
+// @Router /regions [get]
+func (h *regionsV2Handler) List(w http.ResponseWriter, r *http.Request) {
+	rc := h.svr.GetRaftCluster()
+	ranges, err := parseKeyspaceRanges(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	startKey, err := hex.DecodeString(r.URL.Query().Get("start_key"))
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if ranges == nil {
+		ranges = []keyspace.KeyRange{{}}
+	}
+
+	// One more region is scanned to know whether there is a next page.
+	var regions []*core.RegionInfo
+	seen := make(map[uint64]struct{})
+	for _, kr := range ranges {
+		if len(kr.EndKey) > 0 && bytes.Compare(startKey, kr.EndKey) >= 0 {
+			continue
+		}
+		from := kr.StartKey
+		if bytes.Compare(startKey, from) > 0 {
+			from = startKey
+		}
+		for _, region := range rc.ScanRegions(from, kr.EndKey, limit+1-len(regions)) {
+			if _, ok := seen[region.GetID()]; ok {
+				continue
+			}
+			seen[region.GetID()] = struct{}{}
+			regions = append(regions, region)
+		}
+		if len(regions) > limit {
+			break
+		}
+	}
+
+	page := &RegionsPage{}
+	if len(regions) > limit {
+		regions = regions[:limit]
+		page.NextKey = core.HexRegionKeyStr(regions[limit-1].GetEndKey())
+	}
+	regionsInfo := convertToAPIRegions(regions)
+	page.Count, page.Regions = regionsInfo.Count, regionsInfo.Regions
+	h.rd.JSON(w, http.StatusOK, page)
+}
+
+// @Tags region
+// @Summary Get a region by ID.
+// @Param id path integer true "Region Id"
+// @Param keyspace_id query integer false "The keyspace the region should belong to"
+// @Produce json
+// @Success 200 {object} RegionInfo
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The region does not exist."
+// @Router /regions/{id} [get]
+func (h *regionsV2Handler) Get(w http.ResponseWriter, r *http.Request) {
+	rc := h.svr.GetRaftCluster()
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ranges, err := parseKeyspaceRanges(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	region := rc.GetRegion(id)
+	if region == nil || !regionInRanges(ranges, region) {
+		h.rd.JSON(w, http.StatusNotFound, "region not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, NewRegionInfo(region))
+}
+
+// parseStoreID returns the `store_id` of the request, or 0 if it is absent.
+func parseStoreID(r *http.Request) (uint64, error) {
+	idStr := r.URL.Query().Get("store_id")
+	if idStr == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(idStr, 10, 64)
+}
+
+// @Tags region
+// @Summary List regions with down peers page by page in region ID order.
+// @Param keyspace_id query integer false "Only list the regions of the keyspace"
+// @Param store_id query integer false "Only list the regions with down peers on the store"
+// @Param start_id query integer false "Region ID to start from"
+// @Param limit query integer false "Page size"
+// @Produce json
+// @Success 200 {object} PeerIssueRegionsPage
+// @Failure 400 {string} string "The input is invalid."
+// @Router /regions/check/down-peer [get]
+func (h *regionsV2Handler) ListDownPeer(w http.ResponseWriter, r *http.Request) {
+	h.listPeerIssues(w, r, statistics.DownPeer)
+}
+
+// @Tags region
+// @Summary List regions with pending peers page by page in region ID order.
+// @Param keyspace_id query integer false "Only list the regions of the keyspace"
+// @Param store_id query integer false "Only list the regions with pending peers on the store"
+// @Param start_id query integer false "Region ID to start from"
+// @Param limit query integer false "Page size"
+// @Produce json
+// @Success 200 {object} PeerIssueRegionsPage
+// @Failure 400 {string} string "The input is invalid."
+// @Router /regions/check/pending-peer [get]
+func (h *regionsV2Handler) ListPendingPeer(w http.ResponseWriter, r *http.Request) {
+	h.listPeerIssues(w, r, statistics.PendingPeer)
+}
+
+// listPeerIssues lists the regions recorded by the region statistics, so it
+// does not scan all regions.
+func (h *regionsV2Handler) listPeerIssues(w http.ResponseWriter, r *http.Request, typ statistics.RegionStatisticType) {
+	rc := h.svr.GetRaftCluster()
+	ranges, err := parseKeyspaceRanges(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	startID, err := parseStartID(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	storeID, err := parseStoreID(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var ids []uint64
+	for _, region := range rc.GetRegionStatsByType(typ) {
+		if region.GetID() >= startID {
+			ids = append(ids, region.GetID())
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var waitingOps map[uint64]*operator.Operator
+	oc := rc.GetOperatorController()
+	page := &PeerIssueRegionsPage{Regions: make([]*PeerIssueRegion, 0, limit)}
+	for _, id := range ids {
+		// The statistics may be staler than the region, so the latest one is
+		// checked again.
+		region := rc.GetRegion(id)
+		if region == nil || !regionInRanges(ranges, region) {
+			continue
+		}
+		peers := h.peerIssues(rc, region, typ)
+		if storeID != 0 {
+			peers = filterPeerIssues(peers, storeID)
+		}
+		if len(peers) == 0 {
+			continue
+		}
+		if len(page.Regions) == limit {
+			page.NextID = id
+			break
+		}
+		item := &PeerIssueRegion{
+			ID:       id,
+			StartKey: core.HexRegionKeyStr(region.GetStartKey()),
+			EndKey:   core.HexRegionKeyStr(region.GetEndKey()),
+			Peers:    peers,
+		}
+		if op := oc.GetOperator(id); op != nil {
+			item.Operator = newOperatorInfo(op)
+		} else {
+			if waitingOps == nil {
+				waitingOps = make(map[uint64]*operator.Operator)
+				for _, op := range oc.GetWaitingOperators() {
+					waitingOps[op.RegionID()] = op
+				}
+			}
+			if op, ok := waitingOps[id]; ok {
+				item.Operator, item.OperatorWaiting = newOperatorInfo(op), true
+			}
+		}
+		page.Regions = append(page.Regions, item)
+	}
+	page.Count = len(page.Regions)
+	h.rd.JSON(w, http.StatusOK, page)
+}
+
+func (h *regionsV2Handler) peerIssues(rc *cluster.RaftCluster, region *core.RegionInfo, typ statistics.RegionStatisticType) []*PeerIssue {
+	var peers []*PeerIssue
+	if typ == statistics.DownPeer {
+		for _, stats := range region.GetDownPeers() {
+			peers = append(peers, &PeerIssue{
+				PeerID:          stats.GetPeer().GetId(),
+				StoreID:         stats.GetPeer().GetStoreId(),
+				DurationSeconds: stats.GetDownSeconds(),
+			})
+		}
+		return peers
+	}
+	var duration uint64
+	if since := rc.GetPendingPeerStartTime(region.GetID()); !since.IsZero() {
+		duration = uint64(time.Since(since) / time.Second)
+	}
+	for _, peer := range region.GetPendingPeers() {
+		peers = append(peers, &PeerIssue{
+			PeerID:          peer.GetId(),
+			StoreID:         peer.GetStoreId(),
+			DurationSeconds: duration,
+		})
+	}
+	return peers
+}
+
+func filterPeerIssues(peers []*PeerIssue, storeID uint64) []*PeerIssue {
+	var res []*PeerIssue
+	for _, peer := range peers {
+		if peer.StoreID == storeID {
+			res = append(res, peer)
+		}
+	}
+	return res
+}
+
+type storesV2Handler struct {
+	*server.Handler
+	rd *render.Render
+}
+
+func newStoresV2Handler(handler *server.Handler, rd *render.Render) *storesV2Handler {
+	return &storesV2Handler{
+		Handler: handler,
+		rd:      rd,
+	}
+}
+
+// keyspaceStores returns the IDs of the stores which hold any peer of the
+// keyspace. It scans all regions of the keyspace.
+func keyspaceStores(rc *cluster.RaftCluster, ranges []keyspace.KeyRange) map[uint64]struct{} {
+	stores := make(map[uint64]struct{})
+	for _, kr := range ranges {
+		for _, region := range rc.ScanRegions(kr.StartKey, kr.EndKey, 0) {
+			for _, peer := range region.GetPeers() {
+				stores[peer.GetStoreId()] = struct{}{}
+			}
+		}
+	}
+	return stores
+}
+
+// @Tags store
+// @Summary List stores page by page in ID order.
+// @Param keyspace_id query integer false "Only list the stores holding peers of the keyspace"
+// @Param start_id query integer false "Store ID to start from"
+// @Param limit query integer false "Page size"
+// @Produce json
+// @Success 200 {object} StoresPage
+// @Failure 400 {string} string "The input is invalid."
+// @Router /stores [get]
+func (h *storesV2Handler) List(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	ranges, err := parseKeyspaceRanges(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	startID, err := parseStartID(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var keyspaceStoreIDs map[uint64]struct{}
+	if ranges != nil {
+		keyspaceStoreIDs = keyspaceStores(rc, ranges)
+	}
+
+	stores := rc.GetStores()
+	sort.Slice(stores, func(i, j int) bool { return stores[i].GetID() < stores[j].GetID() })
+	opt := h.GetScheduleConfig()
+	page := &StoresPage{Stores: make([]*StoreInfo, 0, limit)}
+	for _, store := range stores {
+		if store.GetID() < startID {
+			continue
+		}
+		if keyspaceStoreIDs != nil {
+			if _, ok := keyspaceStoreIDs[store.GetID()]; !ok {
+				continue
+			}
+		}
+		if len(page.Stores) == limit {
+			page.NextID = store.GetID()
+			break
+		}
+		page.Stores = append(page.Stores, newStoreInfo(opt, store))
+	}
+	page.Count = len(page.Stores)
+	h.rd.JSON(w, http.StatusOK, page)
+}
+
+// @Tags store
+// @Summary Get a store by ID.
+// @Param id path integer true "Store Id"
+// @Produce json
+// @Success 200 {object} StoreInfo
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The store does not exist."
+// @Router /stores/{id} [get]
+func (h *storesV2Handler) Get(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	store := rc.GetStore(id)
+	if store == nil {
+		h.rd.JSON(w, http.StatusNotFound, "store not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, newStoreInfo(h.GetScheduleConfig(), store))
+}
+
+type operatorsV2Handler struct {
+	*server.Handler
+	rd *render.Render
+}
+
+func newOperatorsV2Handler(handler *server.Handler, rd *render.Render) *operatorsV2Handler {
+	return &operatorsV2Handler{
+		Handler: handler,
+		rd:      rd,
+	}
+}
+
+// @Tags operator
+// @Summary List running operators page by page in region ID order.
+// @Param keyspace_id query integer false "Only list the operators of the regions in the keyspace"
+// @Param start_id query integer false "Region ID to start from"
+// @Param limit query integer false "Page size"
+// @Produce json
+// @Success 200 {object} OperatorsPage
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /operators [get]
+func (h *operatorsV2Handler) List(w http.ResponseWriter, r *http.Request) {
+	rc, _ := h.GetRaftCluster()
+	ranges, err := parseKeyspaceRanges(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	startID, err := parseStartID(r)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ops, err := h.GetOperators()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sort.Slice(ops, func(i, j int) bool { return ops[i].RegionID() < ops[j].RegionID() })
+	page := &OperatorsPage{Operators: make([]*OperatorInfo, 0, limit)}
+	for _, op := range ops {
+		if op.RegionID() < startID {
+			continue
+		}
+		if ranges != nil {
+			region := rc.GetRegion(op.RegionID())
+			if region == nil || !regionInRanges(ranges, region) {
+				continue
+			}
+		}
+		if len(page.Operators) == limit {
+			page.NextID = op.RegionID()
+			break
+		}
+		page.Operators = append(page.Operators, newOperatorInfo(op))
+	}
+	page.Count = len(page.Operators)
+	h.rd.JSON(w, http.StatusOK, page)
+}
+
+// @Tags operator
+// @Summary Get the running operator of a region.
+// @Param region_id path integer true "Region Id"
+// @Produce json
+// @Success 200 {object} OperatorInfo
+// @Failure 400 {string} string "The input is invalid."
+// @Failure 404 {string} string "The region has no running operator."
+// @Router /operators/{region_id} [get]
+func (h *operatorsV2Handler) Get(w http.ResponseWriter, r *http.Request) {
+	regionID, err := strconv.ParseUint(mux.Vars(r)["region_id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c, err := h.GetOperatorController()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	op := c.GetOperator(regionID)
+	if op == nil {
+		h.rd.JSON(w, http.StatusNotFound, "operator not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, newOperatorInfo(op))
+}
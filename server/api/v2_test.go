@@ -0,0 +1,265 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/keyspace"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+)
+
+var _ = Suite(&testV2Suite{})
+
+type testV2Suite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testV2Suite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.Replication.EnablePlacementRules = false })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v2", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	for _, id := range []uint64{3, 1, 2} {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+	}
+
+	// The regions 2 and 4 belong to the keyspace 1.
+	ranges := keyspace.MakeKeyRanges(1)
+	keys := [][]byte{{}, ranges[0].StartKey, ranges[0].EndKey, ranges[1].StartKey, ranges[1].EndKey, {}}
+	for i := 0; i < len(keys)-1; i++ {
+		mustRegionHeartbeat(c, s.svr, newTestRegionInfo(uint64(i+1), uint64(i%2+1), keys[i], keys[i+1]))
+	}
+}
+
+func (s *testV2Suite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testV2Suite) TestRegions(c *C) {
+	var ids []uint64
+	nextKey := ""
+	for {
+		page := &RegionsPage{}
+		err := readJSON(testDialClient, fmt.Sprintf("%s/regions?limit=2&start_key=%s", s.urlPrefix, nextKey), page)
+		c.Assert(err, IsNil)
+		c.Assert(page.Count, Equals, len(page.Regions))
+		for _, region := range page.Regions {
+			ids = append(ids, region.ID)
+		}
+		if page.NextKey == "" {
+			break
+		}
+		nextKey = page.NextKey
+	}
+	c.Assert(ids, DeepEquals, []uint64{1, 2, 3, 4, 5})
+
+	page := &RegionsPage{}
+	err := readJSON(testDialClient, fmt.Sprintf("%s/regions?keyspace_id=1", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 2)
+	c.Assert(page.Regions[0].ID, Equals, uint64(2))
+	c.Assert(page.Regions[1].ID, Equals, uint64(4))
+	c.Assert(page.NextKey, Equals, "")
+	page = &RegionsPage{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/regions?keyspace_id=1&limit=1", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 1)
+	c.Assert(page.NextKey, Equals, page.Regions[0].EndKey)
+	nextKey = page.NextKey
+	page = &RegionsPage{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/regions?keyspace_id=1&limit=1&start_key=%s", s.urlPrefix, nextKey), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 1)
+	c.Assert(page.Regions[0].ID, Equals, uint64(4))
+	c.Assert(page.NextKey, Equals, "")
+
+	region := &RegionInfo{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/regions/4?keyspace_id=1", s.urlPrefix), region)
+	c.Assert(err, IsNil)
+	c.Assert(region.ID, Equals, uint64(4))
+	status, _ := requestStatusBody(c, testDialClient, http.MethodGet, fmt.Sprintf("%s/regions/3?keyspace_id=1", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusNotFound)
+	status, _ = requestStatusBody(c, testDialClient, http.MethodGet, fmt.Sprintf("%s/regions?keyspace_id=abc", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusBadRequest)
+}
+
+func (s *testV2Suite) TestStores(c *C) {
+	page := &StoresPage{}
+	err := readJSON(testDialClient, fmt.Sprintf("%s/stores?limit=2", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 2)
+	c.Assert(page.Stores[0].Store.GetId(), Equals, uint64(1))
+	c.Assert(page.Stores[1].Store.GetId(), Equals, uint64(2))
+	c.Assert(page.NextID, Equals, uint64(3))
+	page = &StoresPage{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/stores?limit=2&start_id=3", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 1)
+	c.Assert(page.NextID, Equals, uint64(0))
+
+	// Only the store 2 holds the peers of the keyspace 1.
+	page = &StoresPage{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/stores?keyspace_id=1", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 1)
+	c.Assert(page.Stores[0].Store.GetId(), Equals, uint64(2))
+
+	store := &StoreInfo{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/stores/3", s.urlPrefix), store)
+	c.Assert(err, IsNil)
+	c.Assert(store.Store.GetId(), Equals, uint64(3))
+	status, _ := requestStatusBody(c, testDialClient, http.MethodGet, fmt.Sprintf("%s/stores/100", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusNotFound)
+}
+
+func (s *testV2Suite) TestOperators(c *C) {
+	v1Prefix := fmt.Sprintf("%s%s/api/v1", s.svr.GetAddr(), apiPrefix)
+	for _, regionID := range []uint64{1, 2} {
+		err := postJSON(testDialClient, fmt.Sprintf("%s/operators", v1Prefix), []byte(fmt.Sprintf(`{"name":"add-peer", "region_id": %d, "store_id": 3}`, regionID)))
+		c.Assert(err, IsNil)
+	}
+	defer func() {
+		for _, regionID := range []uint64{1, 2} {
+			_, err := doDelete(testDialClient, fmt.Sprintf("%s/operators/%d", v1Prefix, regionID))
+			c.Assert(err, IsNil)
+		}
+	}()
+
+	page := &OperatorsPage{}
+	err := readJSON(testDialClient, fmt.Sprintf("%s/operators?limit=1", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 1)
+	c.Assert(page.Operators[0].RegionID, Equals, uint64(1))
+	c.Assert(page.NextID, Equals, uint64(2))
+	page = &OperatorsPage{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/operators?keyspace_id=1", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 1)
+	c.Assert(page.Operators[0].RegionID, Equals, uint64(2))
+	c.Assert(page.Operators[0].Steps, HasLen, 2)
+
+	op := &OperatorInfo{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/operators/2", s.urlPrefix), op)
+	c.Assert(err, IsNil)
+	c.Assert(op.Desc, Equals, "admin-add-peer")
+	status, _ := requestStatusBody(c, testDialClient, http.MethodGet, fmt.Sprintf("%s/operators/3", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusNotFound)
+}
+
+func (s *testV2Suite) TestDeprecationHeaders(c *C) {
+	v1Prefix := fmt.Sprintf("%s%s/api/v1", s.svr.GetAddr(), apiPrefix)
+	resp, err := testDialClient.Get(fmt.Sprintf("%s/region/id/1", v1Prefix))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("Deprecation"), Equals, "true")
+	c.Assert(resp.Header.Get("Link"), Equals, fmt.Sprintf(`<%s/api/v2/regions/{id}>; rel="successor-version"`, apiPrefix))
+
+	resp, err = testDialClient.Get(fmt.Sprintf("%s/regions/count", v1Prefix))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.Header.Get("Deprecation"), Equals, "")
+}
+
+func (s *testV2Suite) TestKeyspaceRegionRanges(c *C) {
+	region := newTestRegionInfo(100, 1, []byte("a"), []byte("b"))
+	c.Assert(regionInRanges(nil, region), IsTrue)
+	c.Assert(regionInRanges(keyspace.MakeKeyRanges(1), region), IsFalse)
+	c.Assert(regionInRanges(keyspace.MakeKeyRanges(1), core.NewRegionInfo(&metapb.Region{}, nil)), IsTrue)
+}
+
+func (s *testV2Suite) TestPeerIssueRegions(c *C) {
+	rc := s.svr.GetRaftCluster()
+	regions := make([]*core.RegionInfo, 0, 4)
+	for _, id := range []uint64{1, 2, 3, 4} {
+		origin := rc.GetRegion(id)
+		peer := &metapb.Peer{Id: 100 + id, StoreId: 3}
+		opts := []core.RegionCreateOption{core.WithAddPeer(peer), core.WithIncConfVer()}
+		if id == 2 {
+			opts = append(opts, core.WithPendingPeers([]*metapb.Peer{peer}))
+		} else {
+			opts = append(opts, core.WithDownPeers([]*pdpb.PeerStats{{Peer: peer, DownSeconds: 60}}))
+		}
+		region := origin.Clone(opts...)
+		mustRegionHeartbeat(c, s.svr, region)
+		regions = append(regions, region)
+	}
+	defer func() {
+		for _, region := range regions {
+			mustRegionHeartbeat(c, s.svr, region.Clone(
+				core.WithRemoveStorePeer(3),
+				core.WithIncConfVer(),
+				core.WithDownPeers(nil),
+				core.WithPendingPeers(nil),
+			))
+		}
+	}()
+
+	page := &PeerIssueRegionsPage{}
+	err := readJSON(testDialClient, fmt.Sprintf("%s/regions/check/down-peer?limit=2", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 2)
+	c.Assert(page.Regions[0].ID, Equals, uint64(1))
+	c.Assert(page.Regions[1].ID, Equals, uint64(3))
+	c.Assert(page.Regions[0].Peers, DeepEquals, []*PeerIssue{{PeerID: 101, StoreID: 3, DurationSeconds: 60}})
+	c.Assert(page.NextID, Equals, uint64(4))
+	page = &PeerIssueRegionsPage{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/regions/check/down-peer?limit=2&start_id=4", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 1)
+	c.Assert(page.Regions[0].ID, Equals, uint64(4))
+	c.Assert(page.NextID, Equals, uint64(0))
+	page = &PeerIssueRegionsPage{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/regions/check/down-peer?keyspace_id=1", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 1)
+	c.Assert(page.Regions[0].ID, Equals, uint64(4))
+	page = &PeerIssueRegionsPage{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/regions/check/down-peer?store_id=1", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 0)
+
+	// The operator of the region is reported.
+	v1Prefix := fmt.Sprintf("%s%s/api/v1", s.svr.GetAddr(), apiPrefix)
+	err = postJSON(testDialClient, fmt.Sprintf("%s/operators", v1Prefix), []byte(`{"name":"remove-peer", "region_id": 2, "store_id": 3}`))
+	c.Assert(err, IsNil)
+	defer func() {
+		_, err := doDelete(testDialClient, fmt.Sprintf("%s/operators/2", v1Prefix))
+		c.Assert(err, IsNil)
+	}()
+	page = &PeerIssueRegionsPage{}
+	err = readJSON(testDialClient, fmt.Sprintf("%s/regions/check/pending-peer?store_id=3", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 1)
+	c.Assert(page.Regions[0].ID, Equals, uint64(2))
+	c.Assert(page.Regions[0].Peers[0].PeerID, Equals, uint64(102))
+	c.Assert(page.Regions[0].Operator, NotNil)
+	c.Assert(page.Regions[0].Operator.Desc, Equals, "admin-remove-peer")
+	c.Assert(page.Regions[0].OperatorWaiting, IsFalse)
+
+	status, _ := requestStatusBody(c, testDialClient, http.MethodGet, fmt.Sprintf("%s/regions/check/pending-peer?store_id=abc", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusBadRequest)
+}
@@ -0,0 +1,213 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/versioninfo"
+	"github.com/unrolled/render"
+)
+
+// The component names of the PD members and the stores in the version skew
+// report. The other components use the names they registered with.
+const (
+	pdComponent      = "pd"
+	tikvComponent    = "tikv"
+	tiflashComponent = "tiflash"
+)
+
+// ComponentVersion is the version of a component instance.
+type ComponentVersion struct {
+	Component string `json:"component"`
+	// ID is the member ID of PD or the store ID of TiKV and TiFlash.
+	ID      uint64 `json:"id,omitempty"`
+	Address string `json:"address"`
+	// Version is empty if the instance does not report its version.
+	Version string `json:"version"`
+}
+
+// VersionSkewReport is the versions of all the components in the cluster.
+type VersionSkewReport struct {
+	ClusterVersion string              `json:"cluster_version"`
+	Components     []*ComponentVersion `json:"components"`
+	// Matrix counts the instances of each component by version.
+	Matrix map[string]map[string]int `json:"matrix"`
+	// DisabledFeatures is the features which are disabled by the cluster
+	// version.
+	DisabledFeatures []string `json:"disabled_features"`
+	Warnings         []string `json:"warnings"`
+}
+
+func (r *VersionSkewReport) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+type versionSkewHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newVersionSkewHandler(svr *server.Server, rd *render.Render) *versionSkewHandler {
+	return &versionSkewHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags version
+// @Summary Get the versions of all the components and the warnings about the version skews.
+// @Produce json
+// @Success 200 {object} VersionSkewReport
+// @Failure 500 {string} string "PD server failed to proceed the request."
+// @Router /version/skew [get]
+func (h *versionSkewHandler) GetVersionSkew(w http.ResponseWriter, r *http.Request) {
+	members, err := getMembers(h.svr)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var pds []*ComponentVersion
+	for _, m := range members.GetMembers() {
+		pd := &ComponentVersion{
+			Component: pdComponent,
+			ID:        m.GetMemberId(),
+			Version:   m.GetBinaryVersion(),
+		}
+		if len(m.GetClientUrls()) > 0 {
+			pd.Address = m.GetClientUrls()[0]
+		}
+		pds = append(pds, pd)
+	}
+	h.rd.JSON(w, http.StatusOK, buildVersionSkewReport(h.svr.GetRaftCluster(), pds))
+}
+
+// buildVersionSkewReport checks the versions of the PD members, the stores and
+// the registered components. The stores and components should not be newer
+// than PD, because PD must be upgraded first.
+func buildVersionSkewReport(rc *cluster.RaftCluster, pds []*ComponentVersion) *VersionSkewReport {
+	report := &VersionSkewReport{
+		ClusterVersion:   rc.GetClusterVersion(),
+		Components:       []*ComponentVersion{},
+		Matrix:           make(map[string]map[string]int),
+		DisabledFeatures: []string{},
+		Warnings:         []string{},
+	}
+
+	parse := func(c *ComponentVersion) *semver.Version {
+		report.Components = append(report.Components, c)
+		if report.Matrix[c.Component] == nil {
+			report.Matrix[c.Component] = make(map[string]int)
+		}
+		report.Matrix[c.Component][c.Version]++
+		if c.Version == "" {
+			report.warn("%s %s does not report its version", c.Component, c.Address)
+			return nil
+		}
+		v, err := versioninfo.ParseVersion(c.Version)
+		if err != nil {
+			report.warn("%s %s has invalid version %s", c.Component, c.Address, c.Version)
+			return nil
+		}
+		return v
+	}
+
+	var minPD, maxPD *semver.Version
+	for _, pd := range pds {
+		if v := parse(pd); v != nil {
+			if minPD == nil || v.LessThan(*minPD) {
+				minPD = v
+			}
+			if maxPD == nil || maxPD.LessThan(*v) {
+				maxPD = v
+			}
+		}
+	}
+	if minPD != nil && (minPD.Major != maxPD.Major || minPD.Minor != maxPD.Minor) {
+		report.warn("PD members run different versions from %s to %s", minPD, maxPD)
+	}
+	checkNotNewerThanPD := func(c *ComponentVersion, v *semver.Version) {
+		if minPD != nil && !versioninfo.IsCompatible(*v, *minPD) {
+			report.warn("%s %s version %s is newer than PD version %s, PD should be upgraded first", c.Component, c.Address, v, minPD)
+		}
+	}
+
+	var minStore, maxStore *semver.Version
+	for _, store := range rc.GetStores() {
+		if store.IsTombstone() {
+			continue
+		}
+		c := &ComponentVersion{
+			Component: tikvComponent,
+			ID:        store.GetID(),
+			Address:   store.GetAddress(),
+			Version:   store.GetVersion(),
+		}
+		if store.IsTiFlash() {
+			c.Component = tiflashComponent
+		}
+		v := parse(c)
+		if v == nil {
+			continue
+		}
+		checkNotNewerThanPD(c, v)
+		if minStore == nil || v.LessThan(*minStore) {
+			minStore = v
+		}
+		if maxStore == nil || maxStore.LessThan(*v) {
+			maxStore = v
+		}
+	}
+	if minStore != nil && (minStore.Major != maxStore.Major || minStore.Minor != maxStore.Minor) {
+		report.warn("stores run different versions from %s to %s", minStore, maxStore)
+	}
+
+	addrs := rc.GetComponentManager().GetAllComponentAddrs()
+	names := make([]string, 0, len(addrs))
+	for name := range addrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, addr := range addrs[name] {
+			c := &ComponentVersion{
+				Component: name,
+				Address:   addr,
+				Version:   rc.GetComponentManager().GetComponentVersion(addr),
+			}
+			if v := parse(c); v != nil {
+				checkNotNewerThanPD(c, v)
+			}
+		}
+	}
+
+	clusterVersion, err := versioninfo.ParseVersion(report.ClusterVersion)
+	if err != nil {
+		report.warn("cluster version %s is invalid", report.ClusterVersion)
+		return report
+	}
+	for _, f := range versioninfo.UnsupportedFeatures(*clusterVersion) {
+		report.DisabledFeatures = append(report.DisabledFeatures, f.String())
+		// The feature is enabled once the older stores are upgraded.
+		if maxStore != nil && !maxStore.LessThan(*versioninfo.MinSupportedVersion(f)) {
+			report.warn("feature %s is disabled until all stores are upgraded to %s", f, versioninfo.MinSupportedVersion(f))
+		}
+	}
+	return report
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/server"
+)
+
+var _ = Suite(&testVersionSkewSuite{})
+
+type testVersionSkewSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testVersionSkewSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	for _, store := range []*metapb.Store{
+		{Id: 1, Address: "tikv1", Version: "4.0.0"},
+		{Id: 2, Address: "tikv2", Version: "5.0.0"},
+		{Id: 3, Address: "tiflash3", Version: "4.0.0", Labels: []*metapb.StoreLabel{{Key: "engine", Value: "tiflash"}}},
+	} {
+		_, err := s.svr.PutStore(context.Background(), &pdpb.PutStoreRequest{
+			Header: &pdpb.RequestHeader{ClusterId: s.svr.ClusterID()},
+			Store:  store,
+		})
+		c.Assert(err, IsNil)
+	}
+	for _, req := range []map[string]string{
+		{"component": "c1", "addr": "127.0.0.1:1", "version": "5.0.0"},
+		{"component": "c2", "addr": "127.0.0.1:2"},
+	} {
+		data, err := json.Marshal(req)
+		c.Assert(err, IsNil)
+		c.Assert(postJSON(testDialClient, s.urlPrefix+"/component", data), IsNil)
+	}
+}
+
+func (s *testVersionSkewSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testVersionSkewSuite) TestBuildReport(c *C) {
+	pds := []*ComponentVersion{
+		{Component: pdComponent, ID: 1, Address: "pd1", Version: "4.0.0"},
+		{Component: pdComponent, ID: 2, Address: "pd2", Version: "4.0.1"},
+	}
+	report := buildVersionSkewReport(s.svr.GetRaftCluster(), pds)
+	c.Assert(report.ClusterVersion, Equals, "4.0.0")
+	c.Assert(report.Components, HasLen, 7)
+	c.Assert(report.Matrix, DeepEquals, map[string]map[string]int{
+		pdComponent:      {"4.0.0": 1, "4.0.1": 1},
+		tikvComponent:    {"4.0.0": 1, "5.0.0": 1},
+		tiflashComponent: {"4.0.0": 1},
+		"c1":             {"5.0.0": 1},
+		"c2":             {"": 1},
+	})
+	c.Assert(report.DisabledFeatures, DeepEquals, []string{"joint-consensus"})
+	c.Assert(report.Warnings, DeepEquals, []string{
+		"tikv tikv2 version 5.0.0 is newer than PD version 4.0.0, PD should be upgraded first",
+		"stores run different versions from 4.0.0 to 5.0.0",
+		"c1 127.0.0.1:1 version 5.0.0 is newer than PD version 4.0.0, PD should be upgraded first",
+		"c2 127.0.0.1:2 does not report its version",
+		"feature joint-consensus is disabled until all stores are upgraded to 5.0.0",
+	})
+
+	// PD members with different minor versions.
+	pds[1].Version = "5.0.0"
+	report = buildVersionSkewReport(s.svr.GetRaftCluster(), pds)
+	c.Assert(report.Warnings[0], Equals, "PD members run different versions from 4.0.0 to 5.0.0")
+}
+
+func (s *testVersionSkewSuite) TestGetVersionSkew(c *C) {
+	report := &VersionSkewReport{}
+	c.Assert(readJSON(testDialClient, s.urlPrefix+"/version/skew", report), IsNil)
+	c.Assert(report.ClusterVersion, Equals, "4.0.0")
+	c.Assert(report.Matrix[pdComponent], HasLen, 1)
+	c.Assert(report.Matrix[tikvComponent], HasLen, 2)
+	c.Assert(report.DisabledFeatures, DeepEquals, []string{"joint-consensus"})
+
+	// The version of the component must be valid.
+	data, err := json.Marshal(map[string]string{"component": "c3", "addr": "127.0.0.1:3", "version": "x"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(testDialClient, s.urlPrefix+"/component", data), NotNil)
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/server/core"
+	"google.golang.org/grpc"
+)
+
+// bulkRegionHeartbeatService accepts many region heartbeats in one message, so
+// that a store with lots of regions does not pay the overhead of a message for
+// each region.
+var bulkRegionHeartbeatService = sideService{
+	name:   grpcutil.BulkRegionHeartbeatServiceName,
+	checks: defaultGRPCChecks,
+	streams: []sideStream{
+		{
+			name:          grpcutil.RegionHeartbeatsStreamName,
+			clientStreams: true,
+			handle: func(s *Server, _ interface{}, stream grpc.ServerStream) error {
+				return s.RegionHeartbeats(stream)
+			},
+		},
+	},
+}
+
+// bulkHeartbeatServer wraps the bulk region heartbeat stream like
+// heartbeatServer, so that it can be bound to the stores to send the
+// responses of the regions.
+type bulkHeartbeatServer struct {
+	stream grpc.ServerStream
+	closed int32
+}
+
+func (s *bulkHeartbeatServer) Send(m *pdpb.RegionHeartbeatResponse) error {
+	if atomic.LoadInt32(&s.closed) == 1 {
+		return io.EOF
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.stream.SendMsg(m) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			atomic.StoreInt32(&s.closed, 1)
+		}
+		return errors.WithStack(err)
+	case <-time.After(regionHeartbeatSendTimeout):
+		atomic.StoreInt32(&s.closed, 1)
+		return errors.WithStack(errSendRegionHeartbeatTimeout)
+	}
+}
+
+func (s *bulkHeartbeatServer) Recv() (*grpcutil.RegionHeartbeatBatch, error) {
+	if atomic.LoadInt32(&s.closed) == 1 {
+		return nil, io.EOF
+	}
+	batch := &grpcutil.RegionHeartbeatBatch{}
+	if err := s.stream.RecvMsg(batch); err != nil {
+		atomic.StoreInt32(&s.closed, 1)
+		return nil, errors.WithStack(err)
+	}
+	return batch, nil
+}
+
+// RegionHeartbeats handles the region heartbeats in batches. Each batch is
+// checked and applied to the cluster at once, and the responses of the
+// regions are sent back through the stream as RegionHeartbeat does. The
+// stream is not forwarded, so it should be created with the leader.
+func (s *Server) RegionHeartbeats(stream grpc.ServerStream) error {
+	server := &bulkHeartbeatServer{stream: stream}
+	lastBind := make(map[uint64]time.Time)
+	for {
+		batch, err := server.Recv()
+		if errors.Cause(err) == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rc := s.GetRaftCluster()
+		if rc == nil {
+			resp := &pdpb.RegionHeartbeatResponse{
+				Header: s.notBootstrappedHeader(),
+			}
+			return server.Send(resp)
+		}
+
+		regions := make([]*core.RegionInfo, 0, len(batch.Requests))
+		storeAddresses := make([]string, 0, len(batch.Requests))
+		for _, request := range batch.Requests {
+			// The role is validated when the batch is received. The other
+			// errors only fail the region, so that a bad request does not
+			// drop the heartbeats of the whole batch.
+			if err := s.validateClusterID(request.GetHeader()); err != nil {
+				if err := s.sendRegionHeartbeatErr(server, request, err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			storeID := request.GetLeader().GetStoreId()
+			storeLabel := strconv.FormatUint(storeID, 10)
+			store := rc.GetStore(storeID)
+			if store == nil {
+				if err := s.sendRegionHeartbeatErr(server, request, fmt.Sprintf("invalid store ID %d, not found", storeID)); err != nil {
+					return err
+				}
+				continue
+			}
+			storeAddress := store.GetAddress()
+
+			regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "recv").Inc()
+			regionHeartbeatLatency.WithLabelValues(storeAddress, storeLabel).Observe(float64(time.Now().Unix()) - float64(request.GetInterval().GetEndTimestamp()))
+
+			if time.Since(lastBind[storeID]) > s.cfg.HeartbeatStreamBindInterval.Duration {
+				regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "bind").Inc()
+				s.hbStreams.BindStream(storeID, server)
+				lastBind[storeID] = time.Now()
+			}
+
+			if region := s.regionFromHeartbeat(rc, request, storeAddress, storeLabel); region != nil {
+				regions = append(regions, region)
+				storeAddresses = append(storeAddresses, storeAddress)
+			}
+		}
+		bulkRegionHeartbeatBatchSize.Observe(float64(len(batch.Requests)))
+		if len(regions) == 0 {
+			continue
+		}
+
+		start := time.Now()
+		results := rc.HandleRegionHeartbeats(regions)
+		bulkRegionHeartbeatHandleDuration.Observe(time.Since(start).Seconds())
+		for i, region := range regions {
+			storeAddress, storeLabel := storeAddresses[i], strconv.FormatUint(region.GetLeader().GetStoreId(), 10)
+			if err := results[i]; err != nil {
+				regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "err").Inc()
+				s.hbStreams.SendErr(pdpb.ErrorType_UNKNOWN, err.Error(), region.GetLeader())
+				continue
+			}
+			regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "ok").Inc()
+		}
+	}
+}
+
+// sendRegionHeartbeatErr responds to the heartbeat of a region with an error
+// through the stream directly, since the stream may not be bound to the store
+// of the region.
+func (s *Server) sendRegionHeartbeatErr(server *bulkHeartbeatServer, request *pdpb.RegionHeartbeatRequest, msg string) error {
+	return server.Send(&pdpb.RegionHeartbeatResponse{
+		Header: s.errorHeader(&pdpb.Error{
+			Type:    pdpb.ErrorType_UNKNOWN,
+			Message: msg,
+		}),
+		RegionId:    request.GetRegion().GetId(),
+		RegionEpoch: request.GetRegion().GetRegionEpoch(),
+		TargetPeer:  request.GetLeader(),
+	})
+}
@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coreos/go-semver/semver"
@@ -33,20 +35,27 @@ import (
 	"github.com/tikv/pd/pkg/component"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/etcdutil"
+	"github.com/tikv/pd/pkg/eventbus"
+	"github.com/tikv/pd/pkg/fairqueue"
 	"github.com/tikv/pd/pkg/keyutil"
 	"github.com/tikv/pd/pkg/logutil"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/decisionexport"
+	"github.com/tikv/pd/server/hothistory"
 	"github.com/tikv/pd/server/id"
+	"github.com/tikv/pd/server/ophistory"
 	syncer "github.com/tikv/pd/server/region_syncer"
 	"github.com/tikv/pd/server/replication"
 	"github.com/tikv/pd/server/schedule"
 	"github.com/tikv/pd/server/schedule/checker"
 	"github.com/tikv/pd/server/schedule/hbstream"
+	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/topohistory"
 	"github.com/tikv/pd/server/versioninfo"
 	"go.etcd.io/etcd/clientv3"
 	"go.uber.org/zap"
@@ -73,6 +82,7 @@ type Server interface {
 	GetRaftCluster() *RaftCluster
 	GetBasicCluster() *core.BasicCluster
 	ReplicateFileToAllMembers(ctx context.Context, name string, data []byte) error
+	GetEventBus() *eventbus.Bus
 }
 
 // RaftCluster is used for cluster config management.
@@ -113,6 +123,7 @@ type RaftCluster struct {
 	wg           sync.WaitGroup
 	quit         chan struct{}
 	regionSyncer *syncer.RegionSyncer
+	eventBus     *eventbus.Bus
 
 	ruleManager *placement.RuleManager
 	etcdClient  *clientv3.Client
@@ -120,6 +131,21 @@ type RaftCluster struct {
 
 	replicationMode *replication.ModeManager
 	traceRegionFlow bool
+	hbBudget        heartbeatBudget
+	hbQueue         *fairqueue.Queue
+	hbShare         heartbeatShare
+	splitReports    *splitReports
+	offlinePlans    *storeOfflinePlans
+	epochConflicts  *epochConflicts
+	tombstoneTimes  map[uint64]time.Time
+	opHistory       *ophistory.Store
+	hotHistory      *hothistory.Store
+	topoHistory     *topohistory.Store
+
+	// recoverUnverified is 1 if the cluster is recovered by pd-recover but
+	// not verified yet, when the writes of the stores are rejected.
+	recoverUnverified    uint32
+	storeHeartbeatShards storeHeartbeatShards
 
 	// It's used to manage components.
 	componentManager *component.Manager
@@ -130,6 +156,7 @@ type Status struct {
 	RaftBootstrapTime time.Time `json:"raft_bootstrap_time,omitempty"`
 	IsInitialized     bool      `json:"is_initialized"`
 	ReplicationStatus string    `json:"replication_status"`
+	RecoverUnverified bool      `json:"recover_unverified,omitempty"`
 }
 
 // NewRaftCluster create a new cluster.
@@ -159,10 +186,15 @@ func (c *RaftCluster) LoadClusterStatus() (*Status, error) {
 	if c.replicationMode != nil {
 		replicationStatus = c.replicationMode.GetReplicationStatus().String()
 	}
+	recoverUnverified, err := c.loadRecoverUnverified()
+	if err != nil {
+		return nil, err
+	}
 	return &Status{
 		RaftBootstrapTime: bootstrapTime,
 		IsInitialized:     isInitialized,
 		ReplicationStatus: replicationStatus,
+		RecoverUnverified: recoverUnverified,
 	}, nil
 }
 
@@ -198,6 +230,36 @@ func (c *RaftCluster) loadBootstrapTime() (time.Time, error) {
 	return typeutil.ParseTimestamp([]byte(data))
 }
 
+// loadRecoverUnverified loads if the cluster is recovered by pd-recover but
+// not verified yet. The status is removed by `pd-recover verify`.
+func (c *RaftCluster) loadRecoverUnverified() (bool, error) {
+	data, err := c.storage.Load(c.storage.ClusterStatePath("recover_unverified"))
+	if err != nil {
+		return false, err
+	}
+	return data != "", nil
+}
+
+// CheckRecoverVerified returns an error if the cluster is recovered by
+// pd-recover but not verified yet. The writes of the stores are rejected then,
+// which are the store and region heartbeats, putting the stores, splitting the
+// regions and allocating the IDs.
+func (c *RaftCluster) CheckRecoverVerified() error {
+	if atomic.LoadUint32(&c.recoverUnverified) == 0 {
+		return nil
+	}
+	// The verification may be done while PD is running.
+	unverified, err := c.loadRecoverUnverified()
+	if err != nil {
+		return err
+	}
+	if unverified {
+		return errs.ErrRecoverUnverified.FastGenByArgs()
+	}
+	atomic.StoreUint32(&c.recoverUnverified, 0)
+	return nil
+}
+
 // InitCluster initializes the raft cluster.
 func (c *RaftCluster) InitCluster(id id.Allocator, opt *config.PersistOptions, storage *core.Storage, basicCluster *core.BasicCluster) {
 	c.core = basicCluster
@@ -211,6 +273,11 @@ func (c *RaftCluster) InitCluster(id id.Allocator, opt *config.PersistOptions, s
 	c.suspectRegions = cache.NewIDTTL(c.ctx, time.Minute, 3*time.Minute)
 	c.suspectKeyRanges = cache.NewStringTTL(c.ctx, time.Minute, 3*time.Minute)
 	c.traceRegionFlow = opt.GetPDServerConfig().TraceRegionFlow
+	c.splitReports = newSplitReports(storage)
+	c.offlinePlans = newStoreOfflinePlans(storage)
+	c.epochConflicts = newEpochConflicts()
+	c.tombstoneTimes = make(map[uint64]time.Time)
+	c.hbQueue = fairqueue.NewQueue(heartbeatQueueCapacity(opt.GetRegionHeartbeatFairQueueConcurrency()))
 }
 
 // Start starts a cluster.
@@ -224,6 +291,7 @@ func (c *RaftCluster) Start(s Server) error {
 	}
 
 	c.InitCluster(s.GetAllocator(), s.GetPersistOptions(), s.GetStorage(), s.GetBasicCluster())
+	c.eventBus = s.GetEventBus()
 	cluster, err := c.LoadClusterInfo()
 	if err != nil {
 		return err
@@ -231,6 +299,21 @@ func (c *RaftCluster) Start(s Server) error {
 	if cluster == nil {
 		return nil
 	}
+	if err = c.splitReports.load(time.Now()); err != nil {
+		return err
+	}
+	if err = c.offlinePlans.load(); err != nil {
+		return err
+	}
+	c.applyStoreReservations()
+	recoverUnverified, err := c.loadRecoverUnverified()
+	if err != nil {
+		return err
+	}
+	if recoverUnverified {
+		c.recoverUnverified = 1
+		log.Warn("the cluster is recovered but not verified, the store heartbeats are rejected until `pd-recover verify` succeeds")
+	}
 
 	c.ruleManager = placement.NewRuleManager(c.storage, c)
 	if c.opt.IsPlacementRulesEnabled() {
@@ -252,6 +335,14 @@ func (c *RaftCluster) Start(s Server) error {
 	}
 
 	c.coordinator = newCoordinator(c.ctx, cluster, s.GetHBStreams())
+	c.opHistory = ophistory.NewStore(c.coordinator.ctx, c.storage.GetHotRegionStorage(), ophistory.DefaultTTL)
+	c.coordinator.opController.AddOperatorRecorder(c.opHistory)
+	c.coordinator.opController.AddOperatorRecorder(ophistory.NewPublisher(c.eventBus, cluster))
+	if cfg := s.GetConfig().DecisionExport; cfg.RemoteWriteURL != "" {
+		c.coordinator.opController.AddOperatorRecorder(decisionexport.NewExporter(c.coordinator.ctx, cluster, &cfg))
+	}
+	c.hotHistory = hothistory.NewStore(c.coordinator.ctx, c.storage.GetHotRegionStorage(), c, c.opt)
+	c.topoHistory = topohistory.NewStore(c.coordinator.ctx, c.storage.GetHotRegionStorage(), c, c.opt)
 	c.regionStats = statistics.NewRegionStatistics(c.opt, c.ruleManager)
 	c.limiter = NewStoreLimiter(s.GetPersistOptions())
 	c.quit = make(chan struct{})
@@ -315,6 +406,7 @@ func (c *RaftCluster) runBackgroundJobs(interval time.Duration) {
 	for {
 		select {
 		case <-c.quit:
+			c.splitReports.flush()
 			log.Info("metrics are reset")
 			c.resetMetrics()
 			log.Info("background jobs has been stopped")
@@ -323,6 +415,8 @@ func (c *RaftCluster) runBackgroundJobs(interval time.Duration) {
 			c.checkStores()
 			c.collectMetrics()
 			c.coordinator.opController.PruneHistory()
+			c.splitReports.flush()
+			c.refreshStoreOfflinePlans()
 		}
 	}
 }
@@ -374,6 +468,27 @@ func (c *RaftCluster) IsRunning() bool {
 	return c.running
 }
 
+// GetOperatorHistory returns the history of the operators.
+func (c *RaftCluster) GetOperatorHistory() *ophistory.Store {
+	c.RLock()
+	defer c.RUnlock()
+	return c.opHistory
+}
+
+// GetHotRegionHistory returns the history of the hot regions.
+func (c *RaftCluster) GetHotRegionHistory() *hothistory.Store {
+	c.RLock()
+	defer c.RUnlock()
+	return c.hotHistory
+}
+
+// GetRegionTopologyHistory returns the history of the region topology.
+func (c *RaftCluster) GetRegionTopologyHistory() *topohistory.Store {
+	c.RLock()
+	defer c.RUnlock()
+	return c.topoHistory
+}
+
 // GetOperatorController returns the operator controller.
 func (c *RaftCluster) GetOperatorController() *schedule.OperatorController {
 	c.RLock()
@@ -500,10 +615,20 @@ func (c *RaftCluster) ClearSuspectKeyRanges() {
 
 // HandleStoreHeartbeat updates the store status.
 func (c *RaftCluster) HandleStoreHeartbeat(stats *pdpb.StoreStats) error {
-	c.Lock()
-	defer c.Unlock()
-
+	// The heartbeats of different stores are handled concurrently with the
+	// read lock, while the heartbeats of the same store are serialized by its
+	// shard. The changes of the stores from the other paths take the write
+	// lock, so they are not overwritten by the heartbeats.
+	c.RLock()
+	defer c.RUnlock()
 	storeID := stats.GetStoreId()
+	unlock := c.storeHeartbeatShards.lock(storeID)
+	defer unlock()
+
+	if err := c.CheckRecoverVerified(); err != nil {
+		return err
+	}
+
 	store := c.GetStore(storeID)
 	if store == nil {
 		return errors.Errorf("store %v not found", storeID)
@@ -526,9 +651,7 @@ func (c *RaftCluster) HandleStoreHeartbeat(stats *pdpb.StoreStats) error {
 		c.hotStat.UpdateStoreHeartbeatMetrics(store)
 	}
 	c.core.PutStore(newStore)
-	c.hotStat.Observe(newStore.GetID(), newStore.GetStoreStats())
-	c.hotStat.UpdateTotalLoad(c.core.GetStores())
-	c.hotStat.FilterUnhealthyStore(c)
+	c.hotStat.ObserveStore(newStore)
 
 	// c.limiter is nil before "start" is called
 	if c.limiter != nil && c.opt.GetStoreLimitMode() == "auto" {
@@ -538,24 +661,113 @@ func (c *RaftCluster) HandleStoreHeartbeat(stats *pdpb.StoreStats) error {
 	return nil
 }
 
+// regionHeartbeatUpdate is how a region heartbeat updates the cluster, which
+// is decided with the read lock of the cluster.
+type regionHeartbeatUpdate struct {
+	region                             *core.RegionInfo
+	origin                             *core.RegionInfo
+	skipStats                          bool
+	writeItems, readItems              []*statistics.HotPeerStat
+	saveKV, saveCache, isNew, needSync bool
+}
+
+func (u *regionHeartbeatUpdate) isEmpty() bool {
+	return len(u.writeItems) == 0 && len(u.readItems) == 0 && !u.saveKV && !u.saveCache && !u.isNew
+}
+
 // processRegionHeartbeat updates the region information.
 func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 	c.RLock()
-	origin, err := c.core.PreCheckPutRegion(region)
+	u, err := c.checkRegionHeartbeatLocked(region)
+	c.RUnlock()
+	if err != nil {
+		return err
+	}
+	if u.isEmpty() {
+		return nil
+	}
+
+	failpoint.Inject("concurrentRegionHeartbeat", func() {
+		time.Sleep(500 * time.Millisecond)
+	})
+
+	c.Lock()
+	err = c.applyRegionHeartbeatLocked(u)
+	c.Unlock()
 	if err != nil {
-		c.RUnlock()
 		return err
 	}
-	writeItems := c.CheckWriteStatus(region)
-	readItems := c.CheckReadStatus(region)
+	c.saveRegionHeartbeat(u)
+	return nil
+}
+
+// processRegionHeartbeats is the vectorized processRegionHeartbeat. The
+// regions are checked with one read lock of the cluster and updated with one
+// write lock, instead of locking the cluster twice for each region. The
+// returned errors are in the same order as the regions.
+func (c *RaftCluster) processRegionHeartbeats(regions []*core.RegionInfo) []error {
+	results := make([]error, len(regions))
+	updates := make([]*regionHeartbeatUpdate, len(regions))
+	var changed bool
+	c.RLock()
+	for i, region := range regions {
+		u, err := c.checkRegionHeartbeatLocked(region)
+		if err != nil {
+			results[i] = err
+			continue
+		}
+		if !u.isEmpty() {
+			updates[i], changed = u, true
+		}
+	}
 	c.RUnlock()
+	if !changed {
+		return results
+	}
+
+	c.Lock()
+	for i, u := range updates {
+		if u == nil {
+			continue
+		}
+		if err := c.applyRegionHeartbeatLocked(u); err != nil {
+			results[i], updates[i] = err, nil
+		}
+	}
+	c.Unlock()
+	for _, u := range updates {
+		if u != nil {
+			c.saveRegionHeartbeat(u)
+		}
+	}
+	return results
+}
+
+// checkRegionHeartbeatLocked decides how the heartbeat updates the cluster.
+// It needs the read lock of the cluster.
+func (c *RaftCluster) checkRegionHeartbeatLocked(region *core.RegionInfo) (*regionHeartbeatUpdate, error) {
+	origin, err := c.core.PreCheckPutRegion(region)
+	if err != nil {
+		c.recordEpochConflict(region, err)
+		return nil, err
+	}
+	u := &regionHeartbeatUpdate{region: region, origin: origin}
+	// The statistics are skipped if the latency budget of the heartbeats is used
+	// up. They are updated again by the later heartbeats.
+	u.skipStats = c.isHeartbeatBudgetExhausted()
+	if u.skipStats {
+		regionEventCounter.WithLabelValues("skip_stats").Inc()
+	} else {
+		u.writeItems = c.CheckWriteStatus(region)
+		u.readItems = c.CheckReadStatus(region)
+	}
 
 	// Save to storage if meta is updated.
 	// Save to cache if meta or leader is updated, or contains any down/pending peer.
 	// Mark isNew if the region in cache does not have leader.
 	var saveKV, saveCache, isNew, needSync bool
 	if origin == nil {
-		log.Debug("insert new region",
+		logutil.ModuleDebug(logutil.RegionHeartbeatModule, "insert new region",
 			zap.Uint64("region-id", region.GetID()),
 			logutil.ZapRedactStringer("meta-region", core.RegionToHexMeta(region.GetMeta())))
 		saveKV, saveCache, isNew = true, true, true
@@ -593,11 +805,11 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 			saveCache, needSync = true, true
 		}
 		if !core.SortedPeersStatsEqual(region.GetDownPeers(), origin.GetDownPeers()) {
-			log.Debug("down-peers changed", zap.Uint64("region-id", region.GetID()))
+			logutil.ModuleDebug(logutil.RegionHeartbeatModule, "down-peers changed", zap.Uint64("region-id", region.GetID()))
 			saveCache, needSync = true, true
 		}
 		if !core.SortedPeersEqual(region.GetPendingPeers(), origin.GetPendingPeers()) {
-			log.Debug("pending-peers changed", zap.Uint64("region-id", region.GetID()))
+			logutil.ModuleDebug(logutil.RegionHeartbeatModule, "pending-peers changed", zap.Uint64("region-id", region.GetID()))
 			saveCache, needSync = true, true
 		}
 		if len(region.GetPeers()) != len(origin.GetPeers()) {
@@ -623,22 +835,21 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 		}
 	}
 
-	if len(writeItems) == 0 && len(readItems) == 0 && !saveKV && !saveCache && !isNew {
-		return nil
-	}
-
-	failpoint.Inject("concurrentRegionHeartbeat", func() {
-		time.Sleep(500 * time.Millisecond)
-	})
+	u.saveKV, u.saveCache, u.isNew, u.needSync = saveKV, saveCache, isNew, needSync
+	return u, nil
+}
 
-	c.Lock()
-	if saveCache {
+// applyRegionHeartbeatLocked updates the cache and the statistics of the
+// cluster. It needs the write lock of the cluster.
+func (c *RaftCluster) applyRegionHeartbeatLocked(u *regionHeartbeatUpdate) error {
+	region, origin := u.region, u.origin
+	if u.saveCache {
 		// To prevent a concurrent heartbeat of another region from overriding the up-to-date region info by a stale one,
 		// check its validation again here.
 		//
 		// However it can't solve the race condition of concurrent heartbeats from the same region.
 		if _, err := c.core.PreCheckPutRegion(region); err != nil {
-			c.Unlock()
+			c.recordEpochConflict(region, err)
 			return err
 		}
 		overlaps := c.core.PutRegion(region)
@@ -675,25 +886,30 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 		regionEventCounter.WithLabelValues("update_cache").Inc()
 	}
 
-	if isNew {
+	if u.isNew {
 		c.prepareChecker.collect(region)
 	}
 
-	if c.regionStats != nil {
+	if c.regionStats != nil && !u.skipStats {
 		c.regionStats.Observe(region, c.getRegionStoresLocked(region))
 	}
 
-	for _, writeItem := range writeItems {
+	for _, writeItem := range u.writeItems {
 		c.hotStat.Update(writeItem)
 	}
-	for _, readItem := range readItems {
+	for _, readItem := range u.readItems {
 		c.hotStat.Update(readItem)
 	}
-	c.Unlock()
+	return nil
+}
 
+// saveRegionHeartbeat saves the region to the storage and notifies the region
+// syncer if needed. It is called without the lock of the cluster.
+func (c *RaftCluster) saveRegionHeartbeat(u *regionHeartbeatUpdate) {
+	region := u.region
 	// If there are concurrent heartbeats from the same region, the last write will win even if
 	// writes to storage in the critical area. So don't use mutex to protect it.
-	if saveKV && c.storage != nil {
+	if u.saveKV && c.storage != nil {
 		if err := c.storage.SaveRegion(region.GetMeta()); err != nil {
 			// Not successfully saved to storage is not fatal, it only leads to longer warm-up
 			// after restart. Here we only log the error then go on updating cache.
@@ -704,14 +920,12 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 		}
 		regionEventCounter.WithLabelValues("update_kv").Inc()
 	}
-	if saveKV || needSync {
+	if u.saveKV || u.needSync {
 		select {
 		case c.changedRegions <- region:
 		default:
 		}
 	}
-
-	return nil
 }
 
 func (c *RaftCluster) updateStoreStatusLocked(id uint64) {
@@ -781,6 +995,12 @@ func (c *RaftCluster) GetStoreRegions(storeID uint64) []*core.RegionInfo {
 	return c.core.GetStoreRegions(storeID)
 }
 
+// ScanStoreRegions scans the regions which have peers on the given store in
+// key order, starting from the region that contains startKey.
+func (c *RaftCluster) ScanStoreRegions(storeID uint64, startKey []byte, limit int) []*core.RegionInfo {
+	return c.core.ScanStoreRegions(storeID, startKey, limit)
+}
+
 // RandLeaderRegion returns a random region that has leader on the store.
 func (c *RaftCluster) RandLeaderRegion(storeID uint64, ranges []core.KeyRange, opts ...core.RegionOption) *core.RegionInfo {
 	return c.core.RandLeaderRegion(storeID, ranges, opts...)
@@ -913,8 +1133,76 @@ func (c *RaftCluster) UpdateStoreLabels(storeID uint64, labels []*metapb.StoreLa
 	return c.putStoreImpl(newStore, force)
 }
 
+// UpdateStoresLabels sets and removes the labels of the stores at once. The
+// labels of all stores are checked before any store is updated, so either all
+// of the stores are updated or none of them unless the storage fails in the
+// middle. If 'dryRun' is true, it only returns the stores to be updated.
+func (c *RaftCluster) UpdateStoresLabels(storeIDs []uint64, setLabels []*metapb.StoreLabel, removeKeys []string, dryRun bool) ([]*core.StoreInfo, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	stores := make([]*core.StoreInfo, 0, len(storeIDs))
+	for _, storeID := range storeIDs {
+		store := c.GetStore(storeID)
+		if store == nil {
+			return nil, errors.Errorf("invalid store ID %d, not found", storeID)
+		}
+		store = store.Clone(core.SetStoreLabels(updateLabels(store.GetLabels(), setLabels, removeKeys)))
+		if err := c.checkStoreLabels(store); err != nil {
+			return nil, errors.Errorf("store %d: %s", storeID, err)
+		}
+		stores = append(stores, store)
+	}
+	if dryRun {
+		return stores, nil
+	}
+	for _, store := range stores {
+		if err := c.putStoreLocked(store); err != nil {
+			return nil, err
+		}
+	}
+	return stores, nil
+}
+
+// updateLabels returns a copy of the labels with the keys removed and the new
+// labels set. The keys are case insensitive. Unlike MergeLabels, it does not
+// modify the given labels.
+func updateLabels(labels, setLabels []*metapb.StoreLabel, removeKeys []string) []*metapb.StoreLabel {
+	res := make([]*metapb.StoreLabel, 0, len(labels)+len(setLabels))
+L:
+	for _, label := range labels {
+		for _, key := range removeKeys {
+			if strings.EqualFold(label.GetKey(), key) {
+				continue L
+			}
+		}
+		res = append(res, &metapb.StoreLabel{Key: label.GetKey(), Value: label.GetValue()})
+	}
+S:
+	for _, newLabel := range setLabels {
+		for _, label := range res {
+			if strings.EqualFold(label.GetKey(), newLabel.GetKey()) {
+				label.Value = newLabel.GetValue()
+				continue S
+			}
+		}
+		res = append(res, &metapb.StoreLabel{Key: newLabel.GetKey(), Value: newLabel.GetValue()})
+	}
+	// Like MergeLabels, setting an empty value removes the label.
+	labels = res[:0]
+	for _, label := range res {
+		if label.GetValue() != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
 // PutStore puts a store.
 func (c *RaftCluster) PutStore(store *metapb.Store) error {
+	if err := c.CheckRecoverVerified(); err != nil {
+		return err
+	}
 	if err := c.putStoreImpl(store, false); err != nil {
 		return err
 	}
@@ -1019,6 +1307,13 @@ func (c *RaftCluster) checkStoreLabels(s *core.StoreInfo) error {
 // RemoveStore marks a store as offline in cluster.
 // State transition: Up -> Offline.
 func (c *RaftCluster) RemoveStore(storeID uint64, physicallyDestroyed bool) error {
+	// Reserve the capacity on the remaining stores first, so the offline will
+	// not get stuck halfway for lack of space. The plan is computed before
+	// taking the lock of the cluster, since fitting the regions takes time.
+	c.offlinePlans.planMu.Lock()
+	defer c.offlinePlans.planMu.Unlock()
+	plan, unplacedSize := c.planStoreOffline(storeID)
+
 	c.Lock()
 	defer c.Unlock()
 
@@ -1040,6 +1335,11 @@ func (c *RaftCluster) RemoveStore(storeID uint64, physicallyDestroyed bool) erro
 		return errs.ErrStoreDestroyed.FastGenByArgs(storeID)
 	}
 
+	// A physically destroyed store has to be removed anyway.
+	if plan.UnplacedRegionCount > 0 && !physicallyDestroyed {
+		return errs.ErrStoreOfflineNoSpace.FastGenByArgs(storeID, plan.UnplacedRegionCount, unplacedSize)
+	}
+
 	newStore := store.Clone(core.OfflineStore(physicallyDestroyed))
 	log.Warn("store has been offline",
 		zap.Uint64("store-id", newStore.GetID()),
@@ -1047,6 +1347,10 @@ func (c *RaftCluster) RemoveStore(storeID uint64, physicallyDestroyed bool) erro
 		zap.Bool("physically-destroyed", newStore.IsPhysicallyDestroyed()))
 	err := c.putStoreLocked(newStore)
 	if err == nil {
+		if err := c.offlinePlans.put(plan); err != nil {
+			log.Warn("failed to save the store offline plan", zap.Uint64("store-id", storeID), errs.ZapError(err))
+		}
+		c.applyStoreReservations()
 		// TODO: if the persist operation encounters error, the "Unlimited" will be rollback.
 		// And considering the store state has changed, RemoveStore is actually successful.
 		_ = c.SetStoreLimit(storeID, storelimit.RemovePeer, storelimit.Unlimited)
@@ -1084,7 +1388,10 @@ func (c *RaftCluster) buryStore(storeID uint64) error {
 	err := c.putStoreLocked(newStore)
 	c.onStoreVersionChangeLocked()
 	if err == nil {
+		c.offlinePlans.delete(storeID)
+		c.applyStoreReservations()
 		c.RemoveStoreLimit(storeID)
+		c.tombstoneTimes[storeID] = time.Now()
 	}
 	return err
 }
@@ -1131,7 +1438,180 @@ func (c *RaftCluster) UpStore(storeID uint64) error {
 	log.Warn("store has been up",
 		zap.Uint64("store-id", storeID),
 		zap.String("store-address", newStore.GetAddress()))
-	return c.putStoreLocked(newStore)
+	if err := c.putStoreLocked(newStore); err != nil {
+		return err
+	}
+	c.offlinePlans.delete(storeID)
+	c.applyStoreReservations()
+	return nil
+}
+
+// DrainStore starts to drain a store. The leaders of a draining store are
+// moved away and no new leader or peer is placed on it, while the state of the
+// store is kept Up. Draining a draining store should be OK, nothing to do.
+func (c *RaftCluster) DrainStore(storeID uint64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.GetStore(storeID)
+	if store == nil {
+		return errs.ErrStoreNotFound.FastGenByArgs(storeID)
+	}
+	if store.IsTombstone() {
+		return errs.ErrStoreTombstone.FastGenByArgs(storeID)
+	}
+	if !store.IsUp() {
+		return errs.ErrStoreNotUp.FastGenByArgs(storeID)
+	}
+	if store.IsDraining() {
+		return nil
+	}
+
+	drain := &core.StoreDrain{
+		StartTime:        time.Now(),
+		StartLeaderCount: store.GetLeaderCount(),
+	}
+	if c.storage != nil {
+		if err := c.storage.SaveStoreDrain(storeID, drain); err != nil {
+			return err
+		}
+	}
+	log.Warn("store starts draining",
+		zap.Uint64("store-id", storeID),
+		zap.String("store-address", store.GetAddress()),
+		zap.Int("leader-count", drain.StartLeaderCount))
+	return c.putStoreLocked(store.Clone(core.SetStoreDrain(drain)))
+}
+
+// CancelStoreDrain stops draining a store. Cancelling the drain of a store
+// which is not draining should be OK, nothing to do.
+func (c *RaftCluster) CancelStoreDrain(storeID uint64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.GetStore(storeID)
+	if store == nil {
+		return errs.ErrStoreNotFound.FastGenByArgs(storeID)
+	}
+	if !store.IsDraining() {
+		return nil
+	}
+
+	if c.storage != nil {
+		if err := c.storage.DeleteStoreDrain(storeID); err != nil {
+			return err
+		}
+	}
+	log.Warn("store stops draining",
+		zap.Uint64("store-id", storeID),
+		zap.String("store-address", store.GetAddress()))
+	return c.putStoreLocked(store.Clone(core.SetStoreDrain(nil)))
+}
+
+// SetStoreMaintenance declares a maintenance window of a store. It replaces
+// the window declared before, if any.
+func (c *RaftCluster) SetStoreMaintenance(storeID uint64, maintenance *core.StoreMaintenance) error {
+	return c.SetStoresMaintenance([]uint64{storeID}, maintenance)
+}
+
+// SetStoresMaintenance declares a maintenance window of a group of stores, for
+// example all stores of a zone. It replaces the windows declared before, if
+// any. The windows are rejected as a whole if they would take more failure
+// domains than the maintenance budget at the same time.
+func (c *RaftCluster) SetStoresMaintenance(storeIDs []uint64, maintenance *core.StoreMaintenance) error {
+	if len(storeIDs) == 0 {
+		return errs.ErrStoreMaintenance.FastGenByArgs(storeIDs, "no store is given")
+	}
+	if !maintenance.EndTime.After(maintenance.StartTime) {
+		return errs.ErrStoreMaintenance.FastGenByArgs(storeIDs, "the end time must be after the start time")
+	}
+	if maintenance.IsExpired(time.Now()) {
+		return errs.ErrStoreMaintenance.FastGenByArgs(storeIDs, "the window has already ended")
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	stores := make([]*core.StoreInfo, 0, len(storeIDs))
+	for _, storeID := range storeIDs {
+		store := c.GetStore(storeID)
+		if store == nil {
+			return errs.ErrStoreNotFound.FastGenByArgs(storeID)
+		}
+		if store.IsTombstone() {
+			return errs.ErrStoreTombstone.FastGenByArgs(storeID)
+		}
+		stores = append(stores, store)
+	}
+	if err := c.checkMaintenanceBudgetLocked(stores, maintenance); err != nil {
+		return err
+	}
+
+	// The windows of the group are saved at once, so that a failure does not
+	// leave a part of the group in maintenance.
+	if c.storage != nil {
+		if err := c.storage.SaveStoresMaintenance(storeIDs, maintenance); err != nil {
+			return err
+		}
+	}
+	for _, store := range stores {
+		log.Warn("store maintenance window is set",
+			zap.Uint64("store-id", store.GetID()),
+			zap.String("store-address", store.GetAddress()),
+			zap.Time("start-time", maintenance.StartTime),
+			zap.Time("end-time", maintenance.EndTime),
+			zap.String("reason", maintenance.Reason))
+		if err := c.putStoreLocked(store.Clone(core.SetStoreMaintenance(maintenance))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelStoreMaintenance removes the maintenance window of a store. Cancelling
+// the maintenance of a store which has no window should be OK, nothing to do.
+func (c *RaftCluster) CancelStoreMaintenance(storeID uint64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.GetStore(storeID)
+	if store == nil {
+		return errs.ErrStoreNotFound.FastGenByArgs(storeID)
+	}
+	return c.cancelStoreMaintenanceLocked(store)
+}
+
+func (c *RaftCluster) cancelStoreMaintenanceLocked(store *core.StoreInfo) error {
+	if store.GetMaintenance() == nil {
+		return nil
+	}
+	if c.storage != nil {
+		if err := c.storage.DeleteStoreMaintenance(store.GetID()); err != nil {
+			return err
+		}
+	}
+	log.Warn("store maintenance window is removed",
+		zap.Uint64("store-id", store.GetID()),
+		zap.String("store-address", store.GetAddress()))
+	return c.putStoreLocked(store.Clone(core.SetStoreMaintenance(nil)))
+}
+
+// expireStoreMaintenances removes the maintenance windows which have ended.
+func (c *RaftCluster) expireStoreMaintenances() {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	for _, store := range c.GetStores() {
+		if !store.GetMaintenance().IsExpired(now) {
+			continue
+		}
+		if err := c.cancelStoreMaintenanceLocked(store); err != nil {
+			log.Error("failed to remove the expired store maintenance window",
+				zap.Uint64("store-id", store.GetID()),
+				errs.ZapError(err))
+		}
+	}
 }
 
 // SetStoreWeight sets up a store's leader/region balance weight.
@@ -1164,10 +1644,17 @@ func (c *RaftCluster) putStoreLocked(store *core.StoreInfo) error {
 	}
 	c.core.PutStore(store)
 	c.hotStat.GetOrCreateRollingStoreStats(store.GetID())
+	c.eventBus.Publish(eventbus.TopicStore, &eventbus.StoreEvent{StoreID: store.GetID()})
 	return nil
 }
 
 func (c *RaftCluster) checkStores() {
+	c.expireStoreMaintenances()
+	c.archiveTombstoneStores(time.Now())
+	// The statistics of the stores which stop sending heartbeats are removed
+	// here, as the heartbeat only checks the store itself.
+	c.hotStat.FilterUnhealthyStore(c)
+
 	var offlineStores []*metapb.Store
 	var upStoreCount int
 	stores := c.GetStores()
@@ -1230,6 +1717,7 @@ func (c *RaftCluster) RemoveTombStoneRecords() error {
 				return err
 			}
 			c.RemoveStoreLimit(store.GetID())
+			delete(c.tombstoneTimes, store.GetID())
 			log.Info("delete store succeeded",
 				zap.Stringer("store", store.GetMeta()))
 		}
@@ -1245,6 +1733,7 @@ func (c *RaftCluster) deleteStoreLocked(store *core.StoreInfo) error {
 	}
 	c.core.DeleteStore(store)
 	c.hotStat.RemoveRollingStoreStats(store.GetID())
+	c.eventBus.Publish(eventbus.TopicStore, &eventbus.StoreEvent{StoreID: store.GetID()})
 	return nil
 }
 
@@ -1325,6 +1814,16 @@ func (c *RaftCluster) GetRegionStatsByType(typ statistics.RegionStatisticType) [
 	return c.regionStats.GetRegionStatsByType(typ)
 }
 
+// GetPendingPeerStartTime returns since when the region has pending peers.
+func (c *RaftCluster) GetPendingPeerStartTime(regionID uint64) time.Time {
+	c.RLock()
+	defer c.RUnlock()
+	if c.regionStats == nil {
+		return time.Time{}
+	}
+	return c.regionStats.GetPendingPeerStartTime(regionID)
+}
+
 // GetOfflineRegionStatsByType gets the status of the offline region by types.
 func (c *RaftCluster) GetOfflineRegionStatsByType(typ statistics.RegionStatisticType) []*core.RegionInfo {
 	c.RLock()
@@ -1455,9 +1954,8 @@ func (c *RaftCluster) isPrepared() bool {
 }
 
 // GetStoresLoads returns load stats of all stores.
+// It reads the snapshot of the loads without the lock of the cluster.
 func (c *RaftCluster) GetStoresLoads() map[uint64][]float64 {
-	c.RLock()
-	defer c.RUnlock()
 	return c.hotStat.GetStoresLoads()
 }
 
@@ -1593,6 +2091,24 @@ func (c *RaftCluster) AddScheduler(scheduler schedule.Scheduler, args ...string)
 	return c.coordinator.addScheduler(scheduler, args...)
 }
 
+// DryRunScheduler runs a scheduler once and returns the operators it generates
+// without adding them, and whether the scheduler is allowed to schedule now.
+func (c *RaftCluster) DryRunScheduler(name string, args ...string) ([]*operator.Operator, bool, error) {
+	c.RLock()
+	co := c.coordinator
+	c.RUnlock()
+	return co.dryRunScheduler(name, args...)
+}
+
+// EstimateOperatorRates estimates the operators generated by the schedulers
+// and the checkers in the next hour.
+func (c *RaftCluster) EstimateOperatorRates() (*OperatorRateEstimates, error) {
+	c.RLock()
+	co := c.coordinator
+	c.RUnlock()
+	return co.estimateOperatorRates()
+}
+
 // RemoveScheduler removes a scheduler.
 func (c *RaftCluster) RemoveScheduler(name string) error {
 	c.Lock()
@@ -1723,8 +2239,8 @@ func (c *RaftCluster) SetAllStoresLimit(typ storelimit.Type, ratePerMin float64)
 }
 
 // SetAllStoresLimitTTL sets all store limit for a given type and rate with ttl.
-func (c *RaftCluster) SetAllStoresLimitTTL(typ storelimit.Type, ratePerMin float64, ttl time.Duration) {
-	c.opt.SetAllStoresLimitTTL(c.ctx, c.etcdClient, typ, ratePerMin, ttl)
+func (c *RaftCluster) SetAllStoresLimitTTL(typ storelimit.Type, ratePerMin float64, ttl time.Duration, origin string) {
+	c.opt.SetAllStoresLimitTTL(c.ctx, c.etcdClient, typ, ratePerMin, ttl, origin)
 }
 
 // GetClusterVersion returns the current cluster version.
@@ -15,9 +15,12 @@ package cluster
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,12 +29,17 @@ import (
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/codec"
 	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/eventbus"
+	"github.com/tikv/pd/pkg/keyspace"
 	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/id"
 	"github.com/tikv/pd/server/kv"
+	"github.com/tikv/pd/server/schedule/hbstream"
 	"github.com/tikv/pd/server/schedule/opt"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/statistics"
@@ -95,6 +103,40 @@ func (s *testClusterInfoSuite) TestStoreHeartbeat(c *C) {
 	}
 }
 
+func (s *testClusterInfoSuite) TestConcurrentStoreHeartbeat(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	stores := newTestStores(100, "2.0.0")
+	for _, store := range stores {
+		c.Assert(cluster.putStoreLocked(store), IsNil)
+	}
+
+	// The heartbeats of different stores run concurrently with the changes
+	// of the stores, which should not be lost.
+	var wg sync.WaitGroup
+	for _, store := range stores {
+		wg.Add(1)
+		go func(storeID uint64) {
+			defer wg.Done()
+			for i := uint64(1); i <= 10; i++ {
+				c.Assert(cluster.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: storeID, Capacity: 100, Available: i}), IsNil)
+			}
+		}(store.GetID())
+		c.Assert(cluster.SetStoreWeight(store.GetID(), 2, 3), IsNil)
+	}
+	wg.Wait()
+
+	loads := cluster.GetStoresLoads()
+	c.Assert(loads, HasLen, len(stores))
+	for _, store := range stores {
+		store = cluster.GetStore(store.GetID())
+		c.Assert(store.GetAvailable(), Equals, uint64(10))
+		c.Assert(store.GetLeaderWeight(), Equals, float64(2))
+		c.Assert(store.GetRegionWeight(), Equals, float64(3))
+	}
+}
+
 func (s *testClusterInfoSuite) TestFilterUnhealthyStore(c *C) {
 	_, opt, err := newTestScheduleConfig()
 	c.Assert(err, IsNil)
@@ -173,6 +215,310 @@ func (s *testClusterInfoSuite) TestSetOfflineStore(c *C) {
 	}
 }
 
+func (s *testClusterInfoSuite) TestArchiveTombstoneStores(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	for _, store := range newTestStores(3, "2.0.0") {
+		c.Assert(cluster.PutStore(store.GetMeta()), IsNil)
+	}
+	c.Assert(cluster.RemoveStore(1, false), IsNil)
+	c.Assert(cluster.buryStore(1), IsNil)
+	now := time.Now()
+
+	// Nothing is archived if the archival is disabled.
+	cluster.archiveTombstoneStores(now.Add(time.Hour))
+	c.Assert(cluster.GetStore(1), NotNil)
+
+	cfg := opt.GetPDServerConfig().Clone()
+	cfg.TombstoneStoreRetention = typeutil.NewDuration(time.Hour)
+	opt.SetPDServerConfig(cfg)
+	cluster.archiveTombstoneStores(now.Add(time.Minute))
+	c.Assert(cluster.GetStore(1), NotNil)
+	cluster.archiveTombstoneStores(now.Add(2 * time.Hour))
+	c.Assert(cluster.GetStore(1), IsNil)
+	c.Assert(cluster.GetStores(), HasLen, 2)
+	var ok bool
+	ok, err = cluster.storage.LoadStore(1, &metapb.Store{})
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsFalse)
+	archived, err := cluster.GetArchivedStores()
+	c.Assert(err, IsNil)
+	c.Assert(archived, HasLen, 1)
+	c.Assert(archived[0].Store.GetId(), Equals, uint64(1))
+	c.Assert(archived[0].Store.GetState(), Equals, metapb.StoreState_Tombstone)
+
+	// The tombstone stores found by a new leader restart the retention.
+	newCluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, cluster.storage, core.NewBasicCluster())
+	c.Assert(cluster.storage.LoadStores(newCluster.core.PutStore), IsNil)
+	c.Assert(newCluster.RemoveStore(2, false), IsNil)
+	c.Assert(newCluster.buryStore(2), IsNil)
+	newCluster.tombstoneTimes = make(map[uint64]time.Time)
+	newCluster.archiveTombstoneStores(now.Add(2 * time.Hour))
+	c.Assert(newCluster.GetStore(2), NotNil)
+	newCluster.archiveTombstoneStores(now.Add(4 * time.Hour))
+	c.Assert(newCluster.GetStore(2), IsNil)
+
+	// Restore the archived store.
+	c.Assert(newCluster.RestoreArchivedStore(3), NotNil)
+	c.Assert(newCluster.RestoreArchivedStore(4), NotNil)
+	c.Assert(newCluster.RestoreArchivedStore(1), IsNil)
+	c.Assert(newCluster.GetStore(1).IsTombstone(), IsTrue)
+	c.Assert(newCluster.RestoreArchivedStore(1), NotNil)
+	archived, err = newCluster.GetArchivedStores()
+	c.Assert(err, IsNil)
+	c.Assert(archived, HasLen, 1)
+	c.Assert(archived[0].Store.GetId(), Equals, uint64(2))
+}
+
+func (s *testClusterInfoSuite) TestStoreOfflinePlan(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestCluster(opt)
+	for _, store := range newTestStores(4, "2.0.0") {
+		store = store.Clone(core.SetStoreStats(&pdpb.StoreStats{
+			StoreId:   store.GetID(),
+			Capacity:  100 * (1 << 30),
+			Available: 30 * (1 << 30),
+		}))
+		c.Assert(cluster.putStoreLocked(store), IsNil)
+	}
+	// Each store keeps 10GB before it becomes low space.
+	for i := uint64(1); i <= 3; i++ {
+		peers := []*metapb.Peer{{Id: i * 10, StoreId: 1}, {Id: i*10 + 1, StoreId: 2}}
+		meta := &metapb.Region{Id: i, Peers: peers, StartKey: []byte{byte(i)}, EndKey: []byte{byte(i + 1)}}
+		c.Assert(cluster.putRegion(core.NewRegionInfo(meta, peers[0], core.SetApproximateSize(5000))), IsNil)
+	}
+
+	// The regions of store 1 are placed on store 3 and 4.
+	c.Assert(cluster.RemoveStore(1, false), IsNil)
+	plan := cluster.GetStoreOfflinePlan(1)
+	c.Assert(plan, NotNil)
+	c.Assert(plan.RegionCount, Equals, 3)
+	c.Assert(plan.Size, Equals, int64(15000))
+	c.Assert(plan.UnplacedRegionCount, Equals, 0)
+	c.Assert(plan.Reservations, HasLen, 2)
+	c.Assert(plan.Reservations[3]+plan.Reservations[4], Equals, int64(15000))
+	// The reservations are applied to the stores and persisted.
+	c.Assert(cluster.GetStore(3).GetReservedSize(), Equals, plan.Reservations[3])
+	c.Assert(cluster.GetStore(4).GetReservedSize(), Equals, plan.Reservations[4])
+	loaded := newStoreOfflinePlans(cluster.storage)
+	c.Assert(loaded.load(), IsNil)
+	c.Assert(loaded.get(1).Reservations, DeepEquals, plan.Reservations)
+
+	// The capacity reserved for store 1 cannot be used to take store 2 offline.
+	err = cluster.RemoveStore(2, false)
+	c.Assert(errs.ErrStoreOfflineNoSpace.Equal(err), IsTrue)
+	c.Assert(cluster.GetStore(2).IsUp(), IsTrue)
+	c.Assert(cluster.GetStoreOfflinePlan(2), IsNil)
+	// The physically destroyed store is removed anyway.
+	c.Assert(cluster.RemoveStore(2, true), IsNil)
+	c.Assert(cluster.GetStoreOfflinePlan(2).UnplacedRegionCount, Equals, 2)
+
+	// The reservation shrinks as the regions are moved away.
+	peers := []*metapb.Peer{{Id: 12, StoreId: 3}, {Id: 13, StoreId: 2}}
+	c.Assert(cluster.putRegion(core.NewRegionInfo(&metapb.Region{Id: 1, Peers: peers, StartKey: []byte{1}, EndKey: []byte{2}}, peers[0], core.SetApproximateSize(5000))), IsNil)
+	cluster.refreshStoreOfflinePlans()
+	plan = cluster.GetStoreOfflinePlan(1)
+	c.Assert(plan.RegionCount, Equals, 2)
+	c.Assert(plan.Reservations[3]+plan.Reservations[4], Equals, int64(10000))
+
+	// The reservation is released when the store is up again.
+	c.Assert(cluster.UpStore(1), IsNil)
+	c.Assert(cluster.GetStoreOfflinePlan(1), IsNil)
+	plan = cluster.GetStoreOfflinePlan(2)
+	c.Assert(cluster.GetStore(3).GetReservedSize(), Equals, plan.Reservations[3])
+	c.Assert(cluster.GetStore(4).GetReservedSize(), Equals, plan.Reservations[4])
+	loaded = newStoreOfflinePlans(cluster.storage)
+	c.Assert(loaded.load(), IsNil)
+	c.Assert(loaded.get(1), IsNil)
+}
+
+func (s *testClusterInfoSuite) TestCheckTopology(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cfg := opt.GetReplicationConfig().Clone()
+	cfg.LocationLabels = []string{"zone", "host"}
+	cfg.IsolationLevel = "zone"
+	cfg.EnablePlacementRules = false
+	opt.SetReplicationConfig(cfg)
+	cluster := newTestCluster(opt)
+	for id, labels := range map[uint64]map[string]string{
+		1: {"zone": "z1", "host": "h1"},
+		2: {"zone": "z1", "host": "h2"},
+		3: {"zone": "z2", "host": "h3"},
+		4: {"zone": "z1"},
+	} {
+		c.Assert(cluster.putStoreLocked(core.NewStoreInfoWithLabel(id, 0, labels)), IsNil)
+	}
+
+	// Only two zones for 3 replicas while the isolation level is zone.
+	report := cluster.CheckTopology()
+	c.Assert(report.Valid, IsFalse)
+	c.Assert(report.Findings, HasLen, 2)
+	c.Assert(report.Findings[0].Kind, Equals, TopologyMissingLocationLabel)
+	c.Assert(report.Findings[0].Label, Equals, "host")
+	c.Assert(report.Findings[0].StoreIDs, DeepEquals, []uint64{4})
+	c.Assert(report.Findings[1].Kind, Equals, TopologyIsolationUnachievable)
+	c.Assert(report.Findings[1].Label, Equals, "zone")
+	c.Assert(report.Findings[1].Required, Equals, 3)
+	c.Assert(report.Findings[1].Actual, Equals, 2)
+
+	// Without the isolation level the peers are isolated by host.
+	opt.SetPlacementRuleEnabled(true)
+	c.Assert(cluster.ruleManager.Initialize(3, []string{"zone", "host"}), IsNil)
+	report = cluster.CheckTopology()
+	c.Assert(report.Valid, IsTrue)
+	c.Assert(report.Findings, HasLen, 2)
+	c.Assert(report.Findings[1].Kind, Equals, TopologyInsufficientDomains)
+	c.Assert(report.Findings[1].RuleGroup, Equals, "pd")
+	c.Assert(report.Findings[1].RuleID, Equals, "default")
+
+	// Only one store can hold the two TiFlash learners.
+	c.Assert(cluster.putStoreLocked(core.NewStoreInfoWithLabel(5, 0, map[string]string{"engine": "tiflash"})), IsNil)
+	c.Assert(cluster.ruleManager.SetRule(&placement.Rule{
+		GroupID:          "tiflash",
+		ID:               "learner",
+		Role:             placement.Learner,
+		Count:            2,
+		LabelConstraints: []placement.LabelConstraint{{Key: "engine", Op: placement.In, Values: []string{"tiflash"}}},
+	}), IsNil)
+	report = cluster.CheckTopology()
+	c.Assert(report.Valid, IsFalse)
+	c.Assert(report.Findings, HasLen, 3)
+	c.Assert(report.Findings[2].Kind, Equals, TopologyInsufficientStores)
+	c.Assert(report.Findings[2].RuleGroup, Equals, "tiflash")
+	c.Assert(report.Findings[2].Actual, Equals, 1)
+	c.Assert(report.Findings[2].StoreIDs, DeepEquals, []uint64{5})
+}
+
+func (s *testClusterInfoSuite) TestStoreMaintenance(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestCluster(opt)
+	for _, store := range newTestStores(2, "2.0.0") {
+		c.Assert(cluster.putStoreLocked(store), IsNil)
+	}
+
+	now := time.Now()
+	c.Assert(cluster.SetStoreMaintenance(1, &core.StoreMaintenance{StartTime: now, EndTime: now}), NotNil)
+	c.Assert(cluster.SetStoreMaintenance(1, &core.StoreMaintenance{StartTime: now.Add(-time.Hour), EndTime: now.Add(-time.Minute)}), NotNil)
+	c.Assert(cluster.SetStoreMaintenance(3, &core.StoreMaintenance{StartTime: now, EndTime: now.Add(time.Hour)}), NotNil)
+
+	maintenance := &core.StoreMaintenance{StartTime: now, EndTime: now.Add(time.Hour), Reason: "upgrade"}
+	c.Assert(cluster.SetStoreMaintenance(1, maintenance), IsNil)
+	store := cluster.GetStore(1)
+	c.Assert(store.IsInMaintenance(), IsTrue)
+	c.Assert(store.LeaderScore(core.ByCount, 10) > cluster.GetStore(2).LeaderScore(core.ByCount, 10), IsTrue)
+
+	// The window survives reloading the stores from storage.
+	basicCluster := core.NewBasicCluster()
+	c.Assert(cluster.storage.LoadStores(basicCluster.PutStore), IsNil)
+	c.Assert(basicCluster.GetStore(1).GetMaintenance().Reason, Equals, "upgrade")
+	c.Assert(basicCluster.GetStore(1).IsInMaintenance(), IsTrue)
+
+	c.Assert(cluster.CancelStoreMaintenance(1), IsNil)
+	c.Assert(cluster.CancelStoreMaintenance(1), IsNil)
+	c.Assert(cluster.GetStore(1).GetMaintenance(), IsNil)
+
+	// An ended window is removed by the store check.
+	c.Assert(cluster.SetStoreMaintenance(2, &core.StoreMaintenance{StartTime: now, EndTime: time.Now().Add(100 * time.Millisecond)}), IsNil)
+	time.Sleep(200 * time.Millisecond)
+	cluster.checkStores()
+	c.Assert(cluster.GetStore(2).GetMaintenance(), IsNil)
+	basicCluster = core.NewBasicCluster()
+	c.Assert(cluster.storage.LoadStores(basicCluster.PutStore), IsNil)
+	c.Assert(basicCluster.GetStore(2).GetMaintenance(), IsNil)
+}
+
+func (s *testClusterInfoSuite) TestRecoverUnverified(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestCluster(opt)
+	store := newTestStores(1, "2.0.0")[0]
+	c.Assert(cluster.putStoreLocked(store), IsNil)
+
+	// pd-recover saves the status, which is loaded when the cluster starts.
+	key := cluster.storage.ClusterStatePath("recover_unverified")
+	c.Assert(cluster.storage.Save(key, "1"), IsNil)
+	cluster.recoverUnverified = 1
+	status, err := cluster.LoadClusterStatus()
+	c.Assert(err, IsNil)
+	c.Assert(status.RecoverUnverified, IsTrue)
+	err = cluster.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: store.GetID()})
+	c.Assert(errs.ErrRecoverUnverified.Equal(errors.Cause(err)), IsTrue)
+	region := newTestRegions(1, 1)[0]
+	err = cluster.HandleRegionHeartbeat(region)
+	c.Assert(errs.ErrRecoverUnverified.Equal(errors.Cause(err)), IsTrue)
+	for _, err := range cluster.HandleRegionHeartbeats([]*core.RegionInfo{region}) {
+		c.Assert(errs.ErrRecoverUnverified.Equal(errors.Cause(err)), IsTrue)
+	}
+	err = cluster.PutStore(store.GetMeta())
+	c.Assert(errs.ErrRecoverUnverified.Equal(errors.Cause(err)), IsTrue)
+	_, err = cluster.HandleAskSplit(&pdpb.AskSplitRequest{Region: region.GetMeta()})
+	c.Assert(errs.ErrRecoverUnverified.Equal(errors.Cause(err)), IsTrue)
+	c.Assert(cluster.GetRegion(region.GetID()), IsNil)
+
+	// `pd-recover verify` removes the status.
+	c.Assert(cluster.storage.Remove(key), IsNil)
+	c.Assert(cluster.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: store.GetID()}), IsNil)
+	c.Assert(cluster.recoverUnverified, Equals, uint32(0))
+	status, err = cluster.LoadClusterStatus()
+	c.Assert(err, IsNil)
+	c.Assert(status.RecoverUnverified, IsFalse)
+}
+
+func (s *testClusterInfoSuite) TestMaintenanceBudget(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cfg := opt.GetReplicationConfig().Clone()
+	cfg.LocationLabels = []string{"zone", "host"}
+	opt.SetReplicationConfig(cfg)
+	cluster := newTestCluster(opt)
+	for i, store := range newTestStores(4, "2.0.0") {
+		zone := fmt.Sprintf("z%d", i%3+1)
+		store = store.Clone(core.SetStoreLabels([]*metapb.StoreLabel{{Key: "zone", Value: zone}}))
+		c.Assert(cluster.putStoreLocked(store), IsNil)
+	}
+
+	// With 3 replicas, only 1 zone can be in maintenance at the same time.
+	now := time.Now()
+	window := &core.StoreMaintenance{StartTime: now, EndTime: now.Add(time.Hour)}
+	// The windows of the group are saved at once, or not at all.
+	storage := cluster.storage
+	cluster.storage = core.NewStorage(&testSaveBatchErrorKV{Base: kv.NewMemoryKV()})
+	c.Assert(cluster.SetStoresMaintenance([]uint64{1, 4}, window), NotNil)
+	c.Assert(cluster.GetStore(1).GetMaintenance(), IsNil)
+	c.Assert(cluster.GetStore(4).GetMaintenance(), IsNil)
+	cluster.storage = storage
+	c.Assert(cluster.SetStoresMaintenance([]uint64{1, 4}, window), IsNil)
+	basicCluster := core.NewBasicCluster()
+	c.Assert(cluster.storage.LoadStores(basicCluster.PutStore), IsNil)
+	c.Assert(basicCluster.GetStore(1).IsInMaintenance(), IsTrue)
+	c.Assert(basicCluster.GetStore(4).IsInMaintenance(), IsTrue)
+	err = cluster.SetStoreMaintenance(2, window)
+	c.Assert(errs.ErrMaintenanceBudgetExceeded.Equal(errors.Cause(err)), IsTrue)
+	c.Assert(cluster.GetStore(2).GetMaintenance(), IsNil)
+	// The windows in the same zone, or not overlapped, are OK.
+	c.Assert(cluster.SetStoreMaintenance(4, &core.StoreMaintenance{StartTime: now, EndTime: now.Add(2 * time.Hour)}), IsNil)
+	c.Assert(cluster.SetStoreMaintenance(2, &core.StoreMaintenance{StartTime: now.Add(2 * time.Hour), EndTime: now.Add(3 * time.Hour)}), IsNil)
+
+	budget := cluster.GetMaintenanceBudget()
+	c.Assert(budget.DomainLabel, Equals, "zone")
+	c.Assert(budget.Budget, Equals, 1)
+	c.Assert(budget.Domains, HasLen, 2)
+	c.Assert(budget.Domains[0].Name, Equals, "zone=z1")
+	c.Assert(budget.Domains[0].StoreIDs, DeepEquals, []uint64{1, 4})
+	c.Assert(budget.Domains[0].EndTime.Equal(now.Add(2*time.Hour)), IsTrue)
+	c.Assert(budget.Domains[1].Name, Equals, "zone=z2")
+
+	// 5 replicas allow 2 zones.
+	cfg = opt.GetReplicationConfig().Clone()
+	cfg.MaxReplicas = 5
+	opt.SetReplicationConfig(cfg)
+	c.Assert(cluster.SetStoreMaintenance(3, window), IsNil)
+}
+
 func (s *testClusterInfoSuite) TestReuseAddress(c *C) {
 	_, opt, err := newTestScheduleConfig()
 	c.Assert(err, IsNil)
@@ -244,6 +590,89 @@ func (s *testClusterInfoSuite) TestUpStore(c *C) {
 	c.Assert(errors.ErrorEqual(err, errs.ErrStoreNotFound.FastGenByArgs(4)), IsTrue)
 }
 
+func (s *testClusterInfoSuite) TestStoreEvents(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	cluster.eventBus = eventbus.NewBus()
+	sub := cluster.eventBus.Subscribe("test", 8, eventbus.TopicStore)
+	defer sub.Close()
+
+	for _, store := range newTestStores(2, "2.0.0") {
+		c.Assert(cluster.PutStore(store.GetMeta()), IsNil)
+	}
+	c.Assert(cluster.RemoveStore(2, false), IsNil)
+	// The heartbeat does not change the meta of the store.
+	c.Assert(cluster.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: 1}), IsNil)
+	c.Assert(cluster.deleteStoreLocked(cluster.GetStore(2)), IsNil)
+	c.Assert(sub.Events(), HasLen, 4)
+	for _, id := range []uint64{1, 2, 2, 2} {
+		c.Assert((<-sub.Events()).Payload, DeepEquals, &eventbus.StoreEvent{StoreID: id})
+	}
+}
+
+func (s *testClusterInfoSuite) TestFilterMetaStores(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	cluster.ruleManager = placement.NewRuleManager(core.NewStorage(kv.NewMemoryKV()), cluster)
+	c.Assert(cluster.ruleManager.Initialize(opt.GetMaxReplicas(), opt.GetLocationLabels()), IsNil)
+
+	labels := [][]*metapb.StoreLabel{
+		{{Key: "zone", Value: "z1"}},
+		{{Key: "zone", Value: "z2"}},
+		{{Key: "zone", Value: "z2"}, {Key: "engine", Value: "tiflash"}},
+	}
+	for i, store := range newTestStores(3, "5.0.0") {
+		c.Assert(cluster.PutStore(store.Clone(core.SetStoreLabels(labels[i])).GetMeta()), IsNil)
+	}
+	storeIDs := func(f *StoreFilter) []uint64 {
+		var ids []uint64
+		for _, store := range cluster.FilterMetaStores(cluster.GetMetaStores(), f) {
+			ids = append(ids, store.GetId())
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		return ids
+	}
+	keyspace1, keyspace2 := uint32(1), uint32(2)
+
+	c.Assert(storeIDs(&StoreFilter{}), DeepEquals, []uint64{1, 2, 3})
+	c.Assert(storeIDs(&StoreFilter{Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z2"}}}), DeepEquals, []uint64{2, 3})
+	// The TiFlash store is excluded without the placement rules.
+	c.Assert(storeIDs(&StoreFilter{KeyspaceID: &keyspace1}), DeepEquals, []uint64{1, 2})
+	c.Assert(storeIDs(&StoreFilter{KeyspaceID: &keyspace1, Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z2"}}}), DeepEquals, []uint64{2})
+
+	// Place TiFlash learners for keyspace 1 and only place keyspace 2 in z1.
+	opt.SetPlacementRuleEnabled(true)
+	for _, kr := range keyspace.MakeKeyRanges(keyspace1) {
+		c.Assert(cluster.ruleManager.SetRule(&placement.Rule{
+			GroupID:          "tiflash",
+			ID:               fmt.Sprintf("keyspace-1-%x", kr.StartKey),
+			StartKeyHex:      hex.EncodeToString(kr.StartKey),
+			EndKeyHex:        hex.EncodeToString(kr.EndKey),
+			Role:             placement.Learner,
+			Count:            1,
+			LabelConstraints: []placement.LabelConstraint{{Key: "engine", Op: placement.In, Values: []string{"tiflash"}}},
+		}), IsNil)
+	}
+	for _, kr := range keyspace.MakeKeyRanges(keyspace2) {
+		c.Assert(cluster.ruleManager.SetRule(&placement.Rule{
+			GroupID:          "pd",
+			ID:               fmt.Sprintf("keyspace-2-%x", kr.StartKey),
+			Index:            1,
+			Override:         true,
+			StartKeyHex:      hex.EncodeToString(kr.StartKey),
+			EndKeyHex:        hex.EncodeToString(kr.EndKey),
+			Role:             placement.Voter,
+			Count:            3,
+			LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.In, Values: []string{"z1"}}},
+		}), IsNil)
+	}
+	c.Assert(storeIDs(&StoreFilter{KeyspaceID: &keyspace1}), DeepEquals, []uint64{1, 2, 3})
+	c.Assert(storeIDs(&StoreFilter{KeyspaceID: &keyspace2}), DeepEquals, []uint64{1})
+	c.Assert(storeIDs(&StoreFilter{KeyspaceID: &keyspace2, Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z2"}}}), IsNil)
+}
+
 func (s *testClusterInfoSuite) TestDeleteStoreUpdatesClusterVersion(c *C) {
 	_, opt, err := newTestScheduleConfig()
 	c.Assert(err, IsNil)
@@ -492,6 +921,90 @@ func (s *testClusterInfoSuite) TestRegionHeartbeat(c *C) {
 	}
 }
 
+func (s *testClusterInfoSuite) TestRegionHeartbeats(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+
+	for _, store := range newTestStores(3, "2.0.0") {
+		c.Assert(cluster.putStoreLocked(store), IsNil)
+	}
+	regions := newTestRegions(10, 3)
+	for _, err := range cluster.processRegionHeartbeats(regions) {
+		c.Assert(err, IsNil)
+	}
+	checkRegions(c, cluster.core.Regions, regions)
+	checkRegionsKV(c, cluster.storage, regions)
+
+	// The heartbeat which is stale after the former one in the same batch
+	// fails alone.
+	origin := regions[0]
+	regions[0] = origin.Clone(core.WithIncVersion())
+	stale := origin.Clone(core.WithIncConfVer())
+	regions[2] = regions[2].Clone(core.WithLeader(regions[2].GetPeers()[1]))
+	results := cluster.processRegionHeartbeats([]*core.RegionInfo{regions[0], stale, regions[2]})
+	c.Assert(results, HasLen, 3)
+	c.Assert(results[0], IsNil)
+	c.Assert(results[1], NotNil)
+	c.Assert(results[2], IsNil)
+	checkRegions(c, cluster.core.Regions, regions)
+	checkRegionsKV(c, cluster.storage, regions)
+	c.Assert(cluster.GetRegion(regions[2].GetID()).GetLeader().GetStoreId(), Equals, regions[2].GetPeers()[1].GetStoreId())
+}
+
+func (s *testClusterInfoSuite) TestEpochConflicts(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	regions := newTestRegions(3, 3)
+	for _, region := range regions {
+		c.Assert(cluster.processRegionHeartbeat(region), IsNil)
+	}
+
+	c.Assert(cluster.processRegionHeartbeat(regions[1].Clone(core.WithIncVersion())), IsNil)
+	c.Assert(cluster.processRegionHeartbeat(regions[1]), NotNil)
+	c.Assert(cluster.processRegionHeartbeat(regions[2].Clone(core.WithIncConfVer())), IsNil)
+	c.Assert(cluster.processRegionHeartbeat(regions[2]), NotNil)
+	overlap := core.NewRegionInfo(&metapb.Region{
+		Id:          10,
+		StartKey:    []byte{0},
+		EndKey:      []byte{2},
+		Peers:       []*metapb.Peer{{Id: 100, StoreId: 1}},
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}, &metapb.Peer{Id: 100, StoreId: 1})
+	c.Assert(cluster.processRegionHeartbeat(overlap), NotNil)
+
+	conflicts := cluster.GetEpochConflicts(0, 0, 0)
+	c.Assert(conflicts, HasLen, 3)
+	c.Assert(conflicts[0].Reason, Equals, "overlapped")
+	c.Assert(conflicts[0].StoreID, Equals, uint64(1))
+	c.Assert(conflicts[0].Reported.RegionID, Equals, uint64(10))
+	c.Assert(conflicts[0].Current.RegionID, Equals, uint64(0))
+	c.Assert(conflicts[1].Reason, Equals, "stale-conf-ver")
+	c.Assert(conflicts[1].StoreID, Equals, uint64(2))
+	c.Assert(conflicts[1].Reported.ConfVer, Equals, uint64(2))
+	c.Assert(conflicts[1].Current.ConfVer, Equals, uint64(3))
+	c.Assert(conflicts[2].Reason, Equals, "stale-version")
+	c.Assert(conflicts[2].Reported.Version, Equals, uint64(2))
+	c.Assert(conflicts[2].Current.Version, Equals, uint64(3))
+
+	c.Assert(cluster.GetEpochConflicts(0, 0, 1), DeepEquals, conflicts[:1])
+	c.Assert(cluster.GetEpochConflicts(0, 2, 0), DeepEquals, conflicts[1:2])
+	c.Assert(cluster.GetEpochConflicts(1, 0, 0), DeepEquals, conflicts[2:])
+	c.Assert(cluster.GetEpochConflicts(0, 0, 0), DeepEquals, conflicts)
+	c.Assert(cluster.GetEpochConflicts(5, 0, 0), HasLen, 0)
+
+	// Only the latest conflicts are kept.
+	e := newEpochConflicts()
+	for i := 0; i < maxEpochConflicts+2; i++ {
+		e.record(&EpochConflict{StoreID: uint64(i)})
+	}
+	conflicts = e.list(0, 0, 0)
+	c.Assert(conflicts, HasLen, maxEpochConflicts)
+	c.Assert(conflicts[0].StoreID, Equals, uint64(maxEpochConflicts+1))
+	c.Assert(conflicts[maxEpochConflicts-1].StoreID, Equals, uint64(2))
+}
+
 func (s *testClusterInfoSuite) TestRegionFlowChanged(c *C) {
 	_, opt, err := newTestScheduleConfig()
 	c.Assert(err, IsNil)
@@ -519,6 +1032,112 @@ func (s *testClusterInfoSuite) TestRegionFlowChanged(c *C) {
 	c.Assert(newRegion.GetBytesRead(), Not(Equals), uint64(0))
 }
 
+func (s *testClusterInfoSuite) TestRegionHeartbeatLatencyBudget(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	cluster.ruleManager = placement.NewRuleManager(core.NewStorage(kv.NewMemoryKV()), cluster)
+	c.Assert(cluster.ruleManager.Initialize(opt.GetMaxReplicas(), opt.GetLocationLabels()), IsNil)
+	cluster.regionStats = statistics.NewRegionStatistics(cluster.GetOpts(), cluster.ruleManager)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster.coordinator = newCoordinator(ctx, cluster, hbstream.NewTestHeartbeatStreams(ctx, cluster.getClusterID(), cluster, false))
+	for _, store := range newTestStores(3, "5.0.0") {
+		c.Assert(cluster.PutStore(store.GetMeta()), IsNil)
+	}
+	newRegion := func(id uint64) *core.RegionInfo {
+		peers := []*metapb.Peer{{Id: id*10 + 1, StoreId: 1}, {Id: id*10 + 2, StoreId: 2}}
+		return core.NewRegionInfo(&metapb.Region{
+			Id:          id,
+			StartKey:    []byte(fmt.Sprintf("%20d", id)),
+			EndKey:      []byte(fmt.Sprintf("%20d", id+1)),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+			Peers:       peers,
+		}, peers[0])
+	}
+
+	// No limit by default.
+	c.Assert(cluster.HandleRegionHeartbeat(newRegion(1)), IsNil)
+	c.Assert(cluster.isHeartbeatBudgetExhausted(), IsFalse)
+	c.Assert(cluster.GetRegionStatsByType(statistics.MissPeer), HasLen, 1)
+
+	cfg := opt.GetPDServerConfig().Clone()
+	cfg.RegionHeartbeatLatencyBudget = typeutil.NewDuration(time.Nanosecond)
+	opt.SetPDServerConfig(cfg)
+	c.Assert(cluster.HandleRegionHeartbeat(newRegion(2)), IsNil)
+	c.Assert(cluster.isHeartbeatBudgetExhausted(), IsTrue)
+	// The meta is updated but the statistics are skipped.
+	c.Assert(cluster.HandleRegionHeartbeat(newRegion(3)), IsNil)
+	c.Assert(cluster.GetRegion(3), NotNil)
+	c.Assert(cluster.GetRegionStatsByType(statistics.MissPeer), HasLen, 2)
+
+	// The budget is reset in the next window.
+	cluster.hbBudget.windowStart = time.Now().Add(-heartbeatBudgetWindow)
+	c.Assert(cluster.isHeartbeatBudgetExhausted(), IsFalse)
+	c.Assert(cluster.HandleRegionHeartbeat(newRegion(4)), IsNil)
+	c.Assert(cluster.GetRegionStatsByType(statistics.MissPeer), HasLen, 3)
+}
+
+func (s *testClusterInfoSuite) TestRegionHeartbeatFairQueue(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	cluster.ruleManager = placement.NewRuleManager(core.NewStorage(kv.NewMemoryKV()), cluster)
+	c.Assert(cluster.ruleManager.Initialize(opt.GetMaxReplicas(), opt.GetLocationLabels()), IsNil)
+	cluster.regionStats = statistics.NewRegionStatistics(cluster.GetOpts(), cluster.ruleManager)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster.coordinator = newCoordinator(ctx, cluster, hbstream.NewTestHeartbeatStreams(ctx, cluster.getClusterID(), cluster, false))
+	for _, store := range newTestStores(3, "5.0.0") {
+		c.Assert(cluster.PutStore(store.GetMeta()), IsNil)
+	}
+	newRegion := func(id uint64, prefix []byte) *core.RegionInfo {
+		peers := []*metapb.Peer{{Id: id*10 + 1, StoreId: 1}, {Id: id*10 + 2, StoreId: 2}}
+		return core.NewRegionInfo(&metapb.Region{
+			Id:          id,
+			StartKey:    codec.EncodeBytes(append(prefix, byte(id))),
+			EndKey:      codec.EncodeBytes(append(prefix, byte(id+1))),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+			Peers:       peers,
+		}, peers[0])
+	}
+
+	cfg := opt.GetPDServerConfig().Clone()
+	cfg.RegionHeartbeatFairQueueConcurrency = 1
+	opt.SetPDServerConfig(cfg)
+	cluster.SetRegionHeartbeatFairQueueConcurrency(cfg.RegionHeartbeatFairQueueConcurrency)
+	c.Assert(cluster.HandleRegionHeartbeat(newRegion(1, []byte{'x', 0, 0, 1})), IsNil)
+	// The results of a batch keep the order of the regions across keyspaces.
+	regions := []*core.RegionInfo{
+		newRegion(2, []byte{'x', 0, 0, 2}),
+		newRegion(3, []byte("t")),
+		newRegion(4, []byte{'x', 0, 0, 2}),
+		newRegion(5, []byte{'x', 0, 0, 1}),
+	}
+	results := cluster.HandleRegionHeartbeats(regions)
+	c.Assert(results, HasLen, len(regions))
+	for i, region := range regions {
+		c.Assert(results[i], IsNil)
+		c.Assert(cluster.GetRegion(region.GetID()), NotNil)
+	}
+	c.Assert(cluster.hbQueue.Len(), Equals, 0)
+
+	cluster.hbShare.Lock()
+	c.Assert(cluster.hbShare.used, HasLen, 3)
+	for _, ks := range []string{"1", "2", noneKeyspace} {
+		_, ok := cluster.hbShare.used[ks]
+		c.Assert(ok, IsTrue)
+	}
+	// The shares are reported when the window ends.
+	cluster.hbShare.windowStart = time.Now().Add(-heartbeatBudgetWindow)
+	cluster.hbShare.Unlock()
+	c.Assert(cluster.HandleRegionHeartbeat(newRegion(6, nil)), IsNil)
+	cluster.hbShare.Lock()
+	c.Assert(cluster.hbShare.reported, HasLen, 3)
+	c.Assert(cluster.hbShare.used, HasLen, 1)
+	cluster.hbShare.Unlock()
+}
+
 func (s *testClusterInfoSuite) TestConcurrentRegionHeartbeat(c *C) {
 	_, opt, err := newTestScheduleConfig()
 	c.Assert(err, IsNil)
@@ -947,6 +1566,14 @@ func newTestCluster(opt *config.PersistOptions) *testCluster {
 	return &testCluster{RaftCluster: rc}
 }
 
+type testSaveBatchErrorKV struct {
+	kv.Base
+}
+
+func (kv *testSaveBatchErrorKV) SaveBatch(kvs map[string]string) error {
+	return errors.New("save batch failed")
+}
+
 func newTestRaftCluster(id id.Allocator, opt *config.PersistOptions, storage *core.Storage, basicCluster *core.BasicCluster) *RaftCluster {
 	rc := &RaftCluster{ctx: context.TODO()}
 	rc.InitCluster(id, opt, storage, basicCluster)
@@ -1081,3 +1708,29 @@ func checkStaleRegion(origin *metapb.Region, region *metapb.Region) error {
 
 	return nil
 }
+
+func BenchmarkHandleStoreHeartbeat(b *testing.B) {
+	_, opt, err := newTestScheduleConfig()
+	if err != nil {
+		b.Fatal(err)
+	}
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	storeCount := uint64(1000)
+	for _, store := range newTestStores(storeCount, "2.0.0") {
+		if err := cluster.putStoreLocked(store); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	var next uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			storeID := atomic.AddUint64(&next, 1)%storeCount + 1
+			if err := cluster.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: storeID, Capacity: 100, Available: 50}); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -15,6 +15,7 @@ package cluster
 
 import (
 	"bytes"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/pingcap/errors"
@@ -31,6 +32,23 @@ import (
 
 // HandleRegionHeartbeat processes RegionInfo reports from client.
 func (c *RaftCluster) HandleRegionHeartbeat(region *core.RegionInfo) error {
+	if err := c.CheckRecoverVerified(); err != nil {
+		return err
+	}
+	if c.opt.GetRegionHeartbeatFairQueueConcurrency() > 0 {
+		done, err := c.enterHeartbeatQueue(heartbeatKeyspace(region))
+		if err != nil {
+			return err
+		}
+		defer done()
+	}
+	if limit := c.opt.GetRegionHeartbeatLatencyBudget(); limit > 0 {
+		start := time.Now()
+		defer func() {
+			now := time.Now()
+			regionHeartbeatBudgetUsageGauge.Set(c.hbBudget.consume(now, now.Sub(start), limit))
+		}()
+	}
 	if err := c.processRegionHeartbeat(region); err != nil {
 		return err
 	}
@@ -42,8 +60,128 @@ func (c *RaftCluster) HandleRegionHeartbeat(region *core.RegionInfo) error {
 	return nil
 }
 
+// HandleRegionHeartbeats processes a batch of region heartbeats and dispatches
+// the operators of the regions. The returned errors are in the same order as
+// the regions.
+func (c *RaftCluster) HandleRegionHeartbeats(regions []*core.RegionInfo) []error {
+	if err := c.CheckRecoverVerified(); err != nil {
+		results := make([]error, len(regions))
+		for i := range results {
+			results[i] = err
+		}
+		return results
+	}
+	if c.opt.GetRegionHeartbeatFairQueueConcurrency() <= 0 {
+		return c.handleRegionHeartbeats(regions)
+	}
+	// Queue the regions of each keyspace separately, so a batch does not hold
+	// the turns of the other keyspaces.
+	var keyspaces []string
+	groups := make(map[string][]int)
+	for i, region := range regions {
+		ks := heartbeatKeyspace(region)
+		if _, ok := groups[ks]; !ok {
+			keyspaces = append(keyspaces, ks)
+		}
+		groups[ks] = append(groups[ks], i)
+	}
+	results := make([]error, len(regions))
+	for _, ks := range keyspaces {
+		group := groups[ks]
+		done, err := c.enterHeartbeatQueue(ks)
+		if err != nil {
+			for _, i := range group {
+				results[i] = err
+			}
+			continue
+		}
+		batch := make([]*core.RegionInfo, 0, len(group))
+		for _, i := range group {
+			batch = append(batch, regions[i])
+		}
+		for j, err := range c.handleRegionHeartbeats(batch) {
+			results[group[j]] = err
+		}
+		done()
+	}
+	return results
+}
+
+func (c *RaftCluster) handleRegionHeartbeats(regions []*core.RegionInfo) []error {
+	if limit := c.opt.GetRegionHeartbeatLatencyBudget(); limit > 0 {
+		start := time.Now()
+		defer func() {
+			now := time.Now()
+			regionHeartbeatBudgetUsageGauge.Set(c.hbBudget.consume(now, now.Sub(start), limit))
+		}()
+	}
+	results := c.processRegionHeartbeats(regions)
+
+	c.RLock()
+	co := c.coordinator
+	c.RUnlock()
+	for i, region := range regions {
+		if results[i] == nil {
+			co.opController.Dispatch(region, schedule.DispatchFromHeartBeat)
+		}
+	}
+	return results
+}
+
+// isHeartbeatBudgetExhausted returns if the latency budget of the region
+// heartbeats is used up in the current window.
+func (c *RaftCluster) isHeartbeatBudgetExhausted() bool {
+	limit := c.opt.GetRegionHeartbeatLatencyBudget()
+	return limit > 0 && c.hbBudget.exhausted(time.Now(), limit)
+}
+
+// regionHeartbeatFlowThreshold is the max ratio of the flow change for a
+// heartbeat to be considered as unchanged.
+const regionHeartbeatFlowThreshold = 0.1
+
+// IsRegionHeartbeatUnchanged returns whether handling the heartbeat can be
+// skipped, because it carries nothing new for a region which is neither hot
+// nor being operated.
+func (c *RaftCluster) IsRegionHeartbeatUnchanged(region *core.RegionInfo) bool {
+	origin := c.GetRegion(region.GetID())
+	if !region.IsHeartbeatUnchanged(origin, regionHeartbeatFlowThreshold) {
+		return false
+	}
+	if c.IsRegionHot(origin) {
+		return false
+	}
+	c.RLock()
+	co := c.coordinator
+	c.RUnlock()
+	return co.opController.GetOperator(region.GetID()) == nil
+}
+
+// HandleSkippedRegionHeartbeat feeds the flow of a heartbeat whose handling is
+// skipped to the hot peer cache, so that a quiet region still becomes hot as
+// its flow grows. Neither the region tree nor the storage is updated.
+func (c *RaftCluster) HandleSkippedRegionHeartbeat(region *core.RegionInfo) {
+	c.RLock()
+	writeItems := c.CheckWriteStatus(region)
+	readItems := c.CheckReadStatus(region)
+	c.RUnlock()
+	if len(writeItems) == 0 && len(readItems) == 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for _, writeItem := range writeItems {
+		c.hotStat.Update(writeItem)
+	}
+	for _, readItem := range readItems {
+		c.hotStat.Update(readItem)
+	}
+}
+
 // HandleAskSplit handles the split request.
 func (c *RaftCluster) HandleAskSplit(request *pdpb.AskSplitRequest) (*pdpb.AskSplitResponse, error) {
+	if err := c.CheckRecoverVerified(); err != nil {
+		return nil, err
+	}
 	reqRegion := request.GetRegion()
 	err := c.ValidRequestRegion(reqRegion)
 	if err != nil {
@@ -96,6 +234,9 @@ func (c *RaftCluster) ValidRequestRegion(reqRegion *metapb.Region) error {
 
 // HandleAskBatchSplit handles the batch split request.
 func (c *RaftCluster) HandleAskBatchSplit(request *pdpb.AskBatchSplitRequest) (*pdpb.AskBatchSplitResponse, error) {
+	if err := c.CheckRecoverVerified(); err != nil {
+		return nil, err
+	}
 	reqRegion := request.GetRegion()
 	splitCount := request.GetSplitCount()
 	err := c.ValidRequestRegion(reqRegion)
@@ -191,6 +332,14 @@ func (c *RaftCluster) HandleReportSplit(request *pdpb.ReportSplitRequest) (*pdpb
 		return nil, err
 	}
 
+	// The retries of a processed report are acknowledged without processing.
+	if !c.splitReports.record(splitReportKey(right, []*metapb.Region{left}), time.Now()) {
+		splitReportCounter.WithLabelValues("split", "duplicated").Inc()
+		log.Debug("region split is already reported", zap.Uint64("region-id", right.GetId()))
+		return &pdpb.ReportSplitResponse{}, nil
+	}
+	splitReportCounter.WithLabelValues("split", "processed").Inc()
+
 	// Build origin region by using left and right.
 	originRegion := proto.Clone(right).(*metapb.Region)
 	originRegion.RegionEpoch = nil
@@ -214,6 +363,12 @@ func (c *RaftCluster) HandleBatchReportSplit(request *pdpb.ReportBatchSplitReque
 		return nil, err
 	}
 	last := len(regions) - 1
+	if !c.splitReports.record(splitReportKey(regions[last], regions[:last]), time.Now()) {
+		splitReportCounter.WithLabelValues("batch-split", "duplicated").Inc()
+		log.Debug("region batch split is already reported", zap.Uint64("region-id", regions[last].GetId()))
+		return &pdpb.ReportBatchSplitResponse{}, nil
+	}
+	splitReportCounter.WithLabelValues("batch-split", "processed").Inc()
 	originRegion := proto.Clone(regions[last]).(*metapb.Region)
 	hrm = core.RegionsToHexMeta(regions[:last])
 	log.Info("region batch split, generate new regions",
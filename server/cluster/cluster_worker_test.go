@@ -14,6 +14,8 @@
 package cluster
 
 import (
+	"time"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
@@ -53,3 +55,44 @@ func (s *testClusterWorkerSuite) TestReportBatchSplit(c *C) {
 	_, err = cluster.HandleBatchReportSplit(&pdpb.ReportBatchSplitRequest{Regions: regions})
 	c.Assert(err, IsNil)
 }
+
+func (s *testClusterWorkerSuite) TestReportSplitDeduplicated(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, storage, core.NewBasicCluster())
+	left := &metapb.Region{Id: 1, StartKey: []byte("a"), EndKey: []byte("b"), RegionEpoch: &metapb.RegionEpoch{Version: 2}}
+	right := &metapb.Region{Id: 2, StartKey: []byte("b"), EndKey: []byte("c"), RegionEpoch: &metapb.RegionEpoch{Version: 2}}
+	key := splitReportKey(right, []*metapb.Region{left})
+	for i := 0; i < 2; i++ {
+		_, err = cluster.HandleReportSplit(&pdpb.ReportSplitRequest{Left: left, Right: right})
+		c.Assert(err, IsNil)
+	}
+	c.Assert(cluster.splitReports.expires, HasLen, 1)
+	// The reports are persisted by the background jobs.
+	keys := 0
+	c.Assert(storage.LoadSplitReports(func(string, time.Time) { keys++ }), IsNil)
+	c.Assert(keys, Equals, 0)
+	cluster.splitReports.flush()
+
+	// The new leader loads the processed reports.
+	now := time.Now()
+	cluster = newTestRaftCluster(mockid.NewIDAllocator(), opt, storage, core.NewBasicCluster())
+	c.Assert(cluster.splitReports.load(now), IsNil)
+	c.Assert(cluster.splitReports.record(key, now), IsFalse)
+	// A split of another epoch is not a duplicate.
+	right.RegionEpoch.Version = 3
+	c.Assert(cluster.splitReports.record(splitReportKey(right, []*metapb.Region{left}), now), IsTrue)
+
+	// The reports expire after the window, and are removed from storage.
+	later := now.Add(splitReportWindow)
+	c.Assert(cluster.splitReports.record(key, later), IsTrue)
+	cluster.splitReports.flush()
+	cluster = newTestRaftCluster(mockid.NewIDAllocator(), opt, storage, core.NewBasicCluster())
+	c.Assert(cluster.splitReports.load(later.Add(splitReportWindow)), IsNil)
+	c.Assert(cluster.splitReports.expires, HasLen, 0)
+	cluster.splitReports.flush()
+	keys = 0
+	c.Assert(storage.LoadSplitReports(func(string, time.Time) { keys++ }), IsNil)
+	c.Assert(keys, Equals, 0)
+}
@@ -67,6 +67,8 @@ type coordinator struct {
 	opController    *schedule.OperatorController
 	hbStreams       *hbstream.HeartbeatStreams
 	pluginInterface *schedule.PluginInterface
+	// patrolClean is 1 if the last round of the patrol found no region to fix.
+	patrolClean int32
 }
 
 // newCoordinator creates a new coordinator.
@@ -99,6 +101,7 @@ func (c *coordinator) patrolRegions() {
 	log.Info("coordinator starts patrol regions")
 	start := time.Now()
 	var key []byte
+	var patrolOps int
 	for {
 		select {
 		case <-timer.C:
@@ -134,6 +137,7 @@ func (c *coordinator) patrolRegions() {
 			if len(ops) == 0 {
 				continue
 			}
+			patrolOps += len(ops)
 
 			if !c.opController.ExceedStoreLimit(ops...) {
 				c.opController.AddWaitingOperator(ops...)
@@ -148,6 +152,12 @@ func (c *coordinator) patrolRegions() {
 		if len(key) == 0 {
 			patrolCheckRegionsGauge.Set(time.Since(start).Seconds())
 			start = time.Now()
+			if patrolOps == 0 {
+				atomic.StoreInt32(&c.patrolClean, 1)
+			} else {
+				atomic.StoreInt32(&c.patrolClean, 0)
+			}
+			patrolOps = 0
 		}
 		failpoint.Inject("break-patrol", func() {
 			failpoint.Break()
@@ -353,10 +363,11 @@ func (c *coordinator) run() {
 		log.Error("cannot persist schedule config", errs.ZapError(err))
 	}
 
-	c.wg.Add(2)
+	c.wg.Add(3)
 	// Starts to patrol regions.
 	go c.patrolRegions()
 	go c.drivePushOperator()
+	go c.watchSchedulerWakeEvents()
 }
 
 // LoadPlugin load user plugin
@@ -487,6 +498,11 @@ func (c *coordinator) collectSchedulerMetrics() {
 			allowScheduler = 1
 		}
 		schedulerStatusGauge.WithLabelValues(s.GetName(), "allow").Set(allowScheduler)
+		var hibernating float64
+		if s.IsHibernating() {
+			hibernating = 1
+		}
+		schedulerStatusGauge.WithLabelValues(s.GetName(), "hibernating").Set(hibernating)
 	}
 }
 
@@ -592,6 +608,37 @@ func (c *coordinator) addScheduler(scheduler schedule.Scheduler, args ...string)
 	return nil
 }
 
+// dryRunScheduler runs a scheduler once against the current cluster state and
+// returns the operators it generates without adding them. If no scheduler is
+// named name, a new scheduler of the type name is created with args. It also
+// returns whether the scheduler is allowed to schedule now.
+func (c *coordinator) dryRunScheduler(name string, args ...string) ([]*operator.Operator, bool, error) {
+	c.RLock()
+	if c.cluster == nil {
+		c.RUnlock()
+		return nil, false, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	typ, dec := name, schedule.ConfigSliceDecoder(name, args)
+	if s, ok := c.schedulers[name]; ok {
+		data, err := s.EncodeConfig()
+		if err != nil {
+			c.RUnlock()
+			return nil, false, err
+		}
+		typ, dec = s.GetType(), schedule.ConfigJSONDecoder(data)
+	}
+	c.RUnlock()
+
+	// The scheduler is created with a separate storage and a read-only copy of
+	// the operator controller, so that running it leaves no trace.
+	s, err := schedule.CreateScheduler(typ, c.opController.ReadOnlyCopy(), core.NewStorage(kv.NewMemoryKV()), dec)
+	if err != nil {
+		return nil, false, err
+	}
+	allowed := s.IsScheduleAllowed(c.cluster)
+	return s.Schedule(c.cluster), allowed, nil
+}
+
 func (c *coordinator) removeScheduler(name string) error {
 	c.Lock()
 	defer c.Unlock()
@@ -733,6 +780,19 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 				log.Debug("add operator", zap.Int("added", added), zap.Int("total", len(op)), zap.String("scheduler", s.GetName()))
 			}
 
+		case <-s.wakeCh:
+			if !s.IsHibernating() {
+				continue
+			}
+			s.wakeUp()
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(s.GetInterval())
+
 		case <-s.Ctx().Done():
 			log.Info("scheduler has been stopped",
 				zap.String("scheduler-name", s.GetName()),
@@ -745,12 +805,16 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 // scheduleController is used to manage a scheduler to schedule.
 type scheduleController struct {
 	schedule.Scheduler
+	coordinator  *coordinator
 	cluster      *RaftCluster
 	opController *schedule.OperatorController
 	nextInterval time.Duration
+	idleRounds   int
 	ctx          context.Context
 	cancel       context.CancelFunc
 	delayUntil   int64
+	hibernating  int32
+	wakeCh       chan struct{}
 }
 
 // newScheduleController creates a new scheduleController.
@@ -758,11 +822,13 @@ func newScheduleController(c *coordinator, s schedule.Scheduler) *scheduleContro
 	ctx, cancel := context.WithCancel(c.ctx)
 	return &scheduleController{
 		Scheduler:    s,
+		coordinator:  c,
 		cluster:      c.cluster,
 		opController: c.opController,
 		nextInterval: s.GetMinInterval(),
 		ctx:          ctx,
 		cancel:       cancel,
+		wakeCh:       make(chan struct{}, 1),
 	}
 }
 
@@ -778,11 +844,20 @@ func (s *scheduleController) Schedule() []*operator.Operator {
 	for i := 0; i < maxScheduleRetries; i++ {
 		// If we have schedule, reset interval to the minimal interval.
 		if op := s.Scheduler.Schedule(s.cluster); op != nil {
-			s.nextInterval = s.Scheduler.GetMinInterval()
+			s.wakeUp()
+			for _, o := range op {
+				o.SetSource(operator.SourceScheduler)
+			}
 			return op
 		}
 	}
+	s.idleRounds++
 	s.nextInterval = s.Scheduler.GetNextInterval(s.nextInterval)
+	if interval := s.cluster.GetOpts().GetSchedulerHibernateInterval(); s.canHibernate(interval) {
+		s.hibernate(interval)
+	} else if s.IsHibernating() {
+		s.wakeUp()
+	}
 	return nil
 }
 
@@ -17,6 +17,7 @@ import (
 	"context"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/eraftpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/eventbus"
 	"github.com/tikv/pd/pkg/mock/mockhbstream"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/pkg/typeutil"
@@ -188,6 +190,52 @@ func (s *testCoordinatorSuite) TestBasic(c *C) {
 	c.Assert(oc.GetOperator(1).RegionID(), Equals, op3.RegionID())
 }
 
+func (s *testCoordinatorSuite) TestUnchangedRegionHeartbeat(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	tc.coordinator = co
+
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		c.Assert(tc.addRegionStore(storeID, 10), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	region := tc.GetRegion(1)
+	c.Assert(tc.IsRegionHeartbeatUnchanged(region.Clone()), IsTrue)
+	c.Assert(tc.IsRegionHeartbeatUnchanged(region.Clone(core.SetApproximateSize(20))), IsFalse)
+	c.Assert(tc.IsRegionHeartbeatUnchanged(region.Clone(core.WithLeader(region.GetStorePeer(2)))), IsFalse)
+
+	// The region being operated needs every heartbeat.
+	op := newTestOperator(1, region.GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	c.Assert(co.opController.AddOperator(op), IsTrue)
+	c.Assert(tc.IsRegionHeartbeatUnchanged(region.Clone()), IsFalse)
+}
+
+func (s *testCoordinatorSuite) TestHotRegionWithSkippedHeartbeat(c *C) {
+	tc, co, cleanup := prepare(func(cfg *config.ScheduleConfig) { cfg.HotRegionCacheHitsThreshold = 0 }, nil, nil, c)
+	defer cleanup()
+	tc.coordinator = co
+
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		c.Assert(tc.addRegionStore(storeID, 10), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	// The flow is in the region tree, but the hot peer cache has not seen it.
+	region := tc.GetRegion(1).Clone(
+		core.SetWrittenBytes(100*1024*1024),
+		core.SetReportInterval(statistics.RegionHeartBeatReportInterval),
+	)
+	c.Assert(tc.putRegion(region), IsNil)
+	c.Assert(tc.IsRegionHot(region), IsFalse)
+
+	// The region becomes hot while its heartbeats are skipped, after which
+	// they are handled again.
+	for i := 0; i < 3 && tc.IsRegionHeartbeatUnchanged(region); i++ {
+		tc.HandleSkippedRegionHeartbeat(region.Clone())
+	}
+	c.Assert(tc.IsRegionHot(region), IsTrue)
+	c.Assert(tc.IsRegionHeartbeatUnchanged(region), IsFalse)
+}
+
 func (s *testCoordinatorSuite) TestDispatch(c *C) {
 	tc, co, cleanup := prepare(nil, func(tc *testCluster) { tc.prepareChecker.isPrepared = true }, nil, c)
 	defer cleanup()
@@ -675,6 +723,108 @@ func (s *testCoordinatorSuite) TestAddScheduler(c *C) {
 	waitNoResponse(c, stream)
 }
 
+func (s *testCoordinatorSuite) TestDryRunScheduler(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+
+	c.Assert(tc.addLeaderStore(1, 1), IsNil)
+	c.Assert(tc.addLeaderStore(2, 1), IsNil)
+	c.Assert(tc.addLeaderStore(3, 1), IsNil)
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 2, 1, 3), IsNil)
+	c.Assert(tc.addLeaderRegion(3, 3, 1, 2), IsNil)
+
+	// Create a new scheduler by the type.
+	ops, allowed, err := co.dryRunScheduler(schedulers.GrantLeaderType, "1")
+	c.Assert(err, IsNil)
+	c.Assert(allowed, IsTrue)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].Kind()&operator.OpLeader, Not(Equals), operator.OpKind(0))
+	c.Assert(co.opController.GetOperators(), HasLen, 0)
+	c.Assert(co.schedulers, HasLen, 0)
+
+	// Run a copy of the existing scheduler.
+	gls, err := schedule.CreateScheduler(schedulers.GrantLeaderType, co.opController, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(schedulers.GrantLeaderType, []string{"1"}))
+	c.Assert(err, IsNil)
+	co.schedulers[gls.GetName()] = newScheduleController(co, gls)
+	ops, _, err = co.dryRunScheduler(gls.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(co.opController.GetOperators(), HasLen, 0)
+
+	// The running operators are seen by the scheduler.
+	c.Assert(co.opController.AddOperator(ops[0]), IsTrue)
+	c.Assert(co.opController.ReadOnlyCopy().GetOperators(), HasLen, 1)
+	cfg := tc.GetOpts().GetScheduleConfig().Clone()
+	cfg.LeaderScheduleLimit = 1
+	tc.GetOpts().SetScheduleConfig(cfg)
+	_, allowed, err = co.dryRunScheduler(gls.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(allowed, IsFalse)
+
+	_, _, err = co.dryRunScheduler("no-such-scheduler")
+	c.Assert(err, NotNil)
+	_, _, err = co.dryRunScheduler(schedulers.GrantLeaderType, "no-such-store")
+	c.Assert(err, NotNil)
+}
+
+func (s *testCoordinatorSuite) TestEstimateOperatorRates(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+
+	// Each store can add 60 peers in a minute.
+	for i := uint64(1); i <= 4; i++ {
+		c.Assert(tc.addLeaderStore(i, 1), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 2, 1, 3), IsNil)
+	c.Assert(tc.addLeaderRegion(3, 3, 1, 2), IsNil)
+
+	addScheduler := func(typ string, args ...string) *scheduleController {
+		sc, err := schedule.CreateScheduler(typ, co.opController, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(typ, args))
+		c.Assert(err, IsNil)
+		co.schedulers[sc.GetName()] = newScheduleController(co, sc)
+		return co.schedulers[sc.GetName()]
+	}
+	addScheduler(schedulers.GrantLeaderType, "1")
+	addScheduler(schedulers.ShuffleRegionType)
+	paused := addScheduler(schedulers.ShuffleLeaderType)
+	atomic.StoreInt64(&paused.delayUntil, time.Now().Add(time.Minute).Unix())
+	cfg := tc.GetOpts().GetScheduleConfig().Clone()
+	cfg.LeaderScheduleLimit = 2
+	tc.GetOpts().SetScheduleConfig(cfg)
+
+	result, err := co.estimateOperatorRates()
+	c.Assert(err, IsNil)
+	c.Assert(result.AddPeerCapacityPerHour, Equals, float64(4*60*60))
+	c.Assert(result.Estimates, HasLen, 4)
+	c.Assert(result.Estimates[0].Name, Equals, checkersEstimateName)
+	c.Assert(result.Estimates[0].OperatorsPerHour, Equals, float64(0))
+
+	// The leader transfers are bounded by the leader schedule limit.
+	grant := result.Estimates[1]
+	c.Assert(grant.Name, Equals, "grant-leader-scheduler")
+	c.Assert(grant.OperatorsPerRound, Equals, 1)
+	c.Assert(grant.OperatorsPerHour, Equals, float64(2*60*60))
+	c.Assert(grant.MovedSizePerHour, Equals, float64(0))
+	c.Assert(grant.LimitedBy, Equals, limitedByScheduleLimit)
+
+	// The peer moves are bounded by the store limits.
+	c.Assert(result.Estimates[2].Name, Equals, "shuffle-leader-scheduler")
+	c.Assert(result.Estimates[2].OperatorsPerHour, Equals, float64(0))
+	c.Assert(result.Estimates[2].LimitedBy, Equals, limitedByPaused)
+
+	shuffle := result.Estimates[3]
+	c.Assert(shuffle.Name, Equals, "shuffle-region-scheduler")
+	c.Assert(shuffle.OperatorsPerRound, Equals, 1)
+	c.Assert(shuffle.OperatorsPerHour, Equals, float64(4*60*60))
+	c.Assert(shuffle.MovedSizePerHour, Equals, float64(4*60*60*10))
+	c.Assert(shuffle.LimitedBy, Equals, limitedByStoreLimit)
+
+	c.Assert(result.OperatorsPerHour, Equals, grant.OperatorsPerHour+shuffle.OperatorsPerHour)
+	c.Assert(co.opController.GetOperators(), HasLen, 0)
+}
+
 func (s *testCoordinatorSuite) TestPersistScheduler(c *C) {
 	tc, co, cleanup := prepare(nil, nil, func(co *coordinator) { co.run() }, c)
 	hbStreams := co.hbStreams
@@ -1167,6 +1317,97 @@ func (s *testScheduleControllerSuite) TestInterval(c *C) {
 	}
 }
 
+func (s *testScheduleControllerSuite) TestHibernate(c *C) {
+	tc, co, cleanup := prepare(func(cfg *config.ScheduleConfig) {
+		cfg.SchedulerHibernateInterval = typeutil.NewDuration(time.Hour)
+	}, nil, nil, c)
+	defer cleanup()
+	c.Assert(tc.addLeaderRegion(1, 1), IsNil)
+
+	lb, err := schedule.CreateScheduler(schedulers.BalanceLeaderType, co.opController, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(schedulers.BalanceLeaderType, []string{"", ""}))
+	c.Assert(err, IsNil)
+	sc := newScheduleController(co, lb)
+	co.schedulers[sc.GetName()] = sc
+
+	// The patrol has not finished a clean round yet.
+	for i := 0; i < schedulerHibernateIdleRounds*2; i++ {
+		c.Assert(sc.Schedule(), IsNil)
+	}
+	c.Assert(sc.IsHibernating(), IsFalse)
+	c.Assert(sc.GetInterval(), Less, time.Hour)
+
+	atomic.StoreInt32(&co.patrolClean, 1)
+	c.Assert(sc.Schedule(), IsNil)
+	c.Assert(sc.IsHibernating(), IsTrue)
+	c.Assert(sc.GetInterval(), Equals, time.Hour)
+	c.Assert(sc.Schedule(), IsNil)
+	c.Assert(sc.GetInterval(), Equals, time.Hour)
+
+	// The events wake up the scheduler.
+	co.wakeSchedulers("test")
+	c.Assert(atomic.LoadInt32(&co.patrolClean), Equals, int32(0))
+	c.Assert(sc.wakeCh, HasLen, 1)
+	<-sc.wakeCh
+	sc.wakeUp()
+	c.Assert(sc.IsHibernating(), IsFalse)
+	c.Assert(sc.GetInterval(), Equals, schedulers.MinScheduleInterval)
+
+	// The scheduler wakes up by itself if the cluster is no longer idle.
+	atomic.StoreInt32(&co.patrolClean, 1)
+	for i := 0; i < schedulerHibernateIdleRounds; i++ {
+		c.Assert(sc.Schedule(), IsNil)
+	}
+	c.Assert(sc.IsHibernating(), IsTrue)
+	op := newTestOperator(1, tc.GetRegion(1).GetRegionEpoch(), operator.OpLeader)
+	c.Assert(co.opController.AddWaitingOperator(op), Equals, 1)
+	c.Assert(sc.Schedule(), IsNil)
+	c.Assert(sc.IsHibernating(), IsFalse)
+	c.Assert(sc.GetInterval(), Equals, schedulers.MinScheduleInterval)
+	c.Assert(co.opController.RemoveOperator(op), IsTrue)
+
+	// The hot region scheduler never hibernates.
+	hb, err := schedule.CreateScheduler(schedulers.HotRegionType, co.opController, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigJSONDecoder([]byte("null")))
+	c.Assert(err, IsNil)
+	hc := newScheduleController(co, hb)
+	for i := 0; i < schedulerHibernateIdleRounds*2; i++ {
+		hc.Schedule()
+	}
+	c.Assert(hc.IsHibernating(), IsFalse)
+}
+
+func (s *testScheduleControllerSuite) TestHibernateEvents(c *C) {
+	tc, co, cleanup := prepare(nil, func(tc *testCluster) {
+		tc.eventBus = eventbus.NewBus()
+	}, nil, c)
+	defer cleanup()
+	co.wg.Add(1)
+	go co.watchSchedulerWakeEvents()
+	bus := tc.eventBus
+
+	// The regions are not subscribed while the hibernation is disabled.
+	testutil.WaitUntil(c, func(c *C) bool {
+		return bus.HasSubscribers(eventbus.TopicStore) && bus.HasSubscribers(eventbus.TopicConfig)
+	})
+	c.Assert(bus.HasSubscribers(eventbus.TopicRegion), IsFalse)
+
+	cfg := tc.GetOpts().GetScheduleConfig().Clone()
+	cfg.SchedulerHibernateInterval = typeutil.NewDuration(time.Hour)
+	tc.GetOpts().SetScheduleConfig(cfg)
+	bus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "schedule"})
+	testutil.WaitUntil(c, func(c *C) bool {
+		return bus.HasSubscribers(eventbus.TopicRegion)
+	})
+
+	cfg = cfg.Clone()
+	cfg.SchedulerHibernateInterval = typeutil.NewDuration(0)
+	tc.GetOpts().SetScheduleConfig(cfg)
+	bus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "schedule"})
+	testutil.WaitUntil(c, func(c *C) bool {
+		return !bus.HasSubscribers(eventbus.TopicRegion)
+	})
+	c.Assert(bus.HasSubscribers(eventbus.TopicStore), IsTrue)
+}
+
 func waitAddLearner(c *C, stream mockhbstream.HeartbeatStream, region *core.RegionInfo, storeID uint64) *core.RegionInfo {
 	var res *pdpb.RegionHeartbeatResponse
 	testutil.WaitUntil(c, func(c *C) bool {
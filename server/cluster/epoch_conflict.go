@@ -0,0 +1,137 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/server/core"
+	"go.uber.org/zap"
+)
+
+// maxEpochConflicts is the number of the latest epoch conflicts to keep.
+const maxEpochConflicts = 1024
+
+// RegionEpochInfo is the epoch of a region in an epoch conflict.
+type RegionEpochInfo struct {
+	RegionID      uint64 `json:"region_id"`
+	StartKey      string `json:"start_key"`
+	EndKey        string `json:"end_key"`
+	Version       uint64 `json:"version"`
+	ConfVer       uint64 `json:"conf_ver"`
+	Term          uint64 `json:"term,omitempty"`
+	LeaderStoreID uint64 `json:"leader_store_id"`
+}
+
+func newRegionEpochInfo(region *core.RegionInfo) RegionEpochInfo {
+	return RegionEpochInfo{
+		RegionID:      region.GetID(),
+		StartKey:      core.HexRegionKeyStr(region.GetStartKey()),
+		EndKey:        core.HexRegionKeyStr(region.GetEndKey()),
+		Version:       region.GetRegionEpoch().GetVersion(),
+		ConfVer:       region.GetRegionEpoch().GetConfVer(),
+		Term:          region.GetTerm(),
+		LeaderStoreID: region.GetLeader().GetStoreId(),
+	}
+}
+
+// EpochConflict is a region heartbeat rejected because its epoch is staler
+// than the region in the cluster, such as the heartbeat of an isolated leader
+// after a network partition.
+type EpochConflict struct {
+	Time time.Time `json:"time"`
+	// StoreID is the store which reports the heartbeat.
+	StoreID uint64 `json:"store_id"`
+	// Reason is one of overlapped, stale-version, stale-conf-ver and
+	// stale-term.
+	Reason   string          `json:"reason"`
+	Reported RegionEpochInfo `json:"reported"`
+	// Current is the region in the cluster, which is a different region if
+	// the reported one overlaps with a newer region.
+	Current RegionEpochInfo `json:"current"`
+}
+
+// epochConflicts keeps the latest epoch conflicts in a ring buffer.
+type epochConflicts struct {
+	sync.Mutex
+	conflicts []*EpochConflict
+	next      int
+}
+
+func newEpochConflicts() *epochConflicts {
+	return &epochConflicts{conflicts: make([]*EpochConflict, 0, maxEpochConflicts)}
+}
+
+func (e *epochConflicts) record(conflict *EpochConflict) {
+	e.Lock()
+	defer e.Unlock()
+	if len(e.conflicts) < maxEpochConflicts {
+		e.conflicts = append(e.conflicts, conflict)
+		return
+	}
+	e.conflicts[e.next] = conflict
+	e.next = (e.next + 1) % maxEpochConflicts
+}
+
+// list returns the latest conflicts first. The conflicts are filtered by the
+// region or the reporting store if the ID is not 0, and at most limit of
+// them are returned if limit is positive.
+func (e *epochConflicts) list(regionID, storeID uint64, limit int) []*EpochConflict {
+	e.Lock()
+	defer e.Unlock()
+	res := make([]*EpochConflict, 0)
+	for i := 0; i < len(e.conflicts) && (limit <= 0 || len(res) < limit); i++ {
+		conflict := e.conflicts[(e.next+len(e.conflicts)-1-i)%len(e.conflicts)]
+		if regionID != 0 && conflict.Reported.RegionID != regionID && conflict.Current.RegionID != regionID {
+			continue
+		}
+		if storeID != 0 && conflict.StoreID != storeID {
+			continue
+		}
+		res = append(res, conflict)
+	}
+	return res
+}
+
+// recordEpochConflict records the conflict if the heartbeat of the region is
+// rejected for a stale epoch.
+func (c *RaftCluster) recordEpochConflict(region *core.RegionInfo, err error) {
+	staleErr, ok := err.(*core.RegionStaleError)
+	if !ok {
+		return
+	}
+	conflict := &EpochConflict{
+		Time:     time.Now(),
+		StoreID:  region.GetLeader().GetStoreId(),
+		Reason:   staleErr.Reason(),
+		Reported: newRegionEpochInfo(staleErr.Region),
+		Current:  newRegionEpochInfo(staleErr.Origin),
+	}
+	c.epochConflicts.record(conflict)
+	regionEventCounter.WithLabelValues("epoch_conflict").Inc()
+	logutil.ModuleDebug(logutil.RegionHeartbeatModule, "region heartbeat is rejected for stale epoch",
+		zap.Uint64("region-id", region.GetID()),
+		zap.Uint64("store-id", conflict.StoreID),
+		zap.String("reason", conflict.Reason),
+		zap.Uint64("current-region-id", conflict.Current.RegionID))
+}
+
+// GetEpochConflicts returns the latest epoch conflicts of the region
+// heartbeats, filtered by the region or the reporting store if the ID is not
+// 0. At most limit of them are returned if limit is positive.
+func (c *RaftCluster) GetEpochConflicts(regionID, storeID uint64, limit int) []*EpochConflict {
+	return c.epochConflicts.list(regionID, storeID, limit)
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+const heartbeatBudgetWindow = time.Second
+
+// heartbeatBudget tracks the time spent on handling the region heartbeats in
+// the current window. The time is the wall-clock latency of the handling
+// summed over the heartbeats, as Go cannot tell the CPU time of a goroutine,
+// so it also counts the time blocked on the locks and the fair queue.
+type heartbeatBudget struct {
+	sync.Mutex
+	windowStart time.Time
+	used        time.Duration
+}
+
+func (b *heartbeatBudget) resetLocked(now time.Time) {
+	if now.Sub(b.windowStart) >= heartbeatBudgetWindow {
+		b.windowStart = now
+		b.used = 0
+	}
+}
+
+// consume records the time spent and returns the used ratio of the budget.
+func (b *heartbeatBudget) consume(now time.Time, spent, limit time.Duration) float64 {
+	b.Lock()
+	defer b.Unlock()
+	b.resetLocked(now)
+	b.used += spent
+	return float64(b.used) / float64(limit)
+}
+
+// exhausted returns if the budget is used up in the current window.
+func (b *heartbeatBudget) exhausted(now time.Time, limit time.Duration) bool {
+	b.Lock()
+	defer b.Unlock()
+	b.resetLocked(now)
+	return b.used >= limit
+}
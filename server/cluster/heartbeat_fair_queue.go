@@ -0,0 +1,113 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tikv/pd/pkg/keyspace"
+	"github.com/tikv/pd/server/core"
+)
+
+// noneKeyspace is the queue key of the regions out of any keyspace.
+const noneKeyspace = "none"
+
+// heartbeatKeyspace returns the keyspace the region heartbeat is queued by.
+func heartbeatKeyspace(region *core.RegionInfo) string {
+	if id, ok := keyspace.KeyspaceOfKey(region.GetStartKey()); ok {
+		return strconv.FormatUint(uint64(id), 10)
+	}
+	return noneKeyspace
+}
+
+// heartbeatShare tracks the time spent on the region heartbeats of each
+// keyspace in the current window, and reports the share of each keyspace when
+// the window ends.
+type heartbeatShare struct {
+	sync.Mutex
+	windowStart time.Time
+	used        map[string]time.Duration
+	// reported are the keyspaces reported in the last window.
+	reported map[string]struct{}
+}
+
+func (s *heartbeatShare) consume(now time.Time, ks string, spent time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	if now.Sub(s.windowStart) >= heartbeatBudgetWindow {
+		s.reportLocked()
+		s.windowStart = now
+		s.used = make(map[string]time.Duration)
+	}
+	s.used[ks] += spent
+}
+
+func (s *heartbeatShare) reportLocked() {
+	var total time.Duration
+	for _, used := range s.used {
+		total += used
+	}
+	reported := make(map[string]struct{}, len(s.used))
+	for ks, used := range s.used {
+		if total > 0 {
+			regionHeartbeatKeyspaceShareGauge.WithLabelValues(ks).Set(float64(used) / float64(total))
+			reported[ks] = struct{}{}
+		}
+	}
+	for ks := range s.reported {
+		if _, ok := reported[ks]; !ok {
+			regionHeartbeatKeyspaceShareGauge.DeleteLabelValues(ks)
+		}
+	}
+	s.reported = reported
+}
+
+// heartbeatQueueCapacity returns the capacity of the fair queue with the
+// concurrency. The queue is unbounded if it is disabled, so the heartbeats
+// still waiting in it are released at once.
+func heartbeatQueueCapacity(concurrency int) int {
+	if concurrency <= 0 {
+		return math.MaxInt32
+	}
+	return concurrency
+}
+
+// SetRegionHeartbeatFairQueueConcurrency is called when the concurrency of the
+// fair queue is changed.
+func (c *RaftCluster) SetRegionHeartbeatFairQueueConcurrency(concurrency int) {
+	c.hbQueue.SetCapacity(heartbeatQueueCapacity(concurrency))
+}
+
+// enterHeartbeatQueue waits for the turn of the keyspace in the fair queue,
+// and returns the function to call when the heartbeat is handled. A keyspace
+// gets the same number of turns as the others, but its heartbeats may take a
+// longer time, and a bulk heartbeat takes a single turn for all its regions
+// of the keyspace.
+func (c *RaftCluster) enterHeartbeatQueue(ks string) (func(), error) {
+	waitStart := time.Now()
+	release, err := c.hbQueue.Acquire(c.ctx, ks)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	regionHeartbeatFairQueueWaitHistogram.Observe(start.Sub(waitStart).Seconds())
+	return func() {
+		now := time.Now()
+		c.hbShare.consume(now, ks, now.Sub(start))
+		release()
+	}, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+)
+
+// MaintenanceBudget shows how many failure domains can be in maintenance at
+// the same time, and the failure domains which have maintenance windows.
+type MaintenanceBudget struct {
+	// DomainLabel is the top-level location label which divides the stores
+	// into failure domains. Each store is a failure domain of its own if it
+	// is empty.
+	DomainLabel string               `json:"domain_label,omitempty"`
+	Budget      int                  `json:"budget"`
+	Domains     []*MaintenanceDomain `json:"domains"`
+}
+
+// MaintenanceDomain is a failure domain with the maintenance windows of its
+// stores. StartTime and EndTime span all of the windows.
+type MaintenanceDomain struct {
+	Name      string    `json:"name"`
+	StoreIDs  []uint64  `json:"store_ids"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// maintenanceBudget returns how many failure domains can be in maintenance at
+// the same time while the regions keep a quorum of replicas, which is 1 when
+// there are 3 replicas.
+func (c *RaftCluster) maintenanceBudget() int {
+	return (c.opt.GetMaxReplicas() - 1) / 2
+}
+
+func (c *RaftCluster) maintenanceDomainLabel() string {
+	if labels := c.opt.GetLocationLabels(); len(labels) > 0 {
+		return labels[0]
+	}
+	return ""
+}
+
+// maintenanceDomain returns the failure domain of the store.
+func maintenanceDomain(store *core.StoreInfo, label string) string {
+	if label != "" {
+		if value := store.GetLabelValue(label); value != "" {
+			return label + "=" + value
+		}
+	}
+	return fmt.Sprintf("store-%d", store.GetID())
+}
+
+func maintenanceOverlaps(a, b *core.StoreMaintenance) bool {
+	return a.StartTime.Before(b.EndTime) && b.StartTime.Before(a.EndTime)
+}
+
+// checkMaintenanceBudgetLocked checks if the stores can take the maintenance
+// window. The failure domains of the stores, together with the failure domains
+// having other windows overlapped with the given one, should not exceed the
+// budget. It is conservative as the other windows may not overlap each other.
+func (c *RaftCluster) checkMaintenanceBudgetLocked(stores []*core.StoreInfo, maintenance *core.StoreMaintenance) error {
+	label := c.maintenanceDomainLabel()
+	domains := make(map[string]struct{})
+	replaced := make(map[uint64]struct{}, len(stores))
+	for _, store := range stores {
+		replaced[store.GetID()] = struct{}{}
+		domains[maintenanceDomain(store, label)] = struct{}{}
+	}
+	now := time.Now()
+	for _, store := range c.GetStores() {
+		if _, ok := replaced[store.GetID()]; ok || store.IsTombstone() {
+			continue
+		}
+		window := store.GetMaintenance()
+		if window == nil || window.IsExpired(now) || !maintenanceOverlaps(window, maintenance) {
+			continue
+		}
+		domains[maintenanceDomain(store, label)] = struct{}{}
+	}
+	if budget := c.maintenanceBudget(); len(domains) > budget {
+		return errs.ErrMaintenanceBudgetExceeded.FastGenByArgs(len(domains), budget)
+	}
+	return nil
+}
+
+// GetMaintenanceBudget returns the maintenance budget and the failure domains
+// which have maintenance windows not ended yet.
+func (c *RaftCluster) GetMaintenanceBudget() *MaintenanceBudget {
+	c.RLock()
+	defer c.RUnlock()
+
+	label := c.maintenanceDomainLabel()
+	budget := &MaintenanceBudget{
+		DomainLabel: label,
+		Budget:      c.maintenanceBudget(),
+		Domains:     []*MaintenanceDomain{},
+	}
+	domains := make(map[string]*MaintenanceDomain)
+	for _, store := range c.GetStores() {
+		if store.IsTombstone() || !store.HasPlannedMaintenance() {
+			continue
+		}
+		window := store.GetMaintenance()
+		name := maintenanceDomain(store, label)
+		domain, ok := domains[name]
+		if !ok {
+			domain = &MaintenanceDomain{Name: name, StartTime: window.StartTime, EndTime: window.EndTime}
+			domains[name] = domain
+			budget.Domains = append(budget.Domains, domain)
+		}
+		domain.StoreIDs = append(domain.StoreIDs, store.GetID())
+		if window.StartTime.Before(domain.StartTime) {
+			domain.StartTime = window.StartTime
+		}
+		if window.EndTime.After(domain.EndTime) {
+			domain.EndTime = window.EndTime
+		}
+	}
+	for _, domain := range budget.Domains {
+		sort.Slice(domain.StoreIDs, func(i, j int) bool { return domain.StoreIDs[i] < domain.StoreIDs[j] })
+	}
+	sort.Slice(budget.Domains, func(i, j int) bool { return budget.Domains[i].Name < budget.Domains[j].Name })
+	return budget
+}
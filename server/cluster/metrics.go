@@ -32,6 +32,14 @@ var (
 			Help:      "Counter of the region event",
 		}, []string{"event"})
 
+	splitReportCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "split_report_total",
+			Help:      "Counter of the split reports",
+		}, []string{"type", "result"})
+
 	schedulerStatusGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -78,10 +86,36 @@ var (
 			Name:      "region_waiting_list",
 			Help:      "Number of region in waiting list",
 		})
+
+	regionHeartbeatBudgetUsageGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_heartbeat_budget_usage",
+			Help:      "Used ratio of the latency budget of the region heartbeats in the current second",
+		})
+
+	regionHeartbeatKeyspaceShareGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_heartbeat_keyspace_share",
+			Help:      "Share of the time spent on the region heartbeats of each keyspace in the last second",
+		}, []string{"keyspace"})
+
+	regionHeartbeatFairQueueWaitHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_heartbeat_fair_queue_wait_duration_seconds",
+			Help:      "Bucketed histogram of the time the region heartbeats wait in the fair queue",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+		})
 )
 
 func init() {
 	prometheus.MustRegister(regionEventCounter)
+	prometheus.MustRegister(splitReportCounter)
 	prometheus.MustRegister(healthStatusGauge)
 	prometheus.MustRegister(schedulerStatusGauge)
 	prometheus.MustRegister(hotSpotStatusGauge)
@@ -89,4 +123,7 @@ func init() {
 	prometheus.MustRegister(clusterStateCPUGauge)
 	prometheus.MustRegister(clusterStateCurrent)
 	prometheus.MustRegister(regionWaitingListGauge)
+	prometheus.MustRegister(regionHeartbeatBudgetUsageGauge)
+	prometheus.MustRegister(regionHeartbeatKeyspaceShareGauge)
+	prometheus.MustRegister(regionHeartbeatFairQueueWaitHistogram)
 }
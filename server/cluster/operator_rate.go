@@ -0,0 +1,173 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/statistics"
+)
+
+const (
+	// checkersEstimateName is the name of the estimate of the operators
+	// generated by the checkers to fix the rule violations.
+	checkersEstimateName = "checkers"
+	// estimatedLeaderOperatorDuration is the assumed duration of an operator
+	// which only transfers leaders, used to bound them by the schedule limit.
+	estimatedLeaderOperatorDuration = time.Second
+)
+
+// The reasons why an estimate is limited.
+const (
+	limitedByPaused        = "paused"
+	limitedByNotAllowed    = "not-allowed"
+	limitedByScheduleLimit = "schedule-limit"
+	limitedByStoreLimit    = "store-limit"
+)
+
+// OperatorRateEstimate is the estimated number of operators generated by a
+// scheduler or the checkers in the next hour, and the data they move.
+type OperatorRateEstimate struct {
+	Name string `json:"name"`
+	// OperatorsPerRound is the number of operators generated by one round of
+	// scheduling. For the checkers it is the number of regions to be fixed.
+	OperatorsPerRound int     `json:"operators_per_round"`
+	OperatorsPerHour  float64 `json:"operators_per_hour"`
+	// MovedSizePerHour is the approximate size of the data moved in MB.
+	MovedSizePerHour float64 `json:"moved_size_per_hour"`
+	// LimitedBy is the reason why the estimate is lower than what the
+	// scheduler generates, empty if it is not limited.
+	LimitedBy string `json:"limited_by,omitempty"`
+}
+
+// OperatorRateEstimates is the estimated operators of all the schedulers and
+// the checkers in the next hour.
+type OperatorRateEstimates struct {
+	// AddPeerCapacityPerHour is the number of peers that can be added to all
+	// the stores in an hour under the store limits.
+	AddPeerCapacityPerHour float64                 `json:"add_peer_capacity_per_hour"`
+	Estimates              []*OperatorRateEstimate `json:"estimates"`
+	OperatorsPerHour       float64                 `json:"operators_per_hour"`
+	MovedSizePerHour       float64                 `json:"moved_size_per_hour"`
+}
+
+func (e *OperatorRateEstimates) add(est *OperatorRateEstimate) {
+	e.Estimates = append(e.Estimates, est)
+	e.OperatorsPerHour += est.OperatorsPerHour
+	e.MovedSizePerHour += est.MovedSizePerHour
+}
+
+// estimateOperatorRates estimates the operators generated in the next hour
+// from the current cluster state. The checkers come first, and then each
+// scheduler is assumed to generate the same operators as a dry run every
+// minimal interval, bounded by the schedule limits and the add-peer store
+// limits left by the estimates before it. It is an upper bound, since the
+// schedulers generate less as the cluster becomes balanced.
+func (c *coordinator) estimateOperatorRates() (*OperatorRateEstimates, error) {
+	c.RLock()
+	if c.cluster == nil {
+		c.RUnlock()
+		return nil, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	type schedulerInfo struct {
+		name        string
+		paused      bool
+		minInterval time.Duration
+	}
+	schedulers := make([]schedulerInfo, 0, len(c.schedulers))
+	for name, s := range c.schedulers {
+		schedulers = append(schedulers, schedulerInfo{name: name, paused: s.IsPaused(), minInterval: s.GetMinInterval()})
+	}
+	c.RUnlock()
+	sort.Slice(schedulers, func(i, j int) bool { return schedulers[i].name < schedulers[j].name })
+
+	opt := c.cluster.GetOpts()
+	result := &OperatorRateEstimates{Estimates: []*OperatorRateEstimate{}}
+	for _, store := range c.cluster.GetStores() {
+		if store.IsUp() {
+			result.AddPeerCapacityPerHour += opt.GetStoreLimitByType(store.GetID(), storelimit.AddPeer) / schedule.StoreBalanceBaseTime * time.Hour.Seconds()
+		}
+	}
+	capacity := result.AddPeerCapacityPerHour
+	regionSize := float64(c.cluster.GetAverageRegionSize())
+
+	// Each region missing a peer or with an offline peer needs a new peer, and
+	// each region with an extra peer needs a peer removed.
+	addPeers := len(c.cluster.GetRegionStatsByType(statistics.MissPeer)) + len(c.cluster.GetOfflineRegionStatsByType(statistics.OfflinePeer))
+	removePeers := len(c.cluster.GetRegionStatsByType(statistics.ExtraPeer))
+	checkers := &OperatorRateEstimate{Name: checkersEstimateName, OperatorsPerRound: addPeers + removePeers}
+	added := float64(addPeers)
+	if added > capacity {
+		added, checkers.LimitedBy = capacity, limitedByStoreLimit
+	}
+	capacity -= added
+	checkers.OperatorsPerHour = added + float64(removePeers)
+	checkers.MovedSizePerHour = added * regionSize
+	result.add(checkers)
+
+	for _, s := range schedulers {
+		est := &OperatorRateEstimate{Name: s.name}
+		if s.paused {
+			est.LimitedBy = limitedByPaused
+			result.add(est)
+			continue
+		}
+		ops, allowed, err := c.dryRunScheduler(s.name)
+		if err != nil {
+			return nil, err
+		}
+		est.OperatorsPerRound = len(ops)
+		if !allowed {
+			est.LimitedBy = limitedByNotAllowed
+			result.add(est)
+			continue
+		}
+		var leaderOps, regionOps int
+		var movedSize float64
+		for _, op := range ops {
+			if op.Kind()&operator.OpRegion != 0 {
+				regionOps++
+				if region := c.cluster.GetRegion(op.RegionID()); region != nil {
+					movedSize += float64(region.GetApproximateSize())
+				}
+			} else {
+				leaderOps++
+			}
+		}
+		if s.minInterval <= 0 {
+			s.minInterval = time.Second
+		}
+		rounds := float64(time.Hour) / float64(s.minInterval)
+		leaders := float64(leaderOps) * rounds
+		if maxLeaders := float64(opt.GetLeaderScheduleLimit()) * float64(time.Hour/estimatedLeaderOperatorDuration); leaders > maxLeaders {
+			leaders, est.LimitedBy = maxLeaders, limitedByScheduleLimit
+		}
+		regions := float64(regionOps) * rounds
+		if regions > capacity {
+			regions, est.LimitedBy = capacity, limitedByStoreLimit
+		}
+		capacity -= regions
+		est.OperatorsPerHour = leaders + regions
+		if regionOps > 0 {
+			est.MovedSizePerHour = regions * movedSize / float64(regionOps)
+		}
+		result.add(est)
+	}
+	return result, nil
+}
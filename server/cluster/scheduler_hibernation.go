@@ -0,0 +1,148 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/eventbus"
+	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/server/schedulers"
+	"go.uber.org/zap"
+)
+
+const (
+	// schedulerHibernateIdleRounds is the number of the consecutive rounds
+	// finding nothing to schedule before a scheduler hibernates.
+	schedulerHibernateIdleRounds = 16
+	schedulerWakeSubscriber      = "scheduler-hibernation"
+	schedulerWakeBufferSize      = 64
+)
+
+// isClusterIdle returns whether there is nothing for the schedulers to do
+// but waiting for the cluster to change, which means no operator is running
+// or waiting, no region is waiting to be checked, and the last round of the
+// patrol found no region to fix.
+func (c *coordinator) isClusterIdle() bool {
+	return atomic.LoadInt32(&c.patrolClean) == 1 &&
+		len(c.opController.GetOperators()) == 0 &&
+		len(c.opController.GetWaitingOperators()) == 0 &&
+		len(c.cluster.GetSuspectRegions()) == 0 &&
+		len(c.checkers.GetWaitingRegions()) == 0
+}
+
+// watchSchedulerWakeEvents wakes up the hibernating schedulers when the
+// stores, the regions or the config are changed. The regions are only
+// subscribed while the hibernation is enabled, as the region syncer does not
+// publish the changed regions if nobody subscribes them. The store events
+// are published when a store is put or deleted by the cluster, but not for
+// the store heartbeats which only update the statistics, whose changes are
+// found by the hibernating schedulers in their next round.
+func (c *coordinator) watchSchedulerWakeEvents() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+
+	bus := c.cluster.eventBus
+	if bus == nil {
+		return
+	}
+	for {
+		enabled := c.cluster.opt.GetSchedulerHibernateInterval() > 0
+		topics := []eventbus.Topic{eventbus.TopicStore, eventbus.TopicConfig}
+		if enabled {
+			topics = append(topics, eventbus.TopicRegion)
+		}
+		sub := bus.Subscribe(schedulerWakeSubscriber, schedulerWakeBufferSize, topics...)
+	recv:
+		for {
+			select {
+			case event := <-sub.Events():
+				c.wakeSchedulers(string(event.Topic))
+				if event.Topic == eventbus.TopicConfig && (c.cluster.opt.GetSchedulerHibernateInterval() > 0) != enabled {
+					// Resubscribes the regions as the hibernation is turned on or off.
+					sub.Close()
+					break recv
+				}
+			case <-sub.Stopped():
+				// Some events are lost, so wakes up the schedulers anyway.
+				c.wakeSchedulers("resubscribe")
+				break recv
+			case <-c.ctx.Done():
+				sub.Close()
+				return
+			}
+		}
+	}
+}
+
+// wakeSchedulers wakes up the hibernating schedulers. The patrol has to go
+// through a whole round again before they can hibernate next time.
+func (c *coordinator) wakeSchedulers(reason string) {
+	atomic.StoreInt32(&c.patrolClean, 0)
+	c.RLock()
+	defer c.RUnlock()
+	for _, s := range c.schedulers {
+		if s.IsHibernating() {
+			log.Debug("wake up the scheduler", zap.String("scheduler-name", s.GetName()), zap.String("reason", reason))
+			s.Wake()
+		}
+	}
+}
+
+// canHibernate returns whether the scheduler can hibernate with the
+// interval. The hot region schedulers follow the flow of the regions, whose
+// changes are not published as events, so they never hibernate.
+func (s *scheduleController) canHibernate(interval time.Duration) bool {
+	switch s.GetType() {
+	case schedulers.HotRegionType, schedulers.HotReadRegionType, schedulers.HotWriteRegionType:
+		return false
+	}
+	if interval <= s.nextInterval || !s.coordinator.isClusterIdle() {
+		return false
+	}
+	return s.IsHibernating() || s.idleRounds >= schedulerHibernateIdleRounds
+}
+
+// hibernate makes the scheduler schedule with the interval.
+func (s *scheduleController) hibernate(interval time.Duration) {
+	s.nextInterval = interval
+	if atomic.CompareAndSwapInt32(&s.hibernating, 0, 1) {
+		log.Info("scheduler hibernates", zap.String("scheduler-name", s.GetName()), zap.Duration("interval", interval))
+	}
+}
+
+// IsHibernating returns whether the scheduler is hibernating.
+func (s *scheduleController) IsHibernating() bool {
+	return atomic.LoadInt32(&s.hibernating) == 1
+}
+
+// Wake wakes up the scheduler to schedule at once if it is hibernating.
+func (s *scheduleController) Wake() {
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// wakeUp resets the scheduler to schedule with the minimal interval. It is
+// called by the goroutine running the scheduler.
+func (s *scheduleController) wakeUp() {
+	s.nextInterval = s.Scheduler.GetMinInterval()
+	s.idleRounds = 0
+	if atomic.CompareAndSwapInt32(&s.hibernating, 1, 0) {
+		log.Info("scheduler wakes up", zap.String("scheduler-name", s.GetName()))
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"go.uber.org/zap"
+)
+
+// splitReportWindow is how long a processed split report is remembered, so
+// that its retries are not processed again, even after the leader changes.
+const splitReportWindow = 10 * time.Minute
+
+// splitReports remembers the split reports processed in the window. They are
+// persisted so that the new leader knows the reports processed by the old one.
+// The reports are persisted by flush in the background jobs rather than when
+// they are recorded, so that the split reports do not wait for the storage.
+type splitReports struct {
+	sync.Mutex
+	storage *core.Storage
+	expires map[string]time.Time
+	nextGC  time.Time
+	// pending are the reports to save, and removed are the ones to delete.
+	pending map[string]time.Time
+	removed []string
+}
+
+func newSplitReports(storage *core.Storage) *splitReports {
+	return &splitReports{
+		storage: storage,
+		expires: make(map[string]time.Time),
+		pending: make(map[string]time.Time),
+	}
+}
+
+// splitReportKey identifies a split by the origin region, its epoch version
+// after the split, and the IDs of the new regions.
+func splitReportKey(origin *metapb.Region, newRegions []*metapb.Region) string {
+	ids := make([]string, 0, len(newRegions))
+	for _, region := range newRegions {
+		ids = append(ids, fmt.Sprint(region.GetId()))
+	}
+	return fmt.Sprintf("%d-%d-%s", origin.GetId(), origin.GetRegionEpoch().GetVersion(), strings.Join(ids, "-"))
+}
+
+// load loads the reports persisted by the former leaders.
+func (r *splitReports) load(now time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+	err := r.storage.LoadSplitReports(func(key string, expire time.Time) {
+		r.expires[key] = expire
+	})
+	if err != nil {
+		return err
+	}
+	r.gcLocked(now)
+	return nil
+}
+
+// record records the report of the key and returns true, or returns false if
+// the report has been processed in the window.
+func (r *splitReports) record(key string, now time.Time) bool {
+	r.Lock()
+	defer r.Unlock()
+	r.gcLocked(now)
+	if expire, ok := r.expires[key]; ok && now.Before(expire) {
+		return false
+	}
+	expire := now.Add(splitReportWindow)
+	r.expires[key] = expire
+	r.pending[key] = expire
+	return true
+}
+
+// gcLocked drops the expired reports, at most once per window.
+func (r *splitReports) gcLocked(now time.Time) {
+	if now.Before(r.nextGC) {
+		return
+	}
+	r.nextGC = now.Add(splitReportWindow)
+	for key, expire := range r.expires {
+		if !now.Before(expire) {
+			delete(r.expires, key)
+			if _, ok := r.pending[key]; ok {
+				delete(r.pending, key)
+				continue
+			}
+			r.removed = append(r.removed, key)
+		}
+	}
+}
+
+// flush persists the reports recorded and deletes the ones expired since the
+// last flush. The reports are still deduplicated by this leader if they fail
+// to persist.
+func (r *splitReports) flush() {
+	r.Lock()
+	pending, removed := r.pending, r.removed
+	r.pending, r.removed = make(map[string]time.Time), nil
+	r.Unlock()
+
+	// Delete first, as an expired report may be recorded again.
+	for _, key := range removed {
+		if err := r.storage.DeleteSplitReport(key); err != nil {
+			log.Warn("failed to delete split report", zap.String("key", key), errs.ZapError(err))
+		}
+	}
+	for key, expire := range pending {
+		if err := r.storage.SaveSplitReport(key, expire); err != nil {
+			log.Warn("failed to save split report", zap.String("key", key), errs.ZapError(err))
+		}
+	}
+}
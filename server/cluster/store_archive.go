@@ -0,0 +1,126 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"go.uber.org/zap"
+)
+
+// archiveTombstoneStores archives the tombstone stores kept longer than the
+// retention. The time a store becomes tombstone is only tracked in memory,
+// so the retention of the existing tombstone stores restarts when the leader
+// changes, and a store is never archived earlier than the retention.
+func (c *RaftCluster) archiveTombstoneStores(now time.Time) {
+	retention := c.opt.GetTombstoneStoreRetention()
+	if retention <= 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for _, store := range c.GetStores() {
+		if !store.IsTombstone() || store.GetRegionCount() > 0 {
+			continue
+		}
+		since, ok := c.tombstoneTimes[store.GetID()]
+		if !ok {
+			c.tombstoneTimes[store.GetID()] = now
+			continue
+		}
+		if now.Sub(since) < retention {
+			continue
+		}
+		if err := c.archiveStoreLocked(store, since, now); err != nil {
+			log.Error("failed to archive tombstone store",
+				zap.Stringer("store", store.GetMeta()),
+				errs.ZapError(err))
+		}
+	}
+}
+
+func (c *RaftCluster) archiveStoreLocked(store *core.StoreInfo, since, now time.Time) error {
+	// The store is saved to the archive before it is deleted, so it is not
+	// lost if PD fails in between.
+	archived := &core.ArchivedStore{
+		Store:         store.GetMeta(),
+		TombstoneTime: since,
+		ArchiveTime:   now,
+	}
+	if err := c.storage.SaveArchivedStore(archived); err != nil {
+		return err
+	}
+	if err := c.deleteStoreLocked(store); err != nil {
+		return err
+	}
+	c.RemoveStoreLimit(store.GetID())
+	delete(c.tombstoneTimes, store.GetID())
+	log.Info("tombstone store has been archived",
+		zap.Uint64("store-id", store.GetID()),
+		zap.String("store-address", store.GetAddress()),
+		zap.Time("tombstone-time", since))
+	return nil
+}
+
+// GetArchivedStores returns the archived tombstone stores.
+func (c *RaftCluster) GetArchivedStores() ([]*core.ArchivedStore, error) {
+	stores := make([]*core.ArchivedStore, 0)
+	err := c.storage.LoadArchivedStores(func(store *core.ArchivedStore) {
+		stores = append(stores, store)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stores, nil
+}
+
+// IsStoreArchived returns whether the store is archived.
+func (c *RaftCluster) IsStoreArchived(storeID uint64) (bool, error) {
+	archived, err := c.storage.LoadArchivedStore(storeID)
+	if err != nil {
+		return false, err
+	}
+	return archived != nil, nil
+}
+
+// RestoreArchivedStore moves an archived store back to the cluster as a
+// tombstone store. Its retention restarts from now.
+func (c *RaftCluster) RestoreArchivedStore(storeID uint64) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.GetStore(storeID) != nil {
+		return errs.ErrArchivedStoreExists.FastGenByArgs(storeID)
+	}
+	archived, err := c.storage.LoadArchivedStore(storeID)
+	if err != nil {
+		return err
+	}
+	if archived == nil {
+		return errs.ErrArchivedStoreNotFound.FastGenByArgs(storeID)
+	}
+	if err := c.putStoreLocked(core.NewStoreInfo(archived.Store)); err != nil {
+		return err
+	}
+	if err := c.storage.DeleteArchivedStore(storeID); err != nil {
+		return err
+	}
+	c.tombstoneTimes[storeID] = time.Now()
+	log.Info("archived store has been restored",
+		zap.Uint64("store-id", storeID),
+		zap.String("store-address", archived.Store.GetAddress()))
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/pkg/keyspace"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
+)
+
+// StoreFilter selects the stores by the keyspace and the labels.
+type StoreFilter struct {
+	// KeyspaceID selects the stores which can hold the peers of the keyspace
+	// if it is not nil.
+	KeyspaceID *uint32
+	// Labels selects the stores which have all the labels.
+	Labels []*metapb.StoreLabel
+}
+
+// FilterMetaStores returns the stores selected by the filter. A store can hold
+// the peers of a keyspace if it matches the label constraints of any rule
+// applied to the key ranges of the keyspace. If the placement rules are
+// disabled, all the stores without exclusive labels, such as TiFlash, can.
+func (c *RaftCluster) FilterMetaStores(stores []*metapb.Store, f *StoreFilter) []*metapb.Store {
+	if f.KeyspaceID == nil && len(f.Labels) == 0 {
+		return stores
+	}
+	var rules []*placement.Rule
+	if f.KeyspaceID != nil {
+		if c.opt.IsPlacementRulesEnabled() {
+			for _, kr := range keyspace.MakeKeyRanges(*f.KeyspaceID) {
+				rules = append(rules, c.ruleManager.GetRulesInRange(kr.StartKey, kr.EndKey)...)
+			}
+		} else {
+			rules = []*placement.Rule{{}}
+		}
+	}
+	res := make([]*metapb.Store, 0, len(stores))
+	for _, meta := range stores {
+		store := c.GetStore(meta.GetId())
+		if store == nil || !hasLabels(store, f.Labels) {
+			continue
+		}
+		if f.KeyspaceID != nil && !matchAnyRule(store, rules) {
+			continue
+		}
+		res = append(res, meta)
+	}
+	return res
+}
+
+func hasLabels(store *core.StoreInfo, labels []*metapb.StoreLabel) bool {
+	for _, label := range labels {
+		if store.GetLabelValue(label.GetKey()) != label.GetValue() {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAnyRule(store *core.StoreInfo, rules []*placement.Rule) bool {
+	for _, rule := range rules {
+		if placement.MatchLabelConstraints(store, rule.LabelConstraints) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import "sync"
+
+// storeHeartbeatShardCount is the number of shards of the store heartbeats.
+const storeHeartbeatShardCount = 64
+
+// storeHeartbeatShards serializes the heartbeats of the same store, so that a
+// heartbeat does not overwrite a newer one of the store, while the heartbeats
+// of the stores in different shards are handled concurrently.
+type storeHeartbeatShards struct {
+	locks [storeHeartbeatShardCount]sync.Mutex
+}
+
+// lock locks the shard of the store, and returns the function to unlock it.
+func (s *storeHeartbeatShards) lock(storeID uint64) func() {
+	mu := &s.locks[storeID%storeHeartbeatShardCount]
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,259 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
+	"go.uber.org/zap"
+)
+
+// StoreOfflinePlan is the capacity reserved on the remaining stores to take
+// a store offline. It is kept until the store is buried or up again, and the
+// reserved capacity is not counted as available for the other offline stores.
+// The stores whose room is used up by the reservations are not selected as the
+// targets of the other regions. The plan is recomputed from the remaining
+// regions of the store periodically, so the reservations shrink as the regions
+// are moved away.
+type StoreOfflinePlan struct {
+	StoreID     uint64 `json:"store_id"`
+	RegionCount int    `json:"region_count"`
+	// Size is the approximate size of the regions to move in MB.
+	Size int64 `json:"size"`
+	// Reservations is the capacity reserved on each target store in MB.
+	Reservations map[uint64]int64 `json:"reservations"`
+	// UnplacedRegionCount is the number of the regions which cannot be placed,
+	// it is only non-zero when the store is physically destroyed.
+	UnplacedRegionCount int       `json:"unplaced_region_count"`
+	CreateTime          time.Time `json:"create_time"`
+}
+
+func (p *StoreOfflinePlan) sameReservations(other *StoreOfflinePlan) bool {
+	if len(p.Reservations) != len(other.Reservations) {
+		return false
+	}
+	for id, size := range p.Reservations {
+		if other.Reservations[id] != size {
+			return false
+		}
+	}
+	return true
+}
+
+type storeOfflinePlans struct {
+	sync.RWMutex
+	plans   map[uint64]*StoreOfflinePlan
+	storage *core.Storage
+	// planMu serializes the planning, which runs without the lock of the
+	// cluster, so that two plans do not reserve the same capacity.
+	planMu sync.Mutex
+}
+
+func newStoreOfflinePlans(storage *core.Storage) *storeOfflinePlans {
+	return &storeOfflinePlans{
+		plans:   make(map[uint64]*StoreOfflinePlan),
+		storage: storage,
+	}
+}
+
+// load loads the plans from storage. The plans which cannot be decoded are
+// skipped, and are created again by the next refresh.
+func (p *storeOfflinePlans) load() error {
+	p.Lock()
+	defer p.Unlock()
+	return p.storage.LoadStoreOfflinePlans(func(k, v string) {
+		plan := &StoreOfflinePlan{}
+		if err := json.Unmarshal([]byte(v), plan); err != nil {
+			log.Warn("failed to load the store offline plan", zap.String("store-id", k), errs.ZapError(errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()))
+			return
+		}
+		p.plans[plan.StoreID] = plan
+	})
+}
+
+func (p *storeOfflinePlans) get(storeID uint64) *StoreOfflinePlan {
+	p.RLock()
+	defer p.RUnlock()
+	return p.plans[storeID]
+}
+
+func (p *storeOfflinePlans) storeIDs() []uint64 {
+	p.RLock()
+	defer p.RUnlock()
+	ids := make([]uint64, 0, len(p.plans))
+	for id := range p.plans {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// put keeps the plan, and saves it to storage. The plan takes effect even if
+// it fails to be saved.
+func (p *storeOfflinePlans) put(plan *StoreOfflinePlan) error {
+	p.Lock()
+	p.plans[plan.StoreID] = plan
+	p.Unlock()
+	return p.storage.SaveStoreOfflinePlan(plan.StoreID, plan)
+}
+
+func (p *storeOfflinePlans) delete(storeID uint64) {
+	if p.get(storeID) == nil {
+		return
+	}
+	if err := p.storage.DeleteStoreOfflinePlan(storeID); err != nil {
+		log.Warn("failed to delete the store offline plan", zap.Uint64("store-id", storeID), errs.ZapError(err))
+	}
+	p.Lock()
+	defer p.Unlock()
+	delete(p.plans, storeID)
+}
+
+// reserved returns the capacity reserved on each store by the plans of the
+// other stores.
+func (p *storeOfflinePlans) reserved(excludeStoreID uint64) map[uint64]int64 {
+	p.RLock()
+	defer p.RUnlock()
+	reserved := make(map[uint64]int64)
+	for id, plan := range p.plans {
+		if id == excludeStoreID {
+			continue
+		}
+		for target, size := range plan.Reservations {
+			reserved[target] += size
+		}
+	}
+	return reserved
+}
+
+// planStoreOffline places the regions of the store on the remaining up stores
+// without making them low space, under the placement rules if they are
+// enabled. Each region is placed on the candidate with the most room left, and
+// the regions which cannot be placed are returned as unplaced. It runs without
+// the lock of the cluster, and should be called with planMu held.
+func (c *RaftCluster) planStoreOffline(storeID uint64) (plan *StoreOfflinePlan, unplacedSize int64) {
+	plan = &StoreOfflinePlan{
+		StoreID:      storeID,
+		Reservations: make(map[uint64]int64),
+		CreateTime:   time.Now(),
+	}
+	reserved := c.offlinePlans.reserved(storeID)
+	lowSpaceRatio := c.opt.GetLowSpaceRatio()
+	var candidates []*core.StoreInfo
+	room := make(map[uint64]int64)
+	for _, store := range c.GetStores() {
+		if store.GetID() == storeID || !store.IsUp() {
+			continue
+		}
+		// The store which has not reported its capacity yet is not limited.
+		if store.GetCapacity() == 0 {
+			room[store.GetID()] = math.MaxInt64
+		} else {
+			// The store is low space if its available ratio is less than 1-lowSpaceRatio.
+			free := float64(store.GetAvailable()) - float64(store.GetCapacity())*(1-lowSpaceRatio)
+			room[store.GetID()] = int64(free/(1<<20)) - reserved[store.GetID()]
+		}
+		candidates = append(candidates, store)
+	}
+
+	rulesEnabled := c.opt.IsPlacementRulesEnabled()
+	for _, region := range c.core.GetStoreRegions(storeID) {
+		size := region.GetApproximateSize()
+		plan.RegionCount++
+		plan.Size += size
+		var constraints []placement.LabelConstraint
+		if rulesEnabled {
+			constraints = c.offlinePeerConstraints(region, storeID)
+		}
+		var target *core.StoreInfo
+		for _, store := range candidates {
+			if region.GetStorePeer(store.GetID()) != nil || room[store.GetID()] < size ||
+				!placement.MatchLabelConstraints(store, constraints) {
+				continue
+			}
+			if target == nil || room[store.GetID()] > room[target.GetID()] {
+				target = store
+			}
+		}
+		if target == nil {
+			plan.UnplacedRegionCount++
+			unplacedSize += size
+			continue
+		}
+		room[target.GetID()] -= size
+		plan.Reservations[target.GetID()] += size
+	}
+	return plan, unplacedSize
+}
+
+// offlinePeerConstraints returns the label constraints of the rule which the
+// peer on the store is fitted to.
+func (c *RaftCluster) offlinePeerConstraints(region *core.RegionInfo, storeID uint64) []placement.LabelConstraint {
+	fit := c.ruleManager.FitRegion(c, region)
+	for _, rf := range fit.RuleFits {
+		for _, peer := range rf.Peers {
+			if peer.GetStoreId() == storeID {
+				return rf.Rule.LabelConstraints
+			}
+		}
+	}
+	return nil
+}
+
+// refreshStoreOfflinePlans recomputes the plans from the remaining regions of
+// the offline stores, drops the plans of the stores which are no longer
+// offline, and applies the reservations to the stores. The plans are saved
+// only if their reservations are changed.
+func (c *RaftCluster) refreshStoreOfflinePlans() {
+	c.offlinePlans.planMu.Lock()
+	defer c.offlinePlans.planMu.Unlock()
+	for _, storeID := range c.offlinePlans.storeIDs() {
+		store := c.GetStore(storeID)
+		if store == nil || !store.IsOffline() {
+			c.offlinePlans.delete(storeID)
+			continue
+		}
+		old := c.offlinePlans.get(storeID)
+		plan, _ := c.planStoreOffline(storeID)
+		plan.CreateTime = old.CreateTime
+		if plan.sameReservations(old) && plan.RegionCount == old.RegionCount {
+			continue
+		}
+		if err := c.offlinePlans.put(plan); err != nil {
+			log.Warn("failed to save the store offline plan", zap.Uint64("store-id", storeID), errs.ZapError(err))
+		}
+	}
+	c.applyStoreReservations()
+}
+
+// applyStoreReservations sets the capacity reserved by all the plans on the
+// stores, which the filters check before moving the other regions to them.
+func (c *RaftCluster) applyStoreReservations() {
+	reserved := c.offlinePlans.reserved(0)
+	for _, store := range c.GetStores() {
+		c.core.SetStoreReservedSize(store.GetID(), reserved[store.GetID()])
+	}
+}
+
+// GetStoreOfflinePlan returns the capacity reservation plan of the offline
+// store, or nil if the store is not taken offline.
+func (c *RaftCluster) GetStoreOfflinePlan(storeID uint64) *StoreOfflinePlan {
+	return c.offlinePlans.get(storeID)
+}
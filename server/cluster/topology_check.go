@@ -0,0 +1,179 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
+)
+
+// The kinds of the topology findings.
+const (
+	// TopologyInsufficientStores means there are fewer stores than the peers
+	// to place.
+	TopologyInsufficientStores = "insufficient-stores"
+	// TopologyMissingLocationLabel means some stores do not have a value of a
+	// location label, so their placement cannot be isolated by it.
+	TopologyMissingLocationLabel = "missing-location-label"
+	// TopologyInsufficientDomains means there are fewer domains of a location
+	// label than the peers to place, so the peers cannot be isolated at the
+	// level of the label.
+	TopologyInsufficientDomains = "insufficient-domains"
+	// TopologyIsolationUnachievable means the isolation level cannot be
+	// achieved, so some peers can never be placed.
+	TopologyIsolationUnachievable = "isolation-unachievable"
+)
+
+// The severities of the topology findings.
+const (
+	TopologySeverityError   = "error"
+	TopologySeverityWarning = "warning"
+)
+
+// TopologyFinding is a problem found by checking the store labels against
+// the location labels and the placement rules.
+type TopologyFinding struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	// RuleGroup and RuleID are the placement rule the finding is about, they
+	// are empty if placement rules are disabled.
+	RuleGroup string   `json:"rule_group,omitempty"`
+	RuleID    string   `json:"rule_id,omitempty"`
+	Label     string   `json:"label,omitempty"`
+	Required  int      `json:"required"`
+	Actual    int      `json:"actual"`
+	StoreIDs  []uint64 `json:"store_ids,omitempty"`
+	Message   string   `json:"message"`
+}
+
+// TopologyReport is the result of checking the topology of the cluster.
+type TopologyReport struct {
+	// Valid is false if any finding is an error.
+	Valid    bool               `json:"valid"`
+	Findings []*TopologyFinding `json:"findings"`
+}
+
+func (r *TopologyReport) add(f *TopologyFinding) {
+	if f.Severity == TopologySeverityError {
+		r.Valid = false
+	}
+	r.Findings = append(r.Findings, f)
+}
+
+// CheckTopology checks the labels of the up stores against the location labels
+// and the placement rules, and reports the placements that cannot be
+// satisfied or isolated.
+func (c *RaftCluster) CheckTopology() *TopologyReport {
+	var stores []*core.StoreInfo
+	for _, store := range c.GetStores() {
+		if store.IsUp() {
+			stores = append(stores, store)
+		}
+	}
+	report := &TopologyReport{Valid: true, Findings: []*TopologyFinding{}}
+	if !c.opt.IsPlacementRulesEnabled() {
+		rule := &placement.Rule{
+			Count:          c.opt.GetMaxReplicas(),
+			LocationLabels: c.opt.GetLocationLabels(),
+			IsolationLevel: c.opt.GetIsolationLevel(),
+		}
+		checkRuleTopology(report, rule, stores)
+		return report
+	}
+	for _, rule := range c.ruleManager.GetAllRules() {
+		checkRuleTopology(report, rule, stores)
+	}
+	return report
+}
+
+func checkRuleTopology(report *TopologyReport, rule *placement.Rule, stores []*core.StoreInfo) {
+	if rule.Count <= 0 {
+		return
+	}
+	newFinding := func(kind, severity string) *TopologyFinding {
+		return &TopologyFinding{Kind: kind, Severity: severity, RuleGroup: rule.GroupID, RuleID: rule.ID, Required: rule.Count}
+	}
+
+	var candidates []*core.StoreInfo
+	for _, store := range stores {
+		if placement.MatchLabelConstraints(store, rule.LabelConstraints) {
+			candidates = append(candidates, store)
+		}
+	}
+	if len(candidates) < rule.Count {
+		f := newFinding(TopologyInsufficientStores, TopologySeverityError)
+		f.Actual = len(candidates)
+		f.StoreIDs = sortedStoreIDs(candidates)
+		f.Message = fmt.Sprintf("%d peers are required but only %d up stores match", rule.Count, len(candidates))
+		report.add(f)
+		return
+	}
+
+	for _, label := range rule.LocationLabels {
+		var missing []*core.StoreInfo
+		for _, store := range candidates {
+			if store.GetLabelValue(label) == "" {
+				missing = append(missing, store)
+			}
+		}
+		if len(missing) > 0 {
+			f := newFinding(TopologyMissingLocationLabel, TopologySeverityWarning)
+			f.Label = label
+			f.Actual = len(candidates) - len(missing)
+			f.StoreIDs = sortedStoreIDs(missing)
+			f.Message = fmt.Sprintf("%d stores do not have the location label %s", len(missing), label)
+			report.add(f)
+		}
+	}
+
+	// The domains of a level are made of the values of the location label and
+	// all the labels above it, e.g. the racks are distinguished by zone and rack.
+	for i, label := range rule.LocationLabels {
+		domains := make(map[string]struct{})
+		for _, store := range candidates {
+			values := make([]string, 0, i+1)
+			for _, l := range rule.LocationLabels[:i+1] {
+				values = append(values, store.GetLabelValue(l))
+			}
+			domains[strings.Join(values, "/")] = struct{}{}
+		}
+		if len(domains) >= rule.Count {
+			continue
+		}
+		var f *TopologyFinding
+		if label == rule.IsolationLevel {
+			f = newFinding(TopologyIsolationUnachievable, TopologySeverityError)
+			f.Message = fmt.Sprintf("the isolation level is %s but there are only %d %s domains for %d peers", label, len(domains), label, rule.Count)
+		} else {
+			f = newFinding(TopologyInsufficientDomains, TopologySeverityWarning)
+			f.Message = fmt.Sprintf("there are only %d %s domains for %d peers, the peers cannot be isolated by %s", len(domains), label, rule.Count, label)
+		}
+		f.Label = label
+		f.Actual = len(domains)
+		report.add(f)
+	}
+}
+
+func sortedStoreIDs(stores []*core.StoreInfo) []uint64 {
+	ids := make([]uint64, 0, len(stores))
+	for _, store := range stores {
+		ids = append(ids, store.GetID())
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
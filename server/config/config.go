@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"math"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -31,6 +32,8 @@ import (
 	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/logutil"
 	"github.com/tikv/pd/pkg/metricutil"
+	"github.com/tikv/pd/pkg/ratelimit"
+	"github.com/tikv/pd/pkg/slice"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server/core/storelimit"
 	"github.com/tikv/pd/server/versioninfo"
@@ -58,6 +61,14 @@ type Config struct {
 	PeerUrls            string `toml:"peer-urls" json:"peer-urls"`
 	AdvertiseClientUrls string `toml:"advertise-client-urls" json:"advertise-client-urls"`
 	AdvertisePeerUrls   string `toml:"advertise-peer-urls" json:"advertise-peer-urls"`
+	// AdvertiseClientUrlsByDomain is the advertise client urls for each
+	// routing domain, such as the operator network or the cross-DC network.
+	// The clients which request a routing domain get these urls instead of
+	// advertise-client-urls.
+	// Example:
+	// [advertise-client-urls-by-domain]
+	// operator = "http://10.0.0.1:2379"
+	AdvertiseClientUrlsByDomain map[string]string `toml:"advertise-client-urls-by-domain" json:"advertise-client-urls-by-domain"`
 
 	Name              string `toml:"name" json:"name"`
 	DataDir           string `toml:"data-dir" json:"data-dir"`
@@ -86,6 +97,13 @@ type Config struct {
 
 	// TSOSaveInterval is the interval to save timestamp.
 	TSOSaveInterval typeutil.Duration `toml:"tso-save-interval" json:"tso-save-interval"`
+	// TSOMaxSaveInterval is the max interval to save timestamp. If it is greater than
+	// TSOSaveInterval, the saved time window grows with the TSO QPS up to it to reduce
+	// the etcd writes, and shrinks back to TSOSaveInterval when idle. 0 means disabled.
+	TSOMaxSaveInterval typeutil.Duration `toml:"tso-max-save-interval" json:"tso-max-save-interval"`
+	// TSOMaxSaveIntervalQPS is the TSO QPS at which the saved time window reaches
+	// TSOMaxSaveInterval.
+	TSOMaxSaveIntervalQPS uint64 `toml:"tso-max-save-interval-qps" json:"tso-max-save-interval-qps"`
 
 	// The interval to update physical part of timestamp. Usually, this config should not be set.
 	// It's only useful for test purposes.
@@ -162,6 +180,8 @@ type Config struct {
 	Dashboard DashboardConfig `toml:"dashboard" json:"dashboard"`
 
 	ReplicationMode ReplicationModeConfig `toml:"replication-mode" json:"replication-mode"`
+
+	DecisionExport DecisionExportConfig `toml:"decision-export" json:"decision-export"`
 }
 
 // NewConfig creates a new config.
@@ -230,6 +250,11 @@ const (
 	defaultMaxResetTSGap    = 24 * time.Hour
 	defaultKeyType          = "table"
 
+	defaultMaxConcurrentTSOProxyStreamings = 5000
+	defaultHotStatsSyncInterval            = 10 * time.Second
+	defaultHotStatsMaxStaleness            = time.Minute
+	defaultRegionSyncerVerifyInterval      = 10 * time.Minute
+
 	defaultStrictlyMatchLabel   = false
 	defaultEnablePlacementRules = true
 	defaultEnableGRPCGateway    = true
@@ -237,6 +262,9 @@ const (
 
 	defaultDashboardAddress = "auto"
 
+	defaultDecisionExportSampleRatio = 1.0
+	defaultDecisionExportInterval    = 15 * time.Second
+
 	defaultDRWaitStoreTimeout = time.Minute
 	defaultDRWaitSyncTimeout  = time.Minute
 	defaultDRWaitAsyncTimeout = 2 * time.Minute
@@ -245,6 +273,8 @@ const (
 	DefaultTSOUpdatePhysicalInterval = 50 * time.Millisecond
 	maxTSOUpdatePhysicalInterval     = 10 * time.Second
 	minTSOUpdatePhysicalInterval     = 50 * time.Millisecond
+
+	defaultTSOMaxSaveIntervalQPS = 100000
 )
 
 // Special keys for Labels
@@ -257,6 +287,9 @@ var (
 	defaultEnableTelemetry = true
 	defaultRuntimeServices = []string{}
 	defaultLocationLabels  = []string{}
+	// defaultSoftAntiAffinityLabels is empty rather than nil to be the same as
+	// the value decoded from an empty string.
+	defaultSoftAntiAffinityLabels = []string{}
 	// DefaultStoreLimit is the default store limit of add peer and remove peer.
 	DefaultStoreLimit = StoreLimit{AddPeer: 15, RemovePeer: 15}
 	// DefaultTiFlashStoreLimit is the default TiFlash store limit of add peer and remove peer.
@@ -501,6 +534,11 @@ func (c *Config) Adjust(meta *toml.MetaData, reloading bool) error {
 	adjustString(&c.AdvertiseClientUrls, c.ClientUrls)
 	adjustString(&c.PeerUrls, defaultPeerUrls)
 	adjustString(&c.AdvertisePeerUrls, c.PeerUrls)
+	for domain, urls := range c.AdvertiseClientUrlsByDomain {
+		if _, err := ParseUrls(urls); err != nil {
+			return errors.Errorf("failed to parse advertise client urls of routing domain %s: %v", domain, err)
+		}
+	}
 	adjustDuration(&c.Metric.PushInterval, defaultMetricsPushInterval)
 
 	if len(c.InitialCluster) == 0 {
@@ -528,6 +566,12 @@ func (c *Config) Adjust(meta *toml.MetaData, reloading bool) error {
 
 	adjustDuration(&c.TSOSaveInterval, time.Duration(defaultLeaderLease)*time.Second)
 
+	if c.TSOMaxSaveInterval.Duration != 0 && c.TSOMaxSaveInterval.Duration < c.TSOSaveInterval.Duration {
+		c.TSOMaxSaveInterval.Duration = c.TSOSaveInterval.Duration
+	}
+
+	adjustUint64(&c.TSOMaxSaveIntervalQPS, defaultTSOMaxSaveIntervalQPS)
+
 	adjustDuration(&c.TSOUpdatePhysicalInterval, DefaultTSOUpdatePhysicalInterval)
 
 	if c.TSOUpdatePhysicalInterval.Duration > maxTSOUpdatePhysicalInterval {
@@ -581,9 +625,13 @@ func (c *Config) Adjust(meta *toml.MetaData, reloading bool) error {
 
 	c.ReplicationMode.adjust(configMetaData.Child("replication-mode"))
 
+	if err := c.DecisionExport.adjust(configMetaData.Child("decision-export")); err != nil {
+		return err
+	}
+
 	c.Security.Encryption.Adjust()
 
-	return nil
+	return c.Security.AdminAuth.adjust(c.Security.TLSConfig)
 }
 
 func (c *Config) adjustLog(meta *configMetaData) {
@@ -651,11 +699,38 @@ type ScheduleConfig struct {
 	// If the number of times a region hits the hot cache is greater than this
 	// threshold, it is considered a hot region.
 	HotRegionCacheHitsThreshold uint64 `toml:"hot-region-cache-hits-threshold" json:"hot-region-cache-hits-threshold"`
+	// HotRegionsWriteInterval is the interval to persist the hot peers into
+	// the hot region history.
+	HotRegionsWriteInterval typeutil.Duration `toml:"hot-regions-write-interval" json:"hot-regions-write-interval"`
+	// HotRegionsReservedDays is the number of days the hot region history is
+	// kept. 0 means the hot peers are not persisted.
+	HotRegionsReservedDays uint64 `toml:"hot-regions-reserved-days" json:"hot-regions-reserved-days"`
+	// RegionTopologySnapshotInterval is the interval to persist the snapshot
+	// of the region topology, which is the leader and the peers of every region.
+	RegionTopologySnapshotInterval typeutil.Duration `toml:"region-topology-snapshot-interval" json:"region-topology-snapshot-interval"`
+	// RegionTopologyRetention is how long the snapshots of the region topology
+	// are kept. 0 means the region topology is not persisted.
+	RegionTopologyRetention typeutil.Duration `toml:"region-topology-retention" json:"region-topology-retention"`
+	// SchedulerHibernateInterval is the interval of the schedulers when they
+	// hibernate, which happens after they keep finding nothing to schedule
+	// while the cluster has no operator and no region to check. They are
+	// woken up at once by the changes of the stores, the regions and the
+	// config. 0 means the schedulers never hibernate.
+	SchedulerHibernateInterval typeutil.Duration `toml:"scheduler-hibernate-interval" json:"scheduler-hibernate-interval"`
 	// StoreBalanceRate is the maximum of balance rate for each store.
 	// WARN: StoreBalanceRate is deprecated.
 	StoreBalanceRate float64 `toml:"store-balance-rate" json:"store-balance-rate,omitempty"`
 	// StoreLimit is the limit of scheduling for stores.
 	StoreLimit map[uint64]StoreLimitConfig `toml:"store-limit" json:"store-limit"`
+	// SnapshotLimits overrides MaxSnapshotCount for the stores matching the
+	// store ID or labels, e.g. to allow more concurrent snapshots on NVMe
+	// stores than on HDD ones.
+	SnapshotLimits []SnapshotLimitConfig `toml:"snapshot-limits" json:"snapshot-limits"`
+	// EnableSnapshotLimit makes the operator controller hold back the
+	// operators which would make a store send or receive more snapshots than
+	// its max snapshot count. It is off by default, as the count is also the
+	// limit of the snapshots the store generates by itself.
+	EnableSnapshotLimit bool `toml:"enable-snapshot-limit" json:"enable-snapshot-limit,string"`
 	// TolerantSizeRatio is the ratio of buffer size for balance scheduler.
 	TolerantSizeRatio float64 `toml:"tolerant-size-ratio" json:"tolerant-size-ratio"`
 	//
@@ -726,6 +801,23 @@ type ScheduleConfig struct {
 	// is overwritten, the value is fixed until it is deleted.
 	// Default: manual
 	StoreLimitMode string `toml:"store-limit-mode" json:"store-limit-mode"`
+
+	// SoftAntiAffinityLabels are the label keys used to group stores for soft
+	// anti-affinity. Stores sharing the same value of any of these keys are
+	// considered in the same group, and schedulers prefer not to place peers
+	// of a region into the same group. Unlike location labels, it only works
+	// as a scoring penalty and never blocks a schedule.
+	// For example, ["power-feed"] means that PD tries to avoid putting two
+	// replicas on stores that are powered by the same feed.
+	SoftAntiAffinityLabels typeutil.StringSlice `toml:"soft-anti-affinity-labels" json:"soft-anti-affinity-labels"`
+
+	// StoreTierLabel is the label key whose value is the tier of a store, like
+	// hot, warm and cold. When it is set, balance-region only moves the peers
+	// within a tier, and the rule checker keeps the new peers in the tier of
+	// the region unless the rule constrains the tier itself. The stores
+	// without the label are in the empty tier. Empty means the stores are not
+	// tiered.
+	StoreTierLabel string `toml:"store-tier-label" json:"store-tier-label"`
 }
 
 // Clone returns a cloned scheduling configuration.
@@ -738,9 +830,16 @@ func (c *ScheduleConfig) Clone() *ScheduleConfig {
 			storeLimit[k] = v
 		}
 	}
+	softAntiAffinityLabels := append(c.SoftAntiAffinityLabels[:0:0], c.SoftAntiAffinityLabels...)
+	var snapshotLimits []SnapshotLimitConfig
+	for _, limit := range c.SnapshotLimits {
+		snapshotLimits = append(snapshotLimits, limit.clone())
+	}
 	cfg := *c
+	cfg.SnapshotLimits = snapshotLimits
 	cfg.StoreLimit = storeLimit
 	cfg.Schedulers = schedulers
+	cfg.SoftAntiAffinityLabels = softAntiAffinityLabels
 	cfg.SchedulersPayload = nil
 	return &cfg
 }
@@ -766,6 +865,10 @@ const (
 	// defaultHotRegionCacheHitsThreshold is the low hit number threshold of the
 	// hot region.
 	defaultHotRegionCacheHitsThreshold = 3
+	defaultHotRegionsWriteInterval     = 10 * time.Minute
+	defaultHotRegionsReservedDays      = 7
+	defaultRegionTopologyInterval      = 30 * time.Minute
+	defaultRegionTopologyRetention     = 72 * time.Hour
 	defaultSchedulerMaxWaitingOperator = 5
 	defaultLeaderSchedulePolicy        = "count"
 	defaultStoreLimitMode              = "manual"
@@ -807,6 +910,14 @@ func (c *ScheduleConfig) adjust(meta *configMetaData, reloading bool) error {
 	if !meta.IsDefined("hot-region-cache-hits-threshold") {
 		adjustUint64(&c.HotRegionCacheHitsThreshold, defaultHotRegionCacheHitsThreshold)
 	}
+	adjustDuration(&c.HotRegionsWriteInterval, defaultHotRegionsWriteInterval)
+	if !meta.IsDefined("hot-regions-reserved-days") {
+		adjustUint64(&c.HotRegionsReservedDays, defaultHotRegionsReservedDays)
+	}
+	adjustDuration(&c.RegionTopologySnapshotInterval, defaultRegionTopologyInterval)
+	if !meta.IsDefined("region-topology-retention") {
+		adjustDuration(&c.RegionTopologyRetention, defaultRegionTopologyRetention)
+	}
 	if !meta.IsDefined("tolerant-size-ratio") {
 		adjustFloat64(&c.TolerantSizeRatio, defaultTolerantSizeRatio)
 	}
@@ -825,6 +936,9 @@ func (c *ScheduleConfig) adjust(meta *configMetaData, reloading bool) error {
 	if !meta.IsDefined("enable-cross-table-merge") {
 		c.EnableCrossTableMerge = defaultEnableCrossTableMerge
 	}
+	if !meta.IsDefined("soft-anti-affinity-labels") {
+		c.SoftAntiAffinityLabels = defaultSoftAntiAffinityLabels
+	}
 	adjustFloat64(&c.LowSpaceRatio, defaultLowSpaceRatio)
 	adjustFloat64(&c.HighSpaceRatio, defaultHighSpaceRatio)
 
@@ -913,11 +1027,26 @@ func (c *ScheduleConfig) Validate() error {
 	if c.LowSpaceRatio <= c.HighSpaceRatio {
 		return errors.New("low-space-ratio should be larger than high-space-ratio")
 	}
+	for _, label := range c.SoftAntiAffinityLabels {
+		if err := ValidateLabels([]*metapb.StoreLabel{{Key: label}}); err != nil {
+			return err
+		}
+	}
+	if c.StoreTierLabel != "" {
+		if err := ValidateLabels([]*metapb.StoreLabel{{Key: c.StoreTierLabel}}); err != nil {
+			return err
+		}
+	}
 	for _, scheduleConfig := range c.Schedulers {
 		if !IsSchedulerRegistered(scheduleConfig.Type) {
 			return errors.Errorf("create func of %v is not registered, maybe misspelled", scheduleConfig.Type)
 		}
 	}
+	for _, limit := range c.SnapshotLimits {
+		if limit.StoreID == 0 && len(limit.Labels) == 0 {
+			return errors.New("snapshot-limits should specify store-id or labels")
+		}
+	}
 	return nil
 }
 
@@ -953,6 +1082,25 @@ type StoreLimitConfig struct {
 	RemovePeer float64 `toml:"remove-peer" json:"remove-peer"`
 }
 
+// SnapshotLimitConfig is the max snapshot count of the stores with the store
+// ID, or with all the labels if the store ID is not specified.
+type SnapshotLimitConfig struct {
+	StoreID          uint64            `toml:"store-id" json:"store-id,omitempty"`
+	Labels           map[string]string `toml:"labels" json:"labels,omitempty"`
+	MaxSnapshotCount uint64            `toml:"max-snapshot-count" json:"max-snapshot-count"`
+}
+
+func (c SnapshotLimitConfig) clone() SnapshotLimitConfig {
+	if c.Labels != nil {
+		labels := make(map[string]string, len(c.Labels))
+		for k, v := range c.Labels {
+			labels[k] = v
+		}
+		c.Labels = labels
+	}
+	return c
+}
+
 // SchedulerConfigs is a slice of customized scheduler configuration.
 type SchedulerConfigs []SchedulerConfig
 
@@ -1070,6 +1218,55 @@ type PDServerConfig struct {
 	DashboardAddress string `toml:"dashboard-address" json:"dashboard-address"`
 	// TraceRegionFlow the option to update flow information of regions
 	TraceRegionFlow bool `toml:"trace-region-flow" json:"trace-region-flow,string"`
+	// CallerRateLimits is the request quota of each caller component, such as
+	// "br" or "cdc". The callers without a quota are not limited.
+	CallerRateLimits map[string]ratelimit.Quota `toml:"caller-rate-limits" json:"caller-rate-limits"`
+	// SkipUnchangedRegionHeartbeat skips handling the region heartbeats which
+	// carry nothing new for a quiet region.
+	SkipUnchangedRegionHeartbeat bool `toml:"skip-unchanged-region-heartbeat" json:"skip-unchanged-region-heartbeat,string"`
+	// RegionHeartbeatLatencyBudget is the max time spent on handling the region
+	// heartbeats per second. It is the wall-clock time summed over the
+	// heartbeats, which includes the time waiting for the locks, not the CPU
+	// time of PD. Once it is used up, the hot and region statistics of the
+	// heartbeats are skipped until the next second, while the region meta is
+	// still updated. The stores are not asked to slow down. Zero means no
+	// limit.
+	RegionHeartbeatLatencyBudget typeutil.Duration `toml:"region-heartbeat-latency-budget" json:"region-heartbeat-latency-budget"`
+	// RegionHeartbeatFairQueueConcurrency is the max number of the region
+	// heartbeats handled at the same time. The heartbeats beyond it wait in
+	// a queue which serves the keyspaces in turn, so a keyspace with a
+	// heartbeat storm does not starve the others. The keyspaces get the same
+	// number of turns rather than the same time, so the keyspaces with slower
+	// heartbeats still take a larger share. Zero disables the queue.
+	RegionHeartbeatFairQueueConcurrency int `toml:"region-heartbeat-fair-queue-concurrency" json:"region-heartbeat-fair-queue-concurrency"`
+	// MaxConcurrentTSOProxyStreamings is the max number of the TSO streams a
+	// follower forwards to the leader at the same time. The new streams beyond
+	// it are rejected and retried by the clients. Zero means no limit.
+	MaxConcurrentTSOProxyStreamings int `toml:"max-concurrent-tso-proxy-streamings" json:"max-concurrent-tso-proxy-streamings"`
+	// HotStatsSyncInterval is the interval for a follower to replicate the
+	// hot-region statistics of the leader, which lets the follower serve the
+	// hot-region read APIs. Zero disables the replication.
+	HotStatsSyncInterval typeutil.Duration `toml:"hot-stats-sync-interval" json:"hot-stats-sync-interval"`
+	// HotStatsMaxStaleness is the max age of the replicated hot-region
+	// statistics a follower serves.
+	HotStatsMaxStaleness typeutil.Duration `toml:"hot-stats-max-staleness" json:"hot-stats-max-staleness"`
+	// RegionSyncerVerifyInterval is the interval for a follower to verify
+	// the regions synced from the leader by comparing the checksums of the
+	// key ranges. Zero disables the verification.
+	RegionSyncerVerifyInterval typeutil.Duration `toml:"region-syncer-verify-interval" json:"region-syncer-verify-interval"`
+	// TombstoneStoreRetention is how long a tombstone store is kept in the
+	// cluster before it is archived, which removes it from the store list
+	// but keeps it in storage to restore. Zero disables the archival.
+	TombstoneStoreRetention typeutil.Duration `toml:"tombstone-store-retention" json:"tombstone-store-retention"`
+	// WarnDeprecatedRPC logs a warning for the first call of each caller to a
+	// deprecated RPC, so that the callers can be upgraded before the RPC is
+	// removed.
+	WarnDeprecatedRPC bool `toml:"warn-deprecated-rpc" json:"warn-deprecated-rpc,string"`
+	// ForwardGRPCCompression is the gRPC compressor used by a follower to
+	// forward the region-heavy requests and streams to the leader, such as
+	// "gzip". Empty means no compression. The responses to the clients are
+	// compressed with the compressor chosen by the clients.
+	ForwardGRPCCompression string `toml:"forward-grpc-compression" json:"forward-grpc-compression"`
 }
 
 func (c *PDServerConfig) adjust(meta *configMetaData) error {
@@ -1089,6 +1286,16 @@ func (c *PDServerConfig) adjust(meta *configMetaData) error {
 	if !meta.IsDefined("trace-region-flow") {
 		c.TraceRegionFlow = defaultTraceRegionFlow
 	}
+	if !meta.IsDefined("max-concurrent-tso-proxy-streamings") {
+		c.MaxConcurrentTSOProxyStreamings = defaultMaxConcurrentTSOProxyStreamings
+	}
+	if !meta.IsDefined("hot-stats-sync-interval") {
+		c.HotStatsSyncInterval.Duration = defaultHotStatsSyncInterval
+	}
+	adjustDuration(&c.HotStatsMaxStaleness, defaultHotStatsMaxStaleness)
+	if !meta.IsDefined("region-syncer-verify-interval") {
+		c.RegionSyncerVerifyInterval.Duration = defaultRegionSyncerVerifyInterval
+	}
 	return c.Validate()
 }
 
@@ -1097,6 +1304,12 @@ func (c *PDServerConfig) Clone() *PDServerConfig {
 	runtimeServices := append(c.RuntimeServices[:0:0], c.RuntimeServices...)
 	cfg := *c
 	cfg.RuntimeServices = runtimeServices
+	if c.CallerRateLimits != nil {
+		cfg.CallerRateLimits = make(map[string]ratelimit.Quota, len(c.CallerRateLimits))
+		for caller, quota := range c.CallerRateLimits {
+			cfg.CallerRateLimits[caller] = quota
+		}
+	}
 	return &cfg
 }
 
@@ -1110,6 +1323,35 @@ func (c *PDServerConfig) Validate() error {
 			return err
 		}
 	}
+	for caller, quota := range c.CallerRateLimits {
+		if math.IsNaN(quota.QPS) || math.IsInf(quota.QPS, 0) || quota.QPS < 0 || quota.Burst < 0 {
+			return errors.Errorf("invalid rate limit of caller %s", caller)
+		}
+	}
+	if c.RegionHeartbeatLatencyBudget.Duration < 0 {
+		return errors.Errorf("region-heartbeat-latency-budget should not be negative, got %v", c.RegionHeartbeatLatencyBudget.Duration)
+	}
+	if c.RegionHeartbeatFairQueueConcurrency < 0 {
+		return errors.Errorf("region-heartbeat-fair-queue-concurrency should not be negative, got %d", c.RegionHeartbeatFairQueueConcurrency)
+	}
+	if c.MaxConcurrentTSOProxyStreamings < 0 {
+		return errors.Errorf("max-concurrent-tso-proxy-streamings should not be negative, got %d", c.MaxConcurrentTSOProxyStreamings)
+	}
+	if c.HotStatsSyncInterval.Duration < 0 {
+		return errors.Errorf("hot-stats-sync-interval should not be negative, got %v", c.HotStatsSyncInterval.Duration)
+	}
+	if c.HotStatsMaxStaleness.Duration <= 0 {
+		return errors.Errorf("hot-stats-max-staleness should be positive, got %v", c.HotStatsMaxStaleness.Duration)
+	}
+	if c.RegionSyncerVerifyInterval.Duration < 0 {
+		return errors.Errorf("region-syncer-verify-interval should not be negative, got %v", c.RegionSyncerVerifyInterval.Duration)
+	}
+	if c.TombstoneStoreRetention.Duration < 0 {
+		return errors.Errorf("tombstone-store-retention should not be negative, got %v", c.TombstoneStoreRetention.Duration)
+	}
+	if !grpcutil.IsCompressorSupported(c.ForwardGRPCCompression) {
+		return errors.Errorf("forward-grpc-compression %s is not supported", c.ForwardGRPCCompression)
+	}
 
 	return nil
 }
@@ -1304,6 +1546,31 @@ func (c *DashboardConfig) adjust(meta *configMetaData) {
 	c.EnableTelemetry = c.EnableTelemetry && !c.DisableTelemetry
 }
 
+// DecisionExportConfig is the configuration for exporting the scheduling
+// decisions to Prometheus via remote-write, which is disabled if the URL is
+// empty.
+type DecisionExportConfig struct {
+	RemoteWriteURL string `toml:"remote-write-url" json:"remote-write-url"`
+	// SampleRatio is the ratio of the decisions to be exported.
+	SampleRatio float64 `toml:"sample-ratio" json:"sample-ratio"`
+	// Interval is the interval to send the sampled decisions.
+	Interval typeutil.Duration `toml:"interval" json:"interval"`
+}
+
+func (c *DecisionExportConfig) adjust(meta *configMetaData) error {
+	if !meta.IsDefined("sample-ratio") {
+		c.SampleRatio = defaultDecisionExportSampleRatio
+	}
+	adjustDuration(&c.Interval, defaultDecisionExportInterval)
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return errors.Errorf("decision-export.sample-ratio %v should be between 0 and 1", c.SampleRatio)
+	}
+	if c.RemoteWriteURL != "" {
+		return ValidateURLWithScheme(c.RemoteWriteURL)
+	}
+	return nil
+}
+
 // ReplicationModeConfig is the configuration for the replication policy.
 type ReplicationModeConfig struct {
 	ReplicationMode string                      `toml:"replication-mode" json:"replication-mode"` // can be 'dr-auto-sync' or 'majority', default value is 'majority'
@@ -1363,4 +1630,52 @@ type SecurityConfig struct {
 	// RedactInfoLog indicates that whether enabling redact log
 	RedactInfoLog bool              `toml:"redact-info-log" json:"redact-info-log"`
 	Encryption    encryption.Config `toml:"encryption" json:"encryption"`
+	// AdminAuth restricts the gRPC methods which mutate the cluster metadata.
+	AdminAuth AdminAuthConfig `toml:"admin-auth" json:"admin-auth"`
+}
+
+var (
+	// AdminMethods are the gRPC methods which support the admin authorization.
+	AdminMethods = []string{"Bootstrap", "PutStore", "PutClusterConfig", "SplitRegions", "ScatterRegion",
+		"UpdateGCSafePoint", "UpdateServiceGCSafePoint", "AllocIDRange"}
+	// DefaultAdminMethods are the gRPC methods authorized by default when the
+	// admin authorization is enabled.
+	DefaultAdminMethods = []string{"PutStore", "PutClusterConfig", "SplitRegions", "ScatterRegion"}
+)
+
+// AdminAuthConfig is the configuration of the authorization of the admin gRPC
+// methods. A request to these methods is allowed only if the CN of the client
+// certificate is in the allowlist, or it carries one of the tokens.
+type AdminAuthConfig struct {
+	Enable bool `toml:"enable" json:"enable"`
+	// Methods are the names of the gRPC methods to authorize, which should be
+	// in AdminMethods.
+	Methods []string `toml:"methods" json:"methods"`
+	// AllowedCN is the allowlist of the CN of the client certificates. It
+	// needs the CA to be configured to verify the client certificates. If PD
+	// forwards requests to the leader, the CN of PD itself should be included.
+	AllowedCN []string `toml:"allowed-cn" json:"allowed-cn"`
+	// Tokens are not shown in the API.
+	Tokens []string `toml:"tokens" json:"-"`
+}
+
+func (c *AdminAuthConfig) adjust(tlsConfig grpcutil.TLSConfig) error {
+	if !c.Enable {
+		return nil
+	}
+	if len(c.Methods) == 0 {
+		c.Methods = append(c.Methods, DefaultAdminMethods...)
+	}
+	for _, method := range c.Methods {
+		if slice.NoneOf(AdminMethods, func(i int) bool { return AdminMethods[i] == method }) {
+			return errors.Errorf("admin-auth does not support method %s", method)
+		}
+	}
+	if len(c.AllowedCN) == 0 && len(c.Tokens) == 0 {
+		return errors.New("admin-auth needs allowed-cn or tokens")
+	}
+	if len(c.AllowedCN) > 0 && len(tlsConfig.CAPath) == 0 {
+		return errors.New("admin-auth allowed-cn needs cacert-path to verify the client certificates")
+	}
+	return nil
 }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/BurntSushi/toml"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/kv"
 )
@@ -273,6 +274,20 @@ tso-update-physical-interval = "15s"
 	c.Assert(err, IsNil)
 
 	c.Assert(cfg.TSOUpdatePhysicalInterval.Duration, Equals, maxTSOUpdatePhysicalInterval)
+
+	// Test clamping TSOMaxSaveInterval value
+	cfgData = `
+tso-save-interval = "3s"
+tso-max-save-interval = "1s"
+`
+	cfg = NewConfig()
+	meta, err = toml.Decode(cfgData, &cfg)
+	c.Assert(err, IsNil)
+	err = cfg.Adjust(&meta, false)
+	c.Assert(err, IsNil)
+
+	c.Assert(cfg.TSOMaxSaveInterval.Duration, Equals, 3*time.Second)
+	c.Assert(cfg.TSOMaxSaveIntervalQPS, Equals, uint64(defaultTSOMaxSaveIntervalQPS))
 }
 
 func (s *testConfigSuite) TestMigrateFlags(c *C) {
@@ -317,6 +332,83 @@ func newTestScheduleOption() (*PersistOptions, error) {
 	return opt, nil
 }
 
+func (s *testConfigSuite) TestSnapshotLimits(c *C) {
+	cfgData := `
+[schedule]
+max-snapshot-count = 3
+[[schedule.snapshot-limits]]
+labels = { disk = "nvme" }
+max-snapshot-count = 8
+[[schedule.snapshot-limits]]
+store-id = 2
+max-snapshot-count = 1
+`
+	cfg := NewConfig()
+	meta, err := toml.Decode(cfgData, &cfg)
+	c.Assert(err, IsNil)
+	c.Assert(cfg.Adjust(&meta, false), IsNil)
+	c.Assert(cfg.Schedule.Validate(), IsNil)
+	c.Assert(cfg.Schedule.Clone().SnapshotLimits, DeepEquals, cfg.Schedule.SnapshotLimits)
+
+	opt := NewPersistOptions(cfg)
+	newStore := func(id uint64, disk string) *core.StoreInfo {
+		return core.NewStoreInfo(&metapb.Store{Id: id, Labels: []*metapb.StoreLabel{{Key: "disk", Value: disk}}})
+	}
+	c.Assert(opt.GetStoreMaxSnapshotCount(newStore(1, "nvme")), Equals, uint64(8))
+	// The limit of the store ID takes precedence over the labels.
+	c.Assert(opt.GetStoreMaxSnapshotCount(newStore(2, "nvme")), Equals, uint64(1))
+	c.Assert(opt.GetStoreMaxSnapshotCount(newStore(3, "hdd")), Equals, uint64(3))
+
+	cfg.Schedule.SnapshotLimits = append(cfg.Schedule.SnapshotLimits, SnapshotLimitConfig{MaxSnapshotCount: 1})
+	c.Assert(cfg.Schedule.Validate(), NotNil)
+}
+
+func (s *testConfigSuite) TestAdminAuth(c *C) {
+	cfgData := `
+[security]
+cacert-path = "ca.pem"
+[security.admin-auth]
+enable = true
+allowed-cn = ["tikv"]
+tokens = ["secret"]
+`
+	cfg := NewConfig()
+	meta, err := toml.Decode(cfgData, &cfg)
+	c.Assert(err, IsNil)
+	c.Assert(cfg.Adjust(&meta, false), IsNil)
+	c.Assert(cfg.Security.AdminAuth.Methods, DeepEquals, DefaultAdminMethods)
+	// The tokens are not shown.
+	data, err := json.Marshal(cfg.Security.AdminAuth)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), "secret"), IsFalse)
+
+	for _, cfgData := range []string{
+		// No allowed CN or token.
+		`
+[security.admin-auth]
+enable = true
+`,
+		// The client certificates cannot be verified.
+		`
+[security.admin-auth]
+enable = true
+allowed-cn = ["tikv"]
+`,
+		// Unsupported method.
+		`
+[security.admin-auth]
+enable = true
+methods = ["GetStore"]
+tokens = ["secret"]
+`,
+	} {
+		cfg := NewConfig()
+		meta, err := toml.Decode(cfgData, &cfg)
+		c.Assert(err, IsNil)
+		c.Assert(cfg.Adjust(&meta, false), NotNil)
+	}
+}
+
 func (s *testConfigSuite) TestPDServerConfig(c *C) {
 	tests := []struct {
 		cfgData          string
@@ -455,6 +547,40 @@ wait-store-timeout = "120s"
 	c.Assert(cfg.ReplicationMode.ReplicationMode, Equals, "majority")
 }
 
+func (s *testConfigSuite) TestDecisionExport(c *C) {
+	cfgData := `
+[decision-export]
+remote-write-url = "http://127.0.0.1:9090/api/v1/write"
+sample-ratio = 0.1
+`
+	cfg := NewConfig()
+	meta, err := toml.Decode(cfgData, &cfg)
+	c.Assert(err, IsNil)
+	err = cfg.Adjust(&meta, false)
+	c.Assert(err, IsNil)
+	c.Assert(cfg.DecisionExport.RemoteWriteURL, Equals, "http://127.0.0.1:9090/api/v1/write")
+	c.Assert(cfg.DecisionExport.SampleRatio, Equals, 0.1)
+	c.Assert(cfg.DecisionExport.Interval.Duration, Equals, defaultDecisionExportInterval)
+
+	cfg = NewConfig()
+	meta, err = toml.Decode("", &cfg)
+	c.Assert(err, IsNil)
+	err = cfg.Adjust(&meta, false)
+	c.Assert(err, IsNil)
+	c.Assert(cfg.DecisionExport.RemoteWriteURL, Equals, "")
+	c.Assert(cfg.DecisionExport.SampleRatio, Equals, defaultDecisionExportSampleRatio)
+
+	for _, cfgData := range []string{
+		"[decision-export]\nsample-ratio = 1.5",
+		"[decision-export]\nremote-write-url = \"127.0.0.1:9090\"",
+	} {
+		cfg = NewConfig()
+		meta, err = toml.Decode(cfgData, &cfg)
+		c.Assert(err, IsNil)
+		c.Assert(cfg.Adjust(&meta, false), NotNil)
+	}
+}
+
 func (s *testConfigSuite) TestConfigClone(c *C) {
 	cfg := &Config{}
 	cfg.Adjust(nil, false)
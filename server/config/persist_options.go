@@ -46,6 +46,8 @@ type PersistOptions struct {
 	replicationMode atomic.Value
 	labelProperty   atomic.Value
 	clusterVersion  unsafe.Pointer
+	// persistHook is called with the config after it is persisted.
+	persistHook func(cfg *Config)
 }
 
 // NewPersistOptions creates a new PersistOptions instance.
@@ -136,6 +138,16 @@ func (o *PersistOptions) GetIsolationLevel() string {
 	return o.GetReplicationConfig().IsolationLevel
 }
 
+// GetSoftAntiAffinityLabels returns the label keys used for soft anti-affinity.
+func (o *PersistOptions) GetSoftAntiAffinityLabels() []string {
+	return o.GetScheduleConfig().SoftAntiAffinityLabels
+}
+
+// GetStoreTierLabel returns the label key whose value is the tier of a store.
+func (o *PersistOptions) GetStoreTierLabel() string {
+	return o.GetScheduleConfig().StoreTierLabel
+}
+
 // IsPlacementRulesEnabled returns if the placement rules is enabled.
 func (o *PersistOptions) IsPlacementRulesEnabled() bool {
 	return o.GetReplicationConfig().EnablePlacementRules
@@ -210,6 +222,37 @@ func (o *PersistOptions) GetMaxSnapshotCount() uint64 {
 	return o.getTTLUintOr(maxSnapshotCountKey, o.GetScheduleConfig().MaxSnapshotCount)
 }
 
+// GetStoreMaxSnapshotCount returns the number of the max snapshot of the
+// store. The limit matching the store ID takes precedence over the ones
+// matching the labels, and the first matched one is used.
+func (o *PersistOptions) GetStoreMaxSnapshotCount(store *core.StoreInfo) uint64 {
+	limits := o.GetScheduleConfig().SnapshotLimits
+	for _, limit := range limits {
+		if limit.StoreID != 0 && matchSnapshotLimit(limit, store) {
+			return limit.MaxSnapshotCount
+		}
+	}
+	for _, limit := range limits {
+		if limit.StoreID == 0 && matchSnapshotLimit(limit, store) {
+			return limit.MaxSnapshotCount
+		}
+	}
+	return o.GetMaxSnapshotCount()
+}
+
+// matchSnapshotLimit returns whether the limit applies to the store.
+func matchSnapshotLimit(c SnapshotLimitConfig, store *core.StoreInfo) bool {
+	if c.StoreID != 0 {
+		return c.StoreID == store.GetID()
+	}
+	for k, v := range c.Labels {
+		if store.GetLabelValue(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
 // GetMaxPendingPeerCount returns the number of the max pending peers.
 func (o *PersistOptions) GetMaxPendingPeerCount() uint64 {
 	return o.getTTLUintOr(maxPendingPeerCountKey, o.GetScheduleConfig().MaxPendingPeerCount)
@@ -448,6 +491,66 @@ func (o *PersistOptions) IsUseRegionStorage() bool {
 	return o.GetPDServerConfig().UseRegionStorage
 }
 
+// IsSkipUnchangedRegionHeartbeatEnabled returns if the unchanged region
+// heartbeats are skipped.
+func (o *PersistOptions) IsSkipUnchangedRegionHeartbeatEnabled() bool {
+	return o.GetPDServerConfig().SkipUnchangedRegionHeartbeat
+}
+
+// IsDeprecatedRPCWarningEnabled returns if the calls to the deprecated RPCs
+// are warned.
+func (o *PersistOptions) IsDeprecatedRPCWarningEnabled() bool {
+	return o.GetPDServerConfig().WarnDeprecatedRPC
+}
+
+// GetRegionHeartbeatLatencyBudget returns the max time spent on handling the
+// region heartbeats per second.
+func (o *PersistOptions) GetRegionHeartbeatLatencyBudget() time.Duration {
+	return o.GetPDServerConfig().RegionHeartbeatLatencyBudget.Duration
+}
+
+// GetRegionHeartbeatFairQueueConcurrency returns the max number of the region
+// heartbeats handled at the same time, or 0 if the fair queue is disabled.
+func (o *PersistOptions) GetRegionHeartbeatFairQueueConcurrency() int {
+	return o.GetPDServerConfig().RegionHeartbeatFairQueueConcurrency
+}
+
+// GetMaxConcurrentTSOProxyStreamings returns the max number of the TSO streams
+// forwarded at the same time.
+func (o *PersistOptions) GetMaxConcurrentTSOProxyStreamings() int {
+	return o.GetPDServerConfig().MaxConcurrentTSOProxyStreamings
+}
+
+// GetForwardGRPCCompression returns the gRPC compressor used to forward the
+// region-heavy requests and streams to the leader.
+func (o *PersistOptions) GetForwardGRPCCompression() string {
+	return o.GetPDServerConfig().ForwardGRPCCompression
+}
+
+// GetHotStatsSyncInterval returns the interval for a follower to replicate the
+// hot-region statistics of the leader.
+func (o *PersistOptions) GetHotStatsSyncInterval() time.Duration {
+	return o.GetPDServerConfig().HotStatsSyncInterval.Duration
+}
+
+// GetRegionSyncerVerifyInterval returns the interval for a follower to verify
+// the regions synced from the leader.
+func (o *PersistOptions) GetRegionSyncerVerifyInterval() time.Duration {
+	return o.GetPDServerConfig().RegionSyncerVerifyInterval.Duration
+}
+
+// GetTombstoneStoreRetention returns how long a tombstone store is kept before
+// it is archived.
+func (o *PersistOptions) GetTombstoneStoreRetention() time.Duration {
+	return o.GetPDServerConfig().TombstoneStoreRetention.Duration
+}
+
+// GetHotStatsMaxStaleness returns the max age of the replicated hot-region
+// statistics a follower serves.
+func (o *PersistOptions) GetHotStatsMaxStaleness() time.Duration {
+	return o.GetPDServerConfig().HotStatsMaxStaleness.Duration
+}
+
 // IsRemoveDownReplicaEnabled returns if remove down replica is enabled.
 func (o *PersistOptions) IsRemoveDownReplicaEnabled() bool {
 	return o.GetScheduleConfig().EnableRemoveDownReplica
@@ -485,11 +588,46 @@ func (o *PersistOptions) IsDebugMetricsEnabled() bool {
 	return o.GetScheduleConfig().EnableDebugMetrics
 }
 
+// IsSnapshotLimitEnabled returns if the operators are limited by the max
+// snapshot count of the stores.
+func (o *PersistOptions) IsSnapshotLimitEnabled() bool {
+	return o.GetScheduleConfig().EnableSnapshotLimit
+}
+
 // IsUseJointConsensus returns if using joint consensus as a operator step is enabled.
 func (o *PersistOptions) IsUseJointConsensus() bool {
 	return o.GetScheduleConfig().EnableJointConsensus
 }
 
+// GetHotRegionsWriteInterval returns the interval to persist the hot peers.
+func (o *PersistOptions) GetHotRegionsWriteInterval() time.Duration {
+	return o.GetScheduleConfig().HotRegionsWriteInterval.Duration
+}
+
+// GetHotRegionsReservedDays returns the number of days the hot region history
+// is kept.
+func (o *PersistOptions) GetHotRegionsReservedDays() uint64 {
+	return o.GetScheduleConfig().HotRegionsReservedDays
+}
+
+// GetRegionTopologySnapshotInterval returns the interval to persist the
+// snapshot of the region topology.
+func (o *PersistOptions) GetRegionTopologySnapshotInterval() time.Duration {
+	return o.GetScheduleConfig().RegionTopologySnapshotInterval.Duration
+}
+
+// GetRegionTopologyRetention returns how long the snapshots of the region
+// topology are kept.
+func (o *PersistOptions) GetRegionTopologyRetention() time.Duration {
+	return o.GetScheduleConfig().RegionTopologyRetention.Duration
+}
+
+// GetSchedulerHibernateInterval returns the interval of the schedulers when
+// they hibernate.
+func (o *PersistOptions) GetSchedulerHibernateInterval() time.Duration {
+	return o.GetScheduleConfig().SchedulerHibernateInterval.Duration
+}
+
 // GetHotRegionCacheHitsThreshold is a threshold to decide if a region is hot.
 func (o *PersistOptions) GetHotRegionCacheHitsThreshold() int {
 	return int(o.GetScheduleConfig().HotRegionCacheHitsThreshold)
@@ -558,6 +696,19 @@ func (o *PersistOptions) DeleteLabelProperty(typ, labelKey, labelValue string) {
 
 // Persist saves the configuration to the storage.
 func (o *PersistOptions) Persist(storage *core.Storage) error {
+	cfg, err := o.Save(storage)
+	if err != nil {
+		return err
+	}
+	if o.persistHook != nil {
+		o.persistHook(cfg)
+	}
+	return nil
+}
+
+// Save saves the configuration to the storage in a single write without
+// calling the persist hook, and returns the saved configuration.
+func (o *PersistOptions) Save(storage *core.Storage) (*Config, error) {
 	cfg := &Config{
 		Schedule:        *o.GetScheduleConfig(),
 		Replication:     *o.GetReplicationConfig(),
@@ -566,7 +717,16 @@ func (o *PersistOptions) Persist(storage *core.Storage) error {
 		LabelProperty:   o.GetLabelPropertyConfig(),
 		ClusterVersion:  *o.GetClusterVersion(),
 	}
-	return storage.SaveConfig(cfg)
+	if err := storage.SaveConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// SetPersistHook sets the function called with the config after it is
+// persisted. It must be set before the config is persisted.
+func (o *PersistOptions) SetPersistHook(hook func(cfg *Config)) {
+	o.persistHook = hook
 }
 
 // Reload reloads the configuration from the storage.
@@ -616,21 +776,98 @@ func (o *PersistOptions) CheckLabelProperty(typ string, labels []*metapb.StoreLa
 	return false
 }
 
-const ttlConfigPrefix = "/config/ttl"
+const (
+	ttlConfigPrefix = "/config/ttl"
+	// ttlOriginPrefix is the path of the origins of the temporary
+	// configurations, which share the leases with the configurations.
+	ttlOriginPrefix = "/config/ttl-origin"
+)
 
-// SetTTLData set temporary configuration
-func (o *PersistOptions) SetTTLData(parCtx context.Context, client *clientv3.Client, key string, value string, ttl time.Duration) error {
+// SetTTLData set temporary configuration. The origin tells who sets it, which
+// can be empty.
+func (o *PersistOptions) SetTTLData(parCtx context.Context, client *clientv3.Client, key string, value string, ttl time.Duration, origin string) error {
 	if o.ttl == nil {
 		o.ttl = cache.NewStringTTL(parCtx, time.Second*5, time.Minute*5)
 	}
-	_, err := etcdutil.EtcdKVPutWithTTL(parCtx, client, ttlConfigPrefix+"/"+key, value, int64(ttl.Seconds()))
+	grantResp, err := client.Grant(parCtx, int64(ttl.Seconds()))
 	if err != nil {
 		return err
 	}
+	ops := []clientv3.Op{clientv3.OpPut(ttlConfigPrefix+"/"+key, value, clientv3.WithLease(grantResp.ID))}
+	if origin != "" {
+		ops = append(ops, clientv3.OpPut(ttlOriginPrefix+"/"+key, origin, clientv3.WithLease(grantResp.ID)))
+	}
+	if _, err := client.Txn(parCtx).Then(ops...).Commit(); err != nil {
+		return err
+	}
 	o.ttl.PutWithTTL(key, value, ttl)
 	return nil
 }
 
+// TTLConfig is an active temporary configuration.
+type TTLConfig struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// TTL is the remaining time to live in seconds.
+	TTL    int64  `json:"ttl"`
+	Origin string `json:"origin,omitempty"`
+}
+
+// GetTTLConfigs returns the active temporary configurations persisted in etcd.
+func (o *PersistOptions) GetTTLConfigs(ctx context.Context, client *clientv3.Client) ([]*TTLConfig, error) {
+	resps, err := etcdutil.EtcdKVGet(client, ttlConfigPrefix+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	origins, err := etcdutil.EtcdKVGet(client, ttlOriginPrefix+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	originOf := make(map[string]string, len(origins.Kvs))
+	for _, kv := range origins.Kvs {
+		originOf[string(kv.Key)[len(ttlOriginPrefix)+1:]] = string(kv.Value)
+	}
+	configs := make([]*TTLConfig, 0, len(resps.Kvs))
+	for _, kv := range resps.Kvs {
+		resp, err := client.TimeToLive(ctx, clientv3.LeaseID(kv.Lease))
+		if err != nil {
+			return nil, err
+		}
+		// The lease has expired but the key is not deleted yet.
+		if resp.TTL <= 0 {
+			continue
+		}
+		key := string(kv.Key)[len(ttlConfigPrefix)+1:]
+		configs = append(configs, &TTLConfig{
+			Key:    key,
+			Value:  string(kv.Value),
+			TTL:    resp.TTL,
+			Origin: originOf[key],
+		})
+	}
+	return configs, nil
+}
+
+// DeleteTTLData cancels the temporary configuration before it expires. It
+// returns false if the configuration is not active.
+func (o *PersistOptions) DeleteTTLData(ctx context.Context, client *clientv3.Client, key string) (bool, error) {
+	resp, err := etcdutil.EtcdKVGet(client, ttlConfigPrefix+"/"+key)
+	if err != nil {
+		return false, err
+	}
+	if len(resp.Kvs) == 0 {
+		return false, nil
+	}
+	// Revoking the lease deletes the configuration and its origin at once.
+	if _, err := client.Revoke(ctx, clientv3.LeaseID(resp.Kvs[0].Lease)); err != nil {
+		return false, err
+	}
+	if o.ttl != nil {
+		o.ttl.Remove(key)
+	}
+	return true, nil
+}
+
 func (o *PersistOptions) getTTLUint(key string) (uint64, bool, error) {
 	stringForm, ok := o.getTTLData(key)
 	if !ok {
@@ -681,7 +918,7 @@ func (o *PersistOptions) getTTLData(key string) (string, bool) {
 
 // LoadTTLFromEtcd loads temporary configuration which was persisted into etcd
 func (o *PersistOptions) LoadTTLFromEtcd(ctx context.Context, client *clientv3.Client) error {
-	resps, err := etcdutil.EtcdKVGet(client, ttlConfigPrefix, clientv3.WithPrefix())
+	resps, err := etcdutil.EtcdKVGet(client, ttlConfigPrefix+"/", clientv3.WithPrefix())
 	if err != nil {
 		return err
 	}
@@ -702,13 +939,13 @@ func (o *PersistOptions) LoadTTLFromEtcd(ctx context.Context, client *clientv3.C
 }
 
 // SetAllStoresLimitTTL sets all store limit for a given type and rate with ttl.
-func (o *PersistOptions) SetAllStoresLimitTTL(ctx context.Context, client *clientv3.Client, typ storelimit.Type, ratePerMin float64, ttl time.Duration) error {
+func (o *PersistOptions) SetAllStoresLimitTTL(ctx context.Context, client *clientv3.Client, typ storelimit.Type, ratePerMin float64, ttl time.Duration, origin string) error {
 	var err error
 	switch typ {
 	case storelimit.AddPeer:
-		err = o.SetTTLData(ctx, client, "default-add-peer", fmt.Sprint(ratePerMin), ttl)
+		err = o.SetTTLData(ctx, client, "default-add-peer", fmt.Sprint(ratePerMin), ttl, origin)
 	case storelimit.RemovePeer:
-		err = o.SetTTLData(ctx, client, "default-remove-peer", fmt.Sprint(ratePerMin), ttl)
+		err = o.SetTTLData(ctx, client, "default-remove-peer", fmt.Sprint(ratePerMin), ttl, origin)
 	}
 	return err
 }
@@ -0,0 +1,187 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/eventbus"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/confighistory"
+	"go.uber.org/zap"
+)
+
+// configSnapshot is the part of the config persisted in etcd, which is
+// recorded in the config history.
+type configSnapshot struct {
+	Schedule        config.ScheduleConfig        `json:"schedule"`
+	Replication     config.ReplicationConfig     `json:"replication"`
+	PDServerCfg     config.PDServerConfig        `json:"pd-server"`
+	ReplicationMode config.ReplicationModeConfig `json:"replication-mode"`
+	LabelProperty   config.LabelPropertyConfig   `json:"label-property"`
+	ClusterVersion  string                       `json:"cluster-version"`
+}
+
+func newConfigSnapshot(cfg *config.Config) *configSnapshot {
+	return &configSnapshot{
+		Schedule:        cfg.Schedule,
+		Replication:     cfg.Replication,
+		PDServerCfg:     cfg.PDServerCfg,
+		ReplicationMode: cfg.ReplicationMode,
+		LabelProperty:   cfg.LabelProperty,
+		ClusterVersion:  cfg.ClusterVersion.String(),
+	}
+}
+
+// recordConfigHistory is called after the config is persisted.
+func (s *Server) recordConfigHistory(cfg *config.Config) {
+	if _, err := s.configHistory.Record(newConfigSnapshot(cfg)); err != nil {
+		log.Error("failed to record the config history", errs.ZapError(err))
+	}
+}
+
+// initConfigHistory records the current config when the member becomes the
+// leader, so that the first change has a version to compare with and to roll
+// back to.
+func (s *Server) initConfigHistory() {
+	s.configHistory.Reset()
+	s.recordConfigHistory(&config.Config{
+		Schedule:        *s.persistOptions.GetScheduleConfig(),
+		Replication:     *s.persistOptions.GetReplicationConfig(),
+		PDServerCfg:     *s.persistOptions.GetPDServerConfig(),
+		ReplicationMode: *s.persistOptions.GetReplicationModeConfig(),
+		LabelProperty:   s.persistOptions.GetLabelPropertyConfig(),
+		ClusterVersion:  *s.persistOptions.GetClusterVersion(),
+	})
+}
+
+// GetConfigHistory returns the latest versions of the config, newest first.
+func (s *Server) GetConfigHistory(limit int) ([]*confighistory.Entry, error) {
+	return s.configHistory.List(limit)
+}
+
+// GetConfigVersion returns the version of the config with its content.
+func (s *Server) GetConfigVersion(version uint64) (*confighistory.Entry, error) {
+	entry, err := s.configHistory.Get(version)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, errs.ErrConfigVersionNotFound.FastGenByArgs(version)
+	}
+	return entry, nil
+}
+
+// WithConfigOperator attributes the config changes made by f to the operator
+// in the config history.
+func (s *Server) WithConfigOperator(operator string, f func()) {
+	s.configHistory.WithAuthor(confighistory.Author{Operator: operator}, f)
+}
+
+// RollbackConfig rolls the config back to the version in the config history.
+// The config is persisted in a single write and the rollback is recorded as a
+// single new version. The schedulers are not rolled back since they are
+// managed by the scheduler API, and neither is the cluster version since it
+// cannot be downgraded.
+func (s *Server) RollbackConfig(version uint64, operator string) error {
+	entry, err := s.GetConfigVersion(version)
+	if err != nil {
+		return err
+	}
+	snapshot := &configSnapshot{}
+	if err := json.Unmarshal(entry.Config, snapshot); err != nil {
+		return errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	snapshot.Schedule.Schedulers = s.persistOptions.GetScheduleConfig().Schedulers
+	snapshot.Schedule.SchedulersPayload = nil
+	if err := s.validateConfigRollback(snapshot); err != nil {
+		return err
+	}
+
+	o := s.persistOptions
+	oldSchedule, oldReplication, oldPDServer := o.GetScheduleConfig(), o.GetReplicationConfig(), o.GetPDServerConfig()
+	oldLabelProperty, oldReplicationMode := o.GetLabelPropertyConfig(), o.GetReplicationModeConfig()
+	o.SetScheduleConfig(&snapshot.Schedule)
+	o.SetReplicationConfig(&snapshot.Replication)
+	o.SetPDServerConfig(&snapshot.PDServerCfg)
+	o.SetLabelPropertyConfig(snapshot.LabelProperty)
+	o.SetReplicationModeConfig(&snapshot.ReplicationMode)
+	cfg, err := o.Save(s.storage)
+	if err != nil {
+		o.SetScheduleConfig(oldSchedule)
+		o.SetReplicationConfig(oldReplication)
+		o.SetPDServerConfig(oldPDServer)
+		o.SetLabelPropertyConfig(oldLabelProperty)
+		o.SetReplicationModeConfig(oldReplicationMode)
+		log.Error("failed to roll back the config", zap.Uint64("version", version), errs.ZapError(err))
+		return err
+	}
+	author := confighistory.Author{Operator: operator, RollbackTo: version}
+	if _, err := s.configHistory.RecordBy(newConfigSnapshot(cfg), author); err != nil {
+		log.Error("failed to record the config history", errs.ZapError(err))
+	}
+
+	s.callerLimiter.SetQuotas(snapshot.PDServerCfg.CallerRateLimits)
+	if rc := s.GetRaftCluster(); rc != nil {
+		rc.SetRegionHeartbeatFairQueueConcurrency(snapshot.PDServerCfg.RegionHeartbeatFairQueueConcurrency)
+		if err := rc.GetReplicationMode().UpdateConfig(snapshot.ReplicationMode); err != nil {
+			log.Warn("failed to update replication mode", errs.ZapError(err))
+		}
+	}
+	for _, section := range []string{"schedule", "replication", "pd-server", "label-property", "replication-mode"} {
+		s.eventBus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: section})
+	}
+	log.Info("config is rolled back", zap.Uint64("version", version), zap.String("operator", operator))
+	return nil
+}
+
+// validateConfigRollback validates the sections of the config like they are
+// updated by the API. The placement rules are not rolled back since they are
+// managed by the rule API, so the rollback fails if it changes them.
+func (s *Server) validateConfigRollback(snapshot *configSnapshot) error {
+	if err := snapshot.Schedule.Validate(); err != nil {
+		return err
+	}
+	if err := snapshot.Schedule.Deprecated(); err != nil {
+		return err
+	}
+	if err := snapshot.Replication.Validate(); err != nil {
+		return err
+	}
+	old := s.persistOptions.GetReplicationConfig()
+	if snapshot.Replication.EnablePlacementRules != old.EnablePlacementRules ||
+		(old.EnablePlacementRules && (snapshot.Replication.MaxReplicas != old.MaxReplicas ||
+			!reflect.DeepEqual(snapshot.Replication.LocationLabels, old.LocationLabels))) {
+		return errors.New("cannot roll back the placement rules, please update them by the rule API")
+	}
+	switch address := snapshot.PDServerCfg.DashboardAddress; address {
+	case "auto", "none":
+	default:
+		if !cluster.IsClientURL(address, s.client) {
+			return errors.Errorf("%s is not the client url of any member", address)
+		}
+	}
+	if err := snapshot.PDServerCfg.Validate(); err != nil {
+		return err
+	}
+	if config.NormalizeReplicationMode(snapshot.ReplicationMode.ReplicationMode) == "" {
+		return errors.Errorf("invalid replication mode: %v", snapshot.ReplicationMode.ReplicationMode)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confighistory
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/kv"
+	"go.etcd.io/etcd/clientv3"
+	"go.uber.org/zap"
+)
+
+const (
+	historyPath = "config_history"
+	// MaxEntries is the number of the latest versions kept in the history.
+	MaxEntries = 100
+)
+
+// Change is the change of a config item, such as "schedule.leader-schedule-limit".
+type Change struct {
+	Item string      `json:"item"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// Entry is a version of the persisted config.
+type Entry struct {
+	Version uint64    `json:"version"`
+	Time    time.Time `json:"time"`
+	// Member is the PD member which persists the config.
+	Member string `json:"member"`
+	// Operator is the caller which changes the config. It is empty if PD
+	// changes the config by itself, such as adding the default schedulers.
+	Operator string `json:"operator,omitempty"`
+	// RollbackTo is the version which the config is rolled back to, if the
+	// change is made by a rollback.
+	RollbackTo uint64 `json:"rollback_to,omitempty"`
+	// Changes are empty for the first version, since there is nothing to
+	// compare with.
+	Changes []*Change `json:"changes"`
+	// Config is the persisted config of the version.
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// Author is who makes the changes of the config.
+type Author struct {
+	Operator   string
+	RollbackTo uint64
+}
+
+// History records every version of the persisted config with who makes the
+// change and what is changed, so that the config can be rolled back to a
+// version later. It keeps the latest MaxEntries versions.
+type History struct {
+	storage kv.Base
+	member  string
+
+	// authorMu serializes the changes made by the authors.
+	authorMu sync.Mutex
+	mu       sync.Mutex
+	author   Author
+	// last is the latest version, which is loaded lazily since another
+	// leader may have changed it.
+	last   *Entry
+	loaded bool
+}
+
+// NewHistory creates a History.
+func NewHistory(storage kv.Base, member string) *History {
+	return &History{
+		storage: storage,
+		member:  member,
+	}
+}
+
+// Reset drops the cached latest version. It is called when the member becomes
+// the leader, since the other leaders may have recorded new versions.
+func (h *History) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last, h.loaded = nil, false
+}
+
+// WithAuthor attributes the changes of the config made by f to the author.
+// The changes made by PD itself at the same time are attributed to the author
+// too, which is rare since PD seldom changes the config by itself.
+func (h *History) WithAuthor(author Author, f func()) {
+	h.authorMu.Lock()
+	defer h.authorMu.Unlock()
+	h.setAuthor(author)
+	defer h.setAuthor(Author{})
+	f()
+}
+
+func (h *History) setAuthor(author Author) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.author = author
+}
+
+// Record records the config as a new version if it differs from the latest
+// version, and returns the new version or nil if there is no change. The
+// version is attributed to the author of WithAuthor.
+func (h *History) Record(cfg interface{}) (*Entry, error) {
+	h.mu.Lock()
+	author := h.author
+	h.mu.Unlock()
+	return h.RecordBy(cfg, author)
+}
+
+// RecordBy is like Record, but attributes the version to the author.
+func (h *History) RecordBy(cfg interface{}, author Author) (*Entry, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.loaded {
+		entries, err := h.load()
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			h.last = entries[len(entries)-1]
+		}
+		h.loaded = true
+	}
+	entry := &Entry{
+		Version:    1,
+		Time:       time.Now(),
+		Member:     h.member,
+		Operator:   author.Operator,
+		RollbackTo: author.RollbackTo,
+		Config:     data,
+	}
+	if h.last != nil {
+		entry.Version = h.last.Version + 1
+		entry.Changes, err = diff(h.last.Config, data)
+		if err != nil {
+			return nil, err
+		}
+		if len(entry.Changes) == 0 {
+			return nil, nil
+		}
+	}
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return nil, errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	if err := h.storage.Save(versionPath(entry.Version), string(value)); err != nil {
+		return nil, err
+	}
+	h.last = entry
+	if entry.Version > MaxEntries {
+		if err := h.storage.Remove(versionPath(entry.Version - MaxEntries)); err != nil {
+			log.Warn("failed to remove the expired config version",
+				zap.Uint64("version", entry.Version-MaxEntries), errs.ZapError(err))
+		}
+	}
+	log.Info("config version is recorded",
+		zap.Uint64("version", entry.Version),
+		zap.String("operator", entry.Operator),
+		zap.Int("changes", len(entry.Changes)))
+	return entry, nil
+}
+
+// List returns the latest versions without the config, newest first. It
+// returns all the versions kept if limit is not positive.
+func (h *History) List(limit int) ([]*Entry, error) {
+	entries, err := h.load()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Entry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		entry := *entries[i]
+		entry.Config = nil
+		res = append(res, &entry)
+	}
+	return res, nil
+}
+
+// Get returns the version with its config, or nil if it is not kept.
+func (h *History) Get(version uint64) (*Entry, error) {
+	value, err := h.storage.Load(versionPath(version))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	entry := &Entry{}
+	if err := json.Unmarshal([]byte(value), entry); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return entry, nil
+}
+
+// load loads all the versions kept, oldest first.
+func (h *History) load() ([]*Entry, error) {
+	prefix := historyPath + "/"
+	_, values, err := h.storage.LoadRange(prefix, clientv3.GetPrefixRangeEnd(prefix), 0)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, 0, len(values))
+	for _, value := range values {
+		entry := &Entry{}
+		if err := json.Unmarshal([]byte(value), entry); err != nil {
+			return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func versionPath(version uint64) string {
+	return path.Join(historyPath, fmt.Sprintf("%020d", version))
+}
+
+// diff returns the changes of the items from the old config to the new one.
+func diff(old, new []byte) ([]*Change, error) {
+	var oldItems, newItems map[string]interface{}
+	if err := json.Unmarshal(old, &oldItems); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	if err := json.Unmarshal(new, &newItems); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	oldFlat, newFlat := make(map[string]interface{}), make(map[string]interface{})
+	flatten("", oldItems, oldFlat)
+	flatten("", newItems, newFlat)
+	var changes []*Change
+	for item, newValue := range newFlat {
+		if oldValue, ok := oldFlat[item]; !ok || !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, &Change{Item: item, Old: oldFlat[item], New: newValue})
+		}
+	}
+	for item, oldValue := range oldFlat {
+		if _, ok := newFlat[item]; !ok {
+			changes = append(changes, &Change{Item: item, Old: oldValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Item < changes[j].Item })
+	return changes, nil
+}
+
+// flatten flattens the nested objects into the items joined by dots. The
+// arrays are compared as a whole.
+func flatten(prefix string, items map[string]interface{}, res map[string]interface{}) {
+	for k, v := range items {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			flatten(k, m, res)
+			continue
+		}
+		res[k] = v
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confighistory
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/tikv/pd/server/kv"
+)
+
+func TestConfigHistory(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testHistorySuite{})
+
+type testHistorySuite struct{}
+
+type testConfig struct {
+	Schedule struct {
+		Limit  int      `json:"limit"`
+		Labels []string `json:"labels"`
+	} `json:"schedule"`
+	Version string `json:"version"`
+}
+
+func (s *testHistorySuite) TestRecord(c *C) {
+	storage := kv.NewMemoryKV()
+	h := NewHistory(storage, "pd1")
+	cfg := &testConfig{}
+	cfg.Schedule.Limit = 4
+	entry, err := h.Record(cfg)
+	c.Assert(err, IsNil)
+	c.Assert(entry.Version, Equals, uint64(1))
+	c.Assert(entry.Changes, HasLen, 0)
+	// The same config is not recorded again.
+	entry, err = h.Record(cfg)
+	c.Assert(err, IsNil)
+	c.Assert(entry, IsNil)
+
+	cfg.Schedule.Limit = 8
+	cfg.Schedule.Labels = []string{"zone"}
+	h.WithAuthor(Author{Operator: "pd-ctl@127.0.0.1"}, func() {
+		entry, err = h.Record(cfg)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(entry.Version, Equals, uint64(2))
+	c.Assert(entry.Member, Equals, "pd1")
+	c.Assert(entry.Operator, Equals, "pd-ctl@127.0.0.1")
+	c.Assert(entry.Changes, HasLen, 2)
+	c.Assert(entry.Changes[0].Item, Equals, "schedule.labels")
+	c.Assert(entry.Changes[0].Old, IsNil)
+	c.Assert(entry.Changes[1].Item, Equals, "schedule.limit")
+	c.Assert(entry.Changes[1].Old, Equals, float64(4))
+	c.Assert(entry.Changes[1].New, Equals, float64(8))
+
+	// Another leader continues with the versions in the storage.
+	h = NewHistory(storage, "pd2")
+	cfg.Version = "5.0.0"
+	entry, err = h.Record(cfg)
+	c.Assert(err, IsNil)
+	c.Assert(entry.Version, Equals, uint64(3))
+	c.Assert(entry.Operator, Equals, "")
+	c.Assert(entry.Changes, HasLen, 1)
+	c.Assert(entry.Changes[0].Item, Equals, "version")
+
+	cfg.Schedule.Limit = 4
+	entry, err = h.RecordBy(cfg, Author{Operator: "pd-ctl@127.0.0.1", RollbackTo: 1})
+	c.Assert(err, IsNil)
+	c.Assert(entry.Version, Equals, uint64(4))
+	c.Assert(entry.Operator, Equals, "pd-ctl@127.0.0.1")
+	c.Assert(entry.RollbackTo, Equals, uint64(1))
+
+	entries, err := h.List(2)
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].Version, Equals, uint64(4))
+	c.Assert(entries[1].Version, Equals, uint64(3))
+	c.Assert(entries[0].Config, IsNil)
+	entry, err = h.Get(1)
+	c.Assert(err, IsNil)
+	c.Assert(string(entry.Config), Equals, `{"schedule":{"limit":4,"labels":null},"version":""}`)
+	entry, err = h.Get(5)
+	c.Assert(err, IsNil)
+	c.Assert(entry, IsNil)
+}
+
+func (s *testHistorySuite) TestExpire(c *C) {
+	h := NewHistory(kv.NewMemoryKV(), "pd1")
+	cfg := &testConfig{}
+	for i := 0; i < MaxEntries+10; i++ {
+		cfg.Schedule.Limit = i
+		_, err := h.Record(cfg)
+		c.Assert(err, IsNil)
+	}
+	entries, err := h.List(0)
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, MaxEntries)
+	c.Assert(entries[0].Version, Equals, uint64(MaxEntries+10))
+	c.Assert(entries[MaxEntries-1].Version, Equals, uint64(11))
+}
@@ -89,6 +89,14 @@ func (bc *BasicCluster) GetStoreRegions(storeID uint64) []*RegionInfo {
 	return bc.Regions.GetStoreRegions(storeID)
 }
 
+// ScanStoreRegions scans the regions which have peers on the given store in
+// key order, starting from the region that contains startKey.
+func (bc *BasicCluster) ScanStoreRegions(storeID uint64, startKey []byte, limit int) []*RegionInfo {
+	bc.RLock()
+	defer bc.RUnlock()
+	return bc.Regions.ScanStoreRegions(storeID, startKey, limit)
+}
+
 // GetRegionStores returns all Stores that contains the region's peer.
 func (bc *BasicCluster) GetRegionStores(region *RegionInfo) []*StoreInfo {
 	bc.RLock()
@@ -145,6 +153,14 @@ func (bc *BasicCluster) ResumeLeaderTransfer(storeID uint64) {
 	bc.Stores.ResumeLeaderTransfer(storeID)
 }
 
+// SetStoreReservedSize sets the size in MB reserved on the store for the
+// offline stores.
+func (bc *BasicCluster) SetStoreReservedSize(storeID uint64, size int64) {
+	bc.Lock()
+	defer bc.Unlock()
+	bc.Stores.SetStoreReservedSize(storeID, size)
+}
+
 // AttachAvailableFunc attaches an available function to a specific store.
 func (bc *BasicCluster) AttachAvailableFunc(storeID uint64, limitType storelimit.Type, f func() bool) {
 	bc.Lock()
@@ -290,7 +306,7 @@ func (bc *BasicCluster) PreCheckPutRegion(region *RegionInfo) (*RegionInfo, erro
 		for _, item := range bc.Regions.GetOverlaps(region) {
 			if region.GetRegionEpoch().GetVersion() < item.GetRegionEpoch().GetVersion() {
 				bc.RUnlock()
-				return nil, errRegionIsStale(region.GetMeta(), item.GetMeta())
+				return nil, errRegionIsStale(region, item)
 			}
 		}
 	}
@@ -306,7 +322,7 @@ func (bc *BasicCluster) PreCheckPutRegion(region *RegionInfo) (*RegionInfo, erro
 
 	// Region meta is stale, return an error.
 	if r.GetVersion() < o.GetVersion() || r.GetConfVer() < o.GetConfVer() || isTermBehind {
-		return origin, errRegionIsStale(region.GetMeta(), origin.GetMeta())
+		return origin, errRegionIsStale(region, origin)
 	}
 
 	return origin, nil
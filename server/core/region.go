@@ -23,15 +23,41 @@ import (
 	"unsafe"
 
 	"github.com/gogo/protobuf/proto"
-	"github.com/pingcap/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/kvproto/pkg/replication_modepb"
 )
 
+// RegionStaleError is the error that a reported region is staler than the
+// region in the cluster, which is either the same region or a region
+// overlapping with it.
+type RegionStaleError struct {
+	Region *RegionInfo
+	Origin *RegionInfo
+}
+
+func (e *RegionStaleError) Error() string {
+	return fmt.Sprintf("region is stale: region %v origin %v", e.Region.GetMeta(), e.Origin.GetMeta())
+}
+
+// Reason returns why the region is stale.
+func (e *RegionStaleError) Reason() string {
+	r, o := e.Region.GetRegionEpoch(), e.Origin.GetRegionEpoch()
+	switch {
+	case e.Region.GetID() != e.Origin.GetID():
+		return "overlapped"
+	case r.GetVersion() < o.GetVersion():
+		return "stale-version"
+	case r.GetConfVer() < o.GetConfVer():
+		return "stale-conf-ver"
+	default:
+		return "stale-term"
+	}
+}
+
 // errRegionIsStale is error info for region is stale.
-func errRegionIsStale(region *metapb.Region, origin *metapb.Region) error {
-	return errors.Errorf("region is stale: region %v origin %v", region, origin)
+func errRegionIsStale(region *RegionInfo, origin *RegionInfo) error {
+	return &RegionStaleError{Region: region, Origin: origin}
 }
 
 // RegionInfo records detail region info.
@@ -748,6 +774,47 @@ func (s peerSlice) Less(i, j int) bool {
 	return s[i].GetId() < s[j].GetId()
 }
 
+// IsHeartbeatUnchanged returns whether the heartbeat of the region carries
+// nothing new compared to the origin in the cache: the epoch, leader, peers
+// and approximate size are the same, no peer is down or pending, and each flow
+// differs by at most the threshold ratio.
+func (r *RegionInfo) IsHeartbeatUnchanged(origin *RegionInfo, flowThreshold float64) bool {
+	if origin == nil {
+		return false
+	}
+	if len(r.downPeers) > 0 || len(r.pendingPeers) > 0 || len(origin.downPeers) > 0 || len(origin.pendingPeers) > 0 {
+		return false
+	}
+	epoch, originEpoch := r.GetRegionEpoch(), origin.GetRegionEpoch()
+	if epoch.GetVersion() != originEpoch.GetVersion() || epoch.GetConfVer() != originEpoch.GetConfVer() {
+		return false
+	}
+	if r.GetLeader().GetId() != origin.GetLeader().GetId() || r.term != origin.term {
+		return false
+	}
+	if !SortedPeersEqual(r.GetVoters(), origin.GetVoters()) || !SortedPeersEqual(r.GetLearners(), origin.GetLearners()) {
+		return false
+	}
+	if r.approximateSize != origin.approximateSize || r.approximateKeys != origin.approximateKeys {
+		return false
+	}
+	if r.replicationStatus.GetState() != origin.replicationStatus.GetState() ||
+		r.replicationStatus.GetStateId() != origin.replicationStatus.GetStateId() {
+		return false
+	}
+	return isFlowClose(r.writtenBytes, origin.writtenBytes, flowThreshold) &&
+		isFlowClose(r.writtenKeys, origin.writtenKeys, flowThreshold) &&
+		isFlowClose(r.readBytes, origin.readBytes, flowThreshold) &&
+		isFlowClose(r.readKeys, origin.readKeys, flowThreshold)
+}
+
+func isFlowClose(a, b uint64, threshold float64) bool {
+	if a < b {
+		a, b = b, a
+	}
+	return float64(a-b) <= float64(a)*threshold
+}
+
 // SortedPeersEqual judges whether two sorted `peerSlice` are equal
 func SortedPeersEqual(peersA, peersB []*metapb.Peer) bool {
 	if len(peersA) != len(peersB) {
@@ -852,6 +919,32 @@ func (r *RegionsInfo) GetStoreRegions(storeID uint64) []*RegionInfo {
 	return regions
 }
 
+// ScanStoreRegions scans the regions which have peers on the given store in
+// key order, starting from the region that contains startKey, and returns at
+// most limit regions. It returns all of them if limit <= 0.
+func (r *RegionsInfo) ScanStoreRegions(storeID uint64, startKey []byte, limit int) []*RegionInfo {
+	var regions []*RegionInfo
+	for _, trees := range []map[uint64]*regionSubTree{r.leaders, r.followers, r.learners} {
+		tree, ok := trees[storeID]
+		if !ok {
+			continue
+		}
+		var count int
+		tree.scanRange(startKey, func(region *RegionInfo) bool {
+			regions = append(regions, region)
+			count++
+			return limit <= 0 || count < limit
+		})
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return bytes.Compare(regions[i].GetStartKey(), regions[j].GetStartKey()) < 0
+	})
+	if limit > 0 && len(regions) > limit {
+		regions = regions[:limit]
+	}
+	return regions
+}
+
 // GetStoreLeaderRegionSize get total size of store's leader regions
 func (r *RegionsInfo) GetStoreLeaderRegionSize(storeID uint64) int64 {
 	return r.leaders[storeID].TotalSize()
@@ -0,0 +1,119 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// The region snapshot is a compact dump of the regions with their statistics,
+// which can be loaded into another cluster or the simulator to replay the
+// region distribution. It starts with regionSnapshotMagic, followed by the
+// regions, each of which is encoded as a RegionHeartbeatRequest prefixed with
+// its length as a uvarint.
+const (
+	regionSnapshotMagic = "PDRS\x01"
+	// maxRegionSnapshotItemSize is the max size of a region in the snapshot,
+	// which guards against the corrupted lengths.
+	maxRegionSnapshotItemSize = 64 << 20
+)
+
+// RegionToHeartbeat converts the region to the heartbeat it is created from,
+// which is the reverse of RegionFromHeartbeat.
+func RegionToHeartbeat(r *RegionInfo) *pdpb.RegionHeartbeatRequest {
+	return &pdpb.RegionHeartbeatRequest{
+		Term:              r.term,
+		Region:            r.meta,
+		Leader:            r.leader,
+		DownPeers:         r.downPeers,
+		PendingPeers:      r.pendingPeers,
+		BytesWritten:      r.writtenBytes,
+		KeysWritten:       r.writtenKeys,
+		BytesRead:         r.readBytes,
+		KeysRead:          r.readKeys,
+		ApproximateSize:   uint64(r.approximateSize) << 20,
+		ApproximateKeys:   uint64(r.approximateKeys),
+		Interval:          r.interval,
+		ReplicationStatus: r.replicationStatus,
+	}
+}
+
+// WriteRegionSnapshot writes the regions to w as a region snapshot.
+func WriteRegionSnapshot(w io.Writer, regions []*RegionInfo) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(regionSnapshotMagic); err != nil {
+		return errors.WithStack(err)
+	}
+	var lenBuf [binary.MaxVarintLen64]byte
+	for _, region := range regions {
+		data, err := RegionToHeartbeat(region).Marshal()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(data)))
+		if _, err := bw.Write(lenBuf[:n]); err != nil {
+			return errors.WithStack(err)
+		}
+		if _, err := bw.Write(data); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(bw.Flush())
+}
+
+// ReadRegionSnapshot reads the regions from a region snapshot, and calls f
+// with each of them in order. It stops at the first error returned by f.
+func ReadRegionSnapshot(r io.Reader, f func(*RegionInfo) error) error {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(regionSnapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != regionSnapshotMagic {
+		return errs.ErrInvalidRegionSnapshot.FastGenByArgs("unknown format")
+	}
+	var data []byte
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errs.ErrInvalidRegionSnapshot.FastGenByArgs("failed to read the length: " + err.Error())
+		}
+		if size > maxRegionSnapshotItemSize {
+			return errs.ErrInvalidRegionSnapshot.FastGenByArgs("the region is too large")
+		}
+		if uint64(cap(data)) < size {
+			data = make([]byte, size)
+		}
+		data = data[:size]
+		if _, err := io.ReadFull(br, data); err != nil {
+			return errs.ErrInvalidRegionSnapshot.FastGenByArgs("failed to read the region: " + err.Error())
+		}
+		heartbeat := &pdpb.RegionHeartbeatRequest{}
+		if err := heartbeat.Unmarshal(data); err != nil {
+			return errs.ErrInvalidRegionSnapshot.FastGenByArgs("failed to decode the region: " + err.Error())
+		}
+		if heartbeat.GetRegion() == nil {
+			return errs.ErrInvalidRegionSnapshot.FastGenByArgs("the region meta is missing")
+		}
+		if err := f(RegionFromHeartbeat(heartbeat)); err != nil {
+			return err
+		}
+	}
+}
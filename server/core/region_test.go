@@ -14,6 +14,7 @@
 package core
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
 	"strconv"
@@ -23,6 +24,7 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/mock/mockid"
 	"github.com/tikv/pd/server/id"
 )
@@ -35,6 +37,70 @@ var _ = Suite(&testRegionInfoSuite{})
 
 type testRegionInfoSuite struct{}
 
+func (s *testRegionInfoSuite) TestHeartbeatUnchanged(c *C) {
+	peers := []*metapb.Peer{{Id: 1, StoreId: 1}, {Id: 2, StoreId: 2}}
+	meta := &metapb.Region{Id: 1, Peers: peers, RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1}}
+	origin := NewRegionInfo(meta, peers[0], SetApproximateSize(10), SetWrittenBytes(1000))
+
+	c.Assert(origin.Clone().IsHeartbeatUnchanged(nil, 0.1), IsFalse)
+	c.Assert(origin.Clone().IsHeartbeatUnchanged(origin, 0.1), IsTrue)
+	c.Assert(origin.Clone(SetWrittenBytes(950)).IsHeartbeatUnchanged(origin, 0.1), IsTrue)
+	c.Assert(origin.Clone(SetWrittenBytes(2000)).IsHeartbeatUnchanged(origin, 0.1), IsFalse)
+	c.Assert(origin.Clone(WithLeader(peers[1])).IsHeartbeatUnchanged(origin, 0.1), IsFalse)
+	c.Assert(origin.Clone(WithIncVersion()).IsHeartbeatUnchanged(origin, 0.1), IsFalse)
+	c.Assert(origin.Clone(SetApproximateSize(20)).IsHeartbeatUnchanged(origin, 0.1), IsFalse)
+	c.Assert(origin.Clone(WithPendingPeers(peers[1:])).IsHeartbeatUnchanged(origin, 0.1), IsFalse)
+}
+
+func (s *testRegionInfoSuite) TestRegionSnapshot(c *C) {
+	var regions []*RegionInfo
+	for i := uint64(1); i <= 10; i++ {
+		peers := []*metapb.Peer{{Id: i * 10, StoreId: 1}, {Id: i*10 + 1, StoreId: 2}}
+		meta := &metapb.Region{
+			Id:          i,
+			StartKey:    []byte(fmt.Sprintf("%20d", i)),
+			EndKey:      []byte(fmt.Sprintf("%20d", i+1)),
+			Peers:       peers,
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: i, Version: i},
+		}
+		regions = append(regions, NewRegionInfo(meta, peers[0],
+			SetApproximateSize(int64(i)),
+			SetApproximateKeys(int64(i*100)),
+			SetWrittenBytes(i*1000),
+			SetReadBytes(i*2000),
+			WithPendingPeers(peers[1:]),
+		))
+	}
+
+	var buf bytes.Buffer
+	c.Assert(WriteRegionSnapshot(&buf, regions), IsNil)
+	data := buf.Bytes()
+	var loaded []*RegionInfo
+	err := ReadRegionSnapshot(bytes.NewReader(data), func(region *RegionInfo) error {
+		loaded = append(loaded, region)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(loaded, HasLen, len(regions))
+	for i, region := range loaded {
+		c.Assert(region.GetMeta(), DeepEquals, regions[i].GetMeta())
+		c.Assert(region.GetLeader(), DeepEquals, regions[i].GetLeader())
+		c.Assert(region.GetPendingPeers(), DeepEquals, regions[i].GetPendingPeers())
+		c.Assert(region.GetApproximateSize(), Equals, regions[i].GetApproximateSize())
+		c.Assert(region.GetApproximateKeys(), Equals, regions[i].GetApproximateKeys())
+		c.Assert(region.GetBytesWritten(), Equals, regions[i].GetBytesWritten())
+		c.Assert(region.GetBytesRead(), Equals, regions[i].GetBytesRead())
+	}
+
+	// The snapshots with an unknown format or truncated are rejected.
+	noop := func(*RegionInfo) error { return nil }
+	err = ReadRegionSnapshot(bytes.NewReader([]byte("PDRS\x02")), noop)
+	c.Assert(errs.ErrInvalidRegionSnapshot.Equal(err), IsTrue)
+	err = ReadRegionSnapshot(bytes.NewReader(data[:len(data)-1]), noop)
+	c.Assert(errs.ErrInvalidRegionSnapshot.Equal(err), IsTrue)
+	c.Assert(ReadRegionSnapshot(bytes.NewReader(data[:len(regionSnapshotMagic)]), noop), IsNil)
+}
+
 func (s *testRegionInfoSuite) TestSortedEqual(c *C) {
 	testcases := []struct {
 		idsA    []uint64
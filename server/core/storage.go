@@ -46,6 +46,7 @@ const (
 	componentPath              = "component"
 	customScheduleConfigPath   = "scheduler_config"
 	encryptionKeysPath         = "encryption_keys"
+	splitReportPath            = "split_report"
 	gcWorkerServiceSafePointID = "gc_worker"
 )
 
@@ -58,6 +59,7 @@ const (
 type Storage struct {
 	kv.Base
 	regionStorage        *RegionStorage
+	hotRegionStorage     *kv.LeveldbKV
 	encryptionKeyManager *encryptionkm.KeyManager
 	useRegionStorage     int32
 	regionLoaded         int32
@@ -67,6 +69,7 @@ type Storage struct {
 // StorageOpt represents available options to create Storage.
 type StorageOpt struct {
 	regionStorage        *RegionStorage
+	hotRegionStorage     *kv.LeveldbKV
 	encryptionKeyManager *encryptionkm.KeyManager
 }
 
@@ -80,6 +83,14 @@ func WithRegionStorage(regionStorage *RegionStorage) StorageOption {
 	}
 }
 
+// WithHotRegionStorage sets the local storage of the hot region history to
+// the Storage
+func WithHotRegionStorage(hotRegionStorage *kv.LeveldbKV) StorageOption {
+	return func(opt *StorageOpt) {
+		opt.hotRegionStorage = hotRegionStorage
+	}
+}
+
 // WithEncryptionKeyManager sets EncryptionManager to the Storage
 func WithEncryptionKeyManager(encryptionKeyManager *encryptionkm.KeyManager) StorageOption {
 	return func(opt *StorageOpt) {
@@ -96,6 +107,7 @@ func NewStorage(base kv.Base, opts ...StorageOption) *Storage {
 	return &Storage{
 		Base:                 base,
 		regionStorage:        options.regionStorage,
+		hotRegionStorage:     options.hotRegionStorage,
 		encryptionKeyManager: options.encryptionKeyManager,
 	}
 }
@@ -105,6 +117,16 @@ func (s *Storage) GetRegionStorage() *RegionStorage {
 	return s.regionStorage
 }
 
+// GetHotRegionStorage gets the storage of the hot region history, which also
+// keeps the region topology history. It is the
+// Base if there is no local storage for it, which is only used in tests.
+func (s *Storage) GetHotRegionStorage() kv.Base {
+	if s.hotRegionStorage == nil {
+		return s.Base
+	}
+	return s.hotRegionStorage
+}
+
 // SwitchToRegionStorage switches to the region storage.
 func (s *Storage) SwitchToRegionStorage() {
 	atomic.StoreInt32(&s.useRegionStorage, 1)
@@ -119,6 +141,10 @@ func (s *Storage) storePath(storeID uint64) string {
 	return path.Join(clusterPath, "s", fmt.Sprintf("%020d", storeID))
 }
 
+func (s *Storage) archivedStorePath(storeID uint64) string {
+	return path.Join(clusterPath, "archived_stores", fmt.Sprintf("%020d", storeID))
+}
+
 func regionPath(regionID uint64) string {
 	return path.Join(clusterPath, "r", fmt.Sprintf("%020d", regionID))
 }
@@ -136,6 +162,18 @@ func (s *Storage) storeRegionWeightPath(storeID uint64) string {
 	return path.Join(schedulePath, "store_weight", fmt.Sprintf("%020d", storeID), "region")
 }
 
+func (s *Storage) storeDrainPath(storeID uint64) string {
+	return path.Join(schedulePath, "store_drain", fmt.Sprintf("%020d", storeID))
+}
+
+func (s *Storage) storeMaintenancePath(storeID uint64) string {
+	return path.Join(schedulePath, "store_maintenance", fmt.Sprintf("%020d", storeID))
+}
+
+func (s *Storage) storeOfflinePlanPrefix() string {
+	return path.Join(schedulePath, "store_offline_plan")
+}
+
 // EncryptionKeysPath returns the path to save encryption keys.
 func (s *Storage) EncryptionKeysPath() string {
 	return path.Join(encryptionKeysPath, "keys")
@@ -388,7 +426,15 @@ func (s *Storage) LoadStores(f func(store *StoreInfo)) error {
 			if err != nil {
 				return err
 			}
-			newStoreInfo := NewStoreInfo(store, SetLeaderWeight(leaderWeight), SetRegionWeight(regionWeight))
+			drain, err := s.loadStoreDrain(store.GetId())
+			if err != nil {
+				return err
+			}
+			maintenance, err := s.loadStoreMaintenance(store.GetId())
+			if err != nil {
+				return err
+			}
+			newStoreInfo := NewStoreInfo(store, SetLeaderWeight(leaderWeight), SetRegionWeight(regionWeight), SetStoreDrain(drain), SetStoreMaintenance(maintenance))
 
 			nextID = store.GetId() + 1
 			f(newStoreInfo)
@@ -399,6 +445,70 @@ func (s *Storage) LoadStores(f func(store *StoreInfo)) error {
 	}
 }
 
+// SaveArchivedStore saves an archived store to storage.
+func (s *Storage) SaveArchivedStore(store *ArchivedStore) error {
+	value, err := json.Marshal(store)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return s.Save(s.archivedStorePath(store.Store.GetId()), string(value))
+}
+
+// LoadArchivedStore loads an archived store, or returns nil if it is not
+// archived.
+func (s *Storage) LoadArchivedStore(storeID uint64) (*ArchivedStore, error) {
+	value, err := s.Load(s.archivedStorePath(storeID))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	store := &ArchivedStore{}
+	if err := json.Unmarshal([]byte(value), store); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return store, nil
+}
+
+// LoadArchivedStores loads all archived stores in the order of the store ID.
+func (s *Storage) LoadArchivedStores(f func(store *ArchivedStore)) error {
+	var err error
+	loadErr := s.LoadRangeByPrefix(path.Join(clusterPath, "archived_stores")+"/", func(k, v string) {
+		store := &ArchivedStore{}
+		if e := json.Unmarshal([]byte(v), store); e != nil {
+			err = errs.ErrJSONUnmarshal.Wrap(e).GenWithStackByArgs()
+			return
+		}
+		f(store)
+	})
+	if loadErr != nil {
+		return loadErr
+	}
+	return err
+}
+
+// DeleteArchivedStore deletes an archived store from storage.
+func (s *Storage) DeleteArchivedStore(storeID uint64) error {
+	return s.Remove(s.archivedStorePath(storeID))
+}
+
+// SaveSplitReport saves a processed split report, which is kept until expire.
+func (s *Storage) SaveSplitReport(key string, expire time.Time) error {
+	return s.Save(path.Join(splitReportPath, key), strconv.FormatInt(expire.UnixNano(), 10))
+}
+
+// DeleteSplitReport deletes a processed split report from storage.
+func (s *Storage) DeleteSplitReport(key string) error {
+	return s.Remove(path.Join(splitReportPath, key))
+}
+
+// LoadSplitReports loads the processed split reports with their expire time.
+// The reports which cannot be decoded are treated as expired.
+func (s *Storage) LoadSplitReports(f func(key string, expire time.Time)) error {
+	return s.LoadRangeByPrefix(splitReportPath+"/", func(k, v string) {
+		nanos, _ := strconv.ParseInt(v, 10, 64)
+		f(k, time.Unix(0, nanos))
+	})
+}
+
 // SaveStoreWeight saves a store's leader and region weight to storage.
 func (s *Storage) SaveStoreWeight(storeID uint64, leader, region float64) error {
 	leaderValue := strconv.FormatFloat(leader, 'f', -1, 64)
@@ -409,6 +519,81 @@ func (s *Storage) SaveStoreWeight(storeID uint64, leader, region float64) error
 	return s.Save(s.storeRegionWeightPath(storeID), regionValue)
 }
 
+// SaveStoreDrain saves the drain of a store to storage.
+func (s *Storage) SaveStoreDrain(storeID uint64, drain *StoreDrain) error {
+	value, err := json.Marshal(drain)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return s.Save(s.storeDrainPath(storeID), string(value))
+}
+
+// DeleteStoreDrain deletes the drain of a store from storage.
+func (s *Storage) DeleteStoreDrain(storeID uint64) error {
+	return s.Remove(s.storeDrainPath(storeID))
+}
+
+func (s *Storage) loadStoreDrain(storeID uint64) (*StoreDrain, error) {
+	value, err := s.Load(s.storeDrainPath(storeID))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	drain := &StoreDrain{}
+	if err := json.Unmarshal([]byte(value), drain); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return drain, nil
+}
+
+// SaveStoresMaintenance saves the maintenance window of a group of stores to
+// storage in a single write.
+func (s *Storage) SaveStoresMaintenance(storeIDs []uint64, maintenance *StoreMaintenance) error {
+	value, err := json.Marshal(maintenance)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByArgs()
+	}
+	kvs := make(map[string]string, len(storeIDs))
+	for _, storeID := range storeIDs {
+		kvs[s.storeMaintenancePath(storeID)] = string(value)
+	}
+	return s.SaveBatch(kvs)
+}
+
+// DeleteStoreMaintenance deletes the maintenance window of a store from storage.
+func (s *Storage) DeleteStoreMaintenance(storeID uint64) error {
+	return s.Remove(s.storeMaintenancePath(storeID))
+}
+
+func (s *Storage) loadStoreMaintenance(storeID uint64) (*StoreMaintenance, error) {
+	value, err := s.Load(s.storeMaintenancePath(storeID))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	maintenance := &StoreMaintenance{}
+	if err := json.Unmarshal([]byte(value), maintenance); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return maintenance, nil
+}
+
+// SaveStoreOfflinePlan saves the capacity reservation plan of an offline store
+// to storage.
+func (s *Storage) SaveStoreOfflinePlan(storeID uint64, plan interface{}) error {
+	return s.SaveJSON(s.storeOfflinePlanPrefix(), fmt.Sprintf("%020d", storeID), plan)
+}
+
+// DeleteStoreOfflinePlan deletes the capacity reservation plan of a store from
+// storage.
+func (s *Storage) DeleteStoreOfflinePlan(storeID uint64) error {
+	return s.Remove(path.Join(s.storeOfflinePlanPrefix(), fmt.Sprintf("%020d", storeID)))
+}
+
+// LoadStoreOfflinePlans loads the capacity reservation plans of the offline
+// stores.
+func (s *Storage) LoadStoreOfflinePlans(f func(k, v string)) error {
+	return s.LoadRangeByPrefix(s.storeOfflinePlanPrefix()+"/", f)
+}
+
 func (s *Storage) loadFloatWithDefaultValue(path string, def float64) (float64, error) {
 	res, err := s.Load(path)
 	if err != nil {
@@ -440,6 +625,11 @@ func (s *Storage) Close() error {
 			return err
 		}
 	}
+	if s.hotRegionStorage != nil {
+		if err := s.hotRegionStorage.Close(); err != nil {
+			return errs.ErrLevelDBClose.Wrap(err).GenWithStackByArgs()
+		}
+	}
 	return nil
 }
 
@@ -596,6 +786,47 @@ func (s *Storage) GetAllServiceGCSafePoints() ([]*ServiceSafePoint, error) {
 	return ssps, nil
 }
 
+// GCPause pauses the GC of the whole cluster. Until it expires, neither the GC
+// safe point nor the min service safe point moves beyond SafePoint.
+type GCPause struct {
+	Reason string `json:"reason"`
+	// SafePoint is the GC safe point when the GC is paused.
+	SafePoint uint64 `json:"safe_point"`
+	StartedAt int64  `json:"started_at"`
+	ExpiredAt int64  `json:"expired_at"`
+}
+
+// SaveGCPause saves the GC pause to storage.
+func (s *Storage) SaveGCPause(pause *GCPause) error {
+	value, err := json.Marshal(pause)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return s.Save(path.Join(gcPath, "pause"), string(value))
+}
+
+// LoadGCPause loads the GC pause from storage. It returns nil if the GC is not
+// paused or the pause has expired, and the expired pause is removed.
+func (s *Storage) LoadGCPause(now time.Time) (*GCPause, error) {
+	value, err := s.Load(path.Join(gcPath, "pause"))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	pause := &GCPause{}
+	if err := json.Unmarshal([]byte(value), pause); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByArgs()
+	}
+	if pause.ExpiredAt < now.Unix() {
+		return nil, s.RemoveGCPause()
+	}
+	return pause, nil
+}
+
+// RemoveGCPause removes the GC pause from storage.
+func (s *Storage) RemoveGCPause() error {
+	return s.Remove(path.Join(gcPath, "pause"))
+}
+
 // LoadAllScheduleConfig loads all schedulers' config.
 func (s *Storage) LoadAllScheduleConfig() ([]string, []string, error) {
 	prefix := customScheduleConfigPath + "/"
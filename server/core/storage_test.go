@@ -123,6 +123,24 @@ func (s *testKVSuite) TestStoreWeight(c *C) {
 	}
 }
 
+func (s *testKVSuite) TestStoreDrain(c *C) {
+	storage := NewStorage(kv.NewMemoryKV())
+	const n = 3
+
+	mustSaveStores(c, storage, n)
+	drain := &StoreDrain{StartTime: time.Unix(1600000000, 0), StartLeaderCount: 10}
+	c.Assert(storage.SaveStoreDrain(1, drain), IsNil)
+	c.Assert(storage.SaveStoreDrain(2, drain), IsNil)
+	c.Assert(storage.DeleteStoreDrain(2), IsNil)
+	cache := NewStoresInfo()
+	c.Assert(storage.LoadStores(cache.SetStore), IsNil)
+	c.Assert(cache.GetStore(0).IsDraining(), IsFalse)
+	c.Assert(cache.GetStore(1).IsDraining(), IsTrue)
+	c.Assert(cache.GetStore(1).GetDrain().StartTime.Equal(drain.StartTime), IsTrue)
+	c.Assert(cache.GetStore(1).GetDrain().StartLeaderCount, Equals, 10)
+	c.Assert(cache.GetStore(2).IsDraining(), IsFalse)
+}
+
 func mustSaveRegions(c *C, s *Storage, n int) []*metapb.Region {
 	regions := make([]*metapb.Region, 0, n)
 	for i := 0; i < n; i++ {
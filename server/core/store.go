@@ -49,6 +49,45 @@ type StoreInfo struct {
 	leaderWeight        float64
 	regionWeight        float64
 	available           map[storelimit.Type]func() bool
+	drain               *StoreDrain
+	maintenance         *StoreMaintenance
+	reservedSize        int64 // the size in MB reserved for the regions of the offline stores
+	capabilities        StoreCapabilities
+}
+
+// StoreDrain records a drain of a store. A draining store moves its leaders
+// away and does not accept new leaders or peers, but unlike an offline store,
+// its peers are kept and it can be brought back by cancelling the drain.
+type StoreDrain struct {
+	StartTime        time.Time `json:"start_time"`
+	StartLeaderCount int       `json:"start_leader_count"`
+}
+
+// ArchivedStore is a tombstone store moved out of the cluster after the
+// retention. It is kept in storage so that it can be restored.
+type ArchivedStore struct {
+	Store         *metapb.Store `json:"store"`
+	TombstoneTime time.Time     `json:"tombstone_time"`
+	ArchiveTime   time.Time     `json:"archive_time"`
+}
+
+// StoreMaintenance records a maintenance window of a store. While the window
+// is active, the store does not accept new leaders, its balance weights are
+// lowered, and it is not reported as down or disconnected.
+type StoreMaintenance struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// IsActive returns if the window covers the given time.
+func (m *StoreMaintenance) IsActive(now time.Time) bool {
+	return m != nil && !now.Before(m.StartTime) && now.Before(m.EndTime)
+}
+
+// IsExpired returns if the window has ended at the given time.
+func (m *StoreMaintenance) IsExpired(now time.Time) bool {
+	return m != nil && !now.Before(m.EndTime)
 }
 
 // NewStoreInfo creates StoreInfo with meta data.
@@ -62,6 +101,7 @@ func NewStoreInfo(store *metapb.Store, opts ...StoreCreateOption) *StoreInfo {
 	for _, opt := range opts {
 		opt(storeInfo)
 	}
+	storeInfo.capabilities = detectStoreCapabilities(storeInfo.meta)
 	return storeInfo
 }
 
@@ -81,11 +121,19 @@ func (s *StoreInfo) Clone(opts ...StoreCreateOption) *StoreInfo {
 		leaderWeight:        s.leaderWeight,
 		regionWeight:        s.regionWeight,
 		available:           s.available,
+		drain:               s.drain,
+		maintenance:         s.maintenance,
+		reservedSize:        s.reservedSize,
+		capabilities:        s.capabilities,
 	}
 
 	for _, opt := range opts {
 		opt(store)
 	}
+	// The options which change the meta always replace it.
+	if store.meta != meta {
+		store.capabilities = detectStoreCapabilities(store.meta)
+	}
 	return store
 }
 
@@ -104,20 +152,89 @@ func (s *StoreInfo) ShallowClone(opts ...StoreCreateOption) *StoreInfo {
 		leaderWeight:        s.leaderWeight,
 		regionWeight:        s.regionWeight,
 		available:           s.available,
+		drain:               s.drain,
+		maintenance:         s.maintenance,
+		reservedSize:        s.reservedSize,
+		capabilities:        s.capabilities,
 	}
 
 	for _, opt := range opts {
 		opt(store)
 	}
+	if store.meta != s.meta {
+		store.capabilities = detectStoreCapabilities(store.meta)
+	}
 	return store
 }
 
+// GetCapabilities returns the engine of the store and what it supports.
+func (s *StoreInfo) GetCapabilities() StoreCapabilities {
+	return s.capabilities
+}
+
+// IsTiFlash returns true if the store is a TiFlash store.
+func (s *StoreInfo) IsTiFlash() bool {
+	return s.capabilities.IsTiFlash()
+}
+
 // AllowLeaderTransfer returns if the store is allowed to be selected
 // as source or target of transfer leader.
 func (s *StoreInfo) AllowLeaderTransfer() bool {
 	return !s.pauseLeaderTransfer
 }
 
+// IsDraining returns if the store is being drained.
+func (s *StoreInfo) IsDraining() bool {
+	return s.drain != nil
+}
+
+// GetDrain returns the drain of the store, or nil if the store is not being
+// drained.
+func (s *StoreInfo) GetDrain() *StoreDrain {
+	return s.drain
+}
+
+// GetMaintenance returns the maintenance window of the store, or nil if no
+// window is declared.
+func (s *StoreInfo) GetMaintenance() *StoreMaintenance {
+	return s.maintenance
+}
+
+// IsInMaintenance returns if the store is in an active maintenance window.
+func (s *StoreInfo) IsInMaintenance() bool {
+	return s.maintenance.IsActive(time.Now())
+}
+
+// HasPlannedMaintenance returns if the store has a maintenance window which
+// has not ended yet, whether it is active or still to come.
+func (s *StoreInfo) HasPlannedMaintenance() bool {
+	return s.maintenance != nil && !s.maintenance.IsExpired(time.Now())
+}
+
+// HasMaintenanceWithin returns if the store is in a maintenance window, or has
+// one starting within the duration.
+func (s *StoreInfo) HasMaintenanceWithin(d time.Duration) bool {
+	now := time.Now()
+	return s.maintenance != nil && !s.maintenance.IsExpired(now) && s.maintenance.StartTime.Before(now.Add(d))
+}
+
+// GetReservedSize returns the size in MB reserved on the store for the regions
+// of the stores being taken offline.
+func (s *StoreInfo) GetReservedSize() int64 {
+	return s.reservedSize
+}
+
+// IsLowSpaceAfterReserved checks if the store becomes low space once the size
+// reserved for the offline stores is taken, which leaves no room for the other
+// regions.
+func (s *StoreInfo) IsLowSpaceAfterReserved(lowSpaceRatio float64) bool {
+	if s.reservedSize == 0 || s.GetCapacity() == 0 {
+		return false
+	}
+	available := float64(s.GetAvailable()) - float64(s.reservedSize)*mb
+	return available < float64(s.GetCapacity())*(1-lowSpaceRatio)
+}
+
 // IsAvailable returns if the store bucket of limitation is available
 func (s *StoreInfo) IsAvailable(limitType storelimit.Type) bool {
 	if s.available != nil && s.available[limitType] != nil {
@@ -216,6 +333,24 @@ func (s *StoreInfo) GetRegionWeight() float64 {
 	return s.regionWeight
 }
 
+// scheduleLeaderWeight returns the leader weight used by scheduling, which is
+// lowered while the store is in maintenance.
+func (s *StoreInfo) scheduleLeaderWeight() float64 {
+	if s.IsInMaintenance() {
+		return s.leaderWeight * maintenanceWeightRatio
+	}
+	return s.leaderWeight
+}
+
+// scheduleRegionWeight returns the region weight used by scheduling, which is
+// lowered while the store is in maintenance.
+func (s *StoreInfo) scheduleRegionWeight() float64 {
+	if s.IsInMaintenance() {
+		return s.regionWeight * maintenanceWeightRatio
+	}
+	return s.regionWeight
+}
+
 // GetLastHeartbeatTS returns the last heartbeat timestamp of the store.
 func (s *StoreInfo) GetLastHeartbeatTS() time.Time {
 	return time.Unix(0, s.meta.GetLastHeartbeat())
@@ -227,15 +362,19 @@ func (s *StoreInfo) NeedPersist() bool {
 }
 
 const minWeight = 1e-6
+
+// maintenanceWeightRatio scales the balance weights of a store in maintenance,
+// so that the balance schedulers move less data onto it.
+const maintenanceWeightRatio = 0.1
 const maxScore = 1024 * 1024 * 1024
 
 // LeaderScore returns the store's leader score.
 func (s *StoreInfo) LeaderScore(policy SchedulePolicy, delta int64) float64 {
 	switch policy {
 	case BySize:
-		return float64(s.GetLeaderSize()+delta) / math.Max(s.GetLeaderWeight(), minWeight)
+		return float64(s.GetLeaderSize()+delta) / math.Max(s.scheduleLeaderWeight(), minWeight)
 	case ByCount:
-		return float64(int64(s.GetLeaderCount())+delta) / math.Max(s.GetLeaderWeight(), minWeight)
+		return float64(int64(s.GetLeaderCount())+delta) / math.Max(s.scheduleLeaderWeight(), minWeight)
 	default:
 		return 0
 	}
@@ -298,7 +437,7 @@ func (s *StoreInfo) regionScoreV1(highSpaceRatio, lowSpaceRatio float64, delta i
 		score = k*float64(s.GetRegionSize()+delta) + b
 	}
 
-	return score / math.Max(s.GetRegionWeight(), minWeight)
+	return score / math.Max(s.scheduleRegionWeight(), minWeight)
 }
 
 func (s *StoreInfo) regionScoreV2(delta int64, deviation int, lowSpaceRatio float64) float64 {
@@ -323,7 +462,7 @@ func (s *StoreInfo) regionScoreV2(delta int64, deviation int, lowSpaceRatio floa
 		// store's score will increase rapidly after it has few space. and it will reach similar score when they has no space
 		score = (K+M*math.Log(C)/C)*R + B*(F-A)/F
 	}
-	return score / math.Max(s.GetRegionWeight(), minWeight)
+	return score / math.Max(s.scheduleRegionWeight(), minWeight)
 }
 
 // StorageSize returns store's used storage size reported from tikv.
@@ -380,13 +519,13 @@ func (s *StoreInfo) ResourceSize(kind ResourceKind) int64 {
 func (s *StoreInfo) ResourceWeight(kind ResourceKind) float64 {
 	switch kind {
 	case LeaderKind:
-		leaderWeight := s.GetLeaderWeight()
+		leaderWeight := s.scheduleLeaderWeight()
 		if leaderWeight <= 0 {
 			return minWeight
 		}
 		return leaderWeight
 	case RegionKind:
-		regionWeight := s.GetRegionWeight()
+		regionWeight := s.scheduleRegionWeight()
 		if regionWeight <= 0 {
 			return minWeight
 		}
@@ -471,6 +610,26 @@ func DistinctScore(labels []string, stores []*StoreInfo, other *StoreInfo) float
 	return score
 }
 
+// AntiAffinityScore returns the number of stores in the given stores that are
+// in the same soft anti-affinity group with the other store. Two stores are in
+// the same group if they have the same non-empty value for any of the labels.
+// A lower score is better.
+func AntiAffinityScore(labels []string, stores []*StoreInfo, other *StoreInfo) int {
+	var score int
+	for _, s := range stores {
+		if s.GetID() == other.GetID() {
+			continue
+		}
+		for _, label := range labels {
+			if v := other.GetLabelValue(label); v != "" && s.GetLabelValue(label) == v {
+				score++
+				break
+			}
+		}
+	}
+	return score
+}
+
 // MergeLabels merges the passed in labels with origins, overriding duplicated
 // ones.
 func (s *StoreInfo) MergeLabels(labels []*metapb.StoreLabel) []*metapb.StoreLabel {
@@ -533,6 +692,14 @@ func (s *StoresInfo) PauseLeaderTransfer(storeID uint64) error {
 	return nil
 }
 
+// SetStoreReservedSize sets the size in MB reserved on the store for the
+// offline stores.
+func (s *StoresInfo) SetStoreReservedSize(storeID uint64, size int64) {
+	if store, ok := s.stores[storeID]; ok && store.GetReservedSize() != size {
+		s.stores[storeID] = store.Clone(SetStoreReservedSize(size))
+	}
+}
+
 // ResumeLeaderTransfer cleans a store's pause state. The store can be selected
 // as source or target of TransferLeader again.
 func (s *StoresInfo) ResumeLeaderTransfer(storeID uint64) {
@@ -625,14 +792,3 @@ func (s *StoresInfo) UpdateStoreStatus(storeID uint64, leaderCount int, regionCo
 		s.SetStore(newStore)
 	}
 }
-
-// IsTiFlashStore used to judge flash store.
-// FIXME: remove the hack way
-func IsTiFlashStore(store *metapb.Store) bool {
-	for _, l := range store.GetLabels() {
-		if l.GetKey() == "engine" && l.GetValue() == "tiflash" {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,71 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// The engines of the stores.
+const (
+	EngineTiKV    = "tikv"
+	EngineTiFlash = "tiflash"
+	// EngineRaftKV2 is TiKV with the partitioned raft engine.
+	EngineRaftKV2 = "raft-kv2"
+)
+
+// engineLabelKey is the label key reported by the stores to indicate the
+// engine, which is empty for TiKV.
+const engineLabelKey = "engine"
+
+// StoreCapabilities is the engine of a store and what it supports, detected
+// from the labels reported in its heartbeats.
+type StoreCapabilities struct {
+	Engine string `json:"engine"`
+	// Leader is true if the store can hold leaders. TiFlash only holds
+	// learners.
+	Leader bool `json:"leader"`
+}
+
+// IsTiFlash returns true if the store is a TiFlash store.
+func (c StoreCapabilities) IsTiFlash() bool {
+	return c.Engine == EngineTiFlash
+}
+
+func detectEngine(store *metapb.Store) string {
+	for _, l := range store.GetLabels() {
+		if l.GetKey() != engineLabelKey {
+			continue
+		}
+		switch l.GetValue() {
+		case EngineTiFlash, EngineRaftKV2:
+			return l.GetValue()
+		}
+	}
+	return EngineTiKV
+}
+
+func detectStoreCapabilities(store *metapb.Store) StoreCapabilities {
+	c := StoreCapabilities{Engine: detectEngine(store)}
+	if c.IsTiFlash() {
+		return c
+	}
+	c.Leader = true
+	return c
+}
+
+// IsTiFlashStore returns true if the store is a TiFlash store.
+func IsTiFlashStore(store *metapb.Store) bool {
+	return detectEngine(store) == EngineTiFlash
+}
@@ -166,6 +166,30 @@ func SetRegionWeight(regionWeight float64) StoreCreateOption {
 	}
 }
 
+// SetStoreDrain sets the drain of the store. A nil drain means the store is
+// not being drained.
+func SetStoreDrain(drain *StoreDrain) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.drain = drain
+	}
+}
+
+// SetStoreMaintenance sets the maintenance window of the store. A nil window
+// means no maintenance is declared.
+func SetStoreMaintenance(maintenance *StoreMaintenance) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.maintenance = maintenance
+	}
+}
+
+// SetStoreReservedSize sets the size in MB reserved on the store for the
+// regions of the offline stores.
+func SetStoreReservedSize(size int64) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.reservedSize = size
+	}
+}
+
 // SetLastHeartbeatTS sets the time of last heartbeat for the store.
 func SetLastHeartbeatTS(lastHeartbeatTS time.Time) StoreCreateOption {
 	return func(store *StoreInfo) {
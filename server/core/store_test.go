@@ -61,6 +61,23 @@ func (s *testDistinctScoreSuite) TestDistinctScore(c *C) {
 	c.Assert(DistinctScore(labels, stores, store), Equals, float64(0))
 }
 
+func (s *testDistinctScoreSuite) TestAntiAffinityScore(c *C) {
+	labels := []string{"power", "switch"}
+	stores := []*StoreInfo{
+		NewStoreInfoWithLabel(1, 1, map[string]string{"power": "p1", "switch": "s1"}),
+		NewStoreInfoWithLabel(2, 1, map[string]string{"power": "p1", "switch": "s2"}),
+		NewStoreInfoWithLabel(3, 1, map[string]string{"power": "p2", "switch": "s2"}),
+		NewStoreInfoWithLabel(4, 1, map[string]string{"power": "p3"}),
+	}
+	c.Assert(AntiAffinityScore(labels, stores, stores[0]), Equals, 1)
+	c.Assert(AntiAffinityScore(labels, stores, stores[1]), Equals, 2)
+	c.Assert(AntiAffinityScore(labels, stores, stores[2]), Equals, 1)
+	c.Assert(AntiAffinityScore(labels, stores, stores[3]), Equals, 0)
+	c.Assert(AntiAffinityScore(nil, stores, stores[1]), Equals, 0)
+	store := NewStoreInfoWithLabel(100, 1, nil)
+	c.Assert(AntiAffinityScore(labels, stores, store), Equals, 0)
+}
+
 var _ = Suite(&testConcurrencySuite{})
 
 type testConcurrencySuite struct{}
@@ -126,3 +143,31 @@ func (s *testStoreSuite) TestLowSpaceRatio(c *C) {
 	store.rawStats.Available = store.rawStats.Capacity >> 2
 	c.Assert(store.IsLowSpace(0.8), Equals, false)
 }
+
+func (s *testStoreSuite) TestCapabilities(c *C) {
+	tiflashLabels := []*metapb.StoreLabel{{Key: "engine", Value: "tiflash"}}
+	testCases := []struct {
+		meta         *metapb.Store
+		capabilities StoreCapabilities
+	}{
+		{&metapb.Store{Id: 1}, StoreCapabilities{Engine: EngineTiKV, Leader: true}},
+		{&metapb.Store{Id: 1, Version: "5.0.0"}, StoreCapabilities{Engine: EngineTiKV, Leader: true}},
+		{&metapb.Store{Id: 1, Version: "6.6.0"}, StoreCapabilities{Engine: EngineTiKV, Leader: true}},
+		{&metapb.Store{Id: 1, Version: "v7.1.0", Labels: []*metapb.StoreLabel{{Key: "engine", Value: "raft-kv2"}}},
+			StoreCapabilities{Engine: EngineRaftKV2, Leader: true}},
+		{&metapb.Store{Id: 1, Version: "v7.1.0", Labels: tiflashLabels}, StoreCapabilities{Engine: EngineTiFlash}},
+		{&metapb.Store{Id: 1, Labels: []*metapb.StoreLabel{{Key: "engine", Value: "unknown"}}}, StoreCapabilities{Engine: EngineTiKV, Leader: true}},
+	}
+	for _, t := range testCases {
+		c.Assert(NewStoreInfo(t.meta).GetCapabilities(), Equals, t.capabilities)
+	}
+
+	// The capabilities follow the changes of the labels.
+	store := NewStoreInfo(&metapb.Store{Id: 1, Version: "5.0.0"})
+	store = store.Clone(SetStoreLabels(tiflashLabels))
+	c.Assert(store.IsTiFlash(), IsTrue)
+	c.Assert(IsTiFlashStore(store.GetMeta()), IsTrue)
+	store = store.ShallowClone(SetStoreLabels(nil), SetStoreVersion("", "6.6.0"))
+	c.Assert(store.GetCapabilities(), Equals, StoreCapabilities{Engine: EngineTiKV, Leader: true})
+	c.Assert(store.ShallowClone(SetLeaderCount(1)).GetCapabilities(), Equals, store.GetCapabilities())
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decisionexport
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/opt"
+	"go.uber.org/zap"
+)
+
+const (
+	bufferSize   = 1024
+	writeTimeout = 10 * time.Second
+)
+
+// Decision is a scheduling decision, which is the creation of an operator,
+// with the scores of the stores at that time.
+type Decision struct {
+	Time time.Time
+	Kind string
+	// Source is where the operator comes from, such as the schedulers, the
+	// checkers or the API.
+	Source      string
+	Desc        string
+	SourceStore uint64
+	TargetStore uint64
+	SourceScore float64
+	TargetScore float64
+}
+
+// Exporter samples the scheduling decisions and sends them to a Prometheus
+// remote-write endpoint in the background. The decisions are dropped if the
+// endpoint cannot keep up with them.
+type Exporter struct {
+	ctx       context.Context
+	cluster   opt.Cluster
+	url       string
+	ratio     float64
+	interval  time.Duration
+	client    *http.Client
+	decisions chan *Decision
+}
+
+// NewExporter creates an Exporter and starts sending the decisions in the
+// background until the context is done.
+func NewExporter(ctx context.Context, cluster opt.Cluster, cfg *config.DecisionExportConfig) *Exporter {
+	e := &Exporter{
+		ctx:       ctx,
+		cluster:   cluster,
+		url:       cfg.RemoteWriteURL,
+		ratio:     cfg.SampleRatio,
+		interval:  cfg.Interval.Duration,
+		client:    &http.Client{Timeout: writeTimeout},
+		decisions: make(chan *Decision, bufferSize),
+	}
+	go e.run()
+	return e
+}
+
+// RecordOperator samples the operator if it is just created. It never blocks.
+func (e *Exporter) RecordOperator(op *operator.Operator, event string) {
+	if event != schedule.OperatorEventCreate || rand.Float64() >= e.ratio {
+		return
+	}
+	d := &Decision{
+		Time:   time.Now(),
+		Kind:   op.Kind().String(),
+		Source: op.Source(),
+		Desc:   op.Desc(),
+	}
+	d.SourceStore, d.TargetStore = operatorStores(op)
+	leaderOnly := op.Kind()&operator.OpRegion == 0
+	d.SourceScore = e.storeScore(d.SourceStore, leaderOnly)
+	d.TargetScore = e.storeScore(d.TargetStore, leaderOnly)
+	select {
+	case e.decisions <- d:
+	default:
+		decisionCounter.WithLabelValues("dropped").Inc()
+	}
+}
+
+// operatorStores returns the first store the operator moves a peer or the
+// leader out of, and the first store it moves one into.
+func operatorStores(op *operator.Operator) (source, target uint64) {
+	for i := 0; i < op.Len(); i++ {
+		var from, to uint64
+		switch step := op.Step(i).(type) {
+		case operator.TransferLeader:
+			from, to = step.FromStore, step.ToStore
+		case operator.RemovePeer:
+			from = step.FromStore
+		case operator.AddPeer:
+			to = step.ToStore
+		case operator.AddLearner:
+			to = step.ToStore
+		case operator.AddLightPeer:
+			to = step.ToStore
+		case operator.AddLightLearner:
+			to = step.ToStore
+		}
+		if source == 0 {
+			source = from
+		}
+		if target == 0 {
+			target = to
+		}
+	}
+	return
+}
+
+// storeScore returns the leader score or the region score of the store, or 0
+// if the store is not found.
+func (e *Exporter) storeScore(storeID uint64, leader bool) float64 {
+	store := e.cluster.GetStore(storeID)
+	if store == nil {
+		return 0
+	}
+	opts := e.cluster.GetOpts()
+	if leader {
+		return store.LeaderScore(opts.GetLeaderSchedulePolicy(), 0)
+	}
+	return store.RegionScore(opts.GetRegionScoreFormulaVersion(), opts.GetHighSpaceRatio(), opts.GetLowSpaceRatio(), 0, 0)
+}
+
+func (e *Exporter) run() {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	var batch []*Decision
+	for {
+		select {
+		case d := <-e.decisions:
+			if len(batch) >= bufferSize {
+				decisionCounter.WithLabelValues("dropped").Inc()
+				continue
+			}
+			batch = append(batch, d)
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			if err := e.send(batch); err != nil {
+				log.Warn("failed to export the scheduling decisions", zap.Int("count", len(batch)), errs.ZapError(err))
+				decisionCounter.WithLabelValues("failed").Add(float64(len(batch)))
+			} else {
+				decisionCounter.WithLabelValues("sent").Add(float64(len(batch)))
+			}
+			batch = nil
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Exporter) send(batch []*Decision) error {
+	body := snappy.Encode(nil, encodeWriteRequest(batch))
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return errs.ErrNewHTTPRequest.Wrap(err).GenWithStackByCause()
+	}
+	req = req.WithContext(e.ctx)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errs.ErrSendRequest.Wrap(err).GenWithStackByCause()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errs.ErrPrometheusRemoteWrite.FastGenByArgs(resp.StatusCode)
+	}
+	return nil
+}
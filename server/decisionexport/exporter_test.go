@@ -0,0 +1,152 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decisionexport
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/pkg/mock/mockcluster"
+	"github.com/tikv/pd/pkg/protoext"
+	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testExporterSuite{})
+
+type testExporterSuite struct{}
+
+type series struct {
+	labels map[string]string
+	value  float64
+}
+
+// decodeWriteRequest decodes the series sent by the exporter.
+func decodeWriteRequest(c *C, data []byte) []series {
+	var res []series
+	for len(data) > 0 {
+		_, _, ts, n, err := protoext.DecodeField(data)
+		c.Assert(err, IsNil)
+		data = data[n:]
+		s := series{labels: make(map[string]string)}
+		for len(ts) > 0 {
+			num, _, value, n, err := protoext.DecodeField(ts)
+			c.Assert(err, IsNil)
+			ts = ts[n:]
+			var fields [][]byte
+			for len(value) > 0 {
+				_, _, v, n, err := protoext.DecodeField(value)
+				c.Assert(err, IsNil)
+				fields = append(fields, v)
+				value = value[n:]
+			}
+			c.Assert(fields, HasLen, 2)
+			if num == timeSeriesLabels {
+				s.labels[string(fields[0])] = string(fields[1])
+			} else {
+				s.value = math.Float64frombits(binary.LittleEndian.Uint64(fields[0]))
+				timestamp, _ := proto.DecodeVarint(fields[1])
+				c.Assert(timestamp, Greater, uint64(0))
+			}
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+func (s *testExporterSuite) TestExport(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	var received []series
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Header.Get("Content-Encoding"), Equals, "snappy")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		data, err := snappy.Decode(nil, body)
+		c.Assert(err, IsNil)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, decodeWriteRequest(c, data)...)
+	}))
+	defer server.Close()
+
+	tc := mockcluster.NewCluster(config.NewTestOptions())
+	tc.AddLeaderStore(1, 10)
+	tc.AddLeaderStore(2, 1)
+	cfg := &config.DecisionExportConfig{
+		RemoteWriteURL: server.URL,
+		SampleRatio:    1,
+		Interval:       typeutil.NewDuration(10 * time.Millisecond),
+	}
+	e := NewExporter(ctx, tc, cfg)
+	op := operator.NewOperator("balance-leader", "test", 1, &metapb.RegionEpoch{}, operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	op.SetSource(operator.SourceScheduler)
+	e.RecordOperator(op, schedule.OperatorEventCreate)
+	// Only the creation is a decision.
+	e.RecordOperator(op, schedule.OperatorEventFinish)
+
+	testutil.WaitUntil(c, func(c *C) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	})
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(received, HasLen, 3)
+	values := make(map[string]float64)
+	for _, s := range received {
+		c.Assert(s.labels["desc"], Equals, "balance-leader")
+		c.Assert(s.labels["source"], Equals, operator.SourceScheduler)
+		c.Assert(s.labels["source_store"], Equals, "1")
+		c.Assert(s.labels["target_store"], Equals, "2")
+		values[s.labels["__name__"]] = s.value
+	}
+	c.Assert(values, DeepEquals, map[string]float64{
+		decisionMetric:    1,
+		sourceScoreMetric: 10,
+		targetScoreMetric: 1,
+	})
+}
+
+func (s *testExporterSuite) TestOperatorStores(c *C) {
+	op := operator.NewOperator("balance-region", "test", 1, &metapb.RegionEpoch{}, operator.OpRegion,
+		operator.AddLearner{ToStore: 3, PeerID: 4},
+		operator.PromoteLearner{ToStore: 3, PeerID: 4},
+		operator.TransferLeader{FromStore: 1, ToStore: 2},
+		operator.RemovePeer{FromStore: 1, PeerID: 1},
+	)
+	source, target := operatorStores(op)
+	c.Assert(source, Equals, uint64(1))
+	c.Assert(target, Equals, uint64(3))
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decisionexport
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/tikv/pd/pkg/protoext"
+)
+
+// The names of the series of each decision.
+const (
+	decisionMetric    = "pd_scheduling_decision"
+	sourceScoreMetric = "pd_scheduling_decision_source_score"
+	targetScoreMetric = "pd_scheduling_decision_target_score"
+)
+
+// The field numbers of the messages of the Prometheus remote-write protocol:
+//
+//	message WriteRequest {
+//	    repeated TimeSeries timeseries = 1;
+//	}
+//	message TimeSeries {
+//	    repeated Label labels = 1;
+//	    repeated Sample samples = 2;
+//	}
+//	message Label {
+//	    string name = 1;
+//	    string value = 2;
+//	}
+//	message Sample {
+//	    double value = 1;
+//	    int64 timestamp = 2;
+//	}
+const (
+	writeRequestTimeseries = 1
+	timeSeriesLabels       = 1
+	timeSeriesSamples      = 2
+	labelName              = 1
+	labelValue             = 2
+	sampleValue            = 1
+	sampleTimestamp        = 2
+)
+
+// encodeWriteRequest encodes the decisions into a WriteRequest. Each decision
+// is exported as three series with the same labels, whose values are 1, the
+// source store score and the target store score.
+func encodeWriteRequest(decisions []*Decision) []byte {
+	var data []byte
+	for _, d := range decisions {
+		// The labels are sorted by name as required by the protocol.
+		labels := [][2]string{
+			{"__name__", ""},
+			{"desc", d.Desc},
+			{"kind", d.Kind},
+			{"source", d.Source},
+			{"source_store", strconv.FormatUint(d.SourceStore, 10)},
+			{"target_store", strconv.FormatUint(d.TargetStore, 10)},
+		}
+		timestamp := d.Time.UnixNano() / 1e6
+		for _, series := range []struct {
+			name  string
+			value float64
+		}{
+			{decisionMetric, 1},
+			{sourceScoreMetric, d.SourceScore},
+			{targetScoreMetric, d.TargetScore},
+		} {
+			labels[0][1] = series.name
+			data = protoext.AppendBytesField(data, writeRequestTimeseries, encodeTimeSeries(labels, series.value, timestamp))
+		}
+	}
+	return data
+}
+
+func encodeTimeSeries(labels [][2]string, value float64, timestamp int64) []byte {
+	var data []byte
+	for _, l := range labels {
+		var label []byte
+		label = protoext.AppendBytesField(label, labelName, []byte(l[0]))
+		label = protoext.AppendBytesField(label, labelValue, []byte(l[1]))
+		data = protoext.AppendBytesField(data, timeSeriesLabels, label)
+	}
+	var sample []byte
+	sample = protoext.AppendFixed64Field(sample, sampleValue, math.Float64bits(value))
+	sample = protoext.AppendVarintField(sample, sampleTimestamp, uint64(timestamp))
+	return protoext.AppendBytesField(data, timeSeriesSamples, sample)
+}
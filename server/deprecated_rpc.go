@@ -0,0 +1,175 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// unknownCallerComponent is the component of the callers which do not
+	// tell it.
+	unknownCallerComponent = "unknown"
+	// maxDeprecatedRPCCallers is the max number of the callers recorded, so
+	// that the callers from short-lived addresses do not use up the memory.
+	// The callers beyond it are only counted in the metrics.
+	maxDeprecatedRPCCallers = 1024
+)
+
+// deprecatedRPCs are the deprecated methods of PDServer, with the function
+// telling if a request is in the deprecated form. The methods without the
+// function are deprecated as a whole.
+var deprecatedRPCs = map[string]func(req interface{}) bool{
+	// Use the region scan service instead.
+	"ScanRegions": nil,
+	"SyncMaxTS":   nil,
+	// Use the dc-locations in the etcd instead.
+	"GetDCLocationInfo": nil,
+	// Use the regions_id instead of the region_id.
+	"ScatterRegion": func(req interface{}) bool {
+		return len(req.(*pdpb.ScatterRegionRequest).GetRegionsId()) == 0
+	},
+}
+
+// isDeprecatedRPC returns if the request of the method is deprecated.
+func isDeprecatedRPC(method string, req interface{}) bool {
+	isDeprecated, ok := deprecatedRPCs[method]
+	if !ok {
+		return false
+	}
+	return isDeprecated == nil || isDeprecated(req)
+}
+
+// DeprecatedRPCCaller is a caller of a deprecated RPC, which should be
+// upgraded before the RPC is removed.
+type DeprecatedRPCCaller struct {
+	Method    string    `json:"method"`
+	Component string    `json:"component"`
+	Address   string    `json:"address"`
+	UserAgent string    `json:"user-agent,omitempty"`
+	Count     uint64    `json:"count"`
+	LastSeen  time.Time `json:"last-seen"`
+}
+
+type deprecatedRPCCallerKey struct {
+	method, component, address string
+}
+
+// deprecatedRPCTracker counts the calls to the deprecated RPCs by the
+// callers, which are told by the component in the metadata and the host of
+// the client address.
+type deprecatedRPCTracker struct {
+	sync.Mutex
+	callers map[deprecatedRPCCallerKey]*DeprecatedRPCCaller
+}
+
+func newDeprecatedRPCTracker() *deprecatedRPCTracker {
+	return &deprecatedRPCTracker{callers: make(map[deprecatedRPCCallerKey]*DeprecatedRPCCaller)}
+}
+
+// record counts a call to the deprecated method. It returns true if it is the
+// first call of a caller which is recorded.
+func (t *deprecatedRPCTracker) record(ctx context.Context, method string) (*DeprecatedRPCCaller, bool) {
+	key := deprecatedRPCCallerKey{
+		method:    method,
+		component: grpcutil.GetCallerComponent(ctx),
+		address:   grpcutil.GetPeerAddress(ctx),
+	}
+	if key.component == "" {
+		key.component = unknownCallerComponent
+	}
+	// The ports of the clients are usually random.
+	if host, _, err := net.SplitHostPort(key.address); err == nil {
+		key.address = host
+	}
+	deprecatedRPCCounter.WithLabelValues(method, key.component).Inc()
+
+	t.Lock()
+	defer t.Unlock()
+	caller, ok := t.callers[key]
+	if !ok {
+		caller = &DeprecatedRPCCaller{Method: key.method, Component: key.component, Address: key.address}
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ua := md.Get("user-agent"); len(ua) > 0 {
+				caller.UserAgent = ua[0]
+			}
+		}
+		// The callers beyond the limit are not recorded, and are not
+		// reported as the first call either, or they would be warned about
+		// on every call.
+		if len(t.callers) >= maxDeprecatedRPCCallers {
+			return caller, false
+		}
+		t.callers[key] = caller
+	}
+	caller.Count++
+	caller.LastSeen = time.Now()
+	copied := *caller
+	return &copied, !ok
+}
+
+// getCallers returns the callers sorted by the method, the component and the
+// address.
+func (t *deprecatedRPCTracker) getCallers() []*DeprecatedRPCCaller {
+	t.Lock()
+	defer t.Unlock()
+	callers := make([]*DeprecatedRPCCaller, 0, len(t.callers))
+	for _, caller := range t.callers {
+		copied := *caller
+		callers = append(callers, &copied)
+	}
+	sort.Slice(callers, func(i, j int) bool {
+		if callers[i].Method != callers[j].Method {
+			return callers[i].Method < callers[j].Method
+		}
+		if callers[i].Component != callers[j].Component {
+			return callers[i].Component < callers[j].Component
+		}
+		return callers[i].Address < callers[j].Address
+	})
+	return callers
+}
+
+// recordDeprecatedRPC counts the request if it is deprecated, and warns about
+// the first call of each caller if it is enabled.
+func (s *Server) recordDeprecatedRPC(ctx context.Context, method string, req interface{}) {
+	if !isDeprecatedRPC(method, req) {
+		return
+	}
+	caller, first := s.deprecatedCalls.record(ctx, method)
+	if first && s.persistOptions.IsDeprecatedRPCWarningEnabled() {
+		log.Warn("deprecated rpc is called, please upgrade the caller",
+			zap.String("method", caller.Method),
+			zap.String("component", caller.Component),
+			zap.String("address", caller.Address),
+			zap.String("user-agent", caller.UserAgent))
+	}
+}
+
+// GetDeprecatedRPCCallers returns the callers of the deprecated RPCs handled by
+// the server since it is started. The callers are kept in the memory of each
+// member, so they are lost on restart and differ between the members.
+func (s *Server) GetDeprecatedRPCCallers() []*DeprecatedRPCCaller {
+	return s.deprecatedCalls.getCallers()
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// forwardBreakerThreshold is the number of the consecutive failures to
+	// reach a member after which the requests forwarded to it fail fast.
+	forwardBreakerThreshold = 5
+	// forwardBreakerCooldown is how long the requests fail fast before a
+	// request is forwarded again to probe the member.
+	forwardBreakerCooldown = 3 * time.Second
+)
+
+// The states of a forwarding circuit breaker, which are also the values of
+// the breaker state metrics.
+const (
+	forwardBreakerClosed = iota
+	forwardBreakerOpen
+	forwardBreakerHalfOpen
+)
+
+var forwardBreakerStateNames = []string{"closed", "open", "half-open"}
+
+// forwardBreaker is the circuit breaker of the requests forwarded to a
+// member. It opens after repeated failures to reach the member, so that the
+// requests fail fast with a retryable error instead of waiting for the
+// timeout one by one. After the cooldown, one request is forwarded to probe
+// the member, and the breaker closes if it reaches the member.
+type forwardBreaker struct {
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// isForwardUnreachable returns true if the error means the member cannot be
+// reached, rather than the member handles the request with an error. The
+// deadline exceeded errors are not counted, as the request may only be slow.
+func isForwardUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	// The dial errors wrap their causes, so the chain is searched for them.
+	dialErr := errors.Find(err, func(err error) bool {
+		e, ok := err.(*errors.Error)
+		return ok && e.ID() == errs.ErrGRPCDial.ID()
+	})
+	if dialErr != nil {
+		return true
+	}
+	return status.Code(err) == codes.Unavailable
+}
+
+// forwardBreakerOpenError returns the retryable error of the requests
+// rejected by the open breaker, which recommends retrying after the rest of
+// the cooldown. A follower cannot handle the forwarded requests by itself, as
+// they need the state of the leader, so the clients retry them instead.
+func forwardBreakerOpenError(addr string, b *forwardBreaker, cooldown time.Duration) error {
+	forwardBreakerRejectedCounter.Inc()
+	backoff := cooldown - time.Since(b.openedAt)
+	if backoff < 0 {
+		backoff = 0
+	}
+	msg := fmt.Sprintf("forwarding to %s is suspended after repeated failures, please retry later", addr)
+	return grpcutil.NewRetryableError(codes.Unavailable, msg, grpcutil.RetryHint{Retryable: true, Backoff: backoff})
+}
+
+// allowUnary checks whether a unary request can be forwarded to addr. The
+// result of the forwarded request must be reported by reportUnary.
+func (p *forwardConnPool) allowUnary(addr string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.breakers[addr]
+	if !ok {
+		return nil
+	}
+	if b.state == forwardBreakerOpen && time.Since(b.openedAt) >= p.breakerCooldown {
+		p.setBreakerStateLocked(addr, b, forwardBreakerHalfOpen)
+	}
+	if b.state == forwardBreakerClosed || (b.state == forwardBreakerHalfOpen && !b.probing) {
+		b.probing = b.state == forwardBreakerHalfOpen
+		return nil
+	}
+	return forwardBreakerOpenError(addr, b, p.breakerCooldown)
+}
+
+// allowStream checks whether a stream can be forwarded to addr. A stream does
+// not probe the member, but it is not rejected after the cooldown either, so
+// that the streams are not blocked if there is no unary request to probe.
+func (p *forwardConnPool) allowStream(addr string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.breakers[addr]
+	if ok && b.state == forwardBreakerOpen && time.Since(b.openedAt) < p.breakerCooldown {
+		return forwardBreakerOpenError(addr, b, p.breakerCooldown)
+	}
+	return nil
+}
+
+// reportUnary reports the result of a unary request forwarded to addr.
+func (p *forwardConnPool) reportUnary(addr string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.breakers[addr]
+	if !isForwardUnreachable(err) {
+		if ok {
+			delete(p.breakers, addr)
+			if b.state != forwardBreakerClosed {
+				log.Info("forwarding circuit breaker is closed", zap.String("addr", addr))
+			}
+			forwardBreakerStateGauge.WithLabelValues(addr).Set(forwardBreakerClosed)
+		}
+		return
+	}
+	if !ok {
+		b = &forwardBreaker{}
+		p.breakers[addr] = b
+	}
+	b.failures++
+	b.probing = false
+	if b.state == forwardBreakerHalfOpen || (b.state == forwardBreakerClosed && b.failures >= p.breakerThreshold) {
+		b.openedAt = time.Now()
+		p.setBreakerStateLocked(addr, b, forwardBreakerOpen)
+	}
+}
+
+func (p *forwardConnPool) setBreakerStateLocked(addr string, b *forwardBreaker, state int) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	forwardBreakerStateGauge.WithLabelValues(addr).Set(float64(state))
+	log.Info("forwarding circuit breaker state changed", zap.String("addr", addr),
+		zap.String("state", forwardBreakerStateNames[state]), zap.Int("failures", b.failures))
+}
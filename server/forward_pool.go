@@ -0,0 +1,240 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/logutil"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	forwardConnCheckInterval = 30 * time.Second
+	forwardConnCheckTimeout  = 3 * time.Second
+	// forwardConnIdleTimeout is the time after which a connection without any
+	// forwarded request or stream is closed.
+	forwardConnIdleTimeout = 10 * time.Minute
+	// maxForwardStreamsPerHost is the max number of the streams forwarded to
+	// a member at the same time.
+	maxForwardStreamsPerHost = 4096
+)
+
+// The reasons of evicting a forwarding connection.
+const (
+	forwardConnEvictIdle      = "idle"
+	forwardConnEvictUnhealthy = "unhealthy"
+	forwardConnEvictClose     = "close"
+)
+
+type forwardConn struct {
+	cc       *grpc.ClientConn
+	streams  int
+	lastUsed time.Time
+}
+
+// forwardConnPool keeps the connections to the other members, which are
+// shared by the forwarded unary requests and streams. The connections are
+// checked periodically, and the unhealthy or idle ones are closed, so that a
+// broken connection is not kept forever.
+type forwardConnPool struct {
+	dial             func(ctx context.Context, addr string) (*grpc.ClientConn, error)
+	check            func(ctx context.Context, cc *grpc.ClientConn) error
+	idleTimeout      time.Duration
+	maxStreams       int
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	mu    sync.Mutex
+	conns map[string]*forwardConn
+	// breakers are only kept for the members which fail to be reached.
+	breakers map[string]*forwardBreaker
+}
+
+func newForwardConnPool(dial func(ctx context.Context, addr string) (*grpc.ClientConn, error)) *forwardConnPool {
+	return &forwardConnPool{
+		dial:             dial,
+		check:            checkForwardConn,
+		idleTimeout:      forwardConnIdleTimeout,
+		maxStreams:       maxForwardStreamsPerHost,
+		breakerThreshold: forwardBreakerThreshold,
+		breakerCooldown:  forwardBreakerCooldown,
+		conns:            make(map[string]*forwardConn),
+		breakers:         make(map[string]*forwardBreaker),
+	}
+}
+
+// get returns the connection to addr for the unary requests.
+func (p *forwardConnPool) get(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conn, err := p.getLocked(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.lastUsed = time.Now()
+	return conn.cc, nil
+}
+
+// acquireStream returns the connection to addr for a stream, and the function
+// to release the stream once it is closed. It rejects the stream if there are
+// too many streams forwarded to addr, or the circuit breaker of addr is open.
+func (p *forwardConnPool) acquireStream(ctx context.Context, addr string) (*grpc.ClientConn, func(), error) {
+	if err := p.allowStream(addr); err != nil {
+		return nil, nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conn, err := p.getLocked(ctx, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.maxStreams > 0 && conn.streams >= p.maxStreams {
+		forwardStreamRejectedCounter.Inc()
+		return nil, nil, status.Errorf(codes.ResourceExhausted, "too many streams forwarded to %s, the limit is %d", addr, p.maxStreams)
+	}
+	conn.streams++
+	conn.lastUsed = time.Now()
+	forwardStreamGauge.WithLabelValues(addr).Inc()
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			conn.streams--
+			conn.lastUsed = time.Now()
+			forwardStreamGauge.WithLabelValues(addr).Dec()
+		})
+	}
+	return conn.cc, release, nil
+}
+
+func (p *forwardConnPool) getLocked(ctx context.Context, addr string) (*forwardConn, error) {
+	if conn, ok := p.conns[addr]; ok {
+		return conn, nil
+	}
+	cc, err := p.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &forwardConn{cc: cc, lastUsed: time.Now()}
+	p.conns[addr] = conn
+	forwardConnGauge.Set(float64(len(p.conns)))
+	return conn, nil
+}
+
+// evict closes the connection to addr if it is still conn.
+func (p *forwardConnPool) evict(addr string, conn *forwardConn, reason string) {
+	p.mu.Lock()
+	if p.conns[addr] != conn {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.conns, addr)
+	forwardConnGauge.Set(float64(len(p.conns)))
+	p.mu.Unlock()
+
+	forwardConnEvictedCounter.WithLabelValues(reason).Inc()
+	log.Info("forwarding connection is closed", zap.String("addr", addr), zap.String("reason", reason))
+	if err := conn.cc.Close(); err != nil {
+		log.Warn("failed to close the forwarding connection", zap.String("addr", addr), errs.ZapError(err))
+	}
+}
+
+// checkConns closes the idle connections and the unhealthy ones.
+func (p *forwardConnPool) checkConns(ctx context.Context) {
+	p.mu.Lock()
+	conns := make(map[string]*forwardConn, len(p.conns))
+	idle := make(map[string]bool, len(p.conns))
+	for addr, conn := range p.conns {
+		conns[addr] = conn
+		idle[addr] = conn.streams == 0 && time.Since(conn.lastUsed) > p.idleTimeout
+	}
+	p.mu.Unlock()
+
+	for addr, conn := range conns {
+		if idle[addr] {
+			p.evict(addr, conn, forwardConnEvictIdle)
+			continue
+		}
+		if err := p.check(ctx, conn.cc); err != nil {
+			log.Warn("forwarding connection is unhealthy", zap.String("addr", addr), errs.ZapError(err))
+			p.evict(addr, conn, forwardConnEvictUnhealthy)
+		}
+	}
+}
+
+func (p *forwardConnPool) close() {
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = make(map[string]*forwardConn)
+	p.mu.Unlock()
+	forwardConnGauge.Set(0)
+	for addr, conn := range conns {
+		forwardConnEvictedCounter.WithLabelValues(forwardConnEvictClose).Inc()
+		if err := conn.cc.Close(); err != nil {
+			log.Warn("failed to close the forwarding connection", zap.String("addr", addr), errs.ZapError(err))
+		}
+	}
+}
+
+// checkForwardConn checks the connection with the health service of PD. Only
+// the connection which fails to reach the member is considered unhealthy, a
+// member which is not serving is still reachable.
+func checkForwardConn(ctx context.Context, cc *grpc.ClientConn) error {
+	ctx, cancel := context.WithTimeout(ctx, forwardConnCheckTimeout)
+	defer cancel()
+	err := cc.Invoke(ctx, grpcutil.HealthCheckMethod, &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{})
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (s *Server) forwardConnPoolLoop() {
+	defer logutil.LogPanic()
+	defer s.serverLoopWg.Done()
+	defer s.forwardConns.close()
+
+	ticker := time.NewTicker(forwardConnCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.forwardConns.checkConns(s.serverLoopCtx)
+		case <-s.serverLoopCtx.Done():
+			return
+		}
+	}
+}
+
+// withStreamRelease returns the function which cancels the forwarded stream
+// and releases it from the pool.
+func withStreamRelease(cancel context.CancelFunc, release func()) context.CancelFunc {
+	return func() {
+		cancel()
+		release()
+	}
+}
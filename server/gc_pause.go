@@ -0,0 +1,108 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/tsoutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/tso"
+	"go.uber.org/zap"
+)
+
+// gcPauseServiceID is the service ID reported as the min service safe point
+// while the GC is paused.
+const gcPauseServiceID = "gc_pause"
+
+// tsoLeaseServiceID is the service ID reported as the min service safe point
+// while a TSO lease holds the GC.
+const tsoLeaseServiceID = "tso_lease"
+
+// PauseGC pauses the GC of the whole cluster for ttl. The GC safe point is
+// kept at the current one until the pause is resumed or expires. Pausing the
+// GC again replaces the previous pause.
+func (s *Server) PauseGC(reason string, ttl time.Duration) (*core.GCPause, error) {
+	if reason == "" {
+		return nil, errs.ErrInvalidGCPause.FastGenByArgs("the reason should be given")
+	}
+	if ttl < time.Second {
+		return nil, errs.ErrInvalidGCPause.FastGenByArgs("the ttl should be at least 1s")
+	}
+
+	s.serviceSafePointLock.Lock()
+	defer s.serviceSafePointLock.Unlock()
+
+	now, err := s.gcNow()
+	if err != nil {
+		return nil, err
+	}
+	safePoint, err := s.storage.LoadGCSafePoint()
+	if err != nil {
+		return nil, err
+	}
+	pause := &core.GCPause{
+		Reason:    reason,
+		SafePoint: safePoint,
+		StartedAt: now.Unix(),
+		ExpiredAt: now.Unix() + int64(ttl/time.Second),
+	}
+	if math.MaxInt64-now.Unix() <= int64(ttl/time.Second) {
+		pause.ExpiredAt = math.MaxInt64
+	}
+	if err := s.storage.SaveGCPause(pause); err != nil {
+		return nil, err
+	}
+	log.Warn("gc is paused",
+		zap.String("reason", reason),
+		zap.Uint64("safe-point", pause.SafePoint),
+		zap.Int64("expire-at", pause.ExpiredAt))
+	return pause, nil
+}
+
+// ResumeGC resumes the paused GC. Resuming the GC which is not paused should
+// be OK, nothing to do.
+func (s *Server) ResumeGC() error {
+	s.serviceSafePointLock.Lock()
+	defer s.serviceSafePointLock.Unlock()
+
+	if err := s.storage.RemoveGCPause(); err != nil {
+		return err
+	}
+	log.Warn("gc is resumed")
+	return nil
+}
+
+// GetGCPause returns the GC pause, or nil if the GC is not paused.
+func (s *Server) GetGCPause() (*core.GCPause, error) {
+	now, err := s.gcNow()
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.LoadGCPause(now)
+}
+
+// gcNow returns the time of the TSO, which the expiration of the service safe
+// points and the GC pause is based on.
+func (s *Server) gcNow() (time.Time, error) {
+	nowTSO, err := s.tsoAllocatorManager.HandleTSORequest(tso.GlobalDCLocation, 1)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now, _ := tsoutil.ParseTimestamp(nowTSO)
+	return now, nil
+}
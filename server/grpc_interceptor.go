@@ -0,0 +1,251 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"path"
+	"reflect"
+	_ "unsafe" // for go:linkname
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// grpcChecks are the checks done by the interceptors of PDServer before
+// calling the handlers, so that the handlers cannot forget them.
+type grpcChecks uint8
+
+const (
+	// checkForward forwards the request to the host in the metadata if it is
+	// not the server itself. The streams forward each request by themselves.
+	checkForward grpcChecks = 1 << iota
+	// checkRole validates that the server is the leader and the cluster ID of
+	// the request matches. The streams validate each request by themselves.
+	checkRole
+	// checkRateLimit checks the rate limit of the caller component. It is
+	// done once when a stream is opened.
+	checkRateLimit
+	// checkFollowerRead allows the followers to serve the method with the
+	// store metadata synced from etcd, if the client requests so. Only the
+	// cluster ID is validated then.
+	checkFollowerRead
+
+	defaultGRPCChecks = checkForward | checkRole | checkRateLimit
+)
+
+// grpcMethodChecks are the checks of the methods which do not use the default
+// ones. The admin authorization is done for all the methods, and it is the
+// config which decides the methods to be authorized.
+var grpcMethodChecks = map[string]grpcChecks{
+	// All the members serve GetMembers.
+	"GetMembers": 0,
+	// TSO uses the leader lease instead of the leader check.
+	"Tso":             checkRateLimit,
+	"RegionHeartbeat": checkRateLimit,
+	// The followers sync the regions from the leader.
+	"SyncRegions": 0,
+	// The requests between the TSO allocators are validated by themselves.
+	"SyncMaxTS":         checkForward,
+	"GetDCLocationInfo": checkForward,
+	// The followers serve the store metadata reads if they are synced.
+	"GetStore":     defaultGRPCChecks | checkFollowerRead,
+	"GetAllStores": defaultGRPCChecks | checkFollowerRead,
+}
+
+func getGRPCChecks(method string) grpcChecks {
+	if checks, ok := grpcMethodChecks[method]; ok {
+		return checks
+	}
+	return defaultGRPCChecks
+}
+
+// unaryInterceptor returns the interceptor of a unary method, which does the
+// checks of the method and then calls the handler, unless the request is
+// forwarded to another member. newReply returns the reply to forward the
+// request into.
+func (s *Server) unaryInterceptor(checks grpcChecks, newReply func() interface{}) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method := path.Base(info.FullMethod)
+		if err := s.adminAuthorizer.authorize(ctx, method); err != nil {
+			return nil, err
+		}
+		if checks&checkForward != 0 {
+			if forwardedHost := getForwardedHost(ctx); !s.isLocalRequest(forwardedHost) {
+				return s.forwardUnary(ctx, forwardedHost, info.FullMethod, newReply(), req)
+			}
+		}
+		followerRead := checks&checkFollowerRead != 0 && s.isStoreFollowerRead(ctx)
+		if checks&checkRole != 0 {
+			var err error
+			if followerRead {
+				err = s.validateClusterID(req.(interface{ GetHeader() *pdpb.RequestHeader }).GetHeader())
+			} else {
+				err = s.validateMessage(req)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if checks&checkRateLimit != 0 {
+			if err := s.rateLimitCheck(ctx); err != nil {
+				return nil, err
+			}
+		}
+		s.recordDeprecatedRPC(ctx, method, req)
+		if followerRead {
+			return s.serveStoreFollowerRead(ctx, req)
+		}
+		reply, err := handler(ctx, req)
+		if err == nil {
+			if checks&checkFollowerRead != 0 {
+				s.setStoreRevisionHeader(ctx)
+			}
+			setHeaderErrorRetryHint(ctx, reply)
+		}
+		return reply, err
+	}
+}
+
+// chainUnaryInterceptor returns an interceptor which calls outer and then
+// inner. The outer one may be nil.
+func chainUnaryInterceptor(outer, inner grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	if outer == nil {
+		return inner
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return outer(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return inner(ctx, req, info, handler)
+		})
+	}
+}
+
+// forwardUnary forwards the request of the full method to the member, and
+// passes its trailer on to the client. The reply is decoded into reply.
+func (s *Server) forwardUnary(ctx context.Context, forwardedHost, fullMethod string, reply, req interface{}) (interface{}, error) {
+	if err := s.forwardConns.allowUnary(forwardedHost); err != nil {
+		return nil, err
+	}
+	forwardedRequestCounter.WithLabelValues(path.Base(fullMethod)).Inc()
+	client, err := s.forwardConns.get(ctx, forwardedHost)
+	if err != nil {
+		s.forwardConns.reportUnary(forwardedHost, err)
+		return nil, err
+	}
+	var trailer metadata.MD
+	opts := append(s.forwardCallOptions(path.Base(fullMethod)), grpc.Trailer(&trailer))
+	err = client.Invoke(grpcutil.ResetForwardContext(ctx), fullMethod, req, reply, opts...)
+	s.forwardConns.reportUnary(forwardedHost, err)
+	if len(trailer) > 0 {
+		_ = grpc.SetTrailer(ctx, trailer)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// forwardCallOptions returns the options to forward the method to the leader,
+// which compress the region-heavy ones if configured.
+func (s *Server) forwardCallOptions(method string) []grpc.CallOption {
+	compressor := s.persistOptions.GetForwardGRPCCompression()
+	if compressor == "" || !grpcutil.IsRegionHeavyMethod(method) {
+		return nil
+	}
+	return []grpc.CallOption{grpc.UseCompressor(compressor)}
+}
+
+// interceptStream does the checks of the method when a stream is opened.
+func (s *Server) interceptStream(stream grpc.ServerStream, method string, checks grpcChecks) error {
+	if err := s.adminAuthorizer.authorize(stream.Context(), method); err != nil {
+		return err
+	}
+	if checks&checkRateLimit != 0 {
+		return s.rateLimitCheck(stream.Context())
+	}
+	return nil
+}
+
+// pdServiceDesc is the generated service description of PDServer, which is
+// not exported by kvproto.
+//
+//go:linkname pdServiceDesc github.com/pingcap/kvproto/pkg/pdpb._PD_serviceDesc
+var pdServiceDesc grpc.ServiceDesc
+
+// newPDServiceDesc returns a copy of the generated service description of
+// PDServer, whose handlers call the interceptors before the methods, so that
+// all the requests go through them. A new method gets the default checks
+// without any change here.
+func (s *Server) newPDServiceDesc() *grpc.ServiceDesc {
+	desc := pdServiceDesc
+	serverType := reflect.TypeOf(desc.HandlerType).Elem()
+	desc.Methods = make([]grpc.MethodDesc, 0, len(pdServiceDesc.Methods))
+	for _, m := range pdServiceDesc.Methods {
+		// The reply type is needed to forward the requests, so it is looked
+		// up once here instead of for every request.
+		method, ok := serverType.MethodByName(m.MethodName)
+		if !ok {
+			panic("method " + m.MethodName + " is not implemented by PDServer")
+		}
+		replyType := method.Type.Out(0).Elem()
+		newReply := func() interface{} { return reflect.New(replyType).Interface() }
+		inner := s.unaryInterceptor(getGRPCChecks(m.MethodName), newReply)
+		handle := m.Handler
+		m.Handler = func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			return handle(srv, ctx, dec, chainUnaryInterceptor(interceptor, inner))
+		}
+		desc.Methods = append(desc.Methods, m)
+	}
+	desc.Streams = make([]grpc.StreamDesc, 0, len(pdServiceDesc.Streams))
+	for _, st := range pdServiceDesc.Streams {
+		name, handle := st.StreamName, st.Handler
+		st.Handler = func(srv interface{}, stream grpc.ServerStream) error {
+			if err := s.interceptStream(stream, name, getGRPCChecks(name)); err != nil {
+				return err
+			}
+			return handle(srv, stream)
+		}
+		desc.Streams = append(desc.Streams, st)
+	}
+	return &desc
+}
+
+// validateMessage validates that the server is the leader, and that the
+// cluster ID of the message matches if it has a header.
+func (s *Server) validateMessage(m interface{}) error {
+	if r, ok := m.(interface{ GetHeader() *pdpb.RequestHeader }); ok {
+		return s.validateRequest(r.GetHeader())
+	}
+	if s.IsClosed() || !s.member.IsLeader() {
+		return errors.WithStack(s.notLeaderError())
+	}
+	return nil
+}
+
+// validatedServerStream validates every request received by a stream of the
+// services which are not a part of pdpb.
+type validatedServerStream struct {
+	grpc.ServerStream
+	s *Server
+}
+
+func (vs *validatedServerStream) RecvMsg(m interface{}) error {
+	if err := vs.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return vs.s.validateMessage(m)
+}
@@ -22,6 +22,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -29,11 +30,12 @@ import (
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/idempotency"
 	"github.com/tikv/pd/pkg/logutil"
 	"github.com/tikv/pd/pkg/tsoutil"
 	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/core"
-	"github.com/tikv/pd/server/tso"
+	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/versioninfo"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -42,7 +44,12 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-const slowThreshold = 5 * time.Millisecond
+const (
+	slowThreshold = 5 * time.Millisecond
+	// notLeaderRetryBackoff is the recommended backoff for clients to retry
+	// after receiving a not leader error.
+	notLeaderRetryBackoff = 100 * time.Millisecond
+)
 
 // gRPC errors
 var (
@@ -53,7 +60,7 @@ var (
 )
 
 // GetMembers implements gRPC PDServer.
-func (s *Server) GetMembers(context.Context, *pdpb.GetMembersRequest) (*pdpb.GetMembersResponse, error) {
+func (s *Server) GetMembers(ctx context.Context, request *pdpb.GetMembersRequest) (*pdpb.GetMembersResponse, error) {
 	if s.IsClosed() {
 		return nil, status.Errorf(codes.Unknown, "server not started")
 	}
@@ -77,6 +84,10 @@ func (s *Server) GetMembers(context.Context, *pdpb.GetMembersRequest) (*pdpb.Get
 		return nil, status.Errorf(codes.Unknown, err.Error())
 	}
 
+	if domain := grpcutil.GetRoutingDomain(ctx); domain != "" {
+		s.applyRoutingDomain(domain, members, tsoAllocatorLeaders)
+	}
+
 	leader := s.member.GetLeader()
 	for _, m := range members {
 		if m.MemberId == leader.GetMemberId() {
@@ -94,6 +105,32 @@ func (s *Server) GetMembers(context.Context, *pdpb.GetMembersRequest) (*pdpb.Get
 	}, nil
 }
 
+// applyRoutingDomain replaces the client urls of the members with the ones
+// advertised for the given routing domain. The members which do not advertise
+// urls for the domain are left unchanged.
+func (s *Server) applyRoutingDomain(domain string, members []*pdpb.Member, allocatorLeaders map[string]*pdpb.Member) {
+	domainURLs := make(map[uint64][]string, len(members))
+	for _, m := range members {
+		routingURLs, err := s.member.GetMemberRoutingURLs(m.GetMemberId())
+		if err != nil {
+			log.Warn("failed to load routing urls of member", zap.Uint64("member-id", m.GetMemberId()), errs.ZapError(err))
+			continue
+		}
+		if urls, ok := routingURLs[domain]; ok {
+			domainURLs[m.GetMemberId()] = urls
+			m.ClientUrls = urls
+		}
+	}
+	for dcLocation, m := range allocatorLeaders {
+		if urls, ok := domainURLs[m.GetMemberId()]; ok {
+			// the allocator leader is shared, so copy it before modifying.
+			leader := *m
+			leader.ClientUrls = urls
+			allocatorLeaders[dcLocation] = &leader
+		}
+	}
+}
+
 // Tso implements gRPC PDServer.
 func (s *Server) Tso(stream pdpb.PD_TsoServer) error {
 	var (
@@ -107,6 +144,12 @@ func (s *Server) Tso(stream pdpb.PD_TsoServer) error {
 			cancel()
 		}
 	}()
+	if !s.isLocalRequest(getForwardedHost(stream.Context())) {
+		if err := s.acquireTSOProxyStream(); err != nil {
+			return err
+		}
+		defer s.releaseTSOProxyStream()
+	}
 	for {
 		request, err := stream.Recv()
 		if err == io.EOF {
@@ -122,13 +165,14 @@ func (s *Server) Tso(stream pdpb.PD_TsoServer) error {
 				if cancel != nil {
 					cancel()
 				}
-				client, err := s.getDelegateClient(s.ctx, forwardedHost)
+				client, release, err := s.forwardConns.acquireStream(s.ctx, forwardedHost)
 				if err != nil {
 					return err
 				}
 				// TODO: change it to the info level once the TiKV doesn't use it in a unary way.
 				log.Debug("create TSO forward stream", zap.String("forwarded-host", forwardedHost))
 				forwardStream, cancel, err = s.createTsoForwardStream(client, forwardedHost)
+				cancel = withStreamRelease(cancel, release)
 				if err != nil {
 					return err
 				}
@@ -181,22 +225,27 @@ func (s *Server) Tso(stream pdpb.PD_TsoServer) error {
 	}
 }
 
-// Bootstrap implements gRPC PDServer.
-func (s *Server) Bootstrap(ctx context.Context, request *pdpb.BootstrapRequest) (*pdpb.BootstrapResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).Bootstrap(ctx, request)
-	}
+// acquireTSOProxyStream counts a TSO stream forwarded to the leader. It
+// rejects the stream if there are too many, so that the clients turning to a
+// follower together after the leader fails cannot overwhelm it.
+func (s *Server) acquireTSOProxyStream() error {
+	limit := s.persistOptions.GetMaxConcurrentTSOProxyStreamings()
+	if count := atomic.AddInt32(&s.concurrentTSOProxyStreamings, 1); limit > 0 && int(count) > limit {
+		atomic.AddInt32(&s.concurrentTSOProxyStreamings, -1)
+		tsoProxyRejectedCounter.Inc()
+		return status.Errorf(codes.ResourceExhausted, "too many tso proxy streams, the limit is %d", limit)
+	}
+	tsoProxyStreamGauge.Inc()
+	return nil
+}
 
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
+func (s *Server) releaseTSOProxyStream() {
+	atomic.AddInt32(&s.concurrentTSOProxyStreamings, -1)
+	tsoProxyStreamGauge.Dec()
+}
 
+// Bootstrap implements gRPC PDServer.
+func (s *Server) Bootstrap(ctx context.Context, request *pdpb.BootstrapRequest) (*pdpb.BootstrapResponse, error) {
 	rc := s.GetRaftCluster()
 	if rc != nil {
 		err := &pdpb.Error{
@@ -219,20 +268,6 @@ func (s *Server) Bootstrap(ctx context.Context, request *pdpb.BootstrapRequest)
 
 // IsBootstrapped implements gRPC PDServer.
 func (s *Server) IsBootstrapped(ctx context.Context, request *pdpb.IsBootstrappedRequest) (*pdpb.IsBootstrappedResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).IsBootstrapped(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	return &pdpb.IsBootstrappedResponse{
 		Header:       s.header(),
@@ -242,20 +277,11 @@ func (s *Server) IsBootstrapped(ctx context.Context, request *pdpb.IsBootstrappe
 
 // AllocID implements gRPC PDServer.
 func (s *Server) AllocID(ctx context.Context, request *pdpb.AllocIDRequest) (*pdpb.AllocIDResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
+	if rc := s.GetRaftCluster(); rc != nil {
+		if err := rc.CheckRecoverVerified(); err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, err.Error())
 		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).AllocID(ctx, request)
 	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	// We can use an allocator for all types ID allocation.
 	id, err := s.idAllocator.Alloc()
 	if err != nil {
@@ -270,20 +296,6 @@ func (s *Server) AllocID(ctx context.Context, request *pdpb.AllocIDRequest) (*pd
 
 // GetStore implements gRPC PDServer.
 func (s *Server) GetStore(ctx context.Context, request *pdpb.GetStoreRequest) (*pdpb.GetStoreResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).GetStore(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.GetStoreResponse{Header: s.notBootstrappedHeader()}, nil
@@ -301,8 +313,8 @@ func (s *Server) GetStore(ctx context.Context, request *pdpb.GetStoreRequest) (*
 	}, nil
 }
 
-// checkStore returns an error response if the store exists and is in tombstone state.
-// It returns nil if it can't get the store.
+// checkStore returns an error response if the store exists and is in tombstone state,
+// or if the store is archived. It returns nil if it can't get the store.
 func checkStore(rc *cluster.RaftCluster, storeID uint64) *pdpb.Error {
 	store := rc.GetStore(storeID)
 	if store != nil {
@@ -312,26 +324,26 @@ func checkStore(rc *cluster.RaftCluster, storeID uint64) *pdpb.Error {
 				Message: "store is tombstone",
 			}
 		}
+		return nil
+	}
+	// An archived store was a tombstone store, it must not come back as a
+	// new store with the same ID.
+	archived, err := rc.IsStoreArchived(storeID)
+	if err != nil {
+		log.Warn("failed to check whether the store is archived", zap.Uint64("store-id", storeID), errs.ZapError(err))
+		return nil
+	}
+	if archived {
+		return &pdpb.Error{
+			Type:    pdpb.ErrorType_STORE_TOMBSTONE,
+			Message: "store is archived",
+		}
 	}
 	return nil
 }
 
 // PutStore implements gRPC PDServer.
 func (s *Server) PutStore(ctx context.Context, request *pdpb.PutStoreRequest) (*pdpb.PutStoreResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).PutStore(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.PutStoreResponse{Header: s.notBootstrappedHeader()}, nil
@@ -364,23 +376,9 @@ func (s *Server) PutStore(ctx context.Context, request *pdpb.PutStoreRequest) (*
 
 // GetAllStores implements gRPC PDServer.
 func (s *Server) GetAllStores(ctx context.Context, request *pdpb.GetAllStoresRequest) (*pdpb.GetAllStoresResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).GetAllStores(ctx, request)
-	}
-
 	failpoint.Inject("customTimeout", func() {
 		time.Sleep(5 * time.Second)
 	})
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.GetAllStoresResponse{Header: s.notBootstrappedHeader()}, nil
@@ -397,6 +395,12 @@ func (s *Server) GetAllStores(ctx context.Context, request *pdpb.GetAllStoresReq
 	} else {
 		stores = rc.GetMetaStores()
 	}
+	// The keyspace and labels to filter the stores are carried by metadata.
+	filter := &cluster.StoreFilter{Labels: grpcutil.GetStoreLabels(ctx)}
+	if keyspaceID, ok := grpcutil.GetStoreKeyspace(ctx); ok {
+		filter.KeyspaceID = &keyspaceID
+	}
+	stores = rc.FilterMetaStores(stores, filter)
 
 	return &pdpb.GetAllStoresResponse{
 		Header: s.header(),
@@ -406,20 +410,6 @@ func (s *Server) GetAllStores(ctx context.Context, request *pdpb.GetAllStoresReq
 
 // StoreHeartbeat implements gRPC PDServer.
 func (s *Server) StoreHeartbeat(ctx context.Context, request *pdpb.StoreHeartbeatRequest) (*pdpb.StoreHeartbeatResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).StoreHeartbeat(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	if request.GetStats() == nil {
 		return nil, errors.Errorf("invalid store heartbeat command, but %v", request)
 	}
@@ -531,12 +521,13 @@ func (s *Server) RegionHeartbeat(stream pdpb.PD_RegionHeartbeatServer) error {
 				if cancel != nil {
 					cancel()
 				}
-				client, err := s.getDelegateClient(s.ctx, forwardedHost)
+				client, release, err := s.forwardConns.acquireStream(s.ctx, forwardedHost)
 				if err != nil {
 					return err
 				}
 				log.Info("create region heartbeat forward stream", zap.String("forwarded-host", forwardedHost))
 				forwardStream, cancel, err = s.createHeartbeatForwardStream(client, forwardedHost)
+				cancel = withStreamRelease(cancel, release)
 				if err != nil {
 					return err
 				}
@@ -586,28 +577,8 @@ func (s *Server) RegionHeartbeat(stream pdpb.PD_RegionHeartbeatServer) error {
 			lastBind = time.Now()
 		}
 
-		region := core.RegionFromHeartbeat(request)
-		if region.GetLeader() == nil {
-			log.Error("invalid request, the leader is nil", zap.Reflect("request", request), errs.ZapError(errs.ErrLeaderNil))
-			regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "invalid-leader").Inc()
-			msg := fmt.Sprintf("invalid request leader, %v", request)
-			s.hbStreams.SendErr(pdpb.ErrorType_UNKNOWN, msg, request.GetLeader())
-			continue
-		}
-		if region.GetID() == 0 {
-			regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "invalid-region").Inc()
-			msg := fmt.Sprintf("invalid request region, %v", request)
-			s.hbStreams.SendErr(pdpb.ErrorType_UNKNOWN, msg, request.GetLeader())
-			continue
-		}
-
-		// If the region peer count is 0, then we should not handle this.
-		if len(region.GetPeers()) == 0 {
-			log.Warn("invalid region, zero region peer count",
-				logutil.ZapRedactStringer("region-meta", core.RegionToHexMeta(region.GetMeta())))
-			regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "no-peer").Inc()
-			msg := fmt.Sprintf("invalid region, zero region peer count: %v", logutil.RedactStringer(core.RegionToHexMeta(region.GetMeta())))
-			s.hbStreams.SendErr(pdpb.ErrorType_UNKNOWN, msg, request.GetLeader())
+		region := s.regionFromHeartbeat(rc, request, storeAddress, storeLabel)
+		if region == nil {
 			continue
 		}
 		start := time.Now()
@@ -624,22 +595,44 @@ func (s *Server) RegionHeartbeat(stream pdpb.PD_RegionHeartbeatServer) error {
 	}
 }
 
+// regionFromHeartbeat converts the heartbeat to a region. The invalid
+// heartbeats are reported to the store, and nil is returned for them and for
+// the ones which can be skipped.
+func (s *Server) regionFromHeartbeat(rc *cluster.RaftCluster, request *pdpb.RegionHeartbeatRequest, storeAddress, storeLabel string) *core.RegionInfo {
+	region := core.RegionFromHeartbeat(request)
+	if region.GetLeader() == nil {
+		log.Error("invalid request, the leader is nil", zap.Reflect("request", request), errs.ZapError(errs.ErrLeaderNil))
+		regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "invalid-leader").Inc()
+		msg := fmt.Sprintf("invalid request leader, %v", request)
+		s.hbStreams.SendErr(pdpb.ErrorType_UNKNOWN, msg, request.GetLeader())
+		return nil
+	}
+	if region.GetID() == 0 {
+		regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "invalid-region").Inc()
+		msg := fmt.Sprintf("invalid request region, %v", request)
+		s.hbStreams.SendErr(pdpb.ErrorType_UNKNOWN, msg, request.GetLeader())
+		return nil
+	}
+
+	// If the region peer count is 0, then we should not handle this.
+	if len(region.GetPeers()) == 0 {
+		log.Warn("invalid region, zero region peer count",
+			logutil.ZapRedactStringer("region-meta", core.RegionToHexMeta(region.GetMeta())))
+		regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "no-peer").Inc()
+		msg := fmt.Sprintf("invalid region, zero region peer count: %v", logutil.RedactStringer(core.RegionToHexMeta(region.GetMeta())))
+		s.hbStreams.SendErr(pdpb.ErrorType_UNKNOWN, msg, request.GetLeader())
+		return nil
+	}
+	if s.persistOptions.IsSkipUnchangedRegionHeartbeatEnabled() && rc.IsRegionHeartbeatUnchanged(region) {
+		regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "skip").Inc()
+		rc.HandleSkippedRegionHeartbeat(region)
+		return nil
+	}
+	return region
+}
+
 // GetRegion implements gRPC PDServer.
 func (s *Server) GetRegion(ctx context.Context, request *pdpb.GetRegionRequest) (*pdpb.GetRegionResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).GetRegion(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.GetRegionResponse{Header: s.notBootstrappedHeader()}, nil
@@ -659,20 +652,6 @@ func (s *Server) GetRegion(ctx context.Context, request *pdpb.GetRegionRequest)
 
 // GetPrevRegion implements gRPC PDServer
 func (s *Server) GetPrevRegion(ctx context.Context, request *pdpb.GetRegionRequest) (*pdpb.GetRegionResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).GetPrevRegion(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.GetRegionResponse{Header: s.notBootstrappedHeader()}, nil
@@ -693,20 +672,6 @@ func (s *Server) GetPrevRegion(ctx context.Context, request *pdpb.GetRegionReque
 
 // GetRegionByID implements gRPC PDServer.
 func (s *Server) GetRegionByID(ctx context.Context, request *pdpb.GetRegionByIDRequest) (*pdpb.GetRegionResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).GetRegionByID(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.GetRegionResponse{Header: s.notBootstrappedHeader()}, nil
@@ -726,26 +691,16 @@ func (s *Server) GetRegionByID(ctx context.Context, request *pdpb.GetRegionByIDR
 
 // ScanRegions implements gRPC PDServer.
 func (s *Server) ScanRegions(ctx context.Context, request *pdpb.ScanRegionsRequest) (*pdpb.ScanRegionsResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).ScanRegions(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.ScanRegionsResponse{Header: s.notBootstrappedHeader()}, nil
 	}
 	regions := rc.ScanRegions(request.GetStartKey(), request.GetEndKey(), int(request.GetLimit()))
-	resp := &pdpb.ScanRegionsResponse{Header: s.header()}
+	return newScanRegionsResponse(s.header(), regions), nil
+}
+
+func newScanRegionsResponse(header *pdpb.ResponseHeader, regions []*core.RegionInfo) *pdpb.ScanRegionsResponse {
+	resp := &pdpb.ScanRegionsResponse{Header: header}
 	for _, r := range regions {
 		leader := r.GetLeader()
 		if leader == nil {
@@ -761,25 +716,11 @@ func (s *Server) ScanRegions(ctx context.Context, request *pdpb.ScanRegionsReque
 			PendingPeers: r.GetPendingPeers(),
 		})
 	}
-	return resp, nil
+	return resp
 }
 
 // AskSplit implements gRPC PDServer.
 func (s *Server) AskSplit(ctx context.Context, request *pdpb.AskSplitRequest) (*pdpb.AskSplitResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).AskSplit(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.AskSplitResponse{Header: s.notBootstrappedHeader()}, nil
@@ -804,20 +745,6 @@ func (s *Server) AskSplit(ctx context.Context, request *pdpb.AskSplitRequest) (*
 
 // AskBatchSplit implements gRPC PDServer.
 func (s *Server) AskBatchSplit(ctx context.Context, request *pdpb.AskBatchSplitRequest) (*pdpb.AskBatchSplitResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).AskBatchSplit(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.AskBatchSplitResponse{Header: s.notBootstrappedHeader()}, nil
@@ -846,20 +773,6 @@ func (s *Server) AskBatchSplit(ctx context.Context, request *pdpb.AskBatchSplitR
 
 // ReportSplit implements gRPC PDServer.
 func (s *Server) ReportSplit(ctx context.Context, request *pdpb.ReportSplitRequest) (*pdpb.ReportSplitResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).ReportSplit(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.ReportSplitResponse{Header: s.notBootstrappedHeader()}, nil
@@ -876,20 +789,6 @@ func (s *Server) ReportSplit(ctx context.Context, request *pdpb.ReportSplitReque
 
 // ReportBatchSplit implements gRPC PDServer.
 func (s *Server) ReportBatchSplit(ctx context.Context, request *pdpb.ReportBatchSplitRequest) (*pdpb.ReportBatchSplitResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).ReportBatchSplit(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.ReportBatchSplitResponse{Header: s.notBootstrappedHeader()}, nil
@@ -907,20 +806,6 @@ func (s *Server) ReportBatchSplit(ctx context.Context, request *pdpb.ReportBatch
 
 // GetClusterConfig implements gRPC PDServer.
 func (s *Server) GetClusterConfig(ctx context.Context, request *pdpb.GetClusterConfigRequest) (*pdpb.GetClusterConfigResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).GetClusterConfig(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.GetClusterConfigResponse{Header: s.notBootstrappedHeader()}, nil
@@ -933,20 +818,6 @@ func (s *Server) GetClusterConfig(ctx context.Context, request *pdpb.GetClusterC
 
 // PutClusterConfig implements gRPC PDServer.
 func (s *Server) PutClusterConfig(ctx context.Context, request *pdpb.PutClusterConfigRequest) (*pdpb.PutClusterConfigResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).PutClusterConfig(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.PutClusterConfigResponse{Header: s.notBootstrappedHeader()}, nil
@@ -965,25 +836,21 @@ func (s *Server) PutClusterConfig(ctx context.Context, request *pdpb.PutClusterC
 
 // ScatterRegion implements gRPC PDServer.
 func (s *Server) ScatterRegion(ctx context.Context, request *pdpb.ScatterRegionRequest) (*pdpb.ScatterRegionResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).ScatterRegion(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.ScatterRegionResponse{Header: s.notBootstrappedHeader()}, nil
 	}
 
+	resp, err := s.doIdempotent(ctx, request, func() (proto.Message, error) {
+		return s.scatterRegion(ctx, rc, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pdpb.ScatterRegionResponse), nil
+}
+
+func (s *Server) scatterRegion(ctx context.Context, rc *cluster.RaftCluster, request *pdpb.ScatterRegionRequest) (*pdpb.ScatterRegionResponse, error) {
 	if len(request.GetRegionsId()) > 0 {
 		ops, failures, err := rc.GetRegionScatter().ScatterRegionsByID(request.GetRegionsId(), request.GetGroup(), int(request.GetRetryLimit()))
 		if err != nil {
@@ -991,7 +858,7 @@ func (s *Server) ScatterRegion(ctx context.Context, request *pdpb.ScatterRegionR
 		}
 		for _, op := range ops {
 			if ok := rc.GetOperatorController().AddOperator(op); !ok {
-				failures[op.RegionID()] = fmt.Errorf("region %v failed to add operator", op.RegionID())
+				failures[op.RegionID()] = addScatterOperatorError(rc, op)
 			}
 		}
 		percentage := 100
@@ -1004,6 +871,7 @@ func (s *Server) ScatterRegion(ctx context.Context, request *pdpb.ScatterRegionR
 				}
 				return r
 			}()))
+			setScatterFailures(ctx, failures)
 		}
 		return &pdpb.ScatterRegionResponse{
 			Header:             s.header(),
@@ -1026,7 +894,9 @@ func (s *Server) ScatterRegion(ctx context.Context, request *pdpb.ScatterRegionR
 		return nil, err
 	}
 	if op != nil {
-		rc.GetOperatorController().AddOperator(op)
+		if ok := rc.GetOperatorController().AddOperator(op); !ok {
+			setScatterFailures(ctx, map[uint64]error{op.RegionID(): addScatterOperatorError(rc, op)})
+		}
 	}
 
 	return &pdpb.ScatterRegionResponse{
@@ -1035,22 +905,34 @@ func (s *Server) ScatterRegion(ctx context.Context, request *pdpb.ScatterRegionR
 	}, nil
 }
 
-// GetGCSafePoint implements gRPC PDServer.
-func (s *Server) GetGCSafePoint(ctx context.Context, request *pdpb.GetGCSafePointRequest) (*pdpb.GetGCSafePointResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).GetGCSafePoint(ctx, request)
+// addScatterOperatorError tells why the scatter operator failed to be added.
+func addScatterOperatorError(rc *cluster.RaftCluster, op *operator.Operator) error {
+	oc := rc.GetOperatorController()
+	switch {
+	case oc.ExceedStoreLimit(op) || oc.ExceedSnapshotLimit(op):
+		return errs.ErrScatterStoreLimit.FastGenByArgs(op.RegionID())
+	case oc.GetOperator(op.RegionID()) != nil:
+		return errs.ErrScatterOperatorConflict.FastGenByArgs(op.RegionID())
+	default:
+		return errs.ErrScatterAddOperator.FastGenByArgs(op.RegionID())
 	}
+}
 
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
+// setScatterFailures attaches the structured reasons of the failures to the
+// trailer of the response, so that the clients can tell the transient
+// failures from the structural ones.
+func setScatterFailures(ctx context.Context, failures map[uint64]error) {
+	scatterFailures := make([]*grpcutil.ScatterFailure, 0, len(failures))
+	for regionID, err := range failures {
+		scatterFailures = append(scatterFailures, grpcutil.NewScatterFailure(regionID, err))
 	}
+	if err := grpcutil.SetScatterFailures(ctx, scatterFailures); err != nil {
+		log.Debug("failed to set the scatter failures", errs.ZapError(err))
+	}
+}
 
+// GetGCSafePoint implements gRPC PDServer.
+func (s *Server) GetGCSafePoint(ctx context.Context, request *pdpb.GetGCSafePointRequest) (*pdpb.GetGCSafePointResponse, error) {
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.GetGCSafePointResponse{Header: s.notBootstrappedHeader()}, nil
@@ -1077,20 +959,6 @@ func (s *Server) SyncRegions(stream pdpb.PD_SyncRegionsServer) error {
 
 // UpdateGCSafePoint implements gRPC PDServer.
 func (s *Server) UpdateGCSafePoint(ctx context.Context, request *pdpb.UpdateGCSafePointRequest) (*pdpb.UpdateGCSafePointResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).UpdateGCSafePoint(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.UpdateGCSafePointResponse{Header: s.notBootstrappedHeader()}, nil
@@ -1103,6 +971,35 @@ func (s *Server) UpdateGCSafePoint(ctx context.Context, request *pdpb.UpdateGCSa
 
 	newSafePoint := request.SafePoint
 
+	// The GC safe point cannot move beyond the pause.
+	now, err := s.gcNow()
+	if err != nil {
+		return nil, err
+	}
+	pause, err := s.storage.LoadGCPause(now)
+	if err != nil {
+		return nil, err
+	}
+	if pause != nil && newSafePoint > pause.SafePoint {
+		log.Warn("gc safe point is held by the gc pause",
+			zap.String("reason", pause.Reason),
+			zap.Uint64("pause-safe-point", pause.SafePoint),
+			zap.Uint64("new-safe-point", newSafePoint))
+		newSafePoint = pause.SafePoint
+	}
+	// Nor beyond the timestamps granted as leases.
+	lease, err := s.tsoAllocatorManager.GetMinLeasedTS()
+	if err != nil {
+		return nil, err
+	}
+	if lease != nil && newSafePoint > lease.Start {
+		log.Warn("gc safe point is held by the tso lease",
+			zap.Uint64("lease-id", lease.ID),
+			zap.Uint64("lease-start", lease.Start),
+			zap.Uint64("new-safe-point", newSafePoint))
+		newSafePoint = lease.Start
+	}
+
 	// Only save the safe point if it's greater than the previous one
 	if newSafePoint > oldSafePoint {
 		if err := s.storage.SaveGCSafePoint(newSafePoint); err != nil {
@@ -1128,20 +1025,6 @@ func (s *Server) UpdateServiceGCSafePoint(ctx context.Context, request *pdpb.Upd
 	s.serviceSafePointLock.Lock()
 	defer s.serviceSafePointLock.Unlock()
 
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).UpdateServiceGCSafePoint(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.UpdateServiceGCSafePointResponse{Header: s.notBootstrappedHeader()}, nil
@@ -1152,11 +1035,10 @@ func (s *Server) UpdateServiceGCSafePoint(ctx context.Context, request *pdpb.Upd
 		}
 	}
 
-	nowTSO, err := s.tsoAllocatorManager.HandleTSORequest(tso.GlobalDCLocation, 1)
+	now, err := s.gcNow()
 	if err != nil {
 		return nil, err
 	}
-	now, _ := tsoutil.ParseTimestamp(nowTSO)
 	min, err := s.storage.LoadMinServiceGCSafePoint(now)
 	if err != nil {
 		return nil, err
@@ -1187,6 +1069,32 @@ func (s *Server) UpdateServiceGCSafePoint(ctx context.Context, request *pdpb.Upd
 		}
 	}
 
+	// The paused GC works as a service safe point which holds the GC.
+	pause, err := s.storage.LoadGCPause(now)
+	if err != nil {
+		return nil, err
+	}
+	if pause != nil && pause.SafePoint < min.SafePoint {
+		min = &core.ServiceSafePoint{
+			ServiceID: gcPauseServiceID,
+			ExpiredAt: pause.ExpiredAt,
+			SafePoint: pause.SafePoint,
+		}
+	}
+	// The timestamps granted as leases can still be assigned by the holders,
+	// so they work as a service safe point too.
+	lease, err := s.tsoAllocatorManager.GetMinLeasedTS()
+	if err != nil {
+		return nil, err
+	}
+	if lease != nil && lease.Start < min.SafePoint {
+		min = &core.ServiceSafePoint{
+			ServiceID: tsoLeaseServiceID,
+			ExpiredAt: lease.ExpireTime.Unix(),
+			SafePoint: lease.Start,
+		}
+	}
+
 	return &pdpb.UpdateServiceGCSafePointResponse{
 		Header:       s.header(),
 		ServiceId:    []byte(min.ServiceID),
@@ -1197,20 +1105,6 @@ func (s *Server) UpdateServiceGCSafePoint(ctx context.Context, request *pdpb.Upd
 
 // GetOperator gets information about the operator belonging to the specify region.
 func (s *Server) GetOperator(ctx context.Context, request *pdpb.GetOperatorRequest) (*pdpb.GetOperatorResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).GetOperator(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
-		return nil, err
-	}
-
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.GetOperatorResponse{Header: s.notBootstrappedHeader()}, nil
@@ -1237,17 +1131,74 @@ func (s *Server) GetOperator(ctx context.Context, request *pdpb.GetOperatorReque
 }
 
 // validateRequest checks if Server is leader and clusterID is matched.
-// TODO: Call it in gRPC interceptor.
+// It is called by the interceptors of the unary methods.
 func (s *Server) validateRequest(header *pdpb.RequestHeader) error {
 	if s.IsClosed() || !s.member.IsLeader() {
-		return errors.WithStack(ErrNotLeader)
+		return errors.WithStack(s.notLeaderError())
 	}
+	return s.validateClusterID(header)
+}
+
+// validateClusterID checks if clusterID is matched.
+func (s *Server) validateClusterID(header *pdpb.RequestHeader) error {
 	if header.GetClusterId() != s.clusterID {
 		return status.Errorf(codes.FailedPrecondition, "mismatch cluster id, need %d but got %d", s.clusterID, header.GetClusterId())
 	}
 	return nil
 }
 
+// rateLimitCheck returns a ResourceExhausted error if the caller component of
+// the request is out of its quota, with the retry hint attached like the one of
+// ErrNotLeader. The requests without a caller component are not limited.
+func (s *Server) rateLimitCheck(ctx context.Context) error {
+	component := grpcutil.GetCallerComponent(ctx)
+	if component == "" || s.callerLimiter.Allow(component) {
+		return nil
+	}
+	method, _ := grpc.Method(ctx)
+	rateLimitedCounter.WithLabelValues(component, method).Inc()
+	hint := grpcutil.RetryHint{Retryable: true, Backoff: s.callerLimiter.RetryAfter(component)}
+	return grpcutil.NewRetryableError(codes.ResourceExhausted, fmt.Sprintf("rate limit of caller component %s exceeded, method %s", component, method), hint)
+}
+
+// doIdempotent executes handle only once for the requests which carry the same
+// idempotency key in metadata, and returns the recorded response to the
+// retries. Only the successful responses are recorded, so a failed request
+// can be retried with the same key.
+func (s *Server) doIdempotent(ctx context.Context, request proto.Message, handle func() (proto.Message, error)) (proto.Message, error) {
+	key := grpcutil.GetIdempotencyKey(ctx)
+	if key == "" {
+		return handle()
+	}
+	method, _ := grpc.Method(ctx)
+	data, err := proto.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	type result struct {
+		resp proto.Message
+		err  error
+	}
+	r, _, err := s.idempotencyCache.Do(key, idempotency.Fingerprint([]byte(method), data), func() (interface{}, bool) {
+		resp, err := handle()
+		return result{resp: resp, err: err}, err == nil
+	})
+	if err != nil {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	return r.(result).resp, r.(result).err
+}
+
+// notLeaderError returns ErrNotLeader with the retry hint attached, which
+// carries the current leader if it is known.
+func (s *Server) notLeaderError() error {
+	hint := grpcutil.RetryHint{Retryable: true, Backoff: notLeaderRetryBackoff}
+	if leader := s.member.GetLeader(); leader != nil && len(leader.GetClientUrls()) > 0 {
+		hint.Leader = leader.GetClientUrls()[0]
+	}
+	return grpcutil.NewRetryableError(codes.Unavailable, status.Convert(ErrNotLeader).Message(), hint)
+}
+
 func (s *Server) header() *pdpb.ResponseHeader {
 	return &pdpb.ResponseHeader{ClusterId: s.clusterID}
 }
@@ -1259,6 +1210,19 @@ func (s *Server) errorHeader(err *pdpb.Error) *pdpb.ResponseHeader {
 	}
 }
 
+// setHeaderErrorRetryHint sets the retry hint of the retryable error reported
+// in the header of the reply to the trailer, as the header has no field for
+// it. It is called by the interceptors for all the unary methods.
+func setHeaderErrorRetryHint(ctx context.Context, reply interface{}) {
+	r, ok := reply.(interface{ GetHeader() *pdpb.ResponseHeader })
+	if !ok {
+		return
+	}
+	if hint, ok := grpcutil.GetHeaderErrorRetryHint(r.GetHeader().GetError()); ok {
+		_ = grpcutil.SetRetryHintTrailer(ctx, hint)
+	}
+}
+
 func (s *Server) notBootstrappedHeader() *pdpb.ResponseHeader {
 	return s.errorHeader(&pdpb.Error{
 		Type:    pdpb.ErrorType_NOT_BOOTSTRAPPED,
@@ -1283,16 +1247,6 @@ func (s *Server) incompatibleVersion(tag string) *pdpb.ResponseHeader {
 //    with its current TSO in memory to make sure their local TSOs are not less
 //    than MaxTS by writing MaxTS into memory to finish the global TSO synchronization.
 func (s *Server) SyncMaxTS(ctx context.Context, request *pdpb.SyncMaxTSRequest) (*pdpb.SyncMaxTSResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).SyncMaxTS(ctx, request)
-	}
-
 	if err := s.validateInternalRequest(request.GetHeader(), true); err != nil {
 		return nil, err
 	}
@@ -1349,46 +1303,29 @@ func (s *Server) SyncMaxTS(ctx context.Context, request *pdpb.SyncMaxTSRequest)
 
 // SplitRegions split regions by the given split keys
 func (s *Server) SplitRegions(ctx context.Context, request *pdpb.SplitRegionsRequest) (*pdpb.SplitRegionsResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).SplitRegions(ctx, request)
-	}
-
-	if err := s.validateRequest(request.GetHeader()); err != nil {
+	resp, err := s.doIdempotent(ctx, request, func() (proto.Message, error) {
+		finishedPercentage, newRegionIDs := s.cluster.GetRegionSplitter().SplitRegions(ctx, request.GetSplitKeys(), int(request.GetRetryLimit()))
+		return &pdpb.SplitRegionsResponse{
+			Header:             s.header(),
+			RegionsId:          newRegionIDs,
+			FinishedPercentage: uint64(finishedPercentage),
+		}, nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	finishedPercentage, newRegionIDs := s.cluster.GetRegionSplitter().SplitRegions(ctx, request.GetSplitKeys(), int(request.GetRetryLimit()))
-	return &pdpb.SplitRegionsResponse{
-		Header:             s.header(),
-		RegionsId:          newRegionIDs,
-		FinishedPercentage: uint64(finishedPercentage),
-	}, nil
+	return resp.(*pdpb.SplitRegionsResponse), nil
 }
 
 // GetDCLocationInfo gets the dc-location info of the given dc-location from PD leader's TSO allocator manager, and will collect current max
 // Local TSO if the NeedSyncMaxTSO flag in dc-location info is true.
 func (s *Server) GetDCLocationInfo(ctx context.Context, request *pdpb.GetDCLocationInfoRequest) (*pdpb.GetDCLocationInfoResponse, error) {
-	forwardedHost := getForwardedHost(ctx)
-	if !s.isLocalRequest(forwardedHost) {
-		client, err := s.getDelegateClient(ctx, forwardedHost)
-		if err != nil {
-			return nil, err
-		}
-		ctx = grpcutil.ResetForwardContext(ctx)
-		return pdpb.NewPDClient(client).GetDCLocationInfo(ctx, request)
-	}
-
 	var err error
 	if err = s.validateInternalRequest(request.GetHeader(), false); err != nil {
 		return nil, err
 	}
 	if !s.member.IsLeader() {
-		return nil, ErrNotLeader
+		return nil, s.notLeaderError()
 	}
 	am := s.tsoAllocatorManager
 	info, ok := am.GetDCLocationInfo(request.GetDcLocation())
@@ -1430,21 +1367,12 @@ func (s *Server) validateInternalRequest(header *pdpb.RequestHeader, onlyAllowLe
 	return nil
 }
 
-func (s *Server) getDelegateClient(ctx context.Context, forwardedHost string) (*grpc.ClientConn, error) {
-	client, ok := s.clientConns.Load(forwardedHost)
-	if !ok {
-		tlsConfig, err := s.GetTLSConfig().ToTLSConfig()
-		if err != nil {
-			return nil, err
-		}
-		cc, err := grpcutil.GetClientConn(ctx, forwardedHost, tlsConfig)
-		if err != nil {
-			return nil, err
-		}
-		client = cc
-		s.clientConns.Store(forwardedHost, cc)
+func (s *Server) dialDelegateClient(ctx context.Context, forwardedHost string) (*grpc.ClientConn, error) {
+	tlsConfig, err := s.GetTLSConfig().ToTLSConfig()
+	if err != nil {
+		return nil, err
 	}
-	return client.(*grpc.ClientConn), nil
+	return grpcutil.GetClientConn(ctx, forwardedHost, tlsConfig)
 }
 
 func getForwardedHost(ctx context.Context) string {
@@ -1468,6 +1396,15 @@ func (s *Server) isLocalRequest(forwardedHost string) bool {
 			return true
 		}
 	}
+	// The clients of a routing domain learn the urls of the domain from
+	// GetMembers, and forward the requests to them.
+	for _, urls := range s.routingURLs {
+		for _, addr := range urls {
+			if addr == forwardedHost {
+				return true
+			}
+		}
+	}
 	return false
 }
 
@@ -1484,7 +1421,7 @@ func (s *Server) createHeartbeatForwardStream(client *grpc.ClientConn, addr stri
 	done := make(chan struct{})
 	ctx, cancel := context.WithCancel(s.ctx)
 	go checkStream(ctx, cancel, done)
-	forwardStream, err := pdpb.NewPDClient(client).RegionHeartbeat(ctx)
+	forwardStream, err := pdpb.NewPDClient(client).RegionHeartbeat(ctx, s.forwardCallOptions("RegionHeartbeat")...)
 	done <- struct{}{}
 	return forwardStream, cancel, err
 }
@@ -15,10 +15,12 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
 	"net/http"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,12 +35,16 @@ import (
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/hothistory"
+	"github.com/tikv/pd/server/job"
+	"github.com/tikv/pd/server/ophistory"
 	"github.com/tikv/pd/server/schedule"
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/opt"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/schedulers"
 	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/topohistory"
 	"github.com/tikv/pd/server/tso"
 	"go.uber.org/zap"
 )
@@ -154,10 +160,32 @@ func (h *Handler) GetStores() ([]*core.StoreInfo, error) {
 	return stores, nil
 }
 
+// TombstoneCleanupJobType is the job type of removing tombstone records.
+const TombstoneCleanupJobType = "tombstone-cleanup"
+
+// RemoveTombStoneRecords removes the tombstone records as a job and waits for
+// the job to be done.
+func (h *Handler) RemoveTombStoneRecords(ctx context.Context) (*job.Job, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	j, err := h.s.jobManager.Submit(TombstoneCleanupJobType, func(context.Context, job.Reporter) error {
+		return rc.RemoveTombStoneRecords()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.s.jobManager.Wait(ctx, j.ID)
+}
+
 // GetHotWriteRegions gets all hot write regions stats.
 func (h *Handler) GetHotWriteRegions() *statistics.StoreHotPeersInfos {
 	c, err := h.GetRaftCluster()
 	if err != nil {
+		if snapshot := h.s.GetFollowerHotStats(); snapshot != nil {
+			return snapshot.WriteRegions
+		}
 		return nil
 	}
 	return c.GetHotWriteRegions()
@@ -167,6 +195,9 @@ func (h *Handler) GetHotWriteRegions() *statistics.StoreHotPeersInfos {
 func (h *Handler) GetHotReadRegions() *statistics.StoreHotPeersInfos {
 	c, err := h.GetRaftCluster()
 	if err != nil {
+		if snapshot := h.s.GetFollowerHotStats(); snapshot != nil {
+			return snapshot.ReadRegions
+		}
 		return nil
 	}
 	return c.GetHotReadRegions()
@@ -176,11 +207,43 @@ func (h *Handler) GetHotReadRegions() *statistics.StoreHotPeersInfos {
 func (h *Handler) GetStoresLoads() map[uint64][]float64 {
 	rc := h.s.GetRaftCluster()
 	if rc == nil {
+		if snapshot := h.s.GetFollowerHotStats(); snapshot != nil {
+			return snapshot.StoreLoads
+		}
 		return nil
 	}
 	return rc.GetStoresLoads()
 }
 
+// GetHotStatsSnapshot returns the hot-region statistics of the cluster, which
+// are replicated to the followers.
+func (h *Handler) GetHotStatsSnapshot() (*HotStatsSnapshot, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return &HotStatsSnapshot{
+		ReadRegions:  c.GetHotReadRegions(),
+		WriteRegions: c.GetHotWriteRegions(),
+		StoreLoads:   c.GetStoresLoads(),
+		UpdateTime:   time.Now(),
+	}, nil
+}
+
+// GetFollowerHotStats returns the hot-region statistics replicated from the
+// leader if the server is a follower. It returns ErrHotStatsUnavailable if the
+// follower has no statistics younger than the max staleness.
+func (h *Handler) GetFollowerHotStats() (*HotStatsSnapshot, error) {
+	if h.s.GetRaftCluster() != nil || h.s.IsLeader() {
+		return nil, nil
+	}
+	snapshot := h.s.GetFollowerHotStats()
+	if snapshot == nil {
+		return nil, errs.ErrHotStatsUnavailable.FastGenByArgs()
+	}
+	return snapshot, nil
+}
+
 // AddScheduler adds a scheduler.
 func (h *Handler) AddScheduler(name string, args ...string) error {
 	c, err := h.GetRaftCluster()
@@ -201,6 +264,27 @@ func (h *Handler) AddScheduler(name string, args ...string) error {
 	return err
 }
 
+// DryRunScheduler runs a scheduler once without adding the operators it
+// generates. The name is either a running scheduler, or the type of a new
+// scheduler which is created with args.
+func (h *Handler) DryRunScheduler(name string, args ...string) ([]*operator.Operator, bool, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, false, err
+	}
+	return c.DryRunScheduler(name, args...)
+}
+
+// EstimateOperatorRates estimates the operators generated by the schedulers
+// and the checkers in the next hour.
+func (h *Handler) EstimateOperatorRates() (*cluster.OperatorRateEstimates, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return c.EstimateOperatorRates()
+}
+
 // RemoveScheduler removes a scheduler by name.
 func (h *Handler) RemoveScheduler(name string) error {
 	c, err := h.GetRaftCluster()
@@ -261,9 +345,11 @@ func (h *Handler) AddGrantLeaderScheduler(storeID uint64) error {
 	return h.AddScheduler(schedulers.GrantLeaderType, strconv.FormatUint(storeID, 10))
 }
 
-// AddEvictLeaderScheduler adds an evict-leader-scheduler.
-func (h *Handler) AddEvictLeaderScheduler(storeID uint64) error {
-	return h.AddScheduler(schedulers.EvictLeaderType, strconv.FormatUint(storeID, 10))
+// AddEvictLeaderScheduler adds an evict-leader-scheduler. The ranges are the
+// escaped start and end keys of the key ranges to evict leaders from, and all
+// leaders of the store are evicted if no range is given.
+func (h *Handler) AddEvictLeaderScheduler(storeID uint64, ranges ...string) error {
+	return h.AddScheduler(schedulers.EvictLeaderType, append([]string{strconv.FormatUint(storeID, 10)}, ranges...)...)
 }
 
 // AddShuffleLeaderScheduler adds a shuffle-leader-scheduler.
@@ -301,6 +387,49 @@ func (h *Handler) GetOperator(regionID uint64) (*operator.Operator, error) {
 	return op, nil
 }
 
+// GetOperatorHistory returns at most limit records of the operators in
+// [start, end). If regionID is not 0, only the records of the region are
+// returned.
+func (h *Handler) GetOperatorHistory(regionID uint64, start, end time.Time, limit int) ([]*ophistory.Record, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	history := c.GetOperatorHistory()
+	if history == nil {
+		return nil, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	return history.Query(regionID, start, end, limit)
+}
+
+// GetHotRegionHistory returns at most limit records of the hot peers
+// persisted in [start, end), whose regions overlap [startKey, endKey).
+func (h *Handler) GetHotRegionHistory(start, end time.Time, startKey, endKey []byte, hotType string, limit int) ([]*hothistory.Record, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	history := c.GetHotRegionHistory()
+	if history == nil {
+		return nil, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	return history.Query(start, end, startKey, endKey, hotType, limit)
+}
+
+// GetRegionTopology returns the leader and the peers of the region in the
+// latest snapshot of the region topology taken at or before the time.
+func (h *Handler) GetRegionTopology(regionID uint64, t time.Time) (*topohistory.Record, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	history := c.GetRegionTopologyHistory()
+	if history == nil {
+		return nil, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	return history.Query(regionID, t)
+}
+
 // GetOperatorStatus returns the status of the region operator.
 func (h *Handler) GetOperatorStatus(regionID uint64) (*schedule.OperatorWithStatus, error) {
 	c, err := h.GetOperatorController()
@@ -332,6 +461,88 @@ func (h *Handler) RemoveOperator(regionID uint64) error {
 	return nil
 }
 
+// OperatorFilter selects the operators to cancel in bulk. The zero value of a
+// field matches all operators.
+type OperatorFilter struct {
+	// Desc is the description of the operator, such as "balance-region".
+	Desc   string
+	Source string
+	// Kind matches the operators which have any of the kinds.
+	Kind operator.OpKind
+	// StartKey and EndKey match the operators whose regions overlap the range.
+	StartKey, EndKey []byte
+	TargetStoreID    uint64
+}
+
+// IsEmpty returns true if the filter matches all operators.
+func (f *OperatorFilter) IsEmpty() bool {
+	return f.Desc == "" && f.Source == "" && f.Kind == 0 && len(f.StartKey) == 0 && len(f.EndKey) == 0 && f.TargetStoreID == 0
+}
+
+func (f *OperatorFilter) match(op *operator.Operator, region *core.RegionInfo) bool {
+	if f.Desc != "" && op.Desc() != f.Desc {
+		return false
+	}
+	if f.Source != "" && op.Source() != f.Source {
+		return false
+	}
+	if f.Kind != 0 && op.Kind()&f.Kind == 0 {
+		return false
+	}
+	if len(f.StartKey) > 0 || len(f.EndKey) > 0 {
+		if region == nil || !rangesOverlap(region.GetStartKey(), region.GetEndKey(), f.StartKey, f.EndKey) {
+			return false
+		}
+	}
+	if f.TargetStoreID != 0 {
+		for _, storeID := range op.TargetStores() {
+			if storeID == f.TargetStoreID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// RemoveOperators removes the running and the waiting operators matched by the
+// filter, and returns the removed operators. The two operators of a waiting
+// merge operation are removed together. If dryRun is true, the matched
+// operators are returned without being removed.
+func (h *Handler) RemoveOperators(filter *OperatorFilter, dryRun bool) ([]*operator.Operator, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	c := rc.GetOperatorController()
+	var removed []*operator.Operator
+	for _, op := range c.GetOperators() {
+		if !filter.match(op, rc.GetRegion(op.RegionID())) {
+			continue
+		}
+		if dryRun || c.RemoveOperator(op) {
+			removed = append(removed, op)
+		}
+	}
+	// The other operator of a merge operation may be removed already.
+	waitingRemoved := make(map[*operator.Operator]struct{})
+	for _, op := range c.GetWaitingOperators() {
+		if _, ok := waitingRemoved[op]; ok || !filter.match(op, rc.GetRegion(op.RegionID())) {
+			continue
+		}
+		if dryRun {
+			removed = append(removed, op)
+			continue
+		}
+		for _, op := range c.RemoveWaitingOperator(op) {
+			waitingRemoved[op] = struct{}{}
+			removed = append(removed, op)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].RegionID() < removed[j].RegionID() })
+	return removed, nil
+}
+
 // GetOperators returns the running operators.
 func (h *Handler) GetOperators() ([]*operator.Operator, error) {
 	c, err := h.GetOperatorController()
@@ -389,6 +600,15 @@ func (h *Handler) GetHistory(start time.Time) ([]operator.OpHistory, error) {
 	return c.GetHistory(start), nil
 }
 
+// GetOperatorConflictGraph returns why the waiting operators are blocked.
+func (h *Handler) GetOperatorConflictGraph() (*schedule.ConflictGraph, error) {
+	c, err := h.GetOperatorController()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetConflictGraph(), nil
+}
+
 // SetAllStoresLimit is used to set limit of all stores.
 func (h *Handler) SetAllStoresLimit(ratePerMin float64, limitType storelimit.Type) error {
 	c, err := h.GetRaftCluster()
@@ -399,12 +619,12 @@ func (h *Handler) SetAllStoresLimit(ratePerMin float64, limitType storelimit.Typ
 }
 
 // SetAllStoresLimitTTL is used to set limit of all stores with ttl
-func (h *Handler) SetAllStoresLimitTTL(ratePerMin float64, limitType storelimit.Type, ttl time.Duration) error {
+func (h *Handler) SetAllStoresLimitTTL(ratePerMin float64, limitType storelimit.Type, ttl time.Duration, origin string) error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
 	}
-	c.SetAllStoresLimitTTL(limitType, ratePerMin, ttl)
+	c.SetAllStoresLimitTTL(limitType, ratePerMin, ttl, origin)
 	return nil
 }
 
@@ -897,8 +1117,8 @@ func (h *Handler) GetAddr() string {
 }
 
 // SetStoreLimitTTL set storeLimit with ttl
-func (h *Handler) SetStoreLimitTTL(data string, value float64, ttl time.Duration) error {
+func (h *Handler) SetStoreLimitTTL(data string, value float64, ttl time.Duration, origin string) error {
 	return h.s.SaveTTLConfig(map[string]interface{}{
 		data: value,
-	}, ttl)
+	}, ttl, origin)
 }
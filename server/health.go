@@ -0,0 +1,286 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/server/id"
+	"github.com/tikv/pd/server/tso"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	healthCheckInterval = 500 * time.Millisecond
+	// dependencyCheckInterval is the interval to probe etcd and the storage.
+	dependencyCheckInterval = 5 * time.Second
+	dependencyCheckTimeout  = 3 * time.Second
+	// slowStorageReadThreshold is the read latency above which the storage
+	// is reported unhealthy.
+	slowStorageReadThreshold = time.Second
+)
+
+// healthService is the health service of PD, which is the same as
+// grpc.health.v1.Health except for its name. It is not checked, so that the
+// status of a member can always be queried.
+var healthService = sideService{
+	name: grpcutil.HealthServiceName,
+	methods: []sideMethod{
+		{
+			name:       "Check",
+			newRequest: func() interface{} { return new(healthpb.HealthCheckRequest) },
+			newReply:   func() interface{} { return new(healthpb.HealthCheckResponse) },
+			handle: func(s *Server, ctx context.Context, req interface{}) (interface{}, error) {
+				return s.healthServer.Check(ctx, req.(*healthpb.HealthCheckRequest))
+			},
+		},
+	},
+	streams: []sideStream{
+		{
+			name:       "Watch",
+			newRequest: func() interface{} { return new(healthpb.HealthCheckRequest) },
+			handle: func(s *Server, req interface{}, stream grpc.ServerStream) error {
+				return s.healthServer.Watch(req.(*healthpb.HealthCheckRequest), &healthWatchServer{stream})
+			},
+		},
+	},
+}
+
+type healthWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *healthWatchServer) Send(m *healthpb.HealthCheckResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// healthCheckLoop keeps the statuses of the health service up to date.
+func (s *Server) healthCheckLoop() {
+	defer logutil.LogPanic()
+	defer s.serverLoopWg.Done()
+
+	ctx, cancel := context.WithCancel(s.serverLoopCtx)
+	defer cancel()
+	for {
+		s.updateHealthStatus()
+		select {
+		case <-time.After(healthCheckInterval):
+		case <-ctx.Done():
+			log.Info("server is closed, exit health check loop")
+			return
+		}
+	}
+}
+
+func (s *Server) updateHealthStatus() {
+	isLeader := !s.IsClosed() && s.member.IsLeader()
+	bootstrapped := isLeader && s.GetRaftCluster() != nil
+	tsoInitialized := false
+	if allocator, err := s.tsoAllocatorManager.GetAllocator(tso.GlobalDCLocation); err == nil {
+		tsoInitialized = allocator.IsInitialize()
+		if gta, ok := allocator.(*tso.GlobalTSOAllocator); ok && isLeader && tsoInitialized {
+			s.dependencies.update(grpcutil.HealthServiceTSOPersistence, gta.GetLastSaveError(), 0)
+		}
+	}
+	if !isLeader {
+		s.dependencies.reset(grpcutil.HealthServiceIDAllocator)
+	}
+	if !isLeader || !tsoInitialized {
+		s.dependencies.reset(grpcutil.HealthServiceTSOPersistence)
+	}
+	s.setHealthStatus(grpcutil.HealthServiceLeader, isLeader)
+	s.setHealthStatus(grpcutil.HealthServiceBootstrapped, bootstrapped)
+	s.setHealthStatus(grpcutil.HealthServiceTSO, tsoInitialized)
+	s.setHealthStatus(grpcutil.HealthServiceReady, isLeader && bootstrapped && tsoInitialized)
+	for _, dependency := range s.dependencies.get() {
+		status := healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		switch dependency.Status {
+		case DependencyHealthy:
+			status = healthpb.HealthCheckResponse_SERVING
+			dependencyHealthGauge.WithLabelValues(dependency.Name).Set(1)
+		case DependencyUnhealthy:
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			dependencyHealthGauge.WithLabelValues(dependency.Name).Set(0)
+		default:
+			dependencyHealthGauge.DeleteLabelValues(dependency.Name)
+		}
+		s.healthServer.SetServingStatus(dependency.Name, status)
+	}
+}
+
+func (s *Server) setHealthStatus(service string, serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	s.healthServer.SetServingStatus(service, status)
+}
+
+// dependencyCheckLoop probes etcd and the storage periodically. The
+// allocators are checked by their own results instead, as probing them has
+// side effects.
+func (s *Server) dependencyCheckLoop() {
+	defer logutil.LogPanic()
+	defer s.serverLoopWg.Done()
+
+	ctx, cancel := context.WithCancel(s.serverLoopCtx)
+	defer cancel()
+	for {
+		s.checkEtcd(ctx)
+		s.checkStorage()
+		select {
+		case <-time.After(dependencyCheckInterval):
+		case <-ctx.Done():
+			log.Info("server is closed, exit dependency check loop")
+			return
+		}
+	}
+}
+
+func (s *Server) checkEtcd(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	// The read is linearizable, which needs the quorum of etcd.
+	_, err := s.client.Get(ctx, pdClusterIDPath)
+	if err != nil {
+		err = errs.ErrEtcdKVGet.Wrap(err).GenWithStackByCause()
+	}
+	s.dependencies.update(grpcutil.HealthServiceEtcd, err, time.Since(start))
+}
+
+// checkStorage probes the storage with a read of the cluster meta, so that the
+// probes of all the members do not add writes to the storage.
+func (s *Server) checkStorage() {
+	start := time.Now()
+	_, err := s.storage.LoadMeta(&metapb.Cluster{})
+	latency := time.Since(start)
+	if err == nil && latency > slowStorageReadThreshold {
+		err = errs.ErrSlowStorageRead.FastGenByArgs(latency, slowStorageReadThreshold)
+	}
+	s.dependencies.update(grpcutil.HealthServiceStorage, err, latency)
+}
+
+// GetDependencyHealth returns the health of the dependencies of the server.
+func (s *Server) GetDependencyHealth() []DependencyStatus {
+	return s.dependencies.get()
+}
+
+// The statuses of a dependency.
+const (
+	DependencyHealthy   = "healthy"
+	DependencyUnhealthy = "unhealthy"
+	// DependencyUnknown means the dependency is not checked yet, or not used
+	// by the server, such as the allocators on followers.
+	DependencyUnknown = "unknown"
+)
+
+// DependencyStatus is the health of a dependency of the server.
+type DependencyStatus struct {
+	Name          string    `json:"name"`
+	Status        string    `json:"status"`
+	LastCheckTime time.Time `json:"last_check_time"`
+	// Latency is the latency of the last probe in milliseconds, it is only
+	// reported for etcd and the storage.
+	Latency float64 `json:"latency_ms,omitempty"`
+	// LastError is kept after the dependency recovers.
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+type dependencyHealth struct {
+	sync.RWMutex
+	statuses map[string]*DependencyStatus
+}
+
+func newDependencyHealth() *dependencyHealth {
+	h := &dependencyHealth{statuses: make(map[string]*DependencyStatus)}
+	for _, name := range []string{
+		grpcutil.HealthServiceEtcd,
+		grpcutil.HealthServiceStorage,
+		grpcutil.HealthServiceIDAllocator,
+		grpcutil.HealthServiceTSOPersistence,
+	} {
+		h.statuses[name] = &DependencyStatus{Name: name, Status: DependencyUnknown}
+	}
+	return h
+}
+
+func (h *dependencyHealth) update(name string, err error, latency time.Duration) {
+	now := time.Now()
+	h.Lock()
+	defer h.Unlock()
+	status := h.statuses[name]
+	status.LastCheckTime = now
+	status.Latency = float64(latency) / float64(time.Millisecond)
+	if err == nil {
+		status.Status = DependencyHealthy
+		return
+	}
+	status.Status = DependencyUnhealthy
+	status.LastError = err.Error()
+	status.LastErrorTime = &now
+}
+
+func (h *dependencyHealth) reset(name string) {
+	h.Lock()
+	defer h.Unlock()
+	h.statuses[name].Status = DependencyUnknown
+}
+
+func (h *dependencyHealth) get() []DependencyStatus {
+	h.RLock()
+	defer h.RUnlock()
+	statuses := make([]DependencyStatus, 0, len(h.statuses))
+	for _, status := range h.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// healthReportingAllocator reports the results of the ID allocator to the
+// dependency health. Only the rebase touches etcd, but every result is
+// reported to keep the check time fresh.
+type healthReportingAllocator struct {
+	id.Allocator
+	dependencies *dependencyHealth
+}
+
+func (a *healthReportingAllocator) Alloc() (uint64, error) {
+	allocated, err := a.Allocator.Alloc()
+	a.dependencies.update(grpcutil.HealthServiceIDAllocator, err, 0)
+	return allocated, err
+}
+
+func (a *healthReportingAllocator) AllocRange(count uint64) (uint64, error) {
+	allocated, err := a.Allocator.AllocRange(count)
+	a.dependencies.update(grpcutil.HealthServiceIDAllocator, err, 0)
+	return allocated, err
+}
+
+func (a *healthReportingAllocator) Rebase() error {
+	err := a.Allocator.Rebase()
+	a.dependencies.update(grpcutil.HealthServiceIDAllocator, err, 0)
+	return err
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/server/statistics"
+	"go.uber.org/zap"
+)
+
+const (
+	// hotStatsSnapshotURL is the API which returns the hot-region statistics
+	// of the leader.
+	hotStatsSnapshotURL = "/pd/api/v1/hotspot/snapshot"
+	// hotStatsSyncCheckInterval is the interval to check the config again if
+	// the replication is disabled.
+	hotStatsSyncCheckInterval = 10 * time.Second
+)
+
+// HotStatsSnapshot is the hot-region statistics of the cluster at a time.
+type HotStatsSnapshot struct {
+	ReadRegions  *statistics.StoreHotPeersInfos `json:"read_regions"`
+	WriteRegions *statistics.StoreHotPeersInfos `json:"write_regions"`
+	StoreLoads   map[uint64][]float64           `json:"store_loads"`
+	UpdateTime   time.Time                      `json:"update_time"`
+}
+
+type syncedHotStats struct {
+	snapshot *HotStatsSnapshot
+	syncTime time.Time
+}
+
+// hotStatsSyncer replicates the hot-region statistics of the leader to a
+// follower periodically, so that the follower can serve the hot-region read
+// APIs without bothering the leader for each request.
+type hotStatsSyncer struct {
+	s      *Server
+	synced atomic.Value // Store as *syncedHotStats
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func newHotStatsSyncer(s *Server) *hotStatsSyncer {
+	return &hotStatsSyncer{s: s}
+}
+
+// startSyncWithLeader starts to replicate the statistics from the leader.
+func (h *hotStatsSyncer) startSyncWithLeader(ctx context.Context, addr string) {
+	ctx, h.cancel = context.WithCancel(ctx)
+	h.wg.Add(1)
+	go h.syncLoop(ctx, addr)
+}
+
+// stopSyncWithLeader stops the replication. The replicated statistics are kept
+// until they are stale.
+func (h *hotStatsSyncer) stopSyncWithLeader() {
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	h.wg.Wait()
+	h.cancel = nil
+}
+
+func (h *hotStatsSyncer) syncLoop(ctx context.Context, addr string) {
+	defer logutil.LogPanic()
+	defer h.wg.Done()
+
+	for {
+		interval := h.s.persistOptions.GetHotStatsSyncInterval()
+		if interval > 0 {
+			if err := h.syncWithLeader(ctx, addr); err != nil {
+				log.Warn("failed to sync hot statistics with leader", zap.String("leader", addr), errs.ZapError(err))
+			}
+		} else {
+			interval = hotStatsSyncCheckInterval
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (h *hotStatsSyncer) syncWithLeader(ctx context.Context, addr string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+hotStatsSnapshotURL, nil)
+	if err != nil {
+		return errs.ErrSendRequest.Wrap(err).GenWithStackByCause()
+	}
+	resp, err := h.s.httpClient.Do(req)
+	if err != nil {
+		return errs.ErrSendRequest.Wrap(err).GenWithStackByCause()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errs.ErrSendRequest.FastGenByArgs()
+	}
+	snapshot := &HotStatsSnapshot{}
+	if err := json.NewDecoder(resp.Body).Decode(snapshot); err != nil {
+		return errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	h.synced.Store(&syncedHotStats{snapshot: snapshot, syncTime: time.Now()})
+	return nil
+}
+
+// getSnapshot returns the replicated statistics, or nil if there are none or
+// they are older than the max staleness.
+func (h *hotStatsSyncer) getSnapshot() *HotStatsSnapshot {
+	synced, _ := h.synced.Load().(*syncedHotStats)
+	if synced == nil || time.Since(synced.syncTime) > h.s.persistOptions.GetHotStatsMaxStaleness() {
+		return nil
+	}
+	return synced.snapshot
+}
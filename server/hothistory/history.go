@@ -0,0 +1,190 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hothistory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/kv"
+	"github.com/tikv/pd/server/statistics"
+	"go.uber.org/zap"
+)
+
+const (
+	historyPath = "hot_region"
+	// minWriteInterval bounds the interval to persist the hot peers, in case
+	// it is set to a tiny value by mistake.
+	minWriteInterval = time.Second
+)
+
+// The types of the hot regions.
+const (
+	ReadType  = "read"
+	WriteType = "write"
+)
+
+// Record is a hot peer at the time it is persisted.
+type Record struct {
+	UpdateTime    time.Time `json:"update_time"`
+	RegionID      uint64    `json:"region_id"`
+	StoreID       uint64    `json:"store_id"`
+	PeerID        uint64    `json:"peer_id"`
+	IsLeader      bool      `json:"is_leader"`
+	HotRegionType string    `json:"hot_region_type"`
+	HotDegree     int       `json:"hot_degree"`
+	FlowBytes     float64   `json:"flow_bytes"`
+	KeyRate       float64   `json:"flow_keys"`
+	// StartKey and EndKey are the hex encoded keys of the region.
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+}
+
+// Cluster provides the hot peers to persist.
+type Cluster interface {
+	RegionReadStats() map[uint64][]*statistics.HotPeerStat
+	RegionWriteStats() map[uint64][]*statistics.HotPeerStat
+	GetRegion(regionID uint64) *core.RegionInfo
+}
+
+// Options are the options of the hot region history.
+type Options interface {
+	GetHotRegionsWriteInterval() time.Duration
+	GetHotRegionsReservedDays() uint64
+}
+
+// Store persists the hot peers periodically, and keeps them for the reserved
+// days, so that the hot regions in the past can be queried. Only the rolling
+// window of the hot peers is kept in memory otherwise.
+type Store struct {
+	ctx     context.Context
+	log     *kv.TimeLog
+	cluster Cluster
+	opt     Options
+}
+
+// NewStore creates a Store and starts persisting the hot peers in the
+// background until the context is done.
+func NewStore(ctx context.Context, storage kv.Base, cluster Cluster, opt Options) *Store {
+	s := &Store{
+		ctx:     ctx,
+		log:     kv.NewTimeLog(storage, historyPath),
+		cluster: cluster,
+		opt:     opt,
+	}
+	go s.run()
+	return s
+}
+
+func (s *Store) run() {
+	for {
+		interval := s.opt.GetHotRegionsWriteInterval()
+		if interval < minWriteInterval {
+			interval = minWriteInterval
+		}
+		select {
+		case <-time.After(interval):
+			if days := s.opt.GetHotRegionsReservedDays(); days > 0 {
+				now := time.Now()
+				s.save(now)
+				s.gc(now.Add(-time.Duration(days) * 24 * time.Hour))
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// save persists the current hot peers as the snapshot at the time.
+func (s *Store) save(now time.Time) {
+	for hotType, stats := range map[string]map[uint64][]*statistics.HotPeerStat{
+		ReadType:  s.cluster.RegionReadStats(),
+		WriteType: s.cluster.RegionWriteStats(),
+	} {
+		for _, peers := range stats {
+			for _, peer := range peers {
+				region := s.cluster.GetRegion(peer.RegionID)
+				if region == nil {
+					continue
+				}
+				r := &Record{
+					UpdateTime:    now,
+					RegionID:      peer.RegionID,
+					StoreID:       peer.StoreID,
+					PeerID:        region.GetStorePeer(peer.StoreID).GetId(),
+					IsLeader:      peer.IsLeader(),
+					HotRegionType: hotType,
+					HotDegree:     peer.HotDegree,
+					FlowBytes:     peer.GetByteRate(),
+					KeyRate:       peer.GetKeyRate(),
+					StartKey:      core.HexRegionKeyStr(region.GetStartKey()),
+					EndKey:        core.HexRegionKeyStr(region.GetEndKey()),
+				}
+				if err := s.saveRecord(r); err != nil {
+					log.Warn("failed to save hot region record", zap.Uint64("region-id", r.RegionID), errs.ZapError(err))
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Store) saveRecord(r *Record) error {
+	value, err := json.Marshal(r)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	suffix := fmt.Sprintf("-%s-%020d-%020d", r.HotRegionType, r.RegionID, r.StoreID)
+	return s.log.Save(r.UpdateTime, suffix, string(value))
+}
+
+// gc removes the records persisted before the time.
+func (s *Store) gc(before time.Time) {
+	if err := s.log.GC(before); err != nil {
+		log.Warn("failed to remove hot region records", errs.ZapError(err))
+	}
+}
+
+// Query returns at most limit records persisted in [start, end) ordered by
+// time, whose regions overlap the key range [startKey, endKey). An empty
+// endKey means the end of the key space, and an empty hotType means both the
+// read and write ones. If limit is not positive, all the records are returned.
+func (s *Store) Query(start, end time.Time, startKey, endKey []byte, hotType string, limit int) ([]*Record, error) {
+	records := []*Record{}
+	startHex, endHex := core.HexRegionKeyStr(startKey), core.HexRegionKeyStr(endKey)
+	err := s.log.Range(start, end, func(value string) (bool, error) {
+		r := &Record{}
+		if err := json.Unmarshal([]byte(value), r); err != nil {
+			return false, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+		}
+		if hotType != "" && r.HotRegionType != hotType {
+			return true, nil
+		}
+		// The hex encoding keeps the order of the keys.
+		if (endHex != "" && r.StartKey >= endHex) || (r.EndKey != "" && r.EndKey <= startHex) {
+			return true, nil
+		}
+		records = append(records, r)
+		return limit <= 0 || len(records) < limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hothistory
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/kv"
+	"github.com/tikv/pd/server/statistics"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testHistorySuite{})
+
+type testHistorySuite struct{}
+
+type mockCluster struct {
+	regions     map[uint64]*core.RegionInfo
+	read, write map[uint64][]*statistics.HotPeerStat
+}
+
+func (m *mockCluster) RegionReadStats() map[uint64][]*statistics.HotPeerStat {
+	return m.read
+}
+
+func (m *mockCluster) RegionWriteStats() map[uint64][]*statistics.HotPeerStat {
+	return m.write
+}
+
+func (m *mockCluster) GetRegion(regionID uint64) *core.RegionInfo {
+	return m.regions[regionID]
+}
+
+func (s *testHistorySuite) TestSaveAndQuery(c *C) {
+	cluster := &mockCluster{regions: make(map[uint64]*core.RegionInfo)}
+	// Region 1 is [a, b), region 2 is [b, c) and region 3 is [c, "").
+	for i, keys := range [][2]string{{"a", "b"}, {"b", "c"}, {"c", ""}} {
+		id := uint64(i + 1)
+		meta := &metapb.Region{
+			Id:       id,
+			StartKey: []byte(keys[0]),
+			EndKey:   []byte(keys[1]),
+			Peers:    []*metapb.Peer{{Id: id * 10, StoreId: 1}, {Id: id*10 + 1, StoreId: 2}},
+		}
+		cluster.regions[id] = core.NewRegionInfo(meta, meta.Peers[0])
+	}
+	cluster.read = map[uint64][]*statistics.HotPeerStat{
+		1: {{StoreID: 1, RegionID: 1, HotDegree: 3, ByteRate: 100, KeyRate: 10}},
+	}
+	cluster.write = map[uint64][]*statistics.HotPeerStat{
+		2: {{StoreID: 2, RegionID: 2, HotDegree: 4, ByteRate: 200}, {StoreID: 2, RegionID: 3}},
+	}
+	store := &Store{log: kv.NewTimeLog(kv.NewMemoryKV(), historyPath), cluster: cluster}
+	t1 := time.Unix(1000, 0)
+	store.save(t1)
+	delete(cluster.write, 2)
+	store.save(t1.Add(time.Minute))
+
+	records, err := store.Query(time.Time{}, time.Time{}, nil, nil, "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 4)
+	records, err = store.Query(t1, t1.Add(time.Second), nil, nil, WriteType, 0)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].RegionID, Equals, uint64(2))
+	c.Assert(records[0].StoreID, Equals, uint64(2))
+	c.Assert(records[0].PeerID, Equals, uint64(21))
+	c.Assert(records[0].HotDegree, Equals, 4)
+	c.Assert(records[0].FlowBytes, Equals, 200.0)
+	c.Assert(records[0].StartKey, Equals, core.HexRegionKeyStr([]byte("b")))
+	c.Assert(records[0].UpdateTime.Equal(t1), IsTrue)
+
+	// The regions overlapping [b, c) and [bb, "") respectively.
+	records, err = store.Query(time.Time{}, time.Time{}, []byte("b"), []byte("c"), "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].RegionID, Equals, uint64(2))
+	records, err = store.Query(time.Time{}, time.Time{}, []byte("bb"), nil, "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+	records, err = store.Query(t1.Add(time.Minute), time.Time{}, nil, nil, ReadType, 1)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].RegionID, Equals, uint64(1))
+
+	store.gc(t1.Add(time.Second))
+	records, err = store.Query(time.Time{}, time.Time{}, nil, nil, "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 1)
+}
@@ -30,6 +30,8 @@ import (
 type Allocator interface {
 	// Alloc allocs a unique id.
 	Alloc() (uint64, error)
+	// AllocRange allocs count contiguous unique ids and returns the first one.
+	AllocRange(count uint64) (uint64, error)
 	// Rebase resets the base for the allocator from the persistent window boundary,
 	// which also resets the end of the allocator. (base, end) is the range that can
 	// be allocated in memory.
@@ -60,7 +62,7 @@ func (alloc *allocatorImpl) Alloc() (uint64, error) {
 	defer alloc.mu.Unlock()
 
 	if alloc.base == alloc.end {
-		if err := alloc.rebaseLocked(); err != nil {
+		if err := alloc.rebaseLocked(allocStep); err != nil {
 			return 0, err
 		}
 	}
@@ -70,6 +72,29 @@ func (alloc *allocatorImpl) Alloc() (uint64, error) {
 	return alloc.base, nil
 }
 
+// AllocRange allocs count contiguous ids and returns the first one. If the
+// ids left in memory are not enough, they are skipped and a new window which
+// is large enough is persisted.
+func (alloc *allocatorImpl) AllocRange(count uint64) (uint64, error) {
+	alloc.mu.Lock()
+	defer alloc.mu.Unlock()
+
+	if alloc.end-alloc.base < count {
+		step := allocStep
+		if count > step {
+			step = count
+		}
+		if err := alloc.rebaseLocked(step); err != nil {
+			return 0, err
+		}
+	}
+
+	start := alloc.base + 1
+	alloc.base += count
+
+	return start, nil
+}
+
 // Rebase resets the base for the allocator from the persistent window boundary,
 // which also resets the end of the allocator. (base, end) is the range that can
 // be allocated in memory.
@@ -77,10 +102,10 @@ func (alloc *allocatorImpl) Rebase() error {
 	alloc.mu.Lock()
 	defer alloc.mu.Unlock()
 
-	return alloc.rebaseLocked()
+	return alloc.rebaseLocked(allocStep)
 }
 
-func (alloc *allocatorImpl) rebaseLocked() error {
+func (alloc *allocatorImpl) rebaseLocked(step uint64) error {
 	key := alloc.getAllocIDPath()
 	value, err := etcdutil.GetValue(alloc.client, key)
 	if err != nil {
@@ -105,7 +130,7 @@ func (alloc *allocatorImpl) rebaseLocked() error {
 		cmp = clientv3.Compare(clientv3.Value(key), "=", string(value))
 	}
 
-	end += allocStep
+	end += step
 	value = typeutil.Uint64ToBytes(end)
 	txn := kv.NewSlowLogTxn(alloc.client)
 	leaderPath := path.Join(alloc.rootPath, "leader")
@@ -121,7 +146,7 @@ func (alloc *allocatorImpl) rebaseLocked() error {
 	log.Info("idAllocator allocates a new id", zap.Uint64("alloc-id", end))
 	idGauge.WithLabelValues("idalloc").Set(float64(end))
 	alloc.end = end
-	alloc.base = end - allocStep
+	alloc.base = end - step
 	return nil
 }
 
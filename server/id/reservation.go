@@ -0,0 +1,72 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/etcdutil"
+	"go.etcd.io/etcd/clientv3"
+)
+
+// Reservation is a range of ids leased to a client, which hands them out by
+// itself until the lease expires. The ids in [Start, End) are never allocated
+// again, even if the client dies before the lease expires.
+type Reservation struct {
+	Start      uint64    `json:"start"`
+	End        uint64    `json:"end"`
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+func reservationPath(rootPath string) string {
+	return path.Join(rootPath, "id_reservation")
+}
+
+// SaveReservation records the reservation in etcd until its lease expires.
+func SaveReservation(ctx context.Context, client *clientv3.Client, rootPath string, r *Reservation, ttl time.Duration) error {
+	value, err := json.Marshal(r)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	key := path.Join(reservationPath(rootPath), fmt.Sprintf("%020d", r.Start))
+	ttlSeconds := int64(math.Ceil(ttl.Seconds()))
+	if _, err := etcdutil.EtcdKVPutWithTTL(ctx, client, key, string(value), ttlSeconds); err != nil {
+		return errs.ErrEtcdKVPut.Wrap(err).GenWithStackByCause()
+	}
+	return nil
+}
+
+// LoadReservations returns the reservations whose leases have not expired,
+// in the order of their ids.
+func LoadReservations(client *clientv3.Client, rootPath string) ([]*Reservation, error) {
+	resp, err := etcdutil.EtcdKVGet(client, reservationPath(rootPath)+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	reservations := make([]*Reservation, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		r := &Reservation{}
+		if err := json.Unmarshal(kv.Value, r); err != nil {
+			return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+		}
+		reservations = append(reservations, r)
+	}
+	return reservations, nil
+}
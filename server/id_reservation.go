@@ -0,0 +1,95 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/server/id"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxIDRangeCount = 1000000
+	maxIDRangeTTL   = 24 * time.Hour
+)
+
+// idReservationService leases a range of ids to the client. The count and ttl
+// of the range are carried by the metadata of the request.
+var idReservationService = sideService{
+	name:   grpcutil.IDReservationServiceName,
+	checks: defaultGRPCChecks,
+	methods: []sideMethod{
+		{
+			name:       grpcutil.AllocIDRangeMethodName,
+			newRequest: func() interface{} { return new(pdpb.AllocIDRequest) },
+			newReply:   func() interface{} { return new(pdpb.AllocIDResponse) },
+			handle: func(s *Server, ctx context.Context, req interface{}) (interface{}, error) {
+				return s.AllocIDRange(ctx, req.(*pdpb.AllocIDRequest))
+			},
+		},
+	},
+}
+
+// AllocIDRange allocates a contiguous range of ids and leases it to the
+// client, which can hand the ids out by itself until the lease expires. The
+// range is recorded until then, and is never allocated again.
+func (s *Server) AllocIDRange(ctx context.Context, request *pdpb.AllocIDRequest) (*pdpb.AllocIDResponse, error) {
+	count, ttl, ok := grpcutil.GetIDRange(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "the count and ttl of the id range are required")
+	}
+	if count > maxIDRangeCount {
+		return nil, status.Errorf(codes.InvalidArgument, "the count of the id range should not be greater than %d", maxIDRangeCount)
+	}
+	if ttl > maxIDRangeTTL {
+		return nil, status.Errorf(codes.InvalidArgument, "the ttl of the id range should not be greater than %s", maxIDRangeTTL)
+	}
+	if rc := s.GetRaftCluster(); rc != nil {
+		if err := rc.CheckRecoverVerified(); err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, err.Error())
+		}
+	}
+
+	start, err := s.idAllocator.AllocRange(count)
+	if err != nil {
+		return nil, status.Errorf(codes.Unknown, err.Error())
+	}
+	reservation := &id.Reservation{
+		Start:      start,
+		End:        start + count,
+		ExpireTime: time.Now().Add(ttl),
+	}
+	if err := id.SaveReservation(ctx, s.client, s.rootPath, reservation, ttl); err != nil {
+		// The ids are allocated anyway, so only the record is missing.
+		log.Warn("failed to record the id reservation", zap.Uint64("start", start), zap.Uint64("count", count), errs.ZapError(err))
+	}
+
+	return &pdpb.AllocIDResponse{
+		Header: s.header(),
+		Id:     start,
+	}, nil
+}
+
+// GetIDReservations returns the id ranges whose leases have not expired.
+func (s *Server) GetIDReservations() ([]*id.Reservation, error) {
+	return id.LoadReservations(s.client, s.rootPath)
+}
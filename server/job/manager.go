@@ -0,0 +1,320 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/id"
+	"github.com/tikv/pd/server/kv"
+	"go.etcd.io/etcd/clientv3"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultTTL is how long a job is kept after it is done.
+	DefaultTTL = 24 * time.Hour
+
+	jobPath      = "jobs"
+	loadJobLimit = 1000
+)
+
+// State is the state of a job.
+type State string
+
+// The states of a job. A job starts running as soon as it is submitted, and
+// ends in one of the done states.
+const (
+	StateRunning   State = "running"
+	StateFinished  State = "finished"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// IsDone returns whether the job in the state has ended.
+func (s State) IsDone() bool {
+	return s != StateRunning
+}
+
+// Job is the record of a long-running task.
+type Job struct {
+	ID         uint64    `json:"id"`
+	Type       string    `json:"type"`
+	State      State     `json:"state"`
+	Progress   float64   `json:"progress"`
+	Detail     string    `json:"detail,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CreateTime time.Time `json:"create_time"`
+	EndTime    time.Time `json:"end_time,omitempty"`
+}
+
+func (j *Job) clone() *Job {
+	job := *j
+	return &job
+}
+
+// Reporter reports the progress of a running job.
+type Reporter interface {
+	// SetProgress sets the progress of the job, in the range of [0, 1], and
+	// a short description of the current step.
+	SetProgress(progress float64, detail string)
+}
+
+// RunFunc runs a job. The context is cancelled when the job is cancelled.
+type RunFunc func(ctx context.Context, r Reporter) error
+
+type reporter struct {
+	m  *Manager
+	id uint64
+}
+
+func (r *reporter) SetProgress(progress float64, detail string) {
+	r.m.setProgress(r.id, progress, detail)
+}
+
+// Manager runs the long-running tasks as jobs, and keeps their records in the
+// storage so that they can be listed and inspected in the same way. The done
+// jobs are removed after the TTL.
+type Manager struct {
+	ctx     context.Context
+	storage kv.Base
+	idAlloc id.Allocator
+	ttl     time.Duration
+
+	mu      sync.RWMutex
+	jobs    map[uint64]*Job
+	cancels map[uint64]context.CancelFunc
+	done    map[uint64]chan struct{}
+}
+
+// NewManager creates a Manager. The jobs are cancelled when the context is
+// done.
+func NewManager(ctx context.Context, storage kv.Base, idAlloc id.Allocator, ttl time.Duration) *Manager {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Manager{
+		ctx:     ctx,
+		storage: storage,
+		idAlloc: idAlloc,
+		ttl:     ttl,
+		jobs:    make(map[uint64]*Job),
+		cancels: make(map[uint64]context.CancelFunc),
+		done:    make(map[uint64]chan struct{}),
+	}
+}
+
+// Load loads the jobs from the storage. It should be called after becoming
+// the leader. The jobs which were running on the previous leader cannot be
+// resumed, so they are marked as failed.
+func (m *Manager) Load() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prefix := jobPath + "/"
+	nextKey, endKey := prefix, clientv3.GetPrefixRangeEnd(prefix)
+	for {
+		keys, values, err := m.storage.LoadRange(nextKey, endKey, loadJobLimit)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			job := &Job{}
+			if err := json.Unmarshal([]byte(value), job); err != nil {
+				return errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+			}
+			if _, ok := m.jobs[job.ID]; ok {
+				continue
+			}
+			if !job.State.IsDone() {
+				job.State, job.Error, job.EndTime = StateFailed, "interrupted by leader change", time.Now()
+				m.saveLocked(job)
+			}
+			m.jobs[job.ID] = job
+		}
+		if len(keys) < loadJobLimit {
+			break
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+	m.gcLocked()
+	return nil
+}
+
+// Submit creates a job of the type and runs it in the background.
+func (m *Manager) Submit(typ string, run RunFunc) (*Job, error) {
+	id, err := m.idAlloc.Alloc()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		ID:         id,
+		Type:       typ,
+		State:      StateRunning,
+		CreateTime: time.Now(),
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.saveLocked(job); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.jobs[id] = job
+	m.cancels[id] = cancel
+	m.done[id] = make(chan struct{})
+	m.gcLocked()
+	log.Info("job submitted", zap.Uint64("job-id", id), zap.String("type", typ))
+	go m.run(ctx, id, run)
+	return job.clone(), nil
+}
+
+func (m *Manager) run(ctx context.Context, id uint64, run RunFunc) {
+	err := run(ctx, &reporter{m: m, id: id})
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer func() {
+		m.cancels[id]()
+		delete(m.cancels, id)
+		close(m.done[id])
+		delete(m.done, id)
+	}()
+	job := m.jobs[id]
+	if job.State.IsDone() {
+		return
+	}
+	switch {
+	case err == nil:
+		job.State, job.Progress = StateFinished, 1
+	case ctx.Err() != nil:
+		job.State = StateCancelled
+	default:
+		job.State, job.Error = StateFailed, err.Error()
+	}
+	m.finishLocked(job)
+}
+
+func (m *Manager) setProgress(id uint64, progress float64, detail string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.jobs[id]
+	if job.State.IsDone() {
+		return
+	}
+	job.Progress, job.Detail = progress, detail
+	m.saveLocked(job)
+}
+
+// Cancel cancels the running job.
+func (m *Manager) Cancel(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return errs.ErrJobNotFound.FastGenByArgs(id)
+	}
+	if job.State.IsDone() {
+		return errs.ErrJobNotRunning.FastGenByArgs(id, job.State)
+	}
+	m.cancels[id]()
+	job.State = StateCancelled
+	m.finishLocked(job)
+	return nil
+}
+
+// Wait waits until the job is done and returns it.
+func (m *Manager) Wait(ctx context.Context, id uint64) (*Job, error) {
+	m.mu.RLock()
+	done, ok := m.done[id]
+	m.mu.RUnlock()
+	if ok {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return m.Get(id)
+}
+
+// Get returns the job.
+func (m *Manager) Get(id uint64) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, errs.ErrJobNotFound.FastGenByArgs(id)
+	}
+	return job.clone(), nil
+}
+
+// List returns the jobs sorted by ID. If the type is not empty, only the jobs
+// of the type are returned.
+func (m *Manager) List(typ string) []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gcLocked()
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if typ == "" || job.Type == typ {
+			jobs = append(jobs, job.clone())
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+func (m *Manager) finishLocked(job *Job) {
+	job.EndTime = time.Now()
+	m.saveLocked(job)
+	log.Info("job done", zap.Uint64("job-id", job.ID), zap.String("type", job.Type),
+		zap.String("state", string(job.State)), zap.String("error", job.Error))
+}
+
+// gcLocked removes the done jobs which are expired.
+func (m *Manager) gcLocked() {
+	now := time.Now()
+	for id, job := range m.jobs {
+		if !job.State.IsDone() || now.Sub(job.EndTime) < m.ttl {
+			continue
+		}
+		if err := m.storage.Remove(jobKey(id)); err != nil {
+			log.Warn("failed to remove job", zap.Uint64("job-id", id), errs.ZapError(err))
+			continue
+		}
+		delete(m.jobs, id)
+	}
+}
+
+func (m *Manager) saveLocked(job *Job) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	if err := m.storage.Save(jobKey(job.ID), string(value)); err != nil {
+		log.Warn("failed to save job", zap.Uint64("job-id", job.ID), errs.ZapError(err))
+		return err
+	}
+	return nil
+}
+
+func jobKey(id uint64) string {
+	return path.Join(jobPath, fmt.Sprintf("%020d", id))
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/kv"
+)
+
+func TestJob(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testManagerSuite{})
+
+type testManagerSuite struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (s *testManagerSuite) SetUpTest(c *C) {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+}
+
+func (s *testManagerSuite) TearDownTest(c *C) {
+	s.cancel()
+}
+
+func (s *testManagerSuite) TestLifecycle(c *C) {
+	m := NewManager(s.ctx, kv.NewMemoryKV(), mockid.NewIDAllocator(), 0)
+
+	job, err := m.Submit("finish", func(ctx context.Context, r Reporter) error {
+		r.SetProgress(0.5, "half")
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(job.State, Equals, StateRunning)
+	job, err = m.Wait(s.ctx, job.ID)
+	c.Assert(err, IsNil)
+	c.Assert(job.State, Equals, StateFinished)
+	c.Assert(job.Progress, Equals, 1.0)
+	c.Assert(job.Detail, Equals, "half")
+
+	job, err = m.Submit("fail", func(ctx context.Context, r Reporter) error {
+		return errors.New("boom")
+	})
+	c.Assert(err, IsNil)
+	job, err = m.Wait(s.ctx, job.ID)
+	c.Assert(err, IsNil)
+	c.Assert(job.State, Equals, StateFailed)
+	c.Assert(job.Error, Equals, "boom")
+
+	job, err = m.Submit("cancel", func(ctx context.Context, r Reporter) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.Assert(err, IsNil)
+	c.Assert(m.Cancel(job.ID), IsNil)
+	job, err = m.Wait(s.ctx, job.ID)
+	c.Assert(err, IsNil)
+	c.Assert(job.State, Equals, StateCancelled)
+	c.Assert(m.Cancel(job.ID), NotNil)
+	c.Assert(m.Cancel(100), NotNil)
+
+	c.Assert(m.List(""), HasLen, 3)
+	jobs := m.List("fail")
+	c.Assert(jobs, HasLen, 1)
+	c.Assert(jobs[0].Type, Equals, "fail")
+	_, err = m.Get(100)
+	c.Assert(err, NotNil)
+}
+
+func (s *testManagerSuite) TestLoad(c *C) {
+	storage := kv.NewMemoryKV()
+	m := NewManager(s.ctx, storage, mockid.NewIDAllocator(), 0)
+	block := make(chan struct{})
+	defer close(block)
+	running, err := m.Submit("running", func(ctx context.Context, r Reporter) error {
+		<-block
+		return nil
+	})
+	c.Assert(err, IsNil)
+	finished, err := m.Submit("finished", func(ctx context.Context, r Reporter) error { return nil })
+	c.Assert(err, IsNil)
+	_, err = m.Wait(s.ctx, finished.ID)
+	c.Assert(err, IsNil)
+
+	// A new leader loads the jobs of the previous one.
+	m = NewManager(s.ctx, storage, mockid.NewIDAllocator(), 0)
+	c.Assert(m.Load(), IsNil)
+	job, err := m.Get(running.ID)
+	c.Assert(err, IsNil)
+	c.Assert(job.State, Equals, StateFailed)
+	job, err = m.Get(finished.ID)
+	c.Assert(err, IsNil)
+	c.Assert(job.State, Equals, StateFinished)
+}
+
+func (s *testManagerSuite) TestGC(c *C) {
+	storage := kv.NewMemoryKV()
+	m := NewManager(s.ctx, storage, mockid.NewIDAllocator(), time.Millisecond)
+	job, err := m.Submit("gc", func(ctx context.Context, r Reporter) error { return nil })
+	c.Assert(err, IsNil)
+	_, err = m.Wait(s.ctx, job.ID)
+	c.Assert(err, IsNil)
+	time.Sleep(10 * time.Millisecond)
+	c.Assert(m.List(""), HasLen, 0)
+	value, err := storage.Load(jobKey(job.ID))
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "")
+}
@@ -0,0 +1,256 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/etcdutil"
+	"go.etcd.io/etcd/clientv3"
+	"go.uber.org/zap"
+)
+
+// KeyLayoutVersion is the version of the layout of the keys under the root
+// path of the cluster. It should be bumped once a key is moved or renamed,
+// so that the keys left by an older version can be told.
+const KeyLayoutVersion = 1
+
+const (
+	keyLayoutVersionPath = "key_layout_version"
+	// keyLayoutScanLimit is the number of keys fetched at a time when scanning
+	// the keys of the cluster.
+	keyLayoutScanLimit = 10000
+)
+
+// keyOwnerKind is the kind of the owner of a key, the key is an orphan once
+// its owner is gone.
+type keyOwnerKind int
+
+const (
+	ownerNone keyOwnerKind = iota
+	ownerStore
+	ownerMemberID
+)
+
+// keyLayoutRule matches the keys of a kind. The first submatch of pattern is
+// the owner of the key, if any.
+type keyLayoutRule struct {
+	pattern *regexp.Regexp
+	owner   keyOwnerKind
+}
+
+var keyLayoutRules = []keyLayoutRule{
+	{pattern: regexp.MustCompile(`^(alloc_id|config|leader|timestamp|raft|component|rolling_restart|` + keyLayoutVersionPath + `)$`)},
+	{pattern: regexp.MustCompile(`^raft/(s|r)/\d{20}$`)},
+	{pattern: regexp.MustCompile(`^raft/archived_stores/\d{20}$`)},
+	{pattern: regexp.MustCompile(`^raft/status/[^/]+$`)},
+	{pattern: regexp.MustCompile(`^member/(\d+)/(leader_priority|deploy_path|routing_urls|git_hash|binary_version)$`), owner: ownerMemberID},
+	{pattern: regexp.MustCompile(`^dc-location/(\d+)$`), owner: ownerMemberID},
+	{pattern: regexp.MustCompile(`^local-tso-suffix/[^/]+$`)},
+	{pattern: regexp.MustCompile(`^schedule/store_weight/(\d{20})/(leader|region)$`), owner: ownerStore},
+	{pattern: regexp.MustCompile(`^schedule/(?:store_drain|store_maintenance|store_offline_plan)/(\d{20})$`), owner: ownerStore},
+	{pattern: regexp.MustCompile(`^(rules|rule_group|replication_mode|scheduler_config)/[^/]+$`)},
+	{pattern: regexp.MustCompile(`^(split_report|encryption_keys)/.+$`)},
+	{pattern: regexp.MustCompile(`^(jobs|id_reservation|config_history|tso_lease)/\d{20}$`)},
+	{pattern: regexp.MustCompile(`^gc/(safe_point|pause)$`)},
+	{pattern: regexp.MustCompile(`^gc/safe_point/service/[^/]+$`)},
+}
+
+// localAllocatorKeyPattern matches the keys of the Local TSO Allocator, whose
+// first part is the dc-location.
+var localAllocatorKeyPattern = regexp.MustCompile(`^([^/]+)/(leader|timestamp|next-leader)$`)
+
+// OrphanKey is a key whose owner does not exist anymore.
+type OrphanKey struct {
+	Key   string `json:"key"`
+	Owner string `json:"owner"`
+}
+
+// KeyLayoutReport is the result of checking the keys of the cluster.
+type KeyLayoutReport struct {
+	Valid                 bool         `json:"valid"`
+	LayoutVersion         int          `json:"layout_version"`
+	ExpectedLayoutVersion int          `json:"expected_layout_version"`
+	KeyCount              int          `json:"key_count"`
+	UnknownKeys           []string     `json:"unknown_keys"`
+	OrphanKeys            []*OrphanKey `json:"orphan_keys"`
+	RemovedKeys           []string     `json:"removed_keys,omitempty"`
+}
+
+// CheckKeyLayout scans the keys under the root path of the cluster, and
+// reports the keys which are not known by this version and the keys whose
+// owner is gone. The orphan keys are removed if cleanup is true, the unknown
+// keys are never removed since they may be written by a newer version.
+func (s *Server) CheckKeyLayout(cleanup bool) (*KeyLayoutReport, error) {
+	version, err := s.loadKeyLayoutVersion()
+	if err != nil {
+		return nil, err
+	}
+	report := &KeyLayoutReport{
+		LayoutVersion:         version,
+		ExpectedLayoutVersion: KeyLayoutVersion,
+		UnknownKeys:           []string{},
+		OrphanKeys:            []*OrphanKey{},
+	}
+
+	keys, err := s.scanClusterKeys()
+	if err != nil {
+		return nil, err
+	}
+	report.KeyCount = len(keys)
+	members, err := etcdutil.ListEtcdMembers(s.client)
+	if err != nil {
+		return nil, err
+	}
+	memberIDs := make(map[string]struct{}, len(members.Members))
+	for _, m := range members.Members {
+		memberIDs[strconv.FormatUint(m.GetID(), 10)] = struct{}{}
+	}
+	dcLocations, err := s.loadDCLocations()
+	if err != nil {
+		return nil, err
+	}
+	stores := make(map[string]struct{})
+	for _, key := range keys {
+		if strings.HasPrefix(key, "raft/s/") {
+			stores[strings.TrimPrefix(key, "raft/s/")] = struct{}{}
+		}
+	}
+
+	for _, key := range keys {
+		rule, matches := matchKeyLayoutRule(key)
+		if rule == nil {
+			if m := localAllocatorKeyPattern.FindStringSubmatch(key); m != nil {
+				if _, ok := dcLocations[m[1]]; ok {
+					continue
+				}
+			}
+			report.UnknownKeys = append(report.UnknownKeys, key)
+			continue
+		}
+		var owner string
+		switch rule.owner {
+		case ownerStore:
+			if _, ok := stores[matches[1]]; !ok {
+				id, _ := strconv.ParseUint(matches[1], 10, 64)
+				owner = fmt.Sprintf("store %d", id)
+			}
+		case ownerMemberID:
+			if _, ok := memberIDs[matches[1]]; !ok {
+				owner = "member " + matches[1]
+			}
+		}
+		if owner != "" {
+			report.OrphanKeys = append(report.OrphanKeys, &OrphanKey{Key: key, Owner: owner})
+		}
+	}
+	report.Valid = version == KeyLayoutVersion && len(report.UnknownKeys) == 0 && len(report.OrphanKeys) == 0
+
+	if cleanup {
+		for _, orphan := range report.OrphanKeys {
+			if err := s.storage.Remove(orphan.Key); err != nil {
+				return report, err
+			}
+			report.RemovedKeys = append(report.RemovedKeys, orphan.Key)
+			log.Info("orphan key is removed", zap.String("key", orphan.Key), zap.String("owner", orphan.Owner))
+		}
+	}
+	return report, nil
+}
+
+func matchKeyLayoutRule(key string) (*keyLayoutRule, []string) {
+	for i := range keyLayoutRules {
+		if m := keyLayoutRules[i].pattern.FindStringSubmatch(key); m != nil {
+			return &keyLayoutRules[i], m
+		}
+	}
+	return nil, nil
+}
+
+// scanClusterKeys returns the sorted keys under the root path of the cluster,
+// which are relative to the root path.
+func (s *Server) scanClusterKeys() ([]string, error) {
+	prefix := s.rootPath + "/"
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	var keys []string
+	for start := prefix; ; {
+		resp, err := etcdutil.EtcdKVGet(s.client, start,
+			clientv3.WithRange(end), clientv3.WithKeysOnly(), clientv3.WithLimit(keyLayoutScanLimit))
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range resp.Kvs {
+			keys = append(keys, strings.TrimPrefix(string(kv.Key), prefix))
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			break
+		}
+		start = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// loadDCLocations returns the dc-locations configured by the members.
+func (s *Server) loadDCLocations() (map[string]struct{}, error) {
+	prefix := s.member.GetDCLocationPathPrefix() + "/"
+	resp, err := etcdutil.EtcdKVGet(s.client, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	dcLocations := make(map[string]struct{}, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		dcLocations[string(kv.Value)] = struct{}{}
+	}
+	return dcLocations, nil
+}
+
+// loadKeyLayoutVersion returns the layout version of the stored keys, or 0 if
+// it has never been recorded.
+func (s *Server) loadKeyLayoutVersion() (int, error) {
+	value, err := s.storage.Load(keyLayoutVersionPath)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errs.ErrStrconvParseInt.Wrap(err).GenWithStackByCause()
+	}
+	return version, nil
+}
+
+// initKeyLayoutVersion records the layout version of this PD once it becomes
+// the leader. The version written by a newer PD is kept as it is.
+func (s *Server) initKeyLayoutVersion() error {
+	version, err := s.loadKeyLayoutVersion()
+	if err != nil {
+		return err
+	}
+	if version > KeyLayoutVersion {
+		log.Warn("the keys are written by a newer version of PD",
+			zap.Int("layout-version", version),
+			zap.Int("expected-layout-version", KeyLayoutVersion))
+		return nil
+	}
+	if version == KeyLayoutVersion {
+		return nil
+	}
+	return s.storage.Save(keyLayoutVersionPath, strconv.Itoa(KeyLayoutVersion))
+}
@@ -99,6 +99,25 @@ func (kv *etcdKVBase) Save(key, value string) error {
 	return nil
 }
 
+func (kv *etcdKVBase) SaveBatch(kvs map[string]string) error {
+	ops := make([]clientv3.Op, 0, len(kvs))
+	for key, value := range kvs {
+		ops = append(ops, clientv3.OpPut(path.Join(kv.rootPath, key), value))
+	}
+
+	txn := NewSlowLogTxn(kv.client)
+	resp, err := txn.Then(ops...).Commit()
+	if err != nil {
+		e := errs.ErrEtcdKVPut.Wrap(err).GenWithStackByCause()
+		log.Error("save batch to etcd meet error", zap.Int("keys", len(kvs)), errs.ZapError(e))
+		return e
+	}
+	if !resp.Succeeded {
+		return errs.ErrEtcdTxnConflict.FastGenByArgs()
+	}
+	return nil
+}
+
 func (kv *etcdKVBase) Remove(key string) error {
 	key = path.Join(kv.rootPath, key)
 
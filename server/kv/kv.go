@@ -18,5 +18,8 @@ type Base interface {
 	Load(key string) (string, error)
 	LoadRange(key, endKey string, limit int) (keys []string, values []string, err error)
 	Save(key, value string) error
+	// SaveBatch saves the key-values in a single write, so that either all
+	// or none of them are saved.
+	SaveBatch(kvs map[string]string) error
 	Remove(key string) error
 }
@@ -22,6 +22,7 @@ import (
 	"sort"
 	"strconv"
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/tikv/pd/pkg/tempurl"
@@ -73,6 +74,47 @@ func (s *testKVSuite) TestMemKV(c *C) {
 	s.testRange(c, kv)
 }
 
+func (s *testKVSuite) TestTimeLog(c *C) {
+	kv := NewMemoryKV()
+	c.Assert(kv.Save("other", "0"), IsNil)
+	log := NewTimeLog(kv, "log")
+	t0 := time.Unix(1000, 0)
+	// More records than a load.
+	for i := 0; i < timeLogLoadLimit+10; i++ {
+		c.Assert(log.Save(t0.Add(time.Duration(i)*time.Second), "-a", strconv.Itoa(i)), IsNil)
+	}
+	count := func(start, end time.Time) int {
+		n := 0
+		c.Assert(log.Range(start, end, func(value string) (bool, error) {
+			c.Assert(value, Equals, strconv.Itoa(int(start.Sub(t0)/time.Second)+n))
+			n++
+			return true, nil
+		}), IsNil)
+		return n
+	}
+	c.Assert(count(t0, time.Time{}), Equals, timeLogLoadLimit+10)
+	c.Assert(count(t0.Add(time.Second), t0.Add(3*time.Second)), Equals, 2)
+	// Stop early.
+	n := 0
+	c.Assert(log.Range(t0, time.Time{}, func(string) (bool, error) {
+		n++
+		return n < 3, nil
+	}), IsNil)
+	c.Assert(n, Equals, 3)
+
+	c.Assert(log.GC(t0.Add(timeLogLoadLimit*time.Second)), IsNil)
+	c.Assert(count(t0.Add(timeLogLoadLimit*time.Second), time.Time{}), Equals, 10)
+	n = 0
+	c.Assert(log.Range(time.Time{}, time.Time{}, func(string) (bool, error) {
+		n++
+		return true, nil
+	}), IsNil)
+	c.Assert(n, Equals, 10)
+	value, err := kv.Load("other")
+	c.Assert(err, IsNil)
+	c.Assert(value, Equals, "0")
+}
+
 func (s *testKVSuite) testReadWrite(c *C, kv Base) {
 	v, err := kv.Load("key")
 	c.Assert(err, IsNil)
@@ -71,6 +71,18 @@ func (kv *LeveldbKV) Save(key, value string) error {
 	return errors.WithStack(kv.Put([]byte(key), []byte(value), nil))
 }
 
+// SaveBatch stores the key-value pairs in a single batch.
+func (kv *LeveldbKV) SaveBatch(kvs map[string]string) error {
+	batch := new(leveldb.Batch)
+	for key, value := range kvs {
+		batch.Put([]byte(key), []byte(value))
+	}
+	if err := kv.Write(batch, nil); err != nil {
+		return errs.ErrLevelDBWrite.Wrap(err).GenWithStackByCause()
+	}
+	return nil
+}
+
 // Remove deletes a key-value pair for a given key.
 func (kv *LeveldbKV) Remove(key string) error {
 	return errors.WithStack(kv.Delete([]byte(key), nil))
@@ -0,0 +1,97 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+)
+
+// timeLogLoadLimit is the max number of the records loaded at a time.
+const timeLogLoadLimit = 1000
+
+// TimeLog keeps the records under a prefix ordered by the time they are saved,
+// such as the history of the hot regions, so that the records in a period can
+// be loaded and the old ones can be removed.
+type TimeLog struct {
+	base   Base
+	prefix string
+}
+
+// NewTimeLog creates a TimeLog of the records under the prefix.
+func NewTimeLog(base Base, prefix string) *TimeLog {
+	return &TimeLog{base: base, prefix: prefix}
+}
+
+// Save saves a record at the time. The suffix tells apart the records at the
+// same time.
+func (l *TimeLog) Save(t time.Time, suffix, value string) error {
+	return l.base.Save(l.key(t)+suffix, value)
+}
+
+// Range calls f with the records saved in [start, end) ordered by time until
+// f returns false. A zero end means there is no end.
+func (l *TimeLog) Range(start, end time.Time, f func(value string) (bool, error)) error {
+	nextKey, endKey := l.key(start), l.key(end)
+	if end.IsZero() {
+		endKey = clientv3.GetPrefixRangeEnd(l.prefix + "/")
+	}
+	for {
+		keys, values, err := l.base.LoadRange(nextKey, endKey, timeLogLoadLimit)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if ok, err := f(value); !ok || err != nil {
+				return err
+			}
+		}
+		if len(keys) < timeLogLoadLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// GC removes the records saved before the time.
+func (l *TimeLog) GC(before time.Time) error {
+	endKey := l.key(before)
+	for {
+		keys, _, err := l.base.LoadRange(l.prefix+"/", endKey, timeLogLoadLimit)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := l.base.Remove(key); err != nil {
+				return err
+			}
+		}
+		if len(keys) < timeLogLoadLimit {
+			return nil
+		}
+	}
+}
+
+// key returns the key prefix of the records at the time, which is ordered by
+// time.
+func (l *TimeLog) key(t time.Time) string {
+	nanos := t.UnixNano()
+	if t.IsZero() || nanos < 0 {
+		nanos = 0
+	}
+	return path.Join(l.prefix, fmt.Sprintf("%020d", nanos))
+}
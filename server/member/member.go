@@ -15,6 +15,7 @@ package member
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
@@ -399,6 +400,45 @@ func (m *Member) SetMemberDeployPath(id uint64) error {
 	return nil
 }
 
+func (m *Member) getMemberRoutingURLsPath(id uint64) string {
+	return path.Join(m.rootPath, fmt.Sprintf("member/%d/routing_urls", id))
+}
+
+// GetMemberRoutingURLs loads a member's client urls of each routing domain.
+func (m *Member) GetMemberRoutingURLs(id uint64) (map[string][]string, error) {
+	key := m.getMemberRoutingURLsPath(id)
+	res, err := etcdutil.EtcdKVGet(m.client, key)
+	if err != nil {
+		return nil, err
+	}
+	urls := make(map[string][]string)
+	if len(res.Kvs) == 0 {
+		return urls, nil
+	}
+	if err := json.Unmarshal(res.Kvs[0].Value, &urls); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return urls, nil
+}
+
+// SetMemberRoutingURLs saves a member's client urls of each routing domain.
+func (m *Member) SetMemberRoutingURLs(id uint64, urls map[string][]string) error {
+	key := m.getMemberRoutingURLsPath(id)
+	value, err := json.Marshal(urls)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	txn := kv.NewSlowLogTxn(m.client)
+	res, err := txn.Then(clientv3.OpPut(key, string(value))).Commit()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !res.Succeeded {
+		return errors.New("failed to save routing urls")
+	}
+	return nil
+}
+
 func (m *Member) getMemberGitHashPath(id uint64) string {
 	return path.Join(m.rootPath, fmt.Sprintf("member/%d/git_hash", id))
 }
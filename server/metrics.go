@@ -75,6 +75,24 @@ var (
 			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 29), // 0.1ms ~ 7hours
 		}, []string{"address", "store"})
 
+	bulkRegionHeartbeatHandleDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "handle_bulk_region_heartbeat_duration_seconds",
+			Help:      "Bucketed histogram of processing time (s) of handled bulk region heartbeat batches.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 29), // 0.1ms ~ 7hours
+		})
+
+	bulkRegionHeartbeatBatchSize = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "bulk_region_heartbeat_batch_size",
+			Help:      "Bucketed histogram of the number of region heartbeats in a bulk region heartbeat batch.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 16),
+		})
+
 	storeHeartbeatHandleDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: "pd",
@@ -91,6 +109,126 @@ var (
 			Name:      "info",
 			Help:      "Indicate the pd server info, and the value is the start timestamp (s).",
 		}, []string{"version", "hash"})
+
+	rateLimitedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "grpc_rate_limited_total",
+			Help:      "Counter of gRPC requests rejected by the rate limit of caller components.",
+		}, []string{"component", "method"})
+
+	forwardedRequestCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "grpc_forwarded_total",
+			Help:      "Counter of the unary gRPC requests forwarded to the other members.",
+		}, []string{"method"})
+
+	tsoProxyStreamGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "tso_proxy_streams",
+			Help:      "The number of the TSO streams forwarded to the leader.",
+		})
+
+	tsoProxyRejectedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "tso_proxy_rejected_total",
+			Help:      "Counter of the TSO streams rejected for exceeding the max concurrent TSO proxy streamings.",
+		})
+
+	forwardConnGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "forward_conns",
+			Help:      "The number of the connections to the other members kept for forwarding.",
+		})
+
+	forwardStreamGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "forward_streams",
+			Help:      "The number of the streams forwarded to the other members.",
+		}, []string{"host"})
+
+	forwardConnEvictedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "forward_conn_evicted_total",
+			Help:      "Counter of the forwarding connections closed.",
+		}, []string{"reason"})
+
+	forwardStreamRejectedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "forward_stream_rejected_total",
+			Help:      "Counter of the streams rejected for exceeding the max forwarded streams of a member.",
+		})
+
+	regionSyncerDivergentChunksGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "region_syncer",
+			Name:      "divergent_chunks",
+			Help:      "The number of the chunks of the regions synced from the leader which diverge in the last verification.",
+		})
+
+	forwardBreakerStateGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "forward_breaker_state",
+			Help:      "The state of the circuit breakers of forwarding, 0 is closed, 1 is open and 2 is half-open.",
+		}, []string{"host"})
+
+	forwardBreakerRejectedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "forward_breaker_rejected_total",
+			Help:      "Counter of the forwarded requests and streams rejected by the open circuit breakers.",
+		})
+
+	storeFollowerReadCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "store_follower_read_total",
+			Help:      "Counter of the store metadata reads handled by the follower.",
+		}, []string{"result"})
+
+	deprecatedRPCCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "grpc_deprecated_total",
+			Help:      "Counter of gRPC requests to the deprecated methods.",
+		}, []string{"method", "component"})
+
+	dependencyHealthGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "dependency_healthy",
+			Help:      "Whether the dependency of the server is healthy, 1 for healthy and 0 for unhealthy.",
+		}, []string{"dependency"})
+
+	adminAuthDeniedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "grpc_admin_auth_denied_total",
+			Help:      "Counter of gRPC requests to the admin methods denied by the authorization.",
+		}, []string{"method"})
 )
 
 func init() {
@@ -101,6 +239,23 @@ func init() {
 	prometheus.MustRegister(etcdStateGauge)
 	prometheus.MustRegister(tsoHandleDuration)
 	prometheus.MustRegister(regionHeartbeatHandleDuration)
+	prometheus.MustRegister(bulkRegionHeartbeatHandleDuration)
+	prometheus.MustRegister(bulkRegionHeartbeatBatchSize)
 	prometheus.MustRegister(storeHeartbeatHandleDuration)
 	prometheus.MustRegister(serverInfo)
+	prometheus.MustRegister(rateLimitedCounter)
+	prometheus.MustRegister(adminAuthDeniedCounter)
+	prometheus.MustRegister(deprecatedRPCCounter)
+	prometheus.MustRegister(tsoProxyStreamGauge)
+	prometheus.MustRegister(tsoProxyRejectedCounter)
+	prometheus.MustRegister(forwardedRequestCounter)
+	prometheus.MustRegister(dependencyHealthGauge)
+	prometheus.MustRegister(forwardConnGauge)
+	prometheus.MustRegister(forwardStreamGauge)
+	prometheus.MustRegister(forwardConnEvictedCounter)
+	prometheus.MustRegister(forwardStreamRejectedCounter)
+	prometheus.MustRegister(forwardBreakerStateGauge)
+	prometheus.MustRegister(regionSyncerDivergentChunksGauge)
+	prometheus.MustRegister(forwardBreakerRejectedCounter)
+	prometheus.MustRegister(storeFollowerReadCounter)
 }
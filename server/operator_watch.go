@@ -0,0 +1,108 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"io"
+
+	"github.com/pingcap/errors"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/eventbus"
+	"github.com/tikv/pd/server/ophistory"
+)
+
+const (
+	operatorWatchSubscriber = "operator-watch"
+	operatorWatchBufferSize = 1024
+)
+
+// OperatorWatchFilter selects the records of the operators to watch. The zero
+// fields match all the operators.
+type OperatorWatchFilter struct {
+	RegionID uint64
+	// StoreID matches the operators moving the peers or the leader out of or
+	// into the store.
+	StoreID uint64
+}
+
+func (f OperatorWatchFilter) match(r *ophistory.Record) bool {
+	if f.RegionID != 0 && r.RegionID != f.RegionID {
+		return false
+	}
+	if f.StoreID == 0 {
+		return true
+	}
+	for _, stores := range [][]uint64{r.SourceStores, r.TargetStores} {
+		for _, id := range stores {
+			if id == f.StoreID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OperatorWatcher receives the records of the operators created or ended
+// after it is created.
+type OperatorWatcher struct {
+	sub    *eventbus.Subscription
+	filter OperatorWatchFilter
+	// ended is set if the server is no longer the leader.
+	ended bool
+}
+
+// WatchOperators creates an OperatorWatcher, which should be closed after use.
+func (h *Handler) WatchOperators(filter OperatorWatchFilter) (*OperatorWatcher, error) {
+	if h.s.GetRaftCluster() == nil {
+		return nil, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	w := &OperatorWatcher{
+		sub:    h.s.eventBus.Subscribe(operatorWatchSubscriber, operatorWatchBufferSize, eventbus.TopicOperator, eventbus.TopicLeader),
+		filter: filter,
+	}
+	// Check the leadership after subscribing, so that no change is missed.
+	w.ended = h.s.IsClosed() || !h.s.member.IsLeader()
+	return w, nil
+}
+
+// Next returns the next record matching the filter. It returns io.EOF once
+// the server is no longer the leader, then the watcher should watch the new
+// leader and fill the gap with the operator history.
+func (w *OperatorWatcher) Next(ctx context.Context) (*ophistory.Record, error) {
+	for !w.ended {
+		select {
+		case <-ctx.Done():
+			return nil, errors.WithStack(ctx.Err())
+		case <-w.sub.Stopped():
+			return nil, errs.ErrOperatorWatcherTooSlow.FastGenByArgs()
+		case event := <-w.sub.Events():
+			// Any leadership event means the leadership is lost, even if the
+			// server is elected again.
+			if event.Topic == eventbus.TopicLeader {
+				w.ended = true
+				break
+			}
+			if r := event.Payload.(*ophistory.Record); w.filter.match(r) {
+				return r, nil
+			}
+		}
+	}
+	return nil, io.EOF
+}
+
+// Close closes the watcher.
+func (w *OperatorWatcher) Close() {
+	w.sub.Close()
+}
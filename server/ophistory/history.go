@@ -0,0 +1,225 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ophistory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/kv"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/operator"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultTTL is how long a record is kept by default.
+	DefaultTTL = 7 * 24 * time.Hour
+
+	historyPath = "operator_history"
+	bufferSize  = 1024
+	gcInterval  = 10 * time.Minute
+)
+
+// Record is an event of an operator, such as its creation and its end.
+type Record struct {
+	Time     time.Time `json:"time"`
+	RegionID uint64    `json:"region_id"`
+	Event    string    `json:"event"`
+	// Source is where the operator comes from, such as the schedulers, the
+	// checkers or the API.
+	Source string   `json:"source"`
+	Desc   string   `json:"desc"`
+	Kind   string   `json:"kind"`
+	Steps  []string `json:"steps"`
+	// RunningTime is how long the operator has been running, which is empty
+	// when it is created.
+	RunningTime string `json:"running_time,omitempty"`
+	// SourceStores are the stores the operator moves the peers or the leader
+	// out of, and TargetStores are the ones it moves them into.
+	SourceStores []uint64 `json:"source_stores,omitempty"`
+	TargetStores []uint64 `json:"target_stores,omitempty"`
+	// StartKey and EndKey are the hex encoded key range of the region, which
+	// are only known by the watchers.
+	StartKey string `json:"start_key,omitempty"`
+	EndKey   string `json:"end_key,omitempty"`
+}
+
+// newRecord returns the record of an event of the operator. The region is
+// optional.
+func newRecord(op *operator.Operator, event string, region *core.RegionInfo) *Record {
+	r := &Record{
+		Time:     time.Now(),
+		RegionID: op.RegionID(),
+		Event:    event,
+		Source:   op.Source(),
+		Desc:     op.Desc(),
+		Kind:     op.Kind().String(),
+		Steps:    make([]string, 0, op.Len()),
+	}
+	for i := 0; i < op.Len(); i++ {
+		r.Steps = append(r.Steps, op.Step(i).String())
+	}
+	if event != schedule.OperatorEventCreate {
+		r.RunningTime = op.RunningTime().String()
+	}
+	r.SourceStores, r.TargetStores = operatorStores(op)
+	if region != nil {
+		r.StartKey = core.HexRegionKeyStr(region.GetStartKey())
+		r.EndKey = core.HexRegionKeyStr(region.GetEndKey())
+	}
+	return r
+}
+
+// operatorStores returns the stores the operator moves the peers or the leader
+// out of and into, in the order of the steps.
+func operatorStores(op *operator.Operator) (sources, targets []uint64) {
+	add := func(stores []uint64, id uint64) []uint64 {
+		for _, s := range stores {
+			if s == id {
+				return stores
+			}
+		}
+		return append(stores, id)
+	}
+	for i := 0; i < op.Len(); i++ {
+		switch step := op.Step(i).(type) {
+		case operator.TransferLeader:
+			sources = add(sources, step.FromStore)
+			targets = add(targets, step.ToStore)
+		case operator.RemovePeer:
+			sources = add(sources, step.FromStore)
+		case operator.AddPeer:
+			targets = add(targets, step.ToStore)
+		case operator.AddLearner:
+			targets = add(targets, step.ToStore)
+		case operator.AddLightPeer:
+			targets = add(targets, step.ToStore)
+		case operator.AddLightLearner:
+			targets = add(targets, step.ToStore)
+		}
+	}
+	return
+}
+
+// Store keeps the records of the operators in the local storage of the hot
+// region history for the TTL, so that they can be queried for postmortems.
+// The storage is local to the member, so a leader only has the records of the
+// operators while it was the leader, which survive its restarts. The records
+// are saved in the background, and are dropped if the storage cannot keep up
+// with them.
+type Store struct {
+	ctx     context.Context
+	log     *kv.TimeLog
+	ttl     time.Duration
+	records chan *Record
+	seq     uint64
+}
+
+// NewStore creates a Store and starts saving the records in the background
+// until the context is done.
+func NewStore(ctx context.Context, storage kv.Base, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	s := &Store{
+		ctx:     ctx,
+		log:     kv.NewTimeLog(storage, historyPath),
+		ttl:     ttl,
+		records: make(chan *Record, bufferSize),
+	}
+	go s.run()
+	return s
+}
+
+// RecordOperator records an event of the operator. It never blocks.
+func (s *Store) RecordOperator(op *operator.Operator, event string) {
+	r := newRecord(op, event, nil)
+	select {
+	case s.records <- r:
+	default:
+		droppedRecordCounter.Inc()
+	}
+}
+
+func (s *Store) run() {
+	ticker := time.NewTicker(gcInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case r := <-s.records:
+			s.save(r)
+		case <-ticker.C:
+			s.gc(time.Now())
+		case <-s.ctx.Done():
+			// Save the records left behind before exiting.
+			for {
+				select {
+				case r := <-s.records:
+					s.save(r)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Store) save(r *Record) {
+	value, err := json.Marshal(r)
+	if err != nil {
+		log.Warn("failed to marshal operator record", errs.ZapError(errs.ErrJSONMarshal, err))
+		return
+	}
+	// The sequence keeps the keys of the records at the same time unique.
+	suffix := fmt.Sprintf("-%020d-%d", r.RegionID, atomic.AddUint64(&s.seq, 1))
+	if err := s.log.Save(r.Time, suffix, string(value)); err != nil {
+		log.Warn("failed to save operator record", zap.Uint64("region-id", r.RegionID), errs.ZapError(err))
+	}
+}
+
+// gc removes the records older than the TTL.
+func (s *Store) gc(now time.Time) {
+	if err := s.log.GC(now.Add(-s.ttl)); err != nil {
+		log.Warn("failed to remove operator records", errs.ZapError(err))
+	}
+}
+
+// Query returns at most limit records in [start, end) ordered by time. If
+// regionID is not 0, only the records of the region are returned. If limit is
+// not positive, all the records are returned.
+func (s *Store) Query(regionID uint64, start, end time.Time, limit int) ([]*Record, error) {
+	records := []*Record{}
+	err := s.log.Range(start, end, func(value string) (bool, error) {
+		r := &Record{}
+		if err := json.Unmarshal([]byte(value), r); err != nil {
+			return false, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+		}
+		if regionID != 0 && r.RegionID != regionID {
+			return true, nil
+		}
+		records = append(records, r)
+		return limit <= 0 || len(records) < limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ophistory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/pkg/eventbus"
+	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/kv"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testHistorySuite{})
+
+type testHistorySuite struct{}
+
+func (s *testHistorySuite) TestRecordAndQuery(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storage := kv.NewMemoryKV()
+	store := NewStore(ctx, storage, time.Hour)
+
+	start := time.Now().Add(-time.Second)
+	for id := uint64(1); id <= 3; id++ {
+		op := operator.NewOperator("test", "test", id, &metapb.RegionEpoch{}, operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+		op.SetSource(operator.SourceScheduler)
+		store.RecordOperator(op, schedule.OperatorEventCreate)
+		store.RecordOperator(op, schedule.OperatorEventFinish)
+	}
+	testutil.WaitUntil(c, func(c *C) bool {
+		records, err := store.Query(0, time.Time{}, time.Time{}, 0)
+		c.Assert(err, IsNil)
+		return len(records) == 6
+	})
+
+	records, err := store.Query(2, start, time.Now().Add(time.Second), 0)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].RegionID, Equals, uint64(2))
+	c.Assert(records[0].Event, Equals, schedule.OperatorEventCreate)
+	c.Assert(records[0].Source, Equals, operator.SourceScheduler)
+	c.Assert(records[0].Desc, Equals, "test")
+	c.Assert(records[0].Steps, DeepEquals, []string{"transfer leader from store 1 to store 2"})
+	c.Assert(records[0].RunningTime, Equals, "")
+	c.Assert(records[0].SourceStores, DeepEquals, []uint64{1})
+	c.Assert(records[0].TargetStores, DeepEquals, []uint64{2})
+	c.Assert(records[1].Event, Equals, schedule.OperatorEventFinish)
+	c.Assert(records[1].RunningTime, Not(Equals), "")
+
+	records, err = store.Query(0, time.Time{}, time.Time{}, 4)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 4)
+	records, err = store.Query(0, time.Time{}, start, 0)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 0)
+
+	// The records survive the restart of the store.
+	records, err = NewStore(ctx, storage, time.Hour).Query(0, time.Time{}, time.Time{}, 0)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 6)
+
+	store.gc(time.Now().Add(time.Hour))
+	records, err = store.Query(0, time.Time{}, time.Time{}, 0)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 0)
+}
+
+type mockRegions map[uint64]*core.RegionInfo
+
+func (m mockRegions) GetRegion(regionID uint64) *core.RegionInfo {
+	return m[regionID]
+}
+
+func (s *testHistorySuite) TestPublisher(c *C) {
+	bus := eventbus.NewBus()
+	region := core.NewRegionInfo(&metapb.Region{Id: 1, StartKey: []byte("a"), EndKey: []byte("b")}, nil)
+	p := NewPublisher(bus, mockRegions{1: region})
+	op := operator.NewOperator("test", "test", 1, &metapb.RegionEpoch{}, operator.OpRegion,
+		operator.AddLearner{ToStore: 3, PeerID: 3},
+		operator.PromoteLearner{ToStore: 3, PeerID: 3},
+		operator.TransferLeader{FromStore: 1, ToStore: 2},
+		operator.RemovePeer{FromStore: 1, PeerID: 1})
+	// Nothing is published without any watcher.
+	p.RecordOperator(op, schedule.OperatorEventCreate)
+
+	sub := bus.Subscribe("test", 1, eventbus.TopicOperator)
+	defer sub.Close()
+	p.RecordOperator(op, schedule.OperatorEventCreate)
+	r := (<-sub.Events()).Payload.(*Record)
+	c.Assert(r.RegionID, Equals, uint64(1))
+	c.Assert(r.Event, Equals, schedule.OperatorEventCreate)
+	c.Assert(r.SourceStores, DeepEquals, []uint64{1})
+	c.Assert(r.TargetStores, DeepEquals, []uint64{3, 2})
+	c.Assert(r.StartKey, Equals, "61")
+	c.Assert(r.EndKey, Equals, "62")
+	c.Assert(r.Steps, HasLen, 4)
+	select {
+	case <-sub.Events():
+		c.Fatal("unexpected record")
+	default:
+	}
+}
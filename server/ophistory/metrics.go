@@ -0,0 +1,28 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ophistory
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var droppedRecordCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "pd",
+		Subsystem: "schedule",
+		Name:      "operator_history_dropped_total",
+		Help:      "Counter of the operator records dropped because the storage cannot keep up.",
+	})
+
+func init() {
+	prometheus.MustRegister(droppedRecordCounter)
+}
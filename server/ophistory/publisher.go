@@ -0,0 +1,46 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ophistory
+
+import (
+	"github.com/tikv/pd/pkg/eventbus"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+// RegionGetter gets the region by its ID.
+type RegionGetter interface {
+	GetRegion(regionID uint64) *core.RegionInfo
+}
+
+// Publisher publishes the records of the operators to the event bus for the
+// watchers, such as the data movement view of the dashboard. The records are
+// only built when there is any watcher.
+type Publisher struct {
+	bus     *eventbus.Bus
+	regions RegionGetter
+}
+
+// NewPublisher creates a Publisher.
+func NewPublisher(bus *eventbus.Bus, regions RegionGetter) *Publisher {
+	return &Publisher{bus: bus, regions: regions}
+}
+
+// RecordOperator publishes an event of the operator. It never blocks.
+func (p *Publisher) RecordOperator(op *operator.Operator, event string) {
+	if !p.bus.HasSubscribers(eventbus.TopicOperator) {
+		return
+	}
+	p.bus.Publish(eventbus.TopicOperator, newRecord(op, event, p.regions.GetRegion(op.RegionID())))
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultScanRegionsChunkSize = 1024
+	maxScanRegionsChunkSize     = 10240
+)
+
+// regionScanService streams the regions of a key range in chunks. The limit of
+// the request bounds the total number of regions, and zero means no limit.
+var regionScanService = sideService{
+	name:   grpcutil.RegionScanServiceName,
+	checks: defaultGRPCChecks,
+	streams: []sideStream{
+		{
+			name:       grpcutil.ScanRegionsStreamName,
+			newRequest: func() interface{} { return new(pdpb.ScanRegionsRequest) },
+			handle: func(s *Server, req interface{}, stream grpc.ServerStream) error {
+				return s.ScanRegionsStream(req.(*pdpb.ScanRegionsRequest), stream)
+			},
+		},
+	},
+}
+
+// ScanRegionsStream sends the regions of the key range chunk by chunk. Only
+// one chunk is held in memory at a time, and sending a chunk blocks until the
+// flow control window of the stream allows it, so a slow client slows down the
+// scan instead of piling up responses on the server.
+func (s *Server) ScanRegionsStream(request *pdpb.ScanRegionsRequest, stream grpc.ServerStream) error {
+	rc := s.GetRaftCluster()
+	if rc == nil {
+		return stream.SendMsg(&pdpb.ScanRegionsResponse{Header: s.notBootstrappedHeader()})
+	}
+
+	ctx := stream.Context()
+	chunkSize := defaultScanRegionsChunkSize
+	if size, ok := grpcutil.GetScanChunkSize(ctx); ok {
+		chunkSize = size
+	}
+	if chunkSize > maxScanRegionsChunkSize {
+		chunkSize = maxScanRegionsChunkSize
+	}
+	remaining := int(request.GetLimit())
+	startKey, endKey := request.GetStartKey(), request.GetEndKey()
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		default:
+		}
+		if s.IsClosed() || !s.member.IsLeader() {
+			return errors.WithStack(s.notLeaderError())
+		}
+		limit := chunkSize
+		if remaining > 0 && remaining < limit {
+			limit = remaining
+		}
+		regions := rc.ScanRegions(startKey, endKey, limit)
+		if len(regions) == 0 {
+			return nil
+		}
+		if err := stream.SendMsg(newScanRegionsResponse(s.header(), regions)); err != nil {
+			return errors.WithStack(err)
+		}
+		if remaining > 0 {
+			remaining -= len(regions)
+			if remaining == 0 {
+				return nil
+			}
+		}
+		startKey = regions[len(regions)-1].GetEndKey()
+		if len(regions) < limit || len(startKey) == 0 || (len(endKey) > 0 && bytes.Compare(startKey, endKey) >= 0) {
+			return nil
+		}
+	}
+}
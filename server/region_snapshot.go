@@ -0,0 +1,51 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
+	syncer "github.com/tikv/pd/server/region_syncer"
+	"google.golang.org/grpc"
+)
+
+// regionSnapshotService sends a follower the regions which differ from its own
+// before it syncs the regions with SyncRegions.
+var regionSnapshotService = sideService{
+	name:   grpcutil.RegionSnapshotServiceName,
+	checks: defaultGRPCChecks,
+	streams: []sideStream{
+		{
+			name:       grpcutil.SyncRegionSnapshotStreamName,
+			newRequest: func() interface{} { return new(syncer.SnapshotRequest) },
+			handle: func(s *Server, req interface{}, stream grpc.ServerStream) error {
+				return s.SyncRegionSnapshot(req.(*syncer.SnapshotRequest), stream)
+			},
+		},
+	},
+}
+
+// SyncRegionSnapshot sends the regions in the chunks whose checksums differ
+// from the chunks of the follower. Each response is a SyncRegionResponse whose
+// start index is the index to sync the rest of the regions from with
+// SyncRegions.
+func (s *Server) SyncRegionSnapshot(request *syncer.SnapshotRequest, stream grpc.ServerStream) error {
+	rc := s.GetRaftCluster()
+	if rc == nil {
+		return stream.SendMsg(&pdpb.SyncRegionResponse{Header: s.notBootstrappedHeader()})
+	}
+	return rc.GetRegionSyncer().SyncSnapshot(request, func(resp *pdpb.SyncRegionResponse) error {
+		return stream.SendMsg(resp)
+	})
+}
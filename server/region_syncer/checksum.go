@@ -0,0 +1,225 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc64"
+	"sort"
+	"time"
+
+	"github.com/tikv/pd/server/core"
+)
+
+// ChecksumChunkSize is the number of the regions in a checksum chunk of the
+// leader.
+const ChecksumChunkSize = 4096
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// Chunk is the checksum of the regions starting in the key range
+// [StartKey, EndKey). The chunks of the leader cover the whole key space, so
+// a follower can compute the checksums of the same key ranges to compare.
+type Chunk struct {
+	StartKey []byte `json:"start_key"`
+	EndKey   []byte `json:"end_key"`
+	Count    int    `json:"count"`
+	Checksum uint64 `json:"checksum"`
+}
+
+func (c *Chunk) contains(key []byte) bool {
+	return bytes.Compare(key, c.StartKey) >= 0 && (len(c.EndKey) == 0 || bytes.Compare(key, c.EndKey) < 0)
+}
+
+// RegionDigest is the part of a region covered by the checksums. The leader
+// and the statistics are not covered since they change frequently.
+type RegionDigest struct {
+	ID       uint64 `json:"id"`
+	StartKey []byte `json:"start_key"`
+	EndKey   []byte `json:"end_key"`
+	Version  uint64 `json:"version"`
+	ConfVer  uint64 `json:"conf_ver"`
+	// Peers is the IDs of the peers in order.
+	Peers []uint64 `json:"peers"`
+}
+
+// NewRegionDigest creates the digest of a region.
+func NewRegionDigest(region *core.RegionInfo) *RegionDigest {
+	peers := make([]uint64, 0, len(region.GetPeers()))
+	for _, peer := range region.GetPeers() {
+		peers = append(peers, peer.GetId())
+	}
+	return &RegionDigest{
+		ID:       region.GetID(),
+		StartKey: region.GetStartKey(),
+		EndKey:   region.GetEndKey(),
+		Version:  region.GetRegionEpoch().GetVersion(),
+		ConfVer:  region.GetRegionEpoch().GetConfVer(),
+		Peers:    peers,
+	}
+}
+
+func (d *RegionDigest) equal(o *RegionDigest) bool {
+	if d.ID != o.ID || d.Version != o.Version || d.ConfVer != o.ConfVer ||
+		!bytes.Equal(d.StartKey, o.StartKey) || !bytes.Equal(d.EndKey, o.EndKey) || len(d.Peers) != len(o.Peers) {
+		return false
+	}
+	for i := range d.Peers {
+		if d.Peers[i] != o.Peers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *RegionDigest) checksum(crc uint64) uint64 {
+	var buf [8]byte
+	writeUint64 := func(v uint64) {
+		binary.BigEndian.PutUint64(buf[:], v)
+		crc = crc64.Update(crc, crcTable, buf[:])
+	}
+	writeBytes := func(b []byte) {
+		writeUint64(uint64(len(b)))
+		crc = crc64.Update(crc, crcTable, b)
+	}
+	writeUint64(d.ID)
+	writeBytes(d.StartKey)
+	writeBytes(d.EndKey)
+	writeUint64(d.Version)
+	writeUint64(d.ConfVer)
+	writeUint64(uint64(len(d.Peers)))
+	for _, id := range d.Peers {
+		writeUint64(id)
+	}
+	return crc
+}
+
+// NewChunks splits the regions ordered by the start key into the chunks of
+// chunkSize regions, and computes their checksums.
+func NewChunks(regions []*core.RegionInfo, chunkSize int) []*Chunk {
+	chunks := make([]*Chunk, 0, (len(regions)+chunkSize-1)/chunkSize)
+	for i := 0; i < len(regions); i += chunkSize {
+		chunk := &Chunk{}
+		if i > 0 {
+			chunk.StartKey = regions[i].GetStartKey()
+			chunks[len(chunks)-1].EndKey = chunk.StartKey
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, &Chunk{})
+	}
+	return ComputeChunks(regions, chunks)
+}
+
+// ComputeChunks computes the checksums of the regions ordered by the start
+// key in the key ranges of the chunks, which are ordered and cover the whole
+// key space.
+func ComputeChunks(regions []*core.RegionInfo, chunks []*Chunk) []*Chunk {
+	res := make([]*Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		res = append(res, &Chunk{StartKey: chunk.StartKey, EndKey: chunk.EndKey})
+	}
+	for _, region := range regions {
+		i := chunkOf(res, region.GetStartKey())
+		if i < 0 {
+			continue
+		}
+		res[i].Count++
+		res[i].Checksum = NewRegionDigest(region).checksum(res[i].Checksum)
+	}
+	return res
+}
+
+// chunkOf returns the index of the ordered chunks which contains the key, or
+// -1 if there is none.
+func chunkOf(chunks []*Chunk, key []byte) int {
+	i := sort.Search(len(chunks), func(i int) bool {
+		return len(chunks[i].EndKey) == 0 || bytes.Compare(key, chunks[i].EndKey) < 0
+	})
+	if i == len(chunks) || !chunks[i].contains(key) {
+		return -1
+	}
+	return i
+}
+
+// ChunkDivergence is a chunk whose regions in the follower are different from
+// the leader.
+type ChunkDivergence struct {
+	StartKey      string `json:"start_key"`
+	EndKey        string `json:"end_key"`
+	LeaderCount   int    `json:"leader_count"`
+	FollowerCount int    `json:"follower_count"`
+	// Regions is the divergent regions in the chunk, which are only listed
+	// for the first chunks.
+	Regions []*RegionDivergence `json:"regions,omitempty"`
+}
+
+// RegionDivergence is a region different in the leader and the follower. One
+// of them is nil if the region is missing there.
+type RegionDivergence struct {
+	ID       uint64        `json:"id"`
+	Leader   *RegionDigest `json:"leader"`
+	Follower *RegionDigest `json:"follower"`
+}
+
+// VerifyReport is the result of verifying the region cache of a follower
+// against the leader.
+type VerifyReport struct {
+	Name          string             `json:"name"`
+	Leader        string             `json:"leader"`
+	VerifyTime    time.Time          `json:"verify_time"`
+	ChunkCount    int                `json:"chunk_count"`
+	LeaderCount   int                `json:"leader_count"`
+	FollowerCount int                `json:"follower_count"`
+	Divergences   []*ChunkDivergence `json:"divergences"`
+}
+
+// CompareChunks returns the indexes of the chunks with different checksums.
+func CompareChunks(leader, follower []*Chunk) []int {
+	var res []int
+	for i := range leader {
+		if i >= len(follower) || leader[i].Count != follower[i].Count || leader[i].Checksum != follower[i].Checksum {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// DiffRegions returns the regions different in the digests of the leader and
+// the follower, ordered by the region ID.
+func DiffRegions(leader, follower []*RegionDigest) []*RegionDivergence {
+	diffs := make(map[uint64]*RegionDivergence)
+	followers := make(map[uint64]*RegionDigest, len(follower))
+	for _, d := range follower {
+		followers[d.ID] = d
+	}
+	for _, d := range leader {
+		f, ok := followers[d.ID]
+		if !ok || !d.equal(f) {
+			diffs[d.ID] = &RegionDivergence{ID: d.ID, Leader: d, Follower: f}
+		}
+		delete(followers, d.ID)
+	}
+	for _, f := range followers {
+		diffs[f.ID] = &RegionDivergence{ID: f.ID, Follower: f}
+	}
+	res := make([]*RegionDivergence, 0, len(diffs))
+	for _, d := range diffs {
+		res = append(res, d)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
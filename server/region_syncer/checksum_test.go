@@ -0,0 +1,84 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/server/core"
+)
+
+var _ = Suite(&testChecksumSuite{})
+
+type testChecksumSuite struct{}
+
+func newChecksumTestRegions(n int) []*core.RegionInfo {
+	regions := make([]*core.RegionInfo, 0, n)
+	for i := 0; i < n; i++ {
+		var start, end []byte
+		if i > 0 {
+			start = []byte{byte(i)}
+		}
+		if i < n-1 {
+			end = []byte{byte(i + 1)}
+		}
+		regions = append(regions, core.NewRegionInfo(&metapb.Region{
+			Id:          uint64(i + 1),
+			StartKey:    start,
+			EndKey:      end,
+			Peers:       []*metapb.Peer{{Id: uint64(100 + i), StoreId: 1}},
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}, nil))
+	}
+	return regions
+}
+
+func (t *testChecksumSuite) TestChunks(c *C) {
+	regions := newChecksumTestRegions(10)
+	chunks := NewChunks(regions, 4)
+	c.Assert(chunks, HasLen, 3)
+	c.Assert(chunks[0].StartKey, HasLen, 0)
+	c.Assert(chunks[0].EndKey, DeepEquals, []byte{4})
+	c.Assert(chunks[1].StartKey, DeepEquals, []byte{4})
+	c.Assert(chunks[2].EndKey, HasLen, 0)
+	c.Assert(chunks[0].Count+chunks[1].Count+chunks[2].Count, Equals, 10)
+	c.Assert(CompareChunks(chunks, ComputeChunks(regions, chunks)), HasLen, 0)
+	c.Assert(NewChunks(nil, 4), HasLen, 1)
+
+	// The follower misses a region and has a stale one.
+	follower := append([]*core.RegionInfo{}, regions[:5]...)
+	follower[1] = follower[1].Clone(core.WithIncConfVer())
+	follower = append(follower, regions[6:]...)
+	c.Assert(CompareChunks(chunks, ComputeChunks(follower, chunks)), DeepEquals, []int{0, 1})
+
+	var leaderDigests, followerDigests []*RegionDigest
+	for _, region := range regions {
+		leaderDigests = append(leaderDigests, NewRegionDigest(region))
+	}
+	for _, region := range follower {
+		followerDigests = append(followerDigests, NewRegionDigest(region))
+	}
+	diffs := DiffRegions(leaderDigests, followerDigests)
+	c.Assert(diffs, HasLen, 2)
+	c.Assert(diffs[0].ID, Equals, uint64(2))
+	c.Assert(diffs[0].Leader.ConfVer, Equals, uint64(1))
+	c.Assert(diffs[0].Follower.ConfVer, Equals, uint64(2))
+	c.Assert(diffs[1].ID, Equals, uint64(6))
+	c.Assert(diffs[1].Follower, IsNil)
+
+	// A region only in the follower.
+	diffs = DiffRegions(leaderDigests[:1], followerDigests[:2])
+	c.Assert(diffs, HasLen, 1)
+	c.Assert(diffs[0].Leader, IsNil)
+}
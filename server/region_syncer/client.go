@@ -161,6 +161,17 @@ func (s *RegionSyncer) StartSyncWithLeader(addr string) {
 			default:
 			}
 
+			// Sync the regions which differ from the leader first, unless the
+			// leader is too old to serve the snapshot.
+			if err := s.syncSnapshot(conn); err != nil && status.Code(errors.Cause(err)) != codes.Unimplemented {
+				if status.Code(errors.Cause(err)) == codes.Canceled {
+					return
+				}
+				log.Error("server failed to sync the snapshot of the regions with leader", zap.String("server", s.server.Name()), zap.String("leader", s.server.GetLeader().GetName()), errs.ZapError(err))
+				time.Sleep(time.Second)
+				continue
+			}
+
 			stream, err := s.syncRegion(conn)
 			if err != nil {
 				if ev, ok := status.FromError(err); ok {
@@ -192,36 +203,42 @@ func (s *RegionSyncer) StartSyncWithLeader(addr string) {
 					// reset index
 					s.history.ResetWithIndex(resp.GetStartIndex())
 				}
-				stats := resp.GetRegionStats()
-				regions := resp.GetRegions()
-				regionLeaders := resp.GetRegionLeaders()
-				hasStats := len(stats) == len(regions)
-				for i, r := range regions {
-					var (
-						region       *core.RegionInfo
-						regionLeader *metapb.Peer
-					)
-					if len(regionLeaders) > i && regionLeaders[i].Id != 0 {
-						regionLeader = regionLeaders[i]
-					}
-					if hasStats {
-						region = core.NewRegionInfo(r, regionLeader,
-							core.SetWrittenBytes(stats[i].BytesWritten),
-							core.SetWrittenKeys(stats[i].KeysWritten),
-							core.SetReadBytes(stats[i].BytesRead),
-							core.SetReadKeys(stats[i].KeysRead),
-						)
-					} else {
-						region = core.NewRegionInfo(r, regionLeader)
-					}
-
-					s.server.GetBasicCluster().CheckAndPutRegion(region)
-					err = s.server.GetStorage().SaveRegion(r)
-					if err == nil {
-						s.history.Record(region)
-					}
-				}
+				s.saveRegions(resp, true)
 			}
 		}
 	}()
 }
+
+// saveRegions puts the regions of the response into the cache and the
+// storage, and records them in the history if record is true.
+func (s *RegionSyncer) saveRegions(resp *pdpb.SyncRegionResponse, record bool) {
+	stats := resp.GetRegionStats()
+	regions := resp.GetRegions()
+	regionLeaders := resp.GetRegionLeaders()
+	hasStats := len(stats) == len(regions)
+	for i, r := range regions {
+		var (
+			region       *core.RegionInfo
+			regionLeader *metapb.Peer
+		)
+		if len(regionLeaders) > i && regionLeaders[i].Id != 0 {
+			regionLeader = regionLeaders[i]
+		}
+		if hasStats {
+			region = core.NewRegionInfo(r, regionLeader,
+				core.SetWrittenBytes(stats[i].BytesWritten),
+				core.SetWrittenKeys(stats[i].KeysWritten),
+				core.SetReadBytes(stats[i].BytesRead),
+				core.SetReadKeys(stats[i].KeysRead),
+			)
+		} else {
+			region = core.NewRegionInfo(r, regionLeader)
+		}
+
+		s.server.GetBasicCluster().CheckAndPutRegion(region)
+		err := s.server.GetStorage().SaveRegion(r)
+		if err == nil && record {
+			s.history.Record(region)
+		}
+	}
+}
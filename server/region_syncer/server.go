@@ -25,6 +25,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/eventbus"
 	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/server/core"
 	"go.uber.org/zap"
@@ -58,11 +59,13 @@ type Server interface {
 	ClusterID() uint64
 	GetMemberInfo() *pdpb.Member
 	GetLeader() *pdpb.Member
+	IsLeader() bool
 	GetStorage() *core.Storage
 	Name() string
 	GetRegions() []*core.RegionInfo
 	GetTLSConfig() *grpcutil.TLSConfig
 	GetBasicCluster() *core.BasicCluster
+	GetEventBus() *eventbus.Bus
 }
 
 // RegionSyncer is used to sync the region information without raft.
@@ -75,6 +78,7 @@ type RegionSyncer struct {
 		closed             chan struct{}
 	}
 	server    Server
+	bus       *eventbus.Bus
 	wg        sync.WaitGroup
 	history   *historyBuffer
 	limit     *ratelimit.Bucket
@@ -89,6 +93,7 @@ type RegionSyncer struct {
 func NewRegionSyncer(s Server) *RegionSyncer {
 	syncer := &RegionSyncer{
 		server:    s,
+		bus:       s.GetEventBus(),
 		history:   newHistoryBuffer(defaultHistoryBufferSize, s.GetStorage().GetRegionStorage()),
 		limit:     ratelimit.NewBucketWithRate(defaultBucketRate, defaultBucketCapacity),
 		tlsConfig: s.GetTLSConfig(),
@@ -256,6 +261,7 @@ func (s *RegionSyncer) bindStream(name string, stream ServerStream) {
 }
 
 func (s *RegionSyncer) broadcast(regions *pdpb.SyncRegionResponse) {
+	s.notifyWatchers(regions)
 	var failed []string
 	s.mu.RLock()
 	for name, sender := range s.mu.streams {
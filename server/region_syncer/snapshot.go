@@ -0,0 +1,318 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/protoext"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The field numbers of SnapshotRequest.
+const (
+	snapshotRequestHeaderField     = 1
+	snapshotRequestStartIndexField = 2
+	snapshotRequestChunksField     = 3
+)
+
+// The field numbers of the chunks of SnapshotRequest.
+const (
+	chunkStartKeyField = 1
+	chunkEndKeyField   = 2
+	chunkCountField    = 3
+	chunkChecksumField = 4
+)
+
+// SnapshotRequest requests the regions which differ from the regions of a
+// follower, which is not a part of kvproto. It is encoded as the message:
+//
+//	message SnapshotRequest {
+//	    pdpb.RequestHeader header = 1;
+//	    uint64 start_index = 2;
+//	    repeated Chunk chunks = 3;
+//	}
+//
+//	message Chunk {
+//	    bytes start_key = 1;
+//	    bytes end_key = 2;
+//	    uint64 count = 3;
+//	    fixed64 checksum = 4;
+//	}
+type SnapshotRequest struct {
+	Header *pdpb.RequestHeader
+	// StartIndex is the history index of the follower. No region is sent if
+	// the leader still has the records from it.
+	StartIndex uint64
+	// Chunks are the checksums of the regions of the follower, which are
+	// ordered and cover the whole key space.
+	Chunks []*Chunk
+}
+
+// Reset implements proto.Message.
+func (m *SnapshotRequest) Reset() { *m = SnapshotRequest{} }
+
+// String implements proto.Message.
+func (m *SnapshotRequest) String() string {
+	return fmt.Sprintf("SnapshotRequest{start_index:%d chunks:%d}", m.StartIndex, len(m.Chunks))
+}
+
+// ProtoMessage implements proto.Message.
+func (*SnapshotRequest) ProtoMessage() {}
+
+// GetHeader returns the header of the request.
+func (m *SnapshotRequest) GetHeader() *pdpb.RequestHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+// Marshal implements proto.Marshaler.
+func (m *SnapshotRequest) Marshal() ([]byte, error) {
+	var data []byte
+	if m.Header != nil {
+		b, err := m.Header.Marshal()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		data = protoext.AppendBytesField(data, snapshotRequestHeaderField, b)
+	}
+	data = protoext.AppendVarintField(data, snapshotRequestStartIndexField, m.StartIndex)
+	for _, chunk := range m.Chunks {
+		var b []byte
+		b = protoext.AppendBytesField(b, chunkStartKeyField, chunk.StartKey)
+		b = protoext.AppendBytesField(b, chunkEndKeyField, chunk.EndKey)
+		b = protoext.AppendVarintField(b, chunkCountField, uint64(chunk.Count))
+		b = protoext.AppendFixed64Field(b, chunkChecksumField, chunk.Checksum)
+		data = protoext.AppendBytesField(data, snapshotRequestChunksField, b)
+	}
+	return data, nil
+}
+
+// Unmarshal implements proto.Unmarshaler. The unknown fields are ignored.
+func (m *SnapshotRequest) Unmarshal(data []byte) error {
+	m.Reset()
+	for len(data) > 0 {
+		fieldNum, wireType, value, n, err := protoext.DecodeField(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+		switch {
+		case fieldNum == snapshotRequestHeaderField && wireType == protoext.WireBytes:
+			m.Header = &pdpb.RequestHeader{}
+			if err := m.Header.Unmarshal(value); err != nil {
+				return errors.WithStack(err)
+			}
+		case fieldNum == snapshotRequestStartIndexField && wireType == protoext.WireVarint:
+			m.StartIndex, _ = proto.DecodeVarint(value)
+		case fieldNum == snapshotRequestChunksField && wireType == protoext.WireBytes:
+			chunk, err := unmarshalChunk(value)
+			if err != nil {
+				return err
+			}
+			m.Chunks = append(m.Chunks, chunk)
+		}
+	}
+	return nil
+}
+
+func unmarshalChunk(data []byte) (*Chunk, error) {
+	chunk := &Chunk{}
+	for len(data) > 0 {
+		fieldNum, wireType, value, n, err := protoext.DecodeField(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[n:]
+		switch {
+		case fieldNum == chunkStartKeyField && wireType == protoext.WireBytes:
+			chunk.StartKey = append([]byte(nil), value...)
+		case fieldNum == chunkEndKeyField && wireType == protoext.WireBytes:
+			chunk.EndKey = append([]byte(nil), value...)
+		case fieldNum == chunkCountField && wireType == protoext.WireVarint:
+			count, _ := proto.DecodeVarint(value)
+			chunk.Count = int(count)
+		case fieldNum == chunkChecksumField && wireType == protoext.WireFixed64:
+			chunk.Checksum = binary.LittleEndian.Uint64(value)
+		}
+	}
+	return chunk, nil
+}
+
+// checkChunks returns an error unless the chunks are ordered and cover the
+// whole key space.
+func checkChunks(chunks []*Chunk) error {
+	if len(chunks) == 0 || len(chunks[0].StartKey) != 0 || len(chunks[len(chunks)-1].EndKey) != 0 {
+		return errors.New("the chunks do not cover the whole key space")
+	}
+	for i := 1; i < len(chunks); i++ {
+		if len(chunks[i].StartKey) == 0 || !bytes.Equal(chunks[i-1].EndKey, chunks[i].StartKey) {
+			return errors.Errorf("chunk %d does not follow the previous one", i)
+		}
+	}
+	return nil
+}
+
+// canResumeFrom returns true if the history has all the records from the
+// index.
+func (h *historyBuffer) canResumeFrom(index uint64) bool {
+	h.RLock()
+	defer h.RUnlock()
+	return index == h.nextIndex() || (index >= h.firstIndex() && index < h.nextIndex())
+}
+
+// SyncSnapshot sends the regions in the chunks whose checksums differ from the
+// chunks of the follower, which are the regions the follower misses or has
+// stale. The regions the follower has but the leader does not are replaced by
+// the overlapping regions sent. Each response carries the history index taken
+// before the snapshot, to sync the rest from with SyncRegions. If the history
+// still has the records from the start index of the follower, no region is
+// sent and the start index is carried instead.
+func (s *RegionSyncer) SyncSnapshot(request *SnapshotRequest, send func(*pdpb.SyncRegionResponse) error) error {
+	header := &pdpb.ResponseHeader{ClusterId: s.server.ClusterID()}
+	if s.history.canResumeFrom(request.StartIndex) {
+		return send(&pdpb.SyncRegionResponse{Header: header, StartIndex: request.StartIndex})
+	}
+	if err := checkChunks(request.Chunks); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	start := time.Now()
+	// Take the index first, so the changes during the snapshot are synced
+	// again from the history.
+	index := s.history.GetNextIndex()
+	regions := s.server.GetBasicCluster().ScanRange(nil, nil, 0)
+	divergent := make([]bool, len(request.Chunks))
+	for _, i := range CompareChunks(ComputeChunks(regions, request.Chunks), request.Chunks) {
+		divergent[i] = true
+	}
+	resp := &pdpb.SyncRegionResponse{Header: header, StartIndex: index}
+	sent, count := 0, 0
+	flush := func() error {
+		s.limit.Wait(int64(resp.Size()))
+		if err := send(resp); err != nil {
+			return err
+		}
+		sent++
+		resp = &pdpb.SyncRegionResponse{Header: header, StartIndex: index}
+		return nil
+	}
+	for _, region := range regions {
+		if i := chunkOf(request.Chunks, region.GetStartKey()); i < 0 || !divergent[i] {
+			continue
+		}
+		leader := region.GetLeader()
+		if leader == nil {
+			leader = &metapb.Peer{}
+		}
+		resp.Regions = append(resp.Regions, region.GetMeta())
+		resp.RegionStats = append(resp.RegionStats, region.GetStat())
+		resp.RegionLeaders = append(resp.RegionLeaders, leader)
+		count++
+		if len(resp.Regions) >= maxSyncRegionBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(resp.Regions) > 0 || sent == 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	log.Info("requested server has completed the snapshot synchronization with server",
+		zap.String("server", s.server.Name()),
+		zap.Uint64("start-index", index),
+		zap.Int("chunks", len(request.Chunks)),
+		zap.Int("regions", count),
+		zap.Duration("cost", time.Since(start)))
+	return nil
+}
+
+// syncSnapshot syncs the regions which differ from the leader by the checksums
+// of the chunks, and then resets the history index to the index of the
+// snapshot, so that the rest is synced from the history of the leader.
+func (s *RegionSyncer) syncSnapshot(conn *grpc.ClientConn) error {
+	s.mu.RLock()
+	ctx := s.mu.regionSyncerCtx
+	s.mu.RUnlock()
+	if ctx == nil {
+		return errors.New("syncSnapshot failed due to regionSyncerCtx is nil")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	regions := s.server.GetBasicCluster().ScanRange(nil, nil, 0)
+	request := &SnapshotRequest{
+		Header:     &pdpb.RequestHeader{ClusterId: s.server.ClusterID()},
+		StartIndex: s.history.GetNextIndex(),
+		Chunks:     NewChunks(regions, ChecksumChunkSize),
+	}
+	stream, err := conn.NewStream(ctx, grpcutil.SyncRegionSnapshotStreamDesc, grpcutil.SyncRegionSnapshotStreamMethod)
+	if err != nil {
+		return errs.ErrGRPCCreateStream.Wrap(err).FastGenWithCause()
+	}
+	if err := stream.SendMsg(request); err != nil {
+		return errs.ErrGRPCSend.Wrap(err).FastGenWithCause()
+	}
+	if err := stream.CloseSend(); err != nil {
+		return errs.ErrGRPCCloseSend.Wrap(err).FastGenWithCause()
+	}
+	var index uint64
+	received, count := false, 0
+	for {
+		resp := &pdpb.SyncRegionResponse{}
+		err := stream.RecvMsg(resp)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errs.ErrGRPCRecv.Wrap(err).FastGenWithCause()
+		}
+		if pdErr := resp.GetHeader().GetError(); pdErr != nil {
+			return errors.Errorf("sync the snapshot of the regions failed: %s", pdErr.GetMessage())
+		}
+		index, received = resp.GetStartIndex(), true
+		s.saveRegions(resp, false)
+		count += len(resp.GetRegions())
+	}
+	if !received {
+		return errors.New("no snapshot of the regions is received")
+	}
+	if index != request.StartIndex {
+		s.history.ResetWithIndex(index)
+	}
+	log.Info("server has synchronized the snapshot of the regions with leader",
+		zap.String("server", s.server.Name()),
+		zap.Uint64("start-index", index),
+		zap.Int("regions", count))
+	return nil
+}
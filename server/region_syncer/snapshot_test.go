@@ -0,0 +1,123 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"github.com/juju/ratelimit"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/kv"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Suite(&testSnapshotSuite{})
+
+type testSnapshotSuite struct{}
+
+type mockSnapshotServer struct {
+	Server
+	basicCluster *core.BasicCluster
+}
+
+func (s *mockSnapshotServer) ClusterID() uint64                   { return 1 }
+func (s *mockSnapshotServer) Name() string                        { return "pd1" }
+func (s *mockSnapshotServer) GetBasicCluster() *core.BasicCluster { return s.basicCluster }
+
+func (t *testSnapshotSuite) TestSnapshotRequest(c *C) {
+	request := &SnapshotRequest{
+		Header:     &pdpb.RequestHeader{ClusterId: 1},
+		StartIndex: 10,
+		Chunks: []*Chunk{
+			{EndKey: []byte("a"), Count: 2, Checksum: 1 << 63},
+			{StartKey: []byte("a"), Count: 1, Checksum: 3},
+		},
+	}
+	data, err := request.Marshal()
+	c.Assert(err, IsNil)
+	decoded := &SnapshotRequest{}
+	c.Assert(decoded.Unmarshal(data), IsNil)
+	c.Assert(decoded.GetHeader().GetClusterId(), Equals, uint64(1))
+	c.Assert(decoded.StartIndex, Equals, uint64(10))
+	c.Assert(decoded.Chunks, HasLen, 2)
+	c.Assert(decoded.Chunks[0].StartKey, HasLen, 0)
+	c.Assert(decoded.Chunks[0].EndKey, DeepEquals, []byte("a"))
+	c.Assert(decoded.Chunks[0].Count, Equals, 2)
+	c.Assert(decoded.Chunks[0].Checksum, Equals, uint64(1<<63))
+	c.Assert(decoded.Chunks[1].StartKey, DeepEquals, []byte("a"))
+	c.Assert(decoded.Chunks[1].Checksum, Equals, uint64(3))
+	c.Assert(checkChunks(decoded.Chunks), IsNil)
+
+	c.Assert(checkChunks(nil), NotNil)
+	c.Assert(checkChunks(decoded.Chunks[:1]), NotNil)
+	c.Assert(checkChunks(decoded.Chunks[1:]), NotNil)
+	c.Assert(checkChunks([]*Chunk{{EndKey: []byte("a")}, {StartKey: []byte("b")}}), NotNil)
+}
+
+func (t *testSnapshotSuite) TestSyncSnapshot(c *C) {
+	regions := newChecksumTestRegions(10)
+	bc := core.NewBasicCluster()
+	for _, region := range regions {
+		bc.PutRegion(region)
+	}
+	s := &RegionSyncer{
+		server:  &mockSnapshotServer{basicCluster: bc},
+		history: newHistoryBuffer(3, kv.NewMemoryKV()),
+		limit:   ratelimit.NewBucketWithRate(defaultBucketRate, defaultBucketCapacity),
+	}
+	for _, region := range regions[:5] {
+		s.history.Record(region)
+	}
+	var resps []*pdpb.SyncRegionResponse
+	send := func(resp *pdpb.SyncRegionResponse) error {
+		resps = append(resps, resp)
+		return nil
+	}
+
+	// The follower resumes from the history.
+	c.Assert(s.SyncSnapshot(&SnapshotRequest{StartIndex: 3}, send), IsNil)
+	c.Assert(resps, HasLen, 1)
+	c.Assert(resps[0].GetStartIndex(), Equals, uint64(3))
+	c.Assert(resps[0].GetRegions(), HasLen, 0)
+
+	// Only the regions of the divergent chunks are sent, where the follower
+	// has a stale region and misses a region.
+	follower := append([]*core.RegionInfo{}, regions[:6]...)
+	follower[1] = follower[1].Clone(core.WithIncConfVer())
+	follower = append(follower, regions[7:]...)
+	request := &SnapshotRequest{StartIndex: 0, Chunks: NewChunks(follower, 4)}
+	c.Assert(request.Chunks, HasLen, 3)
+	resps = nil
+	c.Assert(s.SyncSnapshot(request, send), IsNil)
+	c.Assert(resps, HasLen, 1)
+	c.Assert(resps[0].GetStartIndex(), Equals, uint64(5))
+	var ids []uint64
+	for _, region := range resps[0].GetRegions() {
+		ids = append(ids, region.GetId())
+	}
+	c.Assert(ids, DeepEquals, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	c.Assert(resps[0].GetRegionLeaders(), HasLen, 9)
+
+	// Nothing but the index is sent if the follower is in sync.
+	resps = nil
+	c.Assert(s.SyncSnapshot(&SnapshotRequest{Chunks: NewChunks(regions, 4)}, send), IsNil)
+	c.Assert(resps, HasLen, 1)
+	c.Assert(resps[0].GetStartIndex(), Equals, uint64(5))
+	c.Assert(resps[0].GetRegions(), HasLen, 0)
+
+	// The chunks must cover the whole key space.
+	err := s.SyncSnapshot(&SnapshotRequest{Chunks: request.Chunks[1:]}, send)
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/eventbus"
+	"github.com/tikv/pd/server/core"
+)
+
+// watcherBufferSize is the number of batches a watcher can fall behind before
+// it is stopped.
+const watcherBufferSize = 64
+
+// regionWatcherName is the name of the region watchers on the event bus.
+const regionWatcherName = "region-watcher"
+
+type regionWatcher struct {
+	startKey, endKey []byte
+}
+
+func (w *regionWatcher) overlaps(region *metapb.Region) bool {
+	return (len(w.endKey) == 0 || bytes.Compare(region.GetStartKey(), w.endKey) < 0) &&
+		(len(region.GetEndKey()) == 0 || bytes.Compare(region.GetEndKey(), w.startKey) > 0)
+}
+
+// filter returns the response with the regions in the key range only, and
+// the start index set to the index following the batch.
+func (w *regionWatcher) filter(resp *pdpb.SyncRegionResponse) *pdpb.SyncRegionResponse {
+	filtered := &pdpb.SyncRegionResponse{
+		Header:     resp.GetHeader(),
+		StartIndex: resp.GetStartIndex() + uint64(len(resp.GetRegions())),
+	}
+	stats, leaders := resp.GetRegionStats(), resp.GetRegionLeaders()
+	for i, region := range resp.GetRegions() {
+		if !w.overlaps(region) {
+			continue
+		}
+		filtered.Regions = append(filtered.Regions, region)
+		if i < len(stats) {
+			filtered.RegionStats = append(filtered.RegionStats, stats[i])
+		}
+		if i < len(leaders) {
+			filtered.RegionLeaders = append(filtered.RegionLeaders, leaders[i])
+		}
+	}
+	return filtered
+}
+
+// WatchRegions sends the changes of the regions overlapping the key range,
+// including creations, splits, merges and leader changes, starting from the
+// start index if resume is true. The start index of each response is the index
+// to resume watching from. If resume is false, the watch starts from now, and
+// the first response carries no region but the current index. It returns
+// ErrRegionWatcherLeaderLost once the server is no longer the leader.
+func (s *RegionSyncer) WatchRegions(ctx context.Context, startKey, endKey []byte, startIndex uint64, resume bool, send func(*pdpb.SyncRegionResponse) error) error {
+	w := &regionWatcher{startKey: startKey, endKey: endKey}
+	// Subscribe before getting the next index, so that no batch is missed. The
+	// leadership events share the subscription, so that each watch holds a
+	// single queue on the bus.
+	sub := s.bus.Subscribe(regionWatcherName, watcherBufferSize, eventbus.TopicRegion, eventbus.TopicLeader)
+	defer sub.Close()
+	if !s.server.IsLeader() {
+		return errs.ErrRegionWatcherLeaderLost.FastGenByArgs()
+	}
+
+	header := &pdpb.ResponseHeader{ClusterId: s.server.ClusterID()}
+	nextIndex := s.history.GetNextIndex()
+	first := &pdpb.SyncRegionResponse{Header: header, StartIndex: nextIndex}
+	if resume && startIndex != nextIndex {
+		records := s.history.RecordsFrom(startIndex)
+		if len(records) == 0 {
+			return errs.ErrRegionHistoryCompacted.FastGenByArgs(startIndex)
+		}
+		first = w.filter(newSyncRegionResponse(header, startIndex, records))
+	}
+	if err := send(first); err != nil {
+		return errors.WithStack(err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-sub.Stopped():
+			return errs.ErrRegionWatcherTooSlow.FastGenByArgs()
+		case event := <-sub.Events():
+			if event.Topic == eventbus.TopicLeader {
+				// The event of becoming the leader may be queued before the
+				// check above, only the loss of the leadership ends the watch.
+				if !event.Payload.(*eventbus.LeaderEvent).IsLeader {
+					return errs.ErrRegionWatcherLeaderLost.FastGenByArgs()
+				}
+				continue
+			}
+			resp := event.Payload.(*pdpb.SyncRegionResponse)
+			filtered := w.filter(resp)
+			// Skip the batches without any region in the range, except the
+			// keepalive ones which tell the watcher the current index.
+			if len(filtered.GetRegions()) == 0 && len(resp.GetRegions()) > 0 {
+				continue
+			}
+			if err := send(filtered); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+}
+
+func newSyncRegionResponse(header *pdpb.ResponseHeader, startIndex uint64, records []*core.RegionInfo) *pdpb.SyncRegionResponse {
+	resp := &pdpb.SyncRegionResponse{
+		Header:        header,
+		StartIndex:    startIndex,
+		Regions:       make([]*metapb.Region, len(records)),
+		RegionStats:   make([]*pdpb.RegionStat, len(records)),
+		RegionLeaders: make([]*metapb.Peer, len(records)),
+	}
+	for i, r := range records {
+		resp.Regions[i] = r.GetMeta()
+		resp.RegionStats[i] = r.GetStat()
+		resp.RegionLeaders[i] = &metapb.Peer{}
+		if r.GetLeader() != nil {
+			resp.RegionLeaders[i] = r.GetLeader()
+		}
+	}
+	return resp
+}
+
+// notifyWatchers publishes the changed regions to the watchers on the event
+// bus.
+func (s *RegionSyncer) notifyWatchers(resp *pdpb.SyncRegionResponse) {
+	if !s.bus.HasSubscribers(eventbus.TopicRegion) {
+		return
+	}
+	// The slices of the response are reused by the syncer after it is
+	// broadcast, so the watchers get a copy.
+	s.bus.Publish(eventbus.TopicRegion, &pdpb.SyncRegionResponse{
+		Header:        resp.GetHeader(),
+		StartIndex:    resp.GetStartIndex(),
+		Regions:       append([]*metapb.Region(nil), resp.GetRegions()...),
+		RegionStats:   append([]*pdpb.RegionStat(nil), resp.GetRegionStats()...),
+		RegionLeaders: append([]*metapb.Peer(nil), resp.GetRegionLeaders()...),
+	})
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/eventbus"
+)
+
+var _ = Suite(&testRegionWatcher{})
+
+type testRegionWatcher struct{}
+
+func (t *testRegionWatcher) TestFilter(c *C) {
+	w := &regionWatcher{startKey: []byte("b"), endKey: []byte("d")}
+	regions := []*metapb.Region{
+		{Id: 1, StartKey: []byte(""), EndKey: []byte("b")},
+		{Id: 2, StartKey: []byte("a"), EndKey: []byte("c")},
+		{Id: 3, StartKey: []byte("c"), EndKey: []byte("")},
+		{Id: 4, StartKey: []byte("d"), EndKey: []byte("")},
+	}
+	leaders := []*metapb.Peer{{Id: 11}, {Id: 12}, {Id: 13}, {Id: 14}}
+	resp := w.filter(&pdpb.SyncRegionResponse{StartIndex: 10, Regions: regions, RegionLeaders: leaders})
+	c.Assert(resp.GetStartIndex(), Equals, uint64(14))
+	c.Assert(resp.GetRegions(), DeepEquals, regions[1:3])
+	c.Assert(resp.GetRegionLeaders(), DeepEquals, leaders[1:3])
+
+	// The whole key space.
+	w = &regionWatcher{}
+	c.Assert(w.filter(&pdpb.SyncRegionResponse{Regions: regions}).GetRegions(), HasLen, 4)
+}
+
+func (t *testRegionWatcher) TestNotifyWatchers(c *C) {
+	s := &RegionSyncer{bus: eventbus.NewBus()}
+	// Nothing is published without any watcher.
+	s.notifyWatchers(&pdpb.SyncRegionResponse{StartIndex: 1})
+
+	sub := s.bus.Subscribe(regionWatcherName, 1, eventbus.TopicRegion)
+	defer sub.Close()
+	regions := []*metapb.Region{{Id: 1}}
+	s.notifyWatchers(&pdpb.SyncRegionResponse{StartIndex: 2, Regions: regions})
+	c.Assert(sub.Events(), HasLen, 1)
+	resp := (<-sub.Events()).Payload.(*pdpb.SyncRegionResponse)
+	c.Assert(resp.GetStartIndex(), Equals, uint64(2))
+	c.Assert(resp.GetRegions(), DeepEquals, regions)
+	// The watcher gets a copy of the regions.
+	regions[0] = &metapb.Region{Id: 2}
+	c.Assert(resp.GetRegions()[0].GetId(), Equals, uint64(1))
+}
@@ -0,0 +1,283 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/server/core"
+	syncer "github.com/tikv/pd/server/region_syncer"
+	"go.uber.org/zap"
+)
+
+const (
+	// regionSyncerChunksURL is the API which returns the checksum chunks of
+	// the regions of the leader.
+	regionSyncerChunksURL = "/pd/api/v1/admin/region-syncer/chunks"
+	// regionSyncerDigestsURL is the API which returns the digests of the
+	// regions of the leader in a key range.
+	regionSyncerDigestsURL = "/pd/api/v1/admin/region-syncer/digests"
+	// regionSyncerVerifyCheckInterval is the interval to check the config
+	// again if the verification is disabled.
+	regionSyncerVerifyCheckInterval = 10 * time.Second
+	// regionSyncerVerifyGracePeriod is the time to wait before comparing the
+	// divergent chunks again, as the regions may be still in flight.
+	regionSyncerVerifyGracePeriod = 3 * time.Second
+	// maxDiffChunks is the max number of the divergent chunks whose regions
+	// are compared one by one in a verification.
+	maxDiffChunks = 16
+)
+
+// regionSyncerVerifier verifies the regions synced from the leader by the
+// region syncer periodically on a follower. The follower computes the
+// checksums of the key ranges of the leader's chunks, and compares the
+// regions of the divergent chunks one by one.
+type regionSyncerVerifier struct {
+	s *Server
+	// gracePeriod is the time to wait before comparing the divergent chunks
+	// again. Only the chunks divergent in both rounds are reported.
+	gracePeriod time.Duration
+
+	// mu serializes the verifications.
+	mu     sync.Mutex
+	lastMu sync.RWMutex
+	last   *syncer.VerifyReport
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func newRegionSyncerVerifier(s *Server) *regionSyncerVerifier {
+	return &regionSyncerVerifier{s: s, gracePeriod: regionSyncerVerifyGracePeriod}
+}
+
+// startWithLeader starts to verify the regions periodically.
+func (v *regionSyncerVerifier) startWithLeader(ctx context.Context, leader string) {
+	ctx, v.cancel = context.WithCancel(ctx)
+	v.wg.Add(1)
+	go v.verifyLoop(ctx, leader)
+}
+
+// stopWithLeader stops the verification. The last report is kept.
+func (v *regionSyncerVerifier) stopWithLeader() {
+	if v.cancel == nil {
+		return
+	}
+	v.cancel()
+	v.wg.Wait()
+	v.cancel = nil
+}
+
+func (v *regionSyncerVerifier) verifyLoop(ctx context.Context, leader string) {
+	defer logutil.LogPanic()
+	defer v.wg.Done()
+
+	for {
+		interval := v.s.persistOptions.GetRegionSyncerVerifyInterval()
+		if interval > 0 && v.s.persistOptions.IsUseRegionStorage() {
+			if _, err := v.verify(ctx, leader); err != nil {
+				log.Warn("failed to verify the regions synced from leader", zap.String("leader", leader), errs.ZapError(err))
+			}
+		} else {
+			interval = regionSyncerVerifyCheckInterval
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (v *regionSyncerVerifier) verify(ctx context.Context, leader string) (*syncer.VerifyReport, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	chunks, local, regionCount, diff, err := v.compare(ctx, leader)
+	if err != nil {
+		return nil, err
+	}
+	if len(diff) > 0 && v.gracePeriod > 0 {
+		first := make([]*syncer.Chunk, 0, len(diff))
+		for _, i := range diff {
+			first = append(first, chunks[i])
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(v.gracePeriod):
+		}
+		if chunks, local, regionCount, diff, err = v.compare(ctx, leader); err != nil {
+			return nil, err
+		}
+		diff = filterOverlappedChunks(chunks, diff, first)
+	}
+	report := &syncer.VerifyReport{
+		Name:          v.s.Name(),
+		Leader:        leader,
+		VerifyTime:    time.Now(),
+		ChunkCount:    len(chunks),
+		FollowerCount: regionCount,
+		Divergences:   make([]*syncer.ChunkDivergence, 0),
+	}
+	for _, chunk := range chunks {
+		report.LeaderCount += chunk.Count
+	}
+	for n, i := range diff {
+		d := &syncer.ChunkDivergence{
+			StartKey:      core.HexRegionKeyStr(chunks[i].StartKey),
+			EndKey:        core.HexRegionKeyStr(chunks[i].EndKey),
+			LeaderCount:   chunks[i].Count,
+			FollowerCount: local[i].Count,
+		}
+		if n < maxDiffChunks {
+			query := url.Values{}
+			query.Set("start_key", hex.EncodeToString(chunks[i].StartKey))
+			query.Set("end_key", hex.EncodeToString(chunks[i].EndKey))
+			var digests []*syncer.RegionDigest
+			if err := v.getFromLeader(ctx, leader+regionSyncerDigestsURL+"?"+query.Encode(), &digests); err != nil {
+				return nil, err
+			}
+			d.Regions = syncer.DiffRegions(digests, getRegionDigests(v.s.basicCluster, chunks[i].StartKey, chunks[i].EndKey))
+		}
+		report.Divergences = append(report.Divergences, d)
+	}
+	regionSyncerDivergentChunksGauge.Set(float64(len(report.Divergences)))
+	if len(report.Divergences) > 0 {
+		log.Warn("the regions synced from leader diverge",
+			zap.String("leader", leader),
+			zap.Int("divergent-chunks", len(report.Divergences)),
+			zap.Int("leader-region-count", report.LeaderCount),
+			zap.Int("region-count", report.FollowerCount))
+	}
+	v.lastMu.Lock()
+	v.last = report
+	v.lastMu.Unlock()
+	return report, nil
+}
+
+// compare fetches the chunks of the leader and returns them with the local
+// chunks, the count of the local regions and the indexes of the divergent
+// chunks.
+func (v *regionSyncerVerifier) compare(ctx context.Context, leader string) (chunks, local []*syncer.Chunk, regionCount int, diff []int, err error) {
+	if err = v.getFromLeader(ctx, leader+regionSyncerChunksURL, &chunks); err != nil {
+		return nil, nil, 0, nil, err
+	}
+	regions := v.s.basicCluster.ScanRange(nil, nil, 0)
+	local = syncer.ComputeChunks(regions, chunks)
+	return chunks, local, len(regions), syncer.CompareChunks(chunks, local), nil
+}
+
+// filterOverlappedChunks returns the indexes in diff of the chunks which
+// overlap any of the given chunks.
+func filterOverlappedChunks(chunks []*syncer.Chunk, diff []int, others []*syncer.Chunk) []int {
+	res := make([]int, 0, len(diff))
+	for _, i := range diff {
+		for _, other := range others {
+			if chunksOverlap(chunks[i], other) {
+				res = append(res, i)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// chunksOverlap returns whether the key ranges of the chunks overlap. An
+// empty end key means the end of the key space.
+func chunksOverlap(a, b *syncer.Chunk) bool {
+	return (len(b.EndKey) == 0 || bytes.Compare(a.StartKey, b.EndKey) < 0) &&
+		(len(a.EndKey) == 0 || bytes.Compare(b.StartKey, a.EndKey) < 0)
+}
+
+func (v *regionSyncerVerifier) getFromLeader(ctx context.Context, addr string, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
+	if err != nil {
+		return errs.ErrSendRequest.Wrap(err).GenWithStackByCause()
+	}
+	resp, err := v.s.httpClient.Do(req)
+	if err != nil {
+		return errs.ErrSendRequest.Wrap(err).GenWithStackByCause()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errs.ErrSendRequest.FastGenByArgs()
+	}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return nil
+}
+
+func (v *regionSyncerVerifier) getLastReport() *syncer.VerifyReport {
+	v.lastMu.RLock()
+	defer v.lastMu.RUnlock()
+	return v.last
+}
+
+// getRegionDigests returns the digests of the regions starting in the key
+// range [startKey, endKey).
+func getRegionDigests(bc *core.BasicCluster, startKey, endKey []byte) []*syncer.RegionDigest {
+	res := make([]*syncer.RegionDigest, 0)
+	for _, region := range bc.ScanRange(startKey, endKey, 0) {
+		if bytes.Compare(region.GetStartKey(), startKey) >= 0 {
+			res = append(res, syncer.NewRegionDigest(region))
+		}
+	}
+	return res
+}
+
+// GetRegionSyncerChunks returns the checksum chunks of the regions of the
+// server, which are compared by the followers with the regions synced.
+func (s *Server) GetRegionSyncerChunks() []*syncer.Chunk {
+	return syncer.NewChunks(s.basicCluster.ScanRange(nil, nil, 0), syncer.ChecksumChunkSize)
+}
+
+// GetRegionSyncerDigests returns the digests of the regions of the server
+// starting in the key range [startKey, endKey).
+func (s *Server) GetRegionSyncerDigests(startKey, endKey []byte) []*syncer.RegionDigest {
+	return getRegionDigests(s.basicCluster, startKey, endKey)
+}
+
+// VerifyRegionSyncer verifies the regions synced from the leader now. It can
+// only be called on a follower with the region storage enabled.
+func (s *Server) VerifyRegionSyncer(ctx context.Context) (*syncer.VerifyReport, error) {
+	if s.member.IsLeader() {
+		return nil, errors.New("the leader is the source of the region syncer")
+	}
+	if !s.persistOptions.IsUseRegionStorage() {
+		return nil, errors.New("the region syncer is disabled as use-region-storage is false")
+	}
+	leader := s.GetLeader()
+	if leader == nil || len(leader.GetClientUrls()) == 0 {
+		return nil, errors.New("no leader")
+	}
+	return s.regionSyncerVerifier.verify(ctx, leader.GetClientUrls()[0])
+}
+
+// GetRegionSyncerVerifyReport returns the report of the last verification of
+// the regions synced from the leader, or nil if there is none.
+func (s *Server) GetRegionSyncerVerifyReport() *syncer.VerifyReport {
+	return s.regionSyncerVerifier.getLastReport()
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// regionWatchService pushes the changes of the regions in a key range, so that
+// a region cache can be kept warm without polling.
+var regionWatchService = sideService{
+	name:   grpcutil.RegionWatchServiceName,
+	checks: defaultGRPCChecks,
+	streams: []sideStream{
+		{
+			name:       grpcutil.WatchRegionsStreamName,
+			newRequest: func() interface{} { return new(pdpb.ScanRegionsRequest) },
+			handle: func(s *Server, req interface{}, stream grpc.ServerStream) error {
+				return s.WatchRegions(req.(*pdpb.ScanRegionsRequest), stream)
+			},
+		},
+	},
+}
+
+// WatchRegions pushes the changes of the regions in the key range of the
+// request, derived from the history of the region syncer. Each response is a
+// SyncRegionResponse whose start index is the index to resume watching from
+// with a new stream. The stream is closed once the server is no longer the
+// leader.
+func (s *Server) WatchRegions(request *pdpb.ScanRegionsRequest, stream grpc.ServerStream) error {
+	rc := s.GetRaftCluster()
+	if rc == nil {
+		return stream.SendMsg(&pdpb.SyncRegionResponse{Header: s.notBootstrappedHeader()})
+	}
+
+	startIndex, resume := grpcutil.GetWatchStartIndex(stream.Context())
+	err := rc.GetRegionSyncer().WatchRegions(stream.Context(), request.GetStartKey(), request.GetEndKey(), startIndex, resume,
+		func(resp *pdpb.SyncRegionResponse) error {
+			return stream.SendMsg(resp)
+		})
+	switch {
+	case errs.ErrRegionWatcherLeaderLost.Equal(err):
+		return errors.WithStack(s.notLeaderError())
+	case errs.ErrRegionHistoryCompacted.Equal(err):
+		return status.Error(codes.OutOfRange, err.Error())
+	case errs.ErrRegionWatcherTooSlow.Equal(err):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return err
+}
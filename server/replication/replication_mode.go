@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -78,6 +79,10 @@ type ModeManager struct {
 	drTotalRegion        int // number of all regions
 
 	drMemberWaitAsyncTime map[uint64]time.Time // last sync time with follower nodes
+
+	// statusChanged is closed and replaced once the replication status to
+	// sync with tikv servers may have changed.
+	statusChanged chan struct{}
 }
 
 // NewReplicationModeManager creates the replicate mode manager.
@@ -89,6 +94,7 @@ func NewReplicationModeManager(config config.ReplicationModeConfig, storage *cor
 		cluster:               cluster,
 		fileReplicater:        fileReplicater,
 		drMemberWaitAsyncTime: make(map[uint64]time.Time),
+		statusChanged:         make(chan struct{}),
 	}
 	switch config.ReplicationMode {
 	case modeMajority:
@@ -108,7 +114,7 @@ func (m *ModeManager) UpdateConfig(config config.ReplicationModeConfig) error {
 	if m.config.ReplicationMode == modeMajority && config.ReplicationMode == modeDRAutoSync {
 		old := m.config
 		m.config = config
-		err := m.drSwitchToSyncRecoverWithLock()
+		err := m.drSwitchToSyncRecoverWithLock("switched from majority mode")
 		if err != nil {
 			// restore
 			m.config = old
@@ -119,7 +125,7 @@ func (m *ModeManager) UpdateConfig(config config.ReplicationModeConfig) error {
 	if m.config.ReplicationMode == modeDRAutoSync && config.ReplicationMode == modeDRAutoSync && m.config.DRAutoSync.LabelKey != config.DRAutoSync.LabelKey {
 		old := m.config
 		m.config = config
-		err := m.drSwitchToAsyncWithLock()
+		err := m.drSwitchToAsyncWithLock("label key changed")
 		if err != nil {
 			// restore
 			m.config = old
@@ -127,6 +133,7 @@ func (m *ModeManager) UpdateConfig(config config.ReplicationModeConfig) error {
 		return err
 	}
 	m.config = config
+	m.notifyStatusChangedWithLock()
 	return nil
 }
 
@@ -143,7 +150,18 @@ func (m *ModeManager) UpdateMemberWaitAsyncTime(memberID uint64) {
 func (m *ModeManager) GetReplicationStatus() *pb.ReplicationStatus {
 	m.RLock()
 	defer m.RUnlock()
+	return m.getReplicationStatusWithLock()
+}
 
+// WatchReplicationStatus returns the status to sync with tikv servers, and a
+// channel which is closed once the status may have changed.
+func (m *ModeManager) WatchReplicationStatus() (*pb.ReplicationStatus, <-chan struct{}) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.getReplicationStatusWithLock(), m.statusChanged
+}
+
+func (m *ModeManager) getReplicationStatusWithLock() *pb.ReplicationStatus {
 	p := &pb.ReplicationStatus{
 		Mode: modeToPB(m.config.ReplicationMode),
 	}
@@ -164,15 +182,31 @@ func (m *ModeManager) GetReplicationStatus() *pb.ReplicationStatus {
 type HTTPReplicationStatus struct {
 	Mode       string `json:"mode"`
 	DrAutoSync struct {
-		LabelKey        string  `json:"label_key"`
-		State           string  `json:"state"`
-		StateID         uint64  `json:"state_id,omitempty"`
-		TotalRegions    int     `json:"total_regions,omitempty"`
-		SyncedRegions   int     `json:"synced_regions,omitempty"`
-		RecoverProgress float32 `json:"recover_progress,omitempty"`
+		LabelKey        string            `json:"label_key"`
+		State           string            `json:"state"`
+		StateID         uint64            `json:"state_id,omitempty"`
+		Reason          string            `json:"reason,omitempty"`
+		SwitchTime      time.Time         `json:"switch_time,omitempty"`
+		TotalRegions    int               `json:"total_regions,omitempty"`
+		SyncedRegions   int               `json:"synced_regions,omitempty"`
+		RecoverProgress float32           `json:"recover_progress,omitempty"`
+		Stores          []HTTPStoreStatus `json:"stores,omitempty"`
 	} `json:"dr-auto-sync,omitempty"`
 }
 
+// HTTPStoreStatus is the status of a store in the view of the DR auto-sync
+// state machine.
+type HTTPStoreStatus struct {
+	StoreID    uint64 `json:"store_id"`
+	Address    string `json:"address"`
+	LabelValue string `json:"label_value"`
+	// Role is "primary" or "dr" if the store is in one of the two DCs.
+	Role string `json:"role,omitempty"`
+	// Down means the store has been down longer than wait-store-timeout, so
+	// it is counted as failed when deciding whether the cluster can sync.
+	Down bool `json:"down"`
+}
+
 // GetReplicationStatusHTTP returns status for HTTP API.
 func (m *ModeManager) GetReplicationStatusHTTP() *HTTPReplicationStatus {
 	m.RLock()
@@ -185,13 +219,39 @@ func (m *ModeManager) GetReplicationStatusHTTP() *HTTPReplicationStatus {
 		status.DrAutoSync.LabelKey = m.config.DRAutoSync.LabelKey
 		status.DrAutoSync.State = m.drAutoSync.State
 		status.DrAutoSync.StateID = m.drAutoSync.StateID
+		status.DrAutoSync.Reason = m.drAutoSync.Reason
+		status.DrAutoSync.SwitchTime = m.drAutoSync.SwitchTime
 		status.DrAutoSync.RecoverProgress = m.drAutoSync.RecoverProgress
 		status.DrAutoSync.TotalRegions = m.drAutoSync.TotalRegions
 		status.DrAutoSync.SyncedRegions = m.drAutoSync.SyncedRegions
+		status.DrAutoSync.Stores = m.getStoreStatus()
 	}
 	return &status
 }
 
+func (m *ModeManager) getStoreStatus() []HTTPStoreStatus {
+	var stores []HTTPStoreStatus
+	for _, s := range m.cluster.GetStores() {
+		if s.IsTombstone() {
+			continue
+		}
+		status := HTTPStoreStatus{
+			StoreID:    s.GetID(),
+			Address:    s.GetAddress(),
+			LabelValue: s.GetLabelValue(m.config.DRAutoSync.LabelKey),
+			Down:       s.DownTime() >= m.config.DRAutoSync.WaitStoreTimeout.Duration,
+		}
+		switch status.LabelValue {
+		case m.config.DRAutoSync.Primary:
+			status.Role = "primary"
+		case m.config.DRAutoSync.DR:
+			status.Role = "dr"
+		}
+		stores = append(stores, status)
+	}
+	return stores
+}
+
 func (m *ModeManager) getModeName() string {
 	m.RLock()
 	defer m.RUnlock()
@@ -208,6 +268,8 @@ type drAutoSyncStatus struct {
 	State            string    `json:"state,omitempty"`
 	StateID          uint64    `json:"state_id,omitempty"`
 	RecoverStartTime time.Time `json:"recover_start,omitempty"`
+	Reason           string    `json:"reason,omitempty"`
+	SwitchTime       time.Time `json:"switch_time,omitempty"`
 	TotalRegions     int       `json:"total_regions,omitempty"`
 	SyncedRegions    int       `json:"synced_regions,omitempty"`
 	RecoverProgress  float32   `json:"recover_progress,omitempty"`
@@ -220,7 +282,7 @@ func (m *ModeManager) loadDRAutoSync() error {
 	}
 	if !ok {
 		// initialize
-		return m.drSwitchToSync()
+		return m.drSwitchToSync("initialized")
 	}
 	return nil
 }
@@ -242,19 +304,19 @@ func (m *ModeManager) drCheckAsyncTimeout() bool {
 	return time.Since(m.initTime) > timeout
 }
 
-func (m *ModeManager) drSwitchToAsync() error {
+func (m *ModeManager) drSwitchToAsync(reason string) error {
 	m.Lock()
 	defer m.Unlock()
-	return m.drSwitchToAsyncWithLock()
+	return m.drSwitchToAsyncWithLock(reason)
 }
 
-func (m *ModeManager) drSwitchToAsyncWithLock() error {
+func (m *ModeManager) drSwitchToAsyncWithLock(reason string) error {
 	id, err := m.cluster.AllocID()
 	if err != nil {
 		log.Warn("failed to switch to async state", zap.String("replicate-mode", modeDRAutoSync), errs.ZapError(err))
 		return err
 	}
-	dr := drAutoSyncStatus{State: drStateAsync, StateID: id}
+	dr := drAutoSyncStatus{State: drStateAsync, StateID: id, Reason: reason, SwitchTime: time.Now()}
 	if err := m.drPersistStatus(dr); err != nil {
 		return err
 	}
@@ -263,23 +325,25 @@ func (m *ModeManager) drSwitchToAsyncWithLock() error {
 		return err
 	}
 	m.drAutoSync = dr
-	log.Info("switched to async state", zap.String("replicate-mode", modeDRAutoSync))
+	m.notifyStatusChangedWithLock()
+	log.Info("switched to async state", zap.String("replicate-mode", modeDRAutoSync), zap.String("reason", reason))
 	return nil
 }
 
-func (m *ModeManager) drSwitchToSyncRecover() error {
+func (m *ModeManager) drSwitchToSyncRecover(reason string) error {
 	m.Lock()
 	defer m.Unlock()
-	return m.drSwitchToSyncRecoverWithLock()
+	return m.drSwitchToSyncRecoverWithLock(reason)
 }
 
-func (m *ModeManager) drSwitchToSyncRecoverWithLock() error {
+func (m *ModeManager) drSwitchToSyncRecoverWithLock(reason string) error {
 	id, err := m.cluster.AllocID()
 	if err != nil {
 		log.Warn("failed to switch to sync_recover state", zap.String("replicate-mode", modeDRAutoSync), errs.ZapError(err))
 		return err
 	}
-	dr := drAutoSyncStatus{State: drStateSyncRecover, StateID: id, RecoverStartTime: time.Now()}
+	now := time.Now()
+	dr := drAutoSyncStatus{State: drStateSyncRecover, StateID: id, RecoverStartTime: now, Reason: reason, SwitchTime: now}
 	if err := m.drPersistStatus(dr); err != nil {
 		return err
 	}
@@ -288,12 +352,13 @@ func (m *ModeManager) drSwitchToSyncRecoverWithLock() error {
 		return err
 	}
 	m.drAutoSync = dr
+	m.notifyStatusChangedWithLock()
 	m.drRecoverKey, m.drRecoverCount = nil, 0
-	log.Info("switched to sync_recover state", zap.String("replicate-mode", modeDRAutoSync))
+	log.Info("switched to sync_recover state", zap.String("replicate-mode", modeDRAutoSync), zap.String("reason", reason))
 	return nil
 }
 
-func (m *ModeManager) drSwitchToSync() error {
+func (m *ModeManager) drSwitchToSync(reason string) error {
 	m.Lock()
 	defer m.Unlock()
 	id, err := m.cluster.AllocID()
@@ -301,7 +366,7 @@ func (m *ModeManager) drSwitchToSync() error {
 		log.Warn("failed to switch to sync state", zap.String("replicate-mode", modeDRAutoSync), errs.ZapError(err))
 		return err
 	}
-	dr := drAutoSyncStatus{State: drStateSync, StateID: id}
+	dr := drAutoSyncStatus{State: drStateSync, StateID: id, Reason: reason, SwitchTime: time.Now()}
 	if err := m.drPersistStatus(dr); err != nil {
 		return err
 	}
@@ -310,7 +375,8 @@ func (m *ModeManager) drSwitchToSync() error {
 		return err
 	}
 	m.drAutoSync = dr
-	log.Info("switched to sync state", zap.String("replicate-mode", modeDRAutoSync))
+	m.notifyStatusChangedWithLock()
+	log.Info("switched to sync state", zap.String("replicate-mode", modeDRAutoSync), zap.String("reason", reason))
 	return nil
 }
 
@@ -333,6 +399,11 @@ func (m *ModeManager) drPersistStatus(status drAutoSyncStatus) error {
 	return nil
 }
 
+func (m *ModeManager) notifyStatusChangedWithLock() {
+	close(m.statusChanged)
+	m.statusChanged = make(chan struct{})
+}
+
 func (m *ModeManager) drGetState() string {
 	m.RLock()
 	defer m.RUnlock()
@@ -387,11 +458,11 @@ func (m *ModeManager) tickDR() {
 
 	// If hasMajority is false, the cluster is always unavailable. Switch to async won't help.
 	if !canSync && hasMajority && m.drGetState() != drStateAsync && m.drCheckAsyncTimeout() {
-		m.drSwitchToAsync()
+		m.drSwitchToAsync(fmt.Sprintf("%d/%d primary stores and %d/%d dr stores are down", downPrimary, totalPrimary, downDr, totalDr))
 	}
 
 	if canSync && m.drGetState() == drStateAsync {
-		m.drSwitchToSyncRecover()
+		m.drSwitchToSyncRecover("stores are back in both primary and dr")
 	}
 
 	if m.drGetState() == drStateSyncRecover {
@@ -400,7 +471,7 @@ func (m *ModeManager) tickDR() {
 		drRecoverProgressGauge.Set(float64(progress))
 
 		if progress == 1.0 {
-			m.drSwitchToSync()
+			m.drSwitchToSync("all regions are recovered")
 		} else {
 			m.updateRecoverProgress(progress)
 		}
@@ -85,7 +85,7 @@ func (s *testReplicationMode) TestStatus(c *C) {
 		},
 	})
 
-	err = rep.drSwitchToAsync()
+	err = rep.drSwitchToAsync("test")
 	c.Assert(err, IsNil)
 	c.Assert(rep.GetReplicationStatus(), DeepEquals, &pb.ReplicationStatus{
 		Mode: pb.ReplicationMode_DR_AUTO_SYNC,
@@ -97,7 +97,7 @@ func (s *testReplicationMode) TestStatus(c *C) {
 		},
 	})
 
-	err = rep.drSwitchToSyncRecover()
+	err = rep.drSwitchToSyncRecover("test")
 	c.Assert(err, IsNil)
 	stateID := rep.drAutoSync.StateID
 	c.Assert(rep.GetReplicationStatus(), DeepEquals, &pb.ReplicationStatus{
@@ -115,7 +115,7 @@ func (s *testReplicationMode) TestStatus(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(rep.drAutoSync.State, Equals, drStateSyncRecover)
 
-	err = rep.drSwitchToSync()
+	err = rep.drSwitchToSync("test")
 	c.Assert(err, IsNil)
 	c.Assert(rep.GetReplicationStatus(), DeepEquals, &pb.ReplicationStatus{
 		Mode: pb.ReplicationMode_DR_AUTO_SYNC,
@@ -128,6 +128,33 @@ func (s *testReplicationMode) TestStatus(c *C) {
 	})
 }
 
+func (s *testReplicationMode) TestWatchStatus(c *C) {
+	store := core.NewStorage(kv.NewMemoryKV())
+	conf := config.ReplicationModeConfig{ReplicationMode: modeMajority}
+	cluster := mockcluster.NewCluster(config.NewTestOptions())
+	rep, err := NewReplicationModeManager(conf, store, cluster, nil)
+	c.Assert(err, IsNil)
+	status, changed := rep.WatchReplicationStatus()
+	c.Assert(status.GetMode(), Equals, pb.ReplicationMode_MAJORITY)
+	select {
+	case <-changed:
+		c.Fatal("the status is not changed")
+	default:
+	}
+
+	conf.ReplicationMode = modeDRAutoSync
+	conf.DRAutoSync.LabelKey = "dr-label"
+	c.Assert(rep.UpdateConfig(conf), IsNil)
+	<-changed
+	status, changed = rep.WatchReplicationStatus()
+	c.Assert(status.GetDrAutoSync().GetState(), Equals, pb.DRAutoSyncState_SYNC_RECOVER)
+
+	c.Assert(rep.drSwitchToAsync("test"), IsNil)
+	<-changed
+	status, _ = rep.WatchReplicationStatus()
+	c.Assert(status.GetDrAutoSync().GetState(), Equals, pb.DRAutoSyncState_ASYNC)
+}
+
 type mockFileReplicator struct {
 	err error
 }
@@ -182,7 +209,18 @@ func (s *testReplicationMode) TestStateSwitch(c *C) {
 	rep.tickDR()
 	c.Assert(rep.drGetState(), Equals, drStateAsync)
 	assertStateIDUpdate()
-	rep.drSwitchToSync()
+	status := rep.GetReplicationStatusHTTP()
+	c.Assert(status.DrAutoSync.Reason, Equals, "0/2 primary stores and 1/1 dr stores are down")
+	c.Assert(status.DrAutoSync.Stores, HasLen, 5)
+	for _, store := range status.DrAutoSync.Stores {
+		if store.StoreID <= 3 {
+			c.Assert(store.Role, Equals, "primary")
+		} else {
+			c.Assert(store.Role, Equals, "dr")
+		}
+		c.Assert(store.Down, Equals, store.StoreID == 5)
+	}
+	rep.drSwitchToSync("test")
 	replicator.err = errors.New("fail to replicate")
 	rep.tickDR()
 	c.Assert(rep.drGetState(), Equals, drStateAsync)
@@ -193,7 +231,8 @@ func (s *testReplicationMode) TestStateSwitch(c *C) {
 	rep.tickDR()
 	c.Assert(rep.drGetState(), Equals, drStateSyncRecover)
 	assertStateIDUpdate()
-	rep.drSwitchToAsync()
+	c.Assert(rep.drAutoSync.Reason, Equals, "stores are back in both primary and dr")
+	rep.drSwitchToAsync("test")
 	s.setStoreState(cluster, 1, "down")
 	rep.tickDR()
 	c.Assert(rep.drGetState(), Equals, drStateSyncRecover)
@@ -209,7 +248,7 @@ func (s *testReplicationMode) TestStateSwitch(c *C) {
 	assertStateIDUpdate()
 
 	// sync_recover -> sync
-	rep.drSwitchToSyncRecover()
+	rep.drSwitchToSyncRecover("test")
 	assertStateIDUpdate()
 	s.setStoreState(cluster, 4, "up")
 	cluster.AddLeaderRegion(1, 1, 2, 5)
@@ -237,6 +276,7 @@ func (s *testReplicationMode) TestStateSwitch(c *C) {
 	rep.tickDR()
 	c.Assert(rep.drGetState(), Equals, drStateSync)
 	assertStateIDUpdate()
+	c.Assert(rep.drAutoSync.Reason, Equals, "all regions are recovered")
 }
 
 func (s *testReplicationMode) TestAsynctimeout(c *C) {
@@ -268,7 +308,7 @@ func (s *testReplicationMode) TestAsynctimeout(c *C) {
 	rep.tickDR()
 	c.Assert(rep.drGetState(), Equals, drStateAsync)
 
-	rep.drSwitchToSync()
+	rep.drSwitchToSync("test")
 	rep.UpdateMemberWaitAsyncTime(42)
 	rep.tickDR()
 	c.Assert(rep.drGetState(), Equals, drStateSync) // cannot switch state due to member not timeout
@@ -308,7 +348,7 @@ func (s *testReplicationMode) TestRecoverProgress(c *C) {
 	c.Assert(err, IsNil)
 
 	prepare := func(n int, asyncRegions []int) {
-		rep.drSwitchToSyncRecover()
+		rep.drSwitchToSyncRecover("test")
 		regions := s.genRegions(cluster, rep.drAutoSync.StateID, n)
 		for _, i := range asyncRegions {
 			regions[i] = regions[i].Clone(core.SetReplicationStatus(&pb.RegionReplicationStatus{
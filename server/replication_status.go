@@ -0,0 +1,91 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"google.golang.org/grpc"
+)
+
+// replicationStatusCheckInterval is the interval to check whether the server
+// is still the leader while watching the replication status.
+const replicationStatusCheckInterval = time.Second
+
+// replicationStatusService pushes the replication status whenever it changes,
+// so that the DR auto-sync state can be followed without polling.
+var replicationStatusService = sideService{
+	name:   grpcutil.ReplicationStatusServiceName,
+	checks: defaultGRPCChecks,
+	streams: []sideStream{
+		{
+			name:       grpcutil.WatchReplicationStatusStreamName,
+			newRequest: func() interface{} { return new(pdpb.GetClusterConfigRequest) },
+			handle: func(s *Server, req interface{}, stream grpc.ServerStream) error {
+				return s.WatchReplicationStatus(req.(*pdpb.GetClusterConfigRequest), stream)
+			},
+		},
+	},
+}
+
+// WatchReplicationStatus sends the current replication status, and then the
+// status whenever it changes. Each response is a StoreHeartbeatResponse which
+// only carries the status. The stream is closed once the server is no longer
+// the leader.
+func (s *Server) WatchReplicationStatus(request *pdpb.GetClusterConfigRequest, stream grpc.ServerStream) error {
+	rc := s.GetRaftCluster()
+	if rc == nil {
+		return stream.SendMsg(&pdpb.StoreHeartbeatResponse{Header: s.notBootstrappedHeader()})
+	}
+
+	ticker := time.NewTicker(replicationStatusCheckInterval)
+	defer ticker.Stop()
+	var last proto.Message
+	for {
+		manager := rc.GetReplicationMode()
+		status, changed := manager.WatchReplicationStatus()
+		// The status may be notified as changed while it is not.
+		if last == nil || !proto.Equal(last, status) {
+			resp := &pdpb.StoreHeartbeatResponse{
+				Header:            s.header(),
+				ReplicationStatus: status,
+			}
+			if err := stream.SendMsg(resp); err != nil {
+				return err
+			}
+			last = status
+		}
+	wait:
+		for {
+			select {
+			case <-changed:
+				break wait
+			case <-ticker.C:
+				if s.IsClosed() || !s.member.IsLeader() || s.GetRaftCluster() != rc {
+					return errors.WithStack(s.notLeaderError())
+				}
+				// The manager is replaced if the cluster is restarted.
+				if rc.GetReplicationMode() != manager {
+					break wait
+				}
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			}
+		}
+	}
+}
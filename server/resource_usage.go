@@ -0,0 +1,145 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ResourceUsage is the resource usage of a PD server process.
+type ResourceUsage struct {
+	Name        string    `json:"name"`
+	MemberID    uint64    `json:"member_id"`
+	CollectTime time.Time `json:"collect_time"`
+	// CPUSeconds is the CPU time consumed by the process since it started.
+	CPUSeconds float64 `json:"cpu_seconds"`
+	// CPUUsage is the average number of CPU cores used by the process, which
+	// is measured over the last one or two metrics intervals.
+	CPUUsage float64 `json:"cpu_usage"`
+	// CPUQuota is the number of CPU cores allowed by the cgroup, 0 means
+	// the quota is unlimited or unknown.
+	CPUQuota float64 `json:"cpu_quota,omitempty"`
+	// MemoryLimit and MemoryUsage are read from the memory cgroup. They are
+	// 0 if the limit is unlimited or unknown.
+	MemoryLimit uint64 `json:"memory_limit_bytes,omitempty"`
+	MemoryUsage uint64 `json:"memory_usage_bytes,omitempty"`
+	// SysMemory is the memory obtained from the OS by the Go runtime.
+	SysMemory  uint64  `json:"sys_memory_bytes"`
+	HeapInuse  uint64  `json:"heap_inuse_bytes"`
+	Goroutines int     `json:"goroutines"`
+	GC         GCStats `json:"gc"`
+}
+
+// GCStats is the statistics of the garbage collection of a PD server process.
+type GCStats struct {
+	NumGC      uint32  `json:"num_gc"`
+	PauseTotal float64 `json:"pause_total_ms"`
+	LastPause  float64 `json:"last_pause_ms"`
+	// MaxPause is the max pause of the last 256 garbage collections at most.
+	MaxPause    float64    `json:"max_pause_ms"`
+	LastGCTime  *time.Time `json:"last_gc_time,omitempty"`
+	CPUFraction float64    `json:"cpu_fraction"`
+}
+
+type cpuSample struct {
+	time    time.Time
+	cpuTime float64
+}
+
+// resourceUsageCollector keeps the CPU time samples of the process, so that
+// the CPU usage can be computed over a stable window instead of the short
+// period between two requests.
+type resourceUsageCollector struct {
+	mu   sync.Mutex
+	prev cpuSample
+	last cpuSample
+}
+
+func newResourceUsageCollector() *resourceUsageCollector {
+	start := cpuSample{time: time.Now(), cpuTime: getProcessCPUTime()}
+	return &resourceUsageCollector{prev: start, last: start}
+}
+
+// sample is called once per metrics interval.
+func (c *resourceUsageCollector) sample() {
+	s := cpuSample{time: time.Now(), cpuTime: getProcessCPUTime()}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prev, c.last = c.last, s
+}
+
+func (c *resourceUsageCollector) collect() *ResourceUsage {
+	now := time.Now()
+	usage := &ResourceUsage{
+		CollectTime: now,
+		CPUSeconds:  getProcessCPUTime(),
+		Goroutines:  runtime.NumGoroutine(),
+	}
+	c.mu.Lock()
+	if elapsed := now.Sub(c.prev.time).Seconds(); elapsed > 0 {
+		usage.CPUUsage = (usage.CPUSeconds - c.prev.cpuTime) / elapsed
+	}
+	c.mu.Unlock()
+
+	limits := getCgroupLimits()
+	usage.CPUQuota = limits.cpuQuota
+	usage.MemoryLimit = limits.memoryLimit
+	usage.MemoryUsage = limits.memoryUsage
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	usage.SysMemory = ms.Sys
+	usage.HeapInuse = ms.HeapInuse
+	usage.GC = GCStats{
+		NumGC:       ms.NumGC,
+		PauseTotal:  durationToMs(ms.PauseTotalNs),
+		CPUFraction: ms.GCCPUFraction,
+	}
+	if ms.NumGC > 0 {
+		usage.GC.LastPause = durationToMs(ms.PauseNs[(ms.NumGC+255)%256])
+		lastGCTime := time.Unix(0, int64(ms.LastGC))
+		usage.GC.LastGCTime = &lastGCTime
+		n := ms.NumGC
+		if n > 256 {
+			n = 256
+		}
+		for i := uint32(0); i < n; i++ {
+			if pause := durationToMs(ms.PauseNs[i]); pause > usage.GC.MaxPause {
+				usage.GC.MaxPause = pause
+			}
+		}
+	}
+	return usage
+}
+
+func durationToMs(ns uint64) float64 {
+	return float64(ns) / float64(time.Millisecond)
+}
+
+// cgroupLimits is the resource limits of the cgroup of the process.
+type cgroupLimits struct {
+	cpuQuota    float64
+	memoryLimit uint64
+	memoryUsage uint64
+}
+
+// GetResourceUsage returns the resource usage of the server process.
+func (s *Server) GetResourceUsage() *ResourceUsage {
+	usage := s.resourceUsage.collect()
+	usage.Name = s.Name()
+	usage.MemberID = s.member.ID()
+	return usage
+}
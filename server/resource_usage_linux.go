@@ -0,0 +1,89 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package server
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cgroupRoot is where the cgroup of the process is mounted. PD usually runs
+// in a container, whose own cgroup is mounted at the root.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// unlimitedMemory is the threshold above which a cgroup v1 memory limit is
+// regarded as unlimited, as the kernel reports a huge page-aligned value.
+const unlimitedMemory = 1 << 62
+
+func getProcessCPUTime() float64 {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	toSeconds := func(tv syscall.Timeval) float64 {
+		return (time.Duration(tv.Sec)*time.Second + time.Duration(tv.Usec)*time.Microsecond).Seconds()
+	}
+	return toSeconds(ru.Utime) + toSeconds(ru.Stime)
+}
+
+func getCgroupLimits() cgroupLimits {
+	return readCgroupLimits(cgroupRoot)
+}
+
+func readCgroupLimits(root string) cgroupLimits {
+	var limits cgroupLimits
+	if _, err := ioutil.ReadFile(filepath.Join(root, "cgroup.controllers")); err == nil {
+		// cgroup v2
+		if fields := strings.Fields(readCgroupFile(root, "cpu.max")); len(fields) == 2 {
+			limits.cpuQuota = parseCPUQuota(fields[0], fields[1])
+		}
+		limits.memoryLimit, _ = strconv.ParseUint(readCgroupFile(root, "memory.max"), 10, 64)
+		limits.memoryUsage, _ = strconv.ParseUint(readCgroupFile(root, "memory.current"), 10, 64)
+		return limits
+	}
+	// cgroup v1
+	limits.cpuQuota = parseCPUQuota(readCgroupFile(root, "cpu/cpu.cfs_quota_us"), readCgroupFile(root, "cpu/cpu.cfs_period_us"))
+	if limit, err := strconv.ParseUint(readCgroupFile(root, "memory/memory.limit_in_bytes"), 10, 64); err == nil && limit < unlimitedMemory {
+		limits.memoryLimit = limit
+	}
+	limits.memoryUsage, _ = strconv.ParseUint(readCgroupFile(root, "memory/memory.usage_in_bytes"), 10, 64)
+	return limits
+}
+
+func readCgroupFile(root, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(root, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// parseCPUQuota returns 0 if the quota is "max" or -1, which means unlimited.
+func parseCPUQuota(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
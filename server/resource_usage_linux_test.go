@@ -0,0 +1,86 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testResourceUsageSuite{})
+
+type testResourceUsageSuite struct{}
+
+func (s *testResourceUsageSuite) writeFiles(c *C, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(content+"\n"), 0644), IsNil)
+	}
+}
+
+func (s *testResourceUsageSuite) TestCgroupV1(c *C) {
+	root := c.MkDir()
+	c.Assert(readCgroupLimits(root), Equals, cgroupLimits{})
+
+	s.writeFiles(c, root, map[string]string{
+		"cpu/cpu.cfs_quota_us":         "-1",
+		"cpu/cpu.cfs_period_us":        "100000",
+		"memory/memory.limit_in_bytes": "9223372036854771712",
+		"memory/memory.usage_in_bytes": "1024",
+	})
+	c.Assert(readCgroupLimits(root), Equals, cgroupLimits{memoryUsage: 1024})
+
+	s.writeFiles(c, root, map[string]string{
+		"cpu/cpu.cfs_quota_us":         "250000",
+		"memory/memory.limit_in_bytes": "4294967296",
+	})
+	c.Assert(readCgroupLimits(root), Equals, cgroupLimits{cpuQuota: 2.5, memoryLimit: 4294967296, memoryUsage: 1024})
+}
+
+func (s *testResourceUsageSuite) TestCgroupV2(c *C) {
+	root := c.MkDir()
+	s.writeFiles(c, root, map[string]string{
+		"cgroup.controllers": "cpu memory",
+		"cpu.max":            "max 100000",
+		"memory.max":         "max",
+		"memory.current":     "2048",
+	})
+	c.Assert(readCgroupLimits(root), Equals, cgroupLimits{memoryUsage: 2048})
+
+	s.writeFiles(c, root, map[string]string{
+		"cpu.max":    "50000 100000",
+		"memory.max": "1073741824",
+	})
+	c.Assert(readCgroupLimits(root), Equals, cgroupLimits{cpuQuota: 0.5, memoryLimit: 1073741824, memoryUsage: 2048})
+}
+
+func (s *testResourceUsageSuite) TestCollect(c *C) {
+	collector := newResourceUsageCollector()
+	runtime.GC()
+	collector.sample()
+	usage := collector.collect()
+	c.Assert(usage.CPUSeconds > 0, IsTrue)
+	c.Assert(usage.CPUUsage >= 0, IsTrue)
+	c.Assert(usage.Goroutines > 0, IsTrue)
+	c.Assert(usage.HeapInuse > 0, IsTrue)
+	c.Assert(usage.GC.NumGC > 0, IsTrue)
+	c.Assert(usage.GC.MaxPause >= usage.GC.LastPause, IsTrue)
+}
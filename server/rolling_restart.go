@@ -0,0 +1,610 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/job"
+	"go.uber.org/zap"
+)
+
+// RollingRestartJobType is the job type of rolling restarts.
+const RollingRestartJobType = "rolling-restart"
+
+const (
+	defaultRollingRestartStepTimeout = 10 * time.Minute
+	rollingRestartCheckInterval      = time.Second
+	rollingRestartStatusTimeout      = 3 * time.Second
+	rollingRestartPath               = "rolling_restart"
+	memberStatusURL                  = "/pd/api/v1/status"
+)
+
+// The states of a rolling restart job.
+const (
+	RollingRestartRunning  = "running"
+	RollingRestartFinished = "finished"
+	RollingRestartAborted  = "aborted"
+	RollingRestartFailed   = "failed"
+)
+
+// The states of a member in a rolling restart job.
+const (
+	RollingRestartMemberPending       = "pending"
+	RollingRestartMemberCheckHealth   = "checking-health"
+	RollingRestartMemberTransferring  = "transferring-leader"
+	RollingRestartMemberWaitRestart   = "waiting-restart"
+	RollingRestartMemberWaitRejoin    = "waiting-rejoin"
+	RollingRestartMemberDone          = "done"
+	RollingRestartMemberRestartIssued = "restart-issued"
+)
+
+// errRollingRestartHandedOver is returned when the coordinator has transferred
+// the leadership away or issued the restart of itself, and the next leader is
+// going to resume the plan.
+var errRollingRestartHandedOver = errors.New("rolling restart is handed over to the next leader")
+
+// RollingRestartHook restarts the given member. It should return after the
+// member has been restarted. If no hook is set, the coordinator waits for an
+// external signal from MarkRollingRestartMemberRestarted instead.
+type RollingRestartHook func(ctx context.Context, member *pdpb.Member) error
+
+// RollingRestartMemberStatus is the progress of a member in a rolling restart.
+type RollingRestartMemberStatus struct {
+	Name     string `json:"name"`
+	MemberID uint64 `json:"member_id"`
+	State    string `json:"state"`
+	// The start timestamp and the build of the member before the restart. The
+	// member is restarted once any of them changes.
+	StartTimestamp int64  `json:"start_timestamp,omitempty"`
+	Version        string `json:"version,omitempty"`
+	GitHash        string `json:"git_hash,omitempty"`
+}
+
+// RollingRestartStatus is the progress of a rolling restart job.
+type RollingRestartStatus struct {
+	JobID       uint64                        `json:"job_id"`
+	State       string                        `json:"state"`
+	Error       string                        `json:"error,omitempty"`
+	StartTime   time.Time                     `json:"start_time"`
+	EndTime     time.Time                     `json:"end_time,omitempty"`
+	StepTimeout typeutil.Duration             `json:"step_timeout"`
+	Members     []*RollingRestartMemberStatus `json:"members"`
+}
+
+func (s *RollingRestartStatus) clone() *RollingRestartStatus {
+	status := *s
+	status.Members = make([]*RollingRestartMemberStatus, 0, len(s.Members))
+	for _, m := range s.Members {
+		member := *m
+		status.Members = append(status.Members, &member)
+	}
+	return &status
+}
+
+// memberBuildStatus is the part of the status API of a member used to tell
+// whether it has been restarted.
+type memberBuildStatus struct {
+	Version        string `json:"version"`
+	GitHash        string `json:"git_hash"`
+	StartTimestamp int64  `json:"start_timestamp"`
+}
+
+// rollingRestartCoordinator restarts PD members one by one. The members are
+// restarted in the order of followers first and the current leader last. The
+// plan is persisted in etcd, and the leader transfers the leadership away when
+// it is its turn, so the next leader resumes the plan and restarts it. A
+// member is done only after it rejoins with a new start timestamp or build.
+type rollingRestartCoordinator struct {
+	s *Server
+
+	mu        sync.Mutex
+	hook      RollingRestartHook
+	status    *RollingRestartStatus
+	restarted map[uint64]chan struct{}
+}
+
+func newRollingRestartCoordinator(s *Server) *rollingRestartCoordinator {
+	return &rollingRestartCoordinator{s: s}
+}
+
+func (c *rollingRestartCoordinator) setHook(hook RollingRestartHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hook = hook
+}
+
+func (c *rollingRestartCoordinator) start(stepTimeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status != nil && c.status.State == RollingRestartRunning {
+		return errs.ErrRollingRestartRunning.FastGenByArgs()
+	}
+	members, err := cluster.GetMembers(c.s.GetClient())
+	if err != nil {
+		return err
+	}
+	if stepTimeout <= 0 {
+		stepTimeout = defaultRollingRestartStepTimeout
+	}
+
+	// Followers first, the leader last.
+	leaderID := c.s.GetMember().GetLeaderID()
+	ordered := make([]*pdpb.Member, 0, len(members))
+	var leader *pdpb.Member
+	for _, m := range members {
+		if m.GetMemberId() == leaderID {
+			leader = m
+			continue
+		}
+		ordered = append(ordered, m)
+	}
+	if leader != nil {
+		ordered = append(ordered, leader)
+	}
+
+	status := &RollingRestartStatus{
+		State:       RollingRestartRunning,
+		StartTime:   time.Now(),
+		StepTimeout: typeutil.NewDuration(stepTimeout),
+	}
+	for _, m := range ordered {
+		status.Members = append(status.Members, &RollingRestartMemberStatus{
+			Name:     m.GetName(),
+			MemberID: m.GetMemberId(),
+			State:    RollingRestartMemberPending,
+		})
+	}
+	return c.submitLocked(status)
+}
+
+// submitLocked runs the plan as a job. It should be called with the lock held.
+func (c *rollingRestartCoordinator) submitLocked(status *RollingRestartStatus) error {
+	c.restarted = make(map[uint64]chan struct{}, len(status.Members))
+	for _, m := range status.Members {
+		c.restarted[m.MemberID] = make(chan struct{}, 1)
+	}
+	j, err := c.s.jobManager.Submit(RollingRestartJobType, func(ctx context.Context, r job.Reporter) error {
+		return c.run(ctx, r)
+	})
+	if err != nil {
+		return err
+	}
+	status.JobID = j.ID
+	c.status = status
+	if err := c.saveLocked(); err != nil {
+		if err := c.s.jobManager.Cancel(j.ID); err != nil {
+			log.Warn("failed to cancel rolling restart job", errs.ZapError(err))
+		}
+		c.status = nil
+		return err
+	}
+	return nil
+}
+
+// load loads the plan persisted by the previous leader, and resumes it if it
+// is still running. It is called after the server becomes the leader.
+func (c *rollingRestartCoordinator) load() error {
+	value, err := c.s.storage.Load(rollingRestartPath)
+	if err != nil || value == "" {
+		return err
+	}
+	status := &RollingRestartStatus{}
+	if err := json.Unmarshal([]byte(value), status); err != nil {
+		return errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+	if status.State != RollingRestartRunning {
+		return nil
+	}
+	log.Info("resume rolling restart", zap.Uint64("previous-job-id", status.JobID))
+	return c.submitLocked(status)
+}
+
+// saveLocked persists the plan. It should be called with the lock held.
+func (c *rollingRestartCoordinator) saveLocked() error {
+	value, err := json.Marshal(c.status)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	return c.s.storage.Save(rollingRestartPath, string(value))
+}
+
+func (c *rollingRestartCoordinator) abort() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status == nil || c.status.State != RollingRestartRunning {
+		return errs.ErrRollingRestartNotRunning.FastGenByArgs()
+	}
+	if err := c.s.jobManager.Cancel(c.status.JobID); err != nil {
+		log.Warn("failed to cancel rolling restart job", errs.ZapError(err))
+	}
+	return c.finishLocked(RollingRestartAborted, nil)
+}
+
+func (c *rollingRestartCoordinator) markRestarted(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status == nil || c.status.State != RollingRestartRunning {
+		return errs.ErrRollingRestartNotRunning.FastGenByArgs()
+	}
+	for _, m := range c.status.Members {
+		if m.Name != name {
+			continue
+		}
+		if m.State != RollingRestartMemberWaitRestart {
+			return errs.ErrRollingRestartMemberState.FastGenByArgs(name, m.State)
+		}
+		select {
+		case c.restarted[m.MemberID] <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	return errs.ErrRollingRestartMemberState.FastGenByArgs(name, "not found")
+}
+
+func (c *rollingRestartCoordinator) getStatus() *RollingRestartStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status == nil {
+		return nil
+	}
+	return c.status.clone()
+}
+
+// getMember returns a copy of the status of the member.
+func (c *rollingRestartCoordinator) getMember(idx int) RollingRestartMemberStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return *c.status.Members[idx]
+}
+
+// updateMember updates the status of the member and persists the plan.
+func (c *rollingRestartCoordinator) updateMember(idx int, update func(*RollingRestartMemberStatus)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status.State != RollingRestartRunning {
+		return errs.ErrRollingRestartNotRunning.FastGenByArgs()
+	}
+	member := c.status.Members[idx]
+	update(member)
+	log.Info("rolling restart progress", zap.String("member", member.Name), zap.String("state", member.State))
+	return c.saveLocked()
+}
+
+func (c *rollingRestartCoordinator) setMemberState(idx int, state string) error {
+	return c.updateMember(idx, func(m *RollingRestartMemberStatus) { m.State = state })
+}
+
+func (c *rollingRestartCoordinator) finish(state string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status.State != RollingRestartRunning {
+		return
+	}
+	if err := c.finishLocked(state, err); err != nil {
+		log.Error("failed to persist rolling restart", errs.ZapError(err))
+	}
+}
+
+func (c *rollingRestartCoordinator) finishLocked(state string, err error) error {
+	c.status.State = state
+	c.status.EndTime = time.Now()
+	if err != nil {
+		c.status.Error = err.Error()
+		log.Error("rolling restart failed", errs.ZapError(err))
+	} else {
+		log.Info("rolling restart finished", zap.String("state", state))
+	}
+	return c.saveLocked()
+}
+
+func (c *rollingRestartCoordinator) run(ctx context.Context, r job.Reporter) error {
+	c.mu.Lock()
+	plan := c.status.clone()
+	c.mu.Unlock()
+	stepTimeout := plan.StepTimeout.Duration
+
+	etcdMembers, err := cluster.GetMembers(c.s.GetClient())
+	if err != nil {
+		c.finish(RollingRestartFailed, err)
+		return err
+	}
+	byID := make(map[uint64]*pdpb.Member, len(etcdMembers))
+	for _, m := range etcdMembers {
+		byID[m.GetMemberId()] = m
+	}
+	members := make([]*pdpb.Member, 0, len(plan.Members))
+	for _, m := range plan.Members {
+		member, ok := byID[m.MemberID]
+		if !ok {
+			err := errs.ErrRollingRestartStep.FastGenByArgs("find", m.Name, "not found")
+			c.finish(RollingRestartFailed, err)
+			return err
+		}
+		members = append(members, member)
+	}
+
+	for i, m := range members {
+		if plan.Members[i].State == RollingRestartMemberDone {
+			continue
+		}
+		// The next leader resumes the plan.
+		if !c.s.GetMember().IsLeader() {
+			return nil
+		}
+		r.SetProgress(float64(i)/float64(len(members)), "restarting "+m.GetName())
+		if err := c.restartMember(ctx, i, m, members, stepTimeout); err != nil {
+			if err == errRollingRestartHandedOver {
+				return nil
+			}
+			if ctx.Err() == nil {
+				c.finish(RollingRestartFailed, err)
+			}
+			return err
+		}
+	}
+	c.finish(RollingRestartFinished, nil)
+	return nil
+}
+
+func (c *rollingRestartCoordinator) restartMember(ctx context.Context, idx int, m *pdpb.Member, members []*pdpb.Member, stepTimeout time.Duration) error {
+	switch c.getMember(idx).State {
+	case RollingRestartMemberRestartIssued, RollingRestartMemberWaitRejoin:
+		return c.waitRejoin(ctx, idx, m, stepTimeout)
+	case RollingRestartMemberWaitRestart:
+		// The member may be restarted by others when the plan is resumed.
+		if before := c.getMember(idx); c.isRestarted(ctx, m, &before) {
+			if err := c.setMemberState(idx, RollingRestartMemberWaitRejoin); err != nil {
+				return err
+			}
+			return c.waitRejoin(ctx, idx, m, stepTimeout)
+		}
+	default:
+		if err := c.prepareRestart(ctx, idx, m, members, stepTimeout); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	hook, restarted := c.hook, c.restarted[m.GetMemberId()]
+	c.mu.Unlock()
+	if m.GetMemberId() == c.s.GetMember().ID() {
+		// It is the only member, so the coordinator can only issue the restart
+		// of itself, and resumes the plan to wait for the rejoin after the
+		// restart.
+		if hook != nil {
+			if err := c.setMemberState(idx, RollingRestartMemberRestartIssued); err != nil {
+				return err
+			}
+			go func() {
+				if err := hook(c.s.Context(), m); err != nil {
+					log.Error("failed to restart pd member", zap.String("member", m.GetName()), errs.ZapError(err))
+				}
+			}()
+		}
+		return errRollingRestartHandedOver
+	}
+	if hook != nil {
+		hookCtx, cancel := context.WithTimeout(ctx, stepTimeout)
+		err := hook(hookCtx, m)
+		cancel()
+		if err != nil {
+			return errs.ErrRollingRestartStep.FastGenByArgs("restart", m.GetName(), err)
+		}
+	} else {
+		select {
+		case <-restarted:
+		case <-time.After(stepTimeout):
+			return errs.ErrRollingRestartStep.FastGenByArgs("wait for restart signal", m.GetName(), "timeout")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := c.setMemberState(idx, RollingRestartMemberWaitRejoin); err != nil {
+		return err
+	}
+	return c.waitRejoin(ctx, idx, m, stepTimeout)
+}
+
+// prepareRestart waits for all members to be healthy and records the start
+// timestamp and the build of the member before the restart. If the member is
+// the leader, it transfers the leadership away instead, and the next leader
+// prepares again.
+func (c *rollingRestartCoordinator) prepareRestart(ctx context.Context, idx int, m *pdpb.Member, members []*pdpb.Member, stepTimeout time.Duration) error {
+	if err := c.setMemberState(idx, RollingRestartMemberCheckHealth); err != nil {
+		return err
+	}
+	if err := c.waitUntil(ctx, stepTimeout, func() bool { return c.allHealthy(members) }); err != nil {
+		return errs.ErrRollingRestartStep.FastGenByArgs("wait for healthy members", m.GetName(), err)
+	}
+
+	if m.GetMemberId() == c.s.GetMember().GetLeaderID() && len(members) > 1 {
+		if err := c.setMemberState(idx, RollingRestartMemberTransferring); err != nil {
+			return err
+		}
+		// Prefer a member which has been restarted already.
+		var next string
+		if idx > 0 {
+			next = members[0].GetName()
+		}
+		if err := c.s.GetMember().ResignEtcdLeader(ctx, c.s.Name(), next); err != nil {
+			return errs.ErrRollingRestartStep.FastGenByArgs("transfer leader", m.GetName(), err)
+		}
+		// The next leader resumes the plan and restarts this member.
+		return errRollingRestartHandedOver
+	}
+
+	before, err := c.getBuildStatus(ctx, m)
+	if err != nil {
+		return errs.ErrRollingRestartStep.FastGenByArgs("get status", m.GetName(), err)
+	}
+	return c.updateMember(idx, func(status *RollingRestartMemberStatus) {
+		status.State = RollingRestartMemberWaitRestart
+		status.StartTimestamp = before.StartTimestamp
+		status.Version = before.Version
+		status.GitHash = before.GitHash
+	})
+}
+
+func (c *rollingRestartCoordinator) waitRejoin(ctx context.Context, idx int, m *pdpb.Member, stepTimeout time.Duration) error {
+	before := c.getMember(idx)
+	if err := c.waitUntil(ctx, stepTimeout, func() bool { return c.isRejoined(ctx, m, &before) }); err != nil {
+		return errs.ErrRollingRestartStep.FastGenByArgs("wait for rejoin", m.GetName(), err)
+	}
+	return c.setMemberState(idx, RollingRestartMemberDone)
+}
+
+func (c *rollingRestartCoordinator) allHealthy(members []*pdpb.Member) bool {
+	healthy := cluster.CheckHealth(c.s.GetHTTPClient(), members)
+	return len(healthy) == len(members)
+}
+
+// getBuildStatus gets the start timestamp and the build of the member from
+// its status API.
+func (c *rollingRestartCoordinator) getBuildStatus(ctx context.Context, m *pdpb.Member) (*memberBuildStatus, error) {
+	var lastErr error
+	for _, url := range m.GetClientUrls() {
+		reqCtx, cancel := context.WithTimeout(ctx, rollingRestartStatusTimeout)
+		status, err := c.getBuildStatusFromURL(reqCtx, url)
+		cancel()
+		if err == nil {
+			return status, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (c *rollingRestartCoordinator) getBuildStatusFromURL(ctx context.Context, url string) (*memberBuildStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+memberStatusURL, nil)
+	if err != nil {
+		return nil, errs.ErrNewHTTPRequest.Wrap(err).GenWithStackByCause()
+	}
+	// Ask the member itself, otherwise the leader answers for it.
+	req.Header.Set("PD-Allow-follower-handle", "true")
+	resp, err := c.s.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, errs.ErrSendRequest.Wrap(err).GenWithStackByCause()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errs.ErrSendRequest.FastGenByArgs()
+	}
+	status := &memberBuildStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return status, nil
+}
+
+// isRestarted checks whether the member has a new start timestamp or build
+// since the restart is prepared.
+func (c *rollingRestartCoordinator) isRestarted(ctx context.Context, m *pdpb.Member, before *RollingRestartMemberStatus) bool {
+	if before.StartTimestamp == 0 {
+		return false
+	}
+	status, err := c.getBuildStatus(ctx, m)
+	if err != nil {
+		return false
+	}
+	return status.StartTimestamp > before.StartTimestamp || status.Version != before.Version || status.GitHash != before.GitHash
+}
+
+// isRejoined checks whether the member has been restarted, is healthy, and
+// its etcd has caught up with the local one.
+func (c *rollingRestartCoordinator) isRejoined(ctx context.Context, m *pdpb.Member, before *RollingRestartMemberStatus) bool {
+	if !c.isRestarted(ctx, m, before) {
+		return false
+	}
+	if len(cluster.CheckHealth(c.s.GetHTTPClient(), []*pdpb.Member{m})) == 0 {
+		return false
+	}
+	client := c.s.GetClient()
+	local, err := client.Status(ctx, c.s.GetMemberInfo().GetClientUrls()[0])
+	if err != nil {
+		return false
+	}
+	for _, url := range m.GetClientUrls() {
+		remote, err := client.Status(ctx, url)
+		if err == nil && remote.RaftIndex+etcdApplyLagThreshold >= local.RaftIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// etcdApplyLagThreshold is the max raft index gap for a restarted member to
+// be considered as synced.
+const etcdApplyLagThreshold = 100
+
+func (c *rollingRestartCoordinator) waitUntil(ctx context.Context, timeout time.Duration, f func() bool) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(rollingRestartCheckInterval)
+	defer ticker.Stop()
+	for {
+		if f() {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			return context.DeadlineExceeded
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// SetRollingRestartHook sets the hook used to restart members during a
+// rolling restart.
+func (s *Server) SetRollingRestartHook(hook RollingRestartHook) {
+	s.rollingRestart.setHook(hook)
+}
+
+// StartRollingRestart starts a rolling restart of all PD members.
+func (s *Server) StartRollingRestart(stepTimeout time.Duration) error {
+	return s.rollingRestart.start(stepTimeout)
+}
+
+// AbortRollingRestart aborts the running rolling restart.
+func (s *Server) AbortRollingRestart() error {
+	return s.rollingRestart.abort()
+}
+
+// GetRollingRestartStatus returns the status of the last rolling restart, or
+// nil if there is none.
+func (s *Server) GetRollingRestartStatus() *RollingRestartStatus {
+	return s.rollingRestart.getStatus()
+}
+
+// MarkRollingRestartMemberRestarted tells the coordinator that the member
+// which is waiting for restart has been restarted.
+func (s *Server) MarkRollingRestartMemberRestarted(name string) error {
+	return s.rollingRestart.markRestarted(name)
+}
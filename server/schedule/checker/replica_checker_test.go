@@ -447,6 +447,35 @@ func (s *testReplicaCheckerSuite) TestDistinctScore2(c *C) {
 	c.Assert(rc.Check(region), IsNil)
 }
 
+func (s *testReplicaCheckerSuite) TestSoftAntiAffinity(c *C) {
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(opt)
+	tc.DisableFeature(versioninfo.JointConsensus)
+	tc.SetMaxReplicas(3)
+	tc.SetLocationLabels([]string{"zone", "host"})
+
+	rc := NewReplicaChecker(tc, cache.NewDefaultCache(10))
+
+	tc.AddLabelsStore(1, 1, map[string]string{"zone": "z1", "host": "h1", "power": "p1"})
+	tc.AddLabelsStore(2, 1, map[string]string{"zone": "z2", "host": "h1", "power": "p2"})
+	tc.AddLabelsStore(3, 1, map[string]string{"zone": "z3", "host": "h1", "power": "p1"})
+	tc.AddLabelsStore(4, 2, map[string]string{"zone": "z3", "host": "h2", "power": "p3"})
+
+	tc.AddLeaderRegion(1, 1, 2)
+	region := tc.GetRegion(1)
+
+	// Store 3 has smaller region score.
+	testutil.CheckAddPeer(c, rc.Check(region), operator.OpReplica, 3)
+
+	// Store 3 shares the same power feed with store 1, so store 4 is preferred.
+	tc.SetSoftAntiAffinityLabels([]string{"power"})
+	testutil.CheckAddPeer(c, rc.Check(region), operator.OpReplica, 4)
+
+	// Soft anti-affinity never blocks the schedule.
+	tc.SetStoreOffline(4)
+	testutil.CheckAddPeer(c, rc.Check(region), operator.OpReplica, 3)
+}
+
 func (s *testReplicaCheckerSuite) TestStorageThreshold(c *C) {
 	opt := config.NewTestOptions()
 	tc := mockcluster.NewCluster(opt)
@@ -43,6 +43,13 @@ type ReplicaStrategy struct {
 // Meanwhile, we need to provide more constraints to ensure that the isolation
 // level cannot be reduced after replacement.
 func (s *ReplicaStrategy) SelectStoreToAdd(coLocationStores []*core.StoreInfo, extraFilters ...filter.Filter) uint64 {
+	return s.selectStoreToAdd(coLocationStores, true, extraFilters...)
+}
+
+// selectStoreToAdd selects the store to add a replica to. The repair of a
+// missing or replaced replica can select the stores whose maintenance windows
+// have not started yet, while the improvement of the placement cannot.
+func (s *ReplicaStrategy) selectStoreToAdd(coLocationStores []*core.StoreInfo, repair bool, extraFilters ...filter.Filter) uint64 {
 	// The selection process uses a two-stage fashion. The first stage
 	// ignores the temporary state of the stores and selects the stores
 	// with the highest score according to the location label. The second
@@ -68,11 +75,15 @@ func (s *ReplicaStrategy) SelectStoreToAdd(coLocationStores []*core.StoreInfo, e
 	}
 
 	isolationComparer := filter.IsolationComparer(s.locationLabels, coLocationStores)
-	strictStateFilter := &filter.StoreStateFilter{ActionScope: s.checkerName, MoveRegion: true}
+	scoreComparer := filter.CombineComparers(
+		filter.AntiAffinityComparer(s.cluster.GetOpts().GetSoftAntiAffinityLabels(), coLocationStores),
+		filter.RegionScoreComparer(s.cluster.GetOpts()),
+	)
+	strictStateFilter := &filter.StoreStateFilter{ActionScope: s.checkerName, MoveRegion: true, Repair: repair}
 	target := filter.NewCandidates(s.cluster.GetStores()).
 		FilterTarget(s.cluster.GetOpts(), filters...).
 		Sort(isolationComparer).Reverse().Top(isolationComparer).        // greater isolation score is better
-		Sort(filter.RegionScoreComparer(s.cluster.GetOpts())).           // less region score is better
+		Sort(scoreComparer).                                             // less anti-affinity score and region score is better
 		FilterTarget(s.cluster.GetOpts(), strictStateFilter).PickFirst() // the filter does not ignore temp states
 	if target == nil {
 		return 0
@@ -100,7 +111,7 @@ func (s *ReplicaStrategy) SelectStoreToImprove(coLocationStores []*core.StoreInf
 	if len(s.locationLabels) > 0 && s.isolationLevel != "" {
 		filters = append(filters, filter.NewIsolationFilter(s.checkerName, s.isolationLevel, s.locationLabels, coLocationStores[1:]))
 	}
-	return s.SelectStoreToAdd(coLocationStores[1:], filters...)
+	return s.selectStoreToAdd(coLocationStores[1:], false, filters...)
 }
 
 func (s *ReplicaStrategy) swapStoreToFirst(stores []*core.StoreInfo, id uint64) {
@@ -115,10 +126,14 @@ func (s *ReplicaStrategy) swapStoreToFirst(stores []*core.StoreInfo, id uint64)
 // SelectStoreToRemove returns the best option to remove from the region.
 func (s *ReplicaStrategy) SelectStoreToRemove(coLocationStores []*core.StoreInfo) uint64 {
 	isolationComparer := filter.IsolationComparer(s.locationLabels, coLocationStores)
+	scoreComparer := filter.CombineComparers(
+		filter.AntiAffinityComparer(s.cluster.GetOpts().GetSoftAntiAffinityLabels(), coLocationStores),
+		filter.RegionScoreComparer(s.cluster.GetOpts()),
+	)
 	source := filter.NewCandidates(coLocationStores).
 		FilterSource(s.cluster.GetOpts(), &filter.StoreStateFilter{ActionScope: replicaCheckerName, MoveRegion: true}).
 		Sort(isolationComparer).Top(isolationComparer).
-		Sort(filter.RegionScoreComparer(s.cluster.GetOpts())).Reverse().
+		Sort(scoreComparer).Reverse().
 		PickFirst()
 	if source == nil {
 		log.Debug("no removable store", zap.Uint64("region-id", s.region.GetID()))
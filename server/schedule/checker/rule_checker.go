@@ -266,14 +266,43 @@ func (c *RuleChecker) isOfflinePeer(region *core.RegionInfo, peer *metapb.Peer)
 }
 
 func (c *RuleChecker) strategy(region *core.RegionInfo, rule *placement.Rule) *ReplicaStrategy {
+	extraFilters := []filter.Filter{filter.NewLabelConstaintFilter(c.name, rule.LabelConstraints)}
+	if tierFilter := c.tierFilter(region, rule); tierFilter != nil {
+		extraFilters = append(extraFilters, tierFilter)
+	}
 	return &ReplicaStrategy{
 		checkerName:    c.name,
 		cluster:        c.cluster,
 		isolationLevel: rule.IsolationLevel,
 		locationLabels: rule.LocationLabels,
 		region:         region,
-		extraFilters:   []filter.Filter{filter.NewLabelConstaintFilter(c.name, rule.LabelConstraints)},
+		extraFilters:   extraFilters,
+	}
+}
+
+// tierFilter keeps the new peers in the tier of the region, if all the peers
+// of the region are in the same tier and the rule does not constrain the tier.
+func (c *RuleChecker) tierFilter(region *core.RegionInfo, rule *placement.Rule) filter.Filter {
+	label := c.cluster.GetOpts().GetStoreTierLabel()
+	if label == "" {
+		return nil
+	}
+	for _, constraint := range rule.LabelConstraints {
+		if constraint.Key == label {
+			return nil
+		}
+	}
+	stores := c.cluster.GetRegionStores(region)
+	if len(stores) == 0 {
+		return nil
+	}
+	tier := stores[0].GetLabelValue(label)
+	for _, store := range stores[1:] {
+		if store.GetLabelValue(label) != tier {
+			return nil
+		}
 	}
+	return filter.NewTierFilter(c.name, label, tier)
 }
 
 func (c *RuleChecker) getRuleFitStores(rf *placement.RuleFit) []*core.StoreInfo {
@@ -112,6 +112,43 @@ func (s *testRuleCheckerSuite) TestAddRulePeerWithIsolationLevel(c *C) {
 	c.Assert(op.Step(0).(operator.AddLearner).ToStore, Equals, uint64(4))
 }
 
+func (s *testRuleCheckerSuite) TestReplacePeerInTier(c *C) {
+	s.cluster.SetStoreTierLabel("tier")
+	s.cluster.AddLabelsStore(1, 1, map[string]string{"tier": "hot"})
+	s.cluster.AddLabelsStore(2, 1, map[string]string{"tier": "hot"})
+	s.cluster.AddLabelsStore(3, 1, map[string]string{"tier": "hot"})
+	s.cluster.AddLabelsStore(4, 1, map[string]string{"tier": "cold"})
+	s.cluster.AddLabelsStore(5, 10, map[string]string{"tier": "hot"})
+	s.cluster.AddLeaderRegionWithRange(1, "", "", 1, 2, 3)
+	s.cluster.SetStoreDown(2)
+	r := s.cluster.GetRegion(1)
+	r = r.Clone(core.WithDownPeers([]*pdpb.PeerStats{{Peer: r.GetStorePeer(2), DownSeconds: 60000}}))
+	// Store 4 has fewer regions but is in another tier.
+	op := s.rc.Check(r)
+	c.Assert(op, NotNil)
+	c.Assert(op.Desc(), Equals, "replace-rule-down-peer")
+	c.Assert(op.Step(0).(operator.AddLearner).ToStore, Equals, uint64(5))
+
+	// The rule which constrains the tier decides it.
+	s.ruleManager.SetRule(&placement.Rule{
+		GroupID:          "pd",
+		ID:               "default",
+		Role:             placement.Voter,
+		Count:            3,
+		LabelConstraints: []placement.LabelConstraint{placement.TierConstraint("tier", "cold")},
+	})
+	op = s.rc.Check(r)
+	c.Assert(op, NotNil)
+	c.Assert(op.Step(0).(operator.AddLearner).ToStore, Equals, uint64(4))
+
+	// No tier is kept without the tier label.
+	s.ruleManager.SetRule(&placement.Rule{GroupID: "pd", ID: "default", Role: placement.Voter, Count: 3})
+	s.cluster.SetStoreTierLabel("")
+	op = s.rc.Check(r)
+	c.Assert(op, NotNil)
+	c.Assert(op.Step(0).(operator.AddLearner).ToStore, Equals, uint64(4))
+}
+
 func (s *testRuleCheckerSuite) TestFixPeer(c *C) {
 	s.cluster.AddLeaderStore(1, 1)
 	s.cluster.AddLeaderStore(2, 1)
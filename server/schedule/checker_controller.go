@@ -60,6 +60,14 @@ func NewCheckerController(ctx context.Context, cluster opt.Cluster, ruleManager
 
 // CheckRegion will check the region and add a new operator if needed.
 func (c *CheckerController) CheckRegion(region *core.RegionInfo) []*operator.Operator {
+	ops := c.checkRegion(region)
+	for _, op := range ops {
+		op.SetSource(operator.SourceChecker)
+	}
+	return ops
+}
+
+func (c *CheckerController) checkRegion(region *core.RegionInfo) []*operator.Operator {
 	// If PD has restarted, it need to check learners added before and promote them.
 	// Don't check isRaftLearnerEnabled cause it maybe disable learner feature but there are still some learners to promote.
 	opController := c.opController
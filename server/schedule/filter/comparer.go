@@ -54,3 +54,34 @@ func IsolationComparer(locationLabels []string, regionStores []*core.StoreInfo)
 		}
 	}
 }
+
+// AntiAffinityComparer creates a StoreComparer to sort store by soft
+// anti-affinity score.
+func AntiAffinityComparer(labels []string, regionStores []*core.StoreInfo) StoreComparer {
+	return func(a, b *core.StoreInfo) int {
+		sa := core.AntiAffinityScore(labels, regionStores, a)
+		sb := core.AntiAffinityScore(labels, regionStores, b)
+		switch {
+		case sa > sb:
+			return 1
+		case sa < sb:
+			return -1
+		default:
+			return 0
+		}
+	}
+}
+
+// CombineComparers creates a StoreComparer that compares stores by the given
+// comparers in order. The latter comparer is used only when the former ones
+// consider the stores equal.
+func CombineComparers(comparers ...StoreComparer) StoreComparer {
+	return func(a, b *core.StoreInfo) int {
+		for _, cmp := range comparers {
+			if r := cmp(a, b); r != 0 {
+				return r
+			}
+		}
+		return 0
+	}
+}
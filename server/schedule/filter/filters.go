@@ -15,6 +15,7 @@ package filter
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -112,6 +113,12 @@ func Source(opt *config.PersistOptions, store *core.StoreInfo, filters []Filter)
 
 // Target checks if store can pass all Filters as target store.
 func Target(opt *config.PersistOptions, store *core.StoreInfo, filters []Filter) bool {
+	return TargetRejectedBy(opt, store, filters) == nil
+}
+
+// TargetRejectedBy returns the first filter which rejects the store as target
+// store, or nil if the store passes all Filters.
+func TargetRejectedBy(opt *config.PersistOptions, store *core.StoreInfo, filters []Filter) Filter {
 	storeAddress := store.GetAddress()
 	storeID := fmt.Sprintf("%d", store.GetID())
 	for _, filter := range filters {
@@ -124,10 +131,10 @@ func Target(opt *config.PersistOptions, store *core.StoreInfo, filters []Filter)
 			}
 			filterCounter.WithLabelValues("filter-target", storeAddress,
 				targetID, filter.Scope(), filter.Type(), sourceID, targetID).Inc()
-			return false
+			return filter
 		}
 	}
-	return true
+	return nil
 }
 
 type excludedFilter struct {
@@ -203,6 +210,9 @@ const (
 	// 'improve' ensures replacement is BETTER than before.
 	locationSafeguard = "safeguard"
 	locationImprove   = "improve"
+	// plannedMaintenanceLookahead is how long before a maintenance window the
+	// store stops taking the regions.
+	plannedMaintenanceLookahead = time.Hour
 )
 
 // NewLocationSafeguard creates a filter that filters all stores that have
@@ -277,6 +287,9 @@ type StoreStateFilter struct {
 	ScatterRegion bool
 	// Set true if allows temporary states.
 	AllowTemporaryStates bool
+	// Set true if the schedule repairs the replicas, which can be moved to the
+	// stores whose maintenance windows have not started yet.
+	Repair bool
 	// Reason is used to distinguish the reason of store state filter
 	Reason string
 }
@@ -310,6 +323,29 @@ func (f *StoreStateFilter) isOffline(opt *config.PersistOptions, store *core.Sto
 	return store.IsOffline()
 }
 
+func (f *StoreStateFilter) isDraining(opt *config.PersistOptions, store *core.StoreInfo) bool {
+	f.Reason = "draining"
+	return store.IsDraining()
+}
+
+func (f *StoreStateFilter) inMaintenance(opt *config.PersistOptions, store *core.StoreInfo) bool {
+	f.Reason = "maintenance"
+	return store.IsInMaintenance()
+}
+
+func (f *StoreStateFilter) plannedMaintenance(opt *config.PersistOptions, store *core.StoreInfo) bool {
+	f.Reason = "planned-maintenance"
+	if f.Repair {
+		return store.IsInMaintenance()
+	}
+	return store.HasMaintenanceWithin(plannedMaintenanceLookahead)
+}
+
+func (f *StoreStateFilter) lacksReservedSpace(opt *config.PersistOptions, store *core.StoreInfo) bool {
+	f.Reason = "reserved-space"
+	return !f.AllowTemporaryStates && store.IsLowSpaceAfterReserved(opt.GetLowSpaceRatio())
+}
+
 func (f *StoreStateFilter) pauseLeaderTransfer(opt *config.PersistOptions, store *core.StoreInfo) bool {
 	f.Reason = "pause-leader"
 	return !store.AllowLeaderTransfer()
@@ -337,9 +373,10 @@ func (f *StoreStateFilter) exceedAddLimit(opt *config.PersistOptions, store *cor
 
 func (f *StoreStateFilter) tooManySnapshots(opt *config.PersistOptions, store *core.StoreInfo) bool {
 	f.Reason = "too-many-snapshot"
-	return !f.AllowTemporaryStates && (uint64(store.GetSendingSnapCount()) > opt.GetMaxSnapshotCount() ||
-		uint64(store.GetReceivingSnapCount()) > opt.GetMaxSnapshotCount() ||
-		uint64(store.GetApplyingSnapCount()) > opt.GetMaxSnapshotCount())
+	maxSnapshotCount := opt.GetStoreMaxSnapshotCount(store)
+	return !f.AllowTemporaryStates && (uint64(store.GetSendingSnapCount()) > maxSnapshotCount ||
+		uint64(store.GetReceivingSnapCount()) > maxSnapshotCount ||
+		uint64(store.GetApplyingSnapCount()) > maxSnapshotCount)
 }
 
 func (f *StoreStateFilter) tooManyPendingPeers(opt *config.PersistOptions, store *core.StoreInfo) bool {
@@ -359,13 +396,16 @@ func (f *StoreStateFilter) hasRejectLeaderProperty(opts *config.PersistOptions,
 // N: the condition is expected to be true for a long time.
 // X means when the condition is true, the store CANNOT be selected.
 //
-// Condition    Down Offline Tomb Pause Disconn Busy RmLimit AddLimit Snap Pending Reject
-// IsTemporary  N    N       N    N     Y       Y    Y       Y        Y    Y       N
+// Condition    Down Offline Tomb Pause Disconn Busy RmLimit AddLimit Snap Pending Reject Drain Maint PlannedMaint Reserved
+// IsTemporary  N    N       N    N     Y       Y    Y       Y        Y    Y       N      N     Y     Y            Y
 //
 // LeaderSource X            X    X     X
 // RegionSource                                 X    X                X
-// LeaderTarget X    X       X    X     X       X                                  X
-// RegionTarget X    X       X          X       X            X        X    X
+// LeaderTarget X    X       X    X     X       X                                  X      X     X
+// RegionTarget X    X       X          X       X            X        X    X              X           X            X
+//
+// PlannedMaint only counts the windows starting within the lookahead, and the
+// repair only avoids the windows already started.
 
 const (
 	leaderSource = iota
@@ -384,12 +424,14 @@ func (f *StoreStateFilter) anyConditionMatch(typ int, opt *config.PersistOptions
 		funcs = []conditionFunc{f.isBusy, f.exceedRemoveLimit, f.tooManySnapshots}
 	case leaderTarget:
 		funcs = []conditionFunc{f.isTombstone, f.isOffline, f.isDown, f.pauseLeaderTransfer,
-			f.isDisconnected, f.isBusy, f.hasRejectLeaderProperty}
+			f.isDisconnected, f.isBusy, f.hasRejectLeaderProperty, f.isDraining, f.inMaintenance}
 	case regionTarget:
 		funcs = []conditionFunc{f.isTombstone, f.isOffline, f.isDown, f.isDisconnected, f.isBusy,
-			f.exceedAddLimit, f.tooManySnapshots, f.tooManyPendingPeers}
+			f.exceedAddLimit, f.tooManySnapshots, f.tooManyPendingPeers, f.isDraining, f.plannedMaintenance,
+			f.lacksReservedSpace}
 	case scatterRegionTarget:
-		funcs = []conditionFunc{f.isTombstone, f.isOffline, f.isDown, f.isDisconnected, f.isBusy}
+		funcs = []conditionFunc{f.isTombstone, f.isOffline, f.isDown, f.isDisconnected, f.isBusy, f.isDraining,
+			f.plannedMaintenance, f.lacksReservedSpace}
 	}
 	for _, cf := range funcs {
 		if cf(opt, store) {
@@ -437,6 +479,11 @@ func NewLabelConstaintFilter(scope string, constraints []placement.LabelConstrai
 	return labelConstraintFilter{scope: scope, constraints: constraints}
 }
 
+// NewTierFilter creates a filter that selects the stores in the tier.
+func NewTierFilter(scope, label, tier string) Filter {
+	return NewLabelConstaintFilter(scope, []placement.LabelConstraint{placement.TierConstraint(label, tier)})
+}
+
 // Scope returns the scheduler or the checker which the filter acts on.
 func (f labelConstraintFilter) Scope() string {
 	return f.scope
@@ -577,15 +624,15 @@ func NewPlacementLeaderSafeguard(scope string, cluster opt.Cluster, region *core
 }
 
 type engineFilter struct {
-	scope      string
-	constraint placement.LabelConstraint
+	scope          string
+	allowedEngines []string
 }
 
 // NewEngineFilter creates a filter that only keeps allowedEngines.
 func NewEngineFilter(scope string, allowedEngines ...string) Filter {
 	return &engineFilter{
-		scope:      scope,
-		constraint: placement.LabelConstraint{Key: "engine", Op: "in", Values: allowedEngines},
+		scope:          scope,
+		allowedEngines: allowedEngines,
 	}
 }
 
@@ -598,24 +645,25 @@ func (f *engineFilter) Type() string {
 }
 
 func (f *engineFilter) Source(opt *config.PersistOptions, store *core.StoreInfo) bool {
-	return f.constraint.MatchStore(store)
+	return f.match(store)
 }
 
 func (f *engineFilter) Target(opt *config.PersistOptions, store *core.StoreInfo) bool {
-	return f.constraint.MatchStore(store)
+	return f.match(store)
+}
+
+func (f *engineFilter) match(store *core.StoreInfo) bool {
+	engine := store.GetCapabilities().Engine
+	return slice.AnyOf(f.allowedEngines, func(i int) bool { return f.allowedEngines[i] == engine })
 }
 
 type ordinaryEngineFilter struct {
-	scope      string
-	constraint placement.LabelConstraint
+	scope string
 }
 
 // NewOrdinaryEngineFilter creates a filter that only keeps ordinary engine stores.
 func NewOrdinaryEngineFilter(scope string) Filter {
-	return &ordinaryEngineFilter{
-		scope:      scope,
-		constraint: placement.LabelConstraint{Key: "engine", Op: "notIn", Values: allSpeicalEngines},
-	}
+	return &ordinaryEngineFilter{scope: scope}
 }
 
 func (f *ordinaryEngineFilter) Scope() string {
@@ -627,11 +675,16 @@ func (f *ordinaryEngineFilter) Type() string {
 }
 
 func (f *ordinaryEngineFilter) Source(opt *config.PersistOptions, store *core.StoreInfo) bool {
-	return f.constraint.MatchStore(store)
+	return f.match(store)
 }
 
 func (f *ordinaryEngineFilter) Target(opt *config.PersistOptions, store *core.StoreInfo) bool {
-	return f.constraint.MatchStore(store)
+	return f.match(store)
+}
+
+func (f *ordinaryEngineFilter) match(store *core.StoreInfo) bool {
+	engine := store.GetCapabilities().Engine
+	return slice.NoneOf(allSpeicalEngines, func(i int) bool { return allSpeicalEngines[i] == engine })
 }
 
 type specialUseFilter struct {
@@ -685,9 +738,9 @@ const (
 	// EngineKey is the label key used to indicate engine.
 	EngineKey = "engine"
 	// EngineTiFlash is the tiflash value of the engine label.
-	EngineTiFlash = "tiflash"
+	EngineTiFlash = core.EngineTiFlash
 	// EngineTiKV indicates the tikv engine in metrics
-	EngineTiKV = "tikv"
+	EngineTiKV = core.EngineTiKV
 )
 
 var allSpecialUses = []string{SpecialUseHotRegion, SpecialUseReserved}
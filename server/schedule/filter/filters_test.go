@@ -137,6 +137,7 @@ func (s *testFiltersSuite) TestStoreStateFilter(c *C) {
 		&StoreStateFilter{MoveRegion: true},
 		&StoreStateFilter{TransferLeader: true, MoveRegion: true},
 		&StoreStateFilter{MoveRegion: true, AllowTemporaryStates: true},
+		&StoreStateFilter{MoveRegion: true, Repair: true},
 	}
 	opt := config.NewTestOptions()
 	store := core.NewStoreInfoWithLabel(1, 0, map[string]string{})
@@ -180,6 +181,60 @@ func (s *testFiltersSuite) TestStoreStateFilter(c *C) {
 		{3, true, true},
 	}
 	check(store, testCases)
+
+	// Draining
+	store = store.Clone(core.SetNewStoreStats(&pdpb.StoreStats{})).
+		Clone(core.SetStoreDrain(&core.StoreDrain{StartTime: time.Now()}))
+	testCases = []testCase{
+		{0, true, false},
+		{1, true, false},
+		{2, true, false},
+		{3, true, false},
+	}
+	check(store, testCases)
+
+	// Maintenance
+	store = store.Clone(core.SetStoreDrain(nil)).
+		Clone(core.SetStoreMaintenance(&core.StoreMaintenance{StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)}))
+	testCases = []testCase{
+		{0, true, false},
+		{1, true, false},
+		{2, true, false},
+		{3, true, false},
+		{4, true, false},
+	}
+	check(store, testCases)
+
+	// Planned maintenance within the lookahead, which the repair ignores
+	store = store.Clone(core.SetStoreMaintenance(&core.StoreMaintenance{StartTime: time.Now().Add(time.Hour), EndTime: time.Now().Add(2 * time.Hour)}))
+	testCases = []testCase{
+		{0, true, true},
+		{1, true, false},
+		{2, true, false},
+		{3, true, false},
+		{4, true, true},
+	}
+	check(store, testCases)
+
+	// Planned maintenance beyond the lookahead
+	store = store.Clone(core.SetStoreMaintenance(&core.StoreMaintenance{StartTime: time.Now().Add(2 * time.Hour), EndTime: time.Now().Add(3 * time.Hour)}))
+	testCases = []testCase{
+		{1, true, true},
+		{4, true, true},
+	}
+	check(store, testCases)
+
+	// Reserved for the offline stores
+	store = store.Clone(core.SetStoreMaintenance(nil)).
+		Clone(core.SetStoreStats(&pdpb.StoreStats{Capacity: 100 << 30, Available: 30 << 30})).
+		Clone(core.SetStoreReservedSize(15 * 1024))
+	testCases = []testCase{
+		{0, true, true},
+		{1, true, false},
+		{2, true, false},
+		{3, true, true},
+	}
+	check(store, testCases)
 }
 
 func (s *testFiltersSuite) TestIsolationFilter(c *C) {
@@ -58,6 +58,14 @@ var (
 			Help:      "limit rate cost of store.",
 		}, []string{"store", "limit_type"})
 
+	snapshotLimitExceededCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "snapshot_limit_exceeded",
+			Help:      "Counter of operators rejected by the snapshot limit of store.",
+		}, []string{"store"})
+
 	scatterCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "pd",
@@ -80,6 +88,7 @@ func init() {
 	prometheus.MustRegister(operatorDuration)
 	prometheus.MustRegister(operatorWaitDuration)
 	prometheus.MustRegister(storeLimitCostCounter)
+	prometheus.MustRegister(snapshotLimitExceededCounter)
 	prometheus.MustRegister(operatorWaitCounter)
 	prometheus.MustRegister(scatterCounter)
 	prometheus.MustRegister(scatterDistributionCounter)
@@ -40,6 +40,10 @@ type StoreInfluence struct {
 	LeaderSize  int64
 	LeaderCount int64
 	StepCost    map[storelimit.Type]int64
+	// SendingSnapCount and ReceivingSnapCount are the snapshots the
+	// operators will send from or to the store.
+	SendingSnapCount   int64
+	ReceivingSnapCount int64
 }
 
 // ResourceProperty returns delta size of leader/region by influence.
@@ -76,6 +80,15 @@ func (s *StoreInfluence) addStepCost(limitType storelimit.Type, cost int64) {
 	s.StepCost[limitType] += cost
 }
 
+// addSnapshot records the snapshot sent from the leader of the region to the
+// store.
+func addSnapshot(opInfluence OpInfluence, region *core.RegionInfo, toStore uint64) {
+	opInfluence.GetStoreInfluence(toStore).ReceivingSnapCount++
+	if leader := region.GetLeader(); leader != nil {
+		opInfluence.GetStoreInfluence(leader.GetStoreId()).SendingSnapCount++
+	}
+}
+
 // AdjustStepCost adjusts the step cost of specific type store limit according to region size
 func (s *StoreInfluence) AdjustStepCost(limitType storelimit.Type, regionSize int64) {
 	if regionSize > storelimit.SmallRegionThreshold {
@@ -38,6 +38,13 @@ const (
 	SlowOperatorWaitTime = 10 * time.Minute
 )
 
+// The sources of the operators.
+const (
+	SourceScheduler = "scheduler"
+	SourceChecker   = "checker"
+	SourceAPI       = "api"
+)
+
 // Operator contains execution steps generated by scheduler.
 type Operator struct {
 	desc             string
@@ -50,6 +57,7 @@ type Operator struct {
 	currentStep      int32
 	status           OpStatusTracker
 	level            core.PriorityLevel
+	source           string
 	Counters         []prometheus.Counter
 	FinishedCounters []prometheus.Counter
 	AdditionalInfos  map[string]string
@@ -105,6 +113,20 @@ func (o *Operator) SetDesc(desc string) {
 	o.desc = desc
 }
 
+// Source returns where the operator comes from. The operators which are not
+// generated by the schedulers or the checkers are requested through the API.
+func (o *Operator) Source() string {
+	if o.source == "" {
+		return SourceAPI
+	}
+	return o.source
+}
+
+// SetSource sets where the operator comes from.
+func (o *Operator) SetSource(source string) {
+	o.source = source
+}
+
 // AttachKind attaches an operator kind for the operator.
 func (o *Operator) AttachKind(kind OpKind) {
 	o.kind |= kind
@@ -334,6 +356,37 @@ func (o *Operator) History() []OpHistory {
 	return histories
 }
 
+// TargetStores returns the stores which the operator adds a peer to, promotes
+// a learner on, or transfers the leader to.
+func (o *Operator) TargetStores() []uint64 {
+	var stores []uint64
+	add := func(storeID uint64) {
+		for _, id := range stores {
+			if id == storeID {
+				return
+			}
+		}
+		stores = append(stores, storeID)
+	}
+	for _, step := range o.steps {
+		switch s := step.(type) {
+		case TransferLeader:
+			add(s.ToStore)
+		case AddPeer:
+			add(s.ToStore)
+		case AddLightPeer:
+			add(s.ToStore)
+		case AddLearner:
+			add(s.ToStore)
+		case AddLightLearner:
+			add(s.ToStore)
+		case PromoteLearner:
+			add(s.ToStore)
+		}
+	}
+	return stores
+}
+
 // GetAdditionalInfo returns additional info with string
 func (o *Operator) GetAdditionalInfo() string {
 	if len(o.AdditionalInfos) != 0 {
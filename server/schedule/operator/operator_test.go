@@ -157,6 +157,8 @@ func (s *testOperatorSuite) TestInfluence(c *C) {
 		RegionSize:  50,
 		RegionCount: 1,
 		StepCost:    map[storelimit.Type]int64{storelimit.AddPeer: 1000},
+
+		ReceivingSnapCount: 1,
 	})
 
 	TransferLeader{FromStore: 1, ToStore: 2}.Influence(opInfluence, region)
@@ -166,6 +168,8 @@ func (s *testOperatorSuite) TestInfluence(c *C) {
 		RegionSize:  0,
 		RegionCount: 0,
 		StepCost:    nil,
+
+		SendingSnapCount: 1,
 	})
 	c.Assert(*storeOpInfluence[2], DeepEquals, StoreInfluence{
 		LeaderSize:  50,
@@ -173,6 +177,8 @@ func (s *testOperatorSuite) TestInfluence(c *C) {
 		RegionSize:  50,
 		RegionCount: 1,
 		StepCost:    map[storelimit.Type]int64{storelimit.AddPeer: 1000},
+
+		ReceivingSnapCount: 1,
 	})
 
 	RemovePeer{FromStore: 1}.Influence(opInfluence, region)
@@ -182,6 +188,8 @@ func (s *testOperatorSuite) TestInfluence(c *C) {
 		RegionSize:  -50,
 		RegionCount: -1,
 		StepCost:    map[storelimit.Type]int64{storelimit.RemovePeer: 1000},
+
+		SendingSnapCount: 1,
 	})
 	c.Assert(*storeOpInfluence[2], DeepEquals, StoreInfluence{
 		LeaderSize:  50,
@@ -189,6 +197,8 @@ func (s *testOperatorSuite) TestInfluence(c *C) {
 		RegionSize:  50,
 		RegionCount: 1,
 		StepCost:    map[storelimit.Type]int64{storelimit.AddPeer: 1000},
+
+		ReceivingSnapCount: 1,
 	})
 
 	MergeRegion{IsPassive: false}.Influence(opInfluence, region)
@@ -198,6 +208,8 @@ func (s *testOperatorSuite) TestInfluence(c *C) {
 		RegionSize:  -50,
 		RegionCount: -1,
 		StepCost:    map[storelimit.Type]int64{storelimit.RemovePeer: 1000},
+
+		SendingSnapCount: 1,
 	})
 	c.Assert(*storeOpInfluence[2], DeepEquals, StoreInfluence{
 		LeaderSize:  50,
@@ -205,6 +217,8 @@ func (s *testOperatorSuite) TestInfluence(c *C) {
 		RegionSize:  50,
 		RegionCount: 1,
 		StepCost:    map[storelimit.Type]int64{storelimit.AddPeer: 1000},
+
+		ReceivingSnapCount: 1,
 	})
 
 	MergeRegion{IsPassive: true}.Influence(opInfluence, region)
@@ -214,6 +228,8 @@ func (s *testOperatorSuite) TestInfluence(c *C) {
 		RegionSize:  -50,
 		RegionCount: -2,
 		StepCost:    map[storelimit.Type]int64{storelimit.RemovePeer: 1000},
+
+		SendingSnapCount: 1,
 	})
 	c.Assert(*storeOpInfluence[2], DeepEquals, StoreInfluence{
 		LeaderSize:  50,
@@ -221,6 +237,8 @@ func (s *testOperatorSuite) TestInfluence(c *C) {
 		RegionSize:  50,
 		RegionCount: 0,
 		StepCost:    map[storelimit.Type]int64{storelimit.AddPeer: 1000},
+
+		ReceivingSnapCount: 1,
 	})
 }
 
@@ -312,6 +330,19 @@ func (s *testOperatorSuite) TestStart(c *C) {
 	c.Assert(op.Status(), Equals, STARTED)
 }
 
+func (s *testOperatorSuite) TestTargetStores(c *C) {
+	steps := []OpStep{
+		AddLearner{ToStore: 3, PeerID: 3},
+		PromoteLearner{ToStore: 3, PeerID: 3},
+		TransferLeader{FromStore: 1, ToStore: 2},
+		RemovePeer{FromStore: 1},
+		AddLightPeer{ToStore: 4, PeerID: 4},
+	}
+	op := s.newTestOperator(1, OpLeader|OpRegion, steps...)
+	c.Assert(op.TargetStores(), DeepEquals, []uint64{3, 2, 4})
+	c.Assert(s.newTestOperator(1, OpRegion, RemovePeer{FromStore: 1}).TargetStores(), HasLen, 0)
+}
+
 func (s *testOperatorSuite) TestCheckExpired(c *C) {
 	steps := []OpStep{
 		AddPeer{ToStore: 1, PeerID: 1},
@@ -115,6 +115,7 @@ func (ap AddPeer) Influence(opInfluence OpInfluence, region *core.RegionInfo) {
 	to.RegionSize += regionSize
 	to.RegionCount++
 	to.AdjustStepCost(storelimit.AddPeer, regionSize)
+	addSnapshot(opInfluence, region, ap.ToStore)
 }
 
 // CheckSafety checks if the step meets the safety properties.
@@ -176,6 +177,7 @@ func (al AddLearner) Influence(opInfluence OpInfluence, region *core.RegionInfo)
 	to.RegionSize += regionSize
 	to.RegionCount++
 	to.AdjustStepCost(storelimit.AddPeer, regionSize)
+	addSnapshot(opInfluence, region, al.ToStore)
 }
 
 // PromoteLearner is an OpStep that promotes a region learner peer to normal voter.
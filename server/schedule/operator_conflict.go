@@ -0,0 +1,186 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+// The types of the nodes in the operator conflict graph.
+const (
+	ConflictNodeWaiting = "waiting-operator"
+	ConflictNodeRunning = "running-operator"
+	ConflictNodeStore   = "store"
+)
+
+// The reasons of the edges in the operator conflict graph.
+const (
+	// ConflictReasonRegion means the region has a running operator with the
+	// same or a higher priority.
+	ConflictReasonRegion = "region"
+	// ConflictReasonStoreLimit means the store limit of the type in the
+	// detail is used up.
+	ConflictReasonStoreLimit = "store-limit"
+	// ConflictReasonSnapshotLimit means the store sends or receives too many
+	// snapshots.
+	ConflictReasonSnapshotLimit = "snapshot-limit"
+	// ConflictReasonInFlight means the running operator has unfinished steps
+	// on the store.
+	ConflictReasonInFlight = "in-flight"
+)
+
+// ConflictNode is an operator or a store in the operator conflict graph.
+type ConflictNode struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	RegionID uint64 `json:"region_id,omitempty"`
+	StoreID  uint64 `json:"store_id,omitempty"`
+	Desc     string `json:"desc,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+}
+
+// ConflictEdge means the node From is blocked by the node To.
+type ConflictEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ConflictGraph shows why the waiting operators cannot be promoted. The
+// waiting operators point to the running operators and the stores blocking
+// them, and the blocking stores point to the running operators in flight on
+// them.
+type ConflictGraph struct {
+	Nodes []*ConflictNode `json:"nodes"`
+	Edges []*ConflictEdge `json:"edges"`
+}
+
+func (g *ConflictGraph) addEdge(from, to, reason, detail string) {
+	g.Edges = append(g.Edges, &ConflictEdge{From: from, To: to, Reason: reason, Detail: detail})
+}
+
+// GetConflictGraph returns the conflict graph of the waiting operators. It
+// checks the waiting operators as PromoteWaitingOperator does, but lists all
+// the conflicts instead of the first one.
+func (oc *OperatorController) GetConflictGraph() *ConflictGraph {
+	oc.Lock()
+	defer oc.Unlock()
+
+	g := &ConflictGraph{Nodes: []*ConflictNode{}, Edges: []*ConflictEdge{}}
+	runningNodes := make(map[uint64]string)
+	addRunning := func(op *operator.Operator) string {
+		if id, ok := runningNodes[op.RegionID()]; ok {
+			return id
+		}
+		id := fmt.Sprintf("running-%d", op.RegionID())
+		g.Nodes = append(g.Nodes, newOperatorConflictNode(id, ConflictNodeRunning, op))
+		runningNodes[op.RegionID()] = id
+		return id
+	}
+	storeNodes := make(map[uint64]string)
+	addStore := func(storeID uint64) string {
+		if id, ok := storeNodes[storeID]; ok {
+			return id
+		}
+		id := fmt.Sprintf("store-%d", storeID)
+		g.Nodes = append(g.Nodes, &ConflictNode{ID: id, Type: ConflictNodeStore, StoreID: storeID})
+		storeNodes[storeID] = id
+		return id
+	}
+
+	limitNames := make([]string, 0, len(storelimit.TypeNameValue))
+	for name := range storelimit.TypeNameValue {
+		limitNames = append(limitNames, name)
+	}
+	sort.Strings(limitNames)
+	running := oc.getRunningOpInfluenceLocked()
+	for i, op := range oc.wop.ListOperator() {
+		id := fmt.Sprintf("waiting-%d", i)
+		g.Nodes = append(g.Nodes, newOperatorConflictNode(id, ConflictNodeWaiting, op))
+		if old := oc.operators[op.RegionID()]; old != nil && !isHigherPriorityOperator(op, old) {
+			g.addEdge(id, addRunning(old), ConflictReasonRegion, "")
+		}
+		influence := NewTotalOpInfluence([]*operator.Operator{op}, oc.cluster)
+		for _, storeID := range sortedStoreIDs(influence) {
+			storeInfluence := influence.GetStoreInfluence(storeID)
+			for _, name := range limitNames {
+				stepCost := storeInfluence.GetStepCost(storelimit.TypeNameValue[name])
+				if stepCost != 0 && oc.getOrCreateStoreLimit(storeID, storelimit.TypeNameValue[name]).Available() < stepCost {
+					g.addEdge(id, addStore(storeID), ConflictReasonStoreLimit, name)
+				}
+			}
+			if storeInfluence.SendingSnapCount == 0 && storeInfluence.ReceivingSnapCount == 0 {
+				continue
+			}
+			if store := oc.cluster.GetStore(storeID); store != nil {
+				sending, receiving, limit := oc.getSnapshotCount(store, storeInfluence, running)
+				if sending > limit || receiving > limit {
+					g.addEdge(id, addStore(storeID), ConflictReasonSnapshotLimit,
+						fmt.Sprintf("sending %d, receiving %d, limit %d", sending, receiving, limit))
+				}
+			}
+		}
+	}
+	if len(storeNodes) == 0 {
+		return g
+	}
+
+	regionIDs := make([]uint64, 0, len(oc.operators))
+	for regionID := range oc.operators {
+		regionIDs = append(regionIDs, regionID)
+	}
+	sort.Slice(regionIDs, func(i, j int) bool { return regionIDs[i] < regionIDs[j] })
+	for _, regionID := range regionIDs {
+		op := oc.operators[regionID]
+		if op.CheckTimeout() || op.CheckSuccess() {
+			continue
+		}
+		region := oc.cluster.GetRegion(regionID)
+		if region == nil {
+			continue
+		}
+		influence := operator.OpInfluence{StoresInfluence: make(map[uint64]*operator.StoreInfluence)}
+		op.UnfinishedInfluence(influence, region)
+		for _, storeID := range sortedStoreIDs(influence) {
+			if storeNode, ok := storeNodes[storeID]; ok {
+				g.addEdge(storeNode, addRunning(op), ConflictReasonInFlight, "")
+			}
+		}
+	}
+	return g
+}
+
+func newOperatorConflictNode(id, typ string, op *operator.Operator) *ConflictNode {
+	return &ConflictNode{
+		ID:       id,
+		Type:     typ,
+		RegionID: op.RegionID(),
+		Desc:     op.Desc(),
+		Kind:     op.Kind().String(),
+	}
+}
+
+func sortedStoreIDs(influence operator.OpInfluence) []uint64 {
+	storeIDs := make([]uint64, 0, len(influence.StoresInfluence))
+	for storeID := range influence.StoresInfluence {
+		storeIDs = append(storeIDs, storeID)
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+	return storeIDs
+}
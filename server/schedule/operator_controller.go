@@ -68,8 +68,28 @@ type OperatorController struct {
 	wop             WaitingOperator
 	wopStatus       *WaitingOperatorStatus
 	opNotifierQueue operatorQueue
+	// readOnly is set for the copies used by the simulation, which never add
+	// any operator.
+	readOnly  bool
+	recorders []OperatorRecorder
 }
 
+// OperatorRecorder records the events of the operators. It is called with the
+// lock of the controller held, so it should not block.
+type OperatorRecorder interface {
+	RecordOperator(op *operator.Operator, event string)
+}
+
+// The events of the operators passed to OperatorRecorder.
+const (
+	OperatorEventCreate  = "create"
+	OperatorEventFinish  = "finish"
+	OperatorEventReplace = "replace"
+	OperatorEventExpire  = "expire"
+	OperatorEventTimeout = "timeout"
+	OperatorEventCancel  = "cancel"
+)
+
 // NewOperatorController creates a OperatorController.
 func NewOperatorController(ctx context.Context, cluster opt.Cluster, hbStreams *hbstream.HeartbeatStreams) *OperatorController {
 	return &OperatorController{
@@ -87,6 +107,36 @@ func NewOperatorController(ctx context.Context, cluster opt.Cluster, hbStreams *
 	}
 }
 
+// AddOperatorRecorder adds a recorder of the operator events.
+func (oc *OperatorController) AddOperatorRecorder(recorder OperatorRecorder) {
+	oc.Lock()
+	defer oc.Unlock()
+	oc.recorders = append(oc.recorders, recorder)
+}
+
+func (oc *OperatorController) recordOperator(op *operator.Operator, event string) {
+	for _, recorder := range oc.recorders {
+		recorder.RecordOperator(op, event)
+	}
+}
+
+// ReadOnlyCopy returns a copy of the controller with the same running
+// operators, which is used to run a scheduler in simulation. No operator can
+// be added to the copy.
+func (oc *OperatorController) ReadOnlyCopy() *OperatorController {
+	oc.RLock()
+	defer oc.RUnlock()
+	c := NewOperatorController(oc.ctx, oc.cluster, nil)
+	for id, op := range oc.operators {
+		c.operators[id] = op
+	}
+	for kind, count := range oc.counts {
+		c.counts[kind] = count
+	}
+	c.readOnly = true
+	return c
+}
+
 // Ctx returns a context which will be canceled once RaftCluster is stopped.
 // For now, it is only used to control the lifetime of TTL cache in schedulers.
 func (oc *OperatorController) Ctx() context.Context {
@@ -257,6 +307,10 @@ func (oc *OperatorController) PushOperators() {
 func (oc *OperatorController) AddWaitingOperator(ops ...*operator.Operator) int {
 	oc.Lock()
 	added := 0
+	if oc.readOnly {
+		oc.Unlock()
+		return added
+	}
 
 	for i := 0; i < len(ops); i++ {
 		op := ops[i]
@@ -308,12 +362,34 @@ func (oc *OperatorController) AddWaitingOperator(ops ...*operator.Operator) int
 	return added
 }
 
+// RemoveWaitingOperator removes an operator from the waiting operators, and
+// returns the removed operators. The two operators of a merge operation are
+// removed together.
+func (oc *OperatorController) RemoveWaitingOperator(op *operator.Operator) []*operator.Operator {
+	oc.Lock()
+	ops := oc.wop.RemoveOperator(op)
+	if len(ops) > 0 {
+		oc.wopStatus.ops[ops[0].Desc()]--
+	}
+	oc.Unlock()
+	for _, op := range ops {
+		operatorWaitCounter.WithLabelValues(op.Desc(), "remove").Inc()
+		_ = op.Cancel()
+		oc.buryOperator(op)
+	}
+	return ops
+}
+
 // AddOperator adds operators to the running operators.
 func (oc *OperatorController) AddOperator(ops ...*operator.Operator) bool {
 	oc.Lock()
 	defer oc.Unlock()
 
-	if oc.exceedStoreLimitLocked(ops...) || !oc.checkAddOperator(ops...) {
+	if oc.readOnly {
+		return false
+	}
+
+	if oc.exceedStoreLimitLocked(ops...) || oc.exceedSnapshotLimitLocked(ops...) || !oc.checkAddOperator(ops...) {
 		for _, op := range ops {
 			_ = op.Cancel()
 			oc.buryOperator(op)
@@ -341,7 +417,7 @@ func (oc *OperatorController) PromoteWaitingOperator() {
 		}
 		operatorWaitCounter.WithLabelValues(ops[0].Desc(), "get").Inc()
 
-		if oc.exceedStoreLimitLocked(ops...) || !oc.checkAddOperator(ops...) {
+		if oc.exceedStoreLimitLocked(ops...) || oc.exceedSnapshotLimitLocked(ops...) || !oc.checkAddOperator(ops...) {
 			for _, op := range ops {
 				operatorWaitCounter.WithLabelValues(op.Desc(), "promote-canceled").Inc()
 				_ = op.Cancel()
@@ -425,6 +501,9 @@ func isHigherPriorityOperator(new, old *operator.Operator) bool {
 }
 
 func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
+	if oc.readOnly {
+		return false
+	}
 	regionID := op.RegionID()
 
 	log.Info("add operator",
@@ -453,6 +532,7 @@ func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
 	}
 	oc.operators[regionID] = op
 	operatorCounter.WithLabelValues(op.Desc(), "start").Inc()
+	oc.recordOperator(op, OperatorEventCreate)
 	operatorWaitDuration.WithLabelValues(op.Desc()).Observe(op.ElapsedTime().Seconds())
 	opInfluence := NewTotalOpInfluence([]*operator.Operator{op}, oc.cluster)
 	for storeID := range opInfluence.StoresInfluence {
@@ -546,6 +626,7 @@ func (oc *OperatorController) buryOperator(op *operator.Operator, extraFields ..
 			zap.Reflect("operator", op),
 			zap.String("additional-info", op.GetAdditionalInfo()))
 		operatorCounter.WithLabelValues(op.Desc(), "finish").Inc()
+		oc.recordOperator(op, OperatorEventFinish)
 		operatorDuration.WithLabelValues(op.Desc()).Observe(op.RunningTime().Seconds())
 		for _, counter := range op.FinishedCounters {
 			counter.Inc()
@@ -556,18 +637,21 @@ func (oc *OperatorController) buryOperator(op *operator.Operator, extraFields ..
 			zap.Duration("takes", op.RunningTime()),
 			zap.Reflect("operator", op))
 		operatorCounter.WithLabelValues(op.Desc(), "replace").Inc()
+		oc.recordOperator(op, OperatorEventReplace)
 	case operator.EXPIRED:
 		log.Info("operator expired",
 			zap.Uint64("region-id", op.RegionID()),
 			zap.Duration("lives", op.ElapsedTime()),
 			zap.Reflect("operator", op))
 		operatorCounter.WithLabelValues(op.Desc(), "expire").Inc()
+		oc.recordOperator(op, OperatorEventExpire)
 	case operator.TIMEOUT:
 		log.Info("operator timeout",
 			zap.Uint64("region-id", op.RegionID()),
 			zap.Duration("takes", op.RunningTime()),
 			zap.Reflect("operator", op))
 		operatorCounter.WithLabelValues(op.Desc(), "timeout").Inc()
+		oc.recordOperator(op, OperatorEventTimeout)
 	case operator.CANCELED:
 		fields := []zap.Field{
 			zap.Uint64("region-id", op.RegionID()),
@@ -579,6 +663,7 @@ func (oc *OperatorController) buryOperator(op *operator.Operator, extraFields ..
 			fields...,
 		)
 		operatorCounter.WithLabelValues(op.Desc(), "cancel").Inc()
+		oc.recordOperator(op, OperatorEventCancel)
 	}
 
 	oc.opRecords.Put(op)
@@ -901,6 +986,79 @@ func (oc *OperatorController) exceedStoreLimitLocked(ops ...*operator.Operator)
 	return false
 }
 
+// ExceedSnapshotLimit returns true if the snapshots of a store exceed its max
+// snapshot count after adding the operators.
+func (oc *OperatorController) ExceedSnapshotLimit(ops ...*operator.Operator) bool {
+	oc.Lock()
+	defer oc.Unlock()
+	return oc.exceedSnapshotLimitLocked(ops...)
+}
+
+// exceedSnapshotLimitLocked returns true if the snapshots sent or received by
+// a store exceed its max snapshot count after adding the operators. The
+// snapshots of a store are the larger one of those reported by the store and
+// those the running operators will make. It is always false unless the
+// snapshot limit is enabled.
+func (oc *OperatorController) exceedSnapshotLimitLocked(ops ...*operator.Operator) bool {
+	if !oc.cluster.GetOpts().IsSnapshotLimitEnabled() {
+		return false
+	}
+	opInfluence := NewTotalOpInfluence(ops, oc.cluster)
+	var running operator.OpInfluence
+	for storeID, influence := range opInfluence.StoresInfluence {
+		if influence.SendingSnapCount == 0 && influence.ReceivingSnapCount == 0 {
+			continue
+		}
+		store := oc.cluster.GetStore(storeID)
+		if store == nil {
+			continue
+		}
+		if running.StoresInfluence == nil {
+			running = oc.getRunningOpInfluenceLocked()
+		}
+		sending, receiving, maxSnapshotCount := oc.getSnapshotCount(store, influence, running)
+		if sending > maxSnapshotCount || receiving > maxSnapshotCount {
+			log.Debug("store exceeds the snapshot limit", zap.Uint64("store-id", storeID),
+				zap.Int64("sending", sending), zap.Int64("receiving", receiving), zap.Int64("limit", maxSnapshotCount))
+			snapshotLimitExceededCounter.WithLabelValues(strconv.FormatUint(storeID, 10)).Inc()
+			return true
+		}
+	}
+	return false
+}
+
+// getSnapshotCount returns the snapshots sent and received by the store after
+// adding the influence, and the max snapshot count of the store.
+func (oc *OperatorController) getSnapshotCount(store *core.StoreInfo, influence *operator.StoreInfluence, running operator.OpInfluence) (sending, receiving, limit int64) {
+	runningInfluence := running.GetStoreInfluence(store.GetID())
+	sending = maxInt64(int64(store.GetSendingSnapCount()), runningInfluence.SendingSnapCount) + influence.SendingSnapCount
+	receiving = maxInt64(int64(store.GetReceivingSnapCount()), runningInfluence.ReceivingSnapCount) + influence.ReceivingSnapCount
+	return sending, receiving, int64(oc.cluster.GetOpts().GetStoreMaxSnapshotCount(store))
+}
+
+// getRunningOpInfluenceLocked returns the influence of the unfinished steps
+// of the running operators.
+func (oc *OperatorController) getRunningOpInfluenceLocked() operator.OpInfluence {
+	influence := operator.OpInfluence{
+		StoresInfluence: make(map[uint64]*operator.StoreInfluence),
+	}
+	for _, op := range oc.operators {
+		if !op.CheckTimeout() && !op.CheckSuccess() {
+			if region := oc.cluster.GetRegion(op.RegionID()); region != nil {
+				op.UnfinishedInfluence(influence, region)
+			}
+		}
+	}
+	return influence
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // newStoreLimit is used to create the limit of a store.
 func (oc *OperatorController) newStoreLimit(storeID uint64, ratePerSec float64, limitType storelimit.Type) {
 	log.Info("create or update a store limit", zap.Uint64("store-id", storeID), zap.String("type", limitType.String()), zap.Float64("rate", ratePerSec))
@@ -96,6 +96,62 @@ func (t *testOperatorControllerSuite) TestGetOpInfluence(c *C) {
 	c.Assert(oc.GetOperator(2), NotNil)
 }
 
+func (t *testOperatorControllerSuite) TestReadOnlyCopy(c *C) {
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(opt)
+	oc := NewOperatorController(t.ctx, tc, nil)
+	tc.AddLeaderStore(1, 2)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderRegion(1, 1, 2)
+	tc.AddLeaderRegion(2, 1, 2)
+	op1 := operator.NewOperator("test", "test", 1, &metapb.RegionEpoch{}, operator.OpLeader, operator.TransferLeader{ToStore: 2})
+	c.Assert(op1.Start(), IsTrue)
+	oc.SetOperator(op1)
+
+	copied := oc.ReadOnlyCopy()
+	c.Assert(copied.GetOperator(1), Equals, op1)
+	c.Assert(copied.OperatorCount(operator.OpLeader), Equals, uint64(1))
+	op2 := operator.NewOperator("test", "test", 2, &metapb.RegionEpoch{}, operator.OpLeader, operator.TransferLeader{ToStore: 2})
+	c.Assert(copied.AddOperator(op2), IsFalse)
+	c.Assert(copied.AddWaitingOperator(op2), Equals, 0)
+	c.Assert(copied.GetOperator(2), IsNil)
+	c.Assert(oc.GetOperator(2), IsNil)
+}
+
+type mockOperatorRecorder struct {
+	events []string
+}
+
+func (r *mockOperatorRecorder) RecordOperator(op *operator.Operator, event string) {
+	r.events = append(r.events, fmt.Sprintf("%d-%s", op.RegionID(), event))
+}
+
+func (t *testOperatorControllerSuite) TestOperatorRecorder(c *C) {
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(opt)
+	stream := hbstream.NewTestHeartbeatStreams(t.ctx, tc.ID, tc, false /* no need to run */)
+	oc := NewOperatorController(t.ctx, tc, stream)
+	recorder := &mockOperatorRecorder{}
+	oc.AddOperatorRecorder(recorder)
+	tc.AddLeaderStore(1, 2)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderRegion(1, 1, 2)
+	tc.AddLeaderRegion(2, 1, 2)
+	op1 := operator.NewOperator("test", "test", 1, &metapb.RegionEpoch{}, operator.OpLeader, operator.TransferLeader{ToStore: 2})
+	op2 := operator.NewOperator("test", "test", 2, &metapb.RegionEpoch{}, operator.OpLeader, operator.TransferLeader{ToStore: 2})
+	c.Assert(oc.AddOperator(op1, op2), IsTrue)
+	c.Assert(oc.RemoveOperator(op1), IsTrue)
+	// The operator finishes when the leader is transferred.
+	tc.AddLeaderRegion(2, 2, 1)
+	oc.Dispatch(tc.GetRegion(2), DispatchFromHeartBeat)
+	c.Assert(recorder.events, DeepEquals, []string{
+		"1-" + OperatorEventCreate,
+		"2-" + OperatorEventCreate,
+		"1-" + OperatorEventCancel,
+		"2-" + OperatorEventFinish,
+	})
+}
+
 func (t *testOperatorControllerSuite) TestOperatorStatus(c *C) {
 	opt := config.NewTestOptions()
 	tc := mockcluster.NewCluster(opt)
@@ -409,6 +465,134 @@ func (t *testOperatorControllerSuite) TestStoreLimit(c *C) {
 	c.Assert(oc.RemoveOperator(op), IsFalse)
 }
 
+func (t *testOperatorControllerSuite) TestConflictGraph(c *C) {
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(opt)
+	stream := hbstream.NewTestHeartbeatStreams(t.ctx, tc.ID, tc, false /* no need to run */)
+	oc := NewOperatorController(t.ctx, tc, stream)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderStore(3, 0)
+	for i := uint64(1); i <= 7; i++ {
+		tc.AddLeaderRegion(i, 1)
+		tc.PutRegion(tc.GetRegion(i).Clone(core.SetApproximateSize(10)))
+	}
+
+	// The running operators use up the add-peer limit of store 2.
+	tc.SetMaxSnapshotCount(6)
+	tc.SetStoreLimit(2, storelimit.AddPeer, 60)
+	for i := uint64(1); i <= 5; i++ {
+		op := operator.NewOperator("test", "test", i, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 2, PeerID: i * 10})
+		c.Assert(oc.AddOperator(op), IsTrue)
+	}
+	// Blocked by the running operator of region 1, by the store limit of
+	// store 2, and not blocked respectively.
+	oc.wop.PutOperator(operator.NewOperator("test", "test", 1, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 3, PeerID: 11}))
+	oc.wop.PutOperator(operator.NewOperator("test", "test", 6, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 2, PeerID: 60}))
+	oc.wop.PutOperator(operator.NewOperator("test", "test", 7, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 3, PeerID: 70}))
+
+	g := oc.GetConflictGraph()
+	nodes := make(map[string]*ConflictNode)
+	for _, node := range g.Nodes {
+		nodes[node.ID] = node
+	}
+	// 3 waiting operators, 5 running operators and store 2.
+	c.Assert(nodes, HasLen, 9)
+	edges := make(map[uint64][]*ConflictEdge)
+	var inFlight int
+	for _, edge := range g.Edges {
+		if edge.Reason == ConflictReasonInFlight {
+			c.Assert(edge.From, Equals, "store-2")
+			c.Assert(nodes[edge.To].Type, Equals, ConflictNodeRunning)
+			inFlight++
+			continue
+		}
+		from := nodes[edge.From]
+		c.Assert(from.Type, Equals, ConflictNodeWaiting)
+		edges[from.RegionID] = append(edges[from.RegionID], edge)
+	}
+	c.Assert(inFlight, Equals, 5)
+	c.Assert(edges, HasLen, 2)
+	c.Assert(edges[1], HasLen, 1)
+	c.Assert(edges[1][0].Reason, Equals, ConflictReasonRegion)
+	c.Assert(edges[1][0].To, Equals, "running-1")
+	c.Assert(edges[6], HasLen, 1)
+	c.Assert(edges[6][0].Reason, Equals, ConflictReasonStoreLimit)
+	c.Assert(edges[6][0].To, Equals, "store-2")
+	c.Assert(edges[6][0].Detail, Equals, "add-peer")
+	c.Assert(nodes["store-2"].StoreID, Equals, uint64(2))
+
+	// Store 1 sends too many snapshots for all the waiting operators.
+	tc.SetMaxSnapshotCount(5)
+	g = oc.GetConflictGraph()
+	snapshotLimited := make(map[string]struct{})
+	for _, edge := range g.Edges {
+		if edge.Reason == ConflictReasonSnapshotLimit && edge.To == "store-1" {
+			snapshotLimited[edge.From] = struct{}{}
+		}
+	}
+	c.Assert(snapshotLimited, HasLen, 3)
+}
+
+func (t *testOperatorControllerSuite) TestSnapshotLimit(c *C) {
+	opt := config.NewTestOptions()
+	cfg := opt.GetScheduleConfig().Clone()
+	cfg.MaxSnapshotCount = 10
+	cfg.EnableSnapshotLimit = true
+	cfg.SnapshotLimits = []config.SnapshotLimitConfig{
+		{Labels: map[string]string{"disk": "hdd"}, MaxSnapshotCount: 1},
+		{Labels: map[string]string{"disk": "nvme"}, MaxSnapshotCount: 3},
+	}
+	opt.SetScheduleConfig(cfg)
+	tc := mockcluster.NewCluster(opt)
+	stream := hbstream.NewTestHeartbeatStreams(t.ctx, tc.ID, tc, false /* no need to run */)
+	oc := NewOperatorController(t.ctx, tc, stream)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLabelsStore(2, 0, map[string]string{"disk": "hdd"})
+	tc.AddLabelsStore(3, 0, map[string]string{"disk": "nvme"})
+	tc.SetAllStoresLimit(storelimit.AddPeer, 6000)
+	for i := uint64(1); i <= 5; i++ {
+		tc.AddLeaderRegion(i, 1)
+	}
+	newAddPeer := func(regionID, storeID uint64) *operator.Operator {
+		return operator.NewOperator("test", "test", regionID, &metapb.RegionEpoch{}, operator.OpRegion,
+			operator.AddPeer{ToStore: storeID, PeerID: regionID * 10})
+	}
+
+	c.Assert(oc.AddOperator(newAddPeer(1, 2)), IsTrue)
+	c.Assert(oc.AddOperator(newAddPeer(2, 2)), IsFalse)
+	for i := uint64(2); i <= 4; i++ {
+		c.Assert(oc.AddOperator(newAddPeer(i, 3)), IsTrue)
+	}
+	c.Assert(oc.AddOperator(newAddPeer(5, 3)), IsFalse)
+
+	// The snapshots reported by the store are counted too.
+	c.Assert(oc.RemoveOperator(oc.GetOperator(1)), IsTrue)
+	store := tc.GetStore(2)
+	tc.PutStore(store.Clone(core.SetStoreStats(&pdpb.StoreStats{
+		Capacity:           store.GetCapacity(),
+		Available:          store.GetAvailable(),
+		ReceivingSnapCount: 1,
+	})))
+	c.Assert(oc.AddOperator(newAddPeer(1, 2)), IsFalse)
+
+	// The sending snapshots of the leader store are limited as well.
+	cfg = opt.GetScheduleConfig().Clone()
+	cfg.SnapshotLimits = append(cfg.SnapshotLimits, config.SnapshotLimitConfig{StoreID: 1, MaxSnapshotCount: 3})
+	cfg.SnapshotLimits[1].MaxSnapshotCount = 10
+	opt.SetScheduleConfig(cfg)
+	c.Assert(oc.AddOperator(newAddPeer(5, 3)), IsFalse)
+	c.Assert(oc.RemoveOperator(oc.GetOperator(2)), IsTrue)
+	c.Assert(oc.AddOperator(newAddPeer(5, 3)), IsTrue)
+
+	// The snapshots are not limited unless it is enabled.
+	c.Assert(oc.AddOperator(newAddPeer(1, 2)), IsFalse)
+	cfg = opt.GetScheduleConfig().Clone()
+	cfg.EnableSnapshotLimit = false
+	opt.SetScheduleConfig(cfg)
+	c.Assert(oc.AddOperator(newAddPeer(1, 2)), IsTrue)
+}
+
 // #1652
 func (t *testOperatorControllerSuite) TestDispatchOutdatedRegion(c *C) {
 	cluster := mockcluster.NewCluster(config.NewTestOptions())
@@ -700,3 +884,32 @@ func (t *testOperatorControllerSuite) TestAddWaitingOperator(c *C) {
 	// no space left, new operator can not be added.
 	c.Assert(controller.AddWaitingOperator(addPeerOp(0)), Equals, 0)
 }
+
+func (t *testOperatorControllerSuite) TestRemoveWaitingOperator(c *C) {
+	cluster := mockcluster.NewCluster(config.NewTestOptions())
+	stream := hbstream.NewTestHeartbeatStreams(t.ctx, cluster.ID, cluster, false /* no need to run */)
+	controller := NewOperatorController(t.ctx, cluster, stream)
+	cluster.AddLabelsStore(1, 1, map[string]string{"host": "host1"})
+	cluster.AddLabelsStore(2, 1, map[string]string{"host": "host2"})
+	var ops []*operator.Operator
+	for i := uint64(1); i <= 2; i++ {
+		region := newRegionInfo(i, fmt.Sprintf("%da", i), fmt.Sprintf("%db", i), 1, 1, []uint64{100 + i, 1}, []uint64{100 + i, 1})
+		cluster.PutRegion(region)
+		op, err := operator.CreateAddPeerOperator("add-peer", cluster, region, &metapb.Peer{StoreId: 2}, operator.OpKind(0))
+		c.Assert(err, IsNil)
+		ops = append(ops, op)
+	}
+	// Only one of the operators is promoted.
+	c.Assert(controller.AddWaitingOperator(ops...), Equals, 2)
+	waiting := controller.GetWaitingOperators()
+	c.Assert(waiting, HasLen, 1)
+	running := controller.GetOperator(3 - waiting[0].RegionID())
+	c.Assert(running, NotNil)
+
+	c.Assert(controller.RemoveWaitingOperator(running), HasLen, 0)
+	removed := controller.RemoveWaitingOperator(waiting[0])
+	c.Assert(removed, DeepEquals, waiting)
+	c.Assert(waiting[0].Status(), Equals, operator.CANCELED)
+	c.Assert(controller.GetWaitingOperators(), HasLen, 0)
+	c.Assert(controller.wopStatus.ops["add-peer"], Equals, uint64(0))
+}
@@ -65,6 +65,16 @@ func (c *LabelConstraint) MatchStore(store *core.StoreInfo) bool {
 	return false
 }
 
+// TierConstraint returns the constraint which selects the stores in the tier,
+// which is the value of the tier label. The empty tier means the stores
+// without the label.
+func TierConstraint(label, tier string) LabelConstraint {
+	if tier == "" {
+		return LabelConstraint{Key: label, Op: NotExists}
+	}
+	return LabelConstraint{Key: label, Op: In, Values: []string{tier}}
+}
+
 // For backward compatibility. Need to remove later.
 var legacyExclusiveLabels = []string{"engine", "exclusive"}
 
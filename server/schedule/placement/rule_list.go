@@ -196,6 +196,27 @@ func (rl ruleList) getRulesByKey(key []byte) []*Rule {
 	return rl.ranges[i-1].rules
 }
 
+// getRulesInRange returns the rules to apply to any part of [start, end).
+func (rl ruleList) getRulesInRange(start, end []byte) []*Rule {
+	i := sort.Search(len(rl.ranges), func(i int) bool {
+		return bytes.Compare(rl.ranges[i].startKey, start) > 0
+	})
+	if i > 0 {
+		i--
+	}
+	var rules []*Rule
+	seen := make(map[[2]string]struct{})
+	for ; i < len(rl.ranges) && (len(end) == 0 || bytes.Compare(rl.ranges[i].startKey, end) < 0); i++ {
+		for _, r := range rl.ranges[i].applyRules {
+			if _, ok := seen[r.Key()]; !ok {
+				seen[r.Key()] = struct{}{}
+				rules = append(rules, r)
+			}
+		}
+	}
+	return rules
+}
+
 func (rl ruleList) getRulesForApplyRegion(start, end []byte) []*Rule {
 	i := sort.Search(len(rl.ranges), func(i int) bool {
 		return bytes.Compare(rl.ranges[i].startKey, start) > 0
@@ -295,6 +295,13 @@ func (m *RuleManager) GetRulesForApplyRegion(region *core.RegionInfo) []*Rule {
 	return m.ruleList.getRulesForApplyRegion(region.GetStartKey(), region.GetEndKey())
 }
 
+// GetRulesInRange returns the rules to apply to any region in [start, end).
+func (m *RuleManager) GetRulesInRange(start, end []byte) []*Rule {
+	m.RLock()
+	defer m.RUnlock()
+	return m.ruleList.getRulesInRange(start, end)
+}
+
 // FitRegion fits a region to the rules it matches.
 func (m *RuleManager) FitRegion(stores StoreSet, region *core.RegionInfo) *RegionFit {
 	rules := m.GetRulesForApplyRegion(region)
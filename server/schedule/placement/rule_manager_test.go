@@ -198,6 +198,20 @@ func (s *testManagerSuite) TestKeys(c *C) {
 		}
 	}
 
+	rulesInRange := [][]string{ // first two are the query range, rests are rule keys.
+		{"00", "11", "", ""},
+		{"11", "33", "", "", "11", "ff", "22", "dd"},
+		{"ee", "", "", "", "11", "ff"},
+	}
+	for _, keys := range rulesInRange {
+		rules := s.manager.GetRulesInRange(s.dhex(keys[0]), s.dhex(keys[1]))
+		c.Assert(rules, HasLen, (len(keys)-2)/2)
+		for i := range rules {
+			c.Assert(rules[i].StartKeyHex, Equals, keys[i*2+2])
+			c.Assert(rules[i].EndKeyHex, Equals, keys[i*2+3])
+		}
+	}
+
 	rulesByGroup := [][]string{ // first is group, rests are rule keys.
 		{"1", "", ""},
 		{"2", "11", "ff", "22", "dd"},
@@ -183,7 +183,7 @@ func (r *RegionScatterer) ScatterRegionsByID(regionsID []uint64, group string, r
 		if region == nil {
 			scatterCounter.WithLabelValues("skip", "no-region").Inc()
 			log.Warn("failed to find region during scatter", zap.Uint64("region-id", id))
-			failures[id] = errors.New(fmt.Sprintf("failed to find region %v", id))
+			failures[id] = errs.ErrScatterRegionNotFound.FastGenByArgs(id)
 			continue
 		}
 		regions = append(regions, region)
@@ -217,7 +217,7 @@ func (r *RegionScatterer) ScatterRegions(regions map[uint64]*core.RegionInfo, fa
 	ops := make([]*operator.Operator, 0, len(regions))
 	for currentRetry := 0; currentRetry <= retryLimit; currentRetry++ {
 		for _, region := range regions {
-			op, err := r.Scatter(region, group)
+			op, err := r.scatter(region, group)
 			failpoint.Inject("scatterFail", func() {
 				if region.GetID() == 1 {
 					err = errors.New("mock error")
@@ -244,31 +244,81 @@ func (r *RegionScatterer) ScatterRegions(regions map[uint64]*core.RegionInfo, fa
 }
 
 // Scatter relocates the region. If the group is defined, the regions' leader with the same group would be scattered
-// in a group level instead of cluster level.
+// in a group level instead of cluster level. It returns no operator and no error if the region stays where it is.
 func (r *RegionScatterer) Scatter(region *core.RegionInfo, group string) (*operator.Operator, error) {
+	op, err := r.scatter(region, group)
+	if _, ok := err.(*NoCandidateStoreError); ok {
+		return nil, nil
+	}
+	return op, err
+}
+
+// scatter is Scatter, but returns NoCandidateStoreError if the region stays
+// where it is because all the stores of some peer are filtered out, which is
+// reported by the batch scatters as the reason of the failure.
+func (r *RegionScatterer) scatter(region *core.RegionInfo, group string) (*operator.Operator, error) {
 	if !opt.IsRegionReplicated(r.cluster, region) {
 		r.cluster.AddSuspectRegions(region.GetID())
 		scatterCounter.WithLabelValues("skip", "not-replicated").Inc()
 		log.Warn("region not replicated during scatter", zap.Uint64("region-id", region.GetID()))
-		return nil, errors.Errorf("region %d is not fully replicated", region.GetID())
+		return nil, errs.ErrScatterRegionNotReplicated.FastGenByArgs(region.GetID())
 	}
 
 	if region.GetLeader() == nil {
 		scatterCounter.WithLabelValues("skip", "no-leader").Inc()
 		log.Warn("region no leader during scatter", zap.Uint64("region-id", region.GetID()))
-		return nil, errors.Errorf("region %d has no leader", region.GetID())
+		return nil, errs.ErrScatterRegionNoLeader.FastGenByArgs(region.GetID())
 	}
 
 	if r.cluster.IsRegionHot(region) {
 		scatterCounter.WithLabelValues("skip", "hot").Inc()
 		log.Warn("region too hot during scatter", zap.Uint64("region-id", region.GetID()))
-		return nil, errors.Errorf("region %d is hot", region.GetID())
+		return nil, errs.ErrScatterRegionHot.FastGenByArgs(region.GetID())
+	}
+
+	return r.scatterRegion(region, group)
+}
+
+// selectedCountRejection is the reason of rejecting a store which has been
+// selected for more peers than the other stores.
+const selectedCountRejection = "selected-count"
+
+// NoCandidateStoreError is ErrScatterNoCandidateStore with the stores
+// rejected for the peer which has no candidate store.
+type NoCandidateStoreError struct {
+	err error
+	// RejectedStores maps the stores to the names of the filters rejecting
+	// them.
+	RejectedStores map[uint64]string
+}
+
+func newNoCandidateStoreError(regionID uint64, rejected map[uint64]string) *NoCandidateStoreError {
+	return &NoCandidateStoreError{
+		err:            errs.ErrScatterNoCandidateStore.FastGenByArgs(regionID),
+		RejectedStores: rejected,
 	}
+}
+
+// Error implements error.
+func (e *NoCandidateStoreError) Error() string {
+	return e.err.Error()
+}
+
+// Cause returns ErrScatterNoCandidateStore, so that the error can be checked
+// by ErrScatterNoCandidateStore.Equal.
+func (e *NoCandidateStoreError) Cause() error {
+	return e.err
+}
 
-	return r.scatterRegion(region, group), nil
+// GetRejectedStores returns the stores rejected and the names of the filters
+// rejecting them.
+func (e *NoCandidateStoreError) GetRejectedStores() map[uint64]string {
+	return e.RejectedStores
 }
 
-func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, group string) *operator.Operator {
+// scatterRegion returns ErrScatterNoCandidateStore if the region stays where
+// it is because all the stores of some peer are filtered out.
+func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, group string) (*operator.Operator, error) {
 	ordinaryFilter := filter.NewOrdinaryEngineFilter(r.name)
 	ordinaryPeers := make(map[uint64]*metapb.Peer)
 	specialPeers := make(map[string]map[uint64]*metapb.Peer)
@@ -278,7 +328,7 @@ func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, group string) *
 		if ordinaryFilter.Target(r.cluster.GetOpts(), store) {
 			ordinaryPeers[peer.GetId()] = peer
 		} else {
-			engine := store.GetLabelValue(filter.EngineKey)
+			engine := store.GetCapabilities().Engine
 			if _, ok := specialPeers[engine]; !ok {
 				specialPeers[engine] = make(map[uint64]*metapb.Peer)
 			}
@@ -288,9 +338,16 @@ func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, group string) *
 
 	targetPeers := make(map[uint64]*metapb.Peer)
 	selectedStores := make(map[uint64]struct{})
+	var noCandidate map[uint64]string
 	scatterWithSameEngine := func(peers map[uint64]*metapb.Peer, context engineContext) {
 		for _, peer := range peers {
-			candidates := r.selectCandidates(region, peer.GetStoreId(), selectedStores, context)
+			candidates, rejected := r.selectCandidates(region, peer.GetStoreId(), selectedStores, context)
+			if len(candidates) == 0 && noCandidate == nil {
+				noCandidate = rejected
+				if noCandidate == nil {
+					noCandidate = make(map[uint64]string)
+				}
+			}
 			newPeer := r.selectStore(group, peer, peer.GetStoreId(), candidates, context)
 			targetPeers[newPeer.GetStoreId()] = newPeer
 			selectedStores[newPeer.GetStoreId()] = struct{}{}
@@ -320,21 +377,29 @@ func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, group string) *
 		}
 		r.Put(targetPeers, region.GetLeader().GetStoreId(), group)
 		log.Debug("fail to create scatter region operator", errs.ZapError(err))
-		return nil
+		if noCandidate != nil {
+			return nil, newNoCandidateStoreError(region.GetID(), noCandidate)
+		}
+		return nil, nil
 	}
 	if op != nil {
 		scatterCounter.WithLabelValues("success", "").Inc()
 		r.Put(targetPeers, targetLeader, group)
 		op.SetPriorityLevel(core.HighPriority)
 	}
-	return op
+	if noCandidate != nil && (op == nil || op.Len() == 0) {
+		return nil, newNoCandidateStoreError(region.GetID(), noCandidate)
+	}
+	return op, nil
 }
 
-func (r *RegionScatterer) selectCandidates(region *core.RegionInfo, sourceStoreID uint64, selectedStores map[uint64]struct{}, context engineContext) []uint64 {
+// selectCandidates returns the candidate stores of the peer, and the other
+// stores with the names of the filters rejecting them.
+func (r *RegionScatterer) selectCandidates(region *core.RegionInfo, sourceStoreID uint64, selectedStores map[uint64]struct{}, context engineContext) ([]uint64, map[uint64]string) {
 	sourceStore := r.cluster.GetStore(sourceStoreID)
 	if sourceStore == nil {
 		log.Error("failed to get the store", zap.Uint64("store-id", sourceStoreID), errs.ZapError(errs.ErrGetSourceStore))
-		return nil
+		return nil, nil
 	}
 	filters := []filter.Filter{
 		filter.NewExcludedFilter(r.name, nil, selectedStores),
@@ -344,6 +409,7 @@ func (r *RegionScatterer) selectCandidates(region *core.RegionInfo, sourceStoreI
 	filters = append(filters, scoreGuard)
 	stores := r.cluster.GetStores()
 	candidates := make([]uint64, 0)
+	rejected := make(map[uint64]string)
 	maxStoreTotalCount := uint64(0)
 	minStoreTotalCount := uint64(math.MaxUint64)
 	for _, store := range r.cluster.GetStores() {
@@ -361,12 +427,16 @@ func (r *RegionScatterer) selectCandidates(region *core.RegionInfo, sourceStoreI
 		// If the storeCount are all the same for the whole cluster(maxStoreTotalCount == minStoreTotalCount), any store
 		// could be selected as candidate.
 		if storeCount < maxStoreTotalCount || maxStoreTotalCount == minStoreTotalCount {
-			if filter.Target(r.cluster.GetOpts(), store, filters) {
+			if f := filter.TargetRejectedBy(r.cluster.GetOpts(), store, filters); f != nil {
+				rejected[store.GetID()] = f.Type()
+			} else {
 				candidates = append(candidates, store.GetID())
 			}
+		} else {
+			rejected[store.GetID()] = selectedCountRejection
 		}
 	}
-	return candidates
+	return candidates, rejected
 }
 
 func (r *RegionScatterer) selectStore(group string, peer *metapb.Peer, sourceStoreID uint64, candidates []uint64, context engineContext) *metapb.Peer {
@@ -403,8 +473,7 @@ func (r *RegionScatterer) selectAvailableLeaderStores(group string, peers map[ui
 	leaderCandidateStores := make([]uint64, 0)
 	for storeID := range peers {
 		store := r.cluster.GetStore(storeID)
-		engine := store.GetLabelValue(filter.EngineKey)
-		if len(engine) < 1 {
+		if store.GetCapabilities().Leader {
 			leaderCandidateStores = append(leaderCandidateStores, storeID)
 		}
 	}
@@ -434,7 +503,7 @@ func (r *RegionScatterer) Put(peers map[uint64]*metapb.Peer, leaderStoreID uint6
 				fmt.Sprintf("%v", false),
 				filter.EngineTiKV).Inc()
 		} else {
-			engine := store.GetLabelValue(filter.EngineKey)
+			engine := store.GetCapabilities().Engine
 			r.specialEngines[engine].selectedPeer.Put(storeID, group)
 			scatterDistributionCounter.WithLabelValues(
 				fmt.Sprintf("%v", storeID),
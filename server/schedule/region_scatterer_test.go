@@ -9,6 +9,7 @@ import (
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/failpoint"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/mock/mockcluster"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
@@ -282,7 +283,7 @@ func (s *testScatterRegionSuite) TestScatterCheck(c *C) {
 		scatterer := NewRegionScatterer(ctx, tc)
 		_, err := scatterer.Scatter(testcase.checkRegion, "")
 		if testcase.needFix {
-			c.Assert(err, NotNil)
+			c.Assert(errs.ErrScatterRegionNotReplicated.Equal(err), IsTrue)
 			c.Assert(tc.CheckRegionUnderSuspect(1), Equals, true)
 		} else {
 			c.Assert(err, IsNil)
@@ -293,6 +294,44 @@ func (s *testScatterRegionSuite) TestScatterCheck(c *C) {
 	}
 }
 
+func (s *testScatterRegionSuite) TestScatterNoCandidateStore(c *C) {
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(opt)
+	tc.SetEnablePlacementRules(false)
+	tc.SetMaxReplicas(1)
+	for i := uint64(1); i <= 3; i++ {
+		tc.AddRegionStore(i, 0)
+	}
+	region := tc.AddLeaderRegion(1, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scatterer := NewRegionScatterer(ctx, tc)
+
+	// All the stores are filtered out.
+	for i := uint64(1); i <= 3; i++ {
+		tc.SetStoreBusy(i, true)
+	}
+	// The batch scatter reports the reason.
+	failures := make(map[uint64]error)
+	_, err := scatterer.ScatterRegions(map[uint64]*core.RegionInfo{1: region}, failures, "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(errs.ErrScatterNoCandidateStore.Equal(failures[1]), IsTrue)
+	c.Assert(failures[1].(*NoCandidateStoreError).RejectedStores, DeepEquals, map[uint64]string{
+		1: "store-state-busy-filter",
+		2: "store-state-busy-filter",
+		3: "store-state-busy-filter",
+	})
+	// A single scatter just leaves the region there.
+	op, err := scatterer.Scatter(region, "")
+	c.Assert(op, IsNil)
+	c.Assert(err, IsNil)
+
+	tc.SetStoreBusy(2, false)
+	op, err = scatterer.Scatter(region, "")
+	c.Assert(err, IsNil)
+	c.Assert(op, NotNil)
+}
+
 func (s *testScatterRegionSuite) TestScatterGroupInConcurrency(c *C) {
 	opt := config.NewTestOptions()
 	tc := mockcluster.NewCluster(opt)
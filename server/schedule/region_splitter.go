@@ -34,11 +34,18 @@ import (
 const (
 	watchInterval = 100 * time.Millisecond
 	timeout       = 1 * time.Minute
+	// maxSplitBySizeRounds is the max rounds of SplitRegionsBySize. Each round
+	// halves the large regions, so it is enough for 2^16 pieces of a region.
+	maxSplitBySizeRounds = 16
+	// maxSplitBySizeRegions is the max number of the regions which
+	// SplitRegionsBySize splits in all rounds.
+	maxSplitBySizeRegions = 10000
 )
 
 // SplitRegionsHandler used to handle region splitting
 type SplitRegionsHandler interface {
 	SplitRegionByKeys(region *core.RegionInfo, splitKeys [][]byte) error
+	SplitRegionInHalf(region *core.RegionInfo) error
 	ScanRegionsByKeyRange(groupKeys *regionGroupKeys, results *splitKeyResults)
 }
 
@@ -86,6 +93,152 @@ func (r *RegionSplitter) SplitRegions(ctx context.Context, splitKeys [][]byte, r
 	return 100 - len(unprocessedKeys)*100/len(splitKeys), returned
 }
 
+// SplitRegionsBySize splits the regions in [startKey, endKey) until none of them
+// is larger than targetSize in MB. If targetCount is not 0, the target size is
+// the total size of the regions divided by targetCount instead. The range is
+// split at its boundaries first, and then the large regions are split round by
+// round with the APPROXIMATE policy, which makes TiKV split a region at the
+// middle of its size by the size properties of its SSTs, not at the middle of
+// its keys. PD cannot pick the keys itself, as the kvproto used has no region
+// buckets, so it knows nothing about the sizes inside a region. The rounds are
+// bounded by maxSplitBySizeRounds and the splits by maxSplitBySizeRegions, in
+// case the reported sizes stay large. It returns the percentage of the regions
+// in the range which reach the target size, and the IDs of the new regions.
+func (r *RegionSplitter) SplitRegionsBySize(ctx context.Context, startKey, endKey []byte, targetSize, targetCount uint64, retryLimit int) (int, []uint64) {
+	newRegions := make(map[uint64]struct{})
+	var boundaries [][]byte
+	for _, key := range [][]byte{startKey, endKey} {
+		if len(key) == 0 {
+			continue
+		}
+		if region := r.cluster.GetRegionByKey(key); region != nil && !bytes.Equal(region.GetStartKey(), key) {
+			boundaries = append(boundaries, key)
+		}
+	}
+	if len(boundaries) > 0 {
+		_, ids := r.SplitRegions(ctx, boundaries, retryLimit)
+		for _, id := range ids {
+			newRegions[id] = struct{}{}
+		}
+	}
+
+	origin := make(map[uint64]struct{})
+	regions := r.scanRegionsInRange(startKey, endKey)
+	if len(regions) == 0 {
+		return 0, nil
+	}
+	if targetCount > 0 {
+		var totalSize int64
+		for _, region := range regions {
+			totalSize += region.GetApproximateSize()
+		}
+		targetSize = uint64(totalSize) / targetCount
+	}
+	if targetSize == 0 {
+		targetSize = 1
+	}
+	for _, region := range regions {
+		origin[region.GetID()] = struct{}{}
+	}
+
+	splits := 0
+	for i, round := 0, 0; i <= retryLimit && round < maxSplitBySizeRounds; round++ {
+		// The regions whose sizes are unknown are not split.
+		var large []*core.RegionInfo
+		for _, region := range regions {
+			if splits+len(large) >= maxSplitBySizeRegions {
+				break
+			}
+			if uint64(region.GetApproximateSize()) > targetSize && r.checkRegionValid(region) {
+				large = append(large, region)
+			}
+		}
+		if len(large) == 0 {
+			break
+		}
+		splits += len(large)
+		if r.splitRegionsInHalf(ctx, large) == 0 {
+			// sleep for a while between each retry
+			time.Sleep(typeutil.MinDuration(maxSleepDuration, time.Duration(math.Pow(2, float64(i)))*initialSleepDuration))
+			i++
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		regions = r.scanRegionsInRange(startKey, endKey)
+	}
+
+	regions = r.scanRegionsInRange(startKey, endKey)
+	finished := 0
+	for _, region := range regions {
+		if uint64(region.GetApproximateSize()) <= targetSize {
+			finished++
+		}
+		if _, ok := origin[region.GetID()]; !ok {
+			newRegions[region.GetID()] = struct{}{}
+		}
+	}
+	returned := make([]uint64, 0, len(newRegions))
+	for regionID := range newRegions {
+		returned = append(returned, regionID)
+	}
+	if len(regions) == 0 {
+		return 0, returned
+	}
+	return finished * 100 / len(regions), returned
+}
+
+// scanRegionsInRange returns the regions inside [startKey, endKey).
+func (r *RegionSplitter) scanRegionsInRange(startKey, endKey []byte) []*core.RegionInfo {
+	var regions []*core.RegionInfo
+	for _, region := range r.cluster.ScanRegions(startKey, endKey, -1) {
+		if bytes.Compare(region.GetStartKey(), startKey) < 0 {
+			continue
+		}
+		if len(endKey) > 0 && (len(region.GetEndKey()) == 0 || bytes.Compare(region.GetEndKey(), endKey) > 0) {
+			continue
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// splitRegionsInHalf splits the regions in half and waits until they are split
+// or timeout. It returns the number of the regions which are split.
+func (r *RegionSplitter) splitRegionsInHalf(parCtx context.Context, regions []*core.RegionInfo) int {
+	// regionID -> the region version before splitting
+	pending := make(map[uint64]uint64, len(regions))
+	for _, region := range regions {
+		if err := r.handler.SplitRegionInHalf(region); err == nil {
+			pending[region.GetID()] = region.GetRegionEpoch().GetVersion()
+		}
+	}
+	ticker := time.NewTicker(watchInterval)
+	ctx, cancel := context.WithTimeout(parCtx, timeout)
+	defer func() {
+		ticker.Stop()
+		cancel()
+	}()
+	split := 0
+	for len(pending) > 0 {
+		for id, version := range pending {
+			if region := r.cluster.GetRegion(id); region == nil || region.GetRegionEpoch().GetVersion() > version {
+				delete(pending, id)
+				split++
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return split
+		}
+	}
+	return split
+}
+
 func (r *RegionSplitter) splitRegionsByKeys(parCtx context.Context, splitKeys [][]byte, newRegions map[uint64]struct{}) [][]byte {
 	validGroups := r.groupKeysByRegion(splitKeys)
 	for key, group := range validGroups {
@@ -193,6 +346,19 @@ func (h *splitRegionsHandler) SplitRegionByKeys(region *core.RegionInfo, splitKe
 	return nil
 }
 
+func (h *splitRegionsHandler) SplitRegionInHalf(region *core.RegionInfo) error {
+	op, err := operator.CreateSplitRegionOperator("region-splitter", region, 0, pdpb.CheckPolicy_APPROXIMATE, nil)
+	if err != nil {
+		return err
+	}
+
+	if ok := h.oc.AddOperator(op); !ok {
+		log.Warn("add region split operator failed", zap.Uint64("region-id", region.GetID()))
+		return errors.New("add region split operator failed")
+	}
+	return nil
+}
+
 func (h *splitRegionsHandler) ScanRegionsByKeyRange(groupKeys *regionGroupKeys, results *splitKeyResults) {
 	splitKeys := groupKeys.keys
 	startKey, endKey := groupKeys.region.GetStartKey(), groupKeys.region.GetEndKey()
@@ -16,6 +16,7 @@ package schedule
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 
 	. "github.com/pingcap/check"
 	"github.com/tikv/pd/pkg/mock/mockcluster"
@@ -26,6 +27,12 @@ import (
 type mockSplitRegionsHandler struct {
 	// regionID -> startKey, endKey
 	regions map[uint64][2][]byte
+	// cluster is used to split the regions in half, whose keys are 8-byte
+	// big-endian numbers.
+	cluster *mockcluster.Cluster
+	nextID  uint64
+	// keepSize keeps the sizes of the regions split in half.
+	keepSize bool
 }
 
 func newMockSplitRegionsHandler() *mockSplitRegionsHandler {
@@ -43,6 +50,24 @@ func (m *mockSplitRegionsHandler) SplitRegionByKeys(region *core.RegionInfo, spl
 	return nil
 }
 
+// SplitRegionInHalf mock SplitRegionsHandler
+func (m *mockSplitRegionsHandler) SplitRegionInHalf(region *core.RegionInfo) error {
+	start := binary.BigEndian.Uint64(region.GetStartKey())
+	end := binary.BigEndian.Uint64(region.GetEndKey())
+	mid := make([]byte, 8)
+	binary.BigEndian.PutUint64(mid, (start+end)/2)
+	size := region.GetApproximateSize() / 2
+	if m.keepSize {
+		size = region.GetApproximateSize()
+	}
+	version := region.GetRegionEpoch().GetVersion() + 1
+	m.cluster.PutRegion(region.Clone(core.WithEndKey(mid), core.SetApproximateSize(size), core.SetRegionVersion(version)))
+	m.nextID++
+	m.cluster.AddLeaderRegionWithRange(m.nextID, string(mid), string(region.GetEndKey()), 2, 3, 4)
+	m.cluster.PutRegion(m.cluster.GetRegion(m.nextID).Clone(core.SetApproximateSize(size), core.SetRegionVersion(version)))
+	return nil
+}
+
 // WatchRegionsByKeyRange mock SplitRegionsHandler
 func (m *mockSplitRegionsHandler) ScanRegionsByKeyRange(groupKeys *regionGroupKeys, results *splitKeyResults) {
 	splitKeys := groupKeys.keys
@@ -91,6 +116,50 @@ func (s *testRegionSplitterSuite) TestRegionSplitter(c *C) {
 	c.Assert(len(newRegionsID), Equals, 0)
 }
 
+func (s *testRegionSplitterSuite) TestSplitRegionsBySize(c *C) {
+	ctx := context.Background()
+	opt := config.NewTestOptions()
+	opt.SetPlacementRuleEnabled(false)
+	tc := mockcluster.NewCluster(opt)
+	handler := newMockSplitRegionsHandler()
+	handler.cluster, handler.nextID = tc, 100
+	key := func(k uint64) []byte {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, k)
+		return b
+	}
+	tc.AddLeaderRegionWithRange(1, string(key(0)), string(key(1024)), 2, 3, 4)
+	tc.PutRegion(tc.GetRegion(1).Clone(core.SetApproximateSize(40)))
+	tc.AddLeaderRegionWithRange(2, string(key(1024)), string(key(2048)), 2, 3, 4)
+	tc.PutRegion(tc.GetRegion(2).Clone(core.SetApproximateSize(200)))
+	splitter := NewRegionSplitter(tc, handler)
+
+	// Only the regions inside the range are split.
+	percentage, newRegionsID := splitter.SplitRegionsBySize(ctx, key(0), key(1024), 10, 0, 1)
+	c.Assert(percentage, Equals, 100)
+	c.Assert(newRegionsID, HasLen, 3)
+	c.Assert(tc.ScanRegions(key(0), key(1024), -1), HasLen, 4)
+	c.Assert(tc.GetRegion(2).GetApproximateSize(), Equals, int64(200))
+
+	// The target size is computed from the count.
+	percentage, newRegionsID = splitter.SplitRegionsBySize(ctx, key(1024), key(2048), 0, 2, 1)
+	c.Assert(percentage, Equals, 100)
+	c.Assert(newRegionsID, HasLen, 1)
+
+	// There is no region in the range.
+	percentage, newRegionsID = splitter.SplitRegionsBySize(ctx, key(4096), key(8192), 10, 0, 1)
+	c.Assert(percentage, Equals, 0)
+	c.Assert(newRegionsID, HasLen, 0)
+
+	// The splits are bounded even if the sizes stay large.
+	handler.keepSize = true
+	tc.AddLeaderRegionWithRange(3, string(key(1<<20)), string(key(1<<21)), 2, 3, 4)
+	tc.PutRegion(tc.GetRegion(3).Clone(core.SetApproximateSize(200)))
+	percentage, newRegionsID = splitter.SplitRegionsBySize(ctx, key(1<<20), key(1<<21), 10, 0, 1)
+	c.Assert(percentage, Equals, 0)
+	c.Assert(newRegionsID, HasLen, maxSplitBySizeRegions)
+}
+
 func (s *testRegionSplitterSuite) TestGroupKeysByRegion(c *C) {
 	opt := config.NewTestOptions()
 	opt.SetPlacementRuleEnabled(false)
@@ -28,6 +28,7 @@ type WaitingOperator interface {
 	PutOperator(op *operator.Operator)
 	GetOperator() []*operator.Operator
 	ListOperator() []*operator.Operator
+	RemoveOperator(op *operator.Operator) []*operator.Operator
 }
 
 // Bucket is used to maintain the operators created by a specific scheduler.
@@ -108,6 +109,30 @@ func (b *RandBuckets) GetOperator() []*operator.Operator {
 	return nil
 }
 
+// RemoveOperator removes an operator from the random buckets. The two
+// operators of a merge operation are removed together. It returns the removed
+// operators, or nil if the operator is not found.
+func (b *RandBuckets) RemoveOperator(op *operator.Operator) []*operator.Operator {
+	bucket := b.buckets[op.GetPriorityLevel()]
+	n := 1
+	for i := 0; i < len(bucket.ops); i += n {
+		n = 1
+		if bucket.ops[i].Kind()&operator.OpMerge != 0 && i+1 < len(bucket.ops) {
+			n = 2
+		}
+		if bucket.ops[i] != op && bucket.ops[i+n-1] != op {
+			continue
+		}
+		res := append([]*operator.Operator(nil), bucket.ops[i:i+n]...)
+		bucket.ops = append(bucket.ops[:i:i], bucket.ops[i+n:]...)
+		if len(bucket.ops) == 0 {
+			b.totalWeight -= bucket.weight
+		}
+		return res
+	}
+	return nil
+}
+
 // WaitingOperatorStatus is used to limit the count of each kind of operators.
 type WaitingOperatorStatus struct {
 	ops map[string]uint64
@@ -57,6 +57,34 @@ func (s *testWaitingOperatorSuite) TestListOperator(c *C) {
 	c.Assert(len(rb.ListOperator()), Equals, 3)
 }
 
+func (s *testWaitingOperatorSuite) TestRemoveOperator(c *C) {
+	rb := NewRandBuckets()
+	addOperators(rb)
+	ops := rb.ListOperator()
+	c.Assert(rb.RemoveOperator(ops[1]), DeepEquals, ops[1:2])
+	c.Assert(rb.RemoveOperator(ops[1]), IsNil)
+	c.Assert(rb.ListOperator(), DeepEquals, []*operator.Operator{ops[0], ops[2]})
+
+	// the two operators of a merge operation are removed together
+	merge := func(regionID uint64, passive bool) *operator.Operator {
+		return operator.NewOperator("merge-region", "test", regionID, &metapb.RegionEpoch{}, operator.OpRegion|operator.OpMerge, []operator.OpStep{
+			operator.MergeRegion{
+				FromRegion: &metapb.Region{Id: 4, RegionEpoch: &metapb.RegionEpoch{}},
+				ToRegion:   &metapb.Region{Id: 5, RegionEpoch: &metapb.RegionEpoch{}},
+				IsPassive:  passive,
+			},
+		}...)
+	}
+	source, target := merge(4, false), merge(5, true)
+	rb.PutOperator(source)
+	rb.PutOperator(target)
+	c.Assert(rb.RemoveOperator(target), DeepEquals, []*operator.Operator{source, target})
+	c.Assert(rb.ListOperator(), DeepEquals, []*operator.Operator{ops[0], ops[2]})
+	c.Assert(rb.RemoveOperator(ops[0]), HasLen, 1)
+	c.Assert(rb.RemoveOperator(ops[2]), HasLen, 1)
+	c.Assert(rb.GetOperator(), IsNil)
+}
+
 func (s *testWaitingOperatorSuite) TestRandomBucketsWithMergeRegion(c *C) {
 	rb := NewRandBuckets()
 	descs := []string{"merge-region", "admin-merge-region", "random-merge"}
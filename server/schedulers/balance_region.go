@@ -210,10 +210,25 @@ func (s *balanceRegionScheduler) transferPeer(cluster opt.Cluster, region *core.
 		filter.NewSpecialUseFilter(s.GetName()),
 		&filter.StoreStateFilter{ActionScope: s.GetName(), MoveRegion: true},
 	}
+	// The peers are only balanced within the tier of the source store.
+	if label := cluster.GetOpts().GetStoreTierLabel(); label != "" {
+		filters = append(filters, filter.NewTierFilter(s.GetName(), label, source.GetLabelValue(label)))
+	}
 
+	// soft anti-affinity only affects the order of candidates, so that a
+	// store in a different group is preferred but never required.
+	var coLocationStores []*core.StoreInfo
+	for _, store := range cluster.GetRegionStores(region) {
+		if store.GetID() != sourceStoreID {
+			coLocationStores = append(coLocationStores, store)
+		}
+	}
 	candidates := filter.NewCandidates(cluster.GetStores()).
 		FilterTarget(cluster.GetOpts(), filters...).
-		Sort(filter.RegionScoreComparer(cluster.GetOpts()))
+		Sort(filter.CombineComparers(
+			filter.AntiAffinityComparer(cluster.GetOpts().GetSoftAntiAffinityLabels(), coLocationStores),
+			filter.RegionScoreComparer(cluster.GetOpts()),
+		))
 
 	for _, target := range candidates.Stores {
 		regionID := region.GetID()
@@ -614,6 +614,28 @@ func (s *testBalanceRegionSchedulerSuite) TestBalance(c *C) {
 	c.Assert(sb.Schedule(tc), NotNil)
 }
 
+func (s *testBalanceRegionSchedulerSuite) TestStoreTier(c *C) {
+	opt := config.NewTestOptions()
+	opt.SetPlacementRuleEnabled(false)
+	tc := mockcluster.NewCluster(opt)
+	tc.DisableFeature(versioninfo.JointConsensus)
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+
+	sb, err := schedule.CreateScheduler(BalanceRegionType, oc, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(BalanceRegionType, []string{"", ""}))
+	c.Assert(err, IsNil)
+	opt.SetMaxReplicas(1)
+	tc.SetStoreTierLabel("tier")
+	tc.AddLabelsStore(1, 16, map[string]string{"tier": "hot"})
+	tc.AddLabelsStore(2, 8, map[string]string{"tier": "hot"})
+	tc.AddLabelsStore(3, 0, map[string]string{"tier": "cold"})
+	tc.AddLeaderRegion(1, 1)
+	// Store 3 has the least regions but is in another tier.
+	testutil.CheckTransferPeerWithLeaderTransfer(c, sb.Schedule(tc)[0], operator.OpKind(0), 1, 2)
+
+	tc.SetStoreTierLabel("")
+	testutil.CheckTransferPeerWithLeaderTransfer(c, sb.Schedule(tc)[0], operator.OpKind(0), 1, 3)
+}
+
 func (s *testBalanceRegionSchedulerSuite) TestReplicas3(c *C) {
 	opt := config.NewTestOptions()
 	//TODO: enable placementrules
@@ -0,0 +1,192 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/unrolled/render"
+)
+
+// configFieldType is the type of a field in the scheduler config API.
+type configFieldType string
+
+const (
+	// configFieldString is a JSON string.
+	configFieldString configFieldType = "string"
+	// configFieldUint is a non-negative JSON integer.
+	configFieldUint configFieldType = "uint"
+	// configFieldKeyRanges is a flat JSON list of the start and end keys.
+	configFieldKeyRanges configFieldType = "key-ranges"
+)
+
+// configField declares a field which can be updated by the scheduler config API.
+type configField struct {
+	Name        string          `json:"name"`
+	Type        configFieldType `json:"type"`
+	Required    bool            `json:"required"`
+	Description string          `json:"description"`
+	// validate checks the typed value of the field, if it is set.
+	validate func(v interface{}) error
+}
+
+// configSchema declares the fields of a scheduler config. The input of an
+// update is checked against it, so the handlers only see typed values.
+type configSchema []*configField
+
+// configValues holds the typed values of the fields given in an update.
+type configValues map[string]interface{}
+
+func (v configValues) getString(name string) (string, bool) {
+	s, ok := v[name].(string)
+	return s, ok
+}
+
+func (v configValues) getUint(name string) (uint64, bool) {
+	n, ok := v[name].(uint64)
+	return n, ok
+}
+
+func (v configValues) getKeyRanges(name string) ([]string, bool) {
+	keys, ok := v[name].([]string)
+	return keys, ok
+}
+
+// parse converts the decoded JSON input to the typed values. The fields which
+// are not in the schema are ignored, and a null value is the same as absent.
+func (s configSchema) parse(input map[string]interface{}) (configValues, error) {
+	values := make(configValues)
+	for _, field := range s {
+		raw, ok := input[field.Name]
+		if !ok || raw == nil {
+			if field.Required {
+				return nil, errs.ErrSchedulerConfig.FastGenByArgs(field.Name)
+			}
+			continue
+		}
+		v, ok := field.convert(raw)
+		if !ok {
+			return nil, errs.ErrSchedulerConfig.FastGenByArgs(field.Name)
+		}
+		if field.validate != nil {
+			if err := field.validate(v); err != nil {
+				return nil, err
+			}
+		}
+		values[field.Name] = v
+	}
+	return values, nil
+}
+
+func (f *configField) convert(raw interface{}) (interface{}, bool) {
+	switch f.Type {
+	case configFieldString:
+		s, ok := raw.(string)
+		return s, ok
+	case configFieldUint:
+		n, ok := raw.(float64)
+		if !ok || n < 0 || n != math.Trunc(n) || n >= math.MaxUint64 {
+			return nil, false
+		}
+		return uint64(n), true
+	case configFieldKeyRanges:
+		items, ok := raw.([]interface{})
+		if !ok || len(items)%2 != 0 {
+			return nil, false
+		}
+		keys := make([]string, 0, len(items))
+		for _, item := range items {
+			key, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			keys = append(keys, key)
+		}
+		return keys, true
+	}
+	return nil, false
+}
+
+// applyConfigError wraps the errors of applyConfig which are not caused by
+// the values, such as failing to pause the leader transfer of a store, so
+// that they are responded with 500 rather than 400.
+type applyConfigError struct {
+	error
+}
+
+// schemaConfig is a scheduler config whose HTTP handlers are generated from
+// its schema.
+type schemaConfig interface {
+	schema() configSchema
+	// applyConfig applies the values which have passed the schema. The errors
+	// not caused by the values are wrapped in applyConfigError.
+	applyConfig(values configValues) error
+	// listConfig returns a snapshot of the config to show.
+	listConfig() interface{}
+	Persist() error
+}
+
+type schemaConfigHandler struct {
+	rd     *render.Render
+	config schemaConfig
+}
+
+func (handler *schemaConfigHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	var input map[string]interface{}
+	if err := apiutil.ReadJSONRespondError(handler.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	values, err := handler.config.schema().parse(input)
+	if err != nil {
+		handler.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := handler.config.applyConfig(values); err != nil {
+		if _, ok := err.(applyConfigError); ok {
+			handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		} else {
+			handler.rd.JSON(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	if err := handler.config.Persist(); err != nil {
+		handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	handler.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (handler *schemaConfigHandler) ListConfig(w http.ResponseWriter, r *http.Request) {
+	handler.rd.JSON(w, http.StatusOK, handler.config.listConfig())
+}
+
+func (handler *schemaConfigHandler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	handler.rd.JSON(w, http.StatusOK, handler.config.schema())
+}
+
+// newSchemaConfigRouter returns the router serving the config update, the
+// config list and the schema of the config. The scheduler can add its own
+// routes to it.
+func newSchemaConfigRouter(rd *render.Render, config schemaConfig) *mux.Router {
+	h := &schemaConfigHandler{rd: rd, config: config}
+	router := mux.NewRouter()
+	router.HandleFunc("/config", h.UpdateConfig).Methods("POST")
+	router.HandleFunc("/list", h.ListConfig).Methods("GET")
+	router.HandleFunc("/schema", h.GetSchema).Methods("GET")
+	return router
+}
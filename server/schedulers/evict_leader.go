@@ -15,13 +15,13 @@ package schedulers
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
 	"sync"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
-	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
@@ -45,7 +45,7 @@ const (
 func init() {
 	schedule.RegisterSliceDecoderBuilder(EvictLeaderType, func(args []string) schedule.ConfigDecoder {
 		return func(v interface{}) error {
-			if len(args) != 1 {
+			if len(args) == 0 || len(args)%2 == 0 {
 				return errs.ErrSchedulerConfig.FastGenByArgs("id")
 			}
 			conf, ok := v.(*evictLeaderSchedulerConfig)
@@ -84,8 +84,11 @@ type evictLeaderSchedulerConfig struct {
 	cluster           opt.Cluster
 }
 
+// BuildWithArgs adds or updates a store with the args, which are the store ID
+// followed by the start and end keys of the key ranges. The leaders of the
+// whole store are evicted if no key range is given.
 func (conf *evictLeaderSchedulerConfig) BuildWithArgs(args []string) error {
-	if len(args) != 1 {
+	if len(args) == 0 || len(args)%2 == 0 {
 		return errs.ErrSchedulerConfig.FastGenByArgs("id")
 	}
 
@@ -99,7 +102,16 @@ func (conf *evictLeaderSchedulerConfig) BuildWithArgs(args []string) error {
 	}
 	conf.mu.Lock()
 	defer conf.mu.Unlock()
+	old, exists := conf.StoreIDWithRanges[id]
 	conf.StoreIDWithRanges[id] = ranges
+	// Only the store whose leaders are all evicted pauses leader transfer, so
+	// the store with key ranges can still take the leaders of other regions.
+	if conf.cluster != nil && exists && isAllKeyRange(old) != isAllKeyRange(ranges) {
+		if isAllKeyRange(ranges) {
+			return conf.cluster.PauseLeaderTransfer(id)
+		}
+		conf.cluster.ResumeLeaderTransfer(id)
+	}
 	return nil
 }
 
@@ -132,7 +144,7 @@ func (conf *evictLeaderSchedulerConfig) getRanges(id uint64) []string {
 	var res []string
 	ranges := conf.StoreIDWithRanges[id]
 	for index := range ranges {
-		res = append(res, (string)(ranges[index].StartKey), (string)(ranges[index].EndKey))
+		res = append(res, url.QueryEscape(string(ranges[index].StartKey)), url.QueryEscape(string(ranges[index].EndKey)))
 	}
 	return res
 }
@@ -140,17 +152,30 @@ func (conf *evictLeaderSchedulerConfig) getRanges(id uint64) []string {
 func (conf *evictLeaderSchedulerConfig) mayBeRemoveStoreFromConfig(id uint64) (succ bool, last bool) {
 	conf.mu.Lock()
 	defer conf.mu.Unlock()
-	_, exists := conf.StoreIDWithRanges[id]
+	ranges, exists := conf.StoreIDWithRanges[id]
 	succ, last = false, false
 	if exists {
 		delete(conf.StoreIDWithRanges, id)
-		conf.cluster.ResumeLeaderTransfer(id)
+		if isAllKeyRange(ranges) {
+			conf.cluster.ResumeLeaderTransfer(id)
+		}
 		succ = true
 		last = len(conf.StoreIDWithRanges) == 0
 	}
 	return succ, last
 }
 
+// isAllKeyRange returns if the key ranges cover the whole key space, which
+// means the leaders of the whole store are evicted.
+func isAllKeyRange(ranges []core.KeyRange) bool {
+	for _, r := range ranges {
+		if len(r.StartKey) == 0 && len(r.EndKey) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
 type evictLeaderScheduler struct {
 	*BaseScheduler
 	conf    *evictLeaderSchedulerConfig
@@ -158,7 +183,7 @@ type evictLeaderScheduler struct {
 }
 
 // newEvictLeaderScheduler creates an admin scheduler that transfers all leaders
-// out of a store.
+// out of a store, or only the leaders of the regions in the given key ranges.
 func newEvictLeaderScheduler(opController *schedule.OperatorController, conf *evictLeaderSchedulerConfig) schedule.Scheduler {
 	base := NewBaseScheduler(opController)
 	handler := newEvictLeaderHandler(conf)
@@ -191,7 +216,10 @@ func (s *evictLeaderScheduler) Prepare(cluster opt.Cluster) error {
 	s.conf.mu.RLock()
 	defer s.conf.mu.RUnlock()
 	var res error
-	for id := range s.conf.StoreIDWithRanges {
+	for id, ranges := range s.conf.StoreIDWithRanges {
+		if !isAllKeyRange(ranges) {
+			continue
+		}
 		if err := cluster.PauseLeaderTransfer(id); err != nil {
 			res = err
 		}
@@ -202,8 +230,10 @@ func (s *evictLeaderScheduler) Prepare(cluster opt.Cluster) error {
 func (s *evictLeaderScheduler) Cleanup(cluster opt.Cluster) {
 	s.conf.mu.RLock()
 	defer s.conf.mu.RUnlock()
-	for id := range s.conf.StoreIDWithRanges {
-		cluster.ResumeLeaderTransfer(id)
+	for id, ranges := range s.conf.StoreIDWithRanges {
+		if isAllKeyRange(ranges) {
+			cluster.ResumeLeaderTransfer(id)
+		}
 	}
 }
 
@@ -280,50 +310,49 @@ func (s *evictLeaderScheduler) Schedule(cluster opt.Cluster) []*operator.Operato
 	return ops
 }
 
-type evictLeaderHandler struct {
-	rd     *render.Render
-	config *evictLeaderSchedulerConfig
+func (conf *evictLeaderSchedulerConfig) schema() configSchema {
+	return configSchema{
+		{Name: "store_id", Type: configFieldUint, Required: true, Description: "the store to evict the leaders from"},
+		{Name: "ranges", Type: configFieldKeyRanges, Description: "the key ranges to evict the leaders in, all the keys by default"},
+	}
 }
 
-func (handler *evictLeaderHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
-	var input map[string]interface{}
-	if err := apiutil.ReadJSONRespondError(handler.rd, w, r.Body, &input); err != nil {
-		return
-	}
-	var args []string
-	var exists bool
-	var id uint64
-	idFloat, ok := input["store_id"].(float64)
-	if ok {
-		id = (uint64)(idFloat)
-		if _, exists = handler.config.StoreIDWithRanges[id]; !exists {
-			if err := handler.config.cluster.PauseLeaderTransfer(id); err != nil {
-				handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
-				return
-			}
+func (conf *evictLeaderSchedulerConfig) applyConfig(values configValues) error {
+	id, _ := values.getUint("store_id")
+	conf.mu.RLock()
+	_, exists := conf.StoreIDWithRanges[id]
+	conf.mu.RUnlock()
+	args := []string{strconv.FormatUint(id, 10)}
+	if ranges, ok := values.getKeyRanges("ranges"); ok {
+		for _, key := range ranges {
+			args = append(args, url.QueryEscape(key))
 		}
-		args = append(args, strconv.FormatUint(id, 10))
-	}
-
-	ranges, ok := (input["ranges"]).([]string)
-	if ok {
-		args = append(args, ranges...)
 	} else if exists {
-		args = append(args, handler.config.getRanges(id)...)
+		args = append(args, conf.getRanges(id)...)
 	}
-
-	handler.config.BuildWithArgs(args)
-	err := handler.config.Persist()
+	keyRanges, err := getKeyRanges(args[1:])
 	if err != nil {
-		handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
-		return
+		return err
 	}
-	handler.rd.JSON(w, http.StatusOK, nil)
+	if !exists && isAllKeyRange(keyRanges) {
+		if err := conf.cluster.PauseLeaderTransfer(id); err != nil {
+			return applyConfigError{err}
+		}
+	}
+	// The args are valid, so it can only fail to pause the leader transfer.
+	if err := conf.BuildWithArgs(args); err != nil {
+		return applyConfigError{err}
+	}
+	return nil
 }
 
-func (handler *evictLeaderHandler) ListConfig(w http.ResponseWriter, r *http.Request) {
-	conf := handler.config.Clone()
-	handler.rd.JSON(w, http.StatusOK, conf)
+func (conf *evictLeaderSchedulerConfig) listConfig() interface{} {
+	return conf.Clone()
+}
+
+type evictLeaderHandler struct {
+	rd     *render.Render
+	config *evictLeaderSchedulerConfig
 }
 
 func (handler *evictLeaderHandler) DeleteConfig(w http.ResponseWriter, r *http.Request) {
@@ -365,9 +394,7 @@ func newEvictLeaderHandler(config *evictLeaderSchedulerConfig) http.Handler {
 		config: config,
 		rd:     render.New(render.Options{IndentJSON: true}),
 	}
-	router := mux.NewRouter()
-	router.HandleFunc("/config", h.UpdateConfig).Methods("POST")
-	router.HandleFunc("/list", h.ListConfig).Methods("GET")
+	router := newSchemaConfigRouter(h.rd, config)
 	router.HandleFunc("/delete/{store_id}", h.DeleteConfig).Methods("DELETE")
 	return router
 }
@@ -23,6 +23,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/log"
 	"github.com/prometheus/client_golang/prometheus"
@@ -98,6 +99,14 @@ type hotScheduler struct {
 
 	// temporary states but exported to API or metrics
 	stLoadInfos [resourceTypeLen]map[uint64]*storeLoadDetail
+	// canaryLoadInfos is the load detail of the canary stores, which are not
+	// in stLoadInfos, and canaryConf is the config to schedule them.
+	canaryLoadInfos [resourceTypeLen]map[uint64]*storeLoadDetail
+	canaryConf      *hotRegionSchedulerConfig
+	// canaryPass is set if the current schedule is for the canary stores. The
+	// canary stores and the others are scheduled in turn.
+	canaryPass   bool
+	canaryReport hotCanaryReport
 	// pendingSums indicates the [resourceType] storeID -> pending Influence
 	// This stores the pending Influence for each store by resource type.
 	pendingSums [resourceTypeLen]map[uint64]Influence
@@ -120,7 +129,9 @@ func newHotScheduler(opController *schedule.OperatorController, conf *hotRegionS
 	for ty := resourceType(0); ty < resourceTypeLen; ty++ {
 		ret.pendings[ty] = map[*pendingInfluence]struct{}{}
 		ret.stLoadInfos[ty] = map[uint64]*storeLoadDetail{}
+		ret.canaryLoadInfos[ty] = map[uint64]*storeLoadDetail{}
 	}
+	ret.canaryReport.Stable = newHotGroupReport()
 	return ret
 }
 
@@ -147,7 +158,10 @@ func (h *hotScheduler) GetType() string {
 }
 
 func (h *hotScheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.conf.ServeHTTP(w, r)
+	router := mux.NewRouter()
+	router.HandleFunc("/canary", h.handleGetCanaryReport).Methods("GET")
+	router.NotFoundHandler = h.conf
+	router.ServeHTTP(w, r)
 }
 
 func (h *hotScheduler) GetMinInterval() time.Duration {
@@ -191,6 +205,7 @@ func (h *hotScheduler) dispatch(typ rwType, cluster opt.Cluster) []*operator.Ope
 	defer h.Unlock()
 
 	h.prepareForBalance(cluster)
+	h.canaryPass = h.canaryConf != nil && !h.canaryPass
 
 	switch typ {
 	case read:
@@ -206,11 +221,29 @@ func (h *hotScheduler) dispatch(typ rwType, cluster opt.Cluster) []*operator.Ope
 func (h *hotScheduler) prepareForBalance(cluster opt.Cluster) {
 	h.summaryPendingInfluence()
 
-	storesLoads := cluster.GetStoresLoads()
+	canaryStores := h.conf.GetCanaryStoreIDs()
+	stableLoads, canaryLoads := splitStoresLoads(cluster.GetStoresLoads(), canaryStores)
+	regionRead, regionWrite := cluster.RegionReadStats(), cluster.RegionWriteStats()
+	h.stLoadInfos = h.summaryLoadInfos(stableLoads, regionRead, regionWrite)
+	h.canaryLoadInfos = h.summaryLoadInfos(canaryLoads, regionRead, regionWrite)
+	h.canaryConf = nil
+	if len(canaryStores) > 0 {
+		canaryConf, err := h.conf.getCanaryConfig()
+		if err != nil {
+			log.Error("failed to get the canary config of hot region scheduler", errs.ZapError(err))
+		} else {
+			h.canaryConf = canaryConf
+		}
+	}
+	h.updateCanaryReport(canaryStores)
+}
 
+func (h *hotScheduler) summaryLoadInfos(
+	storesLoads map[uint64][]float64,
+	regionRead, regionWrite map[uint64][]*statistics.HotPeerStat,
+) (loadInfos [resourceTypeLen]map[uint64]*storeLoadDetail) {
 	{ // update read statistics
-		regionRead := cluster.RegionReadStats()
-		h.stLoadInfos[readLeader] = summaryStoresLoad(
+		loadInfos[readLeader] = summaryStoresLoad(
 			storesLoads,
 			h.pendingSums[readLeader],
 			regionRead,
@@ -218,19 +251,19 @@ func (h *hotScheduler) prepareForBalance(cluster opt.Cluster) {
 	}
 
 	{ // update write statistics
-		regionWrite := cluster.RegionWriteStats()
-		h.stLoadInfos[writeLeader] = summaryStoresLoad(
+		loadInfos[writeLeader] = summaryStoresLoad(
 			storesLoads,
 			h.pendingSums[writeLeader],
 			regionWrite,
 			write, core.LeaderKind)
 
-		h.stLoadInfos[writePeer] = summaryStoresLoad(
+		loadInfos[writePeer] = summaryStoresLoad(
 			storesLoads,
 			h.pendingSums[writePeer],
 			regionWrite,
 			write, core.RegionKind)
 	}
+	return loadInfos
 }
 
 // summaryPendingInfluence calculate the summary of pending Influence for each store
@@ -444,6 +477,7 @@ func (h *hotScheduler) balanceHotWriteRegions(cluster opt.Cluster) []*operator.O
 
 type balanceSolver struct {
 	sche         *hotScheduler
+	conf         *hotRegionSchedulerConfig
 	cluster      opt.Cluster
 	stLoadDetail map[uint64]*storeLoadDetail
 	rwTy         rwType
@@ -469,13 +503,19 @@ type solution struct {
 }
 
 func (bs *balanceSolver) init() {
+	loadInfos := bs.sche.stLoadInfos
+	bs.conf = bs.sche.conf
+	if bs.sche.canaryPass {
+		loadInfos = bs.sche.canaryLoadInfos
+		bs.conf = bs.sche.canaryConf
+	}
 	switch toResourceType(bs.rwTy, bs.opTy) {
 	case writePeer:
-		bs.stLoadDetail = bs.sche.stLoadInfos[writePeer]
+		bs.stLoadDetail = loadInfos[writePeer]
 	case writeLeader:
-		bs.stLoadDetail = bs.sche.stLoadInfos[writeLeader]
+		bs.stLoadDetail = loadInfos[writeLeader]
 	case readLeader:
-		bs.stLoadDetail = bs.sche.stLoadInfos[readLeader]
+		bs.stLoadDetail = loadInfos[readLeader]
 	}
 	// And it will be unnecessary to filter unhealthy store, because it has been solved in process heartbeat
 
@@ -494,9 +534,9 @@ func (bs *balanceSolver) init() {
 	}
 
 	bs.rankStep = &storeLoad{
-		ByteRate: maxCur.ByteRate * bs.sche.conf.GetByteRankStepRatio(),
-		KeyRate:  maxCur.KeyRate * bs.sche.conf.GetKeyRankStepRatio(),
-		Count:    maxCur.Count * bs.sche.conf.GetCountRankStepRatio(),
+		ByteRate: maxCur.ByteRate * bs.conf.GetByteRankStepRatio(),
+		KeyRate:  maxCur.KeyRate * bs.conf.GetKeyRankStepRatio(),
+		Count:    maxCur.Count * bs.conf.GetCountRankStepRatio(),
 	}
 }
 
@@ -571,6 +611,9 @@ func (bs *balanceSolver) solve() []*operator.Operator {
 			return nil
 		}
 	}
+	if len(ops) > 0 {
+		bs.sche.recordGroupOperators(bs.rwTy, bs.opTy)
+	}
 	return ops
 }
 
@@ -598,8 +641,8 @@ func (bs *balanceSolver) filterSrcStores() map[uint64]*storeLoadDetail {
 		if len(detail.HotPeers) == 0 {
 			continue
 		}
-		if detail.LoadPred.min().ByteRate > bs.sche.conf.GetSrcToleranceRatio()*detail.LoadPred.Expect.ByteRate &&
-			detail.LoadPred.min().KeyRate > bs.sche.conf.GetSrcToleranceRatio()*detail.LoadPred.Expect.KeyRate {
+		if detail.LoadPred.min().ByteRate > bs.conf.GetSrcToleranceRatio()*detail.LoadPred.Expect.ByteRate &&
+			detail.LoadPred.min().KeyRate > bs.conf.GetSrcToleranceRatio()*detail.LoadPred.Expect.KeyRate {
 			ret[id] = detail
 			hotSchedulerResultCounter.WithLabelValues("src-store-succ", strconv.FormatUint(id, 10)).Inc()
 		}
@@ -613,7 +656,7 @@ func (bs *balanceSolver) filterSrcStores() map[uint64]*storeLoadDetail {
 func (bs *balanceSolver) filterHotPeers() []*statistics.HotPeerStat {
 	ret := bs.stLoadDetail[bs.cur.srcStoreID].HotPeers
 	// Return at most MaxPeerNum peers, to prevent balanceSolver.solve() too slow.
-	maxPeerNum := bs.sche.conf.GetMaxPeerNumber()
+	maxPeerNum := bs.conf.GetMaxPeerNumber()
 
 	// filter pending region
 	appendItem := func(items []*statistics.HotPeerStat, item *statistics.HotPeerStat) []*statistics.HotPeerStat {
@@ -771,7 +814,7 @@ func (bs *balanceSolver) filterDstStores() map[uint64]*storeLoadDetail {
 
 func (bs *balanceSolver) pickDstStores(filters []filter.Filter, candidates []*core.StoreInfo) map[uint64]*storeLoadDetail {
 	ret := make(map[uint64]*storeLoadDetail, len(candidates))
-	dstToleranceRatio := bs.sche.conf.GetDstToleranceRatio()
+	dstToleranceRatio := bs.conf.GetDstToleranceRatio()
 	for _, store := range candidates {
 		if filter.Target(bs.cluster.GetOpts(), store, filters) {
 			detail := bs.stLoadDetail[store.GetID()]
@@ -809,10 +852,10 @@ func (bs *balanceSolver) calcProgressiveRank() {
 		// we use DecRatio(Decline Ratio) to expect that the dst store's (key/byte) rate should still be less
 		// than the src store's (key/byte) rate after scheduling one peer.
 		keyDecRatio := (dstLd.KeyRate + peer.GetKeyRate()) / getSrcDecRate(srcLd.KeyRate, peer.GetKeyRate())
-		keyHot := peer.GetKeyRate() >= bs.sche.conf.GetMinHotKeyRate()
+		keyHot := peer.GetKeyRate() >= bs.conf.GetMinHotKeyRate()
 		byteDecRatio := (dstLd.ByteRate + peer.GetByteRate()) / getSrcDecRate(srcLd.ByteRate, peer.GetByteRate())
-		byteHot := peer.GetByteRate() > bs.sche.conf.GetMinHotByteRate()
-		greatDecRatio, minorDecRatio := bs.sche.conf.GetGreatDecRatio(), bs.sche.conf.GetMinorGreatDecRatio()
+		byteHot := peer.GetByteRate() > bs.conf.GetMinHotByteRate()
+		greatDecRatio, minorDecRatio := bs.conf.GetGreatDecRatio(), bs.conf.GetMinorGreatDecRatio()
 		switch {
 		case byteHot && byteDecRatio <= greatDecRatio && keyHot && keyDecRatio <= greatDecRatio:
 			// If belong to the case, both byte rate and key rate will be more balanced, the best choice.
@@ -1049,30 +1092,30 @@ func (bs *balanceSolver) buildOperators() ([]*operator.Operator, []Influence) {
 func (h *hotScheduler) GetHotReadStatus() *statistics.StoreHotPeersInfos {
 	h.RLock()
 	defer h.RUnlock()
-	asLeader := make(statistics.StoreHotPeersStat, len(h.stLoadInfos[readLeader]))
-	for id, detail := range h.stLoadInfos[readLeader] {
-		asLeader[id] = detail.toHotPeersStat()
-	}
 	return &statistics.StoreHotPeersInfos{
-		AsLeader: asLeader,
+		AsLeader: h.toHotPeersStat(readLeader),
 	}
 }
 
 func (h *hotScheduler) GetHotWriteStatus() *statistics.StoreHotPeersInfos {
 	h.RLock()
 	defer h.RUnlock()
-	asLeader := make(statistics.StoreHotPeersStat, len(h.stLoadInfos[writeLeader]))
-	asPeer := make(statistics.StoreHotPeersStat, len(h.stLoadInfos[writePeer]))
-	for id, detail := range h.stLoadInfos[writeLeader] {
-		asLeader[id] = detail.toHotPeersStat()
-	}
-	for id, detail := range h.stLoadInfos[writePeer] {
-		asPeer[id] = detail.toHotPeersStat()
-	}
 	return &statistics.StoreHotPeersInfos{
-		AsLeader: asLeader,
-		AsPeer:   asPeer,
+		AsLeader: h.toHotPeersStat(writeLeader),
+		AsPeer:   h.toHotPeersStat(writePeer),
+	}
+}
+
+// toHotPeersStat returns the hot peers of the stores in both the stable group
+// and the canary group.
+func (h *hotScheduler) toHotPeersStat(ty resourceType) statistics.StoreHotPeersStat {
+	stat := make(statistics.StoreHotPeersStat, len(h.stLoadInfos[ty])+len(h.canaryLoadInfos[ty]))
+	for _, loadInfos := range []map[uint64]*storeLoadDetail{h.stLoadInfos[ty], h.canaryLoadInfos[ty]} {
+		for id, detail := range loadInfos {
+			stat[id] = detail.toHotPeersStat()
+		}
 	}
+	return stat
 }
 
 func (h *hotScheduler) GetWritePendingInfluence() map[uint64]Influence {
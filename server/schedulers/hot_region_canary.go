@@ -0,0 +1,205 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/pingcap/errors"
+	"github.com/unrolled/render"
+)
+
+// The stores are divided into the stable group and the canary group when the
+// canary stores are set. The groups are scheduled separately, i.e. the hot
+// peers are only moved within a group, and the loads of a group are balanced
+// towards the expectation of the group, so the outcomes of the experimental
+// config in the canary group can be compared with the stable group.
+const (
+	hotStableGroup = "stable"
+	hotCanaryGroup = "canary"
+)
+
+// validateCanaryConfig checks that the canary config in the input only
+// overrides the items of the hot region scheduler config.
+func validateCanaryConfig(data []byte) error {
+	var input struct {
+		CanaryConfig json.RawMessage `json:"canary-config"`
+	}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+	if len(input.CanaryConfig) == 0 || string(input.CanaryConfig) == "null" {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(input.CanaryConfig))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&hotRegionSchedulerConfig{}); err != nil {
+		return errors.Errorf("invalid canary-config: %v", err)
+	}
+	return nil
+}
+
+// GetCanaryStoreIDs returns the canary stores.
+func (conf *hotRegionSchedulerConfig) GetCanaryStoreIDs() map[uint64]struct{} {
+	conf.RLock()
+	defer conf.RUnlock()
+	stores := make(map[uint64]struct{}, len(conf.CanaryStoreIDs))
+	for _, id := range conf.CanaryStoreIDs {
+		stores[id] = struct{}{}
+	}
+	return stores
+}
+
+// getCanaryConfig returns the config used to schedule the canary stores,
+// which is the config overridden by the canary config.
+func (conf *hotRegionSchedulerConfig) getCanaryConfig() (*hotRegionSchedulerConfig, error) {
+	conf.RLock()
+	defer conf.RUnlock()
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	canary := &hotRegionSchedulerConfig{}
+	if err := json.Unmarshal(data, canary); err != nil {
+		return nil, err
+	}
+	if len(conf.CanaryConfig) > 0 {
+		if err := json.Unmarshal(conf.CanaryConfig, canary); err != nil {
+			return nil, err
+		}
+	}
+	canary.CanaryStoreIDs, canary.CanaryConfig = nil, nil
+	return canary, nil
+}
+
+// splitStoresLoads splits the loads of the stores into the stable group and
+// the canary group.
+func splitStoresLoads(storesLoads map[uint64][]float64, canaryStores map[uint64]struct{}) (stable, canary map[uint64][]float64) {
+	if len(canaryStores) == 0 {
+		return storesLoads, nil
+	}
+	stable = make(map[uint64][]float64, len(storesLoads))
+	canary = make(map[uint64][]float64, len(canaryStores))
+	for id, loads := range storesLoads {
+		if _, ok := canaryStores[id]; ok {
+			canary[id] = loads
+		} else {
+			stable[id] = loads
+		}
+	}
+	return stable, canary
+}
+
+// hotGroupReport is the outcome of scheduling a group of stores.
+type hotGroupReport struct {
+	StoreIDs []uint64 `json:"store-ids"`
+	// Operators is the number of the operators created for the group by the
+	// type, e.g. "read-transfer-leader".
+	Operators map[string]uint64 `json:"operators"`
+	// Imbalance is the ratio of the max byte rate of the stores to the
+	// expected byte rate by the resource type. 1 means balanced.
+	Imbalance map[string]float64 `json:"imbalance"`
+}
+
+func newHotGroupReport() *hotGroupReport {
+	return &hotGroupReport{
+		Operators: make(map[string]uint64),
+		Imbalance: make(map[string]float64),
+	}
+}
+
+// hotCanaryReport compares the outcomes of the canary group with the stable
+// group. The operator counts start from the time the canary stores are set.
+type hotCanaryReport struct {
+	Stable       *hotGroupReport           `json:"stable"`
+	Canary       *hotGroupReport           `json:"canary,omitempty"`
+	CanaryConfig *hotRegionSchedulerConfig `json:"canary-config,omitempty"`
+}
+
+// updateCanaryReport updates the stores and the imbalance of the groups. It
+// should be called after the loads are summarized.
+func (h *hotScheduler) updateCanaryReport(canaryStores map[uint64]struct{}) {
+	stable, canary := h.canaryReport.Stable, h.canaryReport.Canary
+	if len(canaryStores) == 0 {
+		canary = nil
+	} else if canary == nil || !sameStores(canary.StoreIDs, canaryStores) {
+		// The outcomes are compared from the time the canary stores are set.
+		stable, canary = newHotGroupReport(), newHotGroupReport()
+	}
+	stable.StoreIDs, stable.Imbalance = groupStoresAndImbalance(h.stLoadInfos)
+	if canary != nil {
+		canary.StoreIDs, canary.Imbalance = groupStoresAndImbalance(h.canaryLoadInfos)
+	}
+	h.canaryReport = hotCanaryReport{Stable: stable, Canary: canary, CanaryConfig: h.canaryConf}
+}
+
+func (h *hotScheduler) recordGroupOperators(rwTy rwType, opTy opType) {
+	report := h.canaryReport.Stable
+	if h.canaryPass {
+		report = h.canaryReport.Canary
+	}
+	if report != nil {
+		report.Operators[rwTy.String()+"-"+opTy.String()]++
+	}
+}
+
+func sameStores(ids []uint64, stores map[uint64]struct{}) bool {
+	if len(ids) != len(stores) {
+		return false
+	}
+	for _, id := range ids {
+		if _, ok := stores[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func groupStoresAndImbalance(loadInfos [resourceTypeLen]map[uint64]*storeLoadDetail) ([]uint64, map[string]float64) {
+	var ids []uint64
+	for id := range loadInfos[writePeer] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	imbalance := make(map[string]float64, resourceTypeLen)
+	for ty, details := range map[string]map[uint64]*storeLoadDetail{
+		"write-peer":   loadInfos[writePeer],
+		"write-leader": loadInfos[writeLeader],
+		"read-leader":  loadInfos[readLeader],
+	} {
+		var maxRate, expect float64
+		for _, detail := range details {
+			if detail.LoadPred.Current.ByteRate > maxRate {
+				maxRate = detail.LoadPred.Current.ByteRate
+			}
+			expect = detail.LoadPred.Expect.ByteRate
+		}
+		if expect > 0 {
+			imbalance[ty] = maxRate / expect
+		} else {
+			imbalance[ty] = 1
+		}
+	}
+	return ids, imbalance
+}
+
+func (h *hotScheduler) handleGetCanaryReport(w http.ResponseWriter, r *http.Request) {
+	h.RLock()
+	defer h.RUnlock()
+	rd := render.New(render.Options{IndentJSON: true})
+	rd.JSON(w, http.StatusOK, h.canaryReport)
+}
@@ -65,6 +65,11 @@ type hotRegionSchedulerConfig struct {
 	MinorDecRatio         float64 `json:"minor-dec-ratio"`
 	SrcToleranceRatio     float64 `json:"src-tolerance-ratio"`
 	DstToleranceRatio     float64 `json:"dst-tolerance-ratio"`
+
+	// The canary stores are scheduled separately from the other stores, with
+	// the items in CanaryConfig overriding the config above.
+	CanaryStoreIDs []uint64        `json:"canary-store-ids,omitempty"`
+	CanaryConfig   json.RawMessage `json:"canary-config,omitempty"`
 }
 
 func (conf *hotRegionSchedulerConfig) EncodeConfig() ([]byte, error) {
@@ -177,6 +182,10 @@ func (conf *hotRegionSchedulerConfig) handleSetConfig(w http.ResponseWriter, r *
 		return
 	}
 
+	if err := validateCanaryConfig(data); err != nil {
+		rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err := json.Unmarshal(data, conf); err != nil {
 		rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
@@ -362,6 +362,85 @@ func (s *testHotWriteRegionSchedulerSuite) TestWithKeyRate(c *C) {
 	}
 }
 
+func (s *testHotWriteRegionSchedulerSuite) TestCanary(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	statistics.Denoising = false
+	opt := config.NewTestOptions()
+	sche, err := schedule.CreateScheduler(HotWriteRegionType, schedule.NewOperatorController(ctx, nil, nil), core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+	hb := sche.(*hotScheduler)
+	hb.conf.SetDstToleranceRatio(1)
+	hb.conf.SetSrcToleranceRatio(1)
+	hb.conf.CanaryStoreIDs = []uint64{1, 4}
+
+	tc := mockcluster.NewCluster(opt)
+	tc.SetHotRegionCacheHitsThreshold(0)
+	tc.DisableFeature(versioninfo.JointConsensus)
+	for id := uint64(1); id <= 5; id++ {
+		tc.AddRegionStore(id, 20)
+	}
+	tc.UpdateStorageWrittenStats(1, 10.5*MB*statistics.StoreHeartBeatReportInterval, 10.5*MB*statistics.StoreHeartBeatReportInterval)
+	tc.UpdateStorageWrittenStats(2, 9.5*MB*statistics.StoreHeartBeatReportInterval, 9.5*MB*statistics.StoreHeartBeatReportInterval)
+	tc.UpdateStorageWrittenStats(3, 9.5*MB*statistics.StoreHeartBeatReportInterval, 9.8*MB*statistics.StoreHeartBeatReportInterval)
+	tc.UpdateStorageWrittenStats(4, 9*MB*statistics.StoreHeartBeatReportInterval, 9*MB*statistics.StoreHeartBeatReportInterval)
+	tc.UpdateStorageWrittenStats(5, 8.9*MB*statistics.StoreHeartBeatReportInterval, 9.2*MB*statistics.StoreHeartBeatReportInterval)
+	addRegionInfo(tc, write, []testRegionInfo{
+		{1, []uint64{2, 1, 3}, 0.5 * MB, 0.5 * MB},
+		{2, []uint64{2, 1, 3}, 0.5 * MB, 0.5 * MB},
+		{3, []uint64{2, 4, 3}, 0.05 * MB, 0.1 * MB},
+	})
+
+	// The canary stores and the other stores are scheduled in turn, and the
+	// peers are only moved within the groups.
+	hb.clearPendingInfluence()
+	ops := hb.Schedule(tc)
+	c.Assert(ops, HasLen, 1)
+	testutil.CheckTransferPeer(c, ops[0], operator.OpHotRegion, 1, 4)
+	ops = hb.Schedule(tc)
+	c.Assert(ops, HasLen, 1)
+	testutil.CheckTransferPeer(c, ops[0], operator.OpHotRegion, 3, 5)
+
+	report := hb.canaryReport
+	c.Assert(report.Canary.StoreIDs, DeepEquals, []uint64{1, 4})
+	c.Assert(report.Stable.StoreIDs, DeepEquals, []uint64{2, 3, 5})
+	c.Assert(report.Canary.Operators["write-move-peer"], Equals, uint64(1))
+	c.Assert(report.Stable.Operators["write-move-peer"], Equals, uint64(1))
+	c.Assert(report.Canary.Imbalance["write-peer"] > 1, IsTrue)
+	c.Assert(report.CanaryConfig.GetSrcToleranceRatio(), Equals, 1.0)
+
+	// The canary config only applies to the canary stores.
+	hb.conf.CanaryConfig = []byte(`{"src-tolerance-ratio": 2}`)
+	for i := 0; i < 2; i++ {
+		hb.clearPendingInfluence()
+		ops = hb.Schedule(tc)
+		if hb.canaryPass {
+			c.Assert(ops, HasLen, 0)
+		} else {
+			c.Assert(ops, HasLen, 1)
+			testutil.CheckTransferPeer(c, ops[0], operator.OpHotRegion, 3, 5)
+		}
+	}
+	c.Assert(hb.canaryReport.CanaryConfig.GetSrcToleranceRatio(), Equals, 2.0)
+
+	// Unknown items are rejected.
+	c.Assert(validateCanaryConfig([]byte(`{"canary-config": {"src-tolerance-ratio": 2}}`)), IsNil)
+	c.Assert(validateCanaryConfig([]byte(`{"canary-config": {"unknown": 2}}`)), NotNil)
+	c.Assert(validateCanaryConfig([]byte(`{"canary-config": {"src-tolerance-ratio": "2"}}`)), NotNil)
+
+	// The operators are counted again after the canary stores are changed.
+	hb.conf.CanaryStoreIDs = []uint64{1, 2, 4}
+	hb.clearPendingInfluence()
+	hb.Schedule(tc)
+	c.Assert(hb.canaryReport.Canary.StoreIDs, DeepEquals, []uint64{1, 2, 4})
+	c.Assert(hb.canaryReport.Stable.Operators["write-move-peer"], Equals, uint64(0))
+	hb.conf.CanaryStoreIDs = nil
+	hb.clearPendingInfluence()
+	hb.Schedule(tc)
+	c.Assert(hb.canaryReport.Canary, IsNil)
+	c.Assert(hb.canaryPass, IsFalse)
+}
+
 func (s *testHotWriteRegionSchedulerSuite) TestUnhealthyStore(c *C) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -100,10 +100,13 @@ func (s *labelScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 func (s *labelScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
 	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
 	stores := cluster.GetStores()
-	rejectLeaderStores := make(map[uint64]struct{})
+	// The leaders of the draining stores are moved away as well.
+	rejectLeaderStores := make(map[uint64]string)
 	for _, s := range stores {
 		if cluster.GetOpts().CheckLabelProperty(opt.RejectLeader, s.GetLabels()) {
-			rejectLeaderStores[s.GetID()] = struct{}{}
+			rejectLeaderStores[s.GetID()] = "label-reject-leader"
+		} else if s.IsDraining() && !s.IsTombstone() {
+			rejectLeaderStores[s.GetID()] = "drain-leader"
 		}
 	}
 	if len(rejectLeaderStores) == 0 {
@@ -111,7 +114,7 @@ func (s *labelScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
 		return nil
 	}
 	log.Debug("label scheduler reject leader store list", zap.Reflect("stores", rejectLeaderStores))
-	for id := range rejectLeaderStores {
+	for id, desc := range rejectLeaderStores {
 		if region := cluster.RandLeaderRegion(id, s.conf.Ranges); region != nil {
 			log.Debug("label scheduler selects region to transfer leader", zap.Uint64("region-id", region.GetID()))
 			excludeStores := make(map[uint64]struct{})
@@ -132,7 +135,7 @@ func (s *labelScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
 				continue
 			}
 
-			op, err := operator.CreateTransferLeaderOperator("label-reject-leader", cluster, region, id, target.GetID(), operator.OpLeader)
+			op, err := operator.CreateTransferLeaderOperator(desc, cluster, region, id, target.GetID(), operator.OpLeader)
 			if err != nil {
 				log.Debug("fail to create transfer label reject leader operator", errs.ZapError(err))
 				return nil
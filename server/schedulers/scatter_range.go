@@ -18,9 +18,7 @@ import (
 	"net/http"
 	"sync"
 
-	"github.com/gorilla/mux"
 	"github.com/pingcap/errors"
-	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
@@ -246,61 +244,35 @@ func (l *scatterRangeScheduler) Schedule(cluster opt.Cluster) []*operator.Operat
 	return nil
 }
 
-type scatterRangeHandler struct {
-	rd     *render.Render
-	config *scatterRangeSchedulerConfig
-}
-
-func (handler *scatterRangeHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
-	var input map[string]interface{}
-	if err := apiutil.ReadJSONRespondError(handler.rd, w, r.Body, &input); err != nil {
-		return
-	}
-	var args []string
-	name, ok := input["range-name"].(string)
-	if ok {
-		if name != handler.config.GetRangeName() {
-			handler.rd.JSON(w, http.StatusInternalServerError, errors.New("Cannot change the range name, please delete this schedule").Error())
-			return
-		}
-		args = append(args, name)
-	} else {
-		args = append(args, handler.config.GetRangeName())
-	}
-
-	startKey, ok := input["start-key"].(string)
-	if ok {
-		args = append(args, startKey)
-	} else {
-		args = append(args, string(handler.config.GetStartKey()))
+func (conf *scatterRangeSchedulerConfig) schema() configSchema {
+	return configSchema{
+		{Name: "range-name", Type: configFieldString, Description: "the name of the range, which cannot be changed", validate: func(v interface{}) error {
+			if v.(string) != conf.GetRangeName() {
+				return errors.New("Cannot change the range name, please delete this schedule")
+			}
+			return nil
+		}},
+		{Name: "start-key", Type: configFieldString, Description: "the start key of the range"},
+		{Name: "end-key", Type: configFieldString, Description: "the end key of the range"},
 	}
+}
 
-	endKey, ok := input["end-key"].(string)
-	if ok {
-		args = append(args, endKey)
-	} else {
-		args = append(args, string(handler.config.GetEndKey()))
+func (conf *scatterRangeSchedulerConfig) applyConfig(values configValues) error {
+	startKey, ok := values.getString("start-key")
+	if !ok {
+		startKey = string(conf.GetStartKey())
 	}
-	handler.config.BuildWithArgs(args)
-	err := handler.config.Persist()
-	if err != nil {
-		handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	endKey, ok := values.getString("end-key")
+	if !ok {
+		endKey = string(conf.GetEndKey())
 	}
-	handler.rd.JSON(w, http.StatusOK, nil)
+	return conf.BuildWithArgs([]string{conf.GetRangeName(), startKey, endKey})
 }
 
-func (handler *scatterRangeHandler) ListConfig(w http.ResponseWriter, r *http.Request) {
-	conf := handler.config.Clone()
-	handler.rd.JSON(w, http.StatusOK, conf)
+func (conf *scatterRangeSchedulerConfig) listConfig() interface{} {
+	return conf.Clone()
 }
 
 func newScatterRangeHandler(config *scatterRangeSchedulerConfig) http.Handler {
-	h := &scatterRangeHandler{
-		config: config,
-		rd:     render.New(render.Options{IndentJSON: true}),
-	}
-	router := mux.NewRouter()
-	router.HandleFunc("/config", h.UpdateConfig).Methods("POST")
-	router.HandleFunc("/list", h.ListConfig).Methods("GET")
-	return router
+	return newSchemaConfigRouter(render.New(render.Options{IndentJSON: true}), config)
 }
@@ -15,10 +15,17 @@ package schedulers
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/mock/mockcluster"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server/config"
@@ -134,6 +141,41 @@ func (s *testRejectLeaderSuite) TestRejectLeader(c *C) {
 	testutil.CheckTransferLeader(c, op[0], operator.OpLeader, 1, 2)
 }
 
+func (s *testRejectLeaderSuite) TestDrainLeader(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := config.NewTestOptions()
+	tc := mockcluster.NewCluster(opts)
+
+	// Add 3 stores 1,2,3.
+	tc.AddLeaderStore(1, 1)
+	tc.AddLeaderStore(2, 10)
+	tc.AddLeaderStore(3, 0)
+	// Add 2 regions with leader on 1 and 2.
+	tc.AddLeaderRegion(1, 1, 2, 3)
+	tc.AddLeaderRegion(2, 2, 1, 3)
+
+	oc := schedule.NewOperatorController(ctx, nil, nil)
+	sl, err := schedule.CreateScheduler(LabelType, oc, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(LabelType, []string{"", ""}))
+	c.Assert(err, IsNil)
+	c.Assert(sl.Schedule(tc), IsNil)
+
+	// The label scheduler transfers leader out of the draining store1.
+	tc.PutStore(tc.GetStore(1).Clone(core.SetStoreDrain(&core.StoreDrain{StartTime: time.Now(), StartLeaderCount: 1})))
+	op := sl.Schedule(tc)
+	testutil.CheckTransferLeaderFrom(c, op[0], operator.OpLeader, 1)
+	c.Assert(op[0].Desc(), Equals, "drain-leader")
+
+	// The draining store2 does not receive leaders.
+	tc.PutStore(tc.GetStore(2).Clone(core.SetStoreDrain(&core.StoreDrain{StartTime: time.Now(), StartLeaderCount: 10})))
+	tc.SetStoreDisconnect(3)
+	c.Assert(sl.Schedule(tc), IsNil)
+	tc.SetStoreUp(3)
+	op = sl.Schedule(tc)
+	c.Assert(op, HasLen, 1)
+	c.Assert(op[0].Step(0).(operator.TransferLeader).ToStore, Equals, uint64(3))
+}
+
 var _ = Suite(&testShuffleHotRegionSchedulerSuite{})
 
 type testShuffleHotRegionSchedulerSuite struct{}
@@ -249,6 +291,56 @@ func (s *testEvictLeaderSuite) TestEvictLeader(c *C) {
 	c.Assert(sl.IsScheduleAllowed(tc), IsTrue)
 	op := sl.Schedule(tc)
 	testutil.CheckTransferLeader(c, op[0], operator.OpLeader, 1, 2)
+
+}
+
+func (s *testEvictLeaderSuite) TestEvictLeaderWithRanges(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(opt)
+
+	// Add stores 1, 2, 3
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderStore(3, 0)
+	// Add regions 1, 2, 3 with leaders in store 1
+	tc.AddLeaderRegionWithRange(1, "a", "b", 1, 2, 3)
+	tc.AddLeaderRegionWithRange(2, "b", "c", 1, 2, 3)
+	tc.AddLeaderRegionWithRange(3, "c", "d", 1, 2, 3)
+
+	sl, err := schedule.CreateScheduler(EvictLeaderType, schedule.NewOperatorController(ctx, tc, nil), core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(EvictLeaderType, []string{"1", "b", "c"}))
+	c.Assert(err, IsNil)
+	c.Assert(sl.Prepare(tc), IsNil)
+	// The store with key ranges does not pause leader transfer.
+	c.Assert(tc.GetStore(1).AllowLeaderTransfer(), IsTrue)
+	for i := 0; i < 10; i++ {
+		ops := sl.Schedule(tc)
+		c.Assert(ops, HasLen, 1)
+		c.Assert(ops[0].RegionID(), Equals, uint64(2))
+		testutil.CheckTransferLeaderFrom(c, ops[0], operator.OpLeader, 1)
+	}
+
+	// Evict all leaders of the store.
+	conf := sl.(*evictLeaderScheduler).conf
+	c.Assert(conf.BuildWithArgs([]string{"1"}), IsNil)
+	c.Assert(tc.GetStore(1).AllowLeaderTransfer(), IsFalse)
+	regionIDs := make(map[uint64]struct{})
+	for i := 0; i < 100; i++ {
+		for _, op := range sl.Schedule(tc) {
+			regionIDs[op.RegionID()] = struct{}{}
+		}
+	}
+	c.Assert(regionIDs, HasLen, 3)
+	// Back to the key ranges.
+	c.Assert(conf.BuildWithArgs([]string{"1", "c", "d"}), IsNil)
+	c.Assert(tc.GetStore(1).AllowLeaderTransfer(), IsTrue)
+	c.Assert(conf.getRanges(1), DeepEquals, []string{"c", "d"})
+	ops := sl.Schedule(tc)
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(3))
+
+	c.Assert(conf.BuildWithArgs([]string{"1", "c"}), NotNil)
 }
 
 var _ = Suite(&testShuffleRegionSuite{})
@@ -555,3 +647,88 @@ func (s *testBalanceLeaderSchedulerWithRuleEnabledSuite) TestBalanceLeaderWithCo
 		}
 	}
 }
+
+var _ = Suite(&testConfigSchemaSuite{})
+
+type testConfigSchemaSuite struct{}
+
+func (s *testConfigSchemaSuite) TestParse(c *C) {
+	schema := configSchema{
+		{Name: "id", Type: configFieldUint, Required: true},
+		{Name: "name", Type: configFieldString},
+		{Name: "ranges", Type: configFieldKeyRanges},
+	}
+	values, err := schema.parse(map[string]interface{}{"id": float64(1), "name": "a", "ranges": []interface{}{"a", "b"}, "other": true})
+	c.Assert(err, IsNil)
+	id, ok := values.getUint("id")
+	c.Assert(ok, IsTrue)
+	c.Assert(id, Equals, uint64(1))
+	name, ok := values.getString("name")
+	c.Assert(ok, IsTrue)
+	c.Assert(name, Equals, "a")
+	ranges, ok := values.getKeyRanges("ranges")
+	c.Assert(ok, IsTrue)
+	c.Assert(ranges, DeepEquals, []string{"a", "b"})
+
+	values, err = schema.parse(map[string]interface{}{"id": float64(2), "name": nil})
+	c.Assert(err, IsNil)
+	_, ok = values.getString("name")
+	c.Assert(ok, IsFalse)
+
+	for _, input := range []map[string]interface{}{
+		{},
+		{"id": "1"},
+		{"id": float64(-1)},
+		{"id": 1.5},
+		{"id": float64(1), "name": float64(1)},
+		{"id": float64(1), "ranges": []interface{}{"a"}},
+		{"id": float64(1), "ranges": []interface{}{"a", float64(1)}},
+		{"id": float64(1), "ranges": "a"},
+	} {
+		_, err = schema.parse(input)
+		c.Assert(errs.ErrSchedulerConfig.Equal(errors.Cause(err)), IsTrue, Commentf("input %v", input))
+	}
+}
+
+func (s *testConfigSchemaSuite) TestEvictLeaderHandler(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(opt)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+
+	sl, err := schedule.CreateScheduler(EvictLeaderType, schedule.NewOperatorController(ctx, tc, nil), core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(EvictLeaderType, []string{"1"}))
+	c.Assert(err, IsNil)
+	conf := sl.(*evictLeaderScheduler).conf
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		sl.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	c.Assert(do("POST", "/config", `{"name":"evict-leader-scheduler","store_id":2,"ranges":["a","b"]}`).Code, Equals, http.StatusOK)
+	c.Assert(conf.getRanges(2), DeepEquals, []string{"a", "b"})
+	c.Assert(tc.GetStore(2).AllowLeaderTransfer(), IsTrue)
+	// The wrong types are rejected instead of being taken as absent.
+	for _, body := range []string{`{"store_id":"2"}`, `{"ranges":["a","b"]}`, `{"store_id":2,"ranges":["a"]}`, `{"store_id":2,"ranges":"a"}`} {
+		c.Assert(do("POST", "/config", body).Code, Equals, http.StatusBadRequest, Commentf("body %s", body))
+	}
+	c.Assert(conf.getRanges(2), DeepEquals, []string{"a", "b"})
+	// Failing to pause the leader transfer is not caused by the input.
+	c.Assert(do("POST", "/config", `{"store_id":3}`).Code, Equals, http.StatusInternalServerError)
+	c.Assert(conf.getRanges(3), HasLen, 0)
+
+	w := do("GET", "/schema", "")
+	c.Assert(w.Code, Equals, http.StatusOK)
+	var fields []*configField
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &fields), IsNil)
+	c.Assert(fields, HasLen, 2)
+	c.Assert(fields[0].Name, Equals, "store_id")
+	c.Assert(fields[0].Type, Equals, configFieldUint)
+	c.Assert(fields[0].Required, IsTrue)
+
+	c.Assert(do("GET", "/list", "").Code, Equals, http.StatusOK)
+	c.Assert(do("DELETE", "/delete/2", "").Code, Equals, http.StatusOK)
+	c.Assert(conf.getRanges(2), HasLen, 0)
+}
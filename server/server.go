@@ -41,15 +41,20 @@ import (
 	"github.com/pingcap/sysutil"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/etcdutil"
+	"github.com/tikv/pd/pkg/eventbus"
 	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/idempotency"
 	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/pkg/ratelimit"
 	"github.com/tikv/pd/pkg/systimemon"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/confighistory"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/encryptionkm"
 	"github.com/tikv/pd/server/id"
+	"github.com/tikv/pd/server/job"
 	"github.com/tikv/pd/server/kv"
 	"github.com/tikv/pd/server/member"
 	syncer "github.com/tikv/pd/server/region_syncer"
@@ -64,12 +69,16 @@ import (
 	"go.etcd.io/etcd/pkg/types"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
 )
 
 const (
 	etcdTimeout           = time.Second * 3
 	serverMetricsInterval = time.Minute
 	leaderTickInterval    = 50 * time.Millisecond
+	// followerConfigReloadInterval is the interval for a follower to reload
+	// the config persisted by the leader.
+	followerConfigReloadInterval = 10 * time.Second
 	// pdRootPath for all pd servers.
 	pdRootPath      = "/pd"
 	pdAPIPrefix     = "/pd/"
@@ -98,6 +107,8 @@ type Server struct {
 	etcdCfg        *embed.Config
 	persistOptions *config.PersistOptions
 	handler        *Handler
+	// routingURLs are the client urls advertised for each routing domain.
+	routingURLs map[string][]string
 
 	ctx              context.Context
 	serverLoopCtx    context.Context
@@ -130,6 +141,26 @@ type Server struct {
 	cluster *cluster.RaftCluster
 	// For async region heartbeat.
 	hbStreams *hbstream.HeartbeatStreams
+	// for limiting the requests of each caller component.
+	callerLimiter *ratelimit.Limiter
+	// for limiting the TSO streams forwarded to the leader.
+	concurrentTSOProxyStreamings int32
+	// for serving the hot-region statistics of the leader on a follower.
+	hotStatsSyncer *hotStatsSyncer
+	// for verifying the regions synced from the leader on a follower.
+	regionSyncerVerifier *regionSyncerVerifier
+	// for authorizing the requests to the admin gRPC methods.
+	adminAuthorizer *adminAuthorizer
+	// for counting the callers of the deprecated RPCs.
+	deprecatedCalls *deprecatedRPCTracker
+	// for replaying the results of the requests with idempotency keys.
+	idempotencyCache *idempotency.Cache
+	// for reporting the health and readiness of the server.
+	healthServer *health.Server
+	// for tracking the health of the dependencies, such as etcd.
+	dependencies *dependencyHealth
+	// for reporting the resource usage of the process.
+	resourceUsage *resourceUsageCollector
 	// Zap logger
 	lg       *zap.Logger
 	logProps *log.ZapProperties
@@ -141,8 +172,23 @@ type Server struct {
 	// serviceSafePointLock is a lock for UpdateServiceGCSafePoint
 	serviceSafePointLock sync.Mutex
 
-	// Store as map[string]*grpc.ClientConn
-	clientConns sync.Map
+	// for the connections to the other members used by forwarding.
+	forwardConns *forwardConnPool
+	// storeMetas is the store metadata synced from etcd, which the followers
+	// serve the store metadata reads with.
+	storeMetas *storeMetaWatcher
+
+	// for rolling restart of PD members
+	rollingRestart *rollingRestartCoordinator
+
+	// jobManager runs the long-running tasks as jobs.
+	jobManager *job.Manager
+	// configHistory records the versions of the persisted config.
+	configHistory *confighistory.History
+
+	// eventBus passes the store, region, config and leadership events to the
+	// watch and stream APIs.
+	eventBus *eventbus.Bus
 }
 
 // HandlerBuilder builds a server HTTP handler.
@@ -159,6 +205,8 @@ type ServiceGroup struct {
 const (
 	// CorePath the core group, is at REST path `/pd/api/v1`.
 	CorePath = "/pd/api/v1"
+	// CoreV2Path the v2 API of the core group, is at REST path `/pd/api/v2`.
+	CoreV2Path = "/pd/api/v2"
 	// ExtensionsPath the named groups are REST at `/pd/apis/{GROUP_NAME}/{Version}`.
 	ExtensionsPath = "/pd/apis"
 )
@@ -202,6 +250,7 @@ func combineBuilderServerHTTPService(ctx context.Context, svr *Server, serviceBu
 			// and finally apiService is registered in userHandlers.
 			router.PathPrefix(pathPrefix).Handler(handler)
 			if info.IsCore {
+				router.PathPrefix(CoreV2Path).Handler(handler)
 				// Deprecated
 				router.Path("/pd/health").Handler(handler)
 				// Deprecated
@@ -217,6 +266,15 @@ func combineBuilderServerHTTPService(ctx context.Context, svr *Server, serviceBu
 	return userHandlers, nil
 }
 
+// parseRoutingURLs returns the client urls advertised for each routing domain.
+func parseRoutingURLs(cfg *config.Config) map[string][]string {
+	routingURLs := make(map[string][]string, len(cfg.AdvertiseClientUrlsByDomain))
+	for domain, urls := range cfg.AdvertiseClientUrlsByDomain {
+		routingURLs[domain] = strings.Split(urls, ",")
+	}
+	return routingURLs
+}
+
 // CreateServer creates the UNINITIALIZED pd server with given configuration.
 func CreateServer(ctx context.Context, cfg *config.Config, serviceBuilders ...HandlerBuilder) (*Server, error) {
 	log.Info("PD Config", zap.Reflect("config", cfg))
@@ -225,13 +283,26 @@ func CreateServer(ctx context.Context, cfg *config.Config, serviceBuilders ...Ha
 	s := &Server{
 		cfg:               cfg,
 		persistOptions:    config.NewPersistOptions(cfg),
+		routingURLs:       parseRoutingURLs(cfg),
 		member:            &member.Member{},
+		callerLimiter:     ratelimit.NewLimiter(),
+		adminAuthorizer:   newAdminAuthorizer(cfg.Security.AdminAuth),
+		deprecatedCalls:   newDeprecatedRPCTracker(),
+		healthServer:      health.NewServer(),
+		dependencies:      newDependencyHealth(),
+		resourceUsage:     newResourceUsageCollector(),
+		idempotencyCache:  idempotency.NewCache(idempotency.DefaultTTL, idempotency.DefaultCapacity),
+		eventBus:          eventbus.NewBus(),
 		ctx:               ctx,
 		startTimestamp:    time.Now().Unix(),
 		DiagnosticsServer: sysutil.NewDiagnosticsServer(cfg.Log.File.Filename),
 	}
 
 	s.handler = newHandler(s)
+	s.rollingRestart = newRollingRestartCoordinator(s)
+	s.forwardConns = newForwardConnPool(s.dialDelegateClient)
+	s.hotStatsSyncer = newHotStatsSyncer(s)
+	s.regionSyncerVerifier = newRegionSyncerVerifier(s)
 
 	// Adjust etcd config.
 	etcdCfg, err := s.cfg.GenEmbedEtcdConfig()
@@ -245,8 +316,18 @@ func CreateServer(ctx context.Context, cfg *config.Config, serviceBuilders ...Ha
 		}
 		etcdCfg.UserHandlers = userHandlers
 	}
+	// The services which are not a part of pdpb are intercepted like PDServer.
 	etcdCfg.ServiceRegister = func(gs *grpc.Server) {
-		pdpb.RegisterPDServer(gs, s)
+		gs.RegisterService(s.newPDServiceDesc(), s)
+		gs.RegisterService(s.sideServiceDesc(&regionScanService), s)
+		gs.RegisterService(s.sideServiceDesc(&regionWatchService), s)
+		gs.RegisterService(s.sideServiceDesc(&regionSnapshotService), s)
+		// All the members serve the health checks.
+		gs.RegisterService(s.sideServiceDesc(&healthService), s)
+		gs.RegisterService(s.sideServiceDesc(&idReservationService), s)
+		gs.RegisterService(s.sideServiceDesc(&storeRegionService), s)
+		gs.RegisterService(s.sideServiceDesc(&bulkRegionHeartbeatService), s)
+		gs.RegisterService(s.sideServiceDesc(&replicationStatusService), s)
 		diagnosticspb.RegisterDiagnosticsServer(gs, s)
 	}
 	s.etcdCfg = etcdCfg
@@ -357,12 +438,19 @@ func (s *Server) startServer(ctx context.Context) error {
 
 	s.rootPath = path.Join(pdRootPath, strconv.FormatUint(s.clusterID, 10))
 	s.member.MemberInfo(s.cfg, s.Name(), s.rootPath)
+	s.storeMetas = newStoreMetaWatcher(s.client, s.rootPath)
 	s.member.SetMemberDeployPath(s.member.ID())
 	s.member.SetMemberBinaryVersion(s.member.ID(), versioninfo.PDReleaseVersion)
 	s.member.SetMemberGitHash(s.member.ID(), versioninfo.PDGitHash)
-	s.idAllocator = id.NewAllocator(s.client, s.rootPath, s.member.MemberValue())
+	s.member.SetMemberRoutingURLs(s.member.ID(), s.routingURLs)
+	s.idAllocator = &healthReportingAllocator{
+		Allocator:    id.NewAllocator(s.client, s.rootPath, s.member.MemberValue()),
+		dependencies: s.dependencies,
+	}
 	s.tsoAllocatorManager = tso.NewAllocatorManager(
-		s.member, s.rootPath, s.cfg.TSOSaveInterval.Duration, s.cfg.TSOUpdatePhysicalInterval.Duration,
+		s.member, s.rootPath, s.cfg.TSOSaveInterval.Duration,
+		s.cfg.TSOMaxSaveInterval.Duration, s.cfg.TSOMaxSaveIntervalQPS,
+		s.cfg.TSOUpdatePhysicalInterval.Duration,
 		func() time.Duration { return s.persistOptions.GetMaxResetTSGap() },
 		s.GetTLSConfig())
 	// Set up the Global TSO Allocator here, it will be initialized once the PD campaigns leader successfully.
@@ -384,11 +472,20 @@ func (s *Server) startServer(ctx context.Context) error {
 		return err
 	}
 
+	hotRegionStorage, err := kv.NewLeveldbKV(filepath.Join(s.cfg.DataDir, "hot-region"))
+	if err != nil {
+		return err
+	}
+
 	s.storage = core.NewStorage(
 		kvBase,
 		core.WithRegionStorage(regionStorage),
+		core.WithHotRegionStorage(hotRegionStorage),
 		core.WithEncryptionKeyManager(encryptionKeyManager),
 	)
+	s.jobManager = job.NewManager(ctx, kvBase, s.idAllocator, job.DefaultTTL)
+	s.configHistory = confighistory.NewHistory(kvBase, s.Name())
+	s.persistOptions.SetPersistHook(s.recordConfigHistory)
 	s.basicCluster = core.NewBasicCluster()
 	s.cluster = cluster.NewRaftCluster(ctx, s.GetClusterRootPath(), s.clusterID, syncer.NewRegionSyncer(s), s.client, s.httpClient)
 	s.hbStreams = hbstream.NewHeartbeatStreams(ctx, s.clusterID, s.cluster)
@@ -434,6 +531,7 @@ func (s *Server) Close() {
 	log.Info("closing server")
 
 	s.stopServerLoop()
+	s.healthServer.Shutdown()
 
 	if s.client != nil {
 		if err := s.client.Close(); err != nil {
@@ -469,8 +567,14 @@ func (s *Server) IsClosed() bool {
 	return atomic.LoadInt64(&s.isServing) == 0
 }
 
+// IsLeader returns whether the server is running and is the leader.
+func (s *Server) IsLeader() bool {
+	return !s.IsClosed() && s.member.IsLeader()
+}
+
 // Run runs the pd server.
 func (s *Server) Run() error {
+	s.startTimestamp = time.Now().Unix()
 	go systimemon.StartMonitor(s.ctx, time.Now, func() {
 		log.Error("system time jumps backward", errs.ZapError(errs.ErrIncorrectSystemTime))
 		timeJumpBackCounter.Inc()
@@ -499,12 +603,15 @@ func (s *Server) LoopContext() context.Context {
 
 func (s *Server) startServerLoop(ctx context.Context) {
 	s.serverLoopCtx, s.serverLoopCancel = context.WithCancel(ctx)
-	s.serverLoopWg.Add(5)
+	s.serverLoopWg.Add(8)
 	go s.leaderLoop()
 	go s.etcdLeaderLoop()
 	go s.serverMetricsLoop()
 	go s.tsoAllocatorLoop()
 	go s.encryptionKeyManagerLoop()
+	go s.healthCheckLoop()
+	go s.dependencyCheckLoop()
+	go s.forwardConnPoolLoop()
 }
 
 func (s *Server) stopServerLoop() {
@@ -522,6 +629,7 @@ func (s *Server) serverMetricsLoop() {
 		select {
 		case <-time.After(serverMetricsInterval):
 			s.collectEtcdStateMetrics()
+			s.resourceUsage.sample()
 		case <-ctx.Done():
 			log.Info("server is closed, exit metrics loop")
 			return
@@ -688,6 +796,12 @@ func (s *Server) GetHTTPClient() *http.Client {
 	return s.httpClient
 }
 
+// GetFollowerHotStats returns the hot-region statistics replicated from the
+// leader, or nil if they are unavailable or too stale to serve.
+func (s *Server) GetFollowerHotStats() *HotStatsSnapshot {
+	return s.hotStatsSyncer.getSnapshot()
+}
+
 // GetLeader returns the leader of PD cluster(i.e the PD leader).
 func (s *Server) GetLeader() *pdpb.Member {
 	return s.member.GetLeader()
@@ -724,11 +838,21 @@ func (s *Server) GetHBStreams() *hbstream.HeartbeatStreams {
 	return s.hbStreams
 }
 
+// GetEventBus returns the event bus of server.
+func (s *Server) GetEventBus() *eventbus.Bus {
+	return s.eventBus
+}
+
 // GetAllocator returns the ID allocator of server.
 func (s *Server) GetAllocator() id.Allocator {
 	return s.idAllocator
 }
 
+// GetJobManager returns the manager of the long-running jobs.
+func (s *Server) GetJobManager() *job.Manager {
+	return s.jobManager
+}
+
 // GetTSOAllocatorManager returns the manager of TSO Allocator.
 func (s *Server) GetTSOAllocatorManager() *tso.AllocatorManager {
 	return s.tsoAllocatorManager
@@ -749,6 +873,22 @@ func (s *Server) StartTimestamp() int64 {
 	return s.startTimestamp
 }
 
+// GetConfigRevision returns the etcd revision at which the config was
+// persisted last time, or 0 if it has never been persisted. Together with the
+// etcd revision observed by a member, it tells whether the member has seen the
+// latest config.
+func (s *Server) GetConfigRevision() (int64, error) {
+	// The config is saved by the storage under the root path.
+	resp, err := etcdutil.EtcdKVGet(s.client, path.Join(s.rootPath, "config"))
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, nil
+	}
+	return resp.Kvs[0].ModRevision, nil
+}
+
 // GetConfig gets the config information.
 func (s *Server) GetConfig() *config.Config {
 	cfg := s.cfg.Clone()
@@ -808,6 +948,7 @@ func (s *Server) SetScheduleConfig(cfg config.ScheduleConfig) error {
 		return err
 	}
 	log.Info("schedule config is updated", zap.Reflect("new", cfg), zap.Reflect("old", old))
+	s.eventBus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "schedule"})
 	return nil
 }
 
@@ -835,7 +976,7 @@ func (s *Server) SetReplicationConfig(cfg config.ReplicationConfig) error {
 		} else {
 			// NOTE: can be removed after placement rules feature is enabled by default.
 			for _, s := range raftCluster.GetStores() {
-				if !s.IsTombstone() && core.IsTiFlashStore(s.GetMeta()) {
+				if !s.IsTombstone() && s.IsTiFlash() {
 					return errors.New("cannot disable placement rules with TiFlash nodes")
 				}
 			}
@@ -896,6 +1037,7 @@ func (s *Server) SetReplicationConfig(cfg config.ReplicationConfig) error {
 		return err
 	}
 	log.Info("replication config is updated", zap.Reflect("new", cfg), zap.Reflect("old", old))
+	s.eventBus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "replication"})
 	return nil
 }
 
@@ -931,7 +1073,12 @@ func (s *Server) SetPDServerConfig(cfg config.PDServerConfig) error {
 			errs.ZapError(err))
 		return err
 	}
+	s.callerLimiter.SetQuotas(cfg.CallerRateLimits)
+	if rc := s.GetRaftCluster(); rc != nil {
+		rc.SetRegionHeartbeatFairQueueConcurrency(cfg.RegionHeartbeatFairQueueConcurrency)
+	}
 	log.Info("PD server config is updated", zap.Reflect("new", cfg), zap.Reflect("old", old))
+	s.eventBus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "pd-server"})
 	return nil
 }
 
@@ -948,6 +1095,7 @@ func (s *Server) SetLabelPropertyConfig(cfg config.LabelPropertyConfig) error {
 		return err
 	}
 	log.Info("label property config is updated", zap.Reflect("new", cfg), zap.Reflect("old", old))
+	s.eventBus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "label-property"})
 	return nil
 }
 
@@ -967,6 +1115,7 @@ func (s *Server) SetLabelProperty(typ, labelKey, labelValue string) error {
 	}
 
 	log.Info("label property config is updated", zap.Reflect("config", s.persistOptions.GetLabelPropertyConfig()))
+	s.eventBus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "label-property"})
 	return nil
 }
 
@@ -986,6 +1135,7 @@ func (s *Server) DeleteLabelProperty(typ, labelKey, labelValue string) error {
 	}
 
 	log.Info("label property config is deleted", zap.Reflect("config", s.persistOptions.GetLabelPropertyConfig()))
+	s.eventBus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "label-property"})
 	return nil
 }
 
@@ -1012,6 +1162,7 @@ func (s *Server) SetClusterVersion(v string) error {
 		return err
 	}
 	log.Info("cluster version is updated", zap.String("new-version", v))
+	s.eventBus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "cluster-version"})
 	return nil
 }
 
@@ -1093,6 +1244,34 @@ func (s *Server) SetLogLevel(level string) error {
 	return nil
 }
 
+// maxModuleDebugTTL is the longest time the debug logging of a module can be
+// turned on for.
+const maxModuleDebugTTL = 24 * time.Hour
+
+// EnableModuleDebug turns on the debug logging of the module for the ttl
+// without changing the global log level.
+func (s *Server) EnableModuleDebug(module string, ttl time.Duration) error {
+	if !logutil.IsDebugModule(module) {
+		return errors.Errorf("module %s does not support debug logging", module)
+	}
+	if ttl <= 0 || ttl > maxModuleDebugTTL {
+		return errors.Errorf("ttl %v is out of range (0, %v]", ttl, maxModuleDebugTTL)
+	}
+	logutil.EnableModuleDebug(module, ttl)
+	log.Warn("module debug logging enabled", zap.String("module", module), zap.Duration("ttl", ttl))
+	return nil
+}
+
+// DisableModuleDebug turns off the debug logging of the module.
+func (s *Server) DisableModuleDebug(module string) error {
+	if !logutil.IsDebugModule(module) {
+		return errors.Errorf("module %s does not support debug logging", module)
+	}
+	logutil.DisableModuleDebug(module)
+	log.Warn("module debug logging disabled", zap.String("module", module))
+	return nil
+}
+
 func isLevelLegal(level string) bool {
 	switch strings.ToLower(level) {
 	case "fatal", "error", "warn", "warning", "debug", "info":
@@ -1140,10 +1319,11 @@ func (s *Server) SetReplicationModeConfig(cfg config.ReplicationModeConfig) erro
 			if revertErr != nil {
 				log.Error("failed to revert replication mode persistent config", errs.ZapError(revertErr))
 			}
+			return err
 		}
-		return err
 	}
 
+	s.eventBus.Publish(eventbus.TopicConfig, &eventbus.ConfigEvent{Section: "replication-mode"})
 	return nil
 }
 
@@ -1173,10 +1353,21 @@ func (s *Server) leaderLoop() {
 			if s.persistOptions.IsUseRegionStorage() {
 				syncer.StartSyncWithLeader(leader.GetClientUrls()[0])
 			}
+			s.hotStatsSyncer.startSyncWithLeader(s.serverLoopCtx, leader.GetClientUrls()[0])
+			s.regionSyncerVerifier.startWithLeader(s.serverLoopCtx, leader.GetClientUrls()[0])
+			s.storeMetas.startWithLeader(s.serverLoopCtx)
+			reloadCtx, cancelReload := context.WithCancel(s.serverLoopCtx)
+			reloadDone := make(chan struct{})
+			go s.followerConfigReloadLoop(reloadCtx, reloadDone)
 			log.Info("start to watch pd leader", zap.Stringer("pd-leader", leader))
 			// WatchLeader will keep looping and never return unless the PD leader has changed.
 			s.member.WatchLeader(s.serverLoopCtx, leader, rev)
+			cancelReload()
+			<-reloadDone
 			syncer.StopSyncWithLeader()
+			s.hotStatsSyncer.stopSyncWithLeader()
+			s.regionSyncerVerifier.stopWithLeader()
+			s.storeMetas.stopWithLeader()
 			log.Info("pd leader has changed, try to re-campaign a pd leader")
 		}
 
@@ -1237,6 +1428,7 @@ func (s *Server) campaignLeader() {
 		log.Error("failed to reload configuration", errs.ZapError(err))
 		return
 	}
+	s.initConfigHistory()
 
 	if err := s.encryptionKeyManager.SetLeadership(s.member.GetLeadership()); err != nil {
 		log.Error("failed to initialize encryption", errs.ZapError(err))
@@ -1257,7 +1449,22 @@ func (s *Server) campaignLeader() {
 		log.Error("failed to sync id from etcd", errs.ZapError(err))
 		return
 	}
+	if err := s.jobManager.Load(); err != nil {
+		log.Error("failed to load jobs", errs.ZapError(err))
+		return
+	}
+	if err := s.initKeyLayoutVersion(); err != nil {
+		log.Error("failed to record the key layout version", errs.ZapError(err))
+		return
+	}
 	s.member.EnableLeader()
+	// The resumed plan checks the leadership, so load it after the leader is enabled.
+	if err := s.rollingRestart.load(); err != nil {
+		log.Error("failed to load rolling restart", errs.ZapError(err))
+		return
+	}
+	s.eventBus.Publish(eventbus.TopicLeader, &eventbus.LeaderEvent{IsLeader: true})
+	defer s.eventBus.Publish(eventbus.TopicLeader, &eventbus.LeaderEvent{IsLeader: false})
 
 	CheckPDVersion(s.persistOptions)
 	log.Info("PD cluster leader is ready to serve", zap.String("pd-leader-name", s.Name()))
@@ -1303,8 +1510,7 @@ func (s *Server) etcdLeaderLoop() {
 }
 
 func (s *Server) reloadConfigFromKV() error {
-	err := s.persistOptions.Reload(s.storage)
-	if err != nil {
+	if err := s.reloadOptionsFromKV(); err != nil {
 		return err
 	}
 	if s.persistOptions.IsUseRegionStorage() {
@@ -1317,6 +1523,38 @@ func (s *Server) reloadConfigFromKV() error {
 	return nil
 }
 
+// reloadOptionsFromKV reloads the persisted options and applies the ones which
+// also take effect on a follower.
+func (s *Server) reloadOptionsFromKV() error {
+	if err := s.persistOptions.Reload(s.storage); err != nil {
+		return err
+	}
+	s.callerLimiter.SetQuotas(s.persistOptions.GetPDServerConfig().CallerRateLimits)
+	return nil
+}
+
+// followerConfigReloadLoop reloads the options periodically while following
+// the leader, so that the options read by a follower, such as the limit of
+// the TSO proxy streams and the interval to verify the synced regions, are
+// kept up to date with the changes made on the leader.
+func (s *Server) followerConfigReloadLoop(ctx context.Context, done chan<- struct{}) {
+	defer logutil.LogPanic()
+	defer close(done)
+
+	ticker := time.NewTicker(followerConfigReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reloadOptionsFromKV(); err != nil {
+				log.Warn("failed to reload the config on follower", errs.ZapError(err))
+			}
+		}
+	}
+}
+
 // ReplicateFileToAllMembers is used to synchronize state among all members.
 // Each member will write `data` to a local file named `name`.
 func (s *Server) ReplicateFileToAllMembers(ctx context.Context, name string, data []byte) error {
@@ -1351,17 +1589,35 @@ func (s *Server) PersistFile(name string, data []byte) error {
 	return ioutil.WriteFile(filepath.Join(s.GetConfig().DataDir, name), data, 0644)
 }
 
-// SaveTTLConfig save ttl config
-func (s *Server) SaveTTLConfig(data map[string]interface{}, ttl time.Duration) error {
+// SaveTTLConfig save ttl config. The origin tells who saves it.
+func (s *Server) SaveTTLConfig(data map[string]interface{}, ttl time.Duration, origin string) error {
 	for k := range data {
 		if !config.IsSupportedTTLConfig(k) {
 			return fmt.Errorf("unsupported ttl config %s", k)
 		}
 	}
 	for k, v := range data {
-		if err := s.persistOptions.SetTTLData(s.ctx, s.client, k, fmt.Sprint(v), ttl); err != nil {
+		if err := s.persistOptions.SetTTLData(s.ctx, s.client, k, fmt.Sprint(v), ttl, origin); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// GetTTLConfigs returns the active ttl configs.
+func (s *Server) GetTTLConfigs() ([]*config.TTLConfig, error) {
+	return s.persistOptions.GetTTLConfigs(s.ctx, s.client)
+}
+
+// DeleteTTLConfig cancels the ttl config before it expires.
+func (s *Server) DeleteTTLConfig(key string) error {
+	ok, err := s.persistOptions.DeleteTTLData(s.ctx, s.client, key)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errs.ErrTTLConfigNotFound.FastGenByArgs(key)
+	}
+	log.Info("ttl config is canceled", zap.String("key", key))
+	return nil
+}
@@ -15,18 +15,33 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"reflect"
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/etcdutil"
+	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server/config"
 	"go.etcd.io/etcd/embed"
 	"go.etcd.io/etcd/pkg/types"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 func TestServer(t *testing.T) {
@@ -95,6 +110,77 @@ func (s *testLeaderServerSuite) TearDownSuite(c *C) {
 	}
 }
 
+func (s *testLeaderServerSuite) TestNotLeaderRetryHint(c *C) {
+	svrs := make([]*Server, 0, len(s.svrs))
+	for _, svr := range s.svrs {
+		svrs = append(svrs, svr)
+	}
+	leader := mustWaitLeader(c, svrs)
+	for _, svr := range svrs {
+		if svr == leader {
+			continue
+		}
+		testutil.WaitUntil(c, func(c *C) bool {
+			return svr.GetMember().GetLeaderID() == leader.GetMember().ID()
+		})
+		err := svr.validateRequest(&pdpb.RequestHeader{ClusterId: svr.clusterID})
+		c.Assert(err, NotNil)
+		hint, ok := grpcutil.GetRetryHint(err)
+		c.Assert(ok, IsTrue)
+		c.Assert(hint.Retryable, IsTrue)
+		c.Assert(hint.Leader, Equals, leader.GetMemberInfo().GetClientUrls()[0])
+	}
+	err := leader.validateRequest(&pdpb.RequestHeader{ClusterId: leader.clusterID + 1})
+	c.Assert(err, NotNil)
+	_, ok := grpcutil.GetRetryHint(err)
+	c.Assert(ok, IsFalse)
+}
+
+func (s *testLeaderServerSuite) TestInterceptor(c *C) {
+	svrs := make([]*Server, 0, len(s.svrs))
+	for _, svr := range s.svrs {
+		svrs = append(svrs, svr)
+	}
+	leader := mustWaitLeader(c, svrs)
+	var follower *Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+	testutil.WaitUntil(c, func(c *C) bool {
+		return follower.GetMember().GetLeaderID() == leader.GetMember().ID()
+	})
+	newClient := func(svr *Server) pdpb.PDClient {
+		conn, err := grpcutil.GetClientConn(s.ctx, svr.GetAddr(), nil)
+		c.Assert(err, IsNil)
+		return pdpb.NewPDClient(conn)
+	}
+	leaderClient, followerClient := newClient(leader), newClient(follower)
+	header := &pdpb.RequestHeader{ClusterId: leader.clusterID}
+
+	// The followers do not serve the requests unless they are forwarded.
+	_, err := followerClient.GetStore(s.ctx, &pdpb.GetStoreRequest{Header: header, StoreId: 1})
+	c.Assert(err, ErrorMatches, ".*not leader.*")
+	var trailer metadata.MD
+	resp, err := followerClient.GetStore(grpcutil.BuildForwardContext(s.ctx, leader.GetAddr()), &pdpb.GetStoreRequest{Header: header, StoreId: 1}, grpc.Trailer(&trailer))
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetHeader().GetError().GetType(), Equals, pdpb.ErrorType_NOT_BOOTSTRAPPED)
+	// The retry hint of the header error is passed on by the follower.
+	hint, ok := grpcutil.GetRetryHintFromTrailer(trailer)
+	c.Assert(ok, IsTrue)
+	c.Assert(hint.Retryable, IsTrue)
+	c.Assert(hint.Backoff, Equals, time.Second)
+	// The cluster ID is validated.
+	_, err = leaderClient.GetStore(s.ctx, &pdpb.GetStoreRequest{Header: &pdpb.RequestHeader{ClusterId: leader.clusterID + 1}, StoreId: 1})
+	c.Assert(status.Code(err), Equals, codes.FailedPrecondition)
+	// All the members serve GetMembers.
+	members, err := followerClient.GetMembers(s.ctx, &pdpb.GetMembersRequest{Header: header})
+	c.Assert(err, IsNil)
+	c.Assert(members.GetLeader().GetMemberId(), Equals, leader.GetMember().ID())
+}
+
 var _ = Suite(&testServerSuite{})
 
 type testServerSuite struct{}
@@ -226,3 +312,208 @@ func (s *testServerHandlerSuite) TestRegisterServerHandler(c *C) {
 	bodyString := string(bodyBytes)
 	c.Assert(bodyString, Equals, "Hello World\n")
 }
+
+var _ = Suite(&testAdminAuthSuite{})
+
+type testAdminAuthSuite struct{}
+
+func (s *testAdminAuthSuite) TestAuthorize(c *C) {
+	// The authorization is disabled.
+	var a *adminAuthorizer
+	c.Assert(a.authorize(context.Background(), "PutStore"), IsNil)
+
+	a = newAdminAuthorizer(config.AdminAuthConfig{
+		Enable:    true,
+		Methods:   config.DefaultAdminMethods,
+		AllowedCN: []string{"tikv"},
+		Tokens:    []string{"secret"},
+	})
+	withCN := func(cn string) context.Context {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		return peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+		})
+	}
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(grpcutil.AdminTokenMetadataKey, token))
+	}
+	c.Assert(a.authorize(withCN("tikv"), "PutStore"), IsNil)
+	c.Assert(a.authorize(withToken("secret"), "ScatterRegion"), IsNil)
+	for _, ctx := range []context.Context{context.Background(), withCN("tidb"), withToken("guess")} {
+		err := a.authorize(ctx, "PutClusterConfig")
+		c.Assert(status.Code(err), Equals, codes.PermissionDenied)
+	}
+	// The methods not configured are not authorized.
+	c.Assert(a.authorize(context.Background(), "UpdateGCSafePoint"), IsNil)
+}
+
+var _ = Suite(&testGRPCInterceptorSuite{})
+
+type testGRPCInterceptorSuite struct{}
+
+func (s *testGRPCInterceptorSuite) TestPDServiceDesc(c *C) {
+	desc := (&Server{}).newPDServiceDesc()
+	names := make(map[string]struct{})
+	for _, m := range desc.Methods {
+		names[m.MethodName] = struct{}{}
+	}
+	for _, stream := range desc.Streams {
+		names[stream.StreamName] = struct{}{}
+	}
+	// All the methods of PDServer are served.
+	t := reflect.TypeOf((*pdpb.PDServer)(nil)).Elem()
+	c.Assert(names, HasLen, t.NumMethod())
+	for i := 0; i < t.NumMethod(); i++ {
+		_, ok := names[t.Method(i).Name]
+		c.Assert(ok, IsTrue, Commentf("method %s", t.Method(i).Name))
+	}
+	c.Assert(getGRPCChecks("PutStore"), Equals, defaultGRPCChecks)
+	c.Assert(getGRPCChecks("GetStore"), Equals, defaultGRPCChecks|checkFollowerRead)
+	c.Assert(getGRPCChecks("GetMembers"), Equals, grpcChecks(0))
+}
+
+func (s *testGRPCInterceptorSuite) TestAuthorizeServices(c *C) {
+	svr := &Server{adminAuthorizer: newAdminAuthorizer(config.AdminAuthConfig{
+		Enable:  true,
+		Methods: []string{"PutStore", "AllocIDRange"},
+		Tokens:  []string{"secret"},
+	})}
+	callUnary := func(desc *grpc.ServiceDesc, method string, req interface{}) error {
+		for _, m := range desc.Methods {
+			if m.MethodName == method {
+				dec := func(in interface{}) error {
+					reflect.ValueOf(in).Elem().Set(reflect.ValueOf(req).Elem())
+					return nil
+				}
+				_, err := m.Handler(svr, context.Background(), dec, nil)
+				return err
+			}
+		}
+		c.Fatalf("method %s not found", method)
+		return nil
+	}
+	// Both PDServer and the side services are authorized before any other
+	// checks.
+	err := callUnary(svr.newPDServiceDesc(), "PutStore", &pdpb.PutStoreRequest{})
+	c.Assert(status.Code(err), Equals, codes.PermissionDenied)
+	err = callUnary(svr.sideServiceDesc(&idReservationService), grpcutil.AllocIDRangeMethodName, &pdpb.AllocIDRequest{})
+	c.Assert(status.Code(err), Equals, codes.PermissionDenied)
+}
+
+var _ = Suite(&testForwardConnPoolSuite{})
+
+type testForwardConnPoolSuite struct{}
+
+func (s *testForwardConnPoolSuite) TestForwardConnPool(c *C) {
+	dialed := 0
+	p := newForwardConnPool(func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+		dialed++
+		return grpcutil.GetClientConn(ctx, addr, nil)
+	})
+	defer p.close()
+	p.maxStreams = 2
+	var unhealthy bool
+	p.check = func(context.Context, *grpc.ClientConn) error {
+		if unhealthy {
+			return status.Error(codes.Unavailable, "unreachable")
+		}
+		return nil
+	}
+	ctx := context.Background()
+
+	// The connection is shared by the requests and streams.
+	cc, err := p.get(ctx, "http://127.0.0.1:1")
+	c.Assert(err, IsNil)
+	cc1, release1, err := p.acquireStream(ctx, "http://127.0.0.1:1")
+	c.Assert(err, IsNil)
+	c.Assert(cc1, Equals, cc)
+	_, release2, err := p.acquireStream(ctx, "http://127.0.0.1:1")
+	c.Assert(err, IsNil)
+	_, _, err = p.acquireStream(ctx, "http://127.0.0.1:1")
+	c.Assert(status.Code(err), Equals, codes.ResourceExhausted)
+	c.Assert(dialed, Equals, 1)
+	// Releasing a stream twice should be OK.
+	release2()
+	release2()
+	_, release2, err = p.acquireStream(ctx, "http://127.0.0.1:1")
+	c.Assert(err, IsNil)
+
+	// The connection with streams is not idle.
+	p.idleTimeout = 0
+	p.checkConns(ctx)
+	c.Assert(p.conns, HasLen, 1)
+	release1()
+	release2()
+	p.checkConns(ctx)
+	c.Assert(p.conns, HasLen, 0)
+
+	// The unhealthy connection is closed even if there are streams.
+	p.idleTimeout = time.Hour
+	_, release, err := p.acquireStream(ctx, "http://127.0.0.1:2")
+	c.Assert(err, IsNil)
+	p.checkConns(ctx)
+	c.Assert(p.conns, HasLen, 1)
+	unhealthy = true
+	p.checkConns(ctx)
+	c.Assert(p.conns, HasLen, 0)
+	release()
+	_, err = p.get(ctx, "http://127.0.0.1:2")
+	c.Assert(err, IsNil)
+	c.Assert(dialed, Equals, 3)
+}
+
+func (s *testForwardConnPoolSuite) TestForwardBreaker(c *C) {
+	p := newForwardConnPool(nil)
+	p.breakerThreshold = 2
+	p.breakerCooldown = time.Hour
+	addr := "http://127.0.0.1:1"
+	unreachable := status.Error(codes.Unavailable, "unreachable")
+
+	// The errors returned by the member and the slow requests do not open
+	// the breaker.
+	for _, err := range []error{
+		status.Error(codes.Internal, "internal"),
+		status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+		context.DeadlineExceeded,
+		errors.New("unknown"),
+	} {
+		c.Assert(p.allowUnary(addr), IsNil)
+		p.reportUnary(addr, err)
+	}
+	c.Assert(p.breakers, HasLen, 0)
+
+	// A success resets the failures.
+	p.reportUnary(addr, unreachable)
+	p.reportUnary(addr, nil)
+	p.reportUnary(addr, unreachable)
+	c.Assert(p.allowUnary(addr), IsNil)
+	c.Assert(p.allowStream(addr), IsNil)
+
+	// The breaker opens after the consecutive failures.
+	p.reportUnary(addr, unreachable)
+	err := p.allowUnary(addr)
+	c.Assert(status.Code(err), Equals, codes.Unavailable)
+	hint, ok := grpcutil.GetRetryHint(err)
+	c.Assert(ok, IsTrue)
+	c.Assert(hint.Retryable, IsTrue)
+	c.Assert(hint.Backoff > 0 && hint.Backoff <= time.Hour, IsTrue)
+	_, _, err = p.acquireStream(context.Background(), addr)
+	c.Assert(status.Code(err), Equals, codes.Unavailable)
+	c.Assert(p.allowUnary("http://127.0.0.1:2"), IsNil)
+
+	// Only one request probes the member after the cooldown, and the breaker
+	// opens again if the probe fails.
+	p.breakerCooldown = 0
+	c.Assert(p.allowUnary(addr), IsNil)
+	c.Assert(p.allowUnary(addr), NotNil)
+	c.Assert(p.allowStream(addr), IsNil)
+	p.reportUnary(addr, errs.ErrGRPCDial.Wrap(errors.New("dial failed")).GenWithStackByCause())
+	c.Assert(p.breakers[addr].state, Equals, forwardBreakerOpen)
+
+	// The breaker closes if the probe reaches the member.
+	c.Assert(p.allowUnary(addr), IsNil)
+	p.reportUnary(addr, nil)
+	c.Assert(p.breakers, HasLen, 0)
+	c.Assert(p.allowUnary(addr), IsNil)
+	c.Assert(p.allowUnary(addr), IsNil)
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// sideService describes a gRPC service owned by PD, which is not a part of
+// pdpb. The handlers of its methods are built by sideServiceDesc with the
+// checks of the interceptors, so that a service only declares its methods and
+// messages.
+type sideService struct {
+	name    string
+	checks  grpcChecks
+	methods []sideMethod
+	streams []sideStream
+}
+
+// sideMethod describes a unary method of a side service.
+type sideMethod struct {
+	name       string
+	newRequest func() interface{}
+	// newReply is used to forward the request to another member.
+	newReply func() interface{}
+	handle   func(s *Server, ctx context.Context, req interface{}) (interface{}, error)
+}
+
+// sideStream describes a streaming method of a side service. A server stream
+// receives its only request before handle is called, while a bidirectional
+// stream, whose newRequest is nil, receives the requests by itself.
+type sideStream struct {
+	name          string
+	clientStreams bool
+	newRequest    func() interface{}
+	handle        func(s *Server, req interface{}, stream grpc.ServerStream) error
+}
+
+// sideServiceDesc returns the description of the side service to register,
+// whose handlers do the checks before calling the methods like the ones of
+// PDServer. The streams validate every request they receive, and they are
+// not forwarded.
+func (s *Server) sideServiceDesc(svc *sideService) *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: svc.name,
+		// The handlers call the server by themselves.
+		HandlerType: (*interface{})(nil),
+		Methods:     make([]grpc.MethodDesc, 0, len(svc.methods)),
+		Streams:     make([]grpc.StreamDesc, 0, len(svc.streams)),
+	}
+	for i := range svc.methods {
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: svc.methods[i].name,
+			Handler:    s.sideMethodHandler(svc, &svc.methods[i]),
+		})
+	}
+	for i := range svc.streams {
+		desc.Streams = append(desc.Streams, grpc.StreamDesc{
+			StreamName:    svc.streams[i].name,
+			Handler:       s.sideStreamHandler(svc, &svc.streams[i]),
+			ServerStreams: true,
+			ClientStreams: svc.streams[i].clientStreams,
+		})
+	}
+	return desc
+}
+
+func (s *Server) sideMethodHandler(svc *sideService, m *sideMethod) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	fullMethod := "/" + svc.name + "/" + m.name
+	inner := s.unaryInterceptor(svc.checks, m.newReply)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return m.handle(s, ctx, req)
+	}
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := m.newRequest()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		return chainUnaryInterceptor(interceptor, inner)(ctx, in, info, handler)
+	}
+}
+
+func (s *Server) sideStreamHandler(svc *sideService, st *sideStream) grpc.StreamHandler {
+	return func(_ interface{}, stream grpc.ServerStream) error {
+		if err := s.interceptStream(stream, st.name, svc.checks); err != nil {
+			return err
+		}
+		if svc.checks&checkRole != 0 {
+			stream = &validatedServerStream{ServerStream: stream, s: s}
+		}
+		var req interface{}
+		if st.newRequest != nil {
+			req = st.newRequest()
+			if err := stream.RecvMsg(req); err != nil {
+				return err
+			}
+		}
+		return st.handle(s, req, stream)
+	}
+}
@@ -43,6 +43,7 @@ type RegionInfo struct {
 	*core.RegionInfo
 	startMissVoterPeerTS int64
 	startDownPeerTS      int64
+	startPendingPeerTS   int64
 }
 
 // RegionStatistics is used to record the status of regions.
@@ -91,6 +92,16 @@ func (r *RegionStatistics) GetRegionStatsByType(typ RegionStatisticType) []*core
 	return res
 }
 
+// GetPendingPeerStartTime returns since when the region has pending peers. It
+// returns the zero time if the region has no pending peer.
+func (r *RegionStatistics) GetPendingPeerStartTime(regionID uint64) time.Time {
+	info, ok := r.stats[PendingPeer][regionID]
+	if !ok || info.startPendingPeerTS == 0 {
+		return time.Time{}
+	}
+	return time.Unix(info.startPendingPeerTS, 0)
+}
+
 // GetOfflineRegionStatsByType gets the status of the offline region by types.
 func (r *RegionStatistics) GetOfflineRegionStatsByType(typ RegionStatisticType) []*core.RegionInfo {
 	res := make([]*core.RegionInfo, 0, len(r.stats[typ]))
@@ -182,6 +193,10 @@ func (r *RegionStatistics) Observe(region *core.RegionInfo, stores []*core.Store
 				} else {
 					info.startDownPeerTS = time.Now().Unix()
 				}
+			} else if typ == PendingPeer {
+				if info.startPendingPeerTS == 0 {
+					info.startPendingPeerTS = time.Now().Unix()
+				}
 			} else if typ == MissPeer && len(region.GetVoters()) < desiredVoters {
 				if info.startMissVoterPeerTS != 0 {
 					regionMissVoterPeerDuration.Observe(float64(time.Now().Unix() - info.startMissVoterPeerTS))
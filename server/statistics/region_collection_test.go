@@ -107,6 +107,9 @@ func (t *testRegionStatisticsSuite) TestRegionStatistics(c *C) {
 	c.Assert(len(regionStats.offlineStats[LearnerPeer]), Equals, 1)
 	c.Assert(len(regionStats.offlineStats[EmptyRegion]), Equals, 0)
 	c.Assert(len(regionStats.offlineStats[OfflinePeer]), Equals, 1)
+	pendingStartTime := regionStats.GetPendingPeerStartTime(region1.GetID())
+	c.Assert(pendingStartTime.IsZero(), IsFalse)
+	c.Assert(regionStats.GetPendingPeerStartTime(region2.GetID()).IsZero(), IsTrue)
 
 	region2 = region2.Clone(core.WithDownPeers(downPeers[0:1]))
 	regionStats.Observe(region2, stores[0:2])
@@ -135,6 +138,7 @@ func (t *testRegionStatisticsSuite) TestRegionStatistics(c *C) {
 	c.Assert(len(regionStats.offlineStats[PendingPeer]), Equals, 0)
 	c.Assert(len(regionStats.offlineStats[LearnerPeer]), Equals, 0)
 	c.Assert(len(regionStats.offlineStats[OfflinePeer]), Equals, 0)
+	c.Assert(regionStats.GetPendingPeerStartTime(region1.GetID()), Equals, pendingStartTime)
 
 	store3 = stores[3].Clone(core.UpStore())
 	stores[3] = store3
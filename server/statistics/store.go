@@ -15,6 +15,7 @@ package statistics
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/kvproto/pkg/pdpb"
@@ -24,43 +25,68 @@ import (
 	"go.uber.org/zap"
 )
 
-// StoresStats is a cache hold hot regions.
+// StoresStats is a cache hold hot regions. The stores are kept in a
+// copy-on-write map, so the readers, e.g. the schedulers, do not take any
+// lock. The map is only copied when a store is added or removed.
 type StoresStats struct {
-	sync.RWMutex
-	rollingStoresStats map[uint64]*RollingStoreStats
-	totalLoads         []float64
+	// mu serializes the writers of the map.
+	mu     sync.Mutex
+	stores atomic.Value // map[uint64]*RollingStoreStats
 }
 
 // NewStoresStats creates a new hot spot cache.
 func NewStoresStats() *StoresStats {
-	return &StoresStats{
-		rollingStoresStats: make(map[uint64]*RollingStoreStats),
-		totalLoads:         make([]float64, StoreStatCount),
+	s := &StoresStats{}
+	s.stores.Store(make(map[uint64]*RollingStoreStats))
+	return s
+}
+
+func (s *StoresStats) load() map[uint64]*RollingStoreStats {
+	return s.stores.Load().(map[uint64]*RollingStoreStats)
+}
+
+// update replaces the map with a copy changed by f. It should be called with
+// the writer lock.
+func (s *StoresStats) update(f func(stores map[uint64]*RollingStoreStats)) {
+	old := s.load()
+	stores := make(map[uint64]*RollingStoreStats, len(old)+1)
+	for storeID, stats := range old {
+		stores[storeID] = stats
 	}
+	f(stores)
+	s.stores.Store(stores)
 }
 
 // RemoveRollingStoreStats removes RollingStoreStats with a given store ID.
 func (s *StoresStats) RemoveRollingStoreStats(storeID uint64) {
-	s.Lock()
-	defer s.Unlock()
-	delete(s.rollingStoresStats, storeID)
+	if s.GetRollingStoreStats(storeID) == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(func(stores map[uint64]*RollingStoreStats) {
+		delete(stores, storeID)
+	})
 }
 
 // GetRollingStoreStats gets RollingStoreStats with a given store ID.
 func (s *StoresStats) GetRollingStoreStats(storeID uint64) *RollingStoreStats {
-	s.RLock()
-	defer s.RUnlock()
-	return s.rollingStoresStats[storeID]
+	return s.load()[storeID]
 }
 
 // GetOrCreateRollingStoreStats gets or creates RollingStoreStats with a given store ID.
 func (s *StoresStats) GetOrCreateRollingStoreStats(storeID uint64) *RollingStoreStats {
-	s.Lock()
-	defer s.Unlock()
-	ret, ok := s.rollingStoresStats[storeID]
+	if ret := s.GetRollingStoreStats(storeID); ret != nil {
+		return ret
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret, ok := s.load()[storeID]
 	if !ok {
 		ret = newRollingStoreStats()
-		s.rollingStoresStats[storeID] = ret
+		s.update(func(stores map[uint64]*RollingStoreStats) {
+			stores[storeID] = ret
+		})
 	}
 	return ret
 }
@@ -71,60 +97,54 @@ func (s *StoresStats) Observe(storeID uint64, stats *pdpb.StoreStats) {
 	store.Observe(stats)
 }
 
+// ObserveStore records the status reported by the store heartbeat. The
+// statistics of the store are removed if the store is unhealthy.
+func (s *StoresStats) ObserveStore(store *core.StoreInfo) {
+	if storeIsUnhealthy(store) {
+		s.RemoveRollingStoreStats(store.GetID())
+		return
+	}
+	s.Observe(store.GetID(), store.GetStoreStats())
+}
+
 // Set sets the store statistics (for test).
 func (s *StoresStats) Set(storeID uint64, stats *pdpb.StoreStats) {
 	store := s.GetOrCreateRollingStoreStats(storeID)
 	store.Set(stats)
 }
 
-// UpdateTotalLoad updates the total loads of all stores.
-func (s *StoresStats) UpdateTotalLoad(stores []*core.StoreInfo) {
-	s.Lock()
-	defer s.Unlock()
-	for i := range s.totalLoads {
-		s.totalLoads[i] = 0
-	}
-	for _, store := range stores {
-		if !store.IsUp() {
-			continue
-		}
-		stats, ok := s.rollingStoresStats[store.GetID()]
-		if !ok {
-			continue
-		}
-		for i := range s.totalLoads {
-			s.totalLoads[i] += stats.GetLoad(StoreStatKind(i))
-		}
-	}
-}
-
 // GetStoresLoads returns all stores loads.
 func (s *StoresStats) GetStoresLoads() map[uint64][]float64 {
-	s.RLock()
-	defer s.RUnlock()
-	res := make(map[uint64][]float64, len(s.rollingStoresStats))
-	for storeID, stats := range s.rollingStoresStats {
-		for i := StoreStatKind(0); i < StoreStatCount; i++ {
-			res[storeID] = append(res[storeID], stats.GetLoad(i))
-		}
+	stores := s.load()
+	res := make(map[uint64][]float64, len(stores))
+	for storeID, stats := range stores {
+		res[storeID] = stats.GetLoads()
 	}
 	return res
 }
 
-func (s *StoresStats) storeIsUnhealthy(cluster core.StoreSetInformer, storeID uint64) bool {
-	store := cluster.GetStore(storeID)
-	return store.IsTombstone() || store.IsUnhealthy() || store.IsPhysicallyDestroyed()
+func storeIsUnhealthy(store *core.StoreInfo) bool {
+	return store == nil || store.IsTombstone() || store.IsUnhealthy() || store.IsPhysicallyDestroyed()
 }
 
 // FilterUnhealthyStore filter unhealthy store
 func (s *StoresStats) FilterUnhealthyStore(cluster core.StoreSetInformer) {
-	s.Lock()
-	defer s.Unlock()
-	for storeID := range s.rollingStoresStats {
-		if s.storeIsUnhealthy(cluster, storeID) {
-			delete(s.rollingStoresStats, storeID)
+	var unhealthy []uint64
+	for storeID := range s.load() {
+		if storeIsUnhealthy(cluster.GetStore(storeID)) {
+			unhealthy = append(unhealthy, storeID)
 		}
 	}
+	if len(unhealthy) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.update(func(stores map[uint64]*RollingStoreStats) {
+		for _, storeID := range unhealthy {
+			delete(stores, storeID)
+		}
+	})
 }
 
 // UpdateStoreHeartbeatMetrics is used to update store heartbeat interval metrics
@@ -137,6 +157,9 @@ type RollingStoreStats struct {
 	sync.RWMutex
 	timeMedians map[StoreStatKind]*movingaverage.TimeMedian
 	movingAvgs  map[StoreStatKind]movingaverage.MovingAvg
+	// loads is the snapshot of the loads refreshed by each update, which is
+	// read without the lock.
+	loads atomic.Value // []float64
 }
 
 const (
@@ -163,10 +186,12 @@ func newRollingStoreStats() *RollingStoreStats {
 	movingAvgs[StoreDiskReadRate] = movingaverage.NewMedianFilter(storeStatsRollingWindows)
 	movingAvgs[StoreDiskWriteRate] = movingaverage.NewMedianFilter(storeStatsRollingWindows)
 
-	return &RollingStoreStats{
+	r := &RollingStoreStats{
 		timeMedians: timeMedians,
 		movingAvgs:  movingAvgs,
 	}
+	r.refreshLoads()
+	return r
 }
 
 func collect(records []*pdpb.RecordPair) float64 {
@@ -193,6 +218,7 @@ func (r *RollingStoreStats) Observe(stats *pdpb.StoreStats) {
 	r.movingAvgs[StoreCPUUsage].Add(collect(stats.GetCpuUsages()))
 	r.movingAvgs[StoreDiskReadRate].Add(collect(stats.GetReadIoRates()))
 	r.movingAvgs[StoreDiskWriteRate].Add(collect(stats.GetWriteIoRates()))
+	r.refreshLoads()
 }
 
 // Set sets the statistics (for test).
@@ -211,12 +237,33 @@ func (r *RollingStoreStats) Set(stats *pdpb.StoreStats) {
 	r.movingAvgs[StoreCPUUsage].Set(collect(stats.GetCpuUsages()))
 	r.movingAvgs[StoreDiskReadRate].Set(collect(stats.GetReadIoRates()))
 	r.movingAvgs[StoreDiskWriteRate].Set(collect(stats.GetWriteIoRates()))
+	r.refreshLoads()
+}
+
+// refreshLoads refreshes the snapshot of the loads. It should be called with
+// the lock, or before the stats are shared.
+func (r *RollingStoreStats) refreshLoads() {
+	loads := make([]float64, StoreStatCount)
+	for i := range loads {
+		loads[i] = r.getLoadLocked(StoreStatKind(i))
+	}
+	r.loads.Store(loads)
+}
+
+// GetLoads returns the snapshot of the store's loads, indexed by StoreStatKind.
+// The returned slice should not be modified.
+func (r *RollingStoreStats) GetLoads() []float64 {
+	return r.loads.Load().([]float64)
 }
 
 // GetLoad returns store's load.
 func (r *RollingStoreStats) GetLoad(k StoreStatKind) float64 {
 	r.RLock()
 	defer r.RUnlock()
+	return r.getLoadLocked(k)
+}
+
+func (r *RollingStoreStats) getLoadLocked(k StoreStatKind) float64 {
 	switch k {
 	case StoreReadBytes:
 		return r.timeMedians[StoreReadBytes].Get()
@@ -34,6 +34,7 @@ type storeStatistics struct {
 	Unhealthy       int
 	Down            int
 	Offline         int
+	Maintenance     int
 	Tombstone       int
 	LowSpace        int
 	StorageSize     uint64
@@ -67,7 +68,11 @@ func (s *storeStatistics) Observe(store *core.StoreInfo, stats *StoresStats) {
 	// Store state.
 	switch store.GetState() {
 	case metapb.StoreState_Up:
-		if store.DownTime() >= s.opt.GetMaxStoreDownTime() {
+		// A store in maintenance is expected to be unavailable, so it is
+		// counted separately instead of raising the down store alarms.
+		if store.IsInMaintenance() {
+			s.Maintenance++
+		} else if store.DownTime() >= s.opt.GetMaxStoreDownTime() {
 			s.Down++
 		} else if store.IsUnhealthy() {
 			s.Unhealthy++
@@ -132,6 +137,7 @@ func (s *storeStatistics) Collect() {
 	metrics["store_down_count"] = float64(s.Down)
 	metrics["store_unhealth_count"] = float64(s.Unhealthy)
 	metrics["store_offline_count"] = float64(s.Offline)
+	metrics["store_maintenance_count"] = float64(s.Maintenance)
 	metrics["store_tombstone_count"] = float64(s.Tombstone)
 	metrics["store_low_space_count"] = float64(s.LowSpace)
 	metrics["region_count"] = float64(s.RegionCount)
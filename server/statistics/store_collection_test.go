@@ -55,15 +55,19 @@ func (t *testStoreStatisticsSuite) TestStoreStatistics(c *C) {
 	stores[3] = store3
 	store4 := stores[4].Clone(core.SetLastHeartbeatTS(stores[4].GetLastHeartbeatTS().Add(-time.Hour)))
 	stores[4] = store4
+	store5 := stores[5].Clone(core.SetLastHeartbeatTS(stores[5].GetLastHeartbeatTS().Add(-time.Hour)),
+		core.SetStoreMaintenance(&core.StoreMaintenance{StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)}))
+	stores[5] = store5
 	storeStats := NewStoreStatisticsMap(opt)
 	for _, store := range stores {
 		storeStats.Observe(store, storesStats)
 	}
 	stats := storeStats.stats
 
-	c.Assert(stats.Up, Equals, 6)
+	c.Assert(stats.Up, Equals, 5)
 	c.Assert(stats.Down, Equals, 1)
 	c.Assert(stats.Offline, Equals, 1)
+	c.Assert(stats.Maintenance, Equals, 1)
 	c.Assert(stats.RegionCount, Equals, 0)
 	c.Assert(stats.Unhealthy, Equals, 0)
 	c.Assert(stats.Disconnect, Equals, 0)
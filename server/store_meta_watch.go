@@ -0,0 +1,290 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/etcdutil"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/logutil"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const storeMetaRetryInterval = time.Second
+
+// storeMetaWatcher keeps a copy of the store metadata in sync with etcd, so
+// that the followers can serve GetStore and GetAllStores. Its revision is the
+// latest mod revision of the store metadata it has applied, and it is zero if
+// the copy is out of sync, such as when the member is partitioned from the
+// etcd leader. It only watches while the member follows a leader, since the
+// leader serves the stores of the cluster.
+type storeMetaWatcher struct {
+	client *clientv3.Client
+	prefix string
+
+	mu       sync.RWMutex
+	stores   map[uint64]*metapb.Store
+	revision int64
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func newStoreMetaWatcher(client *clientv3.Client, rootPath string) *storeMetaWatcher {
+	return &storeMetaWatcher{
+		client: client,
+		prefix: path.Join(rootPath, "raft", "s") + "/",
+		stores: make(map[uint64]*metapb.Store),
+	}
+}
+
+// startWithLeader starts to watch the store metadata.
+func (w *storeMetaWatcher) startWithLeader(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.wg.Add(1)
+	go func() {
+		defer logutil.LogPanic()
+		defer w.wg.Done()
+		w.run(ctx)
+	}()
+}
+
+// stopWithLeader stops the watch, and the copy is out of sync afterwards.
+func (w *storeMetaWatcher) stopWithLeader() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+	w.cancel = nil
+}
+
+// run loads and then watches the store metadata until the context is done.
+// It loads the metadata again if the watch fails, such as when the revision
+// is compacted or the etcd leader is lost.
+func (w *storeMetaWatcher) run(ctx context.Context) {
+	for {
+		err := w.sync(ctx)
+		w.setOutOfSync()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(storeMetaRetryInterval):
+		}
+		log.Warn("store metadata is out of sync, load it again", errs.ZapError(err))
+	}
+}
+
+func (w *storeMetaWatcher) sync(ctx context.Context) error {
+	resp, err := etcdutil.EtcdKVGet(w.client, w.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	stores := make(map[uint64]*metapb.Store, len(resp.Kvs))
+	var revision int64
+	for _, kv := range resp.Kvs {
+		store := &metapb.Store{}
+		if err := proto.Unmarshal(kv.Value, store); err != nil {
+			return errs.ErrProtoUnmarshal.Wrap(err).GenWithStackByCause()
+		}
+		stores[store.GetId()] = store
+		if kv.ModRevision > revision {
+			revision = kv.ModRevision
+		}
+	}
+	w.mu.Lock()
+	w.stores, w.revision = stores, revision
+	w.mu.Unlock()
+
+	// Requiring the leader cancels the watch if the member is partitioned,
+	// so that it does not serve the stale metadata.
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	rch := w.client.Watch(watchCtx, w.prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+	for wresp := range rch {
+		if err := wresp.Err(); err != nil {
+			return errs.ErrEtcdWatcherCancel.Wrap(err).GenWithStackByCause()
+		}
+		if err := w.apply(wresp.Events); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (w *storeMetaWatcher) apply(events []*clientv3.Event) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, ev := range events {
+		switch ev.Type {
+		case mvccpb.PUT:
+			store := &metapb.Store{}
+			if err := proto.Unmarshal(ev.Kv.Value, store); err != nil {
+				return errs.ErrProtoUnmarshal.Wrap(err).GenWithStackByCause()
+			}
+			w.stores[store.GetId()] = store
+		case mvccpb.DELETE:
+			id, err := strconv.ParseUint(path.Base(string(ev.Kv.Key)), 10, 64)
+			if err != nil {
+				continue
+			}
+			delete(w.stores, id)
+		}
+		if ev.Kv.ModRevision > w.revision {
+			w.revision = ev.Kv.ModRevision
+		}
+	}
+	return nil
+}
+
+func (w *storeMetaWatcher) setOutOfSync() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.revision = 0
+}
+
+// getStores returns the store metadata and its revision.
+func (w *storeMetaWatcher) getStores() (map[uint64]*metapb.Store, int64) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	stores := make(map[uint64]*metapb.Store, len(w.stores))
+	for id, store := range w.stores {
+		stores[id] = store
+	}
+	return stores, w.revision
+}
+
+// loadRevision returns the latest mod revision of the store metadata in etcd.
+// The leader returns it with the stores it serves, so that the client does not
+// accept the followers older than them later.
+func (w *storeMetaWatcher) loadRevision() (int64, error) {
+	resp, err := etcdutil.EtcdKVGet(w.client, w.prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly(),
+		clientv3.WithSort(clientv3.SortByModRevision, clientv3.SortDescend), clientv3.WithLimit(1))
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, nil
+	}
+	return resp.Kvs[0].ModRevision, nil
+}
+
+// isStoreFollowerRead returns true if the follower serves the request with the
+// synced store metadata, which is allowed by the client.
+func (s *Server) isStoreFollowerRead(ctx context.Context) bool {
+	_, ok := grpcutil.GetStoreMinRevision(ctx)
+	return ok && !s.IsClosed() && !s.member.IsLeader()
+}
+
+// serveStoreFollowerRead serves GetStore and GetAllStores with the synced store
+// metadata. It fails if the metadata is older than the client has seen, and the
+// client falls back to the leader then. The stores are served as they are
+// persisted in etcd, so the responses carry no store stats, and the last
+// heartbeat time is the one last persisted by the leader, which lags behind the
+// heartbeats by up to the store persist interval.
+func (s *Server) serveStoreFollowerRead(ctx context.Context, req interface{}) (interface{}, error) {
+	minRevision, _ := grpcutil.GetStoreMinRevision(ctx)
+	stores, revision := s.storeMetas.getStores()
+	if revision == 0 || revision < minRevision {
+		storeFollowerReadCounter.WithLabelValues("stale").Inc()
+		return nil, status.Errorf(codes.Unavailable, "store metadata is at revision %d, older than %d", revision, minRevision)
+	}
+	if _, ok := grpcutil.GetStoreKeyspace(ctx); ok {
+		storeFollowerReadCounter.WithLabelValues("unsupported").Inc()
+		return nil, status.Errorf(codes.FailedPrecondition, "the stores of a keyspace are only served by the leader")
+	}
+	storeFollowerReadCounter.WithLabelValues("served").Inc()
+	if err := grpc.SetHeader(ctx, metadata.Pairs(grpcutil.StoreRevisionHeaderKey, strconv.FormatInt(revision, 10))); err != nil {
+		return nil, err
+	}
+	switch request := req.(type) {
+	case *pdpb.GetStoreRequest:
+		// A bootstrapped cluster has one store at least.
+		if len(stores) == 0 {
+			return &pdpb.GetStoreResponse{Header: s.notBootstrappedHeader()}, nil
+		}
+		store, ok := stores[request.GetStoreId()]
+		if !ok {
+			return nil, status.Errorf(codes.Unknown, "invalid store ID %d, not found", request.GetStoreId())
+		}
+		return &pdpb.GetStoreResponse{Header: s.header(), Store: store}, nil
+	case *pdpb.GetAllStoresRequest:
+		if len(stores) == 0 {
+			return &pdpb.GetAllStoresResponse{Header: s.notBootstrappedHeader()}, nil
+		}
+		labels := grpcutil.GetStoreLabels(ctx)
+		res := make([]*metapb.Store, 0, len(stores))
+		for _, store := range stores {
+			if request.GetExcludeTombstoneStores() && store.GetState() == metapb.StoreState_Tombstone {
+				continue
+			}
+			if hasStoreLabels(store, labels) {
+				res = append(res, store)
+			}
+		}
+		return &pdpb.GetAllStoresResponse{Header: s.header(), Stores: res}, nil
+	}
+	return nil, status.Errorf(codes.Unimplemented, "follower read is not supported")
+}
+
+// setStoreRevisionHeader returns the revision of the store metadata to the
+// client which allows the follower read, so that it can read the followers
+// synced to the revision afterwards.
+func (s *Server) setStoreRevisionHeader(ctx context.Context) {
+	if _, ok := grpcutil.GetStoreMinRevision(ctx); !ok {
+		return
+	}
+	revision, err := s.storeMetas.loadRevision()
+	if err != nil {
+		log.Warn("failed to load the revision of the store metadata", errs.ZapError(err))
+		return
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(grpcutil.StoreRevisionHeaderKey, strconv.FormatInt(revision, 10))); err != nil {
+		log.Warn("failed to set the revision of the store metadata", errs.ZapError(err))
+	}
+}
+
+// hasStoreLabels is the same as the label filter of the leader, but checks the
+// store metadata.
+func hasStoreLabels(store *metapb.Store, labels []*metapb.StoreLabel) bool {
+	for _, label := range labels {
+		var value string
+		for _, l := range store.GetLabels() {
+			if strings.EqualFold(l.GetKey(), label.GetKey()) {
+				value = l.GetValue()
+				break
+			}
+		}
+		if value != label.GetValue() {
+			return false
+		}
+	}
+	return true
+}
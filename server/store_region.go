@@ -0,0 +1,72 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultStoreRegionsPageSize = 1024
+	maxStoreRegionsPageSize     = 10240
+)
+
+// storeRegionService lists the regions of a store page by page. The store is
+// carried by the metadata of the request.
+var storeRegionService = sideService{
+	name:   grpcutil.StoreRegionServiceName,
+	checks: defaultGRPCChecks,
+	methods: []sideMethod{
+		{
+			name:       grpcutil.GetRegionsByStoreMethodName,
+			newRequest: func() interface{} { return new(pdpb.ScanRegionsRequest) },
+			newReply:   func() interface{} { return new(pdpb.ScanRegionsResponse) },
+			handle: func(s *Server, ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetRegionsByStore(ctx, req.(*pdpb.ScanRegionsRequest))
+			},
+		},
+	},
+}
+
+// GetRegionsByStore returns a page of the regions which have peers on the
+// store, in key order, starting from the region that contains the start key
+// of the request. The next page starts from the end key of the last region,
+// and the listing is done when a page is not full.
+func (s *Server) GetRegionsByStore(ctx context.Context, request *pdpb.ScanRegionsRequest) (*pdpb.ScanRegionsResponse, error) {
+	storeID, ok := grpcutil.GetStoreID(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "the store id is required")
+	}
+	rc := s.GetRaftCluster()
+	if rc == nil {
+		return &pdpb.ScanRegionsResponse{Header: s.notBootstrappedHeader()}, nil
+	}
+	if rc.GetStore(storeID) == nil {
+		return nil, status.Errorf(codes.NotFound, "store %d not found", storeID)
+	}
+	pageSize := int(request.GetLimit())
+	if pageSize <= 0 {
+		pageSize = defaultStoreRegionsPageSize
+	}
+	if pageSize > maxStoreRegionsPageSize {
+		pageSize = maxStoreRegionsPageSize
+	}
+	regions := rc.ScanStoreRegions(storeID, request.GetStartKey(), pageSize)
+	return newScanRegionsResponse(s.header(), regions), nil
+}
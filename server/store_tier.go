@@ -0,0 +1,154 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/schedule/placement"
+	"go.uber.org/zap"
+)
+
+const (
+	// StoreTierRuleGroup is the placement rule group of the key ranges moved
+	// to the store tiers.
+	StoreTierRuleGroup = "pd-tier"
+	// storeTierRuleGroupIndex makes the group override the default group for
+	// the moved key ranges, while the groups with larger indexes, like the
+	// ones of TiFlash, still apply.
+	storeTierRuleGroupIndex = 1
+)
+
+// StoreTier is the stores in a tier, which share the value of the store tier
+// label.
+type StoreTier struct {
+	Name        string   `json:"name"`
+	StoreIDs    []uint64 `json:"store_ids"`
+	RegionCount int      `json:"region_count"`
+	RegionSize  int64    `json:"region_size"`
+}
+
+// GetStoreTiers returns the tiers of the stores which are not tombstone,
+// ordered by the name. The stores without the label are in the tier with the
+// empty name.
+func (h *Handler) GetStoreTiers() ([]*StoreTier, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	label := h.s.GetPersistOptions().GetStoreTierLabel()
+	if label == "" {
+		return nil, errs.ErrStoreTierLabelNotSet.FastGenByArgs()
+	}
+	tiers := make(map[string]*StoreTier)
+	for _, store := range rc.GetStores() {
+		if store.IsTombstone() {
+			continue
+		}
+		name := store.GetLabelValue(label)
+		tier, ok := tiers[name]
+		if !ok {
+			tier = &StoreTier{Name: name}
+			tiers[name] = tier
+		}
+		tier.StoreIDs = append(tier.StoreIDs, store.GetID())
+		tier.RegionCount += store.GetRegionCount()
+		tier.RegionSize += store.GetRegionSize()
+	}
+	result := make([]*StoreTier, 0, len(tiers))
+	for _, tier := range tiers {
+		sort.Slice(tier.StoreIDs, func(i, j int) bool { return tier.StoreIDs[i] < tier.StoreIDs[j] })
+		result = append(result, tier)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// MoveRangeToTier moves the key range [startKey, endKey) to the tier. It is
+// compiled into a placement rule in StoreTierRuleGroup, which places all the
+// voters of the range in the tier, and the rule checker moves the peers then.
+// Moving the same range again replaces the rule, and a range overlapping
+// another moved range is rejected. An empty endKey means the end of the key
+// space.
+func (h *Handler) MoveRangeToTier(startKey, endKey []byte, tier string) (*placement.Rule, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	opts := h.s.GetPersistOptions()
+	label := opts.GetStoreTierLabel()
+	if label == "" {
+		return nil, errs.ErrStoreTierLabelNotSet.FastGenByArgs()
+	}
+	tiers, err := h.GetStoreTiers()
+	if err != nil {
+		return nil, err
+	}
+	if i := sort.Search(len(tiers), func(i int) bool { return tiers[i].Name >= tier }); i == len(tiers) || tiers[i].Name != tier {
+		return nil, errs.ErrStoreTierNotFound.FastGenByArgs(tier)
+	}
+
+	startKeyHex, endKeyHex := hex.EncodeToString(startKey), hex.EncodeToString(endKey)
+	id := startKeyHex + "-" + endKeyHex
+	manager := rc.GetRuleManager()
+	for _, r := range manager.GetRulesByGroup(StoreTierRuleGroup) {
+		if r.ID != id && rangesOverlap(r.StartKey, r.EndKey, startKey, endKey) {
+			return nil, errs.ErrStoreTierRangeOverlap.FastGenByArgs(r.StartKeyHex, r.EndKeyHex, ruleTier(r, label))
+		}
+	}
+	if manager.GetRuleGroup(StoreTierRuleGroup) == nil {
+		if err := manager.SetRuleGroup(&placement.RuleGroup{ID: StoreTierRuleGroup, Index: storeTierRuleGroupIndex, Override: true}); err != nil {
+			return nil, err
+		}
+	}
+	rule := &placement.Rule{
+		GroupID:          StoreTierRuleGroup,
+		ID:               id,
+		StartKeyHex:      startKeyHex,
+		EndKeyHex:        endKeyHex,
+		Role:             placement.Voter,
+		Count:            opts.GetMaxReplicas(),
+		LabelConstraints: []placement.LabelConstraint{placement.TierConstraint(label, tier)},
+		LocationLabels:   opts.GetLocationLabels(),
+		IsolationLevel:   opts.GetIsolationLevel(),
+	}
+	if err := manager.SetRule(rule); err != nil {
+		return nil, err
+	}
+	log.Info("key range is moved to the store tier",
+		zap.String("start-key", startKeyHex),
+		zap.String("end-key", endKeyHex),
+		zap.String("tier", tier))
+	return rule, nil
+}
+
+// ruleTier returns the tier of a rule created by MoveRangeToTier.
+func ruleTier(r *placement.Rule, label string) string {
+	for _, constraint := range r.LabelConstraints {
+		if constraint.Key == label {
+			return strings.Join(constraint.Values, ",")
+		}
+	}
+	return ""
+}
+
+func rangesOverlap(start1, end1, start2, end2 []byte) bool {
+	return (len(end2) == 0 || bytes.Compare(start1, end2) < 0) &&
+		(len(end1) == 0 || bytes.Compare(start2, end1) < 0)
+}
@@ -0,0 +1,312 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topohistory
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/kv"
+	"go.uber.org/zap"
+)
+
+const (
+	historyPath = "region_topology"
+	loadLimit   = 1000
+	// chunkSize is the number of the regions in a chunk of a snapshot, so
+	// that a query only decodes the chunk of the region.
+	chunkSize = 4096
+	// minSnapshotInterval bounds the interval to persist the snapshots, in
+	// case it is set to a tiny value by mistake.
+	minSnapshotInterval = time.Minute
+)
+
+// Peer is a peer of a region in a snapshot.
+type Peer struct {
+	ID        uint64 `json:"id"`
+	StoreID   uint64 `json:"store_id"`
+	IsLearner bool   `json:"is_learner,omitempty"`
+}
+
+// Region is the leader and the peers of a region in a snapshot.
+type Region struct {
+	ID            uint64  `json:"id"`
+	LeaderStoreID uint64  `json:"leader_store_id"`
+	Peers         []*Peer `json:"peers"`
+}
+
+// Record is a region in the latest snapshot taken at or before the queried
+// time.
+type Record struct {
+	SnapshotTime time.Time `json:"snapshot_time"`
+	*Region
+}
+
+// Cluster provides the regions to persist.
+type Cluster interface {
+	GetRegions() []*core.RegionInfo
+}
+
+// Options are the options of the region topology history.
+type Options interface {
+	GetRegionTopologySnapshotInterval() time.Duration
+	GetRegionTopologyRetention() time.Duration
+}
+
+// Store persists the snapshots of the region topology periodically and keeps
+// them for the retention, so that where the replicas and the leader of a
+// region were in the past can be queried.
+//
+// A snapshot is saved as the gzipped chunks of the regions ordered by ID,
+// and an index which lists the last region ID of every chunk. The index is
+// saved after the chunks, so a snapshot is visible only when it is complete.
+type Store struct {
+	ctx     context.Context
+	storage kv.Base
+	cluster Cluster
+	opt     Options
+}
+
+// NewStore creates a Store and starts persisting the snapshots in the
+// background until the context is done.
+func NewStore(ctx context.Context, storage kv.Base, cluster Cluster, opt Options) *Store {
+	s := &Store{
+		ctx:     ctx,
+		storage: storage,
+		cluster: cluster,
+		opt:     opt,
+	}
+	go s.run()
+	return s
+}
+
+func (s *Store) run() {
+	for {
+		interval := s.opt.GetRegionTopologySnapshotInterval()
+		if interval < minSnapshotInterval {
+			interval = minSnapshotInterval
+		}
+		select {
+		case <-time.After(interval):
+			if retention := s.opt.GetRegionTopologyRetention(); retention > 0 {
+				now := time.Now()
+				if err := s.save(now); err != nil {
+					log.Warn("failed to save region topology snapshot", errs.ZapError(err))
+				}
+				s.gc(now.Add(-retention))
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// save persists the current regions as the snapshot at the time.
+func (s *Store) save(now time.Time) error {
+	regions := s.cluster.GetRegions()
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetID() < regions[j].GetID() })
+	index := make([]uint64, 0, (len(regions)+chunkSize-1)/chunkSize)
+	for len(regions) > 0 {
+		n := chunkSize
+		if n > len(regions) {
+			n = len(regions)
+		}
+		chunk := make([]*Region, 0, n)
+		for _, region := range regions[:n] {
+			chunk = append(chunk, newRegion(region))
+		}
+		regions = regions[n:]
+		value, err := encodeChunk(chunk)
+		if err != nil {
+			return err
+		}
+		last := chunk[len(chunk)-1].ID
+		if err := s.storage.Save(chunkKey(now, last), value); err != nil {
+			return err
+		}
+		index = append(index, last)
+	}
+	value, err := json.Marshal(index)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	return s.storage.Save(indexKey(now), string(value))
+}
+
+func newRegion(region *core.RegionInfo) *Region {
+	r := &Region{
+		ID:            region.GetID(),
+		LeaderStoreID: region.GetLeader().GetStoreId(),
+		Peers:         make([]*Peer, 0, len(region.GetPeers())),
+	}
+	for _, peer := range region.GetPeers() {
+		r.Peers = append(r.Peers, &Peer{
+			ID:        peer.GetId(),
+			StoreID:   peer.GetStoreId(),
+			IsLearner: peer.GetRole() == metapb.PeerRole_Learner,
+		})
+	}
+	return r
+}
+
+// gc removes the snapshots persisted before the time.
+func (s *Store) gc(before time.Time) {
+	for {
+		keys, values, err := s.storage.LoadRange(indexKey(time.Time{}), indexKey(before), loadLimit)
+		if err != nil {
+			log.Warn("failed to load region topology snapshots", errs.ZapError(err))
+			return
+		}
+		for i, key := range keys {
+			t, index, err := parseIndex(key, values[i])
+			if err != nil {
+				log.Warn("failed to parse region topology snapshot", zap.String("key", key), errs.ZapError(err))
+				return
+			}
+			// Remove the index first, so the snapshot is not visible when
+			// its chunks are being removed.
+			if err := s.storage.Remove(key); err != nil {
+				log.Warn("failed to remove region topology snapshot", zap.String("key", key), errs.ZapError(err))
+				return
+			}
+			for _, last := range index {
+				if err := s.storage.Remove(chunkKey(t, last)); err != nil {
+					log.Warn("failed to remove region topology snapshot", zap.String("key", chunkKey(t, last)), errs.ZapError(err))
+					return
+				}
+			}
+		}
+		if len(keys) < loadLimit {
+			return
+		}
+	}
+}
+
+// Query returns the region in the latest snapshot taken at or before the
+// time. It returns nil if there is no such snapshot or the region is not in
+// it.
+func (s *Store) Query(regionID uint64, t time.Time) (*Record, error) {
+	snapshot, index, err := s.findSnapshot(t)
+	if err != nil || len(index) == 0 {
+		return nil, err
+	}
+	i := sort.Search(len(index), func(i int) bool { return index[i] >= regionID })
+	if i == len(index) {
+		return nil, nil
+	}
+	value, err := s.storage.Load(chunkKey(snapshot, index[i]))
+	// The chunk may be removed by the gc after the index is loaded.
+	if err != nil || value == "" {
+		return nil, err
+	}
+	chunk, err := decodeChunk(value)
+	if err != nil {
+		return nil, err
+	}
+	j := sort.Search(len(chunk), func(j int) bool { return chunk[j].ID >= regionID })
+	if j == len(chunk) || chunk[j].ID != regionID {
+		return nil, nil
+	}
+	return &Record{SnapshotTime: snapshot, Region: chunk[j]}, nil
+}
+
+// findSnapshot returns the time and the index of the latest snapshot taken at
+// or before the time.
+func (s *Store) findSnapshot(t time.Time) (time.Time, []uint64, error) {
+	var lastKey, lastValue string
+	nextKey, endKey := indexKey(time.Time{}), indexKey(t.Add(time.Nanosecond))
+	for {
+		keys, values, err := s.storage.LoadRange(nextKey, endKey, loadLimit)
+		if err != nil {
+			return time.Time{}, nil, err
+		}
+		if len(keys) > 0 {
+			lastKey, lastValue = keys[len(keys)-1], values[len(values)-1]
+		}
+		if len(keys) < loadLimit {
+			break
+		}
+		nextKey = lastKey + "\x00"
+	}
+	if lastKey == "" {
+		return time.Time{}, nil, nil
+	}
+	return parseIndex(lastKey, lastValue)
+}
+
+func encodeChunk(chunk []*Region) (string, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(w).Encode(chunk); err != nil {
+		return "", errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	if err := w.Close(); err != nil {
+		return "", errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	return buf.String(), nil
+}
+
+func decodeChunk(value string) ([]*Region, error) {
+	r, err := gzip.NewReader(strings.NewReader(value))
+	if err != nil {
+		return nil, errs.ErrIORead.Wrap(err).GenWithStackByCause()
+	}
+	var chunk []*Region
+	if err := json.NewDecoder(r).Decode(&chunk); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return chunk, nil
+}
+
+func parseIndex(key, value string) (time.Time, []uint64, error) {
+	nanos, err := strconv.ParseInt(path.Base(key), 10, 64)
+	if err != nil {
+		return time.Time{}, nil, errs.ErrStrconvParseInt.Wrap(err).GenWithStackByCause()
+	}
+	var index []uint64
+	if err := json.Unmarshal([]byte(value), &index); err != nil {
+		return time.Time{}, nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return time.Unix(0, nanos), index, nil
+}
+
+// indexKey returns the key of the index of the snapshot at the time, which
+// is ordered by time.
+func indexKey(t time.Time) string {
+	return path.Join(historyPath, "index", timeString(t))
+}
+
+func chunkKey(t time.Time, lastRegionID uint64) string {
+	return path.Join(historyPath, "chunk", timeString(t), fmt.Sprintf("%020d", lastRegionID))
+}
+
+func timeString(t time.Time) string {
+	nanos := t.UnixNano()
+	if t.IsZero() || nanos < 0 {
+		nanos = 0
+	}
+	return fmt.Sprintf("%020d", nanos)
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topohistory
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/kv"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testHistorySuite{})
+
+type testHistorySuite struct{}
+
+type mockCluster struct {
+	regions []*core.RegionInfo
+}
+
+func (m *mockCluster) GetRegions() []*core.RegionInfo {
+	return append(m.regions[:0:0], m.regions...)
+}
+
+func newTestRegion(id uint64, leaderStore uint64, stores ...uint64) *core.RegionInfo {
+	meta := &metapb.Region{Id: id}
+	var leader *metapb.Peer
+	for _, storeID := range stores {
+		peer := &metapb.Peer{Id: id*10 + storeID, StoreId: storeID}
+		if storeID == leaderStore {
+			leader = peer
+		}
+		meta.Peers = append(meta.Peers, peer)
+	}
+	return core.NewRegionInfo(meta, leader)
+}
+
+func (s *testHistorySuite) TestSaveAndQuery(c *C) {
+	cluster := &mockCluster{}
+	// More regions than a chunk, in the reverse order of the IDs.
+	for id := uint64(chunkSize + 10); id > 0; id-- {
+		cluster.regions = append(cluster.regions, newTestRegion(id, 1, 1, 2, 3))
+	}
+	store := &Store{storage: kv.NewMemoryKV(), cluster: cluster}
+	t1 := time.Unix(1000, 0)
+	c.Assert(store.save(t1), IsNil)
+	cluster.regions[0] = newTestRegion(chunkSize+10, 4, 2, 3, 4)
+	t2 := t1.Add(time.Hour)
+	c.Assert(store.save(t2), IsNil)
+
+	r, err := store.Query(1, t1.Add(-time.Second))
+	c.Assert(err, IsNil)
+	c.Assert(r, IsNil)
+	r, err = store.Query(1, t1)
+	c.Assert(err, IsNil)
+	c.Assert(r.SnapshotTime.Equal(t1), IsTrue)
+	c.Assert(r.LeaderStoreID, Equals, uint64(1))
+	c.Assert(r.Peers, HasLen, 3)
+	c.Assert(r.Peers[0].ID, Equals, uint64(11))
+
+	// The region in the second chunk is moved between the snapshots.
+	id := uint64(chunkSize + 10)
+	r, err = store.Query(id, t2.Add(-time.Second))
+	c.Assert(err, IsNil)
+	c.Assert(r.SnapshotTime.Equal(t1), IsTrue)
+	c.Assert(r.LeaderStoreID, Equals, uint64(1))
+	r, err = store.Query(id, t2.Add(time.Second))
+	c.Assert(err, IsNil)
+	c.Assert(r.SnapshotTime.Equal(t2), IsTrue)
+	c.Assert(r.LeaderStoreID, Equals, uint64(4))
+	c.Assert(r.Peers[2].StoreID, Equals, uint64(4))
+
+	r, err = store.Query(id+1, t2)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsNil)
+
+	store.gc(t1.Add(time.Second))
+	r, err = store.Query(1, t1)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsNil)
+	keys, _, err := store.storage.LoadRange(chunkKey(t1, 0), chunkKey(t2, 0), loadLimit)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 0)
+	r, err = store.Query(1, t2)
+	c.Assert(err, IsNil)
+	c.Assert(r.SnapshotTime.Equal(t2), IsTrue)
+}
@@ -114,6 +114,8 @@ type AllocatorManager struct {
 	// TSO config
 	rootPath               string
 	saveInterval           time.Duration
+	maxSaveInterval        time.Duration
+	maxSaveIntervalQPS     uint64
 	updatePhysicalInterval time.Duration
 	maxResetTSGap          func() time.Duration
 	securityConfig         *grpcutil.TLSConfig
@@ -122,6 +124,8 @@ type AllocatorManager struct {
 		sync.RWMutex
 		clientConns map[string]*grpc.ClientConn
 	}
+	// outstanding TSO leases granted to clients
+	leases *leaseKeeper
 }
 
 // NewAllocatorManager creates a new TSO Allocator Manager.
@@ -129,6 +133,8 @@ func NewAllocatorManager(
 	m *member.Member,
 	rootPath string,
 	saveInterval time.Duration,
+	maxSaveInterval time.Duration,
+	maxSaveIntervalQPS uint64,
 	updatePhysicalInterval time.Duration,
 	maxResetTSGap func() time.Duration,
 	sc *grpcutil.TLSConfig,
@@ -137,9 +143,12 @@ func NewAllocatorManager(
 		member:                 m,
 		rootPath:               rootPath,
 		saveInterval:           saveInterval,
+		maxSaveInterval:        maxSaveInterval,
+		maxSaveIntervalQPS:     maxSaveIntervalQPS,
 		updatePhysicalInterval: updatePhysicalInterval,
 		maxResetTSGap:          maxResetTSGap,
 		securityConfig:         sc,
+		leases:                 newLeaseKeeper(m, rootPath),
 	}
 	allocatorManager.mu.allocatorGroups = make(map[string]*allocatorGroup)
 	allocatorManager.mu.clusterDCLocations = make(map[string]*DCLocationInfo)
@@ -73,6 +73,8 @@ func NewGlobalTSOAllocator(
 			client:                 leadership.GetClient(),
 			rootPath:               am.rootPath,
 			saveInterval:           am.saveInterval,
+			maxSaveInterval:        am.maxSaveInterval,
+			maxSaveIntervalQPS:     am.maxSaveIntervalQPS,
 			updatePhysicalInterval: am.updatePhysicalInterval,
 			maxResetTSGap:          am.maxResetTSGap,
 			dcLocation:             GlobalDCLocation,
@@ -95,6 +97,12 @@ func (gta *GlobalTSOAllocator) IsInitialize() bool {
 	return gta.timestampOracle.isInitialized()
 }
 
+// GetLastSaveError returns the error of the last attempt to persist the TSO
+// time window to etcd, or nil if it succeeded.
+func (gta *GlobalTSOAllocator) GetLastSaveError() error {
+	return gta.timestampOracle.getLastSaveError()
+}
+
 // UpdateTSO is used to update the TSO in memory and the time window in etcd.
 func (gta *GlobalTSOAllocator) UpdateTSO() error {
 	return gta.timestampOracle.UpdateTimestamp(gta.leadership)
@@ -0,0 +1,209 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/etcdutil"
+	"github.com/tikv/pd/pkg/tsoutil"
+	"github.com/tikv/pd/server/member"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+)
+
+const tsoLeasePath = "tso_lease"
+
+// Lease is a range of timestamps granted to a client, which can be assigned
+// by the client itself until the lease expires. The range is [Start, End].
+type Lease struct {
+	ID         uint64    `json:"id"`
+	DCLocation string    `json:"dc-location"`
+	Start      uint64    `json:"start"`
+	End        uint64    `json:"end"`
+	Count      uint32    `json:"count"`
+	ExpireTime time.Time `json:"expire-time"`
+}
+
+// leaseKeeper keeps the outstanding timestamp leases in etcd, so that they
+// survive the change of the leader. Each lease is attached to an etcd lease
+// with the same TTL, whose ID is used as the ID of the lease, so etcd removes
+// it once it expires.
+type leaseKeeper struct {
+	member   *member.Member
+	rootPath string
+}
+
+func newLeaseKeeper(m *member.Member, rootPath string) *leaseKeeper {
+	return &leaseKeeper{member: m, rootPath: rootPath}
+}
+
+func (k *leaseKeeper) leasePath(id uint64) string {
+	return path.Join(k.rootPath, tsoLeasePath, fmt.Sprintf("%020d", id))
+}
+
+func (k *leaseKeeper) grant(dcLocation string, ts pdpb.Timestamp, count uint32, ttl time.Duration) (*Lease, error) {
+	client := k.member.Client()
+	ctx, cancel := context.WithTimeout(client.Ctx(), etcdutil.DefaultRequestTimeout)
+	leaseResp, err := clientv3.NewLease(client).Grant(ctx, int64(math.Ceil(ttl.Seconds())))
+	cancel()
+	if err != nil {
+		return nil, errs.ErrEtcdGrantLease.Wrap(err).GenWithStackByCause()
+	}
+	// The returned timestamp is the last one of the batch.
+	end := tsoutil.GenerateTS(&ts)
+	lease := &Lease{
+		ID:         uint64(leaseResp.ID),
+		DCLocation: dcLocation,
+		Start:      end - uint64(count) + 1,
+		End:        end,
+		Count:      count,
+		ExpireTime: time.Now().Add(ttl),
+	}
+	value, err := json.Marshal(lease)
+	if err != nil {
+		return nil, errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	// Only the leader can grant the leases.
+	resp, err := k.member.GetLeadership().LeaderTxn().
+		Then(clientv3.OpPut(k.leasePath(lease.ID), string(value), clientv3.WithLease(leaseResp.ID))).
+		Commit()
+	if err != nil {
+		return nil, errs.ErrEtcdKVPut.Wrap(err).GenWithStackByCause()
+	}
+	if !resp.Succeeded {
+		return nil, errs.ErrEtcdTxnConflict.FastGenByArgs()
+	}
+	return lease, nil
+}
+
+// revoke removes the lease with the given ID. It returns false if the lease
+// does not exist or has expired.
+func (k *leaseKeeper) revoke(id uint64) (bool, error) {
+	lease, err := k.load(id)
+	if err != nil || lease == nil || !time.Now().Before(lease.ExpireTime) {
+		return false, err
+	}
+	client := k.member.Client()
+	ctx, cancel := context.WithTimeout(client.Ctx(), etcdutil.DefaultRequestTimeout)
+	defer cancel()
+	// Revoking the etcd lease deletes the key attached to it.
+	if _, err := clientv3.NewLease(client).Revoke(ctx, clientv3.LeaseID(id)); err != nil {
+		if err == rpctypes.ErrLeaseNotFound {
+			return false, nil
+		}
+		return false, errs.ErrEtcdKVDelete.Wrap(err).GenWithStackByCause()
+	}
+	return true, nil
+}
+
+func (k *leaseKeeper) load(id uint64) (*Lease, error) {
+	resp, err := etcdutil.EtcdKVGet(k.member.Client(), k.leasePath(id))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	lease := &Lease{}
+	if err := json.Unmarshal(resp.Kvs[0].Value, lease); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return lease, nil
+}
+
+// list returns the leases which have not expired, ordered by ID.
+func (k *leaseKeeper) list() ([]*Lease, error) {
+	prefix := path.Join(k.rootPath, tsoLeasePath) + "/"
+	resp, err := etcdutil.EtcdKVGet(k.member.Client(), prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	leases := make([]*Lease, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		lease := &Lease{}
+		if err := json.Unmarshal(item.Value, lease); err != nil {
+			return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+		}
+		// The TTL of the etcd lease is rounded up to seconds.
+		if now.Before(lease.ExpireTime) {
+			leases = append(leases, lease)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].ID < leases[j].ID })
+	return leases, nil
+}
+
+// minStart returns the lease with the minimal start timestamp, or nil if
+// there is no outstanding lease.
+func (k *leaseKeeper) minStart() (*Lease, error) {
+	leases, err := k.list()
+	if err != nil {
+		return nil, err
+	}
+	var min *Lease
+	for _, lease := range leases {
+		if min == nil || lease.Start < min.Start {
+			min = lease
+		}
+	}
+	return min, nil
+}
+
+// GrantTSOLease allocates count timestamps from the allocator of the given
+// dc-location and grants them to the caller as a lease with the given TTL.
+func (am *AllocatorManager) GrantTSOLease(dcLocation string, count uint32, ttl time.Duration) (*Lease, error) {
+	if count == 0 {
+		return nil, errs.ErrInvalidTSOLease.FastGenByArgs("count should be positive")
+	}
+	if ttl <= 0 {
+		return nil, errs.ErrInvalidTSOLease.FastGenByArgs("ttl should be positive")
+	}
+	if dcLocation == "" {
+		dcLocation = GlobalDCLocation
+	}
+	ts, err := am.HandleTSORequest(dcLocation, count)
+	if err != nil {
+		return nil, err
+	}
+	return am.leases.grant(dcLocation, ts, count, ttl)
+}
+
+// RevokeTSOLease releases the lease with the given ID. It returns false if
+// the lease does not exist or has expired.
+func (am *AllocatorManager) RevokeTSOLease(id uint64) (bool, error) {
+	return am.leases.revoke(id)
+}
+
+// GetTSOLeases returns all outstanding TSO leases.
+func (am *AllocatorManager) GetTSOLeases() ([]*Lease, error) {
+	return am.leases.list()
+}
+
+// GetMinLeasedTS returns the outstanding TSO lease with the minimal start
+// timestamp, or nil if there is none. Any timestamp that may still be
+// assigned by a lease holder is not less than its start, so the GC safe
+// point must not move beyond it.
+func (am *AllocatorManager) GetMinLeasedTS() (*Lease, error) {
+	return am.leases.minStart()
+}
@@ -59,6 +59,8 @@ func NewLocalTSOAllocator(
 			client:                 leadership.GetClient(),
 			rootPath:               leadership.GetLeaderKey(),
 			saveInterval:           am.saveInterval,
+			maxSaveInterval:        am.maxSaveInterval,
+			maxSaveIntervalQPS:     am.maxSaveIntervalQPS,
 			updatePhysicalInterval: am.updatePhysicalInterval,
 			maxResetTSGap:          am.maxResetTSGap,
 			dcLocation:             dcLocation,
@@ -32,6 +32,14 @@ var (
 			Help:      "Record of tso metadata.",
 		}, []string{"type", "dc"})
 
+	tsoSaveIntervalGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "tso",
+			Name:      "save_interval_seconds",
+			Help:      "The time window saved in etcd by the TSO allocator.",
+		}, []string{"dc"})
+
 	tsoAllocatorRole = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -44,5 +52,6 @@ var (
 func init() {
 	prometheus.MustRegister(tsoCounter)
 	prometheus.MustRegister(tsoGauge)
+	prometheus.MustRegister(tsoSaveIntervalGauge)
 	prometheus.MustRegister(tsoAllocatorRole)
 }
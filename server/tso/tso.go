@@ -15,6 +15,7 @@ package tso
 
 import (
 	"fmt"
+	"math"
 	"path"
 	"sync"
 	"sync/atomic"
@@ -51,20 +52,36 @@ type tsoObject struct {
 	logical  int64
 }
 
+// saveWindow is used to adapt the time window saved in etcd to the TSO QPS.
+type saveWindow struct {
+	// count is the number of TSOs generated since the last adjustment,
+	// it is put first to keep the 64-bit atomic access aligned.
+	count uint64
+	sync.Mutex
+	lastAdjustTime time.Time
+}
+
 // timestampOracle is used to maintain the logic of TSO.
 type timestampOracle struct {
 	client   *clientv3.Client
 	rootPath string
 	// TODO: remove saveInterval
-	saveInterval           time.Duration
+	saveInterval time.Duration
+	// the saved time window grows from saveInterval to maxSaveInterval as the
+	// TSO QPS grows to maxSaveIntervalQPS.
+	maxSaveInterval        time.Duration
+	maxSaveIntervalQPS     uint64
+	window                 saveWindow
 	updatePhysicalInterval time.Duration
 	maxResetTSGap          func() time.Duration
 	// tso info stored in the memory
 	tsoMux *tsoObject
 	// last timestamp window stored in etcd
 	lastSavedTime atomic.Value // stored as time.Time
-	suffix        int
-	dcLocation    string
+	// the result of the last attempt to save the timestamp window
+	lastSaveResult atomic.Value // stored as saveResult
+	suffix         int
+	dcLocation     string
 }
 
 func (t *timestampOracle) setTSOPhysical(next time.Time) {
@@ -133,9 +150,45 @@ func (t *timestampOracle) loadTimestamp() (time.Time, error) {
 	return typeutil.ParseTimestamp(data)
 }
 
+// resetSaveWindow shrinks the time window back to saveInterval and restarts
+// counting the TSO QPS.
+func (t *timestampOracle) resetSaveWindow() time.Duration {
+	t.window.Lock()
+	defer t.window.Unlock()
+	atomic.StoreUint64(&t.window.count, 0)
+	t.window.lastAdjustTime = time.Now()
+	tsoSaveIntervalGauge.WithLabelValues(t.dcLocation).Set(t.saveInterval.Seconds())
+	return t.saveInterval
+}
+
+// adjustSaveWindow returns the time window to be saved in etcd. It grows
+// linearly with the TSO QPS since the last adjustment, from saveInterval to
+// maxSaveInterval, so that the etcd writes are fewer under high QPS and the
+// time jump after a failover is smaller when idle.
+func (t *timestampOracle) adjustSaveWindow() time.Duration {
+	if t.maxSaveInterval <= t.saveInterval || t.maxSaveIntervalQPS == 0 {
+		return t.saveInterval
+	}
+	t.window.Lock()
+	defer t.window.Unlock()
+	now := time.Now()
+	count := atomic.SwapUint64(&t.window.count, 0)
+	elapsed := now.Sub(t.window.lastAdjustTime)
+	t.window.lastAdjustTime = now
+	interval := t.saveInterval
+	if elapsed > 0 {
+		qps := float64(count) / elapsed.Seconds()
+		ratio := math.Min(qps/float64(t.maxSaveIntervalQPS), 1)
+		interval += time.Duration(ratio * float64(t.maxSaveInterval-t.saveInterval))
+	}
+	tsoSaveIntervalGauge.WithLabelValues(t.dcLocation).Set(interval.Seconds())
+	return interval
+}
+
 // save timestamp, if lastTs is 0, we think the timestamp doesn't exist, so create it,
 // otherwise, update it.
-func (t *timestampOracle) saveTimestamp(leadership *election.Leadership, ts time.Time) error {
+func (t *timestampOracle) saveTimestamp(leadership *election.Leadership, ts time.Time) (err error) {
+	defer func() { t.lastSaveResult.Store(saveResult{err: err}) }()
 	key := t.getTimestampPath()
 	data := typeutil.Uint64ToBytes(uint64(ts.UnixNano()))
 	resp, err := leadership.LeaderTxn().
@@ -147,10 +200,24 @@ func (t *timestampOracle) saveTimestamp(leadership *election.Leadership, ts time
 	if !resp.Succeeded {
 		return errs.ErrEtcdTxnConflict.FastGenByArgs()
 	}
+	tsoCounter.WithLabelValues("save_etcd", t.dcLocation).Inc()
 	t.lastSavedTime.Store(ts)
 	return nil
 }
 
+type saveResult struct {
+	err error
+}
+
+// getLastSaveError returns the error of the last attempt to save the
+// timestamp window, or nil if it succeeded or has not been made.
+func (t *timestampOracle) getLastSaveError() error {
+	if result, ok := t.lastSaveResult.Load().(saveResult); ok {
+		return result.err
+	}
+	return nil
+}
+
 // SyncTimestamp is used to synchronize the timestamp.
 func (t *timestampOracle) SyncTimestamp(leadership *election.Leadership) error {
 	tsoCounter.WithLabelValues("sync", t.dcLocation).Inc()
@@ -179,7 +246,7 @@ func (t *timestampOracle) SyncTimestamp(leadership *election.Leadership) error {
 		next = last.Add(updateTimestampGuard)
 	}
 
-	save := next.Add(t.saveInterval)
+	save := next.Add(t.resetSaveWindow())
 	if err = t.saveTimestamp(leadership, save); err != nil {
 		tsoCounter.WithLabelValues("err_save_sync_ts", t.dcLocation).Inc()
 		return err
@@ -236,7 +303,7 @@ func (t *timestampOracle) resetUserTimestamp(leadership *election.Leadership, ts
 	}
 	// save into etcd only if nextPhysical is close to lastSavedTime
 	if typeutil.SubTimeByWallClock(t.lastSavedTime.Load().(time.Time), nextPhysical) <= updateTimestampGuard {
-		save := nextPhysical.Add(t.saveInterval)
+		save := nextPhysical.Add(t.adjustSaveWindow())
 		if err = t.saveTimestamp(leadership, save); err != nil {
 			tsoCounter.WithLabelValues("err_save_reset_ts", t.dcLocation).Inc()
 			return err
@@ -254,7 +321,8 @@ func (t *timestampOracle) resetUserTimestamp(leadership *election.Leadership, ts
 // 1. When the logical time is going to be used up, increase the current physical time.
 // 2. When the time window is not big enough, which means the saved etcd time minus the next physical time
 //    will be less than or equal to `updateTimestampGuard`, then the time window needs to be updated and
-//    we also need to save the next physical time plus `TSOSaveInterval` into etcd.
+//    we also need to save the next physical time plus the time window into etcd, which
+//    is adapted to the TSO QPS between `TSOSaveInterval` and `TSOMaxSaveInterval`.
 //
 // Here is some constraints that this function must satisfy:
 // 1. The saved time is monotonically increasing.
@@ -302,7 +370,7 @@ func (t *timestampOracle) UpdateTimestamp(leadership *election.Leadership) error
 	// It is not safe to increase the physical time to `next`.
 	// The time window needs to be updated and saved to etcd.
 	if typeutil.SubTimeByWallClock(t.lastSavedTime.Load().(time.Time), next) <= updateTimestampGuard {
-		save := next.Add(t.saveInterval)
+		save := next.Add(t.adjustSaveWindow())
 		if err := t.saveTimestamp(leadership, save); err != nil {
 			tsoCounter.WithLabelValues("err_save_update_ts", t.dcLocation).Inc()
 			return err
@@ -360,6 +428,7 @@ func (t *timestampOracle) getTS(leadership *election.Leadership, count uint32, s
 			return pdpb.Timestamp{}, errs.ErrGenerateTimestamp.FastGenByArgs("not the pd or local tso allocator leader")
 		}
 		resp.SuffixBits = uint32(suffixBits)
+		atomic.AddUint64(&t.window.count, uint64(count))
 		return resp, nil
 	}
 	return resp, errs.ErrGenerateTimestamp.FastGenByArgs(fmt.Sprintf("generate %s tso maximum number of retries exceeded", t.dcLocation))
@@ -14,6 +14,8 @@
 package versioninfo
 
 import (
+	"sort"
+
 	"github.com/coreos/go-semver/semver"
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
@@ -55,6 +57,33 @@ var featuresDict = map[Feature]string{
 	JointConsensus: "5.0.0",
 }
 
+var featureNames = map[Feature]string{
+	RegionMerge:    "region-merge",
+	BatchSplit:     "batch-split",
+	JointConsensus: "joint-consensus",
+}
+
+// String implements fmt.Stringer.
+func (f Feature) String() string {
+	if name, ok := featureNames[f]; ok {
+		return name
+	}
+	return featuresDict[f]
+}
+
+// UnsupportedFeatures returns the named features which are not supported by
+// the cluster version, sorted by the feature number.
+func UnsupportedFeatures(clusterVersion semver.Version) []Feature {
+	var features []Feature
+	for f := range featureNames {
+		if clusterVersion.LessThan(*MinSupportedVersion(f)) {
+			features = append(features, f)
+		}
+	}
+	sort.Slice(features, func(i, j int) bool { return features[i] < features[j] })
+	return features
+}
+
 // MinSupportedVersion returns the minimum support version for the specified feature.
 func MinSupportedVersion(v Feature) *semver.Version {
 	target, ok := featuresDict[v]
@@ -19,19 +19,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"os"
 	"path"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/prometheus/client_golang/prometheus"
 	pd "github.com/tikv/pd/client"
+	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/pkg/ratelimit"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/pkg/tsoutil"
 	"github.com/tikv/pd/server"
@@ -40,6 +47,10 @@ import (
 	"github.com/tikv/pd/server/tso"
 	"github.com/tikv/pd/tests"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const tsoRequestConcurrentNumber = 10
@@ -70,6 +81,7 @@ func (s *clientTestSuite) TearDownSuite(c *C) {
 
 type client interface {
 	GetLeaderAddr() string
+	GetNextLeaderAddr() string
 	ScheduleCheckLeader()
 	GetURLs() []string
 	GetAllocatorLeaderURLs() map[string]string
@@ -126,6 +138,162 @@ func (s *clientTestSuite) TestClientLeaderChange(c *C) {
 	c.Assert(urls, DeepEquals, endpoints)
 }
 
+func (s *clientTestSuite) TestWaitForConfigRevision(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 3)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	endpoints := s.runServer(c, cluster)
+	cli := s.setupCli(c, endpoints, false)
+
+	svr := cluster.GetServer(cluster.GetLeader()).GetServer()
+	cfg := svr.GetScheduleConfig().Clone()
+	cfg.LeaderScheduleLimit++
+	c.Assert(svr.SetScheduleConfig(*cfg), IsNil)
+	revision, err := svr.GetConfigRevision()
+	c.Assert(err, IsNil)
+	c.Assert(revision, Greater, int64(0))
+
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+	c.Assert(cli.WaitForConfigRevision(ctx, revision), IsNil)
+	c.Assert(cli.WaitForConfigRevision(ctx, revision, pd.WithAllMembers()), IsNil)
+
+	// The revision has not been reached yet.
+	ctx, cancel = context.WithTimeout(s.ctx, 500*time.Millisecond)
+	defer cancel()
+	err = cli.WaitForConfigRevision(ctx, revision+1000000)
+	c.Assert(errors.Cause(err), Equals, context.DeadlineExceeded)
+}
+
+func (s *clientTestSuite) TestCallerRateLimit(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 1)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	endpoints := s.runServer(c, cluster)
+	cli := s.setupCli(c, endpoints, false)
+	brCli, err := pd.NewClientWithContext(s.ctx, endpoints, pd.SecurityOption{}, pd.WithCallerComponent("br"))
+	c.Assert(err, IsNil)
+	defer brCli.Close()
+
+	svr := cluster.GetServer(cluster.GetLeader()).GetServer()
+	cfg := svr.GetPDServerConfig()
+	cfg.CallerRateLimits = map[string]ratelimit.Quota{"br": {QPS: 0.001, Burst: 1}}
+	c.Assert(svr.SetPDServerConfig(*cfg), IsNil)
+
+	_, err = brCli.GetAllStores(s.ctx)
+	c.Assert(err, IsNil)
+	_, err = brCli.GetAllStores(s.ctx)
+	c.Assert(err, NotNil)
+	c.Assert(status.Code(errors.Cause(err)), Equals, codes.ResourceExhausted)
+	hint, ok := grpcutil.GetRetryHint(err)
+	c.Assert(ok, IsTrue)
+	c.Assert(hint.Retryable, IsTrue)
+	c.Assert(hint.Backoff, Equals, 1000*time.Second)
+	// The services which are not a part of pdpb are limited too.
+	_, err = brCli.GetRegionsByStore(s.ctx, 1, nil, 0)
+	c.Assert(status.Code(errors.Cause(err)), Equals, codes.ResourceExhausted)
+	// The other callers are not limited.
+	for i := 0; i < 3; i++ {
+		_, err = cli.GetAllStores(s.ctx)
+		c.Assert(err, IsNil)
+	}
+
+	cfg.CallerRateLimits = nil
+	c.Assert(svr.SetPDServerConfig(*cfg), IsNil)
+	_, err = brCli.GetAllStores(s.ctx)
+	c.Assert(err, IsNil)
+}
+
+func (s *clientTestSuite) TestHeaderErrorRetryHint(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 1)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+	c.Assert(cluster.RunInitialServers(), IsNil)
+	cluster.WaitLeader()
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	cli := s.setupCli(c, []string{leaderServer.GetConfig().AdvertiseClientUrls}, false)
+	defer cli.Close()
+
+	// The error reported in the response header carries the retry hint.
+	_, err = cli.GetRegionsByStore(s.ctx, 1, nil, 0)
+	c.Assert(err, ErrorMatches, ".*cluster is not bootstrapped.*")
+	hint, ok := grpcutil.GetRetryHint(err)
+	c.Assert(ok, IsTrue)
+	c.Assert(hint.Retryable, IsTrue)
+	c.Assert(hint.Backoff, Equals, time.Second)
+
+	c.Assert(leaderServer.BootstrapCluster(), IsNil)
+	_, err = cli.GetRegionsByStore(s.ctx, 1, nil, 0)
+	c.Assert(err, IsNil)
+}
+
+func (s *clientTestSuite) TestRoutingDomain(c *C) {
+	var routingURL string
+	cluster, err := tests.NewTestCluster(s.ctx, 1, func(conf *config.Config, serverName string) {
+		// The routing domain reaches the same server by another url.
+		routingURL = strings.Replace(conf.AdvertiseClientUrls, "127.0.0.1", "localhost", 1)
+		conf.AdvertiseClientUrlsByDomain = map[string]string{"operator": routingURL}
+	})
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	endpoints := s.runServer(c, cluster)
+	cli, err := pd.NewClientWithContext(s.ctx, endpoints, pd.SecurityOption{}, pd.WithRoutingDomain("operator"))
+	c.Assert(err, IsNil)
+	defer cli.Close()
+	c.Assert(cli.GetLeaderAddr(), Equals, routingURL)
+
+	// The leader serves the requests sent to its url of the routing domain
+	// without forwarding them to itself.
+	forwarded := getForwardedRequestCount(c)
+	_, err = cli.GetAllStores(s.ctx)
+	c.Assert(err, IsNil)
+	_, err = cli.GetRegion(s.ctx, []byte("a"))
+	c.Assert(err, IsNil)
+	_, _, err = cli.GetTS(s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(getForwardedRequestCount(c), Equals, forwarded)
+}
+
+func getForwardedRequestCount(c *C) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	c.Assert(err, IsNil)
+	var count float64
+	for _, family := range families {
+		if family.GetName() != "pd_server_grpc_forwarded_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			count += m.GetCounter().GetValue()
+		}
+	}
+	return count
+}
+
+func (s *clientTestSuite) TestAllocIDRange(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 1)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	endpoints := s.runServer(c, cluster)
+	cli := s.setupCli(c, endpoints, false)
+
+	r1, err := cli.AllocIDRange(s.ctx, 100, time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(r1.End-r1.Start, Equals, uint64(100))
+	c.Assert(r1.Deadline.After(time.Now()), IsTrue)
+	r2, err := cli.AllocIDRange(s.ctx, 10000, time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(r2.Start, GreaterEqual, r1.End)
+
+	_, err = cli.AllocIDRange(s.ctx, 0, time.Minute)
+	c.Assert(err, NotNil)
+	_, err = cli.AllocIDRange(s.ctx, 100, 48*time.Hour)
+	c.Assert(err, NotNil)
+}
+
 func (s *clientTestSuite) TestLeaderTransfer(c *C) {
 	cluster, err := tests.NewTestCluster(s.ctx, 2)
 	c.Assert(err, IsNil)
@@ -182,6 +350,39 @@ func (s *clientTestSuite) TestLeaderTransfer(c *C) {
 	wg.Wait()
 }
 
+func (s *clientTestSuite) TestWarmUp(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 3)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	endpoints := s.runServer(c, cluster)
+	cli, err := pd.NewClientWithContext(s.ctx, endpoints, pd.SecurityOption{}, pd.WithWarmUpOption(true))
+	c.Assert(err, IsNil)
+	defer cli.Close()
+
+	var lastTS uint64
+	for i := 0; i < 3; i++ {
+		leader := cluster.GetServer(cluster.WaitLeader())
+		s.waitLeader(c, cli.(client), leader.GetConfig().ClientUrls)
+		nextLeader := cli.(client).GetNextLeaderAddr()
+		c.Assert(nextLeader, Not(Equals), "")
+		c.Assert(nextLeader, Not(Equals), leader.GetConfig().ClientUrls)
+		testutil.WaitUntil(c, func(c *C) bool {
+			physical, logical, err := cli.GetTS(context.TODO())
+			if err != nil {
+				c.Log(err)
+				return false
+			}
+			ts := tsoutil.ComposeTS(physical, logical)
+			c.Assert(cluster.CheckTSOUnique(ts), IsTrue)
+			c.Assert(lastTS, Less, ts)
+			lastTS = ts
+			return true
+		})
+		c.Assert(leader.ResignLeader(), IsNil)
+	}
+}
+
 func (s *clientTestSuite) TestTSOAllocatorLeader(c *C) {
 	dcLocationConfig := map[string]string{
 		"pd1": "dc-1",
@@ -360,6 +561,69 @@ func (s *clientTestSuite) TestGetRegionFromFollowerClient(c *C) {
 	c.Assert(r, NotNil)
 }
 
+func (s *clientTestSuite) TestStoreFollowerRead(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 3)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	endpoints := s.runServer(c, cluster)
+	leader := cluster.GetServer(cluster.GetLeader())
+	follower := cluster.GetServer(cluster.GetFollower())
+	header := &pdpb.RequestHeader{ClusterId: leader.GetClusterID()}
+	_, err = leader.GetServer().PutStore(s.ctx, &pdpb.PutStoreRequest{
+		Header: header,
+		Store: &metapb.Store{
+			Id:      100,
+			Address: "mock://tikv-100",
+			Labels:  []*metapb.StoreLabel{{Key: "zone", Value: "z1"}},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	// The follower serves the stores once it is synced.
+	grpcPDClient := testutil.MustNewGrpcClient(c, follower.GetAddr())
+	var revision int64
+	testutil.WaitUntil(c, func(c *C) bool {
+		var md metadata.MD
+		ctx := grpcutil.BuildStoreFollowerReadContext(s.ctx, 0)
+		resp, err := grpcPDClient.GetAllStores(ctx, &pdpb.GetAllStoresRequest{Header: header}, grpc.Header(&md))
+		if err != nil || len(resp.GetStores()) != 2 {
+			return false
+		}
+		revision = grpcutil.GetStoreRevision(md)
+		return revision > 0
+	})
+	ctx := grpcutil.BuildStoreLabelContext(grpcutil.BuildStoreFollowerReadContext(s.ctx, revision), "zone", "z1")
+	resp, err := grpcPDClient.GetAllStores(ctx, &pdpb.GetAllStoresRequest{Header: header})
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetStores(), HasLen, 1)
+	c.Assert(resp.GetStores()[0].GetId(), Equals, uint64(100))
+	// The follower rejects the reads newer than it.
+	ctx = grpcutil.BuildStoreFollowerReadContext(s.ctx, revision+1)
+	_, err = grpcPDClient.GetStore(ctx, &pdpb.GetStoreRequest{Header: header, StoreId: 100})
+	c.Assert(status.Code(err), Equals, codes.Unavailable)
+	// The follower does not serve the requests not allowing it.
+	_, err = grpcPDClient.GetStore(s.ctx, &pdpb.GetStoreRequest{Header: header, StoreId: 100})
+	c.Assert(err, NotNil)
+
+	cli, err := pd.NewClientWithContext(s.ctx, endpoints, pd.SecurityOption{}, pd.WithStoreFollowerRead(true))
+	c.Assert(err, IsNil)
+	defer cli.Close()
+	_, err = leader.GetServer().PutStore(s.ctx, &pdpb.PutStoreRequest{
+		Header: header,
+		Store:  &metapb.Store{Id: 101, Address: "mock://tikv-101"},
+	})
+	c.Assert(err, IsNil)
+	for i := 0; i < 10; i++ {
+		store, err := cli.GetStore(s.ctx, 101)
+		c.Assert(err, IsNil)
+		c.Assert(store.GetAddress(), Equals, "mock://tikv-101")
+		stores, err := cli.GetAllStores(s.ctx, pd.WithStoreLabel("zone", "z1"))
+		c.Assert(err, IsNil)
+		c.Assert(stores, HasLen, 1)
+	}
+}
+
 // case 1: unreachable -> normal
 func (s *clientTestSuite) TestGetTsoFromFollowerClient1(c *C) {
 	pd.LeaderHealthCheckInterval = 100 * time.Millisecond
@@ -433,6 +697,49 @@ func checkTS(c *C, cli pd.Client, lastTS uint64) uint64 {
 	return lastTS
 }
 
+func (s *clientTestSuite) TestOfflineCache(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 1)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	endpoints := s.runServer(c, cluster)
+	path := filepath.Join(c.MkDir(), "offline-cache")
+	cli, err := pd.NewClientWithContext(s.ctx, endpoints, pd.SecurityOption{}, pd.WithOfflineCache(path))
+	c.Assert(err, IsNil)
+
+	region, err := cli.GetRegion(s.ctx, []byte("a"))
+	c.Assert(err, IsNil)
+	c.Assert(region.Stale, IsFalse)
+	stores, err := cli.GetAllStores(s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(stores, HasLen, 1)
+
+	// The lookups are served from the cache once every PD member is down.
+	c.Assert(cluster.StopAll(), IsNil)
+	testutil.WaitUntil(c, func(c *C) bool {
+		r, err := cli.GetRegion(s.ctx, []byte("a"))
+		return err == nil && r.Stale && r.Meta.GetId() == region.Meta.GetId()
+	})
+	var stale bool
+	stores, err = cli.GetAllStores(s.ctx, pd.WithStaleFlag(&stale))
+	c.Assert(err, IsNil)
+	c.Assert(stores, HasLen, 1)
+	c.Assert(stale, IsTrue)
+	stale = false
+	store, err := cli.GetStoreWithOptions(s.ctx, stores[0].GetId(), pd.WithStaleFlag(&stale))
+	c.Assert(err, IsNil)
+	c.Assert(store.GetAddress(), Equals, stores[0].GetAddress())
+	c.Assert(stale, IsTrue)
+	// The region not in the cache is not served.
+	_, err = cli.GetRegionByID(s.ctx, region.Meta.GetId()+100)
+	c.Assert(err, NotNil)
+
+	// The cache is saved when the client is closed.
+	cli.Close()
+	_, err = os.Stat(path)
+	c.Assert(err, IsNil)
+}
+
 func (s *clientTestSuite) runServer(c *C, cluster *tests.TestCluster) []string {
 	err := cluster.RunInitialServers()
 	c.Assert(err, IsNil)
@@ -748,6 +1055,151 @@ func (s *testClientSuite) TestScanRegions(c *C) {
 	check([]byte{100}, nil, 1, nil)
 	check([]byte{1}, []byte{6}, 0, regions[1:6])
 	check([]byte{1}, []byte{6}, 2, regions[1:3])
+
+	checkStream := func(start, end []byte, limit, chunkSize int, expect []*metapb.Region, expectChunks int) {
+		var (
+			scanRegions []*pd.Region
+			chunks      int
+		)
+		err := s.client.ScanRegionsStream(context.Background(), start, end, limit, chunkSize, func(regions []*pd.Region) error {
+			c.Assert(len(regions) <= chunkSize || chunkSize == 0, IsTrue)
+			scanRegions = append(scanRegions, regions...)
+			chunks++
+			return nil
+		})
+		c.Assert(err, IsNil)
+		c.Assert(chunks, Equals, expectChunks)
+		c.Assert(scanRegions, HasLen, len(expect))
+		for i := range expect {
+			c.Assert(scanRegions[i].Meta, DeepEquals, expect[i])
+		}
+	}
+	checkStream([]byte{0}, []byte{10}, 0, 0, regions, 1)
+	checkStream([]byte{0}, []byte{10}, 0, 3, regions, 4)
+	checkStream([]byte{0}, []byte{10}, 7, 3, regions[:7], 3)
+	checkStream([]byte{1}, []byte{6}, 0, 5, regions[1:6], 1)
+	checkStream([]byte{100}, nil, 0, 3, nil, 0)
+
+	// The scan stops once the handler fails.
+	chunks := 0
+	err := s.client.ScanRegionsStream(context.Background(), []byte{0}, []byte{10}, 0, 2, func([]*pd.Region) error {
+		chunks++
+		return errors.New("stop")
+	})
+	c.Assert(err, ErrorMatches, "stop")
+	c.Assert(chunks, Equals, 1)
+}
+
+func (s *testClientSuite) TestGetRegionsByStore(c *C) {
+	regionLen := 7
+	regions := make([]*metapb.Region, 0, regionLen)
+	for i := 0; i < regionLen; i++ {
+		r := &metapb.Region{
+			Id: regionIDAllocator.alloc(),
+			RegionEpoch: &metapb.RegionEpoch{
+				ConfVer: 1,
+				Version: 1,
+			},
+			StartKey: []byte(fmt.Sprintf("store-regions-%d", i)),
+			EndKey:   []byte(fmt.Sprintf("store-regions-%d", i+1)),
+			Peers:    peers,
+		}
+		regions = append(regions, r)
+		req := &pdpb.RegionHeartbeatRequest{
+			Header: newHeader(s.srv),
+			Region: r,
+			Leader: peers[0],
+		}
+		c.Assert(s.regionHeartbeat.Send(req), IsNil)
+	}
+	testutil.WaitUntil(c, func(c *C) bool {
+		r, err := s.client.GetRegion(context.Background(), regions[regionLen-1].GetStartKey())
+		return err == nil && r != nil && r.Meta.GetId() == regions[regionLen-1].GetId()
+	})
+
+	// List the regions of the store page by page.
+	var (
+		got   []*metapb.Region
+		pages int
+	)
+	key := regions[0].GetStartKey()
+	for {
+		page, err := s.client.GetRegionsByStore(context.Background(), peers[1].GetStoreId(), key, 3)
+		c.Assert(err, IsNil)
+		pages++
+		for _, r := range page {
+			if bytes.HasPrefix(r.Meta.GetStartKey(), []byte("store-regions-")) {
+				got = append(got, r.Meta)
+			}
+		}
+		if len(page) < 3 || len(got) >= regionLen {
+			break
+		}
+		key = page[len(page)-1].Meta.GetEndKey()
+	}
+	c.Assert(pages, Equals, 3)
+	c.Assert(got, HasLen, regionLen)
+	for i := range regions {
+		c.Assert(got[i], DeepEquals, regions[i])
+	}
+
+	// The store is required, and must exist.
+	_, err := s.client.GetRegionsByStore(context.Background(), 0, nil, 3)
+	c.Assert(err, NotNil)
+	_, err = s.client.GetRegionsByStore(context.Background(), 1000, nil, 3)
+	c.Assert(err, NotNil)
+}
+
+func (s *testClientSuite) TestWatchRegions(c *C) {
+	type event struct {
+		regions   []*pd.Region
+		nextIndex uint64
+	}
+	watch := func(ctx context.Context, opts ...pd.WatchRegionsOption) <-chan event {
+		ch := make(chan event, 16)
+		go s.client.WatchRegions(ctx, []byte("watch-a"), []byte("watch-c"), func(regions []*pd.Region, nextIndex uint64) error {
+			ch <- event{regions: regions, nextIndex: nextIndex}
+			return nil
+		}, opts...)
+		return ch
+	}
+	newRegion := func(start, end string) *metapb.Region {
+		return &metapb.Region{
+			Id:          regionIDAllocator.alloc(),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+			StartKey:    []byte(start),
+			EndKey:      []byte(end),
+			Peers:       peers,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := watch(ctx)
+	first := <-events
+	c.Assert(first.regions, HasLen, 0)
+
+	// Only the regions in the key range are pushed.
+	outside := newRegion("watch-x", "watch-y")
+	inside := newRegion("watch-a", "watch-b")
+	for _, r := range []*metapb.Region{outside, inside} {
+		err := s.srv.GetRaftCluster().HandleRegionHeartbeat(core.NewRegionInfo(r, peers[0]))
+		c.Assert(err, IsNil)
+	}
+	var e event
+	for len(e.regions) == 0 {
+		e = <-events
+	}
+	c.Assert(e.regions, HasLen, 1)
+	c.Assert(e.regions[0].Meta, DeepEquals, inside)
+	c.Assert(e.regions[0].Leader, DeepEquals, peers[0])
+	c.Assert(e.nextIndex > first.nextIndex, IsTrue)
+
+	// Resume watching from the first index.
+	events = watch(ctx, pd.WithResumeIndex(first.nextIndex))
+	e = <-events
+	c.Assert(e.regions, HasLen, 1)
+	c.Assert(e.regions[0].Meta, DeepEquals, inside)
 }
 
 func (s *testClientSuite) TestGetRegionByID(c *C) {
@@ -854,6 +1306,33 @@ func (s *testClientSuite) TestGetStore(c *C) {
 	}
 }
 
+func (s *testClientSuite) TestGetAllStoresFilter(c *C) {
+	cluster := s.srv.GetRaftCluster()
+	c.Assert(cluster, NotNil)
+	tiflash := &metapb.Store{
+		Id:      100,
+		Address: "localhost:100",
+		Labels:  []*metapb.StoreLabel{{Key: "engine", Value: "tiflash"}},
+	}
+	c.Assert(cluster.PutStore(tiflash), IsNil)
+
+	stores, err := s.client.GetAllStores(context.Background(), pd.WithStoreLabel("engine", "tiflash"))
+	c.Assert(err, IsNil)
+	c.Assert(stores, HasLen, 1)
+	c.Assert(stores[0].GetId(), Equals, tiflash.GetId())
+
+	// The TiFlash store cannot hold the peers of the keyspace by the default rule.
+	stores, err = s.client.GetAllStores(context.Background(), pd.WithKeyspaceID(1))
+	c.Assert(err, IsNil)
+	c.Assert(stores, Not(HasLen), 0)
+	for _, store := range stores {
+		c.Assert(store.GetId(), Not(Equals), tiflash.GetId())
+	}
+	stores, err = s.client.GetAllStores(context.Background(), pd.WithKeyspaceID(1), pd.WithStoreLabel("engine", "tiflash"))
+	c.Assert(err, IsNil)
+	c.Assert(stores, HasLen, 0)
+}
+
 func (s *testClientSuite) checkGCSafePoint(c *C, expectedSafePoint uint64) {
 	req := &pdpb.GetGCSafePointRequest{
 		Header: newHeader(s.srv),
@@ -1066,6 +1545,85 @@ func (s *testClientSuite) TestScatterRegion(c *C) {
 	c.Succeed()
 }
 
+func (s *testClientSuite) TestScatterRegionsFailures(c *C) {
+	regionID := regionIDAllocator.alloc()
+	region := &metapb.Region{
+		Id: regionID,
+		RegionEpoch: &metapb.RegionEpoch{
+			ConfVer: 1,
+			Version: 1,
+		},
+		Peers:    peers,
+		StartKey: []byte("hhh"),
+		EndKey:   []byte("iii"),
+	}
+	req := &pdpb.RegionHeartbeatRequest{
+		Header: newHeader(s.srv),
+		Region: region,
+		Leader: peers[0],
+	}
+	c.Assert(s.regionHeartbeat.Send(req), IsNil)
+	testutil.WaitUntil(c, func(c *C) bool {
+		return s.srv.GetRaftCluster().GetRegion(regionID) != nil
+	})
+
+	// The other region is never reported to PD.
+	missingRegionID := regionIDAllocator.alloc()
+	var failures []*pd.ScatterFailure
+	_, err := s.client.ScatterRegions(context.Background(), []uint64{regionID, missingRegionID}, pd.WithScatterFailures(&failures))
+	c.Assert(err, IsNil)
+	// The failures are sorted by the region ID.
+	c.Assert(failures, Not(HasLen), 0)
+	failure := failures[len(failures)-1]
+	c.Assert(failure.RegionID, Equals, missingRegionID)
+	c.Assert(failure.Reason, Equals, grpcutil.ScatterReasonRegionNotFound)
+	c.Assert(failure.Transient, IsFalse)
+}
+
+func (s *testClientSuite) TestScatterRegionsWithIdempotencyKey(c *C) {
+	regionID := regionIDAllocator.alloc()
+	region := &metapb.Region{
+		Id: regionID,
+		RegionEpoch: &metapb.RegionEpoch{
+			ConfVer: 1,
+			Version: 1,
+		},
+		Peers:    peers,
+		StartKey: []byte("ggg"),
+		EndKey:   []byte("hhh"),
+	}
+	req := &pdpb.RegionHeartbeatRequest{
+		Header: newHeader(s.srv),
+		Region: region,
+		Leader: peers[0],
+	}
+	err := s.regionHeartbeat.Send(req)
+	c.Assert(err, IsNil)
+	regionsID := []uint64{regionID}
+	var key string
+	testutil.WaitUntil(c, func(c *C) bool {
+		key = fmt.Sprintf("scatter-%d-%d", regionID, time.Now().UnixNano())
+		scatterResp, err := s.client.ScatterRegions(context.Background(), regionsID, pd.WithIdempotencyKey(key))
+		if err != nil || scatterResp.FinishedPercentage != 100 {
+			return false
+		}
+		resp, err := s.client.GetOperator(context.Background(), regionID)
+		return err == nil && resp.GetStatus() == pdpb.OperatorStatus_RUNNING
+	}, testutil.WithSleepInterval(1*time.Second))
+
+	// The retry with the same key does not create the operator again.
+	opController := s.srv.GetRaftCluster().GetOperatorController()
+	c.Assert(opController.RemoveOperator(opController.GetOperator(regionID)), IsTrue)
+	scatterResp, err := s.client.ScatterRegions(context.Background(), regionsID, pd.WithIdempotencyKey(key))
+	c.Assert(err, IsNil)
+	c.Assert(scatterResp.FinishedPercentage, Equals, uint64(100))
+	c.Assert(opController.GetOperator(regionID), IsNil)
+
+	// The key cannot be reused by a different request.
+	_, err = s.client.ScatterRegions(context.Background(), regionsID, pd.WithIdempotencyKey(key), pd.WithGroup("test"))
+	c.Assert(status.Code(errors.Cause(err)), Equals, codes.AlreadyExists)
+}
+
 type testConfigTTLSuite struct {
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -1127,3 +1685,42 @@ func (s *testConfigTTLSuite) TestConfigTTLAfterTransferLeader(c *C) {
 	leader = cluster.GetServer(cluster.WaitLeader())
 	assertTTLConfig(c, leader.GetPersistOptions(), Equals)
 }
+
+func (s *testClientSuite) TestGRPCCompression(c *C) {
+	cli, err := pd.NewClientWithContext(s.ctx, s.srv.GetEndpoints(), pd.SecurityOption{}, pd.WithGRPCCompression(grpcutil.GzipCompressor))
+	c.Assert(err, IsNil)
+	defer cli.Close()
+
+	regions := make([]*metapb.Region, 0, 3)
+	for i := 0; i < 3; i++ {
+		r := &metapb.Region{
+			Id:          regionIDAllocator.alloc(),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+			StartKey:    []byte(fmt.Sprintf("compress-%d", i)),
+			EndKey:      []byte(fmt.Sprintf("compress-%d", i+1)),
+			Peers:       peers,
+		}
+		regions = append(regions, r)
+		err := s.regionHeartbeat.Send(&pdpb.RegionHeartbeatRequest{
+			Header: newHeader(s.srv),
+			Region: r,
+			Leader: peers[0],
+		})
+		c.Assert(err, IsNil)
+	}
+
+	testutil.WaitUntil(c, func(c *C) bool {
+		scanRegions, err := cli.ScanRegions(context.Background(), []byte("compress-0"), []byte("compress-3"), 0)
+		return err == nil && len(scanRegions) == 3
+	})
+	var scanRegions []*pd.Region
+	err = cli.ScanRegionsStream(context.Background(), []byte("compress-0"), []byte("compress-3"), 0, 2, func(regions []*pd.Region) error {
+		scanRegions = append(scanRegions, regions...)
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(scanRegions, HasLen, 3)
+	for i := range regions {
+		c.Assert(scanRegions[i].Meta, DeepEquals, regions[i])
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/confighistory"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/tests"
 	"github.com/tikv/pd/tests/pdctl"
@@ -560,6 +562,42 @@ func (s *configTestSuite) TestReplicationMode(c *C) {
 	check()
 }
 
+func (s *configTestSuite) TestConfigHistory(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster, err := tests.NewTestCluster(ctx, 1)
+	c.Assert(err, IsNil)
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	cluster.WaitLeader()
+	pdAddr := cluster.GetConfig().GetClientURL()
+	cmd := pdctl.InitCommand()
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	c.Assert(leaderServer.BootstrapCluster(), IsNil)
+	svr := leaderServer.GetServer()
+	defer cluster.Destroy()
+
+	limit := svr.GetScheduleConfig().RegionScheduleLimit
+	args := []string{"-u", pdAddr, "config", "set", "region-schedule-limit", "123"}
+	_, err = pdctl.ExecuteCommand(cmd, args...)
+	c.Assert(err, IsNil)
+	c.Assert(svr.GetScheduleConfig().RegionScheduleLimit, Equals, uint64(123))
+
+	args = []string{"-u", pdAddr, "config", "history", "--limit", "2"}
+	output, err := pdctl.ExecuteCommand(cmd, args...)
+	c.Assert(err, IsNil)
+	var entries []*confighistory.Entry
+	c.Assert(json.Unmarshal(output, &entries), IsNil)
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].Changes[0].Item, Equals, "schedule.region-schedule-limit")
+
+	args = []string{"-u", pdAddr, "config", "rollback", strconv.FormatUint(entries[1].Version, 10)}
+	output, err = pdctl.ExecuteCommand(cmd, args...)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "Success!"), IsTrue)
+	c.Assert(svr.GetScheduleConfig().RegionScheduleLimit, Equals, limit)
+}
+
 func (s *configTestSuite) TestUpdateDefaultReplicaConfig(c *C) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -59,6 +59,7 @@ func InitCommand() *cobra.Command {
 		command.NewHotSpotCommand(),
 		command.NewClusterCommand(),
 		command.NewHealthCommand(),
+		command.NewReplicationModeCommand(),
 		command.NewLogCommand(),
 		command.NewPluginCommand(),
 		command.NewCompletionCommand(),
@@ -25,6 +25,7 @@ import (
 	"github.com/tikv/pd/pkg/etcdutil"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/api"
 	"github.com/tikv/pd/tests"
 	"github.com/tikv/pd/tests/pdctl"
 )
@@ -68,6 +69,18 @@ func (s *memberTestSuite) TestMember(c *C) {
 	c.Assert(json.Unmarshal(output, &leader), IsNil)
 	c.Assert(&leader, DeepEquals, svr.GetLeader())
 
+	// member metrics
+	args = []string{"-u", pdAddr, "member", "metrics"}
+	output, err = pdctl.ExecuteCommand(cmd, args...)
+	c.Assert(err, IsNil)
+	var metrics []api.MemberMetrics
+	c.Assert(json.Unmarshal(output, &metrics), IsNil)
+	c.Assert(metrics, HasLen, 3)
+	for _, m := range metrics {
+		c.Assert(m.Error, Equals, "")
+		c.Assert(m.Metrics.Name, Equals, m.Name)
+	}
+
 	// member leader transfer <member_name>
 	args = []string{"-u", pdAddr, "member", "leader", "transfer", "pd2"}
 	_, err = pdctl.ExecuteCommand(cmd, args...)
@@ -188,6 +188,25 @@ func (s *operatorTestSuite) TestOperator(c *C) {
 	_, err = pdctl.ExecuteCommand(cmd, args...)
 	c.Assert(err, IsNil)
 
+	// operator cancel [--desc=<desc>] [--store=<store_id>] [--dry-run]
+	_, err = pdctl.ExecuteCommand(cmd, "-u", pdAddr, "operator", "add", "add-peer", "1", "3")
+	c.Assert(err, IsNil)
+	output, err = pdctl.ExecuteCommand(cmd, "-u", pdAddr, "operator", "cancel", "--dry-run")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "at least one filter is required"), IsTrue)
+	output, err = pdctl.ExecuteCommand(cmd, "-u", pdAddr, "operator", "cancel", "--desc=admin-add-peer", "--store=2", "--dry-run")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "admin-add-peer"), IsFalse)
+	output, err = pdctl.ExecuteCommand(cmd, "-u", pdAddr, "operator", "cancel", "--desc=admin-add-peer", "--store=3", "--dry-run")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "admin-add-peer"), IsTrue)
+	output, err = pdctl.ExecuteCommand(cmd, "-u", pdAddr, "operator", "cancel", "--desc=admin-add-peer", "--store=3", "--dry-run=false")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "admin-add-peer"), IsTrue)
+	output, err = pdctl.ExecuteCommand(cmd, "-u", pdAddr, "operator", "show")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "admin-add-peer"), IsFalse)
+
 	_, err = pdctl.ExecuteCommand(cmd, "config", "set", "enable-placement-rules", "true")
 	c.Assert(err, IsNil)
 	output, err = pdctl.ExecuteCommand(cmd, "operator", "add", "transfer-region", "1", "2", "3")
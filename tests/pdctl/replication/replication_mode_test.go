@@ -0,0 +1,92 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replication_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/replication"
+	"github.com/tikv/pd/tests"
+	"github.com/tikv/pd/tests/pdctl"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&replicationModeTestSuite{})
+
+type replicationModeTestSuite struct{}
+
+func (s *replicationModeTestSuite) SetUpSuite(c *C) {
+	server.EnableZap = true
+}
+
+func (s *replicationModeTestSuite) TestReplicationModeStatus(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tc, err := tests.NewTestCluster(ctx, 1, func(conf *config.Config, serverName string) {
+		conf.ReplicationMode.ReplicationMode = "dr-auto-sync"
+		conf.ReplicationMode.DRAutoSync.LabelKey = "zone"
+		conf.ReplicationMode.DRAutoSync.Primary = "zone1"
+		conf.ReplicationMode.DRAutoSync.DR = "zone2"
+	})
+	c.Assert(err, IsNil)
+	defer tc.Destroy()
+	err = tc.RunInitialServers()
+	c.Assert(err, IsNil)
+	tc.WaitLeader()
+	leaderServer := tc.GetServer(tc.GetLeader())
+	c.Assert(leaderServer.BootstrapCluster(), IsNil)
+	pdAddr := tc.GetConfig().GetClientURL()
+	cmd := pdctl.InitCommand()
+
+	pdctl.MustPutStore(c, leaderServer.GetServer(), 1, metapb.StoreState_Up, []*metapb.StoreLabel{{Key: "zone", Value: "zone1"}})
+	pdctl.MustPutStore(c, leaderServer.GetServer(), 2, metapb.StoreState_Up, []*metapb.StoreLabel{{Key: "zone", Value: "zone2"}})
+	pdctl.MustPutStore(c, leaderServer.GetServer(), 3, metapb.StoreState_Up, []*metapb.StoreLabel{{Key: "zone", Value: "zone3"}})
+
+	args := []string{"-u", pdAddr, "replication-mode", "status"}
+	output, err := pdctl.ExecuteCommand(cmd, args...)
+	c.Assert(err, IsNil)
+	var status replication.HTTPReplicationStatus
+	c.Assert(json.Unmarshal(output, &status), IsNil)
+	c.Assert(status.Mode, Equals, "dr-auto-sync")
+	c.Assert(status.DrAutoSync.State, Equals, "sync")
+	c.Assert(status.DrAutoSync.StateID, Not(Equals), uint64(0))
+	c.Assert(status.DrAutoSync.Reason, Equals, "initialized")
+	c.Assert(status.DrAutoSync.Stores, HasLen, 3)
+	roles := make(map[uint64]string)
+	for _, store := range status.DrAutoSync.Stores {
+		c.Assert(store.Down, IsFalse)
+		roles[store.StoreID] = store.Role
+	}
+	c.Assert(roles, DeepEquals, map[uint64]string{1: "primary", 2: "dr", 3: ""})
+
+	// switching the label key moves the state machine to async.
+	args = []string{"-u", pdAddr, "config", "set", "replication-mode", "dr-auto-sync", "label-key", "dc"}
+	_, err = pdctl.ExecuteCommand(cmd, args...)
+	c.Assert(err, IsNil)
+	args = []string{"-u", pdAddr, "replication-mode", "status"}
+	output, err = pdctl.ExecuteCommand(cmd, args...)
+	c.Assert(err, IsNil)
+	c.Assert(json.Unmarshal(output, &status), IsNil)
+	c.Assert(status.DrAutoSync.State, Equals, "async")
+	c.Assert(status.DrAutoSync.Reason, Equals, "label key changed")
+}
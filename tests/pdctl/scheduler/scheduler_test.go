@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/tests"
 	"github.com/tikv/pd/tests/pdctl"
 )
@@ -248,6 +249,15 @@ func (s *schedulerTestSuite) TestScheduler(c *C) {
 	c.Assert(strings.Contains(echo, "Success!"), IsTrue)
 	echo = pdctl.GetEcho([]string{"-u", pdAddr, "scheduler", "remove", "evict-leader-scheduler-1"})
 	c.Assert(strings.Contains(echo, "404"), IsTrue)
+	echo = pdctl.GetEcho([]string{"-u", pdAddr, "scheduler", "add", "evict-leader-scheduler", "1", "a", "b", "c"})
+	c.Assert(strings.Contains(echo, "Success!"), IsFalse)
+	echo = pdctl.GetEcho([]string{"-u", pdAddr, "scheduler", "add", "evict-leader-scheduler", "1", "a", "b"})
+	c.Assert(strings.Contains(echo, "Success!"), IsTrue)
+	var evictConf map[string]map[uint64][]core.KeyRange
+	mustExec([]string{"-u", pdAddr, "scheduler", "config", "evict-leader-scheduler"}, &evictConf)
+	c.Assert(evictConf["store-id-ranges"][1], DeepEquals, []core.KeyRange{core.NewKeyRange("a", "b")})
+	echo = pdctl.GetEcho([]string{"-u", pdAddr, "scheduler", "remove", "evict-leader-scheduler-1"})
+	c.Assert(strings.Contains(echo, "Success!"), IsTrue)
 
 	// test hot region config
 	echo = pdctl.GetEcho([]string{"-u", pdAddr, "scheduler", "config", "evict-leader-scheduler"})
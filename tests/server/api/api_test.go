@@ -15,17 +15,22 @@ package api_test
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"testing"
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/tikv/pd/pkg/apiutil/serverapi"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/api"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/statistics"
 	"github.com/tikv/pd/tests"
 	"go.uber.org/goleak"
 )
@@ -106,6 +111,92 @@ func (s *serverTestSuite) TestReconnect(c *C) {
 	}
 }
 
+func (s *serverTestSuite) TestFollowerHotStats(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster, err := tests.NewTestCluster(ctx, 2, func(conf *config.Config, serverName string) {
+		conf.PDServerCfg.HotStatsSyncInterval = typeutil.NewDuration(100 * time.Millisecond)
+	})
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+	c.Assert(cluster.RunInitialServers(), IsNil)
+	leader := cluster.GetServer(cluster.WaitLeader())
+	c.Assert(leader.BootstrapCluster(), IsNil)
+	var follower *server.Server
+	for _, svr := range cluster.GetServers() {
+		if svr != leader {
+			follower = svr.GetServer()
+		}
+	}
+
+	rc := leader.GetRaftCluster()
+	c.Assert(rc.PutStore(&metapb.Store{Id: 1, Address: "tikv1", State: metapb.StoreState_Up, Version: "2.0.0"}), IsNil)
+	bytesWritten := uint64(8 * 1024 * 1024)
+	stats := &pdpb.StoreStats{StoreId: 1, BytesWritten: bytesWritten}
+	now := uint64(time.Now().Unix())
+	for i := uint64(statistics.DefaultWriteMfSize); i > 0; i-- {
+		start := now - statistics.StoreHeartBeatReportInterval*i
+		stats.Interval = &pdpb.TimeInterval{StartTimestamp: start, EndTimestamp: start + statistics.StoreHeartBeatReportInterval}
+		rc.GetStoresStats().Observe(1, stats)
+	}
+
+	// The follower serves the statistics replicated from the leader.
+	testutil.WaitUntil(c, func(c *C) bool {
+		request, err := http.NewRequest("GET", follower.GetAddr()+"/pd/api/v1/hotspot/stores", nil)
+		c.Assert(err, IsNil)
+		request.Header.Add(serverapi.AllowFollowerHandle, "true")
+		resp, err := dialClient.Do(request)
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		// The follower is unavailable until the first replication.
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			return false
+		}
+		c.Assert(resp.StatusCode, Equals, http.StatusOK)
+		c.Assert(resp.Header.Get(serverapi.FollowerHandle), Equals, "true")
+		hotStores := api.HotStoreStats{}
+		c.Assert(json.NewDecoder(resp.Body).Decode(&hotStores), IsNil)
+		return resp.Header.Get("PD-Hot-Stats-Update-Time") != "" &&
+			hotStores.BytesWriteStats[1] == float64(bytesWritten)/statistics.StoreHeartBeatReportInterval
+	})
+}
+
+func (s *serverTestSuite) TestFollowerHotStatsStale(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster, err := tests.NewTestCluster(ctx, 2, func(conf *config.Config, serverName string) {
+		conf.PDServerCfg.HotStatsSyncInterval = typeutil.NewDuration(100 * time.Millisecond)
+		conf.PDServerCfg.HotStatsMaxStaleness = typeutil.NewDuration(time.Nanosecond)
+	})
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+	c.Assert(cluster.RunInitialServers(), IsNil)
+	leader := cluster.GetServer(cluster.WaitLeader())
+	c.Assert(leader.BootstrapCluster(), IsNil)
+	var follower *server.Server
+	for _, svr := range cluster.GetServers() {
+		if svr != leader {
+			follower = svr.GetServer()
+		}
+	}
+
+	// The follower refuses to serve the stale statistics, while the leader
+	// serves its own.
+	for _, addr := range []string{follower.GetAddr(), leader.GetAddr()} {
+		request, err := http.NewRequest("GET", addr+"/pd/api/v1/hotspot/regions/write", nil)
+		c.Assert(err, IsNil)
+		request.Header.Add(serverapi.AllowFollowerHandle, "true")
+		resp, err := dialClient.Do(request)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		if addr == follower.GetAddr() {
+			c.Assert(resp.StatusCode, Equals, http.StatusServiceUnavailable)
+		} else {
+			c.Assert(resp.StatusCode, Equals, http.StatusOK)
+		}
+	}
+}
+
 var _ = Suite(&testRedirectorSuite{})
 
 type testRedirectorSuite struct {
@@ -322,6 +322,42 @@ func testRemoveStore(c *C, clusterID uint64, rc *cluster.RaftCluster, grpcPDClie
 	}
 }
 
+func (s *clusterTestSuite) TestArchivedStore(c *C) {
+	tc, err := tests.NewTestCluster(s.ctx, 1)
+	defer tc.Destroy()
+	c.Assert(err, IsNil)
+	err = tc.RunInitialServers()
+	c.Assert(err, IsNil)
+	tc.WaitLeader()
+	leaderServer := tc.GetServer(tc.GetLeader())
+	grpcPDClient := testutil.MustNewGrpcClient(c, leaderServer.GetAddr())
+	clusterID := leaderServer.GetClusterID()
+	bootstrapCluster(c, clusterID, grpcPDClient, "127.0.0.1:0")
+
+	store := newMetaStore(100, "127.0.0.1:4", "4.0.0", metapb.StoreState_Tombstone, "")
+	archived := &core.ArchivedStore{Store: store, TombstoneTime: time.Now(), ArchiveTime: time.Now()}
+	c.Assert(leaderServer.GetServer().GetStorage().SaveArchivedStore(archived), IsNil)
+
+	// An archived store can not be put back or send heartbeats.
+	up := newMetaStore(100, "127.0.0.1:4", "4.0.0", metapb.StoreState_Up, "")
+	resp, err := putStore(c, grpcPDClient, clusterID, up)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetHeader().GetError().GetType(), Equals, pdpb.ErrorType_STORE_TOMBSTONE)
+	c.Assert(leaderServer.GetRaftCluster().GetStore(100), IsNil)
+	req := &pdpb.StoreHeartbeatRequest{
+		Header: testutil.NewRequestHeader(clusterID),
+		Stats:  &pdpb.StoreStats{StoreId: 100},
+	}
+	hbResp, err := grpcPDClient.StoreHeartbeat(context.Background(), req)
+	c.Assert(err, IsNil)
+	c.Assert(hbResp.GetHeader().GetError().GetType(), Equals, pdpb.ErrorType_STORE_TOMBSTONE)
+
+	// A new store is not affected.
+	resp, err = putStore(c, grpcPDClient, clusterID, newMetaStore(101, "127.0.0.1:5", "4.0.0", metapb.StoreState_Up, ""))
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetHeader().GetError(), IsNil)
+}
+
 // Make sure PD will not panic if it start and stop again and again.
 func (s *clusterTestSuite) TestRaftClusterRestart(c *C) {
 	tc, err := tests.NewTestCluster(s.ctx, 1)
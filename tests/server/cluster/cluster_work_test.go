@@ -16,15 +16,20 @@ package cluster_test
 import (
 	"context"
 	"sort"
+	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/kvproto/pkg/replication_modepb"
+	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/tests"
+	"google.golang.org/grpc"
 )
 
 var _ = Suite(&clusterWorkerTestSuite{})
@@ -84,6 +89,114 @@ func (s *clusterWorkerTestSuite) TestValidRequestRegion(c *C) {
 	rc.Stop()
 }
 
+func (s *clusterWorkerTestSuite) TestBulkRegionHeartbeat(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 1)
+	defer cluster.Destroy()
+	c.Assert(err, IsNil)
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+
+	cluster.WaitLeader()
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	grpcPDClient := testutil.MustNewGrpcClient(c, leaderServer.GetAddr())
+	clusterID := leaderServer.GetClusterID()
+	bootstrapCluster(c, clusterID, grpcPDClient, "127.0.0.1:0")
+	rc := leaderServer.GetRaftCluster()
+
+	conn, err := grpc.Dial(strings.TrimPrefix(leaderServer.GetAddr(), "http://"), grpc.WithInsecure())
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	stream, err := conn.NewStream(ctx, grpcutil.RegionHeartbeatsStreamDesc, grpcutil.RegionHeartbeatsStreamMethod)
+	c.Assert(err, IsNil)
+
+	// Split the bootstrapped region, and report an invalid region and a
+	// region of another cluster in the same batch.
+	leader := &metapb.Peer{Id: 3, StoreId: 1}
+	newLeader := &metapb.Peer{Id: 11, StoreId: 1}
+	batch := &grpcutil.RegionHeartbeatBatch{
+		Requests: []*pdpb.RegionHeartbeatRequest{
+			{
+				Header: testutil.NewRequestHeader(clusterID + 1),
+				Region: &metapb.Region{Id: 20, StartKey: []byte("b"), Peers: []*metapb.Peer{leader}, RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 2}},
+				Leader: leader,
+			},
+			{
+				Header: testutil.NewRequestHeader(clusterID),
+				Region: &metapb.Region{Id: 2, EndKey: []byte("a"), Peers: []*metapb.Peer{leader}, RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 2}},
+				Leader: leader,
+			},
+			{
+				Header: testutil.NewRequestHeader(clusterID),
+				Region: &metapb.Region{Id: 10, StartKey: []byte("a"), Peers: []*metapb.Peer{newLeader}, RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 2}},
+				Leader: newLeader,
+			},
+			{
+				Header: testutil.NewRequestHeader(clusterID),
+				Region: &metapb.Region{Peers: []*metapb.Peer{leader}},
+				Leader: leader,
+			},
+		},
+	}
+	c.Assert(stream.SendMsg(batch), IsNil)
+
+	resp := &pdpb.RegionHeartbeatResponse{}
+	c.Assert(stream.RecvMsg(resp), IsNil)
+	c.Assert(resp.GetRegionId(), Equals, uint64(20))
+	c.Assert(resp.GetHeader().GetError().GetMessage(), Matches, ".*mismatch cluster id.*")
+	resp = &pdpb.RegionHeartbeatResponse{}
+	c.Assert(stream.RecvMsg(resp), IsNil)
+	c.Assert(resp.GetHeader().GetError(), NotNil)
+	c.Assert(resp.GetHeader().GetError().GetMessage(), Matches, "invalid request region.*")
+	testutil.WaitUntil(c, func(c *C) bool {
+		return rc.GetRegion(10) != nil && string(rc.GetRegion(2).GetEndKey()) == "a"
+	})
+	c.Assert(rc.GetRegionCount(), Equals, 2)
+}
+
+func (s *clusterWorkerTestSuite) TestWatchReplicationStatus(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 1)
+	defer cluster.Destroy()
+	c.Assert(err, IsNil)
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+
+	cluster.WaitLeader()
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	grpcPDClient := testutil.MustNewGrpcClient(c, leaderServer.GetAddr())
+	clusterID := leaderServer.GetClusterID()
+	bootstrapCluster(c, clusterID, grpcPDClient, "127.0.0.1:0")
+
+	conn, err := grpc.Dial(strings.TrimPrefix(leaderServer.GetAddr(), "http://"), grpc.WithInsecure())
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	stream, err := conn.NewStream(ctx, grpcutil.WatchReplicationStatusStreamDesc, grpcutil.WatchReplicationStatusStreamMethod)
+	c.Assert(err, IsNil)
+	c.Assert(stream.SendMsg(&pdpb.GetClusterConfigRequest{Header: testutil.NewRequestHeader(clusterID)}), IsNil)
+	c.Assert(stream.CloseSend(), IsNil)
+
+	resp := &pdpb.StoreHeartbeatResponse{}
+	c.Assert(stream.RecvMsg(resp), IsNil)
+	c.Assert(resp.GetHeader().GetError(), IsNil)
+	c.Assert(resp.GetReplicationStatus().GetMode(), Equals, replication_modepb.ReplicationMode_MAJORITY)
+
+	cfg := leaderServer.GetServer().GetReplicationModeConfig().Clone()
+	cfg.ReplicationMode = "dr-auto-sync"
+	cfg.DRAutoSync = config.DRAutoSyncReplicationConfig{LabelKey: "zone", Primary: "z1", DR: "z2"}
+	c.Assert(leaderServer.GetServer().SetReplicationModeConfig(*cfg), IsNil)
+	resp = &pdpb.StoreHeartbeatResponse{}
+	c.Assert(stream.RecvMsg(resp), IsNil)
+	status := resp.GetReplicationStatus()
+	c.Assert(status.GetMode(), Equals, replication_modepb.ReplicationMode_DR_AUTO_SYNC)
+	c.Assert(status.GetDrAutoSync().GetLabelKey(), Equals, "zone")
+	c.Assert(status.GetDrAutoSync().GetState(), Equals, replication_modepb.DRAutoSyncState_SYNC_RECOVER)
+}
+
 func (s *clusterWorkerTestSuite) TestAskSplit(c *C) {
 	cluster, err := tests.NewTestCluster(s.ctx, 1)
 	defer cluster.Destroy()
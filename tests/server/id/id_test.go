@@ -17,13 +17,17 @@ import (
 	"context"
 	"sync"
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/tests"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func Test(t *testing.T) {
@@ -117,6 +121,94 @@ func (s *testAllocIDSuite) TestCommand(c *C) {
 	}
 }
 
+func (s *testAllocIDSuite) TestAllocIDRange(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 1)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	cluster.WaitLeader()
+
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	allocator := leaderServer.GetAllocator()
+	last, err := allocator.Alloc()
+	c.Assert(err, IsNil)
+	for _, count := range []uint64{10, 5 * allocStep, 1} {
+		start, err := allocator.AllocRange(count)
+		c.Assert(err, IsNil)
+		c.Assert(start, Greater, last)
+		last = start + count - 1
+	}
+	id, err := allocator.Alloc()
+	c.Assert(err, IsNil)
+	c.Assert(id, Greater, last)
+
+	// Lease a range through gRPC.
+	cc, err := grpcutil.GetClientConn(s.ctx, leaderServer.GetAddr(), nil)
+	c.Assert(err, IsNil)
+	defer cc.Close()
+	req := &pdpb.AllocIDRequest{Header: testutil.NewRequestHeader(leaderServer.GetClusterID())}
+	resp := &pdpb.AllocIDResponse{}
+	ctx := grpcutil.BuildIDRangeContext(s.ctx, 2*allocStep, time.Minute)
+	c.Assert(cc.Invoke(ctx, grpcutil.AllocIDRangeMethod, req, resp), IsNil)
+	c.Assert(resp.GetId(), Greater, id)
+	reservations, err := leaderServer.GetServer().GetIDReservations()
+	c.Assert(err, IsNil)
+	c.Assert(reservations, HasLen, 1)
+	c.Assert(reservations[0].Start, Equals, resp.GetId())
+	c.Assert(reservations[0].End, Equals, resp.GetId()+2*allocStep)
+
+	// The count and ttl are required.
+	err = cc.Invoke(s.ctx, grpcutil.AllocIDRangeMethod, req, resp)
+	c.Assert(status.Code(err), Equals, codes.InvalidArgument)
+
+	// The range is never allocated again after restart.
+	c.Assert(leaderServer.Stop(), IsNil)
+	c.Assert(leaderServer.Run(), IsNil)
+	cluster.WaitLeader()
+	id, err = leaderServer.GetAllocator().Alloc()
+	c.Assert(err, IsNil)
+	c.Assert(id, GreaterEqual, reservations[0].End)
+}
+
+func (s *testAllocIDSuite) TestAllocIDRangeForwarded(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 2)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	cluster.WaitLeader()
+
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	var follower *tests.TestServer
+	for _, s := range cluster.GetServers() {
+		if s != leaderServer {
+			follower = s
+		}
+	}
+	cc, err := grpcutil.GetClientConn(s.ctx, follower.GetAddr(), nil)
+	c.Assert(err, IsNil)
+	defer cc.Close()
+	req := &pdpb.AllocIDRequest{Header: testutil.NewRequestHeader(leaderServer.GetClusterID())}
+	resp := &pdpb.AllocIDResponse{}
+	ctx := grpcutil.BuildIDRangeContext(s.ctx, 10, time.Minute)
+	// The follower rejects the request, unless it is forwarded to the leader.
+	c.Assert(cc.Invoke(ctx, grpcutil.AllocIDRangeMethod, req, resp), NotNil)
+	// BuildForwardContext replaces the metadata, so it goes first.
+	ctx = grpcutil.BuildIDRangeContext(grpcutil.BuildForwardContext(s.ctx, leaderServer.GetAddr()), 10, time.Minute)
+	c.Assert(cc.Invoke(ctx, grpcutil.AllocIDRangeMethod, req, resp), IsNil)
+	reservations, err := leaderServer.GetServer().GetIDReservations()
+	c.Assert(err, IsNil)
+	c.Assert(reservations, HasLen, 1)
+	c.Assert(reservations[0].Start, Equals, resp.GetId())
+
+	// The cluster ID is validated by the leader.
+	req.Header.ClusterId++
+	c.Assert(cc.Invoke(ctx, grpcutil.AllocIDRangeMethod, req, resp), NotNil)
+}
+
 func (s *testAllocIDSuite) TestMonotonicID(c *C) {
 	cluster, err := tests.NewTestCluster(s.ctx, 2)
 	c.Assert(err, IsNil)
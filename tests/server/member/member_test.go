@@ -28,11 +28,13 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/tikv/pd/pkg/etcdutil"
+	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/tests"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc/metadata"
 )
 
 func Test(t *testing.T) {
@@ -189,6 +191,45 @@ func (s *serverTestSuite) TestLeaderPriority(c *C) {
 	})
 }
 
+func (s *serverTestSuite) TestRoutingDomain(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 2, func(conf *config.Config, serverName string) {
+		if serverName == "pd1" {
+			conf.AdvertiseClientUrlsByDomain = map[string]string{"operator": "http://pd1.operator:2379"}
+		}
+	})
+	defer cluster.Destroy()
+	c.Assert(err, IsNil)
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	leader := cluster.GetServer(cluster.WaitLeader())
+
+	getMembers := func(domain string) map[string]*pdpb.Member {
+		ctx := s.ctx
+		if domain != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(grpcutil.RoutingDomainMetadataKey, domain))
+		}
+		resp, err := leader.GetServer().GetMembers(ctx, &pdpb.GetMembersRequest{})
+		c.Assert(err, IsNil)
+		members := make(map[string]*pdpb.Member)
+		for _, m := range resp.GetMembers() {
+			members[m.GetName()] = m
+		}
+		return members
+	}
+
+	pd1URL := cluster.GetServer("pd1").GetConfig().AdvertiseClientUrls
+	pd2URL := cluster.GetServer("pd2").GetConfig().AdvertiseClientUrls
+	members := getMembers("")
+	c.Assert(members["pd1"].GetClientUrls(), DeepEquals, []string{pd1URL})
+	c.Assert(members["pd2"].GetClientUrls(), DeepEquals, []string{pd2URL})
+	members = getMembers("operator")
+	c.Assert(members["pd1"].GetClientUrls(), DeepEquals, []string{"http://pd1.operator:2379"})
+	c.Assert(members["pd2"].GetClientUrls(), DeepEquals, []string{pd2URL})
+	members = getMembers("unknown")
+	c.Assert(members["pd1"].GetClientUrls(), DeepEquals, []string{pd1URL})
+}
+
 func (s *serverTestSuite) post(c *C, url string, body string) {
 	testutil.WaitUntil(c, func(c *C) bool {
 		res, err := http.Post(url, "", bytes.NewBufferString(body))
@@ -238,6 +279,59 @@ func (s *serverTestSuite) TestLeaderResign(c *C) {
 	c.Assert(leader3, Equals, leader1)
 }
 
+func (s *serverTestSuite) TestRollingRestart(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 2)
+	defer cluster.Destroy()
+	c.Assert(err, IsNil)
+	c.Assert(cluster.RunInitialServers(), IsNil)
+	leader := cluster.WaitLeader()
+
+	restarted := make(chan string, 2)
+	for _, svr := range cluster.GetServers() {
+		svr.GetServer().SetRollingRestartHook(func(_ context.Context, m *pdpb.Member) error {
+			target := cluster.GetServer(m.GetName())
+			if err := target.Stop(); err != nil {
+				return err
+			}
+			// The start timestamp is in seconds.
+			time.Sleep(time.Second)
+			if err := target.Run(); err != nil {
+				return err
+			}
+			restarted <- m.GetName()
+			return nil
+		})
+	}
+	c.Assert(cluster.GetServer(leader).GetServer().StartRollingRestart(30*time.Second), IsNil)
+
+	// The follower is restarted first, then the leader is restarted by the
+	// next leader, which resumes the persisted plan.
+	var order []string
+	for i := 0; i < 2; i++ {
+		select {
+		case name := <-restarted:
+			order = append(order, name)
+		case <-time.After(time.Minute):
+			c.Fatal("the members are not restarted in time")
+		}
+	}
+	c.Assert(order[1], Equals, leader)
+	var status *server.RollingRestartStatus
+	testutil.WaitUntil(c, func(c *C) bool {
+		newLeader := cluster.GetServer(cluster.GetLeader())
+		if newLeader == nil {
+			return false
+		}
+		status = newLeader.GetServer().GetRollingRestartStatus()
+		return status != nil && status.State == server.RollingRestartFinished
+	}, testutil.WithRetryTimes(60), testutil.WithSleepInterval(time.Second))
+	c.Assert(status.Members, HasLen, 2)
+	for _, m := range status.Members {
+		c.Assert(m.State, Equals, server.RollingRestartMemberDone)
+	}
+	c.Assert(status.Members[1].Name, Equals, leader)
+}
+
 func (s *serverTestSuite) waitLeaderChange(c *C, cluster *tests.TestCluster, old string) string {
 	var leader string
 	testutil.WaitUntil(c, func(c *C) bool {
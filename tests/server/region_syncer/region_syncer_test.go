@@ -15,16 +15,22 @@ package syncer_test
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
 	"testing"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/pkg/apiutil/serverapi"
 	"github.com/tikv/pd/pkg/mock/mockid"
 	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/api"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
+	syncer "github.com/tikv/pd/server/region_syncer"
 	"github.com/tikv/pd/tests"
 	"go.uber.org/goleak"
 )
@@ -225,3 +231,130 @@ func (s *serverTestSuite) TestFullSyncWithAddMember(c *C) {
 	loadRegions := pd2.GetServer().GetRaftCluster().GetRegions()
 	c.Assert(len(loadRegions), Equals, regionLen)
 }
+
+func (s *serverTestSuite) TestSnapshotSyncAfterRestart(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 3, func(conf *config.Config, serverName string) { conf.PDServerCfg.UseRegionStorage = true })
+	defer cluster.Destroy()
+	c.Assert(err, IsNil)
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	cluster.WaitLeader()
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	c.Assert(leaderServer.BootstrapCluster(), IsNil)
+	rc := leaderServer.GetServer().GetRaftCluster()
+	allocator := &idAllocator{allocator: mockid.NewIDAllocator()}
+	newRegion := func(i int) *core.RegionInfo {
+		r := &metapb.Region{
+			Id:          allocator.alloc(),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+			StartKey:    []byte{byte(i)},
+			EndKey:      []byte{byte(i + 1)},
+			Peers:       []*metapb.Peer{{Id: allocator.alloc(), StoreId: uint64(0)}},
+		}
+		return core.NewRegionInfo(r, r.Peers[0])
+	}
+	regions := make([]*core.RegionInfo, 0, 20)
+	for i := 0; i < 20; i++ {
+		region := newRegion(i)
+		c.Assert(rc.HandleRegionHeartbeat(region), IsNil)
+		regions = append(regions, region)
+	}
+	followerServer := cluster.GetServer(cluster.GetFollower())
+	testutil.WaitUntil(c, func(c *C) bool {
+		return followerServer.GetServer().GetBasicCluster().GetRegionCount() == len(regions)
+	})
+
+	// The leader changes more regions than its history keeps while the
+	// follower is down.
+	c.Assert(followerServer.Stop(), IsNil)
+	region := regions[5]
+	for i := 0; i <= 10000; i++ {
+		region = region.Clone(core.WithIncConfVer())
+		c.Assert(rc.HandleRegionHeartbeat(region), IsNil)
+	}
+	added := newRegion(20)
+	c.Assert(rc.HandleRegionHeartbeat(added), IsNil)
+
+	// The follower catches up with the regions of the leader after restart.
+	c.Assert(followerServer.Run(), IsNil)
+	testutil.WaitUntil(c, func(c *C) bool {
+		bc := followerServer.GetServer().GetBasicCluster()
+		synced := bc.GetRegion(region.GetID())
+		return bc.GetRegionCount() == len(regions)+1 && bc.GetRegion(added.GetID()) != nil &&
+			synced != nil && synced.GetRegionEpoch().GetConfVer() == region.GetRegionEpoch().GetConfVer()
+	})
+}
+
+func (s *serverTestSuite) TestVerify(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 2, func(conf *config.Config, serverName string) {
+		conf.PDServerCfg.UseRegionStorage = true
+		conf.PDServerCfg.RegionSyncerVerifyInterval = typeutil.NewDuration(100 * time.Millisecond)
+	})
+	defer cluster.Destroy()
+	c.Assert(err, IsNil)
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	cluster.WaitLeader()
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	c.Assert(leaderServer.BootstrapCluster(), IsNil)
+	rc := leaderServer.GetServer().GetRaftCluster()
+	allocator := &idAllocator{allocator: mockid.NewIDAllocator()}
+	regions := make([]*core.RegionInfo, 0, 20)
+	for i := 0; i < 20; i++ {
+		r := &metapb.Region{
+			Id:          allocator.alloc(),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+			StartKey:    []byte{byte(i)},
+			EndKey:      []byte{byte(i + 1)},
+			Peers:       []*metapb.Peer{{Id: allocator.alloc(), StoreId: uint64(0)}},
+		}
+		region := core.NewRegionInfo(r, r.Peers[0])
+		c.Assert(rc.HandleRegionHeartbeat(region), IsNil)
+		regions = append(regions, region)
+	}
+	followerServer := cluster.GetServer(cluster.GetFollower())
+
+	// The follower verifies the synced regions periodically.
+	testutil.WaitUntil(c, func(c *C) bool {
+		var report syncer.VerifyReport
+		code := doVerifyRequest(c, http.MethodGet, followerServer.GetAddr()+"/pd/api/v1/admin/region-syncer/verify/self", true, &report)
+		return code == http.StatusOK && report.LeaderCount == rc.GetRegionCount() &&
+			report.FollowerCount == report.LeaderCount && len(report.Divergences) == 0
+	})
+
+	// The divergent regions are reported.
+	followerServer.GetServer().GetBasicCluster().RemoveRegion(regions[5])
+	var results []api.RegionSyncerVerifyResult
+	code := doVerifyRequest(c, http.MethodPost, leaderServer.GetAddr()+"/pd/api/v1/admin/region-syncer/verify", false, &results)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(results, HasLen, 1)
+	c.Assert(results[0].Name, Equals, followerServer.GetConfig().Name)
+	c.Assert(results[0].Error, Equals, "")
+	report := results[0].Report
+	c.Assert(report.FollowerCount, Equals, report.LeaderCount-1)
+	c.Assert(report.Divergences, HasLen, 1)
+	c.Assert(report.Divergences[0].Regions, HasLen, 1)
+	c.Assert(report.Divergences[0].Regions[0].ID, Equals, regions[5].GetID())
+	c.Assert(report.Divergences[0].Regions[0].Follower, IsNil)
+
+	// The leader does not verify itself.
+	code = doVerifyRequest(c, http.MethodPost, leaderServer.GetAddr()+"/pd/api/v1/admin/region-syncer/verify/self", true, nil)
+	c.Assert(code, Equals, http.StatusInternalServerError)
+}
+
+func doVerifyRequest(c *C, method, url string, self bool, res interface{}) int {
+	req, err := http.NewRequest(method, url, nil)
+	c.Assert(err, IsNil)
+	if self {
+		req.Header.Set(serverapi.AllowFollowerHandle, "true")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && res != nil {
+		c.Assert(json.NewDecoder(resp.Body).Decode(res), IsNil)
+	}
+	return resp.StatusCode
+}
@@ -18,12 +18,16 @@ import (
 	"testing"
 
 	. "github.com/pingcap/check"
+	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/tempurl"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/tests"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 
 	// Register schedulers.
 	_ "github.com/tikv/pd/server/schedulers"
@@ -142,3 +146,72 @@ func (s *serverTestSuite) TestLeader(c *C) {
 		return leader != leader1
 	})
 }
+
+func (s *serverTestSuite) TestHealthService(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 2)
+	defer cluster.Destroy()
+	c.Assert(err, IsNil)
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	leaderName := cluster.WaitLeader()
+	c.Assert(leaderName, Not(Equals), "")
+	leader := cluster.GetServer(leaderName)
+	var follower *tests.TestServer
+	for _, svr := range cluster.GetServers() {
+		if svr != leader {
+			follower = svr
+		}
+	}
+
+	check := func(svr *tests.TestServer, service string) healthpb.HealthCheckResponse_ServingStatus {
+		cc, err := grpcutil.GetClientConn(s.ctx, svr.GetAddr(), nil)
+		c.Assert(err, IsNil)
+		defer cc.Close()
+		resp := &healthpb.HealthCheckResponse{}
+		err = cc.Invoke(s.ctx, grpcutil.HealthCheckMethod, &healthpb.HealthCheckRequest{Service: service}, resp)
+		c.Assert(err, IsNil)
+		return resp.GetStatus()
+	}
+	waitStatus := func(svr *tests.TestServer, service string, status healthpb.HealthCheckResponse_ServingStatus) {
+		testutil.WaitUntil(c, func(c *C) bool {
+			return check(svr, service) == status
+		})
+	}
+
+	// The standard health service is the one of etcd, which does not know the
+	// services of PD.
+	cc, err := grpcutil.GetClientConn(s.ctx, follower.GetAddr(), nil)
+	c.Assert(err, IsNil)
+	defer cc.Close()
+	resp, err := healthpb.NewHealthClient(cc).Check(s.ctx, &healthpb.HealthCheckRequest{})
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetStatus(), Equals, healthpb.HealthCheckResponse_SERVING)
+	_, err = healthpb.NewHealthClient(cc).Check(s.ctx, &healthpb.HealthCheckRequest{Service: grpcutil.HealthServiceLeader})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	waitStatus(leader, grpcutil.HealthServiceLeader, healthpb.HealthCheckResponse_SERVING)
+	waitStatus(leader, grpcutil.HealthServiceTSO, healthpb.HealthCheckResponse_SERVING)
+	c.Assert(check(leader, grpcutil.HealthServiceBootstrapped), Equals, healthpb.HealthCheckResponse_NOT_SERVING)
+	c.Assert(check(leader, grpcutil.HealthServiceReady), Equals, healthpb.HealthCheckResponse_NOT_SERVING)
+	waitStatus(follower, grpcutil.HealthServiceLeader, healthpb.HealthCheckResponse_NOT_SERVING)
+	c.Assert(check(follower, grpcutil.HealthServiceReady), Equals, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	c.Assert(leader.BootstrapCluster(), IsNil)
+	waitStatus(leader, grpcutil.HealthServiceReady, healthpb.HealthCheckResponse_SERVING)
+	c.Assert(check(leader, grpcutil.HealthServiceBootstrapped), Equals, healthpb.HealthCheckResponse_SERVING)
+
+	// The dependencies.
+	for _, service := range []string{
+		grpcutil.HealthServiceEtcd,
+		grpcutil.HealthServiceStorage,
+		grpcutil.HealthServiceIDAllocator,
+		grpcutil.HealthServiceTSOPersistence,
+	} {
+		waitStatus(leader, service, healthpb.HealthCheckResponse_SERVING)
+	}
+	waitStatus(follower, grpcutil.HealthServiceEtcd, healthpb.HealthCheckResponse_SERVING)
+	waitStatus(follower, grpcutil.HealthServiceStorage, healthpb.HealthCheckResponse_SERVING)
+	c.Assert(check(follower, grpcutil.HealthServiceIDAllocator), Equals, healthpb.HealthCheckResponse_SERVICE_UNKNOWN)
+	c.Assert(check(follower, grpcutil.HealthServiceTSOPersistence), Equals, healthpb.HealthCheckResponse_SERVICE_UNKNOWN)
+}
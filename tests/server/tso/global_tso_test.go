@@ -15,6 +15,8 @@ package tso_test
 
 import (
 	"context"
+	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -23,9 +25,11 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/tikv/pd/pkg/etcdutil"
 	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/pkg/tsoutil"
+	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/tso"
@@ -250,6 +254,89 @@ func (s *testNormalGlobalTSOSuite) TestRequestFollower(c *C) {
 	c.Assert(time.Since(start), Less, time.Second)
 }
 
+func (s *testNormalGlobalTSOSuite) TestMaxConcurrentTSOProxyStreamings(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 2, func(conf *config.Config, serverName string) {
+		conf.PDServerCfg.MaxConcurrentTSOProxyStreamings = 1
+	})
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	leaderServer := cluster.GetServer(cluster.WaitLeader())
+	var followerServer *tests.TestServer
+	for _, s := range cluster.GetServers() {
+		if s.GetConfig().Name != cluster.GetLeader() {
+			followerServer = s
+		}
+	}
+	c.Assert(followerServer, NotNil)
+
+	grpcPDClient := testutil.MustNewGrpcClient(c, followerServer.GetAddr())
+	req := &pdpb.TsoRequest{
+		Header:     testutil.NewRequestHeader(followerServer.GetClusterID()),
+		Count:      1,
+		DcLocation: tso.GlobalDCLocation,
+	}
+	requestTSO := func(ctx context.Context) error {
+		tsoClient, err := grpcPDClient.Tso(grpcutil.BuildForwardContext(ctx, leaderServer.GetAddr()))
+		c.Assert(err, IsNil)
+		c.Assert(tsoClient.Send(req), IsNil)
+		_, err = tsoClient.Recv()
+		return err
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	c.Assert(requestTSO(ctx1), IsNil)
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	err = requestTSO(ctx2)
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(err.Error(), "too many tso proxy streams"), IsTrue)
+
+	// The stream can be forwarded after the former one is closed.
+	cancel1()
+	testutil.WaitUntil(c, func(c *C) bool {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		return requestTSO(ctx) == nil
+	})
+}
+
+func (s *testNormalGlobalTSOSuite) TestAdaptiveSaveInterval(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 1, func(conf *config.Config, serverName string) {
+		conf.TSOSaveInterval = typeutil.NewDuration(200 * time.Millisecond)
+		conf.TSOMaxSaveInterval = typeutil.NewDuration(10 * time.Second)
+		conf.TSOMaxSaveIntervalQPS = 1
+	})
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	leaderServer := cluster.GetServer(cluster.WaitLeader())
+	timestampPath := path.Join("/pd", strconv.FormatUint(leaderServer.GetClusterID(), 10), "timestamp")
+	loadSavedTime := func() time.Time {
+		data, err := etcdutil.GetValue(leaderServer.GetEtcdClient(), timestampPath)
+		c.Assert(err, IsNil)
+		saved, err := typeutil.ParseTimestamp(data)
+		c.Assert(err, IsNil)
+		return saved
+	}
+
+	grpcPDClient := testutil.MustNewGrpcClient(c, leaderServer.GetAddr())
+	req := &pdpb.TsoRequest{
+		Header:     testutil.NewRequestHeader(leaderServer.GetClusterID()),
+		Count:      1,
+		DcLocation: tso.GlobalDCLocation,
+	}
+	// The saved time window grows to the max one under the TSO requests.
+	testutil.WaitUntil(c, func(c *C) bool {
+		s.testGetNormalGlobalTimestamp(c, grpcPDClient, req)
+		return time.Until(loadSavedTime()) > 5*time.Second
+	})
+}
+
 // In some cases, when a TSO request arrives, the SyncTimestamp may not finish yet.
 // This test is used to simulate this situation and verify that the retry mechanism.
 func (s *testNormalGlobalTSOSuite) TestDelaySyncTimestamp(c *C) {
@@ -570,3 +657,39 @@ func (s *testSynchronizedGlobalTSO) TestSynchronizedGlobalTSOOverflow(c *C) {
 	s.testGetTimestamp(ctx, c, cluster, tsoCount, tso.GlobalDCLocation)
 	failpoint.Disable("github.com/tikv/pd/server/tso/globalTSOOverflow")
 }
+
+func (s *testNormalGlobalTSOSuite) TestLeaseAfterLeaderChange(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 2)
+	c.Assert(err, IsNil)
+	defer cluster.Destroy()
+	c.Assert(cluster.RunInitialServers(), IsNil)
+	leader := cluster.GetServer(cluster.WaitLeader())
+	c.Assert(leader, NotNil)
+
+	lease, err := leader.GetTSOAllocatorManager().GrantTSOLease("", 10, time.Minute)
+	c.Assert(err, IsNil)
+
+	c.Assert(cluster.ResignLeader(), IsNil)
+	newLeader := cluster.GetServer(cluster.WaitLeader())
+	c.Assert(newLeader, NotNil)
+	c.Assert(newLeader.GetConfig().Name, Not(Equals), leader.GetConfig().Name)
+	am := newLeader.GetTSOAllocatorManager()
+	leases, err := am.GetTSOLeases()
+	c.Assert(err, IsNil)
+	c.Assert(leases, HasLen, 1)
+	c.Assert(leases[0].ID, Equals, lease.ID)
+	c.Assert(leases[0].Start, Equals, lease.Start)
+	min, err := am.GetMinLeasedTS()
+	c.Assert(err, IsNil)
+	c.Assert(min.Start, Equals, lease.Start)
+
+	revoked, err := am.RevokeTSOLease(lease.ID)
+	c.Assert(err, IsNil)
+	c.Assert(revoked, IsTrue)
+	revoked, err = am.RevokeTSOLease(lease.ID)
+	c.Assert(err, IsNil)
+	c.Assert(revoked, IsFalse)
+	leases, err = am.GetTSOLeases()
+	c.Assert(err, IsNil)
+	c.Assert(leases, HasLen, 0)
+}
@@ -16,6 +16,7 @@ package command
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -42,6 +43,8 @@ var (
 	ruleGroupsPrefix      = "pd/api/v1/config/rule_groups"
 	replicationModePrefix = "pd/api/v1/config/replication-mode"
 	ruleBundlePrefix      = "pd/api/v1/config/placement-rule"
+	configHistoryPrefix   = "pd/api/v1/config/history"
+	configRollbackPrefix  = "pd/api/v1/config/rollback"
 )
 
 // NewConfigCommand return a config subcommand of rootCmd
@@ -54,6 +57,8 @@ func NewConfigCommand() *cobra.Command {
 	conf.AddCommand(NewSetConfigCommand())
 	conf.AddCommand(NewDeleteConfigCommand())
 	conf.AddCommand(NewPlacementRulesCommand())
+	conf.AddCommand(newConfigHistoryCommand())
+	conf.AddCommand(newConfigRollbackCommand())
 	return conf
 }
 
@@ -131,6 +136,24 @@ func newShowReplicationModeCommand() *cobra.Command {
 	}
 }
 
+func newConfigHistoryCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "history [<version>]",
+		Short: "show the versions of the config with who changes what, or the content of a version",
+		Run:   showConfigHistoryCommandFunc,
+	}
+	c.Flags().Int("limit", 0, "the maximum number of the versions to show")
+	return c
+}
+
+func newConfigRollbackCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rollback <version>",
+		Short: "roll the config back to a version, except the schedulers and the cluster version",
+		Run:   rollbackConfigCommandFunc,
+	}
+}
+
 // NewSetConfigCommand return a set subcommand of configCmd
 func NewSetConfigCommand() *cobra.Command {
 	sc := &cobra.Command{
@@ -285,6 +308,38 @@ func showReplicationModeCommandFunc(cmd *cobra.Command, args []string) {
 	cmd.Println(r)
 }
 
+func showConfigHistoryCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	prefix := configHistoryPrefix
+	if len(args) == 1 {
+		prefix = path.Join(prefix, args[0])
+	} else if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 {
+		prefix = fmt.Sprintf("%s?limit=%d", prefix, limit)
+	}
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		cmd.Printf("Failed to get config history: %s\n", err)
+		return
+	}
+	cmd.Println(r)
+}
+
+func rollbackConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	_, err := doRequest(cmd, path.Join(configRollbackPrefix, args[0]), http.MethodPost)
+	if err != nil {
+		cmd.Printf("Failed to roll back config: %s\n", err)
+		return
+	}
+	cmd.Println("Success!")
+}
+
 func postConfigDataWithPath(cmd *cobra.Command, key, value, path string) error {
 	var val interface{}
 	data := make(map[string]interface{})
@@ -0,0 +1,62 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	keyLayoutPrefix = "pd/api/v1/admin/key-layout"
+)
+
+// NewKeyLayoutCommand return a key-layout subcommand of rootCmd
+func NewKeyLayoutCommand() *cobra.Command {
+	l := &cobra.Command{
+		Use:   "key-layout",
+		Short: "check the keys of the cluster in etcd for the unknown and orphan keys",
+		Run:   showKeyLayoutCommandFunc,
+	}
+	l.AddCommand(NewCleanupOrphanKeysCommand())
+	return l
+}
+
+// NewCleanupOrphanKeysCommand return a subcommand to remove the orphan keys
+func NewCleanupOrphanKeysCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cleanup",
+		Short: "remove the orphan keys, the unknown keys are kept",
+		Run:   cleanupOrphanKeysCommandFunc,
+	}
+}
+
+func showKeyLayoutCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, keyLayoutPrefix, http.MethodGet)
+	if err != nil {
+		cmd.Printf("Failed to check the key layout: %s\n", err)
+		return
+	}
+	cmd.Println(r)
+}
+
+func cleanupOrphanKeysCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, keyLayoutPrefix+"/orphans", http.MethodDelete)
+	if err != nil {
+		cmd.Printf("Failed to remove the orphan keys: %s\n", err)
+		return
+	}
+	cmd.Println(r)
+}
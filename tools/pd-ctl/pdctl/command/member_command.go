@@ -23,14 +23,15 @@ import (
 )
 
 var (
-	membersPrefix      = "pd/api/v1/members"
-	leaderMemberPrefix = "pd/api/v1/leader"
+	membersPrefix        = "pd/api/v1/members"
+	membersMetricsPrefix = "pd/api/v1/members/metrics"
+	leaderMemberPrefix   = "pd/api/v1/leader"
 )
 
 // NewMemberCommand return a member subcommand of rootCmd
 func NewMemberCommand() *cobra.Command {
 	m := &cobra.Command{
-		Use:   "member [leader|delete|leader_priority]",
+		Use:   "member [leader|delete|leader_priority|metrics]",
 		Short: "show the pd member status",
 		Run:   showMemberCommandFunc,
 	}
@@ -42,6 +43,11 @@ func NewMemberCommand() *cobra.Command {
 		Short: "set the member's priority to be elected as etcd leader",
 		Run:   setLeaderPriorityFunc,
 	})
+	m.AddCommand(&cobra.Command{
+		Use:   "metrics",
+		Short: "show the resource usage of all pd members",
+		Run:   showMembersMetricsCommandFunc,
+	})
 	return m
 }
 
@@ -125,6 +131,15 @@ func deleteMemberByIDCommandFunc(cmd *cobra.Command, args []string) {
 	cmd.Println("Success!")
 }
 
+func showMembersMetricsCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, membersMetricsPrefix, http.MethodGet)
+	if err != nil {
+		cmd.Printf("Failed to get the metrics of pd members: %s\n", err)
+		return
+	}
+	cmd.Println(r)
+}
+
 func getLeaderMemberCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, leaderMemberPrefix, http.MethodGet)
 	if err != nil {
@@ -16,6 +16,7 @@ package command
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/pingcap/errors"
@@ -42,6 +43,7 @@ func NewOperatorCommand() *cobra.Command {
 	c.AddCommand(NewCheckOperatorCommand())
 	c.AddCommand(NewAddOperatorCommand())
 	c.AddCommand(NewRemoveOperatorCommand())
+	c.AddCommand(NewCancelOperatorsCommand())
 	return c
 }
 
@@ -426,6 +428,58 @@ func removeOperatorCommandFunc(cmd *cobra.Command, args []string) {
 	cmd.Println("Success!")
 }
 
+// NewCancelOperatorsCommand returns a command to cancel operators in bulk.
+func NewCancelOperatorsCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "cancel [--desc=<desc>] [--source=scheduler|checker|api] [--kind=<kinds>] [--store=<store_id>] [--start-key=<key> --end-key=<key>] [--dry-run]",
+		Short: "cancel the running and the waiting operators matched by all the given filters",
+		Run:   cancelOperatorsCommandFunc,
+	}
+	c.Flags().String("desc", "", "the description of the operators, such as balance-region")
+	c.Flags().String("source", "", "where the operators come from")
+	c.Flags().String("kind", "", "the operator kinds separated by commas, any of which is matched")
+	c.Flags().Uint64("store", 0, "the store which the operators add a peer to or transfer the leader to")
+	c.Flags().String("start-key", "", "the start of the key range which the regions of the operators overlap")
+	c.Flags().String("end-key", "", "the end of the key range, exclusive")
+	c.Flags().Bool("dry-run", false, "only show the matched operators without canceling them")
+	return c
+}
+
+func cancelOperatorsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	query := make(url.Values)
+	for flag, param := range map[string]string{
+		"desc":      "desc",
+		"source":    "source",
+		"kind":      "kind",
+		"start-key": "start_key",
+		"end-key":   "end_key",
+	} {
+		if v, _ := cmd.Flags().GetString(flag); v != "" {
+			query.Set(param, v)
+		}
+	}
+	if storeID, _ := cmd.Flags().GetUint64("store"); storeID != 0 {
+		query.Set("store_id", strconv.FormatUint(storeID, 10))
+	}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		query.Set("dry_run", "true")
+	}
+	if len(query) == 0 || (len(query) == 1 && query.Get("dry_run") != "") {
+		cmd.Println("at least one filter is required")
+		return
+	}
+	r, err := doRequest(cmd, operatorsPrefix+"?"+query.Encode(), http.MethodDelete)
+	if err != nil {
+		cmd.Println(err)
+		return
+	}
+	cmd.Println(r)
+}
+
 func parseUint64s(args []string) ([]uint64, error) {
 	results := make([]uint64, 0, len(args))
 	for _, arg := range args {
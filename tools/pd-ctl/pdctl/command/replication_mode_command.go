@@ -0,0 +1,97 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	replicationModeStatusPrefix = "pd/api/v1/replication_mode/status"
+)
+
+// NewReplicationModeCommand return a replication-mode subcommand of rootCmd
+func NewReplicationModeCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "replication-mode <subcommand>",
+		Short: "show the replication mode status",
+	}
+	r.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "show the current replication mode state and the status of each store",
+		Run:   showReplicationModeStatusCommandFunc,
+	})
+	w := &cobra.Command{
+		Use:   "watch",
+		Short: "watch the replication mode and print the status once the state changes",
+		Run:   watchReplicationModeStatusCommandFunc,
+	}
+	w.Flags().Duration("interval", time.Second, "the interval to check the status")
+	r.AddCommand(w)
+	return r
+}
+
+func showReplicationModeStatusCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, replicationModeStatusPrefix, http.MethodGet)
+	if err != nil {
+		cmd.Printf("Failed to get replication mode status: %s\n", err)
+		return
+	}
+	cmd.Println(r)
+}
+
+func watchReplicationModeStatusCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+	interval, err := cmd.Flags().GetDuration("interval")
+	if err != nil || interval <= 0 {
+		cmd.Println("the interval should be a positive duration")
+		return
+	}
+	type replicationState struct {
+		Mode       string `json:"mode"`
+		DrAutoSync struct {
+			State   string `json:"state"`
+			StateID uint64 `json:"state_id"`
+		} `json:"dr-auto-sync"`
+	}
+	var last *replicationState
+	for {
+		r, err := doRequest(cmd, replicationModeStatusPrefix, http.MethodGet)
+		if err != nil {
+			cmd.Printf("Failed to get replication mode status: %s\n", err)
+		} else {
+			var state replicationState
+			if err := json.Unmarshal([]byte(r), &state); err != nil {
+				cmd.Printf("Failed to parse replication mode status: %s\n", err)
+				return
+			}
+			if last == nil || *last != state {
+				cmd.Printf("[%s] %s\n", time.Now().Format(time.RFC3339), r)
+				last = &state
+			}
+		}
+		time.Sleep(interval)
+	}
+}
@@ -150,8 +150,8 @@ func NewGrantLeaderSchedulerCommand() *cobra.Command {
 // NewEvictLeaderSchedulerCommand returns a command to add a evict-leader-scheduler.
 func NewEvictLeaderSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
-		Use:   "evict-leader-scheduler <store_id>",
-		Short: "add a scheduler to evict leader from a store",
+		Use:   "evict-leader-scheduler <store_id> [<start_key> <end_key>]...",
+		Short: "add a scheduler to evict leader from a store, or only the leaders of the regions in the key ranges",
 		Run:   addSchedulerForStoreCommandFunc,
 	}
 	return c
@@ -174,7 +174,7 @@ func checkSchedulerExist(cmd *cobra.Command, schedulerName string) (bool, error)
 }
 
 func addSchedulerForStoreCommandFunc(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
+	if len(args) != 1 && (cmd.Name() != evictLeaderSchedulerName || len(args)%2 == 0) {
 		cmd.Println(cmd.UsageString())
 		return
 	}
@@ -201,6 +201,9 @@ func addSchedulerForStoreCommandFunc(cmd *cobra.Command, args []string) {
 		input := make(map[string]interface{})
 		input["name"] = cmd.Name()
 		input["store_id"] = storeID
+		if len(args) > 1 {
+			input["ranges"] = args[1:]
+		}
 		postJSON(cmd, schedulersPrefix, input)
 	}
 
@@ -430,8 +433,8 @@ func newConfigEvictLeaderCommand() *cobra.Command {
 		Run:   listSchedulerConfigCommandFunc,
 	}
 	c.AddCommand(&cobra.Command{
-		Use:   "add-store <store-id>",
-		Short: "add a store to evict leader list",
+		Use:   "add-store <store-id> [<start_key> <end_key>]...",
+		Short: "add a store to evict leader list, or update the key ranges of the store",
 		Run:   func(cmd *cobra.Command, args []string) { addStoreToSchedulerConfig(cmd, c.Name(), args) },
 	}, &cobra.Command{
 		Use:   "delete-store <store-id>",
@@ -478,7 +481,7 @@ func newConfigShuffleRegionCommand() *cobra.Command {
 }
 
 func addStoreToSchedulerConfig(cmd *cobra.Command, schedulerName string, args []string) {
-	if len(args) != 1 {
+	if len(args) != 1 && (schedulerName != evictLeaderSchedulerName || len(args)%2 == 0) {
 		cmd.Println(cmd.UsageString())
 		return
 	}
@@ -490,6 +493,9 @@ func addStoreToSchedulerConfig(cmd *cobra.Command, schedulerName string, args []
 	input := make(map[string]interface{})
 	input["name"] = schedulerName
 	input["store_id"] = storeID
+	if len(args) > 1 {
+		input["ranges"] = args[1:]
+	}
 
 	postJSON(cmd, path.Join(schedulerConfigPrefix, schedulerName, "config"), input)
 }
@@ -87,9 +87,11 @@ func getBasicCmd() *cobra.Command {
 		command.NewHotSpotCommand(),
 		command.NewClusterCommand(),
 		command.NewHealthCommand(),
+		command.NewReplicationModeCommand(),
 		command.NewLogCommand(),
 		command.NewPluginCommand(),
 		command.NewServiceGCSafepointCommand(),
+		command.NewKeyLayoutCommand(),
 		command.NewCompletionCommand(),
 	)
 
@@ -13,6 +13,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/core"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/pkg/transport"
 )
@@ -25,6 +26,9 @@ var (
 	caPath    string
 	certPath  string
 	keyPath   string
+
+	regionSnapshot string
+	requireVerify  bool
 )
 
 const (
@@ -33,6 +37,9 @@ const (
 
 	pdRootPath      = "/pd"
 	pdClusterIDPath = "/pd/cluster_id"
+
+	// maxTxnOps is the max number of operations in one etcd transaction.
+	maxTxnOps = 128
 )
 
 func exitErr(err error) {
@@ -40,15 +47,44 @@ func exitErr(err error) {
 	os.Exit(1)
 }
 
+// addConnectionFlags adds the flags to connect to the PD cluster.
+func addConnectionFlags(fs *flag.FlagSet) {
+	fs.StringVar(&endpoints, "endpoints", "http://127.0.0.1:2379", "endpoints urls")
+	fs.StringVar(&caPath, "cacert", "", "path of file that contains list of trusted SSL CAs")
+	fs.StringVar(&certPath, "cert", "", "path of file that contains list of trusted SSL CAs")
+	fs.StringVar(&keyPath, "key", "", "path of file that contains X509 key in PEM format")
+}
+
+func newClient() (*clientv3.Client, error) {
+	tlsInfo := transport.TLSInfo{
+		CertFile:      certPath,
+		KeyFile:       keyPath,
+		TrustedCAFile: caPath,
+	}
+	tlsConfig, err := tlsInfo.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %v", err)
+	}
+	return clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(endpoints, ","),
+		DialTimeout: etcdTimeout,
+		TLS:         tlsConfig,
+	})
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		runVerify(os.Args[2:])
+		return
+	}
+
 	fs := flag.NewFlagSet("pd-recover", flag.ExitOnError)
 	fs.BoolVar(&v, "V", false, "print version information")
-	fs.StringVar(&endpoints, "endpoints", "http://127.0.0.1:2379", "endpoints urls")
 	fs.Uint64Var(&allocID, "alloc-id", 0, "please make sure alloced ID is safe")
 	fs.Uint64Var(&clusterID, "cluster-id", 0, "please make cluster ID match with tikv")
-	fs.StringVar(&caPath, "cacert", "", "path of file that contains list of trusted SSL CAs")
-	fs.StringVar(&certPath, "cert", "", "path of file that contains list of trusted SSL CAs")
-	fs.StringVar(&keyPath, "key", "", "path of file that contains X509 key in PEM format")
+	fs.StringVar(&regionSnapshot, "region-snapshot", "", "path of the region snapshot exported by PD to recover the regions from")
+	fs.BoolVar(&requireVerify, "require-verify", false, "reject the heartbeats and writes of the stores until `pd-recover verify` is run")
+	addConnectionFlags(fs)
 
 	if len(os.Args[1:]) == 0 {
 		fs.Usage()
@@ -70,28 +106,24 @@ func main() {
 		return
 	}
 
+	var regions []*metapb.Region
+	if regionSnapshot != "" {
+		var err error
+		regions, err = loadRegionSnapshot(regionSnapshot)
+		if err != nil {
+			exitErr(err)
+		}
+		if maxID := maxRegionSnapshotID(regions); allocID <= maxID {
+			fmt.Printf("please specify safe alloc-id, it should be larger than %d\n", maxID)
+			return
+		}
+	}
+
 	rootPath := path.Join(pdRootPath, strconv.FormatUint(clusterID, 10))
 	clusterRootPath := path.Join(rootPath, "raft")
 	raftBootstrapTimeKey := path.Join(clusterRootPath, "status", "raft_bootstrap_time")
 
-	urls := strings.Split(endpoints, ",")
-
-	tlsInfo := transport.TLSInfo{
-		CertFile:      certPath,
-		KeyFile:       keyPath,
-		TrustedCAFile: caPath,
-	}
-	tlsConfig, err := tlsInfo.ClientConfig()
-	if err != nil {
-		fmt.Println("failed to connect: err")
-		return
-	}
-
-	client, err := clientv3.New(clientv3.Config{
-		Endpoints:   urls,
-		DialTimeout: etcdTimeout,
-		TLS:         tlsConfig,
-	})
+	client, err := newClient()
 	if err != nil {
 		exitErr(err)
 	}
@@ -118,6 +150,10 @@ func main() {
 	nano := time.Now().UnixNano()
 	timeData := typeutil.Uint64ToBytes(uint64(nano))
 	ops = append(ops, clientv3.OpPut(raftBootstrapTimeKey, string(timeData)))
+	if requireVerify {
+		// reject the writes of the stores until the recovery is verified
+		ops = append(ops, clientv3.OpPut(path.Join(clusterRootPath, "status", recoverUnverifiedOption), string(timeData)))
+	}
 
 	// the new pd cluster should not bootstrapped by tikv
 	bootstrapCmp := clientv3.Compare(clientv3.CreateRevision(clusterRootPath), "=", 0)
@@ -129,5 +165,75 @@ func main() {
 		fmt.Println("failed to recover: the cluster is already bootstrapped")
 		return
 	}
+	if len(regions) > 0 {
+		if err := recoverRegions(client, clusterRootPath, regions); err != nil {
+			exitErr(err)
+		}
+		fmt.Printf("recover %d regions, they are loaded when use-region-storage is disabled, "+
+			"or import the snapshot with the admin API after restart\n", len(regions))
+	}
+	if requireVerify {
+		fmt.Println("recover success! please run `pd-recover verify` with the store reports, and restart the PD cluster")
+		return
+	}
 	fmt.Println("recover success! please restart the PD cluster")
 }
+
+func loadRegionSnapshot(name string) ([]*metapb.Region, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var regions []*metapb.Region
+	err = core.ReadRegionSnapshot(f, func(region *core.RegionInfo) error {
+		regions = append(regions, region.GetMeta())
+		return nil
+	})
+	return regions, err
+}
+
+// maxRegionSnapshotID returns the max region and peer ID in the snapshot,
+// which the alloc ID must be larger than.
+func maxRegionSnapshotID(regions []*metapb.Region) uint64 {
+	var maxID uint64
+	for _, region := range regions {
+		if region.GetId() > maxID {
+			maxID = region.GetId()
+		}
+		for _, peer := range region.GetPeers() {
+			if peer.GetId() > maxID {
+				maxID = peer.GetId()
+			}
+		}
+	}
+	return maxID
+}
+
+// recoverRegions saves the region metas to etcd in batches, as the number
+// of operations in a transaction is limited.
+func recoverRegions(client *clientv3.Client, clusterRootPath string, regions []*metapb.Region) error {
+	for len(regions) > 0 {
+		n := len(regions)
+		if n > maxTxnOps {
+			n = maxTxnOps
+		}
+		ops := make([]clientv3.Op, 0, n)
+		for _, region := range regions[:n] {
+			value, err := region.Marshal()
+			if err != nil {
+				return err
+			}
+			key := path.Join(clusterRootPath, "r", fmt.Sprintf("%020d", region.GetId()))
+			ops = append(ops, clientv3.OpPut(key, string(value)))
+		}
+		ctx, cancel := context.WithTimeout(client.Ctx(), requestTimeout)
+		_, err := client.Txn(ctx).Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			return err
+		}
+		regions = regions[n:]
+	}
+	return nil
+}
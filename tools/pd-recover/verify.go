@@ -0,0 +1,260 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/pkg/typeutil"
+	"go.etcd.io/etcd/clientv3"
+)
+
+// recoverUnverifiedOption is the cluster status option which is saved by the
+// recovery and removed by a successful verification. PD rejects the writes of
+// the stores while it exists, which are the store and region heartbeats,
+// putting the stores, splitting the regions and allocating the IDs.
+const recoverUnverifiedOption = "recover_unverified"
+
+// loadPageSize is the number of keys to load from etcd in one request.
+const loadPageSize = 1024
+
+var storeReports string
+
+// storeReport is the metadata of a live TiKV store, which is collected from the
+// stores by the operator into the JSON file given by -store-reports, e.g.
+// {"cluster_id": 1, "store": {"id": 1, "address": "tikv1:20160"}, "max_region_id": 100, "max_peer_id": 120}.
+type storeReport struct {
+	ClusterID   uint64        `json:"cluster_id"`
+	Store       *metapb.Store `json:"store"`
+	MaxRegionID uint64        `json:"max_region_id"`
+	MaxPeerID   uint64        `json:"max_peer_id"`
+}
+
+// recoveredState is the metadata in etcd after the recovery.
+type recoveredState struct {
+	clusterID     uint64
+	metaClusterID uint64
+	allocID       uint64
+	stores        []*metapb.Store
+	// maxRegionID is the max region or peer ID of the regions.
+	maxRegionID uint64
+}
+
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("pd-recover verify", flag.ExitOnError)
+	fs.Uint64Var(&clusterID, "cluster-id", 0, "the cluster ID specified when recovering")
+	fs.StringVar(&storeReports, "store-reports", "", "path of the JSON file with the metadata reported by the live TiKV stores")
+	addConnectionFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		exitErr(err)
+	}
+	if clusterID == 0 {
+		fmt.Println("please specify cluster-id")
+		return
+	}
+	if storeReports == "" {
+		fmt.Println("please specify store-reports")
+		return
+	}
+	reports, err := loadStoreReports(storeReports)
+	if err != nil {
+		exitErr(err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		exitErr(err)
+	}
+	rootPath := path.Join(pdRootPath, strconv.FormatUint(clusterID, 10))
+	clusterRootPath := path.Join(rootPath, "raft")
+	state, err := loadRecoveredState(client, rootPath, clusterRootPath)
+	if err != nil {
+		exitErr(err)
+	}
+	problems := verifyRecoveredState(clusterID, state, reports)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		fmt.Println("verify failed! the cluster keeps rejecting the writes of the stores, please recover again with the right arguments")
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(client.Ctx(), requestTimeout)
+	defer cancel()
+	if _, err := client.Delete(ctx, path.Join(clusterRootPath, "status", recoverUnverifiedOption)); err != nil {
+		exitErr(err)
+	}
+	fmt.Println("verify success! the cluster accepts the writes of the stores now")
+}
+
+func loadStoreReports(name string) ([]*storeReport, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var reports []*storeReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func loadRecoveredState(client *clientv3.Client, rootPath, clusterRootPath string) (*recoveredState, error) {
+	state := &recoveredState{}
+	ctx, cancel := context.WithTimeout(client.Ctx(), requestTimeout)
+	defer cancel()
+
+	resp, err := client.Get(ctx, pdClusterIDPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) > 0 {
+		if state.clusterID, err = typeutil.BytesToUint64(resp.Kvs[0].Value); err != nil {
+			return nil, err
+		}
+	}
+	resp, err = client.Get(ctx, path.Join(rootPath, "alloc_id"))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) > 0 {
+		if state.allocID, err = typeutil.BytesToUint64(resp.Kvs[0].Value); err != nil {
+			return nil, err
+		}
+	}
+	resp, err = client.Get(ctx, clusterRootPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) > 0 {
+		meta := &metapb.Cluster{}
+		if err := meta.Unmarshal(resp.Kvs[0].Value); err != nil {
+			return nil, err
+		}
+		state.metaClusterID = meta.GetId()
+	}
+
+	err = loadPrefix(client, path.Join(clusterRootPath, "s")+"/", func(value []byte) error {
+		store := &metapb.Store{}
+		if err := store.Unmarshal(value); err != nil {
+			return err
+		}
+		state.stores = append(state.stores, store)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = loadPrefix(client, path.Join(clusterRootPath, "r")+"/", func(value []byte) error {
+		region := &metapb.Region{}
+		if err := region.Unmarshal(value); err != nil {
+			return err
+		}
+		if maxID := maxRegionSnapshotID([]*metapb.Region{region}); maxID > state.maxRegionID {
+			state.maxRegionID = maxID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// loadPrefix calls f with the values of the keys with the prefix, page by page.
+func loadPrefix(client *clientv3.Client, prefix string, f func(value []byte) error) error {
+	key, end := prefix, clientv3.GetPrefixRangeEnd(prefix)
+	for {
+		ctx, cancel := context.WithTimeout(client.Ctx(), requestTimeout)
+		resp, err := client.Get(ctx, key, clientv3.WithRange(end), clientv3.WithLimit(loadPageSize))
+		cancel()
+		if err != nil {
+			return err
+		}
+		for _, kv := range resp.Kvs {
+			if err := f(kv.Value); err != nil {
+				return err
+			}
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			return nil
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// verifyRecoveredState cross-checks the recovered metadata against the store
+// reports, and returns the problems found. pd-recover does not restore the
+// stores, so PD has no store metadata after a recovery and the stores are only
+// cross-checked if they are registered in PD. The checks that matter then are
+// the cluster IDs and the alloc ID against the IDs reported by the stores.
+func verifyRecoveredState(clusterID uint64, state *recoveredState, reports []*storeReport) []string {
+	var problems []string
+	if state.clusterID != clusterID {
+		problems = append(problems, fmt.Sprintf("the cluster ID is %d, but %d is expected", state.clusterID, clusterID))
+	}
+	if state.metaClusterID != clusterID {
+		problems = append(problems, fmt.Sprintf("the cluster meta has ID %d, but %d is expected", state.metaClusterID, clusterID))
+	}
+	if state.maxRegionID >= state.allocID {
+		problems = append(problems, fmt.Sprintf("the alloc ID %d is not larger than the max region or peer ID %d", state.allocID, state.maxRegionID))
+	}
+
+	stores := make(map[uint64]*metapb.Store, len(state.stores))
+	for _, store := range state.stores {
+		stores[store.GetId()] = store
+		if store.GetId() >= state.allocID {
+			problems = append(problems, fmt.Sprintf("the alloc ID %d is not larger than the store ID %d", state.allocID, store.GetId()))
+		}
+	}
+	reported := make(map[uint64]struct{}, len(reports))
+	addresses := make(map[string]uint64, len(reports))
+	for _, report := range reports {
+		storeID := report.Store.GetId()
+		if report.ClusterID != clusterID {
+			problems = append(problems, fmt.Sprintf("store %d reports cluster ID %d, but %d is expected", storeID, report.ClusterID, clusterID))
+		}
+		if _, ok := reported[storeID]; ok {
+			problems = append(problems, fmt.Sprintf("store %d is reported more than once", storeID))
+		}
+		reported[storeID] = struct{}{}
+		if id, ok := addresses[report.Store.GetAddress()]; ok && report.Store.GetAddress() != "" {
+			problems = append(problems, fmt.Sprintf("stores %d and %d report the same address %s", id, storeID, report.Store.GetAddress()))
+		}
+		addresses[report.Store.GetAddress()] = storeID
+		for _, id := range []uint64{storeID, report.MaxRegionID, report.MaxPeerID} {
+			if id >= state.allocID {
+				problems = append(problems, fmt.Sprintf("the alloc ID %d is not larger than ID %d reported by store %d", state.allocID, id, storeID))
+			}
+		}
+		if store, ok := stores[storeID]; ok && store.GetAddress() != report.Store.GetAddress() {
+			problems = append(problems, fmt.Sprintf("store %d has address %s in PD, but reports %s", storeID, store.GetAddress(), report.Store.GetAddress()))
+		}
+	}
+	for _, store := range state.stores {
+		if _, ok := reported[store.GetId()]; !ok && store.GetState() != metapb.StoreState_Tombstone {
+			problems = append(problems, fmt.Sprintf("store %d in PD is not reported", store.GetId()))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+func TestVerify(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testVerifySuite{})
+
+type testVerifySuite struct{}
+
+func (s *testVerifySuite) TestVerifyRecoveredState(c *C) {
+	state := &recoveredState{
+		clusterID:     1,
+		metaClusterID: 1,
+		allocID:       1000,
+		stores: []*metapb.Store{
+			{Id: 1, Address: "tikv1"},
+			{Id: 2, Address: "tikv2"},
+			{Id: 3, Address: "tikv3", State: metapb.StoreState_Tombstone},
+		},
+		maxRegionID: 100,
+	}
+	reports := []*storeReport{
+		{ClusterID: 1, Store: &metapb.Store{Id: 1, Address: "tikv1"}, MaxRegionID: 200, MaxPeerID: 300},
+		{ClusterID: 1, Store: &metapb.Store{Id: 2, Address: "tikv2"}, MaxRegionID: 200, MaxPeerID: 301},
+	}
+	c.Assert(verifyRecoveredState(1, state, reports), HasLen, 0)
+
+	// The cluster ID is inconsistent.
+	c.Assert(verifyRecoveredState(2, state, reports), HasLen, 4)
+	// The alloc ID is not larger than the IDs in use.
+	state.allocID = 301
+	c.Assert(verifyRecoveredState(1, state, reports), HasLen, 1)
+	state.allocID = 1000
+	// The store metadata is inconsistent.
+	reports[1].Store.Address = "tikv1"
+	c.Assert(verifyRecoveredState(1, state, reports), HasLen, 2)
+	c.Assert(verifyRecoveredState(1, state, reports[:1]), HasLen, 1)
+}
@@ -49,13 +49,14 @@ var (
 	regionNum                   = flag.Int("regionNum", 0, "regionNum of one store")
 	storeNum                    = flag.Int("storeNum", 0, "storeNum")
 	enableTransferRegionCounter = flag.Bool("enableTransferRegionCounter", false, "enableTransferRegionCounter")
+	regionSnapshot              = flag.String("regionSnapshot", "", "region snapshot file used by the region-snapshot case")
 )
 
 func main() {
 	flag.Parse()
 
 	simutil.InitLogger(*simLogLevel, *simLogFile)
-	simutil.InitCaseConfig(*storeNum, *regionNum, *enableTransferRegionCounter)
+	simutil.InitCaseConfig(*storeNum, *regionNum, *enableTransferRegionCounter, *regionSnapshot)
 	statistics.Denoising = false
 	if simutil.CaseConfigure.EnableTransferRegionCounter {
 		analysis.GetTransferCounter().Init(simutil.CaseConfigure.StoreNum, simutil.CaseConfigure.RegionNum)
@@ -39,6 +39,9 @@ type Region struct {
 	Leader *metapb.Peer
 	Size   int64
 	Keys   int64
+	// StartKey and EndKey are generated if both of them are not set.
+	StartKey []byte
+	EndKey   []byte
 }
 
 // CheckerFunc checks if the scheduler is finished.
@@ -102,6 +105,7 @@ var CaseMap = map[string]func() *Case{
 	"hot-write":                newHotWrite,
 	"makeup-down-replicas":     newMakeupDownReplicas,
 	"import-data":              newImportData,
+	"region-snapshot":          newRegionSnapshot,
 }
 
 // NewCase creates a new case.
@@ -0,0 +1,121 @@
+// Copyright 2021 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cases
+
+import (
+	"os"
+	"sort"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/tools/pd-simulator/simulator/info"
+	"github.com/tikv/pd/tools/pd-simulator/simulator/simutil"
+	"go.uber.org/zap"
+)
+
+// newRegionSnapshot replays the region distribution of the region snapshot
+// exported by PD, and waits for the leaders and regions to be balanced.
+func newRegionSnapshot() *Case {
+	var simCase Case
+
+	name := simutil.CaseConfigure.RegionSnapshot
+	if name == "" {
+		simutil.Logger.Fatal("Region snapshot should be specified.")
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		simutil.Logger.Fatal("failed to open the region snapshot", zap.Error(err))
+	}
+	defer f.Close()
+
+	storeIDs := make(map[uint64]struct{})
+	var peerNum int
+	err = core.ReadRegionSnapshot(f, func(region *core.RegionInfo) error {
+		peers := region.GetPeers()
+		if len(peers) == 0 {
+			return nil
+		}
+		leader := region.GetLeader()
+		if leader == nil {
+			leader = peers[0]
+		}
+		for _, peer := range peers {
+			storeIDs[peer.GetStoreId()] = struct{}{}
+			if peer.GetId() > IDAllocator.id {
+				IDAllocator.id = peer.GetId()
+			}
+		}
+		if region.GetID() > IDAllocator.id {
+			IDAllocator.id = region.GetID()
+		}
+		peerNum += len(peers)
+		simCase.Regions = append(simCase.Regions, Region{
+			ID:       region.GetID(),
+			Peers:    peers,
+			Leader:   leader,
+			Size:     region.GetApproximateSize() * MB,
+			Keys:     region.GetApproximateKeys(),
+			StartKey: region.GetStartKey(),
+			EndKey:   region.GetEndKey(),
+		})
+		return nil
+	})
+	if err != nil {
+		simutil.Logger.Fatal("failed to read the region snapshot", zap.Error(err))
+	}
+	if len(simCase.Regions) == 0 {
+		simutil.Logger.Fatal("Region snapshot should not be empty.")
+	}
+	// Sort the regions by the start key to make the replay deterministic.
+	sort.Slice(simCase.Regions, func(i, j int) bool {
+		return string(simCase.Regions[i].StartKey) < string(simCase.Regions[j].StartKey)
+	})
+
+	ids := make([]uint64, 0, len(storeIDs))
+	for id := range storeIDs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		simCase.Stores = append(simCase.Stores, &Store{
+			ID:        id,
+			Status:    metapb.StoreState_Up,
+			Capacity:  1 * TB,
+			Available: 900 * GB,
+			Version:   "2.1.0",
+		})
+		if id > IDAllocator.id {
+			IDAllocator.id = id
+		}
+	}
+
+	storeNum, regionNum := len(ids), len(simCase.Regions)
+	threshold := 0.05
+	simCase.Checker = func(regions *core.RegionsInfo, stats []info.StoreStats) bool {
+		res := true
+		leaderCounts := make([]int, 0, storeNum)
+		regionCounts := make([]int, 0, storeNum)
+		for _, id := range ids {
+			leaderCount := regions.GetStoreLeaderCount(id)
+			regionCount := regions.GetStoreRegionCount(id)
+			leaderCounts = append(leaderCounts, leaderCount)
+			regionCounts = append(regionCounts, regionCount)
+			res = res && isUniform(leaderCount, regionNum/storeNum, threshold) &&
+				isUniform(regionCount, peerNum/storeNum, threshold)
+		}
+		simutil.Logger.Info("current counts", zap.Ints("leader", leaderCounts), zap.Ints("region", regionCounts))
+		return res
+	}
+	return &simCase
+}
@@ -63,11 +63,15 @@ func NewRaftEngine(conf *cases.Case, conn *Connection, storeConfig *SimConfig) *
 			Peers:       region.Peers,
 			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
 		}
-		if i > 0 {
-			meta.StartKey = []byte(splitKeys[i-1])
-		}
-		if i < len(conf.Regions)-1 {
-			meta.EndKey = []byte(splitKeys[i])
+		if region.StartKey != nil || region.EndKey != nil {
+			meta.StartKey, meta.EndKey = region.StartKey, region.EndKey
+		} else {
+			if i > 0 {
+				meta.StartKey = []byte(splitKeys[i-1])
+			}
+			if i < len(conf.Regions)-1 {
+				meta.EndKey = []byte(splitKeys[i])
+			}
 		}
 		regionInfo := core.NewRegionInfo(
 			meta,
@@ -18,16 +18,18 @@ type CaseConfig struct {
 	StoreNum                    int
 	RegionNum                   int
 	EnableTransferRegionCounter bool
+	RegionSnapshot              string
 }
 
 // CaseConfigure is an global instance for CaseConfig
 var CaseConfigure *CaseConfig
 
 // InitCaseConfig is to init caseConfigure
-func InitCaseConfig(StoreNum, RegionNum int, EnableTransferRegionCounter bool) {
+func InitCaseConfig(StoreNum, RegionNum int, EnableTransferRegionCounter bool, RegionSnapshot string) {
 	CaseConfigure = &CaseConfig{
 		StoreNum:                    StoreNum,
 		RegionNum:                   RegionNum,
 		EnableTransferRegionCounter: EnableTransferRegionCounter,
+		RegionSnapshot:              RegionSnapshot,
 	}
 }